@@ -0,0 +1,153 @@
+// AI formula evaluation - scores formula generation against the golden set
+//
+// Each golden request is sent through the same GenerateWithValidation path as
+// the API. The generated formula passes when it is semantically equivalent to
+// the expected one: both are executed with the DSL at sample locations and
+// dates, and every time agrees within the tolerance.
+//
+// Usage:
+//
+//	cd api && go run ./cmd/ai-eval [flags]
+//
+// Flags:
+//
+//	-golden path     golden set JSON (default: the versioned set built in)
+//	-cases a,b       only run cases whose ID contains, or tag equals, a term
+//	-tolerance 60s   largest difference still considered equal
+//	-rag             assemble RAG context from DATABASE_URL like the API does
+//	-json            print the full report as JSON
+//	-min-pass 0.8    exit non-zero when the pass rate is below this
+//
+// Environment variables:
+//
+//	AI_PROVIDER, ANTHROPIC_API_KEY, ANTHROPIC_BASE_URL, ANTHROPIC_MODEL - the model under test
+//	AI_PROVIDER=fake without AI_FAKE_SCRIPT answers with the golden formulas,
+//	which checks the harness end to end in CI
+//	DATABASE_URL, EMBEDDING_PROVIDER, OPENAI_API_KEY - for -rag
+//
+// Compare reports before and after changing formulaSystemPrompt or context
+// assembly to see whether generation got better or worse.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/ai/eval"
+)
+
+func main() {
+	goldenPath := flag.String("golden", "", "golden set JSON file (default: built-in set)")
+	caseFilter := flag.String("cases", "", "comma-separated case IDs or tags to run")
+	tolerance := flag.Duration("tolerance", eval.DefaultTolerance, "largest time difference considered equal")
+	useRAG := flag.Bool("rag", false, "assemble RAG context from DATABASE_URL")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	minPass := flag.Float64("min-pass", 0, "exit 1 when the pass rate is below this (0-1)")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	set, err := loadGoldenSet(*goldenPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var terms []string
+	if *caseFilter != "" {
+		terms = strings.Split(*caseFilter, ",")
+	}
+	cases := set.Filter(terms)
+	if len(cases) == 0 {
+		log.Fatal("no golden cases selected")
+	}
+
+	llm, err := ai.LLMFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure AI provider: %v", err)
+	}
+	if llm == nil {
+		log.Fatal("ANTHROPIC_API_KEY is required (or set AI_PROVIDER=fake)")
+	}
+	if _, ok := llm.(*ai.FakeLLM); ok && os.Getenv("AI_FAKE_SCRIPT") == "" {
+		llm = eval.OracleLLM(cases)
+	}
+
+	runner := &eval.Runner{
+		Generator: ai.NewClaudeServiceWithLLM(llm),
+		Tolerance: *tolerance,
+	}
+
+	if *useRAG {
+		databaseURL := os.Getenv("DATABASE_URL")
+		if databaseURL == "" {
+			log.Fatal("DATABASE_URL is required for -rag")
+		}
+		pool, err := pgxpool.New(ctx, databaseURL)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer pool.Close()
+
+		embedder, err := ai.EmbedderFromEnv()
+		if err != nil {
+			log.Fatalf("Failed to configure embedding provider: %v", err)
+		}
+		runner.Context = ai.NewContextService(ai.NewSearchService(pool, embedder))
+	}
+
+	report, err := runner.Run(ctx, set.Version, cases)
+	if err != nil {
+		log.Fatalf("Evaluation failed: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		printReport(report)
+	}
+
+	if report.Summary.PassRate < *minPass {
+		fmt.Fprintf(os.Stderr, "pass rate %.1f%% is below the required %.1f%%\n", report.Summary.PassRate*100, *minPass*100)
+		os.Exit(1)
+	}
+}
+
+func loadGoldenSet(path string) (*eval.GoldenSet, error) {
+	if path == "" {
+		return eval.DefaultGoldenSet()
+	}
+	return eval.LoadGoldenSet(path)
+}
+
+func printReport(report *eval.Report) {
+	fmt.Printf("Golden set v%d · model %s · RAG %v · tolerance %s\n\n", report.GoldenVersion, report.Model, report.RAG, report.Tolerance)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CASE\tSTATUS\tTRIES\tTOKENS\tGENERATED\tDETAIL")
+	for _, c := range report.Cases {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", c.ID, c.Status, c.Attempts, c.TokensUsed, c.Generated, c.Error)
+	}
+	w.Flush()
+
+	s := report.Summary
+	fmt.Printf("\nPassed %d/%d (%.1f%%) · validation failures %d · retries %d · tokens %d · %s\n",
+		s.Passed, s.Total, s.PassRate*100, s.ValidationFailures, s.Retries, s.TokensUsed,
+		(time.Duration(s.DurationMs) * time.Millisecond).String())
+	for _, status := range []eval.Status{eval.StatusMismatch, eval.StatusInvalid, eval.StatusUnsupported, eval.StatusError} {
+		if n := report.ByStatus[status]; n > 0 {
+			fmt.Printf("  %s: %d\n", status, n)
+		}
+	}
+}
@@ -23,6 +23,11 @@
 //	@name						Authorization
 //	@description				JWT Bearer token from Clerk authentication
 //
+//	@securityDefinitions.apikey	APIKeyAuth
+//	@in							header
+//	@name						X-API-Key
+//	@description				API key issued to a third-party consumer (zl_...)
+//
 //	@tag.name			Publishers
 //	@tag.description	Publisher profile and management endpoints
 //
@@ -64,6 +69,8 @@ import (
 	"github.com/jcom-dev/zmanim-lab/internal/db"
 	"github.com/jcom-dev/zmanim-lab/internal/handlers"
 	custommw "github.com/jcom-dev/zmanim-lab/internal/middleware"
+	"github.com/jcom-dev/zmanim-lab/internal/services"
+	"github.com/jcom-dev/zmanim-lab/internal/terrain"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 
 	_ "github.com/jcom-dev/zmanim-lab/docs" // Swagger generated docs
@@ -97,32 +104,45 @@ func main() {
 		defer redisCache.Close()
 	}
 
-	// Initialize AI services (optional - only if API keys are set)
+	// Initialize AI services (optional - only if a provider is configured)
 	var claudeService *ai.ClaudeService
-	var searchService *ai.SearchService
-	var contextService *ai.ContextService
-	var embeddingService *ai.EmbeddingService
-
-	// Claude service for formula generation/explanation
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		claudeService = ai.NewClaudeService(apiKey)
-		log.Println("Claude AI service initialized")
+
+	// LLM for formula generation/explanation (AI_PROVIDER, default anthropic)
+	llm, err := ai.LLMFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure AI provider: %v", err)
+	}
+	if llm != nil {
+		claudeService = ai.NewClaudeServiceWithLLM(llm)
+		log.Printf("AI generation service initialized (provider %s, model %s)", ai.LLMProviderName(), llm.Model())
 	} else {
-		log.Println("Warning: ANTHROPIC_API_KEY not set - AI generation features will be disabled")
+		log.Printf("Warning: AI provider %s has no API key (ANTHROPIC_API_KEY) - AI generation features will be disabled", ai.LLMProviderName())
 	}
 
-	// OpenAI embeddings and RAG services
-	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
-		embeddingService = ai.NewEmbeddingService(apiKey)
-		searchService = ai.NewSearchService(database.Pool, embeddingService)
-		contextService = ai.NewContextService(searchService)
-		log.Println("OpenAI embedding and RAG services initialized")
+	// Embeddings (EMBEDDING_PROVIDER, default openai). RAG search works without
+	// them, falling back to full-text retrieval only.
+	embeddingService, err := ai.EmbedderFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure embedding provider: %v", err)
+	}
+	if embeddingService != nil {
+		log.Printf("Embedding service initialized (provider %s) - RAG uses hybrid lexical + vector search", ai.EmbeddingProviderName())
 	} else {
-		log.Println("Warning: OPENAI_API_KEY not set - RAG search features will be disabled")
+		log.Printf("Warning: embedding provider %s has no API key (OPENAI_API_KEY) - RAG search will use full-text retrieval only", ai.EmbeddingProviderName())
 	}
+	searchService := ai.NewSearchService(database.Pool, embeddingService)
+	contextService := ai.NewContextService(searchService)
 
 	h.SetAIServices(claudeService, searchService, contextService, embeddingService)
 
+	// SRTM elevations for coordinate-only requests, read from tiles already
+	// downloaded by cmd/import-wof (never fetched while serving)
+	if srtm, err := terrain.NewOfflineSRTMSource(cfg.Geo.SRTMCacheDir); err != nil {
+		log.Printf("Warning: SRTM elevation source unavailable: %v - using nearest-city elevations", err)
+	} else {
+		h.SetElevationSource(srtm)
+	}
+
 	// Setup router
 	r := chi.NewRouter()
 
@@ -138,8 +158,8 @@ func main() {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORS.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Publisher-Id"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Publisher-Id", "X-API-Key"},
+		ExposedHeaders:   []string{"Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -169,11 +189,29 @@ func main() {
 		http.ServeFile(w, r, "./docs/swagger.yaml")
 	})
 
-	// Initialize auth middleware
+	// Initialize auth middleware (Clerk JWTs and API keys)
 	authMiddleware := custommw.NewAuthMiddleware(cfg.JWT.JWKSUrl, cfg.JWT.Issuer)
-
-	// Initialize rate limiter
-	rateLimiter := custommw.NewDefaultRateLimiter()
+	apiKeyService := services.NewAPIKeyService(database)
+	defer apiKeyService.Stop()
+	authMiddleware.SetAPIKeyValidator(apiKeyService)
+	h.SetAPIKeyService(apiKeyService)
+
+	// Webhook delivery worker (queue lives in Postgres, safe to run on every replica)
+	webhookService := services.NewWebhookService(database, 15*time.Second, cfg.Webhooks.AllowPrivateNetworks)
+	webhookService.Start()
+	defer webhookService.Stop()
+	h.SetWebhookService(webhookService)
+
+	// Initialize rate limiter (shared across replicas via Redis when available)
+	var rateLimiter *custommw.RateLimiter
+	if redisCache != nil {
+		rateLimiter = custommw.NewRateLimiterWithStore(custommw.DefaultRateLimiterConfig(), custommw.NewRedisRateLimitStore(redisCache.Client()))
+		log.Println("Rate limiter using Redis store")
+	} else {
+		rateLimiter = custommw.NewDefaultRateLimiter()
+		log.Println("Warning: Rate limiter using in-memory store - limits are per process")
+	}
+	rateLimiter.SetQuotaSource(services.NewRateLimitService(database, time.Minute))
 	defer rateLimiter.Stop()
 
 	// API routes
@@ -189,6 +227,7 @@ func main() {
 			r.Get("/publishers", h.GetPublishers)
 			r.Get("/publishers/{id}", h.GetPublisher)
 			r.Get("/publishers/names", h.GetPublisherNames)
+			r.Get("/publishers/at-point", h.GetPublishersForPoint)
 
 			// Publisher registration requests (public)
 			r.Post("/publisher-requests", h.SubmitPublisherRequest)
@@ -199,6 +238,7 @@ func main() {
 			// Cities - Global location system
 			r.Get("/cities", h.SearchCities)
 			r.Get("/cities/nearby", h.GetNearbyCity)
+			r.Get("/cities/nearest", h.GetNearestCities) // k-nearest / within radius, paged
 			r.Get("/cities/{id}", h.GetCityByID)
 			r.Get("/cities/{cityId}/publishers", h.GetPublishersForCity)
 
@@ -218,10 +258,12 @@ func main() {
 			r.Get("/geo/boundaries/lookup", h.LookupPointLocation)
 			r.Get("/geo/boundaries/at-point", h.SmartLookupPointLocation) // Zoom-aware smart lookup
 			r.Get("/geo/boundaries/stats", h.GetBoundaryStats)
+			r.Get("/geo/boundaries/coverage", h.GetCoverageBoundaries)
+			r.Get("/geo/tiles/{layer}/{z}/{x}/{y}.mvt", h.GetBoundaryTile)
 
 			// Zmanim calculations
-			r.Get("/zmanim", h.GetZmanimForCity) // New: GET with cityId, date, publisherId
-			r.Post("/zmanim", h.CalculateZmanim) // Legacy: POST with coordinates
+			r.With(custommw.RequireAPIKeyScope(custommw.ScopeReadZmanim)).Get("/zmanim", h.GetZmanimForCity) // New: GET with cityId, date, publisherId
+			r.With(custommw.RequireAPIKeyScope(custommw.ScopeReadZmanim)).Post("/zmanim", h.CalculateZmanim) // Legacy: POST with coordinates
 
 			// DSL endpoints (Epic 4)
 			r.Post("/dsl/validate", h.ValidateDSLFormula)        // Validate DSL formula
@@ -235,10 +277,13 @@ func main() {
 			r.Post("/ai/explain-formula", h.ExplainFormula)
 
 			// Hebrew calendar endpoints (Story 4-10)
-			r.Get("/calendar/week", h.GetWeekCalendar)
-			r.Get("/calendar/hebrew-date", h.GetHebrewDate)
-			r.Get("/calendar/gregorian-date", h.GetGregorianDate)
-			r.Get("/calendar/shabbat", h.GetShabbatTimes)
+			r.Group(func(r chi.Router) {
+				r.Use(custommw.RequireAPIKeyScope(custommw.ScopeReadCalendar))
+				r.Get("/calendar/week", h.GetWeekCalendar)
+				r.Get("/calendar/hebrew-date", h.GetHebrewDate)
+				r.Get("/calendar/gregorian-date", h.GetGregorianDate)
+				r.Get("/calendar/shabbat", h.GetShabbatTimes)
+			})
 
 			// Public algorithm browsing (Story 4-12)
 			r.Get("/algorithms/public", h.BrowsePublicAlgorithms)
@@ -298,6 +343,8 @@ func main() {
 			r.Post("/algorithm/preview", h.PreviewAlgorithm)
 			r.Get("/algorithm/templates", h.GetAlgorithmTemplates)
 			r.Get("/algorithm/methods", h.GetZmanMethods)
+			r.Get("/solar-engine", h.GetPublisherSolarEngine)
+			r.Put("/solar-engine", h.UpdatePublisherSolarEngine)
 			// Publisher zmanim management (Story 4-4)
 			r.Get("/zmanim", h.GetPublisherZmanim)
 			r.Get("/zmanim/week", h.GetPublisherZmanimWeek)      // Batch week preview with caching
@@ -315,6 +362,9 @@ func main() {
 			r.Get("/zmanim/{zmanKey}/history", h.GetZmanVersionHistory)
 			r.Get("/zmanim/{zmanKey}/history/{version}", h.GetZmanVersionDetail)
 			r.Post("/zmanim/{zmanKey}/rollback", h.RollbackZmanVersion)
+			// Scheduled (effective-dated) formulas
+			r.Get("/zmanim/{zmanKey}/schedule", h.GetZmanSchedule)
+			r.Delete("/zmanim/{zmanKey}/schedule/{version}", h.CancelZmanSchedule)
 			// Zman aliases (Story 5.4)
 			r.Get("/zmanim/aliases", h.ListAliases)
 			r.Get("/zmanim/{zmanKey}/alias", h.GetAlias)
@@ -332,11 +382,25 @@ func main() {
 			r.Get("/algorithm/versions", h.GetAlgorithmVersions)
 			r.Get("/algorithm/versions/{id}", h.GetAlgorithmVersion)
 			r.Put("/algorithm/versions/{id}/deprecate", h.DeprecateAlgorithmVersion)
+			// Conversational formula builder
+			r.Get("/ai/sessions", h.ListFormulaSessions)
+			r.Post("/ai/sessions", h.CreateFormulaSession)
+			r.Get("/ai/sessions/{id}", h.GetFormulaSession)
+			r.Delete("/ai/sessions/{id}", h.CloseFormulaSession)
+			r.Post("/ai/sessions/{id}/messages", h.SendFormulaSessionMessage)
+			r.Post("/ai/sessions/{id}/apply", h.ApplyFormulaSession)
 			// Coverage management
 			r.Get("/coverage", h.GetPublisherCoverage)
 			r.Post("/coverage", h.CreatePublisherCoverage)
 			r.Put("/coverage/{id}", h.UpdatePublisherCoverage)
 			r.Delete("/coverage/{id}", h.DeletePublisherCoverage)
+			r.Get("/coverage/analysis", h.GetPublisherCoverageAnalysis)
+			// Named locations with exact coordinates
+			r.Get("/locations", h.ListPublisherLocations)
+			r.Post("/locations", h.CreatePublisherLocation)
+			r.Get("/locations/{id}", h.GetPublisherLocation)
+			r.Put("/locations/{id}", h.UpdatePublisherLocation)
+			r.Delete("/locations/{id}", h.DeletePublisherLocation)
 			// Cache management
 			r.Delete("/cache", h.InvalidatePublisherCache)
 			// Team management (Story 2-10)
@@ -366,6 +430,22 @@ func main() {
 			r.Get("/snapshot/{id}", h.GetPublisherSnapshot)
 			r.Post("/snapshot/{id}/restore", h.RestorePublisherSnapshot)
 			r.Delete("/snapshot/{id}", h.DeletePublisherSnapshot)
+
+			// API keys for third-party consumers
+			r.Get("/api-keys", h.ListPublisherAPIKeys)
+			r.Post("/api-keys", h.CreatePublisherAPIKey)
+			r.Post("/api-keys/{id}/rotate", h.RotatePublisherAPIKey)
+			r.Get("/api-keys/{id}/usage", h.GetPublisherAPIKeyUsage)
+			r.Delete("/api-keys/{id}", h.RevokePublisherAPIKey)
+
+			// Webhooks
+			r.Get("/webhooks", h.ListPublisherWebhooks)
+			r.Post("/webhooks", h.CreatePublisherWebhook)
+			r.Put("/webhooks/{id}", h.UpdatePublisherWebhook)
+			r.Delete("/webhooks/{id}", h.DeletePublisherWebhook)
+			r.Get("/webhooks/{id}/deliveries", h.GetPublisherWebhookDeliveries)
+			r.Post("/webhooks/{id}/deliveries/{deliveryId}/redeliver", h.RedeliverPublisherWebhook)
+			r.Post("/webhooks/{id}/test", h.TestPublisherWebhook)
 		})
 
 		// User routes (authenticated)
@@ -409,12 +489,26 @@ func main() {
 
 			// Cache management
 			r.Delete("/cache/zmanim", h.AdminFlushZmanimCache)
+			r.Delete("/cache/tiles", h.AdminFlushTileCache)
+
+			// Coverage overlap and gap analysis
+			r.Get("/coverage/analysis", h.AdminGetCoverageAnalysis)
+
+			// Geographic data-quality audit
+			r.Get("/geo/audit", h.AdminGetGeoAudit)
+			r.Post("/geo/audit/fix", h.AdminApplyGeoAuditFixes)
 
 			// AI management (Story 4-7, 4-8)
 			r.Get("/ai/stats", h.GetAIIndexStats)
 			r.Post("/ai/reindex", h.TriggerReindex)
 			r.Get("/ai/audit", h.GetAIAuditLogs)
 
+			// API keys (platform keys and keys issued on behalf of publishers)
+			r.Get("/api-keys", h.AdminListAPIKeys)
+			r.Post("/api-keys", h.AdminCreateAPIKey)
+			r.Delete("/api-keys/{id}", h.AdminRevokeAPIKey)
+			r.Get("/api-keys/{id}/usage", h.AdminGetAPIKeyUsage)
+
 			// Zman registry request management (Story 5.8, 5.19)
 			r.Get("/zman-requests", h.AdminGetZmanRegistryRequests)
 			r.Get("/zman-requests/{id}", h.AdminGetZmanRegistryRequestByID)
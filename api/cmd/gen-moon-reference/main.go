@@ -0,0 +1,168 @@
+// Moon reference generator - prints the moonrise and moonset table that
+// internal/astro's TestMoonTimesReference checks MoonTimes against
+//
+// The moon's position comes from Montenbruck & Pfleger's MiniMoon
+// (Astronomy on the Personal Computer, 4th ed., section 3.2) with the
+// horizontal parallax from the leading terms of Meeus's series (Astronomical
+// Algorithms, chapter 47), a lunar theory independent of internal/astro.
+// Events use USNO's definition: the upper limb on the horizon with 34' of
+// refraction, at sea level. Times are rounded to the minute.
+//
+// Usage:
+//
+//	cd api && go run ./cmd/gen-moon-reference
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Terrestrial time runs ahead of UT by about 69 s over 2024-2025
+const deltaTSeconds = 69.0
+
+var places = []struct {
+	name     string
+	lat, lng float64
+	tz       string
+}{
+	{"Jerusalem", 31.7683, 35.2137, "Asia/Jerusalem"},
+	{"New York", 40.7128, -74.0060, "America/New_York"},
+}
+
+var dates = []string{"2024-01-18", "2024-04-23", "2024-07-10", "2024-10-17", "2025-03-03", "2025-12-04"}
+
+func main() {
+	for _, p := range places {
+		tz, err := time.LoadLocation(p.tz)
+		if err != nil {
+			log.Fatalf("%s: %v", p.name, err)
+		}
+		for _, d := range dates {
+			date, err := time.ParseInLocation("2006-01-02", d, tz)
+			if err != nil {
+				log.Fatal(err)
+			}
+			rise, set := moonRiseSet(date, p.lat, p.lng)
+			fmt.Printf("{%q, %.4f, %.4f, %s, %q, %q, %q},\n", p.name, p.lat, p.lng, tzVar(p.name), d, hhmm(rise), hhmm(set))
+		}
+	}
+}
+
+// tzVar is the name of the test's variable for a place's timezone
+func tzVar(place string) string {
+	switch place {
+	case "New York":
+		return "newYork"
+	default:
+		return "jerusalem"
+	}
+}
+
+// hhmm formats a time to the minute, or "" when the event does not occur
+func hhmm(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Round(time.Minute).Format("15:04")
+}
+
+func frac(x float64) float64 { return x - math.Floor(x) }
+
+// miniMoon returns the moon's geocentric right ascension and declination in
+// radians and its horizontal parallax in degrees
+func miniMoon(t time.Time) (ra, dec, parallax float64) {
+	jd := float64(t.Unix())/86400 + 2440587.5 + deltaTSeconds/86400
+	T := (jd - 2451545) / 36525
+	const arcs = 3600 * 180 / math.Pi
+	pi2 := 2 * math.Pi
+
+	// Mean elements of the lunar orbit
+	L0 := frac(0.606433 + 1336.855225*T)
+	l := pi2 * frac(0.374897+1325.552410*T)
+	ls := pi2 * frac(0.993133+99.997361*T)
+	D := pi2 * frac(0.827361+1236.853086*T)
+	F := pi2 * frac(0.259086+1342.227825*T)
+
+	// Perturbations in longitude and latitude, arcseconds
+	dL := 22640*math.Sin(l) - 4586*math.Sin(l-2*D) + 2370*math.Sin(2*D) + 769*math.Sin(2*l) -
+		668*math.Sin(ls) - 412*math.Sin(2*F) - 212*math.Sin(2*l-2*D) - 206*math.Sin(l+ls-2*D) +
+		192*math.Sin(l+2*D) - 165*math.Sin(ls-2*D) - 125*math.Sin(D) - 110*math.Sin(l+ls) +
+		148*math.Sin(l-ls) - 55*math.Sin(2*F-2*D)
+	S := F + (dL+412*math.Sin(2*F)+541*math.Sin(ls))/arcs
+	h := F - 2*D
+	N := -526*math.Sin(h) + 44*math.Sin(l+h) - 31*math.Sin(-l+h) - 23*math.Sin(ls+h) +
+		11*math.Sin(-ls+h) - 25*math.Sin(-2*l+F) + 21*math.Sin(-l+F)
+
+	lm := pi2 * frac(L0+dL/1296e3)
+	bm := (18520.0*math.Sin(S) + N) / arcs
+
+	// Ecliptic to equatorial
+	eps := (23.43929111 - 0.0130042*T) * math.Pi / 180
+	x := math.Cos(bm) * math.Cos(lm)
+	y := math.Cos(eps)*math.Cos(bm)*math.Sin(lm) - math.Sin(eps)*math.Sin(bm)
+	z := math.Sin(eps)*math.Cos(bm)*math.Sin(lm) + math.Cos(eps)*math.Sin(bm)
+	ra = math.Atan2(y, x)
+	dec = math.Atan2(z, math.Hypot(x, y))
+
+	parallax = (3422.7 + 186.5398*math.Cos(l) + 34.3117*math.Cos(2*D-l) + 28.2333*math.Cos(2*D) + 10.1657*math.Cos(2*l)) / 3600
+	return ra, dec, parallax
+}
+
+// gmst is the Greenwich mean sidereal time in radians
+func gmst(t time.Time) float64 {
+	jd := float64(t.Unix())/86400 + 2440587.5
+	T := (jd - 2451545) / 36525
+	g := 280.46061837 + 360.98564736629*(jd-2451545) + 0.000387933*T*T
+	return math.Mod(g, 360) * math.Pi / 180
+}
+
+// aboveHorizon is the moon's geocentric altitude above the altitude at which
+// its upper limb touches the horizon, in degrees
+func aboveHorizon(t time.Time, lat, lng float64) float64 {
+	ra, dec, parallax := miniMoon(t)
+	H := gmst(t) + lng*math.Pi/180 - ra
+	phi := lat * math.Pi / 180
+	alt := math.Asin(math.Sin(phi)*math.Sin(dec)+math.Cos(phi)*math.Cos(dec)*math.Cos(H)) * 180 / math.Pi
+	h0 := 0.7275*parallax - 34.0/60
+	return alt - h0
+}
+
+// moonRiseSet scans the local day in five-minute steps and bisects each
+// crossing; a zero time means the event does not occur that day
+func moonRiseSet(date time.Time, lat, lng float64) (rise, set time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+	f := func(t time.Time) float64 { return aboveHorizon(t, lat, lng) }
+	bisect := func(a, b time.Time) time.Time {
+		fa := f(a)
+		for b.Sub(a) > 100*time.Millisecond {
+			m := a.Add(b.Sub(a) / 2)
+			if (f(m) >= 0) == (fa >= 0) {
+				a = m
+			} else {
+				b = m
+			}
+		}
+		return a.Round(time.Second)
+	}
+
+	prev, prevAlt := start, f(start)
+	for prev.Before(end) {
+		t := prev.Add(5 * time.Minute)
+		if t.After(end) {
+			t = end
+		}
+		alt := f(t)
+		if prevAlt < 0 && alt >= 0 && rise.IsZero() {
+			rise = bisect(prev, t)
+		}
+		if prevAlt >= 0 && alt < 0 && set.IsZero() {
+			set = bisect(prev, t)
+		}
+		prev, prevAlt = t, alt
+	}
+	return rise.In(date.Location()), set.In(date.Location())
+}
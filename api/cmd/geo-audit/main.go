@@ -0,0 +1,157 @@
+// Geographic data-quality audit - reports cities that would produce wrong zmanim
+//
+// Checks geo_cities and the boundary tables for missing or zero elevation,
+// missing timezones, timezones not in effect in the city's country, cities
+// outside their region or district boundary, and near-duplicate cities. Each
+// category lists its worst cases with a suggested fix.
+//
+// Usage:
+//
+//	cd api && go run ./cmd/geo-audit [flags]
+//
+// Flags:
+//
+//	-country IL          audit one country (default: every country)
+//	-checks a,b          only run these categories (default: all)
+//	-limit 50            issues listed per category
+//	-duplicate-meters    same-name cities closer than this are duplicates (default 500)
+//	-boundary-km         tolerance outside region/district boundaries (default 10)
+//	-json                print the report as JSON
+//	-fix                 apply the safe fixes (missing elevation from SRTM,
+//	                     missing timezone from the timezone boundaries), then re-audit
+//	-max-elevations N    stop after looking up N elevations (default: no limit)
+//	-srtm-dir data/srtm  SRTM tile cache, shared with import-wof
+//
+// Environment variables:
+//
+//	DATABASE_URL - PostgreSQL connection string
+//
+// The same audit is available to admins at GET /api/admin/geo/audit.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jcom-dev/zmanim-lab/internal/geoaudit"
+	"github.com/jcom-dev/zmanim-lab/internal/terrain"
+)
+
+func main() {
+	country := flag.String("country", "", "ISO 3166-1 alpha-2 country to audit (default: all)")
+	checkList := flag.String("checks", "", "comma-separated categories to run (default: all)")
+	limit := flag.Int("limit", geoaudit.DefaultLimit, "issues listed per category")
+	duplicateMeters := flag.Float64("duplicate-meters", geoaudit.DefaultDuplicateMeters, "same-name cities closer than this are duplicates")
+	boundaryKm := flag.Float64("boundary-km", geoaudit.DefaultBoundaryToleranceKm, "tolerance outside region/district boundaries in km")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	fix := flag.Bool("fix", false, "apply safe fixes, then re-audit")
+	maxElevations := flag.Int("max-elevations", 0, "stop after looking up this many elevations (0: no limit)")
+	srtmDir := flag.String("srtm-dir", "data/srtm", "SRTM tile cache directory")
+	flag.Parse()
+
+	categories, err := geoaudit.ParseCategories(*checkList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL is required")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer pool.Close()
+
+	auditor := &geoaudit.Auditor{Pool: pool}
+	if *country != "" {
+		if err := auditor.ValidateCountry(ctx, *country); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *fix {
+		if err := os.MkdirAll(*srtmDir, 0755); err != nil {
+			log.Fatalf("Failed to create SRTM cache dir: %v", err)
+		}
+		srtm, err := terrain.NewSRTMSource(http.DefaultClient, *srtmDir)
+		if err != nil {
+			log.Fatalf("Failed to initialize SRTM: %v", err)
+		}
+		auditor.Elevations = srtm
+
+		log.Println("Applying safe fixes...")
+		result, err := auditor.ApplySafeFixes(ctx, geoaudit.FixOptions{CountryCode: *country, MaxElevations: *maxElevations})
+		if err != nil {
+			log.Fatalf("Fixes failed: %v", err)
+		}
+		log.Printf("  Timezones set: %d", result.TimezonesSet)
+		log.Printf("  Elevations set: %d of %d checked (%d without SRTM data)",
+			result.ElevationsSet, result.ElevationsChecked, result.ElevationsUnavailable)
+	}
+
+	report, err := auditor.Run(ctx, geoaudit.Options{
+		CountryCode:         *country,
+		Categories:          categories,
+		Limit:               *limit,
+		DuplicateMeters:     *duplicateMeters,
+		BoundaryToleranceKm: *boundaryKm,
+	})
+	if err != nil {
+		log.Fatalf("Audit failed: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	printReport(report)
+}
+
+func printReport(report *geoaudit.Report) {
+	scope := "all countries"
+	if report.CountryCode != "" {
+		scope = report.CountryCode
+	}
+	fmt.Printf("Geo audit · %s · %d issues · %dms\n", scope, report.Total, report.DurationMs)
+
+	for _, c := range report.Categories {
+		safe := ""
+		if c.SafeFix {
+			safe = " (safe: applied by -fix)"
+		}
+		fmt.Printf("\n== %s: %d ==\n%s\nFix: %s%s\n", c.Category, c.Count, c.Description, c.Fix, safe)
+		if c.Skipped != "" {
+			fmt.Printf("Skipped: %s\n", c.Skipped)
+			continue
+		}
+		if len(c.Issues) == 0 {
+			continue
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CITY\tNAME\tCC\tLAT,LNG\tDETAIL\tSUGGESTION")
+		for _, i := range c.Issues {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%.4f,%.4f\t%s\t%s\n",
+				i.CityID, i.CityName, i.CountryCode, i.Latitude, i.Longitude, i.Detail, i.Suggestion)
+		}
+		w.Flush()
+		if c.Count > len(c.Issues) {
+			fmt.Printf("... and %d more\n", c.Count-len(c.Issues))
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+// Checkpointed WOF imports.
+//
+// An import run works through the WOF placetypes in order, each in WOF ID
+// order, and records its progress in geo_boundary_imports: one row per
+// placetype, keyed by run_id. Every checkpointInterval records the row's
+// checkpoint_wof_id advances to the last committed record, so a run that
+// stops part way resumes after it (import --resume). An update run only
+// applies records whose WOF lastmodified is later than the newest one already
+// stored for the placetype.
+//
+// Rows are upserted by wof_id and never deleted and re-created, so city IDs
+// referenced by publisher_coverage survive every import.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	importModeFull   = "full"
+	importModeUpdate = "update"
+
+	// wofImportSource is geo_boundary_imports.source for WOF import runs
+	wofImportSource = "wof"
+
+	// checkpointInterval is how many records are imported between checkpoints
+	checkpointInterval = 1000
+)
+
+// wofLevel maps a WOF placetype to the geo table it is imported into
+type wofLevel struct {
+	placetype string
+	level     string // geo_boundary_imports.level
+	label     string
+	table     string
+}
+
+// wofLevels are imported in order; each level resolves its parents from the
+// ones before it
+var wofLevels = []wofLevel{
+	{placetype: "country", level: "country", label: "countries", table: "geo_countries"},
+	{placetype: "region", level: "region", label: "regions", table: "geo_regions"},
+	{placetype: "county", level: "district", label: "districts", table: "geo_districts"},
+	{placetype: "locality", level: "city", label: "cities", table: "geo_cities"},
+}
+
+// levelRun is one placetype's progress within an import run
+type levelRun struct {
+	runID      string
+	level      wofLevel
+	checkpoint int64 // last WOF ID committed
+	since      int64 // only records with a later lastmodified are applied
+	imported   int
+	skipped    int
+	notes      string
+	completed  bool
+}
+
+// startRun opens a new import run or, with resume, returns the newest run
+// that did not complete, in that run's mode
+func (imp *Importer) startRun(ctx context.Context) (string, error) {
+	if imp.resume {
+		var runID, runMode string
+		var unfinished bool
+		err := imp.pgPool.QueryRow(ctx, `
+			SELECT run_id::text, max(mode),
+			       bool_or(status <> 'completed') OR count(*) < $2
+			FROM geo_boundary_imports
+			WHERE source = $1 AND run_id IS NOT NULL
+			GROUP BY run_id
+			ORDER BY max(imported_at) DESC
+			LIMIT 1
+		`, wofImportSource, len(wofLevels)).Scan(&runID, &runMode, &unfinished)
+		if errors.Is(err, pgx.ErrNoRows) {
+			runID = ""
+		} else if err != nil {
+			return "", fmt.Errorf("find run to resume: %w", err)
+		}
+		if mode, ok := resumeMode(runID, unfinished, runMode, imp.mode); ok {
+			if mode != imp.mode {
+				log.Printf("  Resuming %s run (ignoring requested %s mode)", mode, imp.mode)
+			}
+			imp.mode = mode
+			log.Printf("  Resuming run %s", runID)
+			return runID, nil
+		}
+		log.Println("  No unfinished run to resume; starting a new one")
+	}
+
+	runID := uuid.New().String()
+	log.Printf("  Run %s (%s)", runID, imp.mode)
+	return runID, nil
+}
+
+// resumeMode decides whether startRun resumes the newest run (runID, empty if
+// there is none). Only a run that did not complete is resumed, and always in
+// the mode it was started with rather than the requested one.
+func resumeMode(runID string, unfinished bool, runMode, requested string) (string, bool) {
+	if runID == "" || !unfinished {
+		return requested, false
+	}
+	return runMode, true
+}
+
+// beginLevel returns the progress of a placetype within a run, recording the
+// placetype as running. A new update-mode level starts from the newest
+// lastmodified already imported; a resumed level keeps its original watermark.
+func (imp *Importer) beginLevel(ctx context.Context, runID string, lvl wofLevel) (*levelRun, error) {
+	lr := &levelRun{runID: runID, level: lvl}
+
+	var status string
+	err := imp.pgPool.QueryRow(ctx, `
+		SELECT status, COALESCE(checkpoint_wof_id, 0), COALESCE(since_lastmodified, -1),
+		       COALESCE(records_imported, 0), COALESCE(records_unmatched, 0)
+		FROM geo_boundary_imports
+		WHERE run_id = $1 AND level = $2
+	`, runID, lvl.level).Scan(&status, &lr.checkpoint, &lr.since, &lr.imported, &lr.skipped)
+	switch {
+	case err == nil:
+		lr.completed = status == "completed"
+		if !lr.completed {
+			if _, err := imp.pgPool.Exec(ctx, `
+				UPDATE geo_boundary_imports SET status = 'running', updated_at = now()
+				WHERE run_id = $1 AND level = $2
+			`, runID, lvl.level); err != nil {
+				return nil, fmt.Errorf("resume %s: %w", lvl.level, err)
+			}
+			log.Printf("  Resuming after WOF %d (%d imported so far)", lr.checkpoint, lr.imported)
+		}
+		return lr, nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("load %s progress: %w", lvl.level, err)
+	}
+
+	lr.since = -1
+	if imp.mode == importModeUpdate {
+		since, err := imp.updateWatermark(ctx, lvl)
+		if err != nil {
+			return nil, err
+		}
+		lr.since = since
+		log.Printf("  Applying records modified after %s", formatLastModified(since))
+	}
+
+	if _, err := imp.pgPool.Exec(ctx, `
+		INSERT INTO geo_boundary_imports
+			(source, level, version, run_id, mode, status, checkpoint_wof_id, since_lastmodified)
+		VALUES ($1, $2, $3, $4, $5, 'running', 0, $6)
+	`, wofImportSource, lvl.level, time.Now().Format("2006-01-02"), runID, imp.mode, lr.since); err != nil {
+		return nil, fmt.Errorf("record %s import: %w", lvl.level, err)
+	}
+	return lr, nil
+}
+
+// updateWatermark returns the newest WOF lastmodified stored for a level.
+// Only a completed import makes it safe to skip older records; with none on
+// record (rows imported before runs were tracked) every record is applied.
+func (imp *Importer) updateWatermark(ctx context.Context, lvl wofLevel) (int64, error) {
+	var lastStatus string
+	err := imp.pgPool.QueryRow(ctx, `
+		SELECT status FROM geo_boundary_imports
+		WHERE source = $1 AND level = $2 AND run_id IS NOT NULL
+		ORDER BY imported_at DESC
+		LIMIT 1
+	`, wofImportSource, lvl.level).Scan(&lastStatus)
+	if errors.Is(err, pgx.ErrNoRows) {
+		lastStatus = ""
+	} else if err != nil {
+		return 0, fmt.Errorf("load last %s import: %w", lvl.level, err)
+	}
+
+	newest := int64(-1)
+	if lastStatus == "completed" {
+		if err := imp.pgPool.QueryRow(ctx,
+			`SELECT COALESCE(max(wof_lastmodified), -1) FROM `+lvl.table).Scan(&newest); err != nil {
+			return 0, fmt.Errorf("load %s watermark: %w", lvl.level, err)
+		}
+	}
+	return updateSince(lvl, lastStatus, newest)
+}
+
+// updateSince picks an update run's watermark from the status of the level's
+// last tracked import ("" if there is none) and the newest lastmodified
+// stored: -1, applying everything, when no import is on record; an error when
+// the last import did not complete, since records before its checkpoint may
+// be missing.
+func updateSince(lvl wofLevel, lastStatus string, newest int64) (int64, error) {
+	switch lastStatus {
+	case "":
+		return -1, nil
+	case "completed":
+		return newest, nil
+	default:
+		return 0, fmt.Errorf("last %s import did not complete; run 'import-wof import --resume' first", lvl.level)
+	}
+}
+
+// saveCheckpoint records that every record up to wofID has been committed
+func (imp *Importer) saveCheckpoint(ctx context.Context, lr *levelRun, wofID int64) error {
+	if err := imp.flushGeoNames(ctx); err != nil {
+		return fmt.Errorf("flush %s names: %w", lr.level.level, err)
+	}
+	if _, err := imp.pgPool.Exec(ctx, `
+		UPDATE geo_boundary_imports
+		SET checkpoint_wof_id = $3, records_imported = $4, records_unmatched = $5, updated_at = now()
+		WHERE run_id = $1 AND level = $2
+	`, lr.runID, lr.level.level, wofID, lr.imported, lr.skipped); err != nil {
+		return fmt.Errorf("save %s checkpoint: %w", lr.level.level, err)
+	}
+	lr.checkpoint = wofID
+	return nil
+}
+
+// completeLevel marks a placetype done within its run
+func (imp *Importer) completeLevel(ctx context.Context, lr *levelRun) error {
+	if err := imp.flushGeoNames(ctx); err != nil {
+		return fmt.Errorf("flush %s names: %w", lr.level.level, err)
+	}
+	if _, err := imp.pgPool.Exec(ctx, `
+		UPDATE geo_boundary_imports
+		SET status = 'completed', records_imported = $3, records_unmatched = $4,
+		    notes = NULLIF($5, ''), updated_at = now(), completed_at = now()
+		WHERE run_id = $1 AND level = $2
+	`, lr.runID, lr.level.level, lr.imported, lr.skipped, lr.notes); err != nil {
+		return fmt.Errorf("complete %s import: %w", lr.level.level, err)
+	}
+	lr.completed = true
+	return nil
+}
+
+// failRun marks a run's running placetypes failed so it can be resumed
+func (imp *Importer) failRun(ctx context.Context, runID string) {
+	if _, err := imp.pgPool.Exec(ctx, `
+		UPDATE geo_boundary_imports SET status = 'failed', updated_at = now()
+		WHERE run_id = $1 AND status = 'running'
+	`, runID); err != nil {
+		log.Printf("  Warning: could not mark run %s failed: %v", runID, err)
+	}
+}
+
+// loadWOFMappings rebuilds the WOF ID → PostgreSQL ID map from rows already
+// imported, so parents imported by an earlier run still resolve
+func (imp *Importer) loadWOFMappings(ctx context.Context) error {
+	rows, err := imp.pgPool.Query(ctx, `
+		SELECT wof_id, id::bigint FROM geo_countries WHERE wof_id IS NOT NULL
+		UNION ALL
+		SELECT wof_id, id::bigint FROM geo_regions WHERE wof_id IS NOT NULL
+		UNION ALL
+		SELECT wof_id, id::bigint FROM geo_districts WHERE wof_id IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("load WOF mappings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wofID, pgID int64
+		if err := rows.Scan(&wofID, &pgID); err != nil {
+			return fmt.Errorf("scan WOF mapping: %w", err)
+		}
+		imp.wofToPG[wofID] = pgID
+	}
+	return rows.Err()
+}
+
+// retireCities removes cities whose WOF locality has been deprecated, ceased
+// or superseded since the level's watermark. Cities a publisher covers are
+// kept so their coverage keeps resolving.
+func (imp *Importer) retireCities(ctx context.Context, lr *levelRun) (retired, kept int, err error) {
+	rows, err := imp.wofDB.Query(`
+		SELECT s.id
+		FROM spr s
+		WHERE s.placetype = 'locality'
+		  AND (s.is_deprecated = 1 OR s.is_ceased = 1 OR s.is_superseded = 1)
+		  AND s.lastmodified > ?
+	`, lr.since)
+	if err != nil {
+		return 0, 0, fmt.Errorf("query retired localities: %w", err)
+	}
+	var wofIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("scan retired locality: %w", err)
+		}
+		wofIDs = append(wofIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	const chunkSize = 10000
+	for i := 0; i < len(wofIDs); i += chunkSize {
+		end := min(i+chunkSize, len(wofIDs))
+
+		// The count sees the rows as they were before the deletes
+		var deleted, matched int
+		if err := imp.pgPool.QueryRow(ctx, `
+			WITH retired AS (
+				DELETE FROM geo_cities c
+				WHERE c.wof_id = ANY($1)
+				  AND NOT EXISTS (SELECT 1 FROM publisher_coverage pc WHERE pc.city_id = c.id)
+				RETURNING c.id
+			), names AS (
+				DELETE FROM geo_names n USING retired r
+				WHERE n.entity_type = 'city' AND n.entity_id = r.id::text
+			)
+			SELECT (SELECT count(*) FROM retired), (SELECT count(*) FROM geo_cities WHERE wof_id = ANY($1))
+		`, wofIDs[i:end]).Scan(&deleted, &matched); err != nil {
+			return retired, kept, fmt.Errorf("retire cities: %w", err)
+		}
+		retired += deleted
+		kept += matched - deleted
+	}
+	return retired, kept, nil
+}
+
+// parseWOFIDs parses a comma-separated list of WOF IDs, as stored in
+// spr.supersedes
+func parseWOFIDs(s string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// formatLastModified renders a WOF lastmodified watermark for logs
+func formatLastModified(ts int64) string {
+	if ts < 0 {
+		return "the beginning"
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
+
+// printImportRun prints the placetypes of the newest WOF import run
+func printImportRun(ctx context.Context, pool *pgxpool.Pool) {
+	rows, err := pool.Query(ctx, `
+		SELECT level, mode, status, COALESCE(records_imported, 0), COALESCE(checkpoint_wof_id, 0), updated_at
+		FROM geo_boundary_imports
+		WHERE run_id = (
+			SELECT run_id FROM geo_boundary_imports
+			WHERE source = $1 AND run_id IS NOT NULL
+			ORDER BY imported_at DESC
+			LIMIT 1
+		)
+		ORDER BY id
+	`, wofImportSource)
+	if err != nil {
+		fmt.Printf("Import runs unavailable: %v\n", err)
+		return
+	}
+	defer rows.Close()
+
+	fmt.Println("\n=== Last WOF Import ===")
+	var found bool
+	for rows.Next() {
+		var level, mode, status string
+		var imported int
+		var checkpoint int64
+		var updated time.Time
+		if err := rows.Scan(&level, &mode, &status, &imported, &checkpoint, &updated); err != nil {
+			fmt.Printf("Import runs unavailable: %v\n", err)
+			return
+		}
+		fmt.Printf("%-10s %-7s %-10s %8d imported, checkpoint WOF %d (%s)\n",
+			level, mode, status, imported, checkpoint, updated.Format(time.RFC3339))
+		found = true
+	}
+	if !found {
+		fmt.Println("No tracked runs")
+	}
+}
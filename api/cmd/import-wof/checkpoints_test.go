@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWOFIDs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []int64
+	}{
+		{"", nil},
+		{"85632773", []int64{85632773}},
+		{"85632773,101750367", []int64{85632773, 101750367}},
+		{" 85632773 , 101750367 ", []int64{85632773, 101750367}},
+		{"85632773,,abc,-1,0,101750367", []int64{85632773, 101750367}},
+	}
+
+	for _, tt := range tests {
+		if got := parseWOFIDs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWOFIDs(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLastModified(t *testing.T) {
+	tests := []struct {
+		ts   int64
+		want string
+	}{
+		{-1, "the beginning"},
+		{0, "1970-01-01T00:00:00Z"},
+		{1700000000, "2023-11-14T22:13:20Z"},
+	}
+
+	for _, tt := range tests {
+		if got := formatLastModified(tt.ts); got != tt.want {
+			t.Errorf("formatLastModified(%d) = %q, want %q", tt.ts, got, tt.want)
+		}
+	}
+}
+
+func TestResumeMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		runID      string
+		unfinished bool
+		runMode    string
+		wantMode   string
+		wantResume bool
+	}{
+		{"no runs", "", false, "", importModeFull, false},
+		{"newest run completed", "run-1", false, importModeUpdate, importModeFull, false},
+		{"unfinished run in the requested mode", "run-1", true, importModeFull, importModeFull, true},
+		{"unfinished run keeps its own mode", "run-1", true, importModeUpdate, importModeUpdate, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, resume := resumeMode(tt.runID, tt.unfinished, tt.runMode, importModeFull)
+			if mode != tt.wantMode || resume != tt.wantResume {
+				t.Errorf("resumeMode() = (%q, %v), want (%q, %v)", mode, resume, tt.wantMode, tt.wantResume)
+			}
+		})
+	}
+}
+
+func TestUpdateSince(t *testing.T) {
+	cities := wofLevels[3]
+	tests := []struct {
+		name       string
+		lastStatus string
+		newest     int64
+		want       int64
+		wantErr    bool
+	}{
+		{"no tracked import applies everything", "", -1, -1, false},
+		{"completed import", "completed", 1700000000, 1700000000, false},
+		{"completed import with no lastmodified stored", "completed", -1, -1, false},
+		{"failed import", "failed", 1700000000, 0, true},
+		{"interrupted import", "running", 1700000000, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := updateSince(cities, tt.lastStatus, tt.newest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("updateSince() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("updateSince() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
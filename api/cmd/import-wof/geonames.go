@@ -0,0 +1,821 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// GEONAMES
+// =============================================================================
+//
+// An offline alternative to the WOF download for environments that cannot use
+// it, and a supplement where WOF's locality coverage is thin. Reads the
+// GeoNames dumps from local files:
+//
+//	cities500.txt          cities (required)
+//	admin1CodesASCII.txt   regions (required)
+//	alternateNamesV2.txt   names in other languages (optional; alternateNames.txt also accepted)
+//	countryInfo.txt        countries missing from geo_countries (optional)
+//
+// Existing rows are reconciled rather than replaced: regions through
+// geo_name_mappings (source "geonames", source_name "CC.admin1"), cities
+// through geo_cities.geonameid, linking WOF cities of the same name within
+// 10 km on first import. WOF-owned rows are never overwritten. Re-running is
+// idempotent, and --dry-run performs the whole import in a transaction that
+// is rolled back, printing what would change.
+
+const (
+	defaultGeoNamesDir = "data/geonames"
+	geoNamesSource     = "geonames"
+	// geoNamesLinkRadiusM is how close a WOF city of the same name must be to
+	// be linked to a GeoNames city instead of duplicated
+	geoNamesLinkRadiusM = 10000
+)
+
+// geoNamesLanguages maps GeoNames isolanguage codes to supported ISO 639-3 codes
+var geoNamesLanguages = map[string]string{
+	"en": "eng", "he": "heb", "ar": "ara", "yi": "yid", "ru": "rus", "fr": "fra",
+	"de": "deu", "es": "spa", "pt": "por", "zh": "zho", "ja": "jpn", "ko": "kor",
+	"it": "ita", "nl": "nld", "pl": "pol", "hu": "hun", "uk": "ukr", "tr": "tur",
+	"fa": "fas", "hi": "hin",
+}
+
+// geoNamesContinents names the continent codes used by countryInfo.txt
+var geoNamesContinents = map[string]string{
+	"AF": "Africa", "AN": "Antarctica", "AS": "Asia", "EU": "Europe",
+	"NA": "North America", "OC": "Oceania", "SA": "South America",
+}
+
+// geoNamesSkippedFeatures are populated-place feature codes that are not
+// cities: historical, abandoned and destroyed places, and sections of cities
+var geoNamesSkippedFeatures = map[string]bool{
+	"PPLH": true, "PPLQ": true, "PPLW": true, "PPLX": true, "PPLCH": true,
+}
+
+type geoNamesCountry struct {
+	id          int32
+	continentID int16
+}
+
+// geoNamesImporter imports GeoNames dumps in a single transaction
+type geoNamesImporter struct {
+	tx            pgx.Tx
+	dir           string
+	countryFilter map[string]bool
+	minPopulation int64
+	verbose       bool
+
+	countries map[string]geoNamesCountry // ISO2 → country
+	regions   map[string]int32           // "CC.admin1" → geo_regions.id
+	// geonameid → entity for alternate names of countries and regions
+	entities map[int64]geoNamesEntity
+	// geonameid → country code of every imported city
+	cityCountries map[int64]string
+
+	report geoNamesReport
+}
+
+type geoNamesEntity struct {
+	entityType string
+	entityID   string
+}
+
+// geoNamesReport counts what an import changed (or, in a dry run, would)
+type geoNamesReport struct {
+	CountriesExisting int
+	CountriesCreated  int
+	MissingCountries  map[string]int // country code → cities skipped
+
+	RegionsMapped  int // through an existing geo_name_mappings row
+	RegionsMatched int // to an existing region by name
+	RegionsCreated int
+
+	CitiesRead         int
+	CitiesFiltered     int // filtered by country, population or feature code
+	CitiesUnchanged    int
+	CitiesUpdated      int // GeoNames-owned rows refreshed
+	CitiesWOFMatched   int // WOF rows already linked by geonameid
+	CitiesLinked       int // WOF rows newly linked by name and distance
+	CitiesDuplicates   int // skipped: another GeoNames city claimed the same WOF city
+	CitiesCreated      int
+	NamesWritten       int64
+	HistoricNamesAdded int64
+}
+
+// cmdGeoNames imports GeoNames dumps into the geo_* tables
+func cmdGeoNames(args []string) {
+	dir := defaultGeoNamesDir
+	imp := &geoNamesImporter{}
+	dryRun := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--dir" && i+1 < len(args):
+			dir = args[i+1]
+			i++
+		case args[i] == "--country" && i+1 < len(args):
+			imp.countryFilter = make(map[string]bool)
+			for _, cc := range strings.Split(args[i+1], ",") {
+				if cc = strings.ToUpper(strings.TrimSpace(cc)); cc != "" {
+					imp.countryFilter[cc] = true
+				}
+			}
+			i++
+		case args[i] == "--min-population" && i+1 < len(args):
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n < 0 {
+				log.Fatalf("Invalid --min-population: %s", args[i+1])
+			}
+			imp.minPopulation = n
+			i++
+		case args[i] == "--dry-run":
+			dryRun = true
+		case args[i] == "-v" || args[i] == "--verbose":
+			imp.verbose = true
+		}
+	}
+	imp.dir = dir
+
+	for _, name := range []string{"cities500.txt", "admin1CodesASCII.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			log.Fatalf("GeoNames file not found: %s\nDownload it from https://download.geonames.org/export/dump/", filepath.Join(dir, name))
+		}
+	}
+
+	pgURL := os.Getenv("DATABASE_URL")
+	if pgURL == "" {
+		log.Fatal("DATABASE_URL required")
+	}
+
+	ctx := context.Background()
+	pgPool, err := pgxpool.New(ctx, pgURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer pgPool.Close()
+
+	start := time.Now()
+	if err := imp.Run(ctx, pgPool, dryRun); err != nil {
+		log.Fatalf("GeoNames import failed: %v", err)
+	}
+
+	imp.report.print(os.Stdout, dryRun)
+	log.Printf("GeoNames import complete in %s", time.Since(start).Round(time.Second))
+}
+
+// Run performs the import in one transaction, committing it unless dryRun
+func (imp *geoNamesImporter) Run(ctx context.Context, pool *pgxpool.Pool, dryRun bool) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	imp.tx = tx
+	imp.entities = make(map[int64]geoNamesEntity)
+	imp.cityCountries = make(map[int64]string)
+	imp.report.MissingCountries = make(map[string]int)
+
+	log.Println("Step 1: Reconciling countries...")
+	if err := imp.loadCountries(ctx); err != nil {
+		return fmt.Errorf("countries: %w", err)
+	}
+
+	log.Println("Step 2: Reconciling regions (admin1)...")
+	if err := imp.importRegions(ctx); err != nil {
+		return fmt.Errorf("regions: %w", err)
+	}
+
+	log.Println("Step 3: Reconciling cities (cities500)...")
+	if err := imp.importCities(ctx); err != nil {
+		return fmt.Errorf("cities: %w", err)
+	}
+
+	log.Println("Step 4: Importing alternate names...")
+	if err := imp.importAlternateNames(ctx); err != nil {
+		return fmt.Errorf("alternate names: %w", err)
+	}
+
+	if dryRun {
+		log.Println("Dry run: rolling back")
+		return nil
+	}
+
+	r := imp.report
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO geo_boundary_imports (source, level, version, records_imported, records_matched, records_unmatched, notes)
+		VALUES ($1, 'city', $2, $3, $4, $5, $6)
+	`, geoNamesSource, time.Now().Format("2006-01-02"), r.CitiesCreated,
+		r.CitiesUnchanged+r.CitiesUpdated+r.CitiesWOFMatched+r.CitiesLinked,
+		r.CitiesDuplicates+r.missingCities(),
+		fmt.Sprintf("cities500; %d regions created, %d names", r.RegionsCreated, r.NamesWritten)); err != nil {
+		return fmt.Errorf("record import: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// included reports whether a country passes the --country filter
+func (imp *geoNamesImporter) included(countryCode string) bool {
+	return imp.countryFilter == nil || imp.countryFilter[countryCode]
+}
+
+// loadCountries loads existing countries by ISO code and, when countryInfo.txt
+// is present, creates the ones missing from geo_countries
+func (imp *geoNamesImporter) loadCountries(ctx context.Context) error {
+	imp.countries = make(map[string]geoNamesCountry)
+	rows, err := imp.tx.Query(ctx, `SELECT code, id, continent_id FROM geo_countries`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var code string
+		var c geoNamesCountry
+		if err := rows.Scan(&code, &c.id, &c.continentID); err != nil {
+			rows.Close()
+			return err
+		}
+		imp.countries[code] = c
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	imp.report.CountriesExisting = len(imp.countries)
+
+	path := filepath.Join(imp.dir, "countryInfo.txt")
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	return readGeoNamesFile(path, func(fields []string) error {
+		// ISO, ISO3, ISO-Numeric, fips, Country, Capital, Area, Population, Continent, ... geonameid (16)
+		if len(fields) < 17 {
+			return nil
+		}
+		code, continent := fields[0], fields[8]
+		if !imp.included(code) {
+			return nil
+		}
+		geonameID, _ := strconv.ParseInt(fields[16], 10, 64)
+
+		if c, ok := imp.countries[code]; ok {
+			if geonameID > 0 {
+				imp.entities[geonameID] = geoNamesEntity{"country", strconv.Itoa(int(c.id))}
+			}
+			return nil
+		}
+		continentName, ok := geoNamesContinents[continent]
+		if !ok {
+			return nil
+		}
+
+		var continentID int16
+		if err := imp.tx.QueryRow(ctx, `
+			INSERT INTO geo_continents (code, name) VALUES ($1, $2)
+			ON CONFLICT (code) DO UPDATE SET code = EXCLUDED.code
+			RETURNING id
+		`, continent, continentName).Scan(&continentID); err != nil {
+			return fmt.Errorf("continent %s: %w", continent, err)
+		}
+
+		c := geoNamesCountry{continentID: continentID}
+		if err := imp.tx.QueryRow(ctx, `
+			INSERT INTO geo_countries (code, code_iso3, name, continent_id, is_city_state)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, code, nullString(fields[1]), fields[4], continentID, cityStates[code]).Scan(&c.id); err != nil {
+			return fmt.Errorf("country %s: %w", code, err)
+		}
+		imp.countries[code] = c
+		if geonameID > 0 {
+			imp.entities[geonameID] = geoNamesEntity{"country", strconv.Itoa(int(c.id))}
+		}
+		imp.report.CountriesCreated++
+		if imp.verbose {
+			log.Printf("  Created country %s (%s)", fields[4], code)
+		}
+		return nil
+	})
+}
+
+// importRegions reconciles admin1 codes with geo_regions: an existing
+// mapping, else a region of the same name in the country, else a new region.
+// The result is recorded in geo_name_mappings so later runs are stable.
+func (imp *geoNamesImporter) importRegions(ctx context.Context) error {
+	imp.regions = make(map[string]int32)
+	return readGeoNamesFile(filepath.Join(imp.dir, "admin1CodesASCII.txt"), func(fields []string) error {
+		// code (CC.admin1), name, asciiname, geonameid
+		if len(fields) < 4 {
+			return nil
+		}
+		key, name, asciiName := fields[0], fields[1], fields[2]
+		countryCode, _, ok := strings.Cut(key, ".")
+		if !ok || !imp.included(countryCode) {
+			return nil
+		}
+		country, ok := imp.countries[countryCode]
+		if !ok {
+			return nil
+		}
+		geonameID, _ := strconv.ParseInt(fields[3], 10, 64)
+
+		var regionID int32
+		err := imp.tx.QueryRow(ctx, `
+			SELECT m.target_id FROM geo_name_mappings m
+			JOIN geo_regions r ON r.id = m.target_id
+			WHERE m.level = 'region' AND m.source = $1 AND m.source_name = $2 AND m.source_country_code = $3
+		`, geoNamesSource, key, countryCode).Scan(&regionID)
+		switch {
+		case err == nil:
+			imp.report.RegionsMapped++
+		case errors.Is(err, pgx.ErrNoRows):
+			notes := "matched by name"
+			err = imp.tx.QueryRow(ctx, `
+				SELECT id FROM geo_regions
+				WHERE country_id = $1 AND (lower(name) = lower($2) OR lower(name) = lower($3))
+				ORDER BY wof_id NULLS LAST, id
+				LIMIT 1
+			`, country.id, name, asciiName).Scan(&regionID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				notes = "created"
+				err = imp.tx.QueryRow(ctx, `
+					INSERT INTO geo_regions (continent_id, country_id, code, name)
+					VALUES ($1, $2, $3, $4)
+					ON CONFLICT (country_id, code) DO UPDATE SET name = EXCLUDED.name
+					RETURNING id
+				`, country.continentID, country.id, fmt.Sprintf("gn-%d", geonameID), name).Scan(&regionID)
+				imp.report.RegionsCreated++
+			} else {
+				imp.report.RegionsMatched++
+			}
+			if err != nil {
+				return fmt.Errorf("region %s: %w", key, err)
+			}
+			if _, err := imp.tx.Exec(ctx, `
+				INSERT INTO geo_name_mappings (level, source, source_name, source_country_code, target_id, notes)
+				VALUES ('region', $1, $2, $3, $4, $5)
+				ON CONFLICT (level, source, source_name, source_country_code) DO UPDATE SET
+					target_id = EXCLUDED.target_id, notes = EXCLUDED.notes
+			`, geoNamesSource, key, countryCode, regionID, notes); err != nil {
+				return fmt.Errorf("region mapping %s: %w", key, err)
+			}
+			if imp.verbose {
+				log.Printf("  Region %s (%s) → %d, %s", name, key, regionID, notes)
+			}
+		default:
+			return fmt.Errorf("region %s: %w", key, err)
+		}
+
+		imp.regions[key] = regionID
+		if geonameID > 0 {
+			imp.entities[geonameID] = geoNamesEntity{"region", strconv.Itoa(int(regionID))}
+		}
+		return nil
+	})
+}
+
+// importCities stages cities500 and reconciles it with geo_cities in bulk
+func (imp *geoNamesImporter) importCities(ctx context.Context) error {
+	if _, err := imp.tx.Exec(ctx, `
+		CREATE TEMP TABLE geonames_cities (
+			geonameid integer PRIMARY KEY,
+			name text NOT NULL,
+			name_ascii text,
+			latitude double precision NOT NULL,
+			longitude double precision NOT NULL,
+			continent_id smallint NOT NULL,
+			country_id integer NOT NULL,
+			region_id integer,
+			timezone text NOT NULL,
+			population integer,
+			elevation_m integer
+		) ON COMMIT DROP
+	`); err != nil {
+		return err
+	}
+
+	var rows [][]interface{}
+	err := readGeoNamesFile(filepath.Join(imp.dir, "cities500.txt"), func(fields []string) error {
+		if c := imp.parseCity(fields); c != nil {
+			rows = append(rows, c.values())
+			imp.cityCountries[int64(c.geonameID)] = c.countryCode
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := imp.tx.CopyFrom(ctx, pgx.Identifier{"geonames_cities"},
+		[]string{"geonameid", "name", "name_ascii", "latitude", "longitude", "continent_id", "country_id",
+			"region_id", "timezone", "population", "elevation_m"},
+		pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("stage cities: %w", err)
+	}
+	log.Printf("  %d cities staged", len(rows))
+
+	// GeoNames-owned cities are refreshed from the dump. A city the dump has
+	// no elevation for keeps the one it has (perhaps filled from SRTM), or 0 as
+	// on insert, so an unchanged dump updates nothing on the next run.
+	tag, err := imp.tx.Exec(ctx, `
+		UPDATE geo_cities c SET
+			name = s.name, name_ascii = s.name_ascii, latitude = s.latitude, longitude = s.longitude,
+			continent_id = s.continent_id, country_id = s.country_id, region_id = s.region_id,
+			timezone = s.timezone, population = s.population,
+			elevation_m = COALESCE(s.elevation_m, c.elevation_m, 0), updated_at = now()
+		FROM geonames_cities s
+		WHERE c.geonameid = s.geonameid AND c.wof_id IS NULL
+		  AND (c.name, c.name_ascii, c.latitude, c.longitude, c.country_id, c.region_id, c.timezone, c.population, c.elevation_m)
+		      IS DISTINCT FROM
+		      (s.name, s.name_ascii, s.latitude, s.longitude, s.country_id, s.region_id, s.timezone, s.population,
+		       COALESCE(s.elevation_m, c.elevation_m, 0))
+	`)
+	if err != nil {
+		return fmt.Errorf("update cities: %w", err)
+	}
+	imp.report.CitiesUpdated = int(tag.RowsAffected())
+
+	var unchanged, wofMatched int
+	if err := imp.tx.QueryRow(ctx, `
+		SELECT count(*) FILTER (WHERE c.wof_id IS NULL), count(*) FILTER (WHERE c.wof_id IS NOT NULL)
+		FROM geo_cities c JOIN geonames_cities s ON s.geonameid = c.geonameid
+	`).Scan(&unchanged, &wofMatched); err != nil {
+		return err
+	}
+	imp.report.CitiesUnchanged = unchanged - imp.report.CitiesUpdated
+	imp.report.CitiesWOFMatched = wofMatched
+
+	// WOF-owned cities only gain a population they lack
+	if _, err := imp.tx.Exec(ctx, `
+		UPDATE geo_cities c SET population = s.population, updated_at = now()
+		FROM geonames_cities s
+		WHERE c.geonameid = s.geonameid AND c.wof_id IS NOT NULL AND c.population IS NULL AND s.population IS NOT NULL
+	`); err != nil {
+		return fmt.Errorf("update WOF populations: %w", err)
+	}
+
+	// Unlinked GeoNames cities look for a WOF city of the same name nearby;
+	// each WOF city is linked to at most one of them
+	if _, err := imp.tx.Exec(ctx, `
+		CREATE TEMP TABLE geonames_city_links ON COMMIT DROP AS
+		SELECT s.geonameid, (
+			SELECT c.id FROM geo_cities c
+			WHERE c.geonameid IS NULL AND c.country_id = s.country_id
+			  AND (lower(c.name) = lower(s.name) OR lower(c.name_ascii) = lower(s.name_ascii))
+			  AND ST_DWithin(c.location, ST_SetSRID(ST_MakePoint(s.longitude, s.latitude), 4326)::geography, $1)
+			ORDER BY c.location <-> ST_SetSRID(ST_MakePoint(s.longitude, s.latitude), 4326)::geography
+			LIMIT 1
+		) AS city_id
+		FROM geonames_cities s
+		WHERE NOT EXISTS (SELECT 1 FROM geo_cities c WHERE c.geonameid = s.geonameid)
+	`, geoNamesLinkRadiusM); err != nil {
+		return fmt.Errorf("match cities: %w", err)
+	}
+	tag, err = imp.tx.Exec(ctx, `
+		UPDATE geo_cities c SET geonameid = l.geonameid, population = COALESCE(c.population, s.population), updated_at = now()
+		FROM (
+			SELECT DISTINCT ON (city_id) city_id, geonameid FROM geonames_city_links
+			WHERE city_id IS NOT NULL
+			ORDER BY city_id, geonameid
+		) l
+		JOIN geonames_cities s ON s.geonameid = l.geonameid
+		WHERE c.id = l.city_id
+	`)
+	if err != nil {
+		return fmt.Errorf("link cities: %w", err)
+	}
+	imp.report.CitiesLinked = int(tag.RowsAffected())
+	if err := imp.tx.QueryRow(ctx, `SELECT count(*) FROM geonames_city_links WHERE city_id IS NOT NULL`).Scan(&imp.report.CitiesDuplicates); err != nil {
+		return err
+	}
+	imp.report.CitiesDuplicates -= imp.report.CitiesLinked
+
+	tag, err = imp.tx.Exec(ctx, `
+		INSERT INTO geo_cities (continent_id, country_id, region_id, name, name_ascii,
+		                        latitude, longitude, timezone, elevation_m, population, geonameid)
+		SELECT s.continent_id, s.country_id, s.region_id, s.name, s.name_ascii,
+		       s.latitude, s.longitude, s.timezone, COALESCE(s.elevation_m, 0), s.population, s.geonameid
+		FROM geonames_cities s
+		JOIN geonames_city_links l ON l.geonameid = s.geonameid
+		WHERE l.city_id IS NULL
+		ON CONFLICT (geonameid) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("insert cities: %w", err)
+	}
+	imp.report.CitiesCreated = int(tag.RowsAffected())
+	return nil
+}
+
+// geoNamesCity is a cities500.txt record ready to be staged
+type geoNamesCity struct {
+	geonameID   int32
+	name        string
+	nameASCII   string
+	latitude    float64
+	longitude   float64
+	countryCode string
+	country     geoNamesCountry
+	regionID    *int32
+	timezone    string
+	population  int64
+	elevation   *int32
+}
+
+// parseCity parses a cities500.txt record and counts it in the report. It
+// returns nil for records that are short, malformed, filtered out, or in a
+// country missing from geo_countries.
+func (imp *geoNamesImporter) parseCity(fields []string) *geoNamesCity {
+	// geonameid, name, asciiname, alternatenames, latitude, longitude, feature class,
+	// feature code, country code, cc2, admin1..admin4, population, elevation, dem, timezone, modified
+	if len(fields) < 19 {
+		return nil
+	}
+	imp.report.CitiesRead++
+	countryCode := fields[8]
+	population, _ := strconv.ParseInt(fields[14], 10, 64)
+	if !imp.included(countryCode) || population < imp.minPopulation || geoNamesSkippedFeatures[fields[7]] || fields[17] == "" {
+		imp.report.CitiesFiltered++
+		return nil
+	}
+	country, ok := imp.countries[countryCode]
+	if !ok {
+		imp.report.MissingCountries[countryCode]++
+		return nil
+	}
+
+	geonameID, err := strconv.ParseInt(fields[0], 10, 32)
+	if err != nil {
+		return nil
+	}
+	lat, err1 := strconv.ParseFloat(fields[4], 64)
+	lng, err2 := strconv.ParseFloat(fields[5], 64)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+
+	c := &geoNamesCity{
+		geonameID: int32(geonameID), name: fields[1], nameASCII: fields[2], latitude: lat, longitude: lng,
+		countryCode: countryCode, country: country, timezone: fields[17], population: population,
+	}
+	if id, ok := imp.regions[countryCode+"."+fields[10]]; ok {
+		c.regionID = &id
+	}
+	// Surveyed elevation when known, else the digital elevation model (-9999 is no data)
+	if e, err := strconv.ParseInt(fields[15], 10, 32); err == nil {
+		elevation := int32(e)
+		c.elevation = &elevation
+	} else if e, err := strconv.ParseInt(fields[16], 10, 32); err == nil && e > -9999 {
+		elevation := int32(e)
+		c.elevation = &elevation
+	}
+	return c
+}
+
+// values is the city's geonames_cities row
+func (c *geoNamesCity) values() []interface{} {
+	var regionID, elevation interface{}
+	if c.regionID != nil {
+		regionID = *c.regionID
+	}
+	if c.elevation != nil {
+		elevation = *c.elevation
+	}
+	return []interface{}{
+		c.geonameID, c.name, nullString(c.nameASCII), c.latitude, c.longitude,
+		c.country.continentID, c.country.id, regionID, c.timezone, nullInt32(c.population), elevation,
+	}
+}
+
+// geoNamesAltName is the best alternate name seen for a place and language
+type geoNamesAltName struct {
+	name      string
+	preferred bool
+	short     bool
+}
+
+// importAlternateNames writes the preferred name per language of every
+// imported country, region and city to geo_names, and historic city names to
+// geo_name_variants. Names written by other sources are left alone.
+func (imp *geoNamesImporter) importAlternateNames(ctx context.Context) error {
+	path := filepath.Join(imp.dir, "alternateNamesV2.txt")
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(imp.dir, "alternateNames.txt")
+		if _, err := os.Stat(path); err != nil {
+			log.Println("  No alternateNamesV2.txt or alternateNames.txt, skipping")
+			return nil
+		}
+	}
+
+	best, historic, err := imp.readAlternateNames(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := imp.tx.Exec(ctx, `
+		CREATE TEMP TABLE geonames_names (
+			geonameid integer NOT NULL,
+			entity_type text,
+			entity_id text,
+			language_code varchar(3) NOT NULL,
+			name text NOT NULL
+		) ON COMMIT DROP;
+		CREATE TEMP TABLE geonames_historic_names (
+			geonameid integer NOT NULL,
+			name text NOT NULL
+		) ON COMMIT DROP
+	`); err != nil {
+		return err
+	}
+
+	keys := make([]geoNamesNameKey, 0, len(best))
+	for k := range best {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].geonameID != keys[j].geonameID {
+			return keys[i].geonameID < keys[j].geonameID
+		}
+		return keys[i].lang < keys[j].lang
+	})
+	names := make([][]interface{}, 0, len(keys))
+	for _, k := range keys {
+		var entityType, entityID interface{}
+		if e, ok := imp.entities[k.geonameID]; ok {
+			entityType, entityID = e.entityType, e.entityID
+		}
+		names = append(names, []interface{}{int32(k.geonameID), entityType, entityID, k.lang, best[k].name})
+	}
+
+	if _, err := imp.tx.CopyFrom(ctx, pgx.Identifier{"geonames_names"},
+		[]string{"geonameid", "entity_type", "entity_id", "language_code", "name"}, pgx.CopyFromRows(names)); err != nil {
+		return fmt.Errorf("stage names: %w", err)
+	}
+	if _, err := imp.tx.CopyFrom(ctx, pgx.Identifier{"geonames_historic_names"},
+		[]string{"geonameid", "name"}, pgx.CopyFromRows(historic)); err != nil {
+		return fmt.Errorf("stage historic names: %w", err)
+	}
+
+	tag, err := imp.tx.Exec(ctx, `
+		INSERT INTO geo_names (entity_type, entity_id, language_code, name, is_preferred, source)
+		SELECT DISTINCT ON (entity_type, entity_id, language_code) entity_type, entity_id, language_code, name, true, $1
+		FROM (
+			SELECT n.geonameid, COALESCE(n.entity_type, 'city') AS entity_type,
+			       COALESCE(n.entity_id, c.id::text) AS entity_id, n.language_code, n.name
+			FROM geonames_names n
+			LEFT JOIN geo_cities c ON c.geonameid = n.geonameid AND n.entity_id IS NULL
+			WHERE n.entity_id IS NOT NULL OR c.id IS NOT NULL
+		) named
+		ORDER BY entity_type, entity_id, language_code, geonameid
+		ON CONFLICT (entity_type, entity_id, language_code) DO UPDATE SET name = EXCLUDED.name
+		WHERE geo_names.source = EXCLUDED.source AND geo_names.name <> EXCLUDED.name
+	`, geoNamesSource)
+	if err != nil {
+		return fmt.Errorf("write names: %w", err)
+	}
+	imp.report.NamesWritten = tag.RowsAffected()
+
+	tag, err = imp.tx.Exec(ctx, `
+		INSERT INTO geo_name_variants (entity_type, country_code, canonical_name, variant, kind)
+		SELECT DISTINCT 'city', co.code, c.name, h.name, 'historic'
+		FROM geonames_historic_names h
+		JOIN geo_cities c ON c.geonameid = h.geonameid
+		JOIN geo_countries co ON co.id = c.country_id
+		WHERE lower(h.name) <> lower(c.name)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("write historic names: %w", err)
+	}
+	imp.report.HistoricNamesAdded = tag.RowsAffected()
+	return nil
+}
+
+// geoNamesNameKey identifies a place's name in one language
+type geoNamesNameKey struct {
+	geonameID int64
+	lang      string
+}
+
+// readAlternateNames picks the best name per supported language for every
+// imported country, region and city, and collects historic city names as
+// (geonameid, name) rows. Colloquial names are ignored.
+func (imp *geoNamesImporter) readAlternateNames(path string) (map[geoNamesNameKey]geoNamesAltName, [][]interface{}, error) {
+	best := make(map[geoNamesNameKey]geoNamesAltName)
+	var historic [][]interface{}
+
+	err := readGeoNamesFile(path, func(fields []string) error {
+		// alternateNameId, geonameid, isolanguage, name, isPreferredName, isShortName, isColloquial, isHistoric
+		if len(fields) < 8 || fields[6] == "1" {
+			return nil
+		}
+		geonameID, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil
+		}
+		_, isCity := imp.cityCountries[geonameID]
+		if _, isEntity := imp.entities[geonameID]; !isCity && !isEntity {
+			return nil
+		}
+		name := strings.TrimSpace(fields[3])
+		if name == "" {
+			return nil
+		}
+
+		if fields[7] == "1" {
+			if isCity {
+				historic = append(historic, []interface{}{int32(geonameID), name})
+			}
+			return nil
+		}
+		lang, ok := geoNamesLanguages[fields[2]]
+		if !ok {
+			return nil
+		}
+
+		// Preferred names win, then full names over short ones, then the first seen
+		candidate := geoNamesAltName{name: name, preferred: fields[4] == "1", short: fields[5] == "1"}
+		k := geoNamesNameKey{geonameID, lang}
+		if current, ok := best[k]; !ok ||
+			(candidate.preferred && !current.preferred) ||
+			(candidate.preferred == current.preferred && current.short && !candidate.short) {
+			best[k] = candidate
+		}
+		return nil
+	})
+	return best, historic, err
+}
+
+// readGeoNamesFile calls fn with the tab-separated fields of each line of a
+// GeoNames dump, skipping comments
+func readGeoNamesFile(path string, fn func(fields []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(strings.Split(line, "\t")); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (r geoNamesReport) missingCities() int {
+	var n int
+	for _, count := range r.MissingCountries {
+		n += count
+	}
+	return n
+}
+
+// print writes the import report
+func (r geoNamesReport) print(w io.Writer, dryRun bool) {
+	title := "GeoNames Import Report"
+	if dryRun {
+		title += " (DRY RUN - nothing was written)"
+	}
+	fmt.Fprintf(w, "\n=== %s ===\n", title)
+	fmt.Fprintf(w, "Countries:  %d existing, %d created\n", r.CountriesExisting, r.CountriesCreated)
+	fmt.Fprintf(w, "Regions:    %d mapped, %d matched by name, %d created\n", r.RegionsMapped, r.RegionsMatched, r.RegionsCreated)
+	fmt.Fprintf(w, "Cities:     %d read, %d filtered\n", r.CitiesRead, r.CitiesFiltered)
+	fmt.Fprintf(w, "  created:            %d\n", r.CitiesCreated)
+	fmt.Fprintf(w, "  updated:            %d\n", r.CitiesUpdated)
+	fmt.Fprintf(w, "  unchanged:          %d\n", r.CitiesUnchanged)
+	fmt.Fprintf(w, "  WOF, already linked: %d\n", r.CitiesWOFMatched)
+	fmt.Fprintf(w, "  WOF, newly linked:  %d\n", r.CitiesLinked)
+	fmt.Fprintf(w, "  duplicates skipped: %d\n", r.CitiesDuplicates)
+	fmt.Fprintf(w, "Names:      %d written, %d historic variants added\n", r.NamesWritten, r.HistoricNamesAdded)
+
+	if len(r.MissingCountries) > 0 {
+		codes := make([]string, 0, len(r.MissingCountries))
+		for code := range r.MissingCountries {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		fmt.Fprintf(w, "\nSkipped %d cities in countries missing from geo_countries (add countryInfo.txt to create them):\n", r.missingCities())
+		for _, code := range codes {
+			fmt.Fprintf(w, "  %s: %d\n", code, r.MissingCountries[code])
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCityFields is a cities500.txt record for Jerusalem
+func testCityFields() []string {
+	return []string{
+		"281184", "Jerusalem", "Jerusalem", "Al Quds,Yerushalayim", "31.76904", "35.21633", "P", "PPLC",
+		"IL", "", "06", "", "", "", "801000", "", "786", "Asia/Jerusalem", "2023-01-01",
+	}
+}
+
+func testGeoNamesImporter() *geoNamesImporter {
+	return &geoNamesImporter{
+		countries:     map[string]geoNamesCountry{"IL": {id: 7, continentID: 2}, "US": {id: 9, continentID: 5}},
+		regions:       map[string]int32{"IL.06": 40},
+		entities:      map[int64]geoNamesEntity{294640: {entityType: "country", entityID: "7"}},
+		cityCountries: map[int64]string{281184: "IL"},
+		report:        geoNamesReport{MissingCountries: make(map[string]int)},
+	}
+}
+
+func TestParseCity(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(f []string) []string
+		setup         func(imp *geoNamesImporter)
+		wantCity      bool
+		wantElevation *int32 // checked when a city is returned
+		wantRead      int
+		wantFiltered  int
+		wantMissing   int
+	}{
+		{"DEM used without a surveyed elevation", nil, nil, true, ptr32(786), 1, 0, 0},
+		{"surveyed elevation wins over DEM", func(f []string) []string { f[15] = "754"; return f }, nil, true, ptr32(754), 1, 0, 0},
+		{"DEM no-data value", func(f []string) []string { f[16] = "-9999"; return f }, nil, true, nil, 1, 0, 0},
+		{"no elevation at all", func(f []string) []string { f[16] = ""; return f }, nil, true, nil, 1, 0, 0},
+		{"below sea level", func(f []string) []string { f[15] = ""; f[16] = "-400"; return f }, nil, true, ptr32(-400), 1, 0, 0},
+		{"too few fields", func(f []string) []string { return f[:18] }, nil, false, nil, 0, 0, 0},
+		{"historical place", func(f []string) []string { f[7] = "PPLH"; return f }, nil, false, nil, 1, 1, 0},
+		{"section of a city", func(f []string) []string { f[7] = "PPLX"; return f }, nil, false, nil, 1, 1, 0},
+		{"no timezone", func(f []string) []string { f[17] = ""; return f }, nil, false, nil, 1, 1, 0},
+		{"below minimum population", nil, func(imp *geoNamesImporter) { imp.minPopulation = 1000000 }, false, nil, 1, 1, 0},
+		{"outside country filter", nil, func(imp *geoNamesImporter) { imp.countryFilter = map[string]bool{"US": true} }, false, nil, 1, 1, 0},
+		{"country missing from geo_countries", func(f []string) []string { f[8] = "PS"; return f }, nil, false, nil, 1, 0, 1},
+		{"malformed coordinates", func(f []string) []string { f[4] = "north"; return f }, nil, false, nil, 1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp := testGeoNamesImporter()
+			if tt.setup != nil {
+				tt.setup(imp)
+			}
+			fields := testCityFields()
+			if tt.modify != nil {
+				fields = tt.modify(fields)
+			}
+
+			c := imp.parseCity(fields)
+			if (c != nil) != tt.wantCity {
+				t.Fatalf("parseCity() = %+v, want city %v", c, tt.wantCity)
+			}
+			if imp.report.CitiesRead != tt.wantRead || imp.report.CitiesFiltered != tt.wantFiltered || imp.report.missingCities() != tt.wantMissing {
+				t.Errorf("report read/filtered/missing = %d/%d/%d, want %d/%d/%d",
+					imp.report.CitiesRead, imp.report.CitiesFiltered, imp.report.missingCities(),
+					tt.wantRead, tt.wantFiltered, tt.wantMissing)
+			}
+			if c == nil {
+				return
+			}
+			if (c.elevation == nil) != (tt.wantElevation == nil) || (c.elevation != nil && *c.elevation != *tt.wantElevation) {
+				t.Errorf("elevation = %v, want %v", deref32(c.elevation), deref32(tt.wantElevation))
+			}
+		})
+	}
+}
+
+func TestParseCityValues(t *testing.T) {
+	imp := testGeoNamesImporter()
+	fields := testCityFields()
+	fields[2] = ""
+	c := imp.parseCity(fields)
+	if c == nil {
+		t.Fatal("parseCity() = nil")
+	}
+	got := c.values()
+	if len(got) != 11 {
+		t.Fatalf("values() has %d columns, want 11", len(got))
+	}
+	if got[0] != int32(281184) || got[2] != nil || got[5] != int16(2) || got[6] != int32(7) ||
+		got[7] != int32(40) || got[8] != "Asia/Jerusalem" || got[9] != int32(801000) || got[10] != int32(786) {
+		t.Errorf("values() = %v", got)
+	}
+
+	// No region mapping and no elevation stage as NULL
+	fields = testCityFields()
+	fields[10], fields[16] = "99", "-9999"
+	got = imp.parseCity(fields).values()
+	if got[7] != nil || got[10] != nil {
+		t.Errorf("region and elevation = %v, %v, want nil", got[7], got[10])
+	}
+}
+
+func TestReadAlternateNames(t *testing.T) {
+	lines := []string{
+		"# alternateNameId geonameid isolanguage name isPreferredName isShortName isColloquial isHistoric",
+		"1\t281184\the\tירושלים\t\t\t\t",
+		"2\t281184\tiw\tירושלים\t\t\t\t",     // not a supported language code
+		"3\t281184\tlink\thttps://x\t\t\t\t", // not a language
+		"4\t281184\ten\tJlem\t\t1\t\t",       // short name, replaced by the full one
+		"5\t281184\ten\tJerusalem\t\t\t\t",
+		"6\t281184\tar\tأورشليم\t\t\t\t",
+		"7\t281184\tar\tالقدس\t1\t\t\t",   // preferred name wins
+		"8\t281184\tyi\tירושלים\t\t\t1\t", // colloquial
+		"9\t281184\ten\tAelia Capitolina\t\t\t\t1",
+		"10\t294640\ten\tIsrael\t1\t\t\t",
+		"11\t294640\ten\tPalaestina\t\t\t\t1", // historic names are kept for cities only
+		"12\t999999\ten\tElsewhere\t1\t\t\t",  // not imported
+		"13\t281184\tde\t \t\t\t\t",
+		"14\t281184",
+	}
+	path := filepath.Join(t.TempDir(), "alternateNamesV2.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	best, historic, err := testGeoNamesImporter().readAlternateNames(path)
+	if err != nil {
+		t.Fatalf("readAlternateNames() error: %v", err)
+	}
+
+	want := map[geoNamesNameKey]string{
+		{281184, "heb"}: "ירושלים",
+		{281184, "eng"}: "Jerusalem",
+		{281184, "ara"}: "القدس",
+		{294640, "eng"}: "Israel",
+	}
+	if len(best) != len(want) {
+		t.Errorf("got %d names, want %d: %v", len(best), len(want), best)
+	}
+	for k, name := range want {
+		if got := best[k].name; got != name {
+			t.Errorf("name for %v = %q, want %q", k, got, name)
+		}
+	}
+	if len(historic) != 1 || historic[0][0] != int32(281184) || historic[0][1] != "Aelia Capitolina" {
+		t.Errorf("historic = %v, want only Aelia Capitolina", historic)
+	}
+}
+
+func TestGeoNamesLanguages(t *testing.T) {
+	for iso, want := range map[string]string{"en": "eng", "he": "heb", "yi": "yid", "ar": "ara", "ru": "rus"} {
+		if got := geoNamesLanguages[iso]; got != want {
+			t.Errorf("geoNamesLanguages[%q] = %q, want %q", iso, got, want)
+		}
+	}
+	for iso, lang := range geoNamesLanguages {
+		if len(iso) != 2 || len(lang) != 3 {
+			t.Errorf("geoNamesLanguages[%q] = %q, want a 2-letter key and a 3-letter code", iso, lang)
+		}
+	}
+}
+
+func TestGeoNamesReportPrint(t *testing.T) {
+	r := geoNamesReport{
+		CountriesExisting: 2,
+		CitiesRead:        10, CitiesFiltered: 3, CitiesCreated: 4, CitiesUpdated: 1, CitiesUnchanged: 2,
+		MissingCountries: map[string]int{"XK": 2, "PS": 1},
+	}
+
+	var buf bytes.Buffer
+	r.print(&buf, true)
+	out := buf.String()
+	for _, want := range []string{
+		"GeoNames Import Report (DRY RUN - nothing was written)",
+		"Cities:     10 read, 3 filtered",
+		"  created:            4",
+		"  updated:            1",
+		"  unchanged:          2",
+		"Skipped 3 cities in countries missing from geo_countries",
+		"  PS: 1\n  XK: 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	r.print(&buf, false)
+	if strings.Contains(buf.String(), "DRY RUN") {
+		t.Errorf("report for a real run mentions a dry run:\n%s", buf.String())
+	}
+}
+
+func ptr32(v int32) *int32 { return &v }
+
+func deref32(p *int32) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
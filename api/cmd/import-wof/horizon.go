@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jcom-dev/zmanim-lab/internal/terrain"
+)
+
+// =============================================================================
+// HORIZONS
+// =============================================================================
+
+// cmdHorizons computes terrain horizon profiles for cities from SRTM. Each
+// profile reads a few hundred thousand elevations, so cities are processed
+// most-populous first and can be limited to a country.
+func cmdHorizons(args []string) {
+	var country string
+	limit := 0
+	minPopulation := 0
+	force := false
+	batchSize := 100 // Cities per batch before clearing SRTM cache
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--country" && i+1 < len(args):
+			country = strings.ToUpper(args[i+1])
+			i++
+		case args[i] == "--limit" && i+1 < len(args):
+			fmt.Sscanf(args[i+1], "%d", &limit)
+			i++
+		case args[i] == "--min-population" && i+1 < len(args):
+			fmt.Sscanf(args[i+1], "%d", &minPopulation)
+			i++
+		case args[i] == "--batch" && i+1 < len(args):
+			fmt.Sscanf(args[i+1], "%d", &batchSize)
+			i++
+		case args[i] == "--force" || args[i] == "-f":
+			force = true
+		}
+	}
+
+	pgURL := os.Getenv("DATABASE_URL")
+	if pgURL == "" {
+		log.Fatal("DATABASE_URL required")
+	}
+
+	ctx := context.Background()
+
+	pgPool, err := pgxpool.New(ctx, pgURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer pgPool.Close()
+
+	if err := os.MkdirAll(srtmCacheDir, 0755); err != nil {
+		log.Fatalf("Failed to create SRTM cache dir: %v", err)
+	}
+
+	// With --force every matching city is recomputed; otherwise only those without a profile
+	query := `
+		SELECT c.id, c.name, c.latitude, c.longitude
+		FROM geo_cities c
+		LEFT JOIN geo_countries co ON co.id = c.country_id
+		LEFT JOIN geo_city_horizons hz ON hz.city_id = c.id
+		WHERE c.latitude BETWEEN -60 AND 60
+		  AND ($1 = '' OR co.code = $1)
+		  AND COALESCE(c.population, 0) >= $2
+		  AND ($3 OR hz.city_id IS NULL)
+		ORDER BY c.population DESC NULLS LAST, c.id
+	`
+	queryArgs := []any{country, minPopulation, force}
+	if limit > 0 {
+		query += " LIMIT $4"
+		queryArgs = append(queryArgs, limit)
+	}
+
+	type city struct {
+		id, name string
+		lat, lng float64
+	}
+	rows, err := pgPool.Query(ctx, query, queryArgs...)
+	if err != nil {
+		log.Fatalf("Query failed: %v", err)
+	}
+	var cities []city
+	for rows.Next() {
+		var c city
+		if err := rows.Scan(&c.id, &c.name, &c.lat, &c.lng); err != nil {
+			continue
+		}
+		cities = append(cities, c)
+	}
+	rows.Close()
+
+	if len(cities) == 0 {
+		log.Println("No cities need horizon profiles")
+		return
+	}
+	log.Printf("Computing horizon profiles for %d cities", len(cities))
+
+	var computed, failed int
+	for start := 0; start < len(cities); start += batchSize {
+		// Fresh SRTM source per batch to prevent tile memory buildup
+		src, err := terrain.NewSRTMSource(http.DefaultClient, srtmCacheDir)
+		if err != nil {
+			log.Fatalf("Failed to initialize SRTM: %v", err)
+		}
+
+		end := min(start+batchSize, len(cities))
+		for _, c := range cities[start:end] {
+			profile, err := terrain.Compute(src, c.lat, c.lng, terrain.DefaultOptions)
+			if err != nil {
+				log.Printf("  Warning: %s (%s): %v", c.name, c.id, err)
+				failed++
+				continue
+			}
+			_, err = pgPool.Exec(ctx, `
+				INSERT INTO geo_city_horizons (city_id, ground_elevation_m, observer_elevation_m, azimuth_step, max_distance_m, altitudes, source, computed_at)
+				VALUES ($1, $2, $3, $4, $5, $6, 'srtm', NOW())
+				ON CONFLICT (city_id) DO UPDATE SET
+					ground_elevation_m = EXCLUDED.ground_elevation_m,
+					observer_elevation_m = EXCLUDED.observer_elevation_m,
+					azimuth_step = EXCLUDED.azimuth_step,
+					max_distance_m = EXCLUDED.max_distance_m,
+					altitudes = EXCLUDED.altitudes,
+					source = EXCLUDED.source,
+					computed_at = EXCLUDED.computed_at
+			`, c.id, profile.GroundElevation, profile.ObserverElevation, profile.AzimuthStep, profile.MaxDistance, profile.Altitudes)
+			if err != nil {
+				log.Printf("  Warning: saving %s (%s): %v", c.name, c.id, err)
+				failed++
+				continue
+			}
+			computed++
+		}
+
+		log.Printf("  Computed %d/%d profiles (%.1f%%)", computed, len(cities), float64(computed)/float64(len(cities))*100)
+
+		// Force garbage collection to release SRTM tile memory
+		src = nil
+		runtime.GC()
+	}
+
+	log.Printf("Horizon profiles complete: %d computed, %d failed", computed, failed)
+}
@@ -59,6 +59,8 @@ func main() {
 		cmdDownload(os.Args[2:])
 	case "import":
 		cmdImport(os.Args[2:])
+	case "update":
+		cmdUpdate(os.Args[2:])
 	case "seed":
 		cmdSeed(os.Args[2:])
 	case "status":
@@ -67,6 +69,12 @@ func main() {
 		cmdReset(os.Args[2:])
 	case "elevation":
 		cmdElevation(os.Args[2:])
+	case "horizons":
+		cmdHorizons(os.Args[2:])
+	case "timezones":
+		cmdTimezones(os.Args[2:])
+	case "geonames":
+		cmdGeoNames(os.Args[2:])
 	case "help", "-h", "--help":
 		usage()
 	default:
@@ -83,14 +91,25 @@ Single source of truth for geographic hierarchy + SRTM elevation.
 
 Commands:
   download    Download WOF SQLite database (~8.6GB compressed → ~40GB)
-  import      Import WOF data into PostgreSQL (elevation skipped to save RAM)
+  import      Import WOF data into PostgreSQL (elevation skipped to save RAM);
+              checkpointed, keeps existing city IDs
+              [--resume] continue the last unfinished import or update
+  update      Apply only WOF records modified since the last import, retiring
+              deprecated cities no publisher covers [--resume]
   seed        Download + import in one step
   elevation   Populate SRTM elevation for cities (run after import)
+  horizons    Compute terrain horizon profiles for cities from SRTM
+              [--country CC] [--limit N] [--min-population N] [--force]
+  timezones   Import IANA timezone boundaries (timezone-boundary-builder)
+              [--version 2024a] [--file path.zip]
+  geonames    Import cities from local GeoNames dumps (no WOF download needed),
+              reconciling with existing rows; re-runnable
+              [--dir data/geonames] [--country CC,CC] [--min-population N] [--dry-run]
   status      Show current status
   reset       Nuclear wipe - delete ALL geographic data from database
 
 Environment:
-  DATABASE_URL    PostgreSQL connection string (required for import/seed/elevation)
+  DATABASE_URL    PostgreSQL connection string (required for import/seed/elevation/horizons/timezones)
 
 Data Sources:
   WOF:  %s
@@ -145,7 +164,7 @@ func cmdDownload(args []string) {
 	fmt.Printf("Source: %s\n", wofAdminDBURL)
 	fmt.Printf("Target: %s\n", dbPath)
 	fmt.Println("Size: ~8.6GB compressed → ~40GB uncompressed")
-	fmt.Println("This may take 30-60 minutes...\n")
+	fmt.Print("This may take 30-60 minutes...\n\n")
 
 	if err := downloadBZ2(wofAdminDBURL, dbPath); err != nil {
 		log.Fatalf("Download failed: %v", err)
@@ -215,8 +234,18 @@ func (p *progressReader) Read(b []byte) (int, error) {
 // =============================================================================
 
 func cmdImport(args []string) {
+	runImport(args, importModeFull)
+}
+
+// cmdUpdate applies only the WOF records modified since the last import
+func cmdUpdate(args []string) {
+	runImport(args, importModeUpdate)
+}
+
+func runImport(args []string, mode string) {
 	dataDir := defaultDataDir
 	verbose := false
+	resume := false
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "--dir":
@@ -224,6 +253,8 @@ func cmdImport(args []string) {
 				dataDir = args[i+1]
 				i++
 			}
+		case "--resume":
+			resume = true
 		case "-v", "--verbose":
 			verbose = true
 		}
@@ -270,6 +301,8 @@ func cmdImport(args []string) {
 		srtm:    srtm,
 		verbose: verbose,
 		wofToPG: make(map[int64]int64), // WOF ID → PostgreSQL ID
+		mode:    mode,
+		resume:  resume,
 	}
 
 	if err := imp.Run(ctx); err != nil {
@@ -347,10 +380,15 @@ func cmdStatus(args []string) {
 	pool.QueryRow(ctx, "SELECT COUNT(*) FROM geo_regions").Scan(&r)
 	pool.QueryRow(ctx, "SELECT COUNT(*) FROM geo_districts").Scan(&d)
 	pool.QueryRow(ctx, "SELECT COUNT(*) FROM geo_cities").Scan(&ci)
+	var tz int
+	pool.QueryRow(ctx, "SELECT COUNT(*) FROM geo_timezone_boundaries").Scan(&tz)
 	fmt.Printf("Countries:  %d\n", c)
 	fmt.Printf("Regions:    %d\n", r)
 	fmt.Printf("Districts:  %d\n", d)
 	fmt.Printf("Cities:     %d\n", ci)
+	fmt.Printf("Timezones:  %d\n", tz)
+
+	printImportRun(ctx, pool)
 }
 
 // =============================================================================
@@ -377,6 +415,7 @@ func cmdReset(args []string) {
 		fmt.Println("   - geo_continents (imported from WOF)")
 		fmt.Println("   - geo_boundary_imports")
 		fmt.Println("   - geo_name_mappings")
+		fmt.Println("   - geo_timezone_boundaries")
 		fmt.Println("   - publisher_coverage (all geographic coverage)")
 		fmt.Println("")
 		fmt.Println("   This will NOT delete:")
@@ -419,6 +458,7 @@ func cmdReset(args []string) {
 			geo_city_boundaries,
 			geo_boundary_imports,
 			geo_name_mappings,
+			geo_timezone_boundaries,
 			publisher_coverage
 		CASCADE
 	`)
@@ -431,6 +471,7 @@ func cmdReset(args []string) {
 			"publisher_coverage",
 			"geo_boundary_imports",
 			"geo_name_mappings",
+			"geo_timezone_boundaries",
 			"geo_names",
 			"geo_city_boundaries",
 			"geo_cities",
@@ -584,6 +625,10 @@ type Importer struct {
 	wofToPG         map[int64]int64   // WOF ID → PostgreSQL ID (for all placetypes)
 	wofContinentMap map[int64]int     // WOF continent ID → our geo_continents.id
 	geoNamesBatch   []geoNameEntry    // Batch queue for geo_names inserts
+
+	// Run options
+	mode   string // importModeFull or importModeUpdate
+	resume bool   // continue the newest unfinished run
 }
 
 // WOFRecord holds data extracted from WOF SQLite
@@ -599,6 +644,10 @@ type WOFRecord struct {
 	Timezone   string
 	Geometry   string // GeoJSON geometry
 	Hierarchy  map[string]int64
+
+	// Versioning, for checkpointed and update imports
+	LastModified int64   // unix seconds
+	Supersedes   []int64 // WOF IDs this record replaces
 }
 
 // Supported language codes for geo_names (must match languages table)
@@ -607,13 +656,13 @@ var supportedLanguages = []string{
 	"zho", "jpn", "kor", "ita", "nld", "pol", "hun", "ukr", "tur", "fas", "hin",
 }
 
-// cityNameEntry stores names for batch city import (JSON-serializable for disk storage)
+// cityNameEntry stores names for batch city import
 type cityNameEntry struct {
 	WofID int64             `json:"w"`
 	Names map[string]string `json:"n"`
 }
 
-// cityBoundaryEntry stores boundary geometry for batch city import (JSON-serializable for disk storage)
+// cityBoundaryEntry stores boundary geometry for batch city import
 type cityBoundaryEntry struct {
 	WofID    int64  `json:"w"`
 	Geometry string `json:"g"` // GeoJSON geometry string
@@ -763,89 +812,58 @@ func toASCII(s string) string {
 func (imp *Importer) Run(ctx context.Context) error {
 	start := time.Now()
 
-	// Step 1: Reset
-	log.Println("Step 1: Resetting database...")
-	if err := imp.reset(ctx); err != nil {
-		return err
-	}
-
-	// Step 2: Import continents from WOF
-	log.Println("Step 2: Importing continents from WOF...")
+	// Step 1: Import continents from WOF (always, they are few)
+	log.Println("Step 1: Importing continents from WOF...")
 	continents, err := imp.importContinents(ctx)
 	if err != nil {
 		return err
 	}
 	log.Printf("  %d continents", continents)
 
-	// Step 3: Import countries
-	log.Println("Step 3: Importing countries...")
-	countries, err := imp.importPlacetype(ctx, "country")
-	if err != nil {
+	// Step 2: Start or resume a run. Rows are upserted in place, so cities
+	// keep the IDs publisher coverage references.
+	log.Println("Step 2: Starting import run...")
+	if err := imp.loadWOFMappings(ctx); err != nil {
 		return err
 	}
-	log.Printf("  %d countries", countries)
-	if err := imp.flushGeoNames(ctx); err != nil {
-		log.Printf("  Warning: failed to flush country names: %v", err)
-	}
-
-	// Step 4: Import regions
-	log.Println("Step 4: Importing regions...")
-	regions, err := imp.importPlacetype(ctx, "region")
+	runID, err := imp.startRun(ctx)
 	if err != nil {
 		return err
 	}
-	log.Printf("  %d regions", regions)
-	if err := imp.flushGeoNames(ctx); err != nil {
-		log.Printf("  Warning: failed to flush region names: %v", err)
-	}
 
-	// Step 5: Import districts (counties)
-	log.Println("Step 5: Importing districts...")
-	districts, err := imp.importPlacetype(ctx, "county")
-	if err != nil {
-		return err
-	}
-	log.Printf("  %d districts", districts)
-	if err := imp.flushGeoNames(ctx); err != nil {
-		log.Printf("  Warning: failed to flush district names: %v", err)
-	}
-
-	// Step 6: Import cities (localities)
-	log.Println("Step 6: Importing cities...")
-	cities, err := imp.importCities(ctx)
-	if err != nil {
-		return err
-	}
-	log.Printf("  %d cities", cities)
+	// Steps 3-6: Import each placetype from its checkpoint
+	for i, lvl := range wofLevels {
+		log.Printf("Step %d: Importing %s...", i+3, lvl.label)
+		lr, err := imp.beginLevel(ctx, runID, lvl)
+		if err != nil {
+			imp.failRun(ctx, runID)
+			return err
+		}
+		if lr.completed {
+			log.Printf("  Already completed in this run (%d %s)", lr.imported, lvl.label)
+			continue
+		}
 
-	log.Printf("\nComplete in %s", time.Since(start).Round(time.Second))
-	return nil
-}
+		if lvl.placetype == "locality" {
+			err = imp.importCities(ctx, lr)
+		} else {
+			err = imp.importPlacetype(ctx, lr)
+		}
+		if err == nil {
+			err = imp.completeLevel(ctx, lr)
+		}
+		if err != nil {
+			imp.failRun(ctx, runID)
+			return fmt.Errorf("%w (resume with --resume)", err)
+		}
 
-func (imp *Importer) reset(ctx context.Context) error {
-	// Delete in FK order (leaf tables first)
-	queries := []string{
-		"DELETE FROM publisher_coverage",
-		"DELETE FROM geo_boundary_imports",
-		"DELETE FROM geo_name_mappings",
-		"DELETE FROM geo_names",
-		"DELETE FROM geo_city_boundaries",
-		"DELETE FROM geo_district_boundaries",
-		"DELETE FROM geo_region_boundaries",
-		"DELETE FROM geo_country_boundaries",
-		"DELETE FROM geo_cities",
-		"DELETE FROM geo_districts",
-		"DELETE FROM geo_regions",
-		"DELETE FROM geo_countries",
-		"DELETE FROM geo_continents",
-	}
-	for _, q := range queries {
-		if _, err := imp.pgPool.Exec(ctx, q); err != nil {
-			return fmt.Errorf("%s: %w", q, err)
+		log.Printf("  %d %s", lr.imported, lvl.label)
+		if lr.skipped > 0 {
+			log.Printf("  (skipped %d invalid entries)", lr.skipped)
 		}
 	}
 
-	// Continents will be imported from WOF in importContinents()
+	log.Printf("\nComplete in %s", time.Since(start).Round(time.Second))
 	return nil
 }
 
@@ -926,63 +944,81 @@ func (imp *Importer) importContinents(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (imp *Importer) importPlacetype(ctx context.Context, placetype string) (int, error) {
+// importPlacetype imports countries, regions or districts in WOF ID order
+// from the level's checkpoint, saving a checkpoint every checkpointInterval
+// records
+func (imp *Importer) importPlacetype(ctx context.Context, lr *levelRun) error {
+	placetype := lr.level.placetype
+
 	// Query WOF for this placetype
 	rows, err := imp.wofDB.Query(`
 		SELECT s.id, s.name, s.placetype, s.country, s.latitude, s.longitude,
-		       g.body
+		       s.lastmodified, s.supersedes, g.body
 		FROM spr s
 		JOIN geojson g ON s.id = g.id AND g.is_alt = 0
 		WHERE s.placetype = ?
 		  AND s.is_deprecated = 0
 		  AND s.is_ceased = 0
-		ORDER BY s.name
-	`, placetype)
+		  AND s.id > ?
+		  AND s.lastmodified > ?
+		ORDER BY s.id
+	`, placetype, lr.checkpoint, lr.since)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer rows.Close()
 
-	var count, skipped int
+	var processed int
+	var lastID int64
 	for rows.Next() {
 		rec, err := imp.scanRecord(rows)
 		if err != nil {
-			return 0, fmt.Errorf("scan %s record: %w", placetype, err)
+			return fmt.Errorf("scan %s record: %w", placetype, err)
 		}
+		lastID = rec.WOFID
 
 		pgID, err := imp.insertRecord(ctx, rec)
-		if err != nil {
-			if errors.Is(err, ErrSkipRecord) {
-				log.Printf("  SKIP: %s (WOF %d) - %v", rec.Name, rec.WOFID, err)
-				skipped++
-				continue
+		switch {
+		case errors.Is(err, ErrSkipRecord):
+			log.Printf("  SKIP: %s (WOF %d) - %v", rec.Name, rec.WOFID, err)
+			lr.skipped++
+		case err != nil:
+			return fmt.Errorf("insert %s (WOF %d): %w", rec.Name, rec.WOFID, err)
+		default:
+			imp.wofToPG[rec.WOFID] = pgID
+			lr.imported++
+		}
+
+		processed++
+		if processed%checkpointInterval == 0 {
+			if err := imp.saveCheckpoint(ctx, lr, lastID); err != nil {
+				return err
+			}
+			if imp.verbose {
+				log.Printf("  %d %ss...", lr.imported, placetype)
 			}
-			return 0, fmt.Errorf("insert %s (WOF %d): %w", rec.Name, rec.WOFID, err)
-		}
-
-		imp.wofToPG[rec.WOFID] = pgID
-		count++
-
-		if imp.verbose && count%1000 == 0 {
-			log.Printf("  %d %ss...", count, placetype)
 		}
 	}
-
-	if skipped > 0 {
-		log.Printf("  (skipped %d invalid entries)", skipped)
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	return count, nil
+	if lastID > lr.checkpoint {
+		return imp.saveCheckpoint(ctx, lr, lastID)
+	}
+	return nil
 }
 
 func (imp *Importer) scanRecord(rows *sql.Rows) (*WOFRecord, error) {
 	var rec WOFRecord
 	var body string
+	var supersedes sql.NullString
 
 	if err := rows.Scan(&rec.WOFID, &rec.Name, &rec.Placetype, &rec.Country,
-		&rec.Latitude, &rec.Longitude, &body); err != nil {
+		&rec.Latitude, &rec.Longitude, &rec.LastModified, &supersedes, &body); err != nil {
 		return nil, err
 	}
+	rec.Supersedes = parseWOFIDs(supersedes.String)
 
 	// Parse GeoJSON to extract properties and geometry
 	var feature struct {
@@ -1091,14 +1127,15 @@ func (imp *Importer) insertCountry(ctx context.Context, rec *WOFRecord) (int64,
 
 	var pgID int64
 	err := imp.pgPool.QueryRow(ctx, `
-		INSERT INTO geo_countries (code, name, continent_id, wof_id, is_city_state)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO geo_countries (code, name, continent_id, wof_id, is_city_state, wof_lastmodified)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (code) DO UPDATE SET
 			name = EXCLUDED.name,
 			wof_id = EXCLUDED.wof_id,
-			is_city_state = EXCLUDED.is_city_state
+			is_city_state = EXCLUDED.is_city_state,
+			wof_lastmodified = EXCLUDED.wof_lastmodified
 		RETURNING id
-	`, code, rec.Name, continentID, rec.WOFID, isCityState).Scan(&pgID)
+	`, code, rec.Name, continentID, rec.WOFID, isCityState, rec.LastModified).Scan(&pgID)
 	if err != nil {
 		return 0, err
 	}
@@ -1148,14 +1185,15 @@ func (imp *Importer) insertRegion(ctx context.Context, rec *WOFRecord) (int64, e
 
 	var pgID int64
 	err := imp.pgPool.QueryRow(ctx, `
-		INSERT INTO geo_regions (continent_id, country_id, code, name, wof_id)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO geo_regions (continent_id, country_id, code, name, wof_id, wof_lastmodified)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (wof_id) DO UPDATE SET
 			continent_id = EXCLUDED.continent_id,
 			country_id = EXCLUDED.country_id,
-			name = EXCLUDED.name
+			name = EXCLUDED.name,
+			wof_lastmodified = EXCLUDED.wof_lastmodified
 		RETURNING id
-	`, continentID, countryPGID, code, rec.Name, rec.WOFID).Scan(&pgID)
+	`, continentID, countryPGID, code, rec.Name, rec.WOFID, rec.LastModified).Scan(&pgID)
 	if err != nil {
 		return 0, err
 	}
@@ -1227,15 +1265,16 @@ func (imp *Importer) insertDistrict(ctx context.Context, rec *WOFRecord) (int64,
 
 	var pgID int64
 	err := imp.pgPool.QueryRow(ctx, `
-		INSERT INTO geo_districts (continent_id, country_id, region_id, code, name, wof_id)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO geo_districts (continent_id, country_id, region_id, code, name, wof_id, wof_lastmodified)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (wof_id) DO UPDATE SET
 			continent_id = EXCLUDED.continent_id,
 			country_id = EXCLUDED.country_id,
 			region_id = EXCLUDED.region_id,
-			name = EXCLUDED.name
+			name = EXCLUDED.name,
+			wof_lastmodified = EXCLUDED.wof_lastmodified
 		RETURNING id
-	`, continentID, countryPGID, regionPGID, code, rec.Name, rec.WOFID).Scan(&pgID)
+	`, continentID, countryPGID, regionPGID, code, rec.Name, rec.WOFID, rec.LastModified).Scan(&pgID)
 	if err != nil {
 		return 0, err
 	}
@@ -1260,38 +1299,28 @@ func (imp *Importer) insertDistrict(ctx context.Context, rec *WOFRecord) (int64,
 
 // cityRecord holds pre-processed city data for batch operations
 type cityRecord struct {
-	continentID int16
-	countryID   *int32
-	regionID    *int32
-	districtID  *int32
-	name        string
-	lat         float64
-	lng         float64
-	tz          string
-	population  int64
-	wofID       int64
-	names       map[string]string
-	elevation   *int32 // filled in by elevation lookup
+	continentID  int16
+	countryID    *int32
+	regionID     *int32
+	districtID   *int32
+	name         string
+	lat          float64
+	lng          float64
+	tz           string
+	population   int64
+	wofID        int64
+	lastModified int64
+	supersedes   []int64 // WOF IDs of the localities this one replaces
+	names        map[string]string
+	geometry     string
 }
 
-// cityRecordDisk is a compact version for disk storage (no names map to save space)
-type cityRecordDisk struct {
-	ContinentID int16    `json:"c"`
-	CountryID   *int32   `json:"co,omitempty"`
-	RegionID    *int32   `json:"r,omitempty"`
-	DistrictID  *int32   `json:"d,omitempty"`
-	Name        string   `json:"n"`
-	Lat         float64  `json:"la"`
-	Lng         float64  `json:"lo"`
-	Tz          string   `json:"t"`
-	Population  int64    `json:"p,omitempty"`
-	WofID       int64    `json:"w"`
-	Elevation   *int32   `json:"e,omitempty"`
-}
-
-func (imp *Importer) importCities(ctx context.Context) (int, error) {
-	// Disable trigger for bulk import performance (COPY doesn't fire row triggers anyway,
-	// but this ensures consistency if we switch to INSERT)
+// importCities imports localities in WOF ID order from the level's
+// checkpoint. Each batch is committed with its names and boundaries before
+// the checkpoint moves past it; localities retired in WOF are removed last.
+func (imp *Importer) importCities(ctx context.Context, lr *levelRun) error {
+	// Disable trigger for bulk import performance; hierarchies are validated
+	// once the import is done
 	if _, err := imp.pgPool.Exec(ctx, "ALTER TABLE geo_cities DISABLE TRIGGER trg_validate_city_hierarchy"); err != nil {
 		log.Printf("  Warning: could not disable trigger: %v", err)
 	}
@@ -1302,38 +1331,10 @@ func (imp *Importer) importCities(ctx context.Context) (int, error) {
 		}
 	}()
 
-	// Create temp file for streaming city data (avoids RAM usage for 4.5M+ records)
-	tmpFile, err := os.CreateTemp("", "wof-cities-*.jsonl")
-	if err != nil {
-		return 0, fmt.Errorf("create temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
-	log.Printf("  Using temp file: %s", tmpPath)
-
-	// Create temp file for names (separate to avoid loading into memory)
-	namesFile, err := os.CreateTemp("", "wof-city-names-*.jsonl")
-	if err != nil {
-		tmpFile.Close()
-		return 0, fmt.Errorf("create names temp file: %w", err)
-	}
-	namesPath := namesFile.Name()
-	defer os.Remove(namesPath)
-
-	// Create temp file for boundaries (separate to avoid loading into memory)
-	boundariesFile, err := os.CreateTemp("", "wof-city-boundaries-*.jsonl")
-	if err != nil {
-		tmpFile.Close()
-		namesFile.Close()
-		return 0, fmt.Errorf("create boundaries temp file: %w", err)
-	}
-	boundariesPath := boundariesFile.Name()
-	defer os.Remove(boundariesPath)
-
 	// Query WOF for localities
 	rows, err := imp.wofDB.Query(`
 		SELECT s.id, s.name, s.placetype, s.country, s.latitude, s.longitude,
-		       g.body
+		       s.lastmodified, s.supersedes, g.body
 		FROM spr s
 		JOIN geojson g ON s.id = g.id AND g.is_alt = 0
 		WHERE s.placetype = 'locality'
@@ -1341,39 +1342,32 @@ func (imp *Importer) importCities(ctx context.Context) (int, error) {
 		  AND s.is_ceased = 0
 		  AND s.latitude != 0
 		  AND s.longitude != 0
-		ORDER BY s.country, s.name
-	`)
+		  AND s.id > ?
+		  AND s.lastmodified > ?
+		ORDER BY s.id
+	`, lr.checkpoint, lr.since)
 	if err != nil {
-		tmpFile.Close()
-		namesFile.Close()
-		boundariesFile.Close()
-		return 0, err
+		return err
 	}
 	defer rows.Close()
 
-	// Phase 1: Stream localities from WOF to disk
-	log.Println("  Phase 1: Reading localities from WOF to disk...")
-	encoder := json.NewEncoder(tmpFile)
-	namesEncoder := json.NewEncoder(namesFile)
-	boundariesEncoder := json.NewEncoder(boundariesFile)
-	var totalRecords, skipped int
+	// Elevation is skipped: the SRTM library caches tiles in RAM, which
+	// causes OOM with 4.5M cities. The elevation command fills it in later.
+	log.Println("  Streaming localities from WOF in batches (elevation skipped to save memory)...")
+	const batchSize = 5000
+	batch := make([]cityRecord, 0, batchSize)
+	var lastID int64
 
 	for rows.Next() {
 		rec, err := imp.scanRecord(rows)
 		if err != nil {
-			tmpFile.Close()
-			namesFile.Close()
-			boundariesFile.Close()
-			return 0, fmt.Errorf("scan locality record: %w", err)
+			return fmt.Errorf("scan locality record: %w", err)
 		}
+		lastID = rec.WOFID
 
-		// Get hierarchy IDs from WOF
 		// Cities MUST have a continent (anchor point), everything else is optional
-
-		// Get continent (REQUIRED)
-		continentWOFID := rec.Hierarchy["continent_id"]
 		var continentPGID int16
-		if continentWOFID > 0 {
+		if continentWOFID := rec.Hierarchy["continent_id"]; continentWOFID > 0 {
 			if pgID, ok := imp.wofContinentMap[continentWOFID]; ok {
 				continentPGID = int16(pgID)
 			}
@@ -1383,164 +1377,58 @@ func (imp *Importer) importCities(ctx context.Context) (int, error) {
 			if imp.verbose {
 				log.Printf("  SKIP: locality %s (WOF %d) - no valid continent", rec.Name, rec.WOFID)
 			}
-			skipped++
-			continue
-		}
-
-		// Get optional hierarchy IDs
-		var countryPGID, regionPGID, districtPGID *int32
-		if countryWOFID := rec.Hierarchy["country_id"]; countryWOFID > 0 {
-			if pgID, ok := imp.wofToPG[countryWOFID]; ok {
-				v := int32(pgID)
-				countryPGID = &v
-			}
-		}
-		if regionWOFID := rec.Hierarchy["region_id"]; regionWOFID > 0 {
-			if pgID, ok := imp.wofToPG[regionWOFID]; ok {
-				v := int32(pgID)
-				regionPGID = &v
-			}
-		}
-		if countyWOFID := rec.Hierarchy["county_id"]; countyWOFID > 0 {
-			if pgID, ok := imp.wofToPG[countyWOFID]; ok {
-				v := int32(pgID)
-				districtPGID = &v
-			}
-		}
-
-		tz := rec.Timezone
-		if tz == "" {
-			tz = "UTC"
-		}
-
-		// Write city record to disk
-		diskRec := cityRecordDisk{
-			ContinentID: continentPGID,
-			CountryID:   countryPGID,
-			RegionID:    regionPGID,
-			DistrictID:  districtPGID,
-			Name:        rec.Name,
-			Lat:         rec.Latitude,
-			Lng:         rec.Longitude,
-			Tz:          tz,
-			Population:  rec.Population,
-			WofID:       rec.WOFID,
-		}
-		if err := encoder.Encode(diskRec); err != nil {
-			tmpFile.Close()
-			namesFile.Close()
-			boundariesFile.Close()
-			return 0, fmt.Errorf("write city to disk: %w", err)
-		}
-
-		// Write names to separate file (if any)
-		if len(rec.Names) > 0 {
-			namesEntry := cityNameEntry{WofID: rec.WOFID, Names: rec.Names}
-			if err := namesEncoder.Encode(namesEntry); err != nil {
-				log.Printf("  Warning: could not write names for WOF %d: %v", rec.WOFID, err)
-			}
-		}
-
-		// Write boundary to separate file (if geometry exists and is valid)
-		if rec.Geometry != "" && rec.Geometry != "null" {
-			boundaryEntry := cityBoundaryEntry{WofID: rec.WOFID, Geometry: rec.Geometry}
-			if err := boundariesEncoder.Encode(boundaryEntry); err != nil {
-				log.Printf("  Warning: could not write boundary for WOF %d: %v", rec.WOFID, err)
+			lr.skipped++
+		} else {
+			tz := rec.Timezone
+			if tz == "" {
+				tz = "UTC"
 			}
-		}
 
-		totalRecords++
-		if totalRecords%50000 == 0 {
-			log.Printf("  Read %d localities to disk...", totalRecords)
+			batch = append(batch, cityRecord{
+				continentID:  continentPGID,
+				countryID:    imp.wofParentID(rec, "country_id"),
+				regionID:     imp.wofParentID(rec, "region_id"),
+				districtID:   imp.wofParentID(rec, "county_id"),
+				name:         rec.Name,
+				lat:          rec.Latitude,
+				lng:          rec.Longitude,
+				tz:           tz,
+				population:   rec.Population,
+				wofID:        rec.WOFID,
+				lastModified: rec.LastModified,
+				supersedes:   rec.Supersedes,
+				names:        rec.Names,
+				geometry:     rec.Geometry,
+			})
 		}
-	}
-	tmpFile.Close()
-	namesFile.Close()
-	boundariesFile.Close()
-	log.Printf("  Read %d localities total (%d skipped)", totalRecords, skipped)
-
-	// Phase 2: Stream from disk, insert in batches (skip elevation to save memory)
-	// Elevation lookup will be done in a separate pass or on-demand via API
-	log.Println("  Phase 2: Inserting cities (elevation skipped to save memory)...")
-
-	readFile, err := os.Open(tmpPath)
-	if err != nil {
-		return 0, fmt.Errorf("reopen temp file: %w", err)
-	}
-	defer readFile.Close()
-
-	decoder := json.NewDecoder(readFile)
-	var count int
-	const batchSize = 5000 // Smaller batch to reduce memory
-	batch := make([][]interface{}, 0, batchSize)
-
-	for {
-		var diskRec cityRecordDisk
-		if err := decoder.Decode(&diskRec); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return 0, fmt.Errorf("read city from disk: %w", err)
-		}
-
-		// Skip elevation for now - will be looked up later or on-demand
-		// The SRTM library caches tiles in RAM which causes OOM with 4.5M cities
-		var elevation *int32 = nil
-
-		batch = append(batch, []interface{}{
-			diskRec.ContinentID,
-			diskRec.CountryID,
-			diskRec.RegionID,
-			diskRec.DistrictID,
-			diskRec.Name,
-			toASCII(diskRec.Name),
-			diskRec.Lat,
-			diskRec.Lng,
-			diskRec.Tz,
-			elevation,
-			nullInt32(diskRec.Population),
-			diskRec.WofID,
-		})
 
 		if len(batch) >= batchSize {
-			inserted, err := imp.insertCityBatch(ctx, batch)
-			if err != nil {
-				return 0, fmt.Errorf("batch insert cities: %w", err)
+			if err := imp.commitCityBatch(ctx, lr, batch, lastID); err != nil {
+				return err
 			}
-			count += inserted
 			batch = batch[:0] // Reset batch but keep capacity
-
-			if count%50000 == 0 {
-				log.Printf("  Inserted %d/%d cities...", count, totalRecords)
-			}
 		}
 	}
-
-	// Insert remaining batch
-	if len(batch) > 0 {
-		inserted, err := imp.insertCityBatch(ctx, batch)
-		if err != nil {
-			return 0, fmt.Errorf("batch insert cities: %w", err)
-		}
-		count += inserted
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	log.Printf("  Total cities inserted: %d", count)
-
-	// Phase 3: Insert city names from disk file
-	log.Println("  Phase 3: Inserting city name translations...")
-	if err := imp.insertCityNamesFromDisk(ctx, namesPath); err != nil {
-		log.Printf("  Warning: could not insert city names: %v", err)
+	// Commit the remaining batch, and checkpoint past any trailing skips
+	if len(batch) > 0 || lastID > lr.checkpoint {
+		if err := imp.commitCityBatch(ctx, lr, batch, lastID); err != nil {
+			return err
+		}
 	}
+	log.Printf("  Total cities imported: %d", lr.imported)
 
-	// Clear memory before Phase 4
-	runtime.GC()
-
-	// Phase 4: Insert city boundaries from disk file
-	log.Println("  Phase 4: Inserting city boundaries...")
-	if err := imp.insertCityBoundariesFromDisk(ctx, boundariesPath); err != nil {
-		log.Printf("  Warning: could not insert city boundaries: %v", err)
+	// Remove localities WOF has since deprecated, ceased or superseded
+	log.Println("  Retiring cities removed from WOF...")
+	retired, kept, err := imp.retireCities(ctx, lr)
+	if err != nil {
+		return err
 	}
+	log.Printf("  Retired %d cities (%d kept for publisher coverage)", retired, kept)
+	lr.notes = fmt.Sprintf("%d cities retired; %d retired in WOF but kept for publisher coverage", retired, kept)
 
 	// Validate hierarchy integrity after bulk import
 	log.Println("  Validating hierarchy integrity...")
@@ -1551,122 +1439,67 @@ func (imp *Importer) importCities(ctx context.Context) (int, error) {
 		defer validationRows.Close()
 		var integrityErrors int
 		for validationRows.Next() {
-			var cityID int64
+			var cityID string
 			var cityName, errorType, details string
 			if err := validationRows.Scan(&cityID, &cityName, &errorType, &details); err != nil {
 				continue
 			}
-			log.Printf("  INTEGRITY ERROR: city %d (%s) - %s: %s", cityID, cityName, errorType, details)
+			log.Printf("  INTEGRITY ERROR: city %s (%s) - %s: %s", cityID, cityName, errorType, details)
 			integrityErrors++
 		}
 		if integrityErrors > 0 {
-			return 0, fmt.Errorf("hierarchy integrity validation failed: %d errors found", integrityErrors)
+			return fmt.Errorf("hierarchy integrity validation failed: %d errors found", integrityErrors)
 		}
 		log.Println("  Hierarchy integrity OK")
 	}
 
-	return count, nil
+	return nil
 }
 
-// insertCityNamesFromDisk reads city names from disk file and inserts into geo_names
-func (imp *Importer) insertCityNamesFromDisk(ctx context.Context, namesPath string) error {
-	file, err := os.Open(namesPath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	const batchSize = 2000 // How many city name entries to process at once
-	var entries []cityNameEntry
-	var totalProcessed int
-
-	for {
-		var entry cityNameEntry
-		if err := decoder.Decode(&entry); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
-		entries = append(entries, entry)
-
-		if len(entries) >= batchSize {
-			if err := imp.insertCityGeoNames(ctx, entries); err != nil {
-				return err
-			}
-			totalProcessed += len(entries)
-			entries = entries[:0]
-
-			if totalProcessed%50000 == 0 {
-				log.Printf("    Processed %d city name entries...", totalProcessed)
-			}
+// wofParentID resolves one of a record's hierarchy parents to its PostgreSQL ID
+func (imp *Importer) wofParentID(rec *WOFRecord, key string) *int32 {
+	if wofID := rec.Hierarchy[key]; wofID > 0 {
+		if pgID, ok := imp.wofToPG[wofID]; ok {
+			v := int32(pgID)
+			return &v
 		}
 	}
-
-	// Insert remaining entries
-	if len(entries) > 0 {
-		if err := imp.insertCityGeoNames(ctx, entries); err != nil {
-			return err
-		}
-		totalProcessed += len(entries)
-	}
-
-	log.Printf("    Total city name entries processed: %d", totalProcessed)
 	return nil
 }
 
-// insertCityBoundariesFromDisk reads city boundaries from disk file and inserts into geo_city_boundaries
-func (imp *Importer) insertCityBoundariesFromDisk(ctx context.Context, boundariesPath string) error {
-	file, err := os.Open(boundariesPath)
+// commitCityBatch upserts a batch of cities with their names and boundaries,
+// then checkpoints at lastID
+func (imp *Importer) commitCityBatch(ctx context.Context, lr *levelRun, batch []cityRecord, lastID int64) error {
+	count, err := imp.upsertCityBatch(ctx, batch)
 	if err != nil {
-		return err
+		return fmt.Errorf("batch upsert cities: %w", err)
 	}
-	defer file.Close()
-
-	decoder := json.NewDecoder(file)
-	const batchSize = 500 // Smaller batch for geometry (larger data per record)
-	var entries []cityBoundaryEntry
-	var totalProcessed, totalInserted, totalErrors int
 
-	for {
-		var entry cityBoundaryEntry
-		if err := decoder.Decode(&entry); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
+	var names []cityNameEntry
+	var boundaries []cityBoundaryEntry
+	for _, c := range batch {
+		if len(c.names) > 0 {
+			names = append(names, cityNameEntry{WofID: c.wofID, Names: c.names})
 		}
-		entries = append(entries, entry)
-
-		if len(entries) >= batchSize {
-			inserted, errors, err := imp.insertCityBoundaries(ctx, entries)
-			if err != nil {
-				return err
-			}
-			totalInserted += inserted
-			totalErrors += errors
-			totalProcessed += len(entries)
-			entries = entries[:0]
-
-			if totalProcessed%10000 == 0 {
-				log.Printf("    Processed %d boundary entries (%d inserted, %d errors)...", totalProcessed, totalInserted, totalErrors)
-			}
+		if c.geometry != "" && c.geometry != "null" {
+			boundaries = append(boundaries, cityBoundaryEntry{WofID: c.wofID, Geometry: c.geometry})
 		}
 	}
-
-	// Insert remaining entries
-	if len(entries) > 0 {
-		inserted, errors, err := imp.insertCityBoundaries(ctx, entries)
-		if err != nil {
-			return err
-		}
-		totalInserted += inserted
-		totalErrors += errors
-		totalProcessed += len(entries)
+	if err := imp.insertCityGeoNames(ctx, names); err != nil {
+		return fmt.Errorf("insert city names: %w", err)
+	}
+	if _, _, err := imp.insertCityBoundaries(ctx, boundaries); err != nil {
+		return fmt.Errorf("insert city boundaries: %w", err)
 	}
 
-	log.Printf("    Total boundary entries processed: %d (inserted: %d, errors: %d)", totalProcessed, totalInserted, totalErrors)
+	before := lr.imported
+	lr.imported += count
+	if err := imp.saveCheckpoint(ctx, lr, lastID); err != nil {
+		return err
+	}
+	if lr.imported/50000 != before/50000 {
+		log.Printf("  Imported %d cities (through WOF %d)...", lr.imported, lastID)
+	}
 	return nil
 }
 
@@ -1762,19 +1595,108 @@ func (imp *Importer) insertCityBoundaries(ctx context.Context, entries []cityBou
 	return inserted, errorCount + skippedGeomType, nil
 }
 
-func (imp *Importer) insertCityBatch(ctx context.Context, batch [][]interface{}) (int, error) {
-	// Batch format: [continent_id, country_id, region_id, district_id, name, name_local, lat, lng, tz, elevation, population, wof_id]
-	// continent_id is required (anchor point), country/region/district are optional
-	// Trigger validates: country belongs to continent, region belongs to country, district belongs to region
-	count, err := imp.pgPool.CopyFrom(
-		ctx,
-		pgx.Identifier{"geo_cities"},
+// upsertCityBatch inserts or updates a batch of cities by wof_id in one
+// transaction. Existing rows are updated in place and a superseding locality
+// takes over the row of the one it replaces, so city IDs never change.
+func (imp *Importer) upsertCityBatch(ctx context.Context, batch []cityRecord) (int, error) {
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	tx, err := imp.pgPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE wof_city_stage (
+			continent_id smallint,
+			country_id integer,
+			region_id integer,
+			district_id integer,
+			name text,
+			name_ascii text,
+			latitude double precision,
+			longitude double precision,
+			timezone text,
+			population integer,
+			wof_id bigint,
+			wof_lastmodified bigint,
+			supersedes bigint[]
+		) ON COMMIT DROP
+	`); err != nil {
+		return 0, fmt.Errorf("create stage table: %w", err)
+	}
+
+	rows := make([][]interface{}, 0, len(batch))
+	for _, c := range batch {
+		rows = append(rows, []interface{}{
+			c.continentID, c.countryID, c.regionID, c.districtID, c.name, toASCII(c.name),
+			c.lat, c.lng, c.tz, nullInt32(c.population), c.wofID, c.lastModified, c.supersedes,
+		})
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"wof_city_stage"},
 		[]string{"continent_id", "country_id", "region_id", "district_id", "name", "name_ascii",
-			"latitude", "longitude", "timezone", "elevation_m", "population", "wof_id"},
-		pgx.CopyFromRows(batch),
-	)
+			"latitude", "longitude", "timezone", "population", "wof_id", "wof_lastmodified", "supersedes"},
+		pgx.CopyFromRows(rows)); err != nil {
+		return 0, fmt.Errorf("stage cities: %w", err)
+	}
+
+	// A new locality that supersedes imported ones takes over one of their
+	// rows (each row moves at most once) instead of getting a new ID
+	if _, err := tx.Exec(ctx, `
+		UPDATE geo_cities c SET wof_id = m.wof_id
+		FROM (
+			SELECT DISTINCT ON (wof_id) wof_id, city_id
+			FROM (
+				SELECT DISTINCT ON (old.id) st.wof_id, old.id AS city_id
+				FROM wof_city_stage st
+				CROSS JOIN LATERAL unnest(st.supersedes) AS s(old_wof_id)
+				JOIN geo_cities old ON old.wof_id = s.old_wof_id
+				WHERE NOT EXISTS (SELECT 1 FROM geo_cities cur WHERE cur.wof_id = st.wof_id)
+				ORDER BY old.id, st.wof_id
+			) candidates
+			ORDER BY wof_id, city_id
+		) m
+		WHERE c.id = m.city_id
+	`); err != nil {
+		return 0, fmt.Errorf("apply supersedes: %w", err)
+	}
+
+	// Cities that moved lose their elevation so the elevation command looks
+	// it up again
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO geo_cities (continent_id, country_id, region_id, district_id, name, name_ascii,
+			latitude, longitude, timezone, elevation_m, population, wof_id, wof_lastmodified)
+		SELECT continent_id, country_id, region_id, district_id, name, name_ascii,
+			latitude, longitude, timezone, NULL, population, wof_id, wof_lastmodified
+		FROM wof_city_stage
+		ON CONFLICT (wof_id) DO UPDATE SET
+			continent_id = EXCLUDED.continent_id,
+			country_id = EXCLUDED.country_id,
+			region_id = EXCLUDED.region_id,
+			district_id = EXCLUDED.district_id,
+			name = EXCLUDED.name,
+			name_ascii = EXCLUDED.name_ascii,
+			latitude = EXCLUDED.latitude,
+			longitude = EXCLUDED.longitude,
+			timezone = EXCLUDED.timezone,
+			elevation_m = CASE
+				WHEN geo_cities.latitude = EXCLUDED.latitude AND geo_cities.longitude = EXCLUDED.longitude
+				THEN geo_cities.elevation_m
+			END,
+			population = COALESCE(EXCLUDED.population, geo_cities.population),
+			wof_lastmodified = EXCLUDED.wof_lastmodified
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("upsert cities: %w", err)
+	}
 
-	return int(count), err
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
 }
 
 // getContinentID looks up the continent for a country using WOF hierarchy.
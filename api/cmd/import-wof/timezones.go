@@ -0,0 +1,216 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// TIMEZONES
+// =============================================================================
+
+const (
+	timezoneReleaseURL     = "https://github.com/evansiroky/timezone-boundary-builder/releases/download/%s/timezones-with-oceans.geojson.zip"
+	defaultTimezoneVersion = "2024a"
+	defaultTimezoneDir     = "data/timezones"
+)
+
+// timezoneFeature is one GeoJSON feature of the timezone-boundary-builder release
+type timezoneFeature struct {
+	Properties struct {
+		TZID string `json:"tzid"`
+	} `json:"properties"`
+	Geometry json.RawMessage `json:"geometry"`
+}
+
+// cmdTimezones downloads the timezone-boundary-builder release and replaces
+// geo_timezone_boundaries with its polygons
+func cmdTimezones(args []string) {
+	version := defaultTimezoneVersion
+	var file string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--version" && i+1 < len(args):
+			version = args[i+1]
+			i++
+		case args[i] == "--file" && i+1 < len(args):
+			file = args[i+1]
+			i++
+		}
+	}
+
+	pgURL := os.Getenv("DATABASE_URL")
+	if pgURL == "" {
+		log.Fatal("DATABASE_URL required")
+	}
+
+	if file == "" {
+		file = filepath.Join(defaultTimezoneDir, fmt.Sprintf("timezones-with-oceans-%s.geojson.zip", version))
+		if _, err := os.Stat(file); err != nil {
+			url := fmt.Sprintf(timezoneReleaseURL, version)
+			fmt.Printf("Downloading timezone boundaries %s...\n", version)
+			fmt.Printf("Source: %s\n", url)
+			if err := os.MkdirAll(defaultTimezoneDir, 0755); err != nil {
+				log.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := downloadFile(url, file); err != nil {
+				log.Fatalf("Download failed: %v", err)
+			}
+		}
+	}
+
+	ctx := context.Background()
+	pgPool, err := pgxpool.New(ctx, pgURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer pgPool.Close()
+
+	start := time.Now()
+	count, err := importTimezones(ctx, pgPool, file, version)
+	if err != nil {
+		log.Fatalf("Timezone import failed: %v", err)
+	}
+	log.Printf("Timezone import complete: %d zones in %s", count, time.Since(start).Round(time.Second))
+}
+
+// importTimezones replaces the timezone boundaries in one transaction, so
+// lookups keep working against the old polygons until the import commits
+func importTimezones(ctx context.Context, pool *pgxpool.Pool, file, version string) (int, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	var entry *zip.File
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, ".json") || strings.HasSuffix(f.Name, ".geojson") {
+			entry = f
+			break
+		}
+	}
+	if entry == nil {
+		return 0, fmt.Errorf("no GeoJSON file in %s", file)
+	}
+	rc, err := entry.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM geo_timezone_boundaries"); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = decodeFeatures(rc, func(f timezoneFeature) error {
+		if f.Properties.TZID == "" {
+			return nil
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO geo_timezone_boundaries (tzid, boundary)
+			VALUES ($1, ST_Multi(ST_MakeValid(ST_GeomFromGeoJSON($2)))::geography)
+		`, f.Properties.TZID, string(f.Geometry))
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Properties.TZID, err)
+		}
+		count++
+		if count%50 == 0 {
+			log.Printf("  %d zones...", count)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := recordTimezoneImport(ctx, tx, version, count); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit(ctx)
+}
+
+// decodeFeatures streams the features of a GeoJSON FeatureCollection; the
+// release is several hundred MB, so it is not decoded in one piece
+func decodeFeatures(r io.Reader, fn func(timezoneFeature) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("no features array: %w", err)
+		}
+		if key, ok := tok.(string); ok && key == "features" {
+			break
+		}
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return fmt.Errorf("features is not an array")
+	}
+	for dec.More() {
+		var f timezoneFeature
+		if err := dec.Decode(&f); err != nil {
+			return err
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordTimezoneImport(ctx context.Context, tx pgx.Tx, version string, count int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO geo_boundary_imports (source, level, version, records_imported, records_matched, notes)
+		VALUES ('timezone-boundary-builder', 'timezone', $1, $2, $2, 'timezones-with-oceans')
+	`, version, count)
+	return err
+}
+
+// downloadFile fetches url to destPath via a temporary file
+func downloadFile(url, destPath string) error {
+	tmpPath := destPath + ".tmp"
+	defer os.Remove(tmpPath)
+
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	pr := &progressReader{r: resp.Body, total: resp.ContentLength, start: time.Now()}
+	if _, err := io.Copy(out, pr); err != nil {
+		return err
+	}
+
+	out.Close()
+	return os.Rename(tmpPath, destPath)
+}
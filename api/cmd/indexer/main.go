@@ -11,6 +11,8 @@
 //
 //	DATABASE_URL - PostgreSQL connection string
 //	OPENAI_API_KEY - OpenAI API key for embeddings
+//	OPENAI_BASE_URL, EMBEDDING_MODEL - optional endpoint/model overrides
+//	EMBEDDING_PROVIDER=fake - use deterministic in-process embeddings (CI)
 //
 // When to run:
 //
@@ -51,6 +53,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/validation"
 	pgvector "github.com/pgvector/pgvector-go"
 )
 
@@ -66,13 +69,17 @@ func main() {
 
 	// Get environment variables
 	databaseURL := os.Getenv("DATABASE_URL")
-	openAIKey := os.Getenv("OPENAI_API_KEY")
 
 	if databaseURL == "" {
 		log.Fatal("DATABASE_URL environment variable is required")
 	}
-	if openAIKey == "" {
-		log.Fatal("OPENAI_API_KEY environment variable is required")
+
+	embeddings, err := ai.EmbedderFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure embedding provider: %v", err)
+	}
+	if embeddings == nil {
+		log.Fatalf("Embedding provider %s has no API key: OPENAI_API_KEY is required", ai.EmbeddingProviderName())
 	}
 
 	// Connect to database
@@ -83,7 +90,6 @@ func main() {
 	defer pool.Close()
 
 	// Initialize services
-	embeddings := ai.NewEmbeddingService(openAIKey)
 	chunker := ai.NewChunker()
 
 	// Define documents to index
@@ -203,7 +209,7 @@ func findProjectRoot() string {
 	}
 }
 
-func indexDocument(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.EmbeddingService, chunker *ai.Chunker, source DocumentSource) (int, int, error) {
+func indexDocument(ctx context.Context, pool *pgxpool.Pool, embeddings ai.Embedder, chunker *ai.Chunker, source DocumentSource) (int, int, error) {
 	// Read file
 	content, err := os.ReadFile(source.Path)
 	if err != nil {
@@ -253,9 +259,9 @@ func indexDocument(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.Embed
 		for j, chunk := range validChunks {
 			vec := pgvector.NewVector(embeds[j])
 			_, err := pool.Exec(ctx, `
-				INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding)
-				VALUES ($1, $2, $3, $4, $5, $6)
-			`, chunk.Content, source.Source, source.ContentType, chunk.Index, chunk.Metadata, vec)
+				INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding, search_text)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, chunk.Content, source.Source, source.ContentType, chunk.Index, chunk.Metadata, vec, validation.NormalizeHebrew(chunk.Content))
 			if err != nil {
 				return 0, 0, fmt.Errorf("failed to insert embedding: %w", err)
 			}
@@ -268,7 +274,7 @@ func indexDocument(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.Embed
 	return len(chunks), totalTokens, nil
 }
 
-func indexMasterZmanim(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.EmbeddingService, chunker *ai.Chunker) (int, int, error) {
+func indexMasterZmanim(ctx context.Context, pool *pgxpool.Pool, embeddings ai.Embedder, chunker *ai.Chunker) (int, int, error) {
 	// Query master zmanim registry
 	rows, err := pool.Query(ctx, `
 		SELECT zman_key, canonical_hebrew_name, canonical_english_name, time_category, description, default_formula_dsl
@@ -319,9 +325,9 @@ func indexMasterZmanim(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.E
 
 		vec := pgvector.NewVector(embed)
 		_, err = pool.Exec(ctx, `
-			INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding)
-			VALUES ($1, $2, $3, $4, $5, $6)
-		`, chunk.Content, "master-registry", "reference", chunk.Index, chunk.Metadata, vec)
+			INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding, search_text)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, chunk.Content, "master-registry", "reference", chunk.Index, chunk.Metadata, vec, validation.NormalizeHebrew(chunk.Content))
 		if err != nil {
 			return 0, 0, fmt.Errorf("failed to insert embedding: %w", err)
 		}
@@ -332,7 +338,7 @@ func indexMasterZmanim(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.E
 	return len(chunks), totalTokens, nil
 }
 
-func indexDSLExamples(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.EmbeddingService, chunker *ai.Chunker) (int, int, error) {
+func indexDSLExamples(ctx context.Context, pool *pgxpool.Pool, embeddings ai.Embedder, chunker *ai.Chunker) (int, int, error) {
 	// Create example content
 	examples := `# DSL Formula Examples
 
@@ -483,9 +489,9 @@ Major fasts (Yom Kippur, Tisha B'Av) begin at sunset the night before.
 
 		vec := pgvector.NewVector(embed)
 		_, err = pool.Exec(ctx, `
-			INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding)
-			VALUES ($1, $2, $3, $4, $5, $6)
-		`, chunk.Content, "dsl-examples", "example", chunk.Index, chunk.Metadata, vec)
+			INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding, search_text)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, chunk.Content, "dsl-examples", "example", chunk.Index, chunk.Metadata, vec, validation.NormalizeHebrew(chunk.Content))
 		if err != nil {
 			return 0, 0, fmt.Errorf("failed to insert embedding: %w", err)
 		}
@@ -506,7 +512,7 @@ func cloneRepo(repoURL, destDir string) error {
 }
 
 // indexKosherJava clones the KosherJava zmanim library and indexes its documentation
-func indexKosherJava(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.EmbeddingService, chunker *ai.Chunker, tempDir string) (int, int, error) {
+func indexKosherJava(ctx context.Context, pool *pgxpool.Pool, embeddings ai.Embedder, chunker *ai.Chunker, tempDir string) (int, int, error) {
 	repoDir := filepath.Join(tempDir, "KosherJava")
 
 	log.Println("   Cloning KosherJava zmanim library...")
@@ -614,7 +620,7 @@ func extractMethodName(signature string) string {
 }
 
 // indexHebcalGo clones the hebcal-go library and indexes its documentation
-func indexHebcalGo(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.EmbeddingService, chunker *ai.Chunker, tempDir string) (int, int, error) {
+func indexHebcalGo(ctx context.Context, pool *pgxpool.Pool, embeddings ai.Embedder, chunker *ai.Chunker, tempDir string) (int, int, error) {
 	repoDir := filepath.Join(tempDir, "hebcal-go")
 
 	log.Println("   Cloning hebcal-go library...")
@@ -805,7 +811,7 @@ func extractGoTypeName(signature string) string {
 }
 
 // indexContent is a helper to index arbitrary content
-func indexContent(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.EmbeddingService, chunker *ai.Chunker, content, source, contentType string) (int, int, error) {
+func indexContent(ctx context.Context, pool *pgxpool.Pool, embeddings ai.Embedder, chunker *ai.Chunker, content, source, contentType string) (int, int, error) {
 	chunks := chunker.ChunkDocument(content, source, contentType)
 	if len(chunks) == 0 {
 		return 0, 0, nil
@@ -848,9 +854,9 @@ func indexContent(ctx context.Context, pool *pgxpool.Pool, embeddings *ai.Embedd
 		for j, chunk := range validChunks {
 			vec := pgvector.NewVector(embeds[j])
 			_, err := pool.Exec(ctx, `
-				INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding)
-				VALUES ($1, $2, $3, $4, $5, $6)
-			`, chunk.Content, source, contentType, chunk.Index, chunk.Metadata, vec)
+				INSERT INTO embeddings (content, source, content_type, chunk_index, metadata, embedding, search_text)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, chunk.Content, source, contentType, chunk.Index, chunk.Metadata, vec, validation.NormalizeHebrew(chunk.Content))
 			if err != nil {
 				return 0, 0, fmt.Errorf("failed to insert embedding: %w", err)
 			}
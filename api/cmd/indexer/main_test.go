@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+)
+
+// TestIndexDocumentWithFakeEmbeddings indexes a document into the database
+// named by TEST_DATABASE_URL with EMBEDDING_PROVIDER=fake, and finds it again
+// through hybrid search
+func TestIndexDocumentWithFakeEmbeddings(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	const source = "indexer-test"
+	clear := func() { _, _ = pool.Exec(ctx, `DELETE FROM embeddings WHERE source = $1`, source) }
+	clear()
+	t.Cleanup(clear)
+
+	t.Setenv("EMBEDDING_PROVIDER", "fake")
+	embeddings, err := ai.EmbedderFromEnv()
+	if err != nil || embeddings == nil {
+		t.Fatalf("EmbedderFromEnv() = %v, %v", embeddings, err)
+	}
+
+	var doc strings.Builder
+	doc.WriteString("# Bein hashmashos\n\n")
+	for i := 0; i < 40; i++ {
+		doc.WriteString("Bein hashmashos of Rabbeinu Yonah begins at shkia and lasts three quarters of a mil. ")
+	}
+	doc.WriteString("\n\n# Plag hamincha\n\nPlag hamincha is one and a quarter proportional hours before nightfall.\n")
+	path := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(path, []byte(doc.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, tokens, err := indexDocument(ctx, pool, embeddings, ai.NewChunker(), DocumentSource{
+		Path: path, Source: source, ContentType: "documentation",
+	})
+	if err != nil {
+		t.Fatalf("indexDocument() error: %v", err)
+	}
+	if chunks < 2 || tokens == 0 {
+		t.Errorf("indexDocument() = %d chunks, %d tokens", chunks, tokens)
+	}
+
+	var rows, dims int
+	if err := pool.QueryRow(ctx, `
+		SELECT count(*), COALESCE(max(vector_dims(embedding)), 0) FROM embeddings WHERE source = $1
+	`, source).Scan(&rows, &dims); err != nil {
+		t.Fatal(err)
+	}
+	if rows != chunks || dims != ai.EmbeddingDimensions {
+		t.Errorf("stored %d rows of %d dimensions, want %d of %d", rows, dims, chunks, ai.EmbeddingDimensions)
+	}
+
+	results, err := ai.NewSearchService(pool, embeddings).SearchSimilarBySource(ctx, "plag hamincha proportional hours", source, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !strings.Contains(results[0].Content, "Plag hamincha") {
+		t.Errorf("search results = %+v, want the plag hamincha chunk", results)
+	}
+}
@@ -12,29 +12,148 @@ import (
 	"time"
 )
 
-// ClaudeService provides Claude AI integration for formula generation
+// Anthropic API defaults
+const (
+	DefaultAnthropicBaseURL = "https://api.anthropic.com"
+	DefaultAnthropicModel   = "claude-sonnet-4-20250514"
+)
+
+// ClaudeService provides formula generation and explanation on top of an LLM
+// (Claude by default)
 type ClaudeService struct {
+	llm LLM
+}
+
+// NewClaudeService creates a new Claude service backed by the Anthropic API
+func NewClaudeService(apiKey string) *ClaudeService {
+	return NewClaudeServiceWithLLM(NewAnthropicLLM(AnthropicConfig{APIKey: apiKey}))
+}
+
+// NewClaudeServiceWithLLM creates a service backed by any LLM provider
+func NewClaudeServiceWithLLM(llm LLM) *ClaudeService {
+	return &ClaudeService{llm: llm}
+}
+
+// Model returns the underlying model name
+func (s *ClaudeService) Model() string {
+	return s.llm.Model()
+}
+
+// AnthropicConfig configures the Anthropic Messages API client
+type AnthropicConfig struct {
+	APIKey  string
+	BaseURL string // defaults to DefaultAnthropicBaseURL
+	Model   string // defaults to DefaultAnthropicModel
+	Timeout time.Duration
+}
+
+// AnthropicLLM implements LLM using the Anthropic Messages API
+type AnthropicLLM struct {
 	apiKey     string
+	baseURL    string
 	model      string
 	httpClient *http.Client
 }
 
-// NewClaudeService creates a new Claude service
-func NewClaudeService(apiKey string) *ClaudeService {
-	return &ClaudeService{
-		apiKey: apiKey,
-		model:  "claude-sonnet-4-20250514",
+// NewAnthropicLLM creates an Anthropic client, filling in defaults
+func NewAnthropicLLM(cfg AnthropicConfig) *AnthropicLLM {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultAnthropicBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultAnthropicModel
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	return &AnthropicLLM{
+		apiKey:  cfg.APIKey,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		model:   cfg.Model,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout: cfg.Timeout,
 		},
 	}
 }
 
+// Model returns the configured model name
+func (c *AnthropicLLM) Model() string {
+	return c.model
+}
+
+// Complete sends a request to the Messages API
+func (c *AnthropicLLM) Complete(ctx context.Context, creq CompletionRequest) (*Completion, error) {
+	reqBody := claudeRequest{
+		Model:     c.model,
+		MaxTokens: creq.MaxTokens,
+		System:    creq.System,
+		Messages:  make([]claudeMessage, len(creq.Messages)),
+	}
+	for i, m := range creq.Messages {
+		reqBody.Messages[i] = toClaudeMessage(m)
+	}
+	for _, t := range creq.Tools {
+		reqBody.Tools = append(reqBody.Tools, claudeTool(t))
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Claude API error: status %d", resp.StatusCode)
+	}
+
+	var claudeResp claudeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(claudeResp.Content) == 0 {
+		return nil, errors.New("no content in response")
+	}
+
+	completion := &Completion{
+		InputTokens:  claudeResp.Usage.InputTokens,
+		OutputTokens: claudeResp.Usage.OutputTokens,
+		StopReason:   claudeResp.StopReason,
+	}
+	var text []string
+	for _, block := range claudeResp.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			completion.ToolCalls = append(completion.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
+	}
+	completion.Text = strings.Join(text, "\n")
+
+	return completion, nil
+}
+
 // GenerationResult represents the result of formula generation
 type GenerationResult struct {
 	Formula    string  `json:"formula"`
 	Confidence float64 `json:"confidence"`
 	TokensUsed int     `json:"tokens_used"`
+	Attempts   int     `json:"attempts,omitempty"`
 }
 
 // ExplainResult represents the result of formula explanation
@@ -50,25 +169,65 @@ type claudeRequest struct {
 	MaxTokens int             `json:"max_tokens"`
 	System    string          `json:"system,omitempty"`
 	Messages  []claudeMessage `json:"messages"`
+	Tools     []claudeTool    `json:"tools,omitempty"`
 }
 
+// claudeMessage content is a plain string, or content blocks when the turn
+// carries tool calls or results
 type claudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type claudeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type claudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
 }
 
 type claudeResponse struct {
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	Usage struct {
+	Content []claudeBlock `json:"content"`
+	Usage   struct {
 		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
 	StopReason string `json:"stop_reason"`
 }
 
+// toClaudeMessage converts a provider-neutral message to the Messages API shape
+func toClaudeMessage(m Message) claudeMessage {
+	if len(m.ToolCalls) == 0 && len(m.ToolResults) == 0 {
+		return claudeMessage{Role: m.Role, Content: m.Content}
+	}
+
+	var blocks []claudeBlock
+	for _, r := range m.ToolResults {
+		blocks = append(blocks, claudeBlock{Type: "tool_result", ToolUseID: r.CallID, Content: r.Content, IsError: r.IsError})
+	}
+	if m.Content != "" {
+		blocks = append(blocks, claudeBlock{Type: "text", Text: m.Content})
+	}
+	for _, c := range m.ToolCalls {
+		input := c.Input
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		blocks = append(blocks, claudeBlock{Type: "tool_use", ID: c.ID, Name: c.Name, Input: input})
+	}
+	return claudeMessage{Role: m.Role, Content: blocks}
+}
+
 // System prompt for formula generation
 const formulaSystemPrompt = `You are an expert in Jewish prayer times (zmanim) and the Zmanim DSL language.
 
@@ -118,63 +277,34 @@ func (s *ClaudeService) GenerateFormula(ctx context.Context, request string, rag
 		systemPrompt += "\n\n## Additional Context\n\n" + ragContext
 	}
 
-	reqBody := claudeRequest{
-		Model:     s.model,
-		MaxTokens: 256,
+	completion, err := s.llm.Complete(ctx, CompletionRequest{
 		System:    systemPrompt,
-		Messages: []claudeMessage{
-			{Role: "user", Content: request},
-		},
-	}
-
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := s.httpClient.Do(req)
+		Messages:  []Message{{Role: "user", Content: request}},
+		MaxTokens: 256,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Claude API error: status %d", resp.StatusCode)
+		return nil, err
 	}
 
-	var claudeResp claudeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(claudeResp.Content) == 0 {
-		return nil, errors.New("no content in response")
-	}
-
-	responseText := claudeResp.Content[0].Text
+	responseText := completion.Text
 	formula := extractFormula(responseText)
 	confidence := estimateConfidence(responseText)
 
 	return &GenerationResult{
 		Formula:    formula,
 		Confidence: confidence,
-		TokensUsed: claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens,
+		TokensUsed: completion.TokensUsed(),
 	}, nil
 }
 
-// GenerateWithValidation generates a formula with automatic validation and retry
+// GenerateWithValidation generates a formula with automatic validation and retry.
+// The result reports the attempts made and the tokens used across all of them;
+// on failure it is the last formula generated (nil if no attempt succeeded).
 func (s *ClaudeService) GenerateWithValidation(ctx context.Context, request string, ragContext string, validateFn func(string) error) (*GenerationResult, error) {
 	maxRetries := 2
 	var lastErr error
+	var last *GenerationResult
+	tokensUsed := 0
 
 	for i := 0; i <= maxRetries; i++ {
 		result, err := s.GenerateFormula(ctx, request, ragContext)
@@ -182,10 +312,14 @@ func (s *ClaudeService) GenerateWithValidation(ctx context.Context, request stri
 			lastErr = err
 			continue
 		}
+		tokensUsed += result.TokensUsed
+		result.TokensUsed = tokensUsed
+		result.Attempts = i + 1
+		last = result
 
 		// Check for unsupported
 		if strings.HasPrefix(result.Formula, "UNSUPPORTED:") {
-			return nil, errors.New(result.Formula)
+			return result, errors.New(result.Formula)
 		}
 
 		// Validate formula
@@ -207,12 +341,13 @@ Original request: %s`, result.Formula, validationErr, request)
 				lastErr = validationErr
 				continue
 			}
+			lastErr = validationErr
 		} else {
 			return result, nil
 		}
 	}
 
-	return nil, fmt.Errorf("failed to generate valid formula after retries: %v", lastErr)
+	return last, fmt.Errorf("failed to generate valid formula after retries: %v", lastErr)
 }
 
 // ExplainFormula generates a human-readable explanation of a formula
@@ -253,50 +388,17 @@ Example: "72 minutes before netz per the Magen Avraham (SA OC 233). Fixed offset
 		systemPrompt += "\n\n## Reference Material\n\n" + ragContext
 	}
 
-	reqBody := claudeRequest{
-		Model:     s.model,
-		MaxTokens: 300,
+	completion, err := s.llm.Complete(ctx, CompletionRequest{
 		System:    systemPrompt,
-		Messages: []claudeMessage{
-			{Role: "user", Content: fmt.Sprintf("Explain this zman calculation: %s", formula)},
-		},
-	}
-
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := s.httpClient.Do(req)
+		Messages:  []Message{{Role: "user", Content: fmt.Sprintf("Explain this zman calculation: %s", formula)}},
+		MaxTokens: 300,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Claude API error: status %d", resp.StatusCode)
-	}
-
-	var claudeResp claudeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&claudeResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	if len(claudeResp.Content) == 0 {
-		return nil, errors.New("no content in response")
+		return nil, err
 	}
 
 	return &ExplainResult{
-		Explanation: claudeResp.Content[0].Text,
+		Explanation: completion.Text,
 		Source:      "ai",
 		Language:    language,
 	}, nil
@@ -55,7 +55,10 @@ func (s *ContextService) AssembleContext(ctx context.Context, query string, opts
 	}
 
 	// Search for relevant chunks
-	results, err := s.search.SearchSimilar(ctx, query, opts.MaxDocs*3)
+	results, err := s.search.Search(ctx, query, SearchOptions{
+		TopK:    opts.MaxDocs * 3,
+		Sources: opts.FilterSources,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to search: %w", err)
 	}
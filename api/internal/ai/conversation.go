@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxToolRounds caps how many tool-call round trips a single turn may take
+const MaxToolRounds = 6
+
+// ErrTooManyToolRounds is returned when the model keeps calling tools
+var ErrTooManyToolRounds = errors.New("assistant exceeded the tool call limit")
+
+// ToolExecutor runs a tool call and returns its output for the model. An
+// error is reported back to the model as a failed tool result, not to the caller.
+type ToolExecutor func(ctx context.Context, call ToolCall) (string, error)
+
+// TurnResult is the outcome of one user message in a conversation
+type TurnResult struct {
+	Messages   []Message  // messages appended to the history this turn, starting with the user's
+	Reply      string     // the assistant's final text
+	Formula    string     // formula proposed in the reply, if any
+	ToolCalls  []ToolCall // tools the assistant ran, in order
+	TokensUsed int
+}
+
+// System prompt for the multi-turn formula builder
+const conversationSystemPrompt = `You are helping a publisher of Jewish prayer times (zmanim) build and refine one formula in the Zmanim DSL over several messages.
+
+## DSL Syntax Reference
+
+Primitives: sunrise, sunset, solar_noon, solar_midnight, visible_sunrise, visible_sunset, visible_sunrise_terrain, visible_sunset_terrain (over mountains; needs terrain data), civil_dawn, civil_dusk, nautical_dawn, nautical_dusk, astronomical_dawn, astronomical_dusk, moonrise, moonset (none on about one day a month)
+
+Functions:
+- solar(degrees, direction) - Direction: before_sunrise or after_sunset
+- proportional_hours(hours, base) - Base: gra, mga, mga_90, mga_120 or custom(start, end)
+- midpoint(time1, time2)
+
+Operators: + and - with durations (Nmin, Nh). References: @zman_key.
+Conditionals: if (condition) { formula } else { formula }, with conditions on latitude, longitude, month, day_length, season (e.g. season == "summer"), moon_illumination (0-1 at sunset) and moon_phase (new, waxing_crescent, first_quarter, waxing_gibbous, full, waning_gibbous, last_quarter, waning_crescent), combined with && || !. Times can be compared (e.g. moonrise < sunset).
+
+## Tools
+
+- validate_formula: check a formula before you propose it
+- preview_formula: compute a formula for dates and a city, to answer "what would that give in ..." questions
+- lookup_registry: find standard zmanim and their default formulas
+
+## Instructions
+
+1. Each message refines the current formula; keep everything the publisher has not asked to change.
+2. Always validate a formula before proposing it.
+3. When proposing a formula, put it (and only it) in a single triple-backtick block.
+4. Answer preview questions with the computed times, not estimates.
+5. Keep replies to 2-4 sentences. If something cannot be expressed in the DSL, say so.`
+
+// Converse runs one conversational turn: it sends the history plus the new
+// user message, executes any tools the model calls, and returns once the model
+// replies with text. The caller persists TurnResult.Messages.
+func (s *ClaudeService) Converse(ctx context.Context, history []Message, userMessage string, ragContext string, tools []ToolSpec, exec ToolExecutor) (*TurnResult, error) {
+	systemPrompt := conversationSystemPrompt
+	if ragContext != "" {
+		systemPrompt += "\n\n## Reference Material\n\n" + ragContext
+	}
+
+	turn := &TurnResult{Messages: []Message{{Role: "user", Content: userMessage}}}
+	messages := append(append([]Message(nil), history...), turn.Messages[0])
+
+	for round := 0; ; round++ {
+		completion, err := s.llm.Complete(ctx, CompletionRequest{
+			System:    systemPrompt,
+			Messages:  messages,
+			Tools:     tools,
+			MaxTokens: 1024,
+		})
+		if err != nil {
+			return turn, err
+		}
+		turn.TokensUsed += completion.TokensUsed()
+
+		assistant := Message{Role: "assistant", Content: completion.Text, ToolCalls: completion.ToolCalls}
+		messages = append(messages, assistant)
+		turn.Messages = append(turn.Messages, assistant)
+
+		if len(completion.ToolCalls) == 0 {
+			turn.Reply = completion.Text
+			if strings.Contains(completion.Text, "```") {
+				turn.Formula = extractFormula(completion.Text)
+			}
+			return turn, nil
+		}
+		if round >= MaxToolRounds {
+			return turn, ErrTooManyToolRounds
+		}
+
+		results := Message{Role: "user"}
+		for _, call := range completion.ToolCalls {
+			turn.ToolCalls = append(turn.ToolCalls, call)
+			output, err := exec(ctx, call)
+			if err != nil {
+				results.ToolResults = append(results.ToolResults, ToolResult{CallID: call.ID, Content: fmt.Sprintf("error: %v", err), IsError: true})
+				continue
+			}
+			results.ToolResults = append(results.ToolResults, ToolResult{CallID: call.ID, Content: output})
+		}
+		messages = append(messages, results)
+		turn.Messages = append(turn.Messages, results)
+	}
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConverseRunsToolsUntilReply(t *testing.T) {
+	fake := NewFakeLLM().
+		EnqueueToolCall("validate_formula", map[string]string{"formula": "solar(18, before_sunrise)"}).
+		Enqueue("Changed to 18 degrees:\n```\nsolar(18, before_sunrise)\n```")
+	svc := NewClaudeServiceWithLLM(fake)
+
+	var executed []string
+	exec := func(ctx context.Context, call ToolCall) (string, error) {
+		executed = append(executed, call.Name+" "+string(call.Input))
+		return "ok", nil
+	}
+	history := []Message{
+		{Role: "user", Content: "alos at 16.1 degrees"},
+		{Role: "assistant", Content: "```\nsolar(16.1, before_sunrise)\n```"},
+	}
+
+	turn, err := svc.Converse(context.Background(), history, "make it 18 degrees", "", nil, exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn.Formula != "solar(18, before_sunrise)" {
+		t.Errorf("formula = %q", turn.Formula)
+	}
+	if len(executed) != 1 || executed[0] != `validate_formula {"formula":"solar(18, before_sunrise)"}` {
+		t.Errorf("executed = %v", executed)
+	}
+	// user, assistant tool call, tool result, assistant reply
+	if len(turn.Messages) != 4 || turn.Messages[0].Content != "make it 18 degrees" {
+		t.Fatalf("messages = %+v", turn.Messages)
+	}
+	if res := turn.Messages[2].ToolResults; len(res) != 1 || res[0].CallID != "toolu_fake_1" || res[0].Content != "ok" {
+		t.Errorf("tool results = %+v", res)
+	}
+	if turn.TokensUsed == 0 {
+		t.Error("tokens not counted")
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 || len(calls[0].Messages) != 3 || len(calls[1].Messages) != 5 {
+		t.Errorf("history not threaded: %d calls", len(calls))
+	}
+}
+
+func TestConverseReportsToolErrorsToModel(t *testing.T) {
+	fake := NewFakeLLM().
+		EnqueueToolCall("preview_formula", map[string]string{"city": "Atlantis"}).
+		Enqueue("I could not find that city.")
+	svc := NewClaudeServiceWithLLM(fake)
+
+	exec := func(ctx context.Context, call ToolCall) (string, error) {
+		return "", errors.New(`city "Atlantis" not found`)
+	}
+	turn, err := svc.Converse(context.Background(), nil, "what about Atlantis?", "", nil, exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := turn.Messages[2].ToolResults
+	if len(res) != 1 || !res[0].IsError || !strings.Contains(res[0].Content, "Atlantis") {
+		t.Errorf("tool results = %+v", res)
+	}
+	if turn.Formula != "" {
+		t.Errorf("formula = %q", turn.Formula)
+	}
+}
+
+func TestConverseStopsRunawayToolLoops(t *testing.T) {
+	fake := NewFakeLLM()
+	for i := 0; i <= MaxToolRounds; i++ {
+		fake.EnqueueToolCall("lookup_registry", map[string]string{"query": "alos"})
+	}
+	svc := NewClaudeServiceWithLLM(fake)
+
+	exec := func(ctx context.Context, call ToolCall) (string, error) { return "none", nil }
+	if _, err := svc.Converse(context.Background(), nil, "alos", "", nil, exec); !errors.Is(err, ErrTooManyToolRounds) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestAnthropicLLMToolUse(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"validate_formula","input":{"formula":"sunset + 42min"}}],"usage":{"input_tokens":10,"output_tokens":5},"stop_reason":"tool_use"}`))
+	}))
+	defer srv.Close()
+
+	llm := NewAnthropicLLM(AnthropicConfig{APIKey: "k", BaseURL: srv.URL})
+	completion, err := llm.Complete(context.Background(), CompletionRequest{
+		Messages: []Message{
+			{Role: "user", Content: "tzais"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "toolu_0", Name: "lookup_registry", Input: json.RawMessage(`{"query":"tzais"}`)}}},
+			{Role: "user", ToolResults: []ToolResult{{CallID: "toolu_0", Content: "@tzais"}}},
+		},
+		Tools: []ToolSpec{{Name: "validate_formula", Description: "d", InputSchema: json.RawMessage(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completion.Text != "Checking." || len(completion.ToolCalls) != 1 {
+		t.Fatalf("completion = %+v", completion)
+	}
+	if c := completion.ToolCalls[0]; c.ID != "toolu_1" || c.Name != "validate_formula" || string(c.Input) != `{"formula":"sunset + 42min"}` {
+		t.Errorf("tool call = %+v", c)
+	}
+
+	tools, _ := got["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Errorf("tools = %v", got["tools"])
+	}
+	messages, _ := got["messages"].([]interface{})
+	if len(messages) != 3 {
+		t.Fatalf("messages = %v", got["messages"])
+	}
+	result := messages[2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	if result["type"] != "tool_result" || result["tool_use_id"] != "toolu_0" {
+		t.Errorf("tool result block = %v", result)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -14,12 +15,17 @@ import (
 // EmbeddingDimensions is the dimensionality of text-embedding-3-small
 const EmbeddingDimensions = 1536
 
-// EmbeddingModel is the OpenAI model used for embeddings
+// EmbeddingModel is the default OpenAI model used for embeddings
 const EmbeddingModel = "text-embedding-3-small"
 
+// DefaultOpenAIBaseURL is the default embeddings API endpoint
+const DefaultOpenAIBaseURL = "https://api.openai.com"
+
 // EmbeddingService generates text embeddings using OpenAI API
 type EmbeddingService struct {
 	apiKey     string
+	baseURL    string
+	model      string
 	httpClient *http.Client
 	cache      sync.Map // Simple in-memory cache
 }
@@ -48,12 +54,37 @@ type openAIError struct {
 	} `json:"error"`
 }
 
+// EmbeddingConfig configures an OpenAI-compatible embeddings client
+type EmbeddingConfig struct {
+	APIKey  string
+	BaseURL string // defaults to DefaultOpenAIBaseURL
+	Model   string // defaults to EmbeddingModel
+	Timeout time.Duration
+}
+
 // NewEmbeddingService creates a new embedding service
 func NewEmbeddingService(apiKey string) *EmbeddingService {
+	return NewEmbeddingServiceWithConfig(EmbeddingConfig{APIKey: apiKey})
+}
+
+// NewEmbeddingServiceWithConfig creates an embedding service for any
+// OpenAI-compatible endpoint, filling in defaults
+func NewEmbeddingServiceWithConfig(cfg EmbeddingConfig) *EmbeddingService {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOpenAIBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = EmbeddingModel
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
 	return &EmbeddingService{
-		apiKey: apiKey,
+		apiKey:  cfg.APIKey,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		model:   cfg.Model,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: cfg.Timeout,
 		},
 	}
 }
@@ -101,7 +132,7 @@ func (s *EmbeddingService) GenerateEmbeddings(ctx context.Context, texts []strin
 	}
 
 	reqBody := embeddingRequest{
-		Model: EmbeddingModel,
+		Model: s.model,
 		Input: validTexts,
 	}
 
@@ -110,7 +141,7 @@ func (s *EmbeddingService) GenerateEmbeddings(ctx context.Context, texts []strin
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/embeddings", bytes.NewReader(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/v1/embeddings", bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
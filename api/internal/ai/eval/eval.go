@@ -0,0 +1,389 @@
+// Package eval scores AI formula generation against a versioned golden set.
+//
+// A generated formula passes when it is semantically equivalent to the
+// expected one: both are executed with the DSL at a set of sample locations
+// and dates and every pair of times agrees within a tolerance. Textual
+// differences ("sunset + 72min" vs "sunset + 1h + 12min") don't matter.
+package eval
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+)
+
+//go:embed golden_v1.json
+var goldenV1 []byte
+
+// Case is one natural-language request and its reference formula
+type Case struct {
+	ID       string   `json:"id"`
+	Request  string   `json:"request"`
+	Expected string   `json:"expected"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// GoldenSet is a versioned collection of cases
+type GoldenSet struct {
+	Version     int    `json:"version"`
+	Description string `json:"description,omitempty"`
+	Cases       []Case `json:"cases"`
+}
+
+// DefaultGoldenSet returns the golden set shipped with the binary
+func DefaultGoldenSet() (*GoldenSet, error) {
+	return parseGoldenSet(goldenV1)
+}
+
+// LoadGoldenSet reads a golden set from a JSON file
+func LoadGoldenSet(path string) (*GoldenSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden set: %w", err)
+	}
+	return parseGoldenSet(data)
+}
+
+func parseGoldenSet(data []byte) (*GoldenSet, error) {
+	var set GoldenSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse golden set: %w", err)
+	}
+	if set.Version <= 0 || len(set.Cases) == 0 {
+		return nil, fmt.Errorf("golden set needs a version and at least one case")
+	}
+	seen := make(map[string]bool, len(set.Cases))
+	for _, c := range set.Cases {
+		if c.ID == "" || c.Request == "" || c.Expected == "" {
+			return nil, fmt.Errorf("golden case %q is incomplete", c.ID)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("duplicate golden case %q", c.ID)
+		}
+		seen[c.ID] = true
+	}
+	return &set, nil
+}
+
+// Filter returns the cases whose ID or any tag contains one of the terms
+func (s *GoldenSet) Filter(terms []string) []Case {
+	if len(terms) == 0 {
+		return s.Cases
+	}
+	var cases []Case
+	for _, c := range s.Cases {
+		for _, term := range terms {
+			if strings.Contains(c.ID, term) || containsString(c.Tags, term) {
+				cases = append(cases, c)
+				break
+			}
+		}
+	}
+	return cases
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Location is a place formulas are executed at
+type Location struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Elevation float64
+	Timezone  string
+}
+
+// DefaultLocations span both hemispheres and a range of latitudes. Stockholm
+// is above 55°, where formulas commonly switch to a high-latitude fallback
+// and deep solar angles go unreached around the June solstice.
+var DefaultLocations = []Location{
+	{"Jerusalem", 31.7683, 35.2137, 754, "Asia/Jerusalem"},
+	{"New York", 40.7128, -74.0060, 10, "America/New_York"},
+	{"London", 51.5074, -0.1278, 11, "Europe/London"},
+	{"Stockholm", 59.3293, 18.0686, 28, "Europe/Stockholm"},
+	{"Johannesburg", -26.2041, 28.0473, 1753, "Africa/Johannesburg"},
+}
+
+// DefaultDates are the 2025 equinoxes and solstices
+var DefaultDates = []time.Time{
+	time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC),
+	time.Date(2025, 6, 21, 0, 0, 0, 0, time.UTC),
+	time.Date(2025, 9, 22, 0, 0, 0, 0, time.UTC),
+	time.Date(2025, 12, 21, 0, 0, 0, 0, time.UTC),
+}
+
+// DefaultTolerance is the largest difference still considered the same time
+const DefaultTolerance = time.Minute
+
+// Comparison is the result of executing two formulas over the samples
+type Comparison struct {
+	Samples  int           `json:"samples"`
+	MaxDiff  time.Duration `json:"-"`
+	Mismatch string        `json:"mismatch,omitempty"` // first disagreeing sample
+}
+
+// MarshalJSON reports the largest difference in seconds
+func (c Comparison) MarshalJSON() ([]byte, error) {
+	type alias Comparison
+	return json.Marshal(struct {
+		alias
+		MaxDiffSeconds float64 `json:"max_diff_seconds"`
+	}{alias(c), c.MaxDiff.Seconds()})
+}
+
+// Equivalent reports whether every sample agreed
+func (c Comparison) Equivalent() bool {
+	return c.Mismatch == ""
+}
+
+// Compare executes both formulas at every location and date. A sample where
+// neither formula has a result (e.g. a solar angle the sun never reaches)
+// agrees; a sample where only one does is a mismatch.
+func Compare(expected, got string, locations []Location, dates []time.Time, tolerance time.Duration) (Comparison, error) {
+	var cmp Comparison
+	for _, loc := range locations {
+		tz, err := time.LoadLocation(loc.Timezone)
+		if err != nil {
+			return cmp, fmt.Errorf("failed to load timezone %s: %w", loc.Timezone, err)
+		}
+		for _, d := range dates {
+			date := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, tz)
+			want, wantErr := execute(expected, date, loc, tz)
+			have, haveErr := execute(got, date, loc, tz)
+			cmp.Samples++
+
+			sample := fmt.Sprintf("%s %s", loc.Name, date.Format("2006-01-02"))
+			switch {
+			case wantErr != nil && haveErr != nil:
+				continue
+			case wantErr != nil:
+				cmp.mismatch(fmt.Sprintf("%s: expected no time (%v), got %s", sample, wantErr, have.Format("15:04:05")))
+				continue
+			case haveErr != nil:
+				cmp.mismatch(fmt.Sprintf("%s: expected %s, got error: %v", sample, want.Format("15:04:05"), haveErr))
+				continue
+			}
+
+			diff := have.Sub(want)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > cmp.MaxDiff {
+				cmp.MaxDiff = diff
+			}
+			if diff > tolerance {
+				cmp.mismatch(fmt.Sprintf("%s: expected %s, got %s", sample, want.Format("15:04:05"), have.Format("15:04:05")))
+			}
+		}
+	}
+	return cmp, nil
+}
+
+func (c *Comparison) mismatch(msg string) {
+	if c.Mismatch == "" {
+		c.Mismatch = msg
+	}
+}
+
+func execute(formula string, date time.Time, loc Location, tz *time.Location) (time.Time, error) {
+	ctx := dsl.NewExecutionContext(date, loc.Latitude, loc.Longitude, loc.Elevation, tz)
+	t, err := dsl.ExecuteFormula(formula, ctx)
+	if err == nil && t.IsZero() {
+		err = fmt.Errorf("no result")
+	}
+	return t, err
+}
+
+// Status is the outcome of one case
+type Status string
+
+// Case outcomes
+const (
+	StatusPass        Status = "pass"
+	StatusMismatch    Status = "mismatch"    // valid formula, different times
+	StatusInvalid     Status = "invalid"     // still failed validation after retries
+	StatusUnsupported Status = "unsupported" // model answered UNSUPPORTED
+	StatusError       Status = "error"       // provider or harness error
+)
+
+// CaseResult is the scored outcome of one case
+type CaseResult struct {
+	ID                 string      `json:"id"`
+	Request            string      `json:"request"`
+	Expected           string      `json:"expected"`
+	Generated          string      `json:"generated,omitempty"`
+	Status             Status      `json:"status"`
+	Attempts           int         `json:"attempts"`
+	ValidationFailures int         `json:"validation_failures"`
+	TokensUsed         int         `json:"tokens_used"`
+	Comparison         *Comparison `json:"comparison,omitempty"`
+	Error              string      `json:"error,omitempty"`
+}
+
+// Summary aggregates a run
+type Summary struct {
+	Total              int     `json:"total"`
+	Passed             int     `json:"passed"`
+	PassRate           float64 `json:"pass_rate"`
+	ValidationFailures int     `json:"validation_failures"`
+	Retries            int     `json:"retries"`
+	TokensUsed         int     `json:"tokens_used"`
+	DurationMs         int64   `json:"duration_ms"`
+}
+
+// Report is the outcome of a full run
+type Report struct {
+	GoldenVersion int            `json:"golden_version"`
+	Model         string         `json:"model"`
+	RAG           bool           `json:"rag"`
+	Tolerance     string         `json:"tolerance"`
+	Summary       Summary        `json:"summary"`
+	ByStatus      map[Status]int `json:"by_status"`
+	Cases         []CaseResult   `json:"cases"`
+}
+
+// Runner generates a formula for each case and scores it
+type Runner struct {
+	Generator *ai.ClaudeService
+	Context   *ai.ContextService // optional; enables RAG like the API handler
+	Locations []Location
+	Dates     []time.Time
+	Tolerance time.Duration
+}
+
+// Run evaluates the given cases sequentially
+func (r *Runner) Run(ctx context.Context, version int, cases []Case) (*Report, error) {
+	locations, dates, tolerance := r.Locations, r.Dates, r.Tolerance
+	if len(locations) == 0 {
+		locations = DefaultLocations
+	}
+	if len(dates) == 0 {
+		dates = DefaultDates
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	report := &Report{
+		GoldenVersion: version,
+		Model:         r.Generator.Model(),
+		RAG:           r.Context != nil,
+		Tolerance:     tolerance.String(),
+		ByStatus:      make(map[Status]int),
+	}
+	start := time.Now()
+
+	for _, c := range cases {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res, err := r.runCase(ctx, c, locations, dates, tolerance)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Cases = append(report.Cases, res)
+		report.ByStatus[res.Status]++
+		report.Summary.Total++
+		if res.Status == StatusPass {
+			report.Summary.Passed++
+		}
+		report.Summary.ValidationFailures += res.ValidationFailures
+		if res.Attempts > 1 {
+			report.Summary.Retries += res.Attempts - 1
+		}
+		report.Summary.TokensUsed += res.TokensUsed
+	}
+
+	if report.Summary.Total > 0 {
+		report.Summary.PassRate = float64(report.Summary.Passed) / float64(report.Summary.Total)
+	}
+	report.Summary.DurationMs = time.Since(start).Milliseconds()
+	return report, nil
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case, locations []Location, dates []time.Time, tolerance time.Duration) (CaseResult, error) {
+	res := CaseResult{ID: c.ID, Request: c.Request, Expected: c.Expected}
+
+	// Same RAG settings as the GenerateFormula handler
+	var ragContext string
+	if r.Context != nil {
+		assembled, err := r.Context.AssembleContext(ctx, c.Request, ai.ContextOptions{
+			MaxTokens:       1500,
+			MaxDocs:         3,
+			IncludeExamples: true,
+			IncludeHalachic: true,
+		})
+		if err == nil && assembled != nil {
+			ragContext = assembled.Context
+		}
+	}
+
+	validate := func(formula string) error {
+		_, _, err := dsl.ValidateFormula(formula, nil)
+		if err != nil {
+			res.ValidationFailures++
+		}
+		return err
+	}
+
+	result, genErr := r.Generator.GenerateWithValidation(ctx, c.Request, ragContext, validate)
+	if result != nil {
+		res.Generated = result.Formula
+		res.Attempts = result.Attempts
+		res.TokensUsed = result.TokensUsed
+	}
+
+	switch {
+	case genErr != nil && result != nil && strings.HasPrefix(result.Formula, "UNSUPPORTED:"):
+		res.Status = StatusUnsupported
+		res.Error = genErr.Error()
+		return res, nil
+	case genErr != nil && res.ValidationFailures > 0 && result != nil:
+		res.Status = StatusInvalid
+		res.Error = genErr.Error()
+		return res, nil
+	case genErr != nil:
+		res.Status = StatusError
+		res.Error = genErr.Error()
+		return res, nil
+	}
+
+	cmp, err := Compare(c.Expected, result.Formula, locations, dates, tolerance)
+	if err != nil {
+		return res, err
+	}
+	res.Comparison = &cmp
+	if cmp.Equivalent() {
+		res.Status = StatusPass
+	} else {
+		res.Status = StatusMismatch
+		res.Error = cmp.Mismatch
+	}
+	return res, nil
+}
+
+// OracleLLM returns a fake LLM that answers every case with its expected
+// formula. Running against it checks the harness and golden set end to end
+// without a network call.
+func OracleLLM(cases []Case) *ai.FakeLLM {
+	fake := ai.NewFakeLLM()
+	for _, c := range cases {
+		fake.When(c.Request, "```\n"+c.Expected+"\n```")
+	}
+	return fake
+}
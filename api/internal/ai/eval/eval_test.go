@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+)
+
+func TestDefaultGoldenSetIsExecutable(t *testing.T) {
+	set, err := DefaultGoldenSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range set.Cases {
+		t.Run(c.ID, func(t *testing.T) {
+			if _, _, err := dsl.ValidateFormula(c.Expected, nil); err != nil {
+				t.Fatalf("expected formula %q is invalid: %v", c.Expected, err)
+			}
+			cmp, err := Compare(c.Expected, c.Expected, DefaultLocations, DefaultDates, DefaultTolerance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equivalent() || cmp.MaxDiff != 0 {
+				t.Errorf("formula not equivalent to itself: %+v", cmp)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  string
+		got       string
+		tolerance time.Duration
+		want      bool
+	}{
+		{"identical", "sunset + 72min", "sunset + 72min", time.Second, true},
+		{"different units", "sunset + 72min", "sunset + 1h + 12min", time.Second, true},
+		{"midpoint is solar noon", "midpoint(sunrise, sunset)", "solar_noon", 2 * time.Minute, true},
+		{"one minute off", "sunrise - 72min", "sunrise - 73min", 30 * time.Second, false},
+		{"within tolerance", "sunrise - 72min", "sunrise - 73min", 2 * time.Minute, true},
+		{"wrong base", "proportional_hours(3, gra)", "proportional_hours(3, mga)", time.Minute, false},
+		{"missing high-latitude branch", "if (latitude > 55) { sunrise - 90min } else { solar(16.1, before_sunrise) }", "solar(16.1, before_sunrise)", time.Minute, false},
+		{"unreached angle on both sides", "solar(18, before_sunrise)", "solar(18, before_sunrise)", time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmp, err := Compare(tt.expected, tt.got, DefaultLocations, DefaultDates, tt.tolerance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cmp.Equivalent() != tt.want {
+				t.Errorf("Equivalent() = %v, want %v (max diff %v, %s)", cmp.Equivalent(), tt.want, cmp.MaxDiff, cmp.Mismatch)
+			}
+		})
+	}
+}
+
+func TestRunWithOracle(t *testing.T) {
+	set, err := DefaultGoldenSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &Runner{Generator: ai.NewClaudeServiceWithLLM(OracleLLM(set.Cases))}
+	report, err := runner.Run(context.Background(), set.Version, set.Cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary.PassRate != 1 || report.Summary.Retries != 0 || report.Model != ai.FakeModel {
+		t.Errorf("summary = %+v, by status %v", report.Summary, report.ByStatus)
+	}
+	if report.Summary.TokensUsed == 0 {
+		t.Error("expected token usage from the fake")
+	}
+}
+
+func TestRunScoresRetriesAndFailures(t *testing.T) {
+	cases := []Case{
+		{ID: "retry", Request: "72 minutes before sunrise", Expected: "sunrise - 72min"},
+		{ID: "mismatch", Request: "Rabbeinu Tam", Expected: "sunset + 72min"},
+		{ID: "invalid", Request: "Chatzos", Expected: "solar_noon"},
+		{ID: "unsupported", Request: "Moonrise", Expected: "sunrise"},
+	}
+	fake := ai.NewFakeLLM().Enqueue(
+		"```\nsunrise -\n```", "```\nsunrise - 72min\n```", // retry then pass
+		"```\nsunset + 50min\n```",                               // valid but wrong
+		"```\nnoon\n```", "```\nnoon()\n```", "```\nmidday\n```", // never valid
+		"UNSUPPORTED: the DSL has no lunar primitives",
+	)
+
+	runner := &Runner{Generator: ai.NewClaudeServiceWithLLM(fake)}
+	report, err := runner.Run(context.Background(), 1, cases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Status{StatusPass, StatusMismatch, StatusInvalid, StatusUnsupported}
+	for i, res := range report.Cases {
+		if res.Status != want[i] {
+			t.Errorf("%s: status = %s, want %s (%s)", res.ID, res.Status, want[i], res.Error)
+		}
+	}
+	if s := report.Summary; s.Passed != 1 || s.Retries != 3 || s.ValidationFailures != 4 {
+		t.Errorf("summary = %+v", s)
+	}
+	if report.Cases[2].Attempts != 3 || report.Cases[2].TokensUsed == 0 {
+		t.Errorf("invalid case attempts = %d, tokens = %d", report.Cases[2].Attempts, report.Cases[2].TokensUsed)
+	}
+}
@@ -0,0 +1,245 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FakeModel is the model name reported by FakeLLM
+const FakeModel = "fake"
+
+// ErrNoScriptedResponse is returned by FakeLLM when nothing matches a request
+var ErrNoScriptedResponse = errors.New("fake LLM: no scripted response")
+
+// FakeRule answers any request whose last user message contains Match
+// (case-insensitive). An empty Match matches everything.
+type FakeRule struct {
+	Match    string `json:"match"`
+	Response string `json:"response"`
+}
+
+// FakeLLM is a deterministic, in-process LLM for tests and CI. Queued
+// responses are returned first, in order; after that the first matching rule
+// answers. Every request is recorded for assertions.
+type FakeLLM struct {
+	mu     sync.Mutex
+	queue  []Completion
+	rules  []FakeRule
+	calls  []CompletionRequest
+	nextID int
+}
+
+// NewFakeLLM creates a fake with no scripted responses
+func NewFakeLLM() *FakeLLM {
+	return &FakeLLM{}
+}
+
+// Enqueue adds responses returned verbatim to the next requests
+func (f *FakeLLM) Enqueue(responses ...string) *FakeLLM {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range responses {
+		f.queue = append(f.queue, Completion{Text: r})
+	}
+	return f
+}
+
+// EnqueueToolCall queues a response that asks to run a tool. Call IDs are
+// numbered sequentially (toolu_fake_1, toolu_fake_2, ...).
+func (f *FakeLLM) EnqueueToolCall(name string, input interface{}) *FakeLLM {
+	data, err := json.Marshal(input)
+	if err != nil {
+		panic(fmt.Sprintf("fake LLM: invalid tool input: %v", err))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	f.queue = append(f.queue, Completion{
+		ToolCalls:  []ToolCall{{ID: fmt.Sprintf("toolu_fake_%d", f.nextID), Name: name, Input: data}},
+		StopReason: "tool_use",
+	})
+	return f
+}
+
+// When adds a rule answering requests that mention match
+func (f *FakeLLM) When(match, response string) *FakeLLM {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, FakeRule{Match: match, Response: response})
+	return f
+}
+
+// LoadScript appends rules from a JSON file containing an array of FakeRule
+func (f *FakeLLM) LoadScript(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fake script: %w", err)
+	}
+	var rules []FakeRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("failed to parse fake script: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rules...)
+	return nil
+}
+
+// Calls returns a copy of the requests received so far
+func (f *FakeLLM) Calls() []CompletionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]CompletionRequest(nil), f.calls...)
+}
+
+// Model returns FakeModel
+func (f *FakeLLM) Model() string {
+	return FakeModel
+}
+
+// Complete answers from the queue or the first matching rule
+func (f *FakeLLM) Complete(ctx context.Context, req CompletionRequest) (*Completion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, req)
+
+	var prompt string
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" && req.Messages[i].Content != "" {
+			prompt = req.Messages[i].Content
+			break
+		}
+	}
+
+	var completion Completion
+	ok := false
+	if len(f.queue) > 0 {
+		completion, f.queue, ok = f.queue[0], f.queue[1:], true
+	} else {
+		lower := strings.ToLower(prompt)
+		for _, rule := range f.rules {
+			if strings.Contains(lower, strings.ToLower(rule.Match)) {
+				completion, ok = Completion{Text: rule.Response}, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w for %q", ErrNoScriptedResponse, prompt)
+	}
+
+	completion.InputTokens = estimateTokens(req.System)
+	for _, m := range req.Messages {
+		completion.InputTokens += estimateTokens(m.Content)
+		for _, r := range m.ToolResults {
+			completion.InputTokens += estimateTokens(r.Content)
+		}
+	}
+	completion.OutputTokens = estimateTokens(completion.Text)
+	for _, c := range completion.ToolCalls {
+		completion.OutputTokens += estimateTokens(string(c.Input))
+	}
+	if completion.StopReason == "" {
+		completion.StopReason = "end_turn"
+	}
+	return &completion, nil
+}
+
+// FakeEmbedder produces deterministic embeddings by hashing words into a
+// fixed number of buckets (the "hashing trick"). Texts sharing words get
+// similar vectors, so cosine search behaves sensibly without a network call.
+type FakeEmbedder struct {
+	Dimensions int
+}
+
+// NewFakeEmbedder creates a fake producing EmbeddingDimensions vectors, which
+// matches the embeddings table column
+func NewFakeEmbedder() *FakeEmbedder {
+	return &FakeEmbedder{Dimensions: EmbeddingDimensions}
+}
+
+// GenerateEmbedding hashes a single text
+func (e *FakeEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+	return e.embed(text), nil
+}
+
+// GenerateEmbeddings hashes each text; empty texts yield nil entries like the
+// OpenAI-backed service
+func (e *FakeEmbedder) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([][]float32, len(texts))
+	empty := true
+	for i, t := range texts {
+		if t != "" {
+			result[i] = e.embed(t)
+			empty = false
+		}
+	}
+	if empty {
+		return nil, fmt.Errorf("all texts were empty")
+	}
+	return result, nil
+}
+
+func (e *FakeEmbedder) embed(text string) []float32 {
+	dims := e.Dimensions
+	if dims <= 0 {
+		dims = EmbeddingDimensions
+	}
+	vec := make([]float32, dims)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		words = []string{text}
+	}
+	for _, w := range words {
+		h := fnv.New64a()
+		h.Write([]byte(w))
+		sum := h.Sum64()
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vec[sum%uint64(dims)] += sign
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		vec[0] = 1
+		return vec
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= scale
+	}
+	return vec
+}
@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Provider names accepted by AI_PROVIDER and EMBEDDING_PROVIDER
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderFake      = "fake"
+)
+
+// Message is a single turn in a chat completion. Assistant turns may request
+// tool calls; the following user turn carries their results.
+type Message struct {
+	Role        string       `json:"role"`
+	Content     string       `json:"content,omitempty"`
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+}
+
+// ToolSpec describes a server-side tool the model may call
+type ToolSpec struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"` // JSON Schema object
+}
+
+// ToolCall is a model's request to run a tool
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ToolResult is the output of a tool call, sent back to the model
+type ToolResult struct {
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// CompletionRequest is a provider-neutral chat completion request
+type CompletionRequest struct {
+	System    string
+	Messages  []Message
+	Tools     []ToolSpec
+	MaxTokens int
+}
+
+// Completion is the text, tool calls and token usage returned by an LLM
+type Completion struct {
+	Text         string
+	ToolCalls    []ToolCall
+	InputTokens  int
+	OutputTokens int
+	StopReason   string
+}
+
+// TokensUsed returns the total tokens billed for the completion
+func (c *Completion) TokensUsed() int {
+	return c.InputTokens + c.OutputTokens
+}
+
+// LLM is a chat completion model. ClaudeService builds formula generation and
+// explanation on top of it, so any provider (or FakeLLM in tests) can be used.
+type LLM interface {
+	Complete(ctx context.Context, req CompletionRequest) (*Completion, error)
+	// Model identifies the model for audit logging
+	Model() string
+}
+
+// Embedder turns text into fixed-size vectors for semantic search
+type Embedder interface {
+	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// LLMProviderName is the provider selected by AI_PROVIDER
+func LLMProviderName() string {
+	if provider := strings.ToLower(os.Getenv("AI_PROVIDER")); provider != "" {
+		return provider
+	}
+	return ProviderAnthropic
+}
+
+// EmbeddingProviderName is the provider selected by EMBEDDING_PROVIDER
+func EmbeddingProviderName() string {
+	if provider := strings.ToLower(os.Getenv("EMBEDDING_PROVIDER")); provider != "" {
+		return provider
+	}
+	return ProviderOpenAI
+}
+
+// LLMFromEnv builds the LLM selected by AI_PROVIDER (default "anthropic").
+// It returns nil when the selected provider has no credentials configured.
+//
+//	AI_PROVIDER        - anthropic | fake
+//	ANTHROPIC_API_KEY  - required for anthropic
+//	ANTHROPIC_BASE_URL - override the API endpoint (e.g. a proxy or mock server)
+//	ANTHROPIC_MODEL    - override the model
+//	AI_FAKE_SCRIPT     - JSON script of responses for the fake provider
+func LLMFromEnv() (LLM, error) {
+	switch provider := LLMProviderName(); provider {
+	case ProviderAnthropic:
+		apiKey := os.Getenv("ANTHROPIC_API_KEY")
+		if apiKey == "" {
+			return nil, nil
+		}
+		return NewAnthropicLLM(AnthropicConfig{
+			APIKey:  apiKey,
+			BaseURL: os.Getenv("ANTHROPIC_BASE_URL"),
+			Model:   os.Getenv("ANTHROPIC_MODEL"),
+		}), nil
+	case ProviderFake:
+		fake := NewFakeLLM()
+		if path := os.Getenv("AI_FAKE_SCRIPT"); path != "" {
+			if err := fake.LoadScript(path); err != nil {
+				return nil, err
+			}
+		}
+		return fake, nil
+	default:
+		return nil, fmt.Errorf("unknown AI_PROVIDER %q", provider)
+	}
+}
+
+// EmbedderFromEnv builds the Embedder selected by EMBEDDING_PROVIDER (default "openai").
+// It returns nil when the selected provider has no credentials configured.
+//
+//	EMBEDDING_PROVIDER - openai | fake
+//	OPENAI_API_KEY     - required for openai
+//	OPENAI_BASE_URL    - override the API endpoint
+//	EMBEDDING_MODEL    - override the model (must produce EmbeddingDimensions vectors)
+func EmbedderFromEnv() (Embedder, error) {
+	switch provider := EmbeddingProviderName(); provider {
+	case ProviderOpenAI:
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, nil
+		}
+		return NewEmbeddingServiceWithConfig(EmbeddingConfig{
+			APIKey:  apiKey,
+			BaseURL: os.Getenv("OPENAI_BASE_URL"),
+			Model:   os.Getenv("EMBEDDING_MODEL"),
+		}), nil
+	case ProviderFake:
+		return NewFakeEmbedder(), nil
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDING_PROVIDER %q", provider)
+	}
+}
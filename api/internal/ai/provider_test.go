@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAnthropicLLMUsesConfiguredEndpoint(t *testing.T) {
+	var got claudeRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("x-api-key = %q", r.Header.Get("x-api-key"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"` + "```\\nsunrise - 72min\\n```" + `"}],"usage":{"input_tokens":40,"output_tokens":8},"stop_reason":"end_turn"}`))
+	}))
+	defer srv.Close()
+
+	llm := NewAnthropicLLM(AnthropicConfig{APIKey: "test-key", BaseURL: srv.URL + "/", Model: "claude-test"})
+	svc := NewClaudeServiceWithLLM(llm)
+
+	result, err := svc.GenerateFormula(context.Background(), "72 minutes before sunrise", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Formula != "sunrise - 72min" || result.TokensUsed != 48 || result.Confidence != 0.9 {
+		t.Errorf("result = %+v", result)
+	}
+	if got.Model != "claude-test" || len(got.Messages) != 1 || got.Messages[0].Content != "72 minutes before sunrise" {
+		t.Errorf("request = %+v", got)
+	}
+	if svc.Model() != "claude-test" {
+		t.Errorf("model = %q", svc.Model())
+	}
+}
+
+func TestGenerateWithValidationRetriesOnFakeLLM(t *testing.T) {
+	fake := NewFakeLLM().Enqueue("```\nsunrise - 72\n```", "```\nsunrise - 72min\n```")
+	svc := NewClaudeServiceWithLLM(fake)
+
+	validate := func(formula string) error {
+		if !strings.HasSuffix(formula, "min") {
+			return errors.New("missing duration unit")
+		}
+		return nil
+	}
+
+	result, err := svc.GenerateWithValidation(context.Background(), "72 minutes before sunrise", "", validate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Formula != "sunrise - 72min" {
+		t.Errorf("formula = %q", result.Formula)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("calls = %d, want 2", len(calls))
+	}
+	if retry := calls[1].Messages[0].Content; !strings.Contains(retry, "missing duration unit") || !strings.Contains(retry, "sunrise - 72") {
+		t.Errorf("retry prompt lacks error context: %q", retry)
+	}
+}
+
+func TestFakeLLMRules(t *testing.T) {
+	fake := NewFakeLLM().
+		When("misheyakir", "```\nsolar(11.5, before_sunrise)\n```").
+		When("", "UNSUPPORTED: unknown request")
+	svc := NewClaudeServiceWithLLM(fake)
+	ctx := context.Background()
+
+	result, err := svc.GenerateFormula(ctx, "Earliest tallis (Misheyakir)", "")
+	if err != nil || result.Formula != "solar(11.5, before_sunrise)" {
+		t.Errorf("matched rule: %+v, %v", result, err)
+	}
+	if _, err := svc.GenerateWithValidation(ctx, "when the moon sets", "", nil); err == nil || !strings.HasPrefix(err.Error(), "UNSUPPORTED:") {
+		t.Errorf("fallback rule error = %v", err)
+	}
+
+	empty := NewClaudeServiceWithLLM(NewFakeLLM())
+	if _, err := empty.ExplainFormula(ctx, "sunrise", "en", ""); !errors.Is(err, ErrNoScriptedResponse) {
+		t.Errorf("unscripted error = %v", err)
+	}
+}
+
+func TestEmbeddingServiceUsesConfiguredEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embeddingRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/v1/embeddings" || req.Model != "local-embed" || len(req.Input) != 1 {
+			t.Errorf("path = %s, request = %+v", r.URL.Path, req)
+		}
+		_, _ = w.Write([]byte(`{"data":[{"embedding":[0.6,0.8],"index":0}]}`))
+	}))
+	defer srv.Close()
+
+	svc := NewEmbeddingServiceWithConfig(EmbeddingConfig{BaseURL: srv.URL, Model: "local-embed"})
+	got, err := svc.GenerateEmbeddings(context.Background(), []string{"", "alos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != nil || len(got[1]) != 2 {
+		t.Errorf("embeddings = %v", got)
+	}
+}
+
+func TestFakeEmbedder(t *testing.T) {
+	e := NewFakeEmbedder()
+	ctx := context.Background()
+
+	a, _ := e.GenerateEmbedding(ctx, "Alos hashachar 72 minutes before sunrise")
+	b, _ := e.GenerateEmbedding(ctx, "alos HaShachar: 72 minutes before sunrise!")
+	c, _ := e.GenerateEmbedding(ctx, "Plag hamincha proportional hours")
+
+	if len(a) != EmbeddingDimensions {
+		t.Fatalf("dimensions = %d", len(a))
+	}
+	if cosine(a, b) < 0.999 {
+		t.Errorf("same words should embed identically, cosine = %f", cosine(a, b))
+	}
+	if cosine(a, c) > 0.5 {
+		t.Errorf("unrelated texts too similar, cosine = %f", cosine(a, c))
+	}
+	if math.Abs(cosine(a, a)-1) > 1e-5 {
+		t.Errorf("vectors not normalized")
+	}
+
+	batch, err := e.GenerateEmbeddings(ctx, []string{"", "sunset"})
+	if err != nil || batch[0] != nil || len(batch[1]) != EmbeddingDimensions {
+		t.Errorf("batch = %d entries, err %v", len(batch), err)
+	}
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func TestProvidersFromEnv(t *testing.T) {
+	t.Setenv("AI_PROVIDER", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("EMBEDDING_PROVIDER", "")
+	t.Setenv("OPENAI_API_KEY", "")
+	if LLMProviderName() != ProviderAnthropic || EmbeddingProviderName() != ProviderOpenAI {
+		t.Errorf("defaults = %s, %s", LLMProviderName(), EmbeddingProviderName())
+	}
+	if llm, err := LLMFromEnv(); llm != nil || err != nil {
+		t.Errorf("anthropic without a key = %v, %v; want nil, nil", llm, err)
+	}
+
+	t.Setenv("AI_PROVIDER", "Fake")
+	t.Setenv("EMBEDDING_PROVIDER", "fake")
+	llm, err := LLMFromEnv()
+	if err != nil || llm == nil || llm.Model() != FakeModel || LLMProviderName() != ProviderFake {
+		t.Errorf("fake LLM = %v, %v (provider %s)", llm, err, LLMProviderName())
+	}
+	if e, err := EmbedderFromEnv(); err != nil || e == nil {
+		t.Errorf("fake embedder = %v, %v", e, err)
+	}
+
+	t.Setenv("AI_PROVIDER", "gpt")
+	if _, err := LLMFromEnv(); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jcom-dev/zmanim-lab/internal/validation"
 	pgvector "github.com/pgvector/pgvector-go"
 )
 
@@ -21,57 +27,84 @@ type SearchResult struct {
 	Score       float64           `json:"score"`
 }
 
-// SearchService provides semantic search capabilities
+// SearchService provides hybrid lexical + semantic search over the embeddings
+// table. Without an embedder it degrades to full-text search only.
 type SearchService struct {
 	db         *pgxpool.Pool
-	embeddings *EmbeddingService
+	embeddings Embedder
 }
 
-// NewSearchService creates a new search service
-func NewSearchService(db *pgxpool.Pool, embeddings *EmbeddingService) *SearchService {
+// NewSearchService creates a new search service. embeddings may be nil.
+func NewSearchService(db *pgxpool.Pool, embeddings Embedder) *SearchService {
 	return &SearchService{
 		db:         db,
 		embeddings: embeddings,
 	}
 }
 
-// SearchSimilar finds chunks similar to the query
-func (s *SearchService) SearchSimilar(ctx context.Context, query string, topK int) ([]SearchResult, error) {
-	if topK <= 0 {
-		topK = 5
+// SearchOptions narrows a search
+type SearchOptions struct {
+	TopK        int
+	Sources     []string // restrict to these sources (empty = all)
+	ContentType string   // restrict to one content type (empty = all)
+}
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion; 60 is the
+// value from the original RRF paper and works well without tuning
+const rrfK = 60
+
+// Search runs full-text and vector retrieval and fuses the rankings with
+// reciprocal rank fusion. If the embedding call fails, lexical results are
+// still returned.
+func (s *SearchService) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	if opts.TopK <= 0 {
+		opts.TopK = 5
+	}
+	candidates := opts.TopK * 4
+	if candidates < 20 {
+		candidates = 20
 	}
 
-	// Generate embedding for query
-	queryEmbed, err := s.embeddings.GenerateEmbedding(ctx, query)
+	lexical, err := s.searchLexical(ctx, query, opts, candidates)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+		return nil, err
 	}
 
-	// Convert to pgvector
-	vec := pgvector.NewVector(queryEmbed)
+	var vector []SearchResult
+	if s.embeddings != nil {
+		vector, err = s.searchVector(ctx, query, opts, candidates)
+		if err != nil {
+			if len(lexical) == 0 {
+				return nil, err
+			}
+			slog.Warn("vector search unavailable, using lexical results only", "error", err)
+		}
+	}
 
-	// Search using cosine distance
-	rows, err := s.db.Query(ctx, `
-		SELECT id, content, source, content_type, chunk_index, metadata,
-		       1 - (embedding <=> $1) as score
-		FROM embeddings
-		ORDER BY embedding <=> $1
-		LIMIT $2
-	`, vec, topK)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	results := fuseRankings(lexical, vector)
+	if len(results) > opts.TopK {
+		results = results[:opts.TopK]
 	}
-	defer rows.Close()
+	return results, nil
+}
 
-	return scanSearchResults(rows)
+// SearchSimilar finds chunks similar to the query
+func (s *SearchService) SearchSimilar(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	return s.Search(ctx, query, SearchOptions{TopK: topK})
 }
 
 // SearchSimilarBySource searches within a specific source
 func (s *SearchService) SearchSimilarBySource(ctx context.Context, query string, source string, topK int) ([]SearchResult, error) {
-	if topK <= 0 {
-		topK = 5
-	}
+	return s.Search(ctx, query, SearchOptions{TopK: topK, Sources: []string{source}})
+}
+
+// SearchSimilarByType searches within a specific content type
+func (s *SearchService) SearchSimilarByType(ctx context.Context, query string, contentType string, topK int) ([]SearchResult, error) {
+	return s.Search(ctx, query, SearchOptions{TopK: topK, ContentType: contentType})
+}
 
+// searchVector ranks chunks by cosine similarity to the query embedding
+func (s *SearchService) searchVector(ctx context.Context, query string, opts SearchOptions, limit int) ([]SearchResult, error) {
 	queryEmbed, err := s.embeddings.GenerateEmbedding(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
@@ -83,10 +116,12 @@ func (s *SearchService) SearchSimilarBySource(ctx context.Context, query string,
 		SELECT id, content, source, content_type, chunk_index, metadata,
 		       1 - (embedding <=> $1) as score
 		FROM embeddings
-		WHERE source = $2
+		WHERE embedding IS NOT NULL
+		  AND (cardinality($2::text[]) = 0 OR source = ANY($2))
+		  AND ($3 = '' OR content_type = $3)
 		ORDER BY embedding <=> $1
-		LIMIT $3
-	`, vec, source, topK)
+		LIMIT $4
+	`, vec, sourcesParam(opts.Sources), opts.ContentType, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search embeddings: %w", err)
 	}
@@ -95,35 +130,97 @@ func (s *SearchService) SearchSimilarBySource(ctx context.Context, query string,
 	return scanSearchResults(rows)
 }
 
-// SearchSimilarByType searches within a specific content type
-func (s *SearchService) SearchSimilarByType(ctx context.Context, query string, contentType string, topK int) ([]SearchResult, error) {
-	if topK <= 0 {
-		topK = 5
-	}
-
-	queryEmbed, err := s.embeddings.GenerateEmbedding(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+// searchLexical ranks chunks by full-text match against search_tsv
+func (s *SearchService) searchLexical(ctx context.Context, query string, opts SearchOptions, limit int) ([]SearchResult, error) {
+	tsquery := buildTSQuery(query)
+	if tsquery == "" {
+		return nil, nil
 	}
 
-	vec := pgvector.NewVector(queryEmbed)
-
 	rows, err := s.db.Query(ctx, `
 		SELECT id, content, source, content_type, chunk_index, metadata,
-		       1 - (embedding <=> $1) as score
-		FROM embeddings
-		WHERE content_type = $2
-		ORDER BY embedding <=> $1
-		LIMIT $3
-	`, vec, contentType, topK)
+		       ts_rank_cd(search_tsv, q)::float8 as score
+		FROM embeddings, to_tsquery('english', $1) q
+		WHERE search_tsv @@ q
+		  AND (cardinality($2::text[]) = 0 OR source = ANY($2))
+		  AND ($3 = '' OR content_type = $3)
+		ORDER BY score DESC, chunk_index
+		LIMIT $4
+	`, tsquery, sourcesParam(opts.Sources), opts.ContentType, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+		return nil, fmt.Errorf("failed to search embeddings text: %w", err)
 	}
 	defer rows.Close()
 
 	return scanSearchResults(rows)
 }
 
+func sourcesParam(sources []string) []string {
+	if sources == nil {
+		return []string{}
+	}
+	return sources
+}
+
+// buildTSQuery turns free text into an OR of prefix-matched terms after
+// Hebrew normalization, e.g. "Alos 72 min" -> "'alos':* | '72':* | 'min':*".
+// Only letters and digits survive, so the result is always valid tsquery syntax.
+func buildTSQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(validation.NormalizeHebrew(query)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	seen := make(map[string]bool, len(words))
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if utf8.RuneCountInString(w) < 2 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, "'"+w+"':*")
+	}
+	return strings.Join(terms, " | ")
+}
+
+// fuseRankings merges ranked lists with reciprocal rank fusion:
+// score(d) = sum over lists of 1/(rrfK + rank). Scores are scaled so a chunk
+// ranked first in every list scores 1.
+func fuseRankings(lists ...[]SearchResult) []SearchResult {
+	var used int
+	scores := make(map[string]float64)
+	byID := make(map[string]SearchResult)
+	var order []string
+
+	for _, list := range lists {
+		if len(list) == 0 {
+			continue
+		}
+		used++
+		for rank, r := range list {
+			if _, ok := byID[r.ID]; !ok {
+				byID[r.ID] = r
+				order = append(order, r.ID)
+			}
+			scores[r.ID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+	if used == 0 {
+		return nil
+	}
+
+	maxScore := float64(used) / float64(rrfK+1)
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		r := byID[id]
+		r.Score = scores[id] / maxScore
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
 // scanSearchResults scans rows into SearchResult slice
 func scanSearchResults(rows pgx.Rows) ([]SearchResult, error) {
 	var results []SearchResult
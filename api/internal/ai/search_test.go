@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBuildTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"Alos 72 min", "'alos':* | '72':* | 'min':*"},
+		{"sunrise - 72min", "'sunrise':* | '72min':*"},
+		{"שְׁקִיעָה לפי שו\"ע", "'שקיעה':* | 'לפי':* | 'שוע':*"},
+		{"a & b | c'); DROP", "'drop':*"},
+		{"Gra gra GRA", "'gra':*"},
+		{"!!", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := buildTSQuery(tt.query); got != tt.want {
+				t.Errorf("buildTSQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	lexical := []SearchResult{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	vector := []SearchResult{{ID: "b"}, {ID: "d"}, {ID: "a"}}
+
+	got := fuseRankings(lexical, vector)
+	var ids []string
+	for _, r := range got {
+		ids = append(ids, r.ID)
+	}
+	// b: 1/62 + 1/61, a: 1/61 + 1/63, d: 1/62, c: 1/63
+	want := []string{"b", "a", "d", "c"}
+	if len(ids) != len(want) {
+		t.Fatalf("ids = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("ids = %v, want %v", ids, want)
+		}
+	}
+	if got[0].Score >= 1 || got[0].Score <= got[1].Score {
+		t.Errorf("scores = %v, %v", got[0].Score, got[1].Score)
+	}
+
+	// Lexical-only: the top result is ranked first in every list used
+	only := fuseRankings(lexical, nil)
+	if len(only) != 3 || math.Abs(only[0].Score-1) > 1e-9 || only[0].ID != "a" {
+		t.Errorf("lexical-only = %+v", only)
+	}
+
+	if fuseRankings(nil, nil) != nil {
+		t.Error("expected nil for no results")
+	}
+}
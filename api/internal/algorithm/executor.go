@@ -15,6 +15,7 @@ type Executor struct {
 	longitude float64
 	elevation float64 // Elevation in meters above sea level
 	timezone  *time.Location
+	engine    astro.SolarEngine
 
 	// Cached core calculations
 	sunTimes   *astro.SunTimes
@@ -31,8 +32,14 @@ func NewExecutor(date time.Time, latitude, longitude float64, tz *time.Location)
 // NewExecutorWithElevation creates a new algorithm executor with elevation support
 // Elevation is in meters above sea level and affects sunrise/sunset calculations
 func NewExecutorWithElevation(date time.Time, latitude, longitude, elevation float64, tz *time.Location) *Executor {
+	return NewExecutorWithEngine(date, latitude, longitude, elevation, tz, astro.DefaultEngine)
+}
+
+// NewExecutorWithEngine creates a new algorithm executor that calculates with
+// the given solar engine, such as the publisher's choice of SPA
+func NewExecutorWithEngine(date time.Time, latitude, longitude, elevation float64, tz *time.Location, engine astro.SolarEngine) *Executor {
 	// Calculate core sun times with elevation adjustment
-	sunTimes := astro.CalculateSunTimesWithElevation(date, latitude, longitude, elevation, tz)
+	sunTimes := engine.SunTimes(date, latitude, longitude, elevation, tz)
 
 	// Calculate alos/tzeis 72 for MGA calculations
 	alos72 := astro.SubtractMinutes(sunTimes.Sunrise, 72)
@@ -44,6 +51,7 @@ func NewExecutorWithElevation(date time.Time, latitude, longitude, elevation flo
 		longitude:  longitude,
 		elevation:  elevation,
 		timezone:   tz,
+		engine:     engine,
 		sunTimes:   sunTimes,
 		alos72:     alos72,
 		tzeis72:    tzeis72,
@@ -144,7 +152,7 @@ func (e *Executor) calculateSolarAngle(config *ZmanConfig) (time.Time, error) {
 	// But we need to check the context - we return the appropriate time based on usage
 
 	// Use elevation-adjusted calculation
-	dawn, dusk := astro.SunTimeAtAngleWithElevation(e.date, e.latitude, e.longitude, e.elevation, e.timezone, degrees)
+	dawn, dusk := e.engine.SunTimeAtAngle(e.date, e.latitude, e.longitude, e.elevation, e.timezone, degrees)
 
 	// If degrees > 10, it's likely alos (return dawn)
 	// If degrees < 10, it's likely tzeis (return dusk)
@@ -0,0 +1,64 @@
+package astro
+
+import (
+	"fmt"
+	"time"
+)
+
+// SolarEngine computes sun times for a date and location. Implementations
+// differ in the solar model they use; results are in tz and the zero time
+// means the event does not occur that day (polar day or night).
+type SolarEngine interface {
+	// Name is the engine's identifier as stored on publishers
+	Name() string
+
+	// SunTimes calculates sunrise, solar noon and sunset
+	SunTimes(date time.Time, latitude, longitude, elevation float64, tz *time.Location) *SunTimes
+
+	// SunTimeAtAngle calculates when the sun is angle degrees below the
+	// horizon before sunrise (dawn) and after sunset (dusk)
+	SunTimeAtAngle(date time.Time, latitude, longitude, elevation float64, tz *time.Location, angle float64) (dawn, dusk time.Time)
+}
+
+// Solar engine names
+const (
+	EngineNOAA = "noaa" // NOAA Solar Calculator (default)
+	EngineSPA  = "spa"  // NREL Solar Position Algorithm
+)
+
+// EngineNames lists the selectable engines
+var EngineNames = []string{EngineNOAA, EngineSPA}
+
+// DefaultEngine is used wherever no engine has been selected
+var DefaultEngine SolarEngine = NOAAEngine{}
+
+// EngineByName returns the engine with the given name. An empty name selects
+// the default engine; the SPA engine uses the standard atmosphere and an
+// estimated ΔT.
+func EngineByName(name string) (SolarEngine, error) {
+	switch name {
+	case "":
+		return DefaultEngine, nil
+	case EngineNOAA:
+		return NOAAEngine{}, nil
+	case EngineSPA:
+		return NewSPAEngine(), nil
+	}
+	return nil, fmt.Errorf("unknown solar engine %q (expected one of %v)", name, EngineNames)
+}
+
+// NOAAEngine is the NOAA Solar Calculator implemented in sun.go
+type NOAAEngine struct{}
+
+// Name implements SolarEngine
+func (NOAAEngine) Name() string { return EngineNOAA }
+
+// SunTimes implements SolarEngine
+func (NOAAEngine) SunTimes(date time.Time, latitude, longitude, elevation float64, tz *time.Location) *SunTimes {
+	return CalculateSunTimesWithElevation(date, latitude, longitude, elevation, tz)
+}
+
+// SunTimeAtAngle implements SolarEngine
+func (NOAAEngine) SunTimeAtAngle(date time.Time, latitude, longitude, elevation float64, tz *time.Location, angle float64) (dawn, dusk time.Time) {
+	return SunTimeAtAngleWithElevation(date, latitude, longitude, elevation, tz, angle)
+}
@@ -0,0 +1,72 @@
+package astro
+
+import (
+	"time"
+)
+
+// Horizon is the apparent altitude of an observer's local horizon - the
+// skyline formed by terrain - as a function of azimuth
+type Horizon interface {
+	// Altitude returns the horizon's apparent altitude in degrees at the
+	// given azimuth (degrees eastward from north). Negative values are below
+	// the astronomical horizon, as seen from high ground over low terrain.
+	Altitude(azimuth float64) float64
+}
+
+// horizonScanStep is the interval at which the sun is tested against the
+// horizon before the crossing is refined. The sun moves about a quarter of
+// its own diameter in this time, so no gap in the skyline is skipped.
+const horizonScanStep = time.Minute
+
+// VisibleSunTimesOverHorizon finds when the upper limb of the sun first
+// clears the horizon in the morning and last clears it in the evening.
+// Because a skyline varies with azimuth, the sun's apparent altitude and
+// azimuth are followed with the SPA engine rather than solving for a single
+// zenith angle. Elevation is the observer's height above sea level, used for
+// parallax; the horizon already accounts for the view from that height.
+// A zero time means the sun does not clear the horizon that day.
+func VisibleSunTimesOverHorizon(date time.Time, latitude, longitude, elevation float64, tz *time.Location, horizon Horizon) (sunrise, sunset time.Time) {
+	spa := NewSPAEngine()
+	transit := spa.transit(date, longitude)
+
+	// clearance is how far the sun's upper limb is above the skyline, in degrees
+	clearance := func(t time.Time) float64 {
+		topo := spaGeocentricAt(julianDate(t), spa.deltaT(t)).topocentric(latitude, longitude, elevation)
+		upperLimb := topo.e0 + sunRadiusDegrees
+		apparent := upperLimb + spa.atmosphereFactor()*standardRefraction(upperLimb)
+		return apparent - horizon.Altitude(topo.azimuth(latitude))
+	}
+
+	sunrise = firstClearance(transit.Add(-12*time.Hour), transit, horizonScanStep, clearance)
+	sunset = firstClearance(transit.Add(12*time.Hour), transit, -horizonScanStep, clearance)
+	return inZone(sunrise, tz), inZone(sunset, tz)
+}
+
+// firstClearance walks from start toward end in steps of step (negative to
+// walk backward in time) and returns the first instant the sun clears the
+// horizon, refined to the second. It returns the zero time if the sun never
+// clears it, or is already clear at start (it never drops behind the horizon).
+func firstClearance(start, end time.Time, step time.Duration, clearance func(time.Time) float64) time.Time {
+	if clearance(start) >= 0 {
+		return time.Time{}
+	}
+	before := start
+	for t := start.Add(step); (step > 0 && !t.After(end)) || (step < 0 && !t.Before(end)); t = t.Add(step) {
+		if clearance(t) < 0 {
+			before = t
+			continue
+		}
+		// The crossing lies between before (hidden) and t (clear)
+		hidden, clear := before, t
+		for clear.Sub(hidden).Abs() > 50*time.Millisecond {
+			mid := hidden.Add(clear.Sub(hidden) / 2)
+			if clearance(mid) >= 0 {
+				clear = mid
+			} else {
+				hidden = mid
+			}
+		}
+		return clear.Round(time.Second)
+	}
+	return time.Time{}
+}
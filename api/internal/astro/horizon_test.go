@@ -0,0 +1,69 @@
+package astro
+
+import (
+	"testing"
+	"time"
+)
+
+// flatHorizon is a skyline at a constant altitude in every direction
+type flatHorizon float64
+
+func (f flatHorizon) Altitude(float64) float64 { return float64(f) }
+
+// ridgeHorizon raises the skyline to altitude between two azimuths
+type ridgeHorizon struct {
+	from, to, altitude float64
+}
+
+func (r ridgeHorizon) Altitude(azimuth float64) float64 {
+	if azimuth >= r.from && azimuth <= r.to {
+		return r.altitude
+	}
+	return 0
+}
+
+func TestVisibleSunTimesOverFlatHorizon(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	date := time.Date(2024, 3, 21, 0, 0, 0, 0, loc)
+
+	// A flat 0° skyline is the standard visible sunrise and sunset
+	want := NewSPAEngine().SunTimes(date, 31.7683, 35.2137, 0, loc)
+	rise, set := VisibleSunTimesOverHorizon(date, 31.7683, 35.2137, 0, loc, flatHorizon(0))
+
+	if diff := rise.Sub(want.Sunrise).Abs(); diff > 2*time.Second {
+		t.Errorf("sunrise %s, SPA %s", rise.Format("15:04:05"), want.Sunrise.Format("15:04:05"))
+	}
+	if diff := set.Sub(want.Sunset).Abs(); diff > 2*time.Second {
+		t.Errorf("sunset %s, SPA %s", set.Format("15:04:05"), want.Sunset.Format("15:04:05"))
+	}
+}
+
+func TestVisibleSunTimesOverRidge(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	date := time.Date(2024, 3, 21, 0, 0, 0, 0, loc)
+
+	flatRise, flatSet := VisibleSunTimesOverHorizon(date, 31.7683, 35.2137, 0, loc, flatHorizon(0))
+	rise, set := VisibleSunTimesOverHorizon(date, 31.7683, 35.2137, 0, loc, ridgeHorizon{from: 45, to: 135, altitude: 5})
+
+	// At the equinox the sun climbs about 0.2° a minute at this latitude, so a
+	// 5° ridge in the east delays sunrise by roughly 25 minutes
+	delay := rise.Sub(flatRise)
+	if delay < 22*time.Minute || delay > 32*time.Minute {
+		t.Errorf("sunrise delayed %s by a 5° ridge, want about 25m", delay)
+	}
+	// The western horizon is flat
+	if !set.Equal(flatSet) {
+		t.Errorf("sunset %s, want %s with a flat western horizon", set.Format("15:04:05"), flatSet.Format("15:04:05"))
+	}
+}
+
+func TestVisibleSunTimesNeverClear(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	date := time.Date(2024, 3, 21, 0, 0, 0, 0, loc)
+
+	// A skyline higher than the sun ever gets
+	rise, set := VisibleSunTimesOverHorizon(date, 31.7683, 35.2137, 0, loc, flatHorizon(80))
+	if !rise.IsZero() || !set.IsZero() {
+		t.Errorf("got %v / %v, want zero times", rise, set)
+	}
+}
@@ -0,0 +1,232 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Lunar position
+// Reference: Meeus, J. (1998), "Astronomical Algorithms", 2nd ed., ch. 47-48
+//
+// The truncated ELP-2000/82 series places the moon to about 10" in longitude
+// and 4" in latitude, which puts moonrise and moonset within a few seconds -
+// well inside the night-to-night variation of refraction at the horizon. The
+// sun, nutation and sidereal time come from the SPA model.
+
+const (
+	// earthRadiusKm is the Earth's equatorial radius
+	earthRadiusKm = 6378.14
+
+	// auKm is the astronomical unit
+	auKm = 149597870.7
+
+	// moonRadiusRatio is the moon's radius in Earth equatorial radii, which
+	// relates its semidiameter to its horizontal parallax
+	moonRadiusRatio = 0.272481
+
+	// moonScanStep is the interval at which the moon's altitude is tested
+	// for a horizon crossing before the crossing is refined
+	moonScanStep = 10 * time.Minute
+)
+
+// MoonPosition is the moon's position for an observer at an instant
+type MoonPosition struct {
+	Time           time.Time
+	Longitude      float64 // apparent geocentric ecliptic longitude, degrees
+	Latitude       float64 // geocentric ecliptic latitude, degrees
+	Distance       float64 // Earth-moon distance, km
+	RightAscension float64 // apparent geocentric, degrees
+	Declination    float64 // apparent geocentric, degrees
+	Altitude       float64 // topocentric altitude of the center without refraction, degrees
+	Azimuth        float64 // topocentric azimuth eastward from north, degrees
+	Illumination   float64 // illuminated fraction of the disc, 0 (new) to 1 (full)
+	Elongation     float64 // moon's longitude less the sun's, 0-360: 0 new, 180 full
+}
+
+// Moon phases reported by MoonPosition.Phase
+const (
+	MoonNew            = "new"
+	MoonWaxingCrescent = "waxing_crescent"
+	MoonFirstQuarter   = "first_quarter"
+	MoonWaxingGibbous  = "waxing_gibbous"
+	MoonFull           = "full"
+	MoonWaningGibbous  = "waning_gibbous"
+	MoonLastQuarter    = "last_quarter"
+	MoonWaningCrescent = "waning_crescent"
+)
+
+// moonPhases are the phases in order of elongation, each spanning 45°
+// centered on a multiple of 45°
+var moonPhases = [8]string{
+	MoonNew, MoonWaxingCrescent, MoonFirstQuarter, MoonWaxingGibbous,
+	MoonFull, MoonWaningGibbous, MoonLastQuarter, MoonWaningCrescent,
+}
+
+// Phase names the moon's phase from its elongation
+func (p MoonPosition) Phase() string {
+	return moonPhases[int(normalizeDegrees(p.Elongation+22.5)/45)%8]
+}
+
+// moonGeocentric is the moon's geocentric position at an instant, with the
+// SPA solution for the sun at the same instant
+type moonGeocentric struct {
+	lambda, beta float64 // apparent ecliptic longitude and latitude, degrees
+	distance     float64 // km
+	alpha, delta float64 // apparent right ascension and declination, degrees
+	parallax     float64 // equatorial horizontal parallax, degrees
+	sun          spaGeocentric
+}
+
+// moonGeocentricAt evaluates Meeus chapter 47 at t
+func moonGeocentricAt(t time.Time) moonGeocentric {
+	sun := spaGeocentricAt(julianDate(t), EstimateDeltaT(t))
+	jc := (sun.jde - 2451545) / 36525
+
+	// Fundamental arguments, degrees
+	lp := 218.3164477 + jc*(481267.88123421+jc*(-0.0015786+jc*(1.0/538841-jc/65194000)))
+	d := 297.8501921 + jc*(445267.1114034+jc*(-0.0018819+jc*(1.0/545868-jc/113065000)))
+	m := 357.5291092 + jc*(35999.0502909+jc*(-0.0001536+jc/24490000))
+	mp := 134.9633964 + jc*(477198.8675055+jc*(0.0087414+jc*(1.0/69699-jc/14712000)))
+	f := 93.2720950 + jc*(483202.0175233+jc*(-0.0036539+jc*(-1.0/3526000+jc/863310000)))
+	a1 := 119.75 + 131.849*jc
+	a2 := 53.09 + 479264.290*jc
+	a3 := 313.45 + 481266.484*jc
+
+	// Terms involving the sun's mean anomaly shrink with the Earth's orbital eccentricity
+	e := 1 - jc*(0.002516+0.0000074*jc)
+	eccentricity := func(mult float64) float64 {
+		switch math.Abs(mult) {
+		case 1:
+			return e
+		case 2:
+			return e * e
+		}
+		return 1
+	}
+	sin := func(deg float64) float64 { return math.Sin(deg * deg2rad) }
+	cos := func(deg float64) float64 { return math.Cos(deg * deg2rad) }
+
+	var sumL, sumR, sumB float64
+	for _, t := range moonLRTerms {
+		arg := t[0]*d + t[1]*m + t[2]*mp + t[3]*f
+		sumL += t[4] * eccentricity(t[1]) * sin(arg)
+		sumR += t[5] * eccentricity(t[1]) * cos(arg)
+	}
+	for _, t := range moonBTerms {
+		arg := t[0]*d + t[1]*m + t[2]*mp + t[3]*f
+		sumB += t[4] * eccentricity(t[1]) * sin(arg)
+	}
+
+	// Venus, Jupiter and the Earth's flattening
+	sumL += 3958*sin(a1) + 1962*sin(lp-f) + 318*sin(a2)
+	sumB += -2235*sin(lp) + 382*sin(a3) + 175*sin(a1-f) + 175*sin(a1+f) + 127*sin(lp-mp) - 115*sin(lp+mp)
+
+	g := moonGeocentric{
+		lambda:   normalizeDegrees(lp + sumL/1e6 + sun.dPsi),
+		beta:     sumB / 1e6,
+		distance: 385000.56 + sumR/1000,
+		sun:      sun,
+	}
+	g.alpha, g.delta = eclipticToEquatorial(g.lambda, g.beta, sun.eps)
+	g.parallax = math.Asin(earthRadiusKm/g.distance) * rad2deg
+	return g
+}
+
+// eclipticToEquatorial converts ecliptic longitude and latitude to right
+// ascension and declination for obliquity eps (all in degrees)
+func eclipticToEquatorial(lambda, beta, eps float64) (alpha, delta float64) {
+	lambdaRad, betaRad, epsRad := lambda*deg2rad, beta*deg2rad, eps*deg2rad
+	alpha = normalizeDegrees(rad2deg * math.Atan2(math.Sin(lambdaRad)*math.Cos(epsRad)-math.Tan(betaRad)*math.Sin(epsRad), math.Cos(lambdaRad)))
+	delta = rad2deg * math.Asin(math.Sin(betaRad)*math.Cos(epsRad)+math.Cos(betaRad)*math.Sin(epsRad)*math.Sin(lambdaRad))
+	return alpha, delta
+}
+
+// topocentric corrects the moon's position for an observer
+func (g moonGeocentric) topocentric(latitude, longitude, elevation float64) spaTopocentric {
+	hourAngle := normalizeDegrees(g.sun.nu + longitude - g.alpha)
+	return topocentricPosition(hourAngle, g.delta, g.parallax, latitude, elevation)
+}
+
+// illumination returns the illuminated fraction of the disc and the
+// elongation in longitude from the sun (Meeus 48.2-48.4)
+func (g moonGeocentric) illumination() (fraction, elongation float64) {
+	elongation = normalizeDegrees(g.lambda - g.sun.lambda)
+	psi := math.Acos(math.Cos(g.beta*deg2rad) * math.Cos(elongation*deg2rad))
+	sunDistance := g.sun.r * auKm
+	phaseAngle := math.Atan2(sunDistance*math.Sin(psi), g.distance-sunDistance*math.Cos(psi))
+	return (1 + math.Cos(phaseAngle)) / 2, elongation
+}
+
+// MoonPositionAt computes the moon's position at t for the observer
+func MoonPositionAt(t time.Time, latitude, longitude, elevation float64) MoonPosition {
+	g := moonGeocentricAt(t)
+	topo := g.topocentric(latitude, longitude, elevation)
+	fraction, elongation := g.illumination()
+
+	return MoonPosition{
+		Time:           t,
+		Longitude:      g.lambda,
+		Latitude:       g.beta,
+		Distance:       g.distance,
+		RightAscension: g.alpha,
+		Declination:    g.delta,
+		Altitude:       topo.e0,
+		Azimuth:        topo.azimuth(latitude),
+		Illumination:   fraction,
+		Elongation:     elongation,
+	}
+}
+
+// MoonIllumination returns the illuminated fraction of the moon's disc at t
+func MoonIllumination(t time.Time) float64 {
+	fraction, _ := moonGeocentricAt(t).illumination()
+	return fraction
+}
+
+// MoonTimes finds when the moon's upper limb rises above and sets below the
+// sea-level horizon during the local calendar day of date. The moon rises
+// about 50 minutes later each day, so once a month there is no moonrise (or
+// moonset) within the day; that time is zero.
+func MoonTimes(date time.Time, latitude, longitude, elevation float64, tz *time.Location) (moonrise, moonset time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, tz)
+	end := start.AddDate(0, 0, 1)
+
+	// clearance is how far the upper limb is above the horizon, in degrees
+	dip := calcElevationAdjustment(elevation)
+	clearance := func(t time.Time) float64 {
+		g := moonGeocentricAt(t)
+		semidiameter := moonRadiusRatio * g.parallax
+		return g.topocentric(latitude, longitude, elevation).e0 + semidiameter + horizonRefractionDegrees + dip
+	}
+
+	prevT, prev := start, clearance(start)
+	for prevT.Before(end) {
+		t := prevT.Add(moonScanStep)
+		if t.After(end) {
+			t = end
+		}
+		cur := clearance(t)
+		switch {
+		case prev < 0 && cur >= 0 && moonrise.IsZero():
+			moonrise = refineCrossing(prevT, t, clearance)
+		case prev >= 0 && cur < 0 && moonset.IsZero():
+			moonset = refineCrossing(t, prevT, clearance)
+		}
+		prevT, prev = t, cur
+	}
+	return inZone(moonrise, tz), inZone(moonset, tz)
+}
+
+// refineCrossing bisects between a time below the horizon and a time above it
+// and returns the crossing to the second
+func refineCrossing(below, above time.Time, clearance func(time.Time) float64) time.Time {
+	for above.Sub(below).Abs() > 50*time.Millisecond {
+		mid := below.Add(above.Sub(below) / 2)
+		if clearance(mid) >= 0 {
+			above = mid
+		} else {
+			below = mid
+		}
+	}
+	return below.Add(above.Sub(below) / 2).Round(time.Second)
+}
@@ -0,0 +1,166 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Meeus example 47.a: the moon on 1992 April 12 at 0h TD
+func TestMoonMeeusExample(t *testing.T) {
+	td := time.Date(1992, 4, 12, 0, 0, 0, 0, time.UTC)
+	ut := td.Add(-time.Duration(EstimateDeltaT(td) * float64(time.Second)))
+	g := moonGeocentricAt(ut)
+
+	assertNear(t, "λ", g.lambda, 133.167265, 1e-5)
+	assertNear(t, "β", g.beta, -3.229126, 1e-5)
+	assertNear(t, "Δ", g.distance, 368409.7, 0.1)
+	assertNear(t, "π", g.parallax, 0.991990, 1e-5)
+	assertNear(t, "α", g.alpha, 134.688470, 1e-5)
+	assertNear(t, "δ", g.delta, 13.768368, 1e-5)
+
+	// Example 48.a: illuminated fraction at the same instant
+	k, _ := g.illumination()
+	assertNear(t, "k", k, 0.6786, 1e-4)
+}
+
+// Published lunar phase instants (UT) for 2024
+func TestMoonPhases(t *testing.T) {
+	tests := []struct {
+		time         time.Time
+		elongation   float64
+		illumination float64
+		phase        string
+	}{
+		{time.Date(2024, 1, 11, 11, 57, 0, 0, time.UTC), 0, 0, MoonNew},
+		{time.Date(2024, 1, 25, 17, 54, 0, 0, time.UTC), 180, 1, MoonFull},
+		{time.Date(2024, 4, 8, 18, 21, 0, 0, time.UTC), 0, 0, MoonNew}, // total solar eclipse
+		{time.Date(2024, 4, 15, 19, 13, 0, 0, time.UTC), 90, 0.5, MoonFirstQuarter},
+		{time.Date(2024, 4, 23, 23, 49, 0, 0, time.UTC), 180, 1, MoonFull},
+	}
+	for _, tt := range tests {
+		p := MoonPositionAt(tt.time, 0, 0, 0)
+		elongation := p.Elongation
+		if tt.elongation == 0 && elongation > 180 {
+			elongation -= 360
+		}
+		// The moon moves 0.5° in elongation an hour; instants are to the minute
+		assertNear(t, tt.time.Format("2006-01-02 elongation"), elongation, tt.elongation, 0.02)
+		// At new and full moon the moon can stand up to 5° off the ecliptic
+		assertNear(t, tt.time.Format("2006-01-02 illumination"), p.Illumination, tt.illumination, 0.003)
+		if got := p.Phase(); got != tt.phase {
+			t.Errorf("%s: phase %s, want %s", tt.time.Format("2006-01-02"), got, tt.phase)
+		}
+	}
+}
+
+func TestMoonTimes(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	lat, lng := 31.7683, 35.2137
+
+	var prevRise time.Time
+	for day := 20; day <= 27; day++ {
+		date := time.Date(2024, 4, day, 0, 0, 0, 0, loc)
+		rise, set := MoonTimes(date, lat, lng, 0, loc)
+		if rise.IsZero() || set.IsZero() {
+			t.Fatalf("2024-04-%d: rise %v, set %v", day, rise, set)
+		}
+		if rise.Day() != day || set.Day() != day {
+			t.Errorf("2024-04-%d: rise %s, set %s fall outside the day", day, rise, set)
+		}
+
+		// At moonrise the upper limb sits on the refracted horizon
+		p := MoonPositionAt(rise, lat, lng, 0)
+		assertNear(t, "upper limb at moonrise", p.Altitude+moonRadiusRatio*math.Asin(earthRadiusKm/p.Distance)*rad2deg+horizonRefractionDegrees, 0, 0.01)
+		if p.Azimuth > 180 {
+			t.Errorf("2024-04-%d: moon rises at azimuth %.1f°, want the eastern sky", day, p.Azimuth)
+		}
+
+		// The moon rises roughly 50 minutes later each day
+		if !prevRise.IsZero() {
+			if delay := rise.Sub(prevRise) - 24*time.Hour; delay < 40*time.Minute || delay > 70*time.Minute {
+				t.Errorf("2024-04-%d: moonrise %s later than the day before", day, delay)
+			}
+		}
+		prevRise = rise
+	}
+
+	// Full moon rises around sunset
+	sun := NewSPAEngine().SunTimes(time.Date(2024, 4, 23, 0, 0, 0, 0, loc), lat, lng, 0, loc)
+	rise, _ := MoonTimes(time.Date(2024, 4, 23, 0, 0, 0, 0, loc), lat, lng, 0, loc)
+	if diff := rise.Sub(sun.Sunset).Abs(); diff > 45*time.Minute {
+		t.Errorf("full moon rises %s, sunset %s", rise.Format("15:04:05"), sun.Sunset.Format("15:04:05"))
+	}
+}
+
+// Reference moonrise and moonset to the minute, local time, sea level, with
+// USNO's definition: the upper limb on the horizon with 34' of refraction.
+// The times come from an independent low-precision lunar theory (Montenbruck
+// & Pfleger's MiniMoon with Meeus's parallax series), not from this package;
+// the table is printed by:
+//
+//	cd api && go run ./cmd/gen-moon-reference
+func TestMoonTimesReference(t *testing.T) {
+	jerusalem, _ := time.LoadLocation("Asia/Jerusalem")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	tests := []struct {
+		place     string
+		lat, lng  float64
+		tz        *time.Location
+		date      string
+		rise, set string // "" when there is none that day
+	}{
+		{"Jerusalem", 31.7683, 35.2137, jerusalem, "2024-01-18", "11:11", ""},
+		{"Jerusalem", 31.7683, 35.2137, jerusalem, "2024-04-23", "18:54", "05:34"},
+		{"Jerusalem", 31.7683, 35.2137, jerusalem, "2024-07-10", "09:42", "22:50"},
+		{"Jerusalem", 31.7683, 35.2137, jerusalem, "2024-10-17", "18:00", "06:26"},
+		{"Jerusalem", 31.7683, 35.2137, jerusalem, "2025-03-03", "07:59", "21:42"},
+		{"Jerusalem", 31.7683, 35.2137, jerusalem, "2025-12-04", "15:51", "05:42"},
+		{"New York", 40.7128, -74.0060, newYork, "2024-01-18", "11:24", "00:32"},
+		{"New York", 40.7128, -74.0060, newYork, "2024-04-23", "19:44", "05:47"},
+		{"New York", 40.7128, -74.0060, newYork, "2024-07-10", "10:07", "23:20"},
+		{"New York", 40.7128, -74.0060, newYork, "2024-10-17", "18:14", "07:16"},
+		{"New York", 40.7128, -74.0060, newYork, "2025-03-03", "08:12", "22:40"},
+		{"New York", 40.7128, -74.0060, newYork, "2025-12-04", "15:54", "06:52"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.place+" "+tt.date, func(t *testing.T) {
+			date, _ := time.ParseInLocation("2006-01-02", tt.date, tt.tz)
+			rise, set := MoonTimes(date, tt.lat, tt.lng, 0, tt.tz)
+			for _, ev := range []struct {
+				name string
+				got  time.Time
+				want string
+			}{{"moonrise", rise, tt.rise}, {"moonset", set, tt.set}} {
+				if ev.want == "" {
+					if !ev.got.IsZero() {
+						t.Errorf("%s = %s, want none", ev.name, ev.got.Format("15:04:05"))
+					}
+					continue
+				}
+				want, _ := time.ParseInLocation("2006-01-02 15:04", tt.date+" "+ev.want, tt.tz)
+				if diff := ev.got.Sub(want).Abs(); ev.got.IsZero() || diff > 2*time.Minute {
+					t.Errorf("%s = %s, want %s ±2min", ev.name, ev.got.Format("15:04:05"), ev.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMoonTimesMissingEvent(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+
+	// Once a month moonrise slips past midnight and one day has none
+	missing := 0
+	for day := 1; day <= 30; day++ {
+		rise, _ := MoonTimes(time.Date(2024, 4, day, 0, 0, 0, 0, loc), 31.7683, 35.2137, 0, loc)
+		if rise.IsZero() {
+			missing++
+		}
+	}
+	if missing != 1 {
+		t.Errorf("%d days without moonrise in April 2024, want 1", missing)
+	}
+}
@@ -0,0 +1,322 @@
+// Package reference holds a checked-in corpus of zmanim computed by an
+// independent implementation (hebcal-go's zmanim package, built on the
+// go-sunrise solar model) and compares our calculations against it.
+//
+// The corpus is regenerated with:
+//
+//	cd api && go run ./cmd/gen-reference-vectors
+//
+// Regenerate only when the reference library or the set of locations, dates
+// or zmanim changes; never to make a failing comparison pass.
+package reference
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+//go:embed vectors.json
+var vectorsJSON []byte
+
+//go:embed drift_baseline.json
+var baselineJSON []byte
+
+// BaselinePath is the baseline file relative to the api module root
+const BaselinePath = "internal/astro/reference/drift_baseline.json"
+
+// Kind says how a zman is derived, so implementations without a DSL can compute it
+type Kind string
+
+const (
+	KindSunrise   Kind = "sunrise"    // sea-level sunrise
+	KindSunset    Kind = "sunset"     // sea-level sunset
+	KindDawnAngle Kind = "dawn_angle" // sun Value degrees below the horizon, morning
+	KindDuskAngle Kind = "dusk_angle" // sun Value degrees below the horizon, evening
+	KindGRAHours  Kind = "gra_hours"  // Value proportional hours after sunrise (sunrise to sunset day)
+	KindMGAHours  Kind = "mga_hours"  // Value proportional hours after sunrise-72min (72-minute day)
+)
+
+// Zman is one reference zman and its equivalent DSL formula
+type Zman struct {
+	Key     string  `json:"key"`
+	Formula string  `json:"formula"`
+	Kind    Kind    `json:"kind"`
+	Value   float64 `json:"value,omitempty"`
+}
+
+// Location is a reference location
+type Location struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timezone  string  `json:"timezone"`
+}
+
+// Case holds the reference times for one location and date. A nil time means
+// the zman does not occur that day (polar day or night).
+type Case struct {
+	Location string             `json:"location"`
+	Date     string             `json:"date"`
+	Note     string             `json:"note,omitempty"` // e.g. "DST starts"
+	Times    map[string]*string `json:"times"`          // zman key -> RFC 3339 local time
+}
+
+// Corpus is the checked-in set of reference vectors
+type Corpus struct {
+	Source    string     `json:"source"`
+	Generator string     `json:"generator"`
+	Zmanim    []Zman     `json:"zmanim"`
+	Locations []Location `json:"locations"`
+	Cases     []Case     `json:"cases"`
+}
+
+// Vector is a single (location, date, zman) reference value
+type Vector struct {
+	Location Location
+	Date     time.Time // local midnight
+	Note     string
+	Zman     Zman
+	Expected time.Time // zero when the zman does not occur
+}
+
+// Load parses the embedded corpus
+func Load() (*Corpus, error) {
+	var c Corpus
+	if err := json.Unmarshal(vectorsJSON, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse reference vectors: %w", err)
+	}
+	return &c, nil
+}
+
+// Vectors flattens the corpus into individual reference values
+func (c *Corpus) Vectors() ([]Vector, error) {
+	locations := make(map[string]Location, len(c.Locations))
+	for _, l := range c.Locations {
+		locations[l.Name] = l
+	}
+
+	var vectors []Vector
+	for _, cs := range c.Cases {
+		l, ok := locations[cs.Location]
+		if !ok {
+			return nil, fmt.Errorf("case %s %s: unknown location", cs.Location, cs.Date)
+		}
+		tz, err := time.LoadLocation(l.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("location %s: %w", l.Name, err)
+		}
+		date, err := time.ParseInLocation("2006-01-02", cs.Date, tz)
+		if err != nil {
+			return nil, fmt.Errorf("case %s %s: %w", cs.Location, cs.Date, err)
+		}
+		for _, z := range c.Zmanim {
+			value, ok := cs.Times[z.Key]
+			if !ok {
+				continue
+			}
+			v := Vector{Location: l, Date: date, Note: cs.Note, Zman: z}
+			if value != nil {
+				if v.Expected, err = time.Parse(time.RFC3339, *value); err != nil {
+					return nil, fmt.Errorf("case %s %s %s: %w", cs.Location, cs.Date, z.Key, err)
+				}
+			}
+			vectors = append(vectors, v)
+		}
+	}
+	return vectors, nil
+}
+
+// Tolerance is the largest difference from the reference accepted for v.
+// go-sunrise uses a simplified sunrise equation that is itself only good to a
+// minute or two; near the polar circles the sun crosses the horizon so
+// shallowly that small declination differences move the time by many minutes.
+// Subtler regressions are caught by comparing drift with the baseline.
+func Tolerance(v Vector) time.Duration {
+	lat := math.Abs(v.Location.Latitude)
+	switch {
+	case lat < 50:
+		return 2 * time.Minute
+	case lat < 60:
+		return 3 * time.Minute
+	default:
+		return 15 * time.Minute
+	}
+}
+
+// CalcFunc computes v with the implementation under test. It returns the zero
+// time when the zman does not occur.
+type CalcFunc func(v Vector) (time.Time, error)
+
+// Mismatch is a vector the implementation disagrees with
+type Mismatch struct {
+	Vector
+	Got    time.Time
+	Diff   time.Duration
+	Reason string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s %s %s: %s (expected %s, got %s)", m.Location.Name, m.Date.Format("2006-01-02"),
+		m.Zman.Key, m.Reason, formatTime(m.Expected), formatTime(m.Got))
+}
+
+// Drift summarizes how far one zman is from the reference across all vectors
+type Drift struct {
+	Zman     string
+	Compared int
+	Mean     time.Duration
+	Max      time.Duration
+	WorstAt  string // location and date of Max
+}
+
+// Report is the outcome of comparing an implementation with the corpus
+type Report struct {
+	Checked    int
+	Mismatches []Mismatch
+	Drift      []Drift
+}
+
+// Check compares calc with every vector
+func Check(vectors []Vector, calc CalcFunc) Report {
+	report := Report{Checked: len(vectors)}
+	drift := make(map[string]*Drift)
+	var order []string
+	total := make(map[string]time.Duration)
+
+	for _, v := range vectors {
+		got, err := calc(v)
+		if err != nil {
+			report.Mismatches = append(report.Mismatches, Mismatch{Vector: v, Reason: err.Error()})
+			continue
+		}
+		if got.IsZero() || v.Expected.IsZero() {
+			if got.IsZero() != v.Expected.IsZero() && !nearPolarBoundary(v) {
+				report.Mismatches = append(report.Mismatches, Mismatch{Vector: v, Got: got, Reason: "occurrence differs"})
+			}
+			continue
+		}
+
+		diff := got.Sub(v.Expected)
+		if diff < 0 {
+			diff = -diff
+		}
+		d, ok := drift[v.Zman.Key]
+		if !ok {
+			d = &Drift{Zman: v.Zman.Key}
+			drift[v.Zman.Key] = d
+			order = append(order, v.Zman.Key)
+		}
+		d.Compared++
+		total[v.Zman.Key] += diff
+		if diff > d.Max {
+			d.Max = diff
+			d.WorstAt = v.Location.Name + " " + v.Date.Format("2006-01-02")
+		}
+		if diff > Tolerance(v) {
+			report.Mismatches = append(report.Mismatches, Mismatch{Vector: v, Got: got, Diff: diff,
+				Reason: fmt.Sprintf("off by %s (tolerance %s)", diff.Round(time.Second), Tolerance(v))})
+		}
+	}
+
+	for _, key := range order {
+		d := drift[key]
+		d.Mean = total[key] / time.Duration(d.Compared)
+		report.Drift = append(report.Drift, *d)
+	}
+	sort.SliceStable(report.Mismatches, func(i, j int) bool { return report.Mismatches[i].Diff > report.Mismatches[j].Diff })
+	return report
+}
+
+// BaselineDrift is the recorded drift of one zman
+type BaselineDrift struct {
+	Compared    int     `json:"compared"`
+	MeanSeconds float64 `json:"mean_seconds"`
+	MaxSeconds  float64 `json:"max_seconds"`
+}
+
+// Baseline is the drift of internal/astro from the corpus when it was last
+// accepted. Any change to the solar algorithm shows up as a change in drift.
+type Baseline map[string]BaselineDrift
+
+// Drift may not grow over the baseline. The slack only absorbs rounding:
+// vectors are to the second, and the baseline to a tenth of one.
+const (
+	baselineMeanSlack = 100 * time.Millisecond
+	baselineMaxSlack  = time.Second
+)
+
+// LoadBaseline parses the embedded drift baseline
+func LoadBaseline() (Baseline, error) {
+	var b Baseline
+	if err := json.Unmarshal(baselineJSON, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse drift baseline: %w", err)
+	}
+	return b, nil
+}
+
+// Baseline returns the report's drift in baseline form
+func (r Report) Baseline() Baseline {
+	b := make(Baseline, len(r.Drift))
+	for _, d := range r.Drift {
+		b[d.Zman] = BaselineDrift{
+			Compared:    d.Compared,
+			MeanSeconds: math.Round(d.Mean.Seconds()*10) / 10,
+			MaxSeconds:  math.Round(d.Max.Seconds()*10) / 10,
+		}
+	}
+	return b
+}
+
+// Regressions lists zmanim whose drift grew beyond the baseline, or that are
+// compared on fewer days (more failures to occur) than before
+func (r Report) Regressions(baseline Baseline) []string {
+	var regressions []string
+	current := r.Baseline()
+	for _, d := range r.Drift {
+		want, ok := baseline[d.Zman]
+		if !ok {
+			regressions = append(regressions, fmt.Sprintf("%s: no baseline recorded", d.Zman))
+			continue
+		}
+		got := current[d.Zman]
+		if got.Compared < want.Compared {
+			regressions = append(regressions, fmt.Sprintf("%s: compared on %d days, baseline %d", d.Zman, got.Compared, want.Compared))
+		}
+		if got.MeanSeconds > want.MeanSeconds+baselineMeanSlack.Seconds() {
+			regressions = append(regressions, fmt.Sprintf("%s: mean drift %.1fs, baseline %.1fs", d.Zman, got.MeanSeconds, want.MeanSeconds))
+		}
+		if got.MaxSeconds > want.MaxSeconds+baselineMaxSlack.Seconds() {
+			regressions = append(regressions, fmt.Sprintf("%s: max drift %.1fs at %s, baseline %.1fs", d.Zman, got.MaxSeconds, d.WorstAt, want.MaxSeconds))
+		}
+	}
+	return regressions
+}
+
+// nearPolarBoundary reports whether v is at a latitude where one model may
+// find the sun just reaching a depression the other narrowly misses
+func nearPolarBoundary(v Vector) bool {
+	return math.Abs(v.Location.Latitude) >= 60
+}
+
+// WriteDrift prints the per-zman drift table
+func (r Report) WriteDrift(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ZMAN\tCOMPARED\tMEAN\tMAX\tWORST AT")
+	for _, d := range r.Drift {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", d.Zman, d.Compared, d.Mean.Round(time.Second), d.Max.Round(time.Second), d.WorstAt)
+	}
+	tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "none"
+	}
+	return t.Format(time.RFC3339)
+}
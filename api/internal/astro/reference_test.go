@@ -0,0 +1,99 @@
+package astro
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro/reference"
+)
+
+// updateBaseline rewrites the drift baseline after a deliberate algorithm change:
+//
+//	go test ./internal/astro -run TestReferenceVectors -update-baseline
+var updateBaseline = flag.Bool("update-baseline", false, "rewrite the reference drift baseline")
+
+// astroReferenceCalc computes a reference vector with the NOAA functions directly
+func astroReferenceCalc(v reference.Vector) (time.Time, error) {
+	l, date := v.Location, v.Date
+	switch v.Zman.Kind {
+	case reference.KindSunrise:
+		return CalculateSunTimes(date, l.Latitude, l.Longitude, date.Location()).Sunrise, nil
+	case reference.KindSunset:
+		return CalculateSunTimes(date, l.Latitude, l.Longitude, date.Location()).Sunset, nil
+	case reference.KindDawnAngle:
+		dawn, _ := SunTimeAtAngle(date, l.Latitude, l.Longitude, date.Location(), v.Zman.Value)
+		return dawn, nil
+	case reference.KindDuskAngle:
+		_, dusk := SunTimeAtAngle(date, l.Latitude, l.Longitude, date.Location(), v.Zman.Value)
+		return dusk, nil
+	case reference.KindGRAHours:
+		st := CalculateSunTimes(date, l.Latitude, l.Longitude, date.Location())
+		if st.Sunrise.IsZero() || st.Sunset.IsZero() {
+			return time.Time{}, nil
+		}
+		return ShaosZmaniyosGRA(st.Sunrise, st.Sunset, v.Zman.Value), nil
+	case reference.KindMGAHours:
+		st := CalculateSunTimes(date, l.Latitude, l.Longitude, date.Location())
+		if st.Sunrise.IsZero() || st.Sunset.IsZero() {
+			return time.Time{}, nil
+		}
+		return ShaosZmaniyosMGA(SubtractMinutes(st.Sunrise, 72), AddMinutes(st.Sunset, 72), v.Zman.Value), nil
+	}
+	return time.Time{}, nil
+}
+
+// TestReferenceVectors is the regression gate for the NOAA algorithm: every
+// reference vector must agree within reference.Tolerance, and drift may not
+// grow beyond the recorded baseline
+func TestReferenceVectors(t *testing.T) {
+	corpus, err := reference.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := corpus.Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) < 1000 {
+		t.Fatalf("only %d reference vectors", len(vectors))
+	}
+
+	report := reference.Check(vectors, astroReferenceCalc)
+
+	var drift strings.Builder
+	report.WriteDrift(&drift)
+	t.Logf("drift from %s over %d vectors:\n%s", corpus.Source, report.Checked, drift.String())
+
+	for i, m := range report.Mismatches {
+		if i == 20 {
+			t.Errorf("... and %d more mismatches", len(report.Mismatches)-i)
+			break
+		}
+		t.Error(m.String())
+	}
+
+	if *updateBaseline {
+		data, err := json.MarshalIndent(report.Baseline(), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join("reference", filepath.Base(reference.BaselinePath))
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("wrote %s", path)
+		return
+	}
+	baseline, err := reference.LoadBaseline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report.Regressions(baseline) {
+		t.Errorf("drift regression: %s", r)
+	}
+}
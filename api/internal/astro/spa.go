@@ -0,0 +1,365 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// NREL Solar Position Algorithm
+// Reference: Reda, I. & Andreas, A. (2008), "Solar Position Algorithm for
+// Solar Radiation Applications", NREL/TP-560-34302
+//
+// SPA computes the sun's topocentric position to ±0.0003° over -2000..6000.
+// Unlike the NOAA calculator it accounts for ΔT (terrestrial minus universal
+// time), nutation, aberration, parallax and refraction under the observer's
+// pressure and temperature. Event times are found by root-finding on the
+// topocentric altitude rather than with a closed-form hour angle.
+//
+// Elevation is handled as in KosherJava: the horizon dip acos(R / (R + h))
+// lowers the sunrise and sunset threshold, and depression angles are measured
+// from the astronomical horizon. The NOAA calculator adjusts for elevation
+// differently, so the two engines disagree by several minutes at altitude.
+
+// Standard atmosphere assumed when no conditions are given
+const (
+	spaStandardPressure    = 1010.0 // millibars
+	spaStandardTemperature = 10.0   // °C
+)
+
+// Solar disc and refraction at the horizon, in degrees
+const (
+	sunRadiusDegrees         = 0.26667
+	horizonRefractionDegrees = 0.5667
+)
+
+// SPAEngine is a SolarEngine backed by the NREL Solar Position Algorithm
+type SPAEngine struct {
+	// DeltaT is TT - UT1 in seconds. Zero estimates it from the date with
+	// the Espenak-Meeus polynomials.
+	DeltaT float64
+
+	// Pressure is the annual average local pressure in millibars; zero
+	// means the standard 1010 mbar
+	Pressure float64
+
+	// Temperature is the annual average local temperature in °C
+	Temperature float64
+}
+
+// NewSPAEngine returns an SPA engine for the standard atmosphere (1010 mbar,
+// 10 °C) with an estimated ΔT
+func NewSPAEngine() *SPAEngine {
+	return &SPAEngine{Pressure: spaStandardPressure, Temperature: spaStandardTemperature}
+}
+
+// SolarPosition is the sun's position for an observer at an instant
+type SolarPosition struct {
+	Time           time.Time
+	Zenith         float64 // topocentric zenith angle, refraction corrected, degrees
+	Azimuth        float64 // topocentric azimuth eastward from north, degrees
+	Elevation      float64 // 90 - Zenith
+	RightAscension float64 // geocentric, degrees
+	Declination    float64 // geocentric, degrees
+	HourAngle      float64 // observer local hour angle, degrees
+}
+
+// Name implements SolarEngine
+func (e *SPAEngine) Name() string { return EngineSPA }
+
+// Position computes the sun's position at t for the observer
+func (e *SPAEngine) Position(t time.Time, latitude, longitude, elevation float64) SolarPosition {
+	g := spaGeocentricAt(julianDate(t), e.deltaT(t))
+	topo := g.topocentric(latitude, longitude, elevation)
+
+	el := topo.e0 + e.refraction(topo.e0)
+
+	return SolarPosition{
+		Time:           t,
+		Zenith:         90 - el,
+		Azimuth:        topo.azimuth(latitude),
+		Elevation:      el,
+		RightAscension: g.alpha,
+		Declination:    g.delta,
+		HourAngle:      g.hourAngle(longitude),
+	}
+}
+
+// SunTimes implements SolarEngine
+func (e *SPAEngine) SunTimes(date time.Time, latitude, longitude, elevation float64, tz *time.Location) *SunTimes {
+	transit := e.transit(date, longitude)
+	threshold := -(sunRadiusDegrees + horizonRefractionDegrees*e.atmosphereFactor() + calcElevationAdjustment(elevation))
+
+	sunrise := e.crossing(transit.Add(-12*time.Hour), transit, latitude, longitude, elevation, threshold)
+	sunset := e.crossing(transit.Add(12*time.Hour), transit, latitude, longitude, elevation, threshold)
+
+	dayLength := 0.0
+	if !sunrise.IsZero() && !sunset.IsZero() {
+		dayLength = sunset.Sub(sunrise).Minutes()
+	}
+
+	return &SunTimes{
+		Date:             date,
+		Latitude:         latitude,
+		Longitude:        longitude,
+		Elevation:        elevation,
+		Timezone:         tz,
+		Sunrise:          inZone(sunrise, tz),
+		SolarNoon:        inZone(transit.Round(time.Second), tz),
+		Sunset:           inZone(sunset, tz),
+		DayLengthMinutes: dayLength,
+	}
+}
+
+// SunTimeAtAngle implements SolarEngine. The angle is geometric (no
+// refraction or horizon dip), matching the NOAA engine's zenith of 90° + angle
+// at sea level.
+func (e *SPAEngine) SunTimeAtAngle(date time.Time, latitude, longitude, elevation float64, tz *time.Location, angle float64) (dawn, dusk time.Time) {
+	transit := e.transit(date, longitude)
+	threshold := -angle
+
+	dawn = e.crossing(transit.Add(-12*time.Hour), transit, latitude, longitude, elevation, threshold)
+	dusk = e.crossing(transit.Add(12*time.Hour), transit, latitude, longitude, elevation, threshold)
+	return inZone(dawn, tz), inZone(dusk, tz)
+}
+
+// transit finds the sun's upper meridian transit nearest local noon of date
+func (e *SPAEngine) transit(date time.Time, longitude float64) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	t := day.Add(time.Duration((12 - longitude/15) * float64(time.Hour)))
+	for i := 0; i < 3; i++ {
+		h := spaGeocentricAt(julianDate(t), e.deltaT(t)).hourAngle(longitude)
+		if h > 180 {
+			h -= 360
+		}
+		// The sun's hour angle advances 360.985647° per day
+		t = t.Add(-time.Duration(h / 360.985647 * 86400 * float64(time.Second)))
+	}
+	return t
+}
+
+// crossing finds when the topocentric altitude of the sun's center passes
+// threshold between from (the antitransit side) and transit. Between the two
+// the altitude changes monotonically, so the crossing is found by bisection.
+func (e *SPAEngine) crossing(from, transit time.Time, latitude, longitude, elevation, threshold float64) time.Time {
+	altitude := func(t time.Time) float64 {
+		return spaGeocentricAt(julianDate(t), e.deltaT(t)).topocentric(latitude, longitude, elevation).e0
+	}
+	if altitude(transit) < threshold || altitude(from) > threshold {
+		return time.Time{} // the sun stays below (or above) threshold all day
+	}
+
+	low, high := from, transit // altitude(low) <= threshold < altitude(high)
+	for high.Sub(low).Abs() > 50*time.Millisecond {
+		mid := low.Add(high.Sub(low) / 2)
+		if altitude(mid) > threshold {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return low.Add(high.Sub(low) / 2).Round(time.Second)
+}
+
+// atmosphereFactor scales standard refraction to the engine's pressure and temperature
+func (e *SPAEngine) atmosphereFactor() float64 {
+	pressure := e.Pressure
+	if pressure == 0 {
+		pressure = spaStandardPressure
+	}
+	return pressure / 1010 * 283 / (273 + e.Temperature)
+}
+
+// refraction is the atmospheric refraction correction for a sun at true
+// altitude e0, zero once the sun is fully below the horizon
+func (e *SPAEngine) refraction(e0 float64) float64 {
+	if e0 < -(sunRadiusDegrees + horizonRefractionDegrees) {
+		return 0
+	}
+	return e.atmosphereFactor() * standardRefraction(e0)
+}
+
+// standardRefraction is the refraction in degrees, at the standard atmosphere,
+// of a body at true altitude e0 (Sæmundsson's formula, as used by SPA). Below
+// -2° (only seen from high ground) the formula diverges, so the value at -2°
+// is used.
+func standardRefraction(e0 float64) float64 {
+	e0 = math.Max(e0, -2)
+	return 1.02 / (60 * math.Tan((e0+10.3/(e0+5.11))*deg2rad))
+}
+
+// deltaT returns the configured ΔT or an estimate for t
+func (e *SPAEngine) deltaT(t time.Time) float64 {
+	if e.DeltaT != 0 {
+		return e.DeltaT
+	}
+	return EstimateDeltaT(t)
+}
+
+// EstimateDeltaT estimates ΔT (TT - UT1) in seconds with the Espenak-Meeus
+// polynomials, good to about a second for 1950-2050
+func EstimateDeltaT(t time.Time) float64 {
+	y := float64(t.Year()) + (float64(t.YearDay())-0.5)/365.25
+	switch {
+	case y >= 1941 && y < 1961:
+		u := y - 1950
+		return 29.07 + 0.407*u - u*u/233 + u*u*u/2547
+	case y >= 1961 && y < 1986:
+		u := y - 1975
+		return 45.45 + 1.067*u - u*u/260 - u*u*u/718
+	case y >= 1986 && y < 2005:
+		u := y - 2000
+		return 63.86 + 0.3345*u - 0.060374*u*u + 0.0017275*math.Pow(u, 3) + 0.000651814*math.Pow(u, 4) + 0.00002373599*math.Pow(u, 5)
+	case y >= 2005 && y < 2050:
+		u := y - 2000
+		return 62.92 + 0.32217*u + 0.005589*u*u
+	case y >= 2050 && y < 2150:
+		u := (y - 1820) / 100
+		return -20 + 32*u*u - 0.5628*(2150-y)
+	}
+	u := (y - 1820) / 100
+	return -20 + 32*u*u
+}
+
+// spaGeocentric is the sun's geocentric position at an instant
+type spaGeocentric struct {
+	jd, jde      float64 // Julian day and Julian ephemeris day
+	l, b, r      float64 // Earth heliocentric longitude and latitude (degrees) and radius vector (AU)
+	dPsi, dEps   float64 // nutation in longitude and obliquity, degrees
+	eps          float64 // true obliquity of the ecliptic, degrees
+	lambda       float64 // apparent sun longitude, degrees
+	nu           float64 // apparent sidereal time at Greenwich, degrees
+	alpha, delta float64 // geocentric right ascension and declination, degrees
+}
+
+// spaGeocentricAt evaluates SPA steps 3.1-3.9 for Julian day jd
+func spaGeocentricAt(jd, deltaT float64) spaGeocentric {
+	g := spaGeocentric{jd: jd, jde: jd + deltaT/86400}
+	jc := (jd - 2451545) / 36525
+	jce := (g.jde - 2451545) / 36525
+	jme := jce / 10
+
+	g.l = normalizeDegrees(rad2deg * spaEarthValue(spaLTerms, jme))
+	g.b = rad2deg * spaEarthValue(spaBTerms, jme)
+	g.r = spaEarthValue(spaRTerms, jme)
+
+	// Geocentric longitude and latitude
+	theta := normalizeDegrees(g.l + 180)
+	beta := -g.b
+
+	g.dPsi, g.dEps = spaNutation(jce)
+
+	u := jme / 10
+	eps0 := 84381.448 + u*(-4680.93+u*(-1.55+u*(1999.25+u*(-51.38+u*(-249.67+u*(-39.05+u*(7.12+u*(27.87+u*(5.79+u*2.45)))))))))
+	g.eps = eps0/3600 + g.dEps
+
+	aberration := -20.4898 / (3600 * g.r)
+	g.lambda = theta + g.dPsi + aberration
+
+	nu0 := normalizeDegrees(280.46061837 + 360.98564736629*(jd-2451545) + jc*jc*(0.000387933-jc/38710000))
+	g.nu = nu0 + g.dPsi*math.Cos(g.eps*deg2rad)
+
+	lambdaRad, epsRad, betaRad := g.lambda*deg2rad, g.eps*deg2rad, beta*deg2rad
+	g.alpha = normalizeDegrees(rad2deg * math.Atan2(math.Sin(lambdaRad)*math.Cos(epsRad)-math.Tan(betaRad)*math.Sin(epsRad), math.Cos(lambdaRad)))
+	g.delta = rad2deg * math.Asin(math.Sin(betaRad)*math.Cos(epsRad)+math.Cos(betaRad)*math.Sin(epsRad)*math.Sin(lambdaRad))
+	return g
+}
+
+// hourAngle is the observer local hour angle in [0, 360)
+func (g spaGeocentric) hourAngle(longitude float64) float64 {
+	return normalizeDegrees(g.nu + longitude - g.alpha)
+}
+
+// spaTopocentric is a body's position corrected for the observer's parallax
+type spaTopocentric struct {
+	delta float64 // topocentric declination, degrees
+	h     float64 // topocentric local hour angle, degrees
+	e0    float64 // topocentric elevation without refraction, degrees
+}
+
+// topocentric evaluates SPA steps 3.10-3.12 for an observer
+func (g spaGeocentric) topocentric(latitude, longitude, elevation float64) spaTopocentric {
+	// Equatorial horizontal parallax of the sun
+	xi := 8.794 / (3600 * g.r)
+	return topocentricPosition(g.hourAngle(longitude), g.delta, xi, latitude, elevation)
+}
+
+// topocentricPosition corrects a body's geocentric hour angle and declination
+// for the observer's parallax, given the body's equatorial horizontal parallax
+// (all in degrees)
+func topocentricPosition(hourAngle, declination, parallax, latitude, elevation float64) spaTopocentric {
+	latRad := latitude * deg2rad
+	h := hourAngle * deg2rad
+	deltaRad := declination * deg2rad
+	xi := parallax * deg2rad
+
+	u := math.Atan(0.99664719 * math.Tan(latRad))
+	x := math.Cos(u) + elevation/6378140*math.Cos(latRad)
+	y := 0.99664719*math.Sin(u) + elevation/6378140*math.Sin(latRad)
+
+	dAlpha := math.Atan2(-x*math.Sin(xi)*math.Sin(h), math.Cos(deltaRad)-x*math.Sin(xi)*math.Cos(h))
+	deltaPrime := math.Atan2((math.Sin(deltaRad)-y*math.Sin(xi))*math.Cos(dAlpha), math.Cos(deltaRad)-x*math.Sin(xi)*math.Cos(h))
+	hPrime := h - dAlpha
+
+	e0 := math.Asin(math.Sin(latRad)*math.Sin(deltaPrime) + math.Cos(latRad)*math.Cos(deltaPrime)*math.Cos(hPrime))
+	return spaTopocentric{delta: deltaPrime * rad2deg, h: hPrime * rad2deg, e0: e0 * rad2deg}
+}
+
+// azimuth is the topocentric azimuth eastward from north, in degrees
+func (t spaTopocentric) azimuth(latitude float64) float64 {
+	return normalizeDegrees(rad2deg*math.Atan2(math.Sin(t.h*deg2rad),
+		math.Cos(t.h*deg2rad)*math.Sin(latitude*deg2rad)-math.Tan(t.delta*deg2rad)*math.Cos(latitude*deg2rad)) + 180)
+}
+
+// spaEarthValue sums a set of Earth periodic term tables as a polynomial in jme
+func spaEarthValue(tables [][][3]float64, jme float64) float64 {
+	value, power := 0.0, 1.0
+	for _, terms := range tables {
+		sum := 0.0
+		for _, t := range terms {
+			sum += t[0] * math.Cos(t[1]+t[2]*jme)
+		}
+		value += sum * power
+		power *= jme
+	}
+	return value / 1e8
+}
+
+// spaNutation returns the nutation in longitude and obliquity in degrees
+func spaNutation(jce float64) (dPsi, dEps float64) {
+	x := [5]float64{
+		297.85036 + jce*(445267.111480+jce*(-0.0019142+jce/189474)), // mean elongation of the moon
+		357.52772 + jce*(35999.050340+jce*(-0.0001603-jce/300000)),  // mean anomaly of the sun
+		134.96298 + jce*(477198.867398+jce*(0.0086972+jce/56250)),   // mean anomaly of the moon
+		93.27191 + jce*(483202.017538+jce*(-0.0036825+jce/327270)),  // moon's argument of latitude
+		125.04452 + jce*(-1934.136261+jce*(0.0020708+jce/450000)),   // longitude of the moon's ascending node
+	}
+	for i, y := range spaNutationY {
+		arg := (x[0]*y[0] + x[1]*y[1] + x[2]*y[2] + x[3]*y[3] + x[4]*y[4]) * deg2rad
+		c := spaNutationCoeffs[i]
+		dPsi += (c[0] + c[1]*jce) * math.Sin(arg)
+		dEps += (c[2] + c[3]*jce) * math.Cos(arg)
+	}
+	return dPsi / 36000000, dEps / 36000000
+}
+
+// julianDate converts an instant to a Julian day
+func julianDate(t time.Time) float64 {
+	return float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5
+}
+
+// normalizeDegrees limits an angle to [0, 360)
+func normalizeDegrees(d float64) float64 {
+	d = math.Mod(d, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
+
+// inZone converts a time to tz, leaving the zero time (no event) untouched
+func inZone(t time.Time, tz *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(tz)
+}
@@ -31,8 +31,16 @@ const (
 	AlgorithmTTL = 1 * time.Hour
 	// CityTTL is the TTL for city data (7 days - rarely changes)
 	CityTTL = 7 * 24 * time.Hour
+	// TileTTL is the TTL for boundary vector tiles (24 hours - boundaries change only on import)
+	TileTTL = 24 * time.Hour
 )
 
+// TileEntry is a cached vector tile with its ETag
+type TileEntry struct {
+	Data []byte `json:"data"`
+	ETag string `json:"etag"`
+}
+
 // New creates a new Redis cache client
 func New() (*Cache, error) {
 	redisURL := os.Getenv("REDIS_URL")
@@ -64,6 +72,12 @@ func (c *Cache) Close() error {
 	return c.client.Close()
 }
 
+// Client returns the underlying Redis client for components that share the
+// connection (e.g. the distributed rate limiter)
+func (c *Cache) Client() *redis.Client {
+	return c.client
+}
+
 // zmanimKey generates a cache key for zmanim calculations
 // Format: zmanim:{publisherId}:{cityId}:{date}
 func zmanimKey(publisherID, cityID, date string) string {
@@ -82,6 +96,16 @@ func cityKey(cityID string) string {
 	return fmt.Sprintf("city:%s", cityID)
 }
 
+// tileKey generates a cache key for a boundary vector tile; tiles without
+// publisher coverage use "all"
+// Format: tile:{publisherId}:{layer}:{z}:{x}:{y}
+func tileKey(publisherID, layer string, z, x, y int) string {
+	if publisherID == "" {
+		publisherID = "all"
+	}
+	return fmt.Sprintf("tile:%s:%s:%d:%d:%d", publisherID, layer, z, x, y)
+}
+
 // GetZmanim retrieves cached zmanim calculations
 func (c *Cache) GetZmanim(ctx context.Context, publisherID, cityID, date string) (*ZmanimCacheEntry, error) {
 	key := zmanimKey(publisherID, cityID, date)
@@ -139,6 +163,7 @@ func (c *Cache) InvalidatePublisherCache(ctx context.Context, publisherID string
 		fmt.Sprintf("zmanim:%s:*", publisherID), // Standard zmanim cache
 		fmt.Sprintf("%s:*", publisherID),        // Filtered zmanim cache (publisherId:date:lat:lon)
 		fmt.Sprintf("week:%s:*", publisherID),   // Week batch cache
+		fmt.Sprintf("tile:%s:*", publisherID),   // Coverage vector tiles
 	}
 
 	var totalDeleted int64
@@ -215,6 +240,44 @@ func (c *Cache) SetCity(ctx context.Context, cityID string, data []byte) error {
 	return c.client.Set(ctx, key, data, CityTTL).Err()
 }
 
+// GetTile retrieves a cached vector tile
+func (c *Cache) GetTile(ctx context.Context, publisherID, layer string, z, x, y int) (*TileEntry, error) {
+	data, err := c.client.Get(ctx, tileKey(publisherID, layer, z, x, y)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached tile: %w", err)
+	}
+
+	var entry TileEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached tile: %w", err)
+	}
+	return &entry, nil
+}
+
+// SetTile caches a vector tile
+func (c *Cache) SetTile(ctx context.Context, publisherID, layer string, z, x, y int, entry *TileEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tile: %w", err)
+	}
+	return c.client.Set(ctx, tileKey(publisherID, layer, z, x, y), data, TileTTL).Err()
+}
+
+// InvalidateTiles removes cached coverage tiles for a publisher
+// Used when coverage changes
+func (c *Cache) InvalidateTiles(ctx context.Context, publisherID string) error {
+	return c.deleteByPattern(ctx, fmt.Sprintf("tile:%s:*", publisherID))
+}
+
+// FlushAllTiles removes all cached vector tiles
+// Used after a boundary import
+func (c *Cache) FlushAllTiles(ctx context.Context) error {
+	return c.deleteByPattern(ctx, "tile:*")
+}
+
 // deleteByPattern deletes all keys matching a pattern
 func (c *Cache) deleteByPattern(ctx context.Context, pattern string) error {
 	var cursor uint64
@@ -16,6 +16,8 @@ type Config struct {
 	JWT       JWTConfig
 	CORS      CORSConfig
 	RateLimit RateLimitConfig
+	Geo       GeoConfig
+	Webhooks  WebhooksConfig
 }
 
 // ServerConfig holds server-specific configuration
@@ -47,6 +49,19 @@ type RateLimitConfig struct {
 	Duration time.Duration
 }
 
+// GeoConfig holds offline geographic data configuration
+type GeoConfig struct {
+	// SRTMCacheDir is where cmd/import-wof stores SRTM elevation tiles
+	SRTMCacheDir string
+}
+
+// WebhooksConfig holds outbound webhook configuration
+type WebhooksConfig struct {
+	// AllowPrivateNetworks lets subscriptions target loopback and private
+	// addresses, for local testing against a receiver on this machine
+	AllowPrivateNetworks bool
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists (for local development)
@@ -81,6 +96,12 @@ func Load() (*Config, error) {
 			Requests: getEnvInt("RATE_LIMIT_REQUESTS", 60),
 			Duration: getEnvDuration("RATE_LIMIT_DURATION", time.Minute),
 		},
+		Geo: GeoConfig{
+			SRTMCacheDir: getEnv("SRTM_CACHE_DIR", "data/srtm"),
+		},
+		Webhooks: WebhooksConfig{
+			AllowPrivateNetworks: getEnv("WEBHOOKS_ALLOW_PRIVATE_NETWORKS", "") == "true",
+		},
 	}
 
 	if err := config.Validate(); err != nil {
@@ -98,6 +119,9 @@ func (c *Config) Validate() error {
 	if c.JWT.Secret == "" && c.Server.Environment == "production" {
 		return fmt.Errorf("JWT_SECRET is required in production")
 	}
+	if c.Webhooks.AllowPrivateNetworks && c.Server.Environment == "production" {
+		return fmt.Errorf("WEBHOOKS_ALLOW_PRIVATE_NETWORKS must not be set in production")
+	}
 	return nil
 }
 
@@ -2,6 +2,7 @@ package dsl
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -94,7 +95,7 @@ type NumberNode struct {
 
 func (n *NumberNode) Type() NodeType     { return NodeTypeNumber }
 func (n *NumberNode) Position() Position { return n.Pos }
-func (n *NumberNode) String() string     { return fmt.Sprintf("%g", n.Value) }
+func (n *NumberNode) String() string     { return strconv.FormatFloat(n.Value, 'f', -1, 64) }
 
 // ReferenceNode represents a reference to another zman (@zman_key)
 type ReferenceNode struct {
@@ -114,7 +115,7 @@ type StringNode struct {
 
 func (n *StringNode) Type() NodeType     { return NodeTypeString }
 func (n *StringNode) Position() Position { return n.Pos }
-func (n *StringNode) String() string     { return fmt.Sprintf("%q", n.Value) }
+func (n *StringNode) String() string     { return `"` + n.Value + `"` } // DSL strings have no escapes
 
 // DirectionNode represents a direction keyword for solar function
 type DirectionNode struct {
@@ -136,8 +137,12 @@ type BaseNode struct {
 func (n *BaseNode) Type() NodeType     { return NodeTypeString }
 func (n *BaseNode) Position() Position { return n.Pos }
 func (n *BaseNode) String() string {
-	if n.Base == "custom" && len(n.CustomArgs) == 2 {
-		return fmt.Sprintf("custom(%s, %s)", n.CustomArgs[0].String(), n.CustomArgs[1].String())
+	if n.Base == "custom" {
+		args := make([]string, len(n.CustomArgs))
+		for i, arg := range n.CustomArgs {
+			args[i] = arg.String()
+		}
+		return "custom(" + strings.Join(args, ", ") + ")"
 	}
 	return n.Base
 }
@@ -1,8 +1,11 @@
 package dsl
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
 )
 
 // TestLexer tests the DSL lexer
@@ -595,3 +598,185 @@ func TestExecuteFormulaSet(t *testing.T) {
 		t.Error("sunset should be before tzeis")
 	}
 }
+
+// TestExecuteFormulaSetReferences tests that referenced zmanim are calculated first
+func TestExecuteFormulaSetReferences(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	date := time.Date(2024, 3, 21, 0, 0, 0, 0, loc)
+
+	formulas := map[string]string{
+		"plag":          "@mincha_ketana + 75min",
+		"mincha_ketana": "@chatzos + 150min",
+		"chatzos":       "solar_noon",
+		"tzeis":         "sunset + 42min",
+	}
+
+	order, err := GetCalculationOrder(formulas)
+	if err != nil {
+		t.Fatalf("GetCalculationOrder error: %v", err)
+	}
+	pos := make(map[string]int)
+	for i, key := range order {
+		pos[key] = i
+	}
+	if len(order) != 4 || pos["chatzos"] > pos["mincha_ketana"] || pos["mincha_ketana"] > pos["plag"] {
+		t.Errorf("order = %v, want dependencies first", order)
+	}
+
+	results, err := ExecuteFormulaSet(formulas, NewExecutionContext(date, 31.7683, 35.2137, 0, loc))
+	if err != nil {
+		t.Fatalf("ExecuteFormulaSet error: %v", err)
+	}
+	if got := results["plag"].Sub(results["chatzos"]); got != 225*time.Minute {
+		t.Errorf("plag - chatzos = %v, want 3h45m", got)
+	}
+}
+
+func TestExecutionContextEngine(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	date := time.Date(2024, 3, 21, 0, 0, 0, 0, loc)
+
+	for _, formula := range []string{"sunrise", "sunset", "civil_dawn", "solar(16.1, before_sunrise)", "proportional_hours(3, gra)"} {
+		noaa := NewExecutionContext(date, 31.7683, 35.2137, 0, loc)
+		spa := NewExecutionContext(date, 31.7683, 35.2137, 0, loc)
+		spa.Engine = astro.NewSPAEngine()
+
+		a, err := ExecuteFormula(formula, noaa)
+		if err != nil {
+			t.Fatalf("%s (noaa): %v", formula, err)
+		}
+		b, err := ExecuteFormula(formula, spa)
+		if err != nil {
+			t.Fatalf("%s (spa): %v", formula, err)
+		}
+		if a.Equal(b) {
+			t.Errorf("%s: engines gave identical times %s; SPA engine not used", formula, a.Format("15:04:05"))
+		}
+		if diff := a.Sub(b).Abs(); diff > time.Minute {
+			t.Errorf("%s: noaa %s, spa %s (%s apart)", formula, a.Format("15:04:05"), b.Format("15:04:05"), diff)
+		}
+	}
+}
+
+// flatHorizon is a skyline at a constant altitude in every direction
+type flatHorizon float64
+
+func (f flatHorizon) Altitude(float64) float64 { return float64(f) }
+
+func TestTerrainPrimitives(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	date := time.Date(2024, 3, 21, 0, 0, 0, 0, loc)
+
+	// Without a horizon profile the terrain primitives cannot be calculated
+	ctx := NewExecutionContext(date, 31.7683, 35.2137, 0, loc)
+	if _, err := ExecuteFormula("visible_sunrise_terrain", ctx); err == nil {
+		t.Error("visible_sunrise_terrain without a horizon: expected an error")
+	}
+
+	// A flat skyline gives the ordinary visible sunrise and sunset
+	for _, tt := range []struct{ terrain, visible string }{
+		{"visible_sunrise_terrain", "visible_sunrise"},
+		{"visible_sunset_terrain", "visible_sunset"},
+	} {
+		ctx := NewExecutionContext(date, 31.7683, 35.2137, 0, loc)
+		ctx.Horizon = flatHorizon(0)
+		got, err := ExecuteFormula(tt.terrain, ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.terrain, err)
+		}
+		want, err := ExecuteFormula(tt.visible, ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.visible, err)
+		}
+		if diff := got.Sub(want).Abs(); diff > time.Minute {
+			t.Errorf("%s = %s, %s = %s", tt.terrain, got.Format("15:04:05"), tt.visible, want.Format("15:04:05"))
+		}
+	}
+
+	// A high eastern skyline makes sunrise later
+	ctx = NewExecutionContext(date, 31.7683, 35.2137, 0, loc)
+	ctx.Horizon = flatHorizon(3)
+	late, err := ExecuteFormula("visible_sunrise_terrain", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	early, _ := ExecuteFormula("visible_sunrise", ctx)
+	if !late.After(early.Add(10 * time.Minute)) {
+		t.Errorf("sunrise behind a 3° skyline %s, flat %s", late.Format("15:04:05"), early.Format("15:04:05"))
+	}
+}
+
+func TestMoonPrimitives(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	// Full moon: 2024-04-23 23:49 UT
+	fullMoon := time.Date(2024, 4, 23, 0, 0, 0, 0, loc)
+
+	ctx := NewExecutionContext(fullMoon, 31.7683, 35.2137, 0, loc)
+	rise, err := ExecuteFormula("moonrise", ctx)
+	if err != nil {
+		t.Fatalf("moonrise: %v", err)
+	}
+	set, err := ExecuteFormula("moonset", ctx)
+	if err != nil {
+		t.Fatalf("moonset: %v", err)
+	}
+	if rise.Day() != 23 || set.Day() != 23 {
+		t.Errorf("moonrise %s, moonset %s: want times on the 23rd", rise, set)
+	}
+
+	tests := []struct {
+		formula string
+		want    string // "a" for the true branch, "b" for the false branch
+	}{
+		{`if (moon_illumination > 0.95) { sunset } else { sunrise }`, "a"},
+		{`if (moon_phase == "full") { sunset } else { sunrise }`, "a"},
+		{`if (moon_phase == "new") { sunset } else { sunrise }`, "b"},
+		// The full moon rises around sunset
+		{`if (moonrise > solar_noon) { sunset } else { sunrise }`, "a"},
+	}
+	for _, tt := range tests {
+		ctx := NewExecutionContext(fullMoon, 31.7683, 35.2137, 0, loc)
+		got, err := ExecuteFormula(tt.formula, ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.formula, err)
+		}
+		sunset, _ := ExecuteFormula("sunset", ctx)
+		if (got.Equal(sunset)) != (tt.want == "a") {
+			t.Errorf("%s: took the wrong branch", tt.formula)
+		}
+	}
+
+	// New moon: a sliver at sunset
+	ctx = NewExecutionContext(time.Date(2024, 4, 8, 0, 0, 0, 0, loc), 31.7683, 35.2137, 0, loc)
+	if _, err := ExecuteFormula(`if (moon_illumination < 0.02) { sunset } else { sunrise }`, ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMoonPrimitiveErrors(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+
+	// Once a month moonrise falls after midnight and a day has none
+	missing := false
+	for day := 1; day <= 30 && !missing; day++ {
+		ctx := NewExecutionContext(time.Date(2024, 4, day, 0, 0, 0, 0, loc), 31.7683, 35.2137, 0, loc)
+		if _, err := ExecuteFormula("moonrise", ctx); err != nil {
+			if !strings.Contains(err.Error(), "no moonrise") {
+				t.Errorf("2024-04-%d: %v", day, err)
+			}
+			missing = true
+		}
+	}
+	if !missing {
+		t.Error("expected a day in April 2024 without moonrise")
+	}
+
+	for _, formula := range []string{
+		`if (moon_phase == "gibbous") { sunset } else { sunrise }`,
+		`if (moon_illumination > "full") { sunset } else { sunrise }`,
+	} {
+		if _, _, err := ValidateFormula(formula, nil); err == nil {
+			t.Errorf("%s: expected a validation error", formula)
+		}
+	}
+}
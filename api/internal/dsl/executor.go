@@ -15,8 +15,17 @@ type ExecutionContext struct {
 	Elevation float64
 	Timezone  *time.Location
 
+	// Engine computes sun times; nil uses astro.DefaultEngine
+	Engine astro.SolarEngine
+
+	// Horizon is the terrain skyline for the terrain-aware primitives;
+	// nil when no horizon profile is available for the location
+	Horizon astro.Horizon
+
 	// Cached astronomical primitives (computed lazily)
-	sunTimes *astro.SunTimes
+	sunTimes     *astro.SunTimes
+	terrainTimes *[2]time.Time // visible sunrise and sunset over Horizon
+	moonTimes    *[2]time.Time // moonrise and moonset
 
 	// Publisher's zmanim for references (computed in dependency order)
 	ZmanimCache map[string]time.Time
@@ -34,14 +43,57 @@ func NewExecutionContext(date time.Time, latitude, longitude, elevation float64,
 	}
 }
 
+// solarEngine returns the engine selected for this context
+func (ctx *ExecutionContext) solarEngine() astro.SolarEngine {
+	if ctx.Engine == nil {
+		return astro.DefaultEngine
+	}
+	return ctx.Engine
+}
+
 // getSunTimes lazily computes and caches sun times
+// Primitives are sea-level times; Elevation is not applied
 func (ctx *ExecutionContext) getSunTimes() *astro.SunTimes {
 	if ctx.sunTimes == nil {
-		ctx.sunTimes = astro.CalculateSunTimes(ctx.Date, ctx.Latitude, ctx.Longitude, ctx.Timezone)
+		ctx.sunTimes = ctx.solarEngine().SunTimes(ctx.Date, ctx.Latitude, ctx.Longitude, 0, ctx.Timezone)
 	}
 	return ctx.sunTimes
 }
 
+// getTerrainTimes lazily computes and caches visible sunrise and sunset over
+// the terrain horizon
+func (ctx *ExecutionContext) getTerrainTimes() (sunrise, sunset time.Time) {
+	if ctx.terrainTimes == nil {
+		rise, set := astro.VisibleSunTimesOverHorizon(ctx.Date, ctx.Latitude, ctx.Longitude, ctx.Elevation, ctx.Timezone, ctx.Horizon)
+		ctx.terrainTimes = &[2]time.Time{rise, set}
+	}
+	return ctx.terrainTimes[0], ctx.terrainTimes[1]
+}
+
+// getMoonTimes lazily computes and caches moonrise and moonset
+func (ctx *ExecutionContext) getMoonTimes() (moonrise, moonset time.Time) {
+	if ctx.moonTimes == nil {
+		rise, set := astro.MoonTimes(ctx.Date, ctx.Latitude, ctx.Longitude, 0, ctx.Timezone)
+		ctx.moonTimes = &[2]time.Time{rise, set}
+	}
+	return ctx.moonTimes[0], ctx.moonTimes[1]
+}
+
+// moonAtSunset returns the moon's position at sunset, when the night's moon
+// is judged, or at local noon where the sun does not set
+func (ctx *ExecutionContext) moonAtSunset() astro.MoonPosition {
+	t := ctx.getSunTimes().Sunset
+	if t.IsZero() {
+		t = time.Date(ctx.Date.Year(), ctx.Date.Month(), ctx.Date.Day(), 12, 0, 0, 0, ctx.Timezone)
+	}
+	return astro.MoonPositionAt(t, ctx.Latitude, ctx.Longitude, 0)
+}
+
+// sunTimeAtAngle returns the sea-level dawn and dusk for a solar depression angle
+func (ctx *ExecutionContext) sunTimeAtAngle(angle float64) (dawn, dusk time.Time) {
+	return ctx.solarEngine().SunTimeAtAngle(ctx.Date, ctx.Latitude, ctx.Longitude, 0, ctx.Timezone, angle)
+}
+
 // DayLength returns the day length in minutes
 func (ctx *ExecutionContext) DayLength() float64 {
 	st := ctx.getSunTimes()
@@ -207,24 +259,46 @@ func (e *Executor) executePrimitive(n *PrimitiveNode) Value {
 	case "visible_sunset":
 		// Visible sunset accounts for atmospheric refraction
 		t = st.Sunset
+	case "visible_sunrise_terrain", "visible_sunset_terrain":
+		// Upper limb clears the actual skyline (mountains included)
+		if e.ctx.Horizon == nil {
+			e.addError("%s needs a terrain horizon profile, and none is available for this location", n.Name)
+			return Value{}
+		}
+		if n.Name == "visible_sunrise_terrain" {
+			t, _ = e.ctx.getTerrainTimes()
+		} else {
+			_, t = e.ctx.getTerrainTimes()
+		}
 	case "civil_dawn":
 		// Sun at -6° below horizon (morning)
-		t, _ = astro.SunTimeAtAngle(e.ctx.Date, e.ctx.Latitude, e.ctx.Longitude, e.ctx.Timezone, 6)
+		t, _ = e.ctx.sunTimeAtAngle(6)
 	case "civil_dusk":
 		// Sun at -6° below horizon (evening)
-		_, t = astro.SunTimeAtAngle(e.ctx.Date, e.ctx.Latitude, e.ctx.Longitude, e.ctx.Timezone, 6)
+		_, t = e.ctx.sunTimeAtAngle(6)
 	case "nautical_dawn":
 		// Sun at -12° below horizon (morning)
-		t, _ = astro.SunTimeAtAngle(e.ctx.Date, e.ctx.Latitude, e.ctx.Longitude, e.ctx.Timezone, 12)
+		t, _ = e.ctx.sunTimeAtAngle(12)
 	case "nautical_dusk":
 		// Sun at -12° below horizon (evening)
-		_, t = astro.SunTimeAtAngle(e.ctx.Date, e.ctx.Latitude, e.ctx.Longitude, e.ctx.Timezone, 12)
+		_, t = e.ctx.sunTimeAtAngle(12)
 	case "astronomical_dawn":
 		// Sun at -18° below horizon (morning)
-		t, _ = astro.SunTimeAtAngle(e.ctx.Date, e.ctx.Latitude, e.ctx.Longitude, e.ctx.Timezone, 18)
+		t, _ = e.ctx.sunTimeAtAngle(18)
 	case "astronomical_dusk":
 		// Sun at -18° below horizon (evening)
-		_, t = astro.SunTimeAtAngle(e.ctx.Date, e.ctx.Latitude, e.ctx.Longitude, e.ctx.Timezone, 18)
+		_, t = e.ctx.sunTimeAtAngle(18)
+	case "moonrise", "moonset":
+		// Upper limb crosses the sea-level horizon; once a month there is none
+		if n.Name == "moonrise" {
+			t, _ = e.ctx.getMoonTimes()
+		} else {
+			_, t = e.ctx.getMoonTimes()
+		}
+		if t.IsZero() {
+			e.addError("there is no %s on this date at this location", n.Name)
+			return Value{}
+		}
 	default:
 		e.addError("unknown primitive: %s", n.Name)
 		return Value{}
@@ -284,7 +358,7 @@ func (e *Executor) executeSolar(n *FunctionNode) Value {
 	}
 
 	// Calculate sun time at angle
-	dawn, dusk := astro.SunTimeAtAngle(e.ctx.Date, e.ctx.Latitude, e.ctx.Longitude, e.ctx.Timezone, degrees)
+	dawn, dusk := e.ctx.sunTimeAtAngle(degrees)
 
 	var t time.Time
 	switch direction {
@@ -613,6 +687,28 @@ func (e *Executor) executeCondition(n *ConditionNode) Value {
 		return Value{Type: ValueTypeBoolean, Boolean: result}
 	}
 
+	// Time comparisons, e.g. moonrise < sunset
+	if left.Type == ValueTypeTime && right.Type == ValueTypeTime {
+		switch n.Op {
+		case ">":
+			result = left.Time.After(right.Time)
+		case "<":
+			result = left.Time.Before(right.Time)
+		case ">=":
+			result = !left.Time.Before(right.Time)
+		case "<=":
+			result = !left.Time.After(right.Time)
+		case "==":
+			result = left.Time.Equal(right.Time)
+		case "!=":
+			result = !left.Time.Equal(right.Time)
+		default:
+			e.addError("invalid comparison operator: %s", n.Op)
+			return Value{}
+		}
+		return Value{Type: ValueTypeBoolean, Boolean: result}
+	}
+
 	// String comparisons
 	if left.Type == ValueTypeString && right.Type == ValueTypeString {
 		switch n.Op {
@@ -702,6 +798,10 @@ func (e *Executor) executeConditionVar(n *ConditionVarNode) Value {
 		return Value{Type: ValueTypeNumber, Number: float64(e.ctx.Month())}
 	case "season":
 		return Value{Type: ValueTypeString, String: e.ctx.Season()}
+	case "moon_illumination":
+		return Value{Type: ValueTypeNumber, Number: e.ctx.moonAtSunset().Illumination}
+	case "moon_phase":
+		return Value{Type: ValueTypeString, String: e.ctx.moonAtSunset().Phase()}
 	default:
 		e.addError("unknown condition variable: %s", n.Name)
 		return Value{}
@@ -0,0 +1,612 @@
+package dsl
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Explanation languages supported by Explain. Mixed is English with the
+// Hebrew terms for the zmanim.
+const (
+	LangEnglish = "en"
+	LangHebrew  = "he"
+	LangMixed   = "mixed"
+)
+
+// explainVocab holds the wording for one language
+type explainVocab struct {
+	primitives map[string]string
+	conditions map[string]string
+	seasons    map[string]string
+	moonPhases map[string]string
+	bases      map[string]string // base name -> description of the day it divides
+	dayStarts  map[string]string // base name -> start of the day it divides
+
+	solar       string // degrees, direction
+	directions  map[string]string
+	propHours   string // hours, base description, start
+	propHour    string // singular form of propHours
+	customBase  string // start, end
+	midpoint    string // a, b
+	after       string // duration, time
+	before      string // duration, time
+	between     string // later, earlier
+	plus        string
+	minus       string
+	unless      string // otherwise, condition, then
+	onlyWhen    string // then, condition
+	and         string
+	or          string
+	not         string
+	hour        string
+	hours       string
+	minute      string
+	minutes     string
+	durationSep string
+	meters      string
+}
+
+var explainVocabs = map[string]*explainVocab{
+	LangEnglish: {
+		primitives: map[string]string{
+			"sunrise":                 "sunrise",
+			"sunset":                  "sunset",
+			"solar_noon":              "solar noon",
+			"solar_midnight":          "solar midnight",
+			"visible_sunrise":         "visible sunrise",
+			"visible_sunset":          "visible sunset",
+			"visible_sunrise_terrain": "visible sunrise over the local terrain",
+			"visible_sunset_terrain":  "visible sunset behind the local terrain",
+			"civil_dawn":              "civil dawn (sun 6° below the horizon)",
+			"civil_dusk":              "civil dusk (sun 6° below the horizon)",
+			"nautical_dawn":           "nautical dawn (sun 12° below the horizon)",
+			"nautical_dusk":           "nautical dusk (sun 12° below the horizon)",
+			"astronomical_dawn":       "astronomical dawn (sun 18° below the horizon)",
+			"astronomical_dusk":       "astronomical dusk (sun 18° below the horizon)",
+			"moonrise":                "moonrise",
+			"moonset":                 "moonset",
+		},
+		conditions: map[string]string{
+			"latitude":          "latitude",
+			"longitude":         "longitude",
+			"elevation":         "elevation",
+			"day_length":        "day length",
+			"month":             "month",
+			"season":            "season",
+			"moon_illumination": "the moon's illuminated fraction",
+			"moon_phase":        "the moon's phase",
+		},
+		seasons: map[string]string{
+			"spring": "spring",
+			"summer": "summer",
+			"autumn": "autumn",
+			"winter": "winter",
+		},
+		moonPhases: map[string]string{
+			"new":             "new moon",
+			"waxing_crescent": "waxing crescent",
+			"first_quarter":   "first quarter",
+			"waxing_gibbous":  "waxing gibbous",
+			"full":            "full moon",
+			"waning_gibbous":  "waning gibbous",
+			"last_quarter":    "last quarter",
+			"waning_crescent": "waning crescent",
+		},
+		bases: map[string]string{
+			"gra":     "GRA: sunrise→sunset",
+			"mga":     "MGA: 72 minutes before sunrise→72 minutes after sunset",
+			"mga_90":  "MGA: 90 minutes before sunrise→90 minutes after sunset",
+			"mga_120": "MGA: 120 minutes before sunrise→120 minutes after sunset",
+		},
+		dayStarts: map[string]string{
+			"gra":     "sunrise",
+			"mga":     "72 minutes before sunrise",
+			"mga_90":  "90 minutes before sunrise",
+			"mga_120": "120 minutes before sunrise",
+		},
+		solar: "when the sun is %s° below the horizon %s",
+		directions: map[string]string{
+			"before_sunrise": "before sunrise",
+			"after_sunset":   "after sunset",
+			"before_noon":    "in the morning",
+			"after_noon":     "in the afternoon",
+		},
+		propHours:   "%s proportional hours (%s) counted from %s",
+		propHour:    "%s proportional hour (%s) counted from %s",
+		customBase:  "custom: %s→%s",
+		midpoint:    "the midpoint between %s and %s",
+		after:       "%s after %s",
+		before:      "%s before %s",
+		between:     "the time from %[2]s to %[1]s",
+		plus:        "%s plus %s",
+		minus:       "%s minus %s",
+		unless:      "%s, unless %s, in which case %s",
+		onlyWhen:    "%s, only when %s",
+		and:         "%s and %s",
+		or:          "%s or %s",
+		not:         "not (%s)",
+		hour:        "1 hour",
+		hours:       "%s hours",
+		minute:      "1 minute",
+		minutes:     "%s minutes",
+		durationSep: " and ",
+		meters:      "m",
+	},
+	LangHebrew: {
+		primitives: map[string]string{
+			"sunrise":                 "הנץ החמה",
+			"sunset":                  "שקיעת החמה",
+			"solar_noon":              "חצות היום",
+			"solar_midnight":          "חצות הלילה",
+			"visible_sunrise":         "הנץ הנראה",
+			"visible_sunset":          "השקיעה הנראית",
+			"visible_sunrise_terrain": "הנץ הנראה מעל ההרים",
+			"visible_sunset_terrain":  "השקיעה הנראית מאחורי ההרים",
+			"civil_dawn":              "שחר אזרחי (השמש 6° מתחת לאופק)",
+			"civil_dusk":              "דמדומים אזרחיים (השמש 6° מתחת לאופק)",
+			"nautical_dawn":           "שחר ימי (השמש 12° מתחת לאופק)",
+			"nautical_dusk":           "דמדומים ימיים (השמש 12° מתחת לאופק)",
+			"astronomical_dawn":       "שחר אסטרונומי (השמש 18° מתחת לאופק)",
+			"astronomical_dusk":       "דמדומים אסטרונומיים (השמש 18° מתחת לאופק)",
+			"moonrise":                "זריחת הלבנה",
+			"moonset":                 "שקיעת הלבנה",
+		},
+		conditions: map[string]string{
+			"latitude":          "קו הרוחב",
+			"longitude":         "קו האורך",
+			"elevation":         "הגובה",
+			"day_length":        "אורך היום",
+			"month":             "החודש",
+			"season":            "העונה",
+			"moon_illumination": "החלק המואר של הלבנה",
+			"moon_phase":        "מופע הלבנה",
+		},
+		seasons: map[string]string{
+			"spring": "אביב",
+			"summer": "קיץ",
+			"autumn": "סתיו",
+			"winter": "חורף",
+		},
+		moonPhases: map[string]string{
+			"new":             "מולד",
+			"waxing_crescent": "סהר מתמלא",
+			"first_quarter":   "רבע ראשון",
+			"waxing_gibbous":  "לבנה מתמלאת",
+			"full":            "מילוי הלבנה",
+			"waning_gibbous":  "לבנה מתמעטת",
+			"last_quarter":    "רבע אחרון",
+			"waning_crescent": "סהר מתמעט",
+		},
+		bases: map[string]string{
+			"gra":     "גר\"א: הנץ→שקיעה",
+			"mga":     "מג\"א: 72 דקות לפני הנץ→72 דקות אחרי השקיעה",
+			"mga_90":  "מג\"א: 90 דקות לפני הנץ→90 דקות אחרי השקיעה",
+			"mga_120": "מג\"א: 120 דקות לפני הנץ→120 דקות אחרי השקיעה",
+		},
+		dayStarts: map[string]string{
+			"gra":     "הנץ החמה",
+			"mga":     "72 דקות לפני הנץ",
+			"mga_90":  "90 דקות לפני הנץ",
+			"mga_120": "120 דקות לפני הנץ",
+		},
+		solar: "כשהשמש %s° מתחת לאופק %s",
+		directions: map[string]string{
+			"before_sunrise": "לפני הנץ",
+			"after_sunset":   "אחרי השקיעה",
+			"before_noon":    "בבוקר",
+			"after_noon":     "אחר הצהריים",
+		},
+		propHours:   "%s שעות זמניות (%s) מתחילת היום (%s)",
+		propHour:    "%s שעה זמנית (%s) מתחילת היום (%s)",
+		customBase:  "מותאם: %s→%s",
+		midpoint:    "האמצע בין %s לבין %s",
+		after:       "%s אחרי %s",
+		before:      "%s לפני %s",
+		between:     "הזמן שבין %[2]s לבין %[1]s",
+		plus:        "%s ועוד %s",
+		minus:       "%s פחות %s",
+		unless:      "%s, אלא אם %s, ואז %s",
+		onlyWhen:    "%s, רק כאשר %s",
+		and:         "%s וגם %s",
+		or:          "%s או %s",
+		not:         "לא (%s)",
+		hour:        "שעה",
+		hours:       "%s שעות",
+		minute:      "דקה",
+		minutes:     "%s דקות",
+		durationSep: " ו-",
+		meters:      " מ'",
+	},
+}
+
+func init() {
+	explainVocabs[LangMixed] = mixedVocab(explainVocabs[LangEnglish])
+}
+
+// mixedTerms swaps English terms for the Hebrew ones a talmid chacham uses
+var mixedTerms = strings.NewReplacer(
+	"proportional hours", "שעות זמניות",
+	"proportional hour", "שעה זמנית",
+	"visible sunrise", "נץ הנראה",
+	"visible sunset", "שקיעה הנראית",
+	"sunrise", "נץ",
+	"sunset", "שקיעה",
+	"solar noon", "חצות היום",
+	"solar midnight", "חצות הלילה",
+	"GRA", `גר"א`,
+	"MGA", `מג"א`,
+)
+
+// mixedVocab copies the English wording with the zmanim named in Hebrew
+func mixedVocab(en *explainVocab) *explainVocab {
+	v := *en
+	swap := func(m map[string]string) map[string]string {
+		out := make(map[string]string, len(m))
+		for k, s := range m {
+			out[k] = mixedTerms.Replace(s)
+		}
+		return out
+	}
+	v.primitives = swap(en.primitives)
+	v.bases = swap(en.bases)
+	v.dayStarts = swap(en.dayStarts)
+	v.directions = swap(en.directions)
+	v.propHours = mixedTerms.Replace(en.propHours)
+	v.propHour = mixedTerms.Replace(en.propHour)
+	return &v
+}
+
+// Explain renders a precise, deterministic explanation of a formula's AST in
+// English ("en"), Hebrew ("he") or English with Hebrew terms ("mixed").
+// Unknown languages fall back to English. References are named from their
+// zman keys; use ExplainWithNames to supply display names.
+func Explain(node Node, lang string) string {
+	return ExplainWithNames(node, lang, nil)
+}
+
+// ExplainWithNames is Explain with display names for referenced zmanim, keyed
+// by zman key. References missing from names are named from their keys.
+func ExplainWithNames(node Node, lang string, names map[string]string) string {
+	v, ok := explainVocabs[lang]
+	if !ok {
+		v = explainVocabs[LangEnglish]
+	}
+	return (&explainer{v: v, names: names}).explain(node)
+}
+
+// ExplainFormula parses a formula and explains it
+func ExplainFormula(formula string, lang string) (string, error) {
+	node, err := Parse(formula)
+	if err != nil {
+		return "", err
+	}
+	return Explain(node, lang), nil
+}
+
+type explainer struct {
+	v     *explainVocab
+	names map[string]string
+}
+
+func (e *explainer) explain(node Node) string {
+	switch n := node.(type) {
+	case *PrimitiveNode:
+		if s, ok := e.v.primitives[n.Name]; ok {
+			return s
+		}
+		return n.Name
+	case *FunctionNode:
+		return e.explainFunction(n)
+	case *BaseNode:
+		return e.explainBase(n)
+	case *DirectionNode:
+		if s, ok := e.v.directions[n.Direction]; ok {
+			return s
+		}
+		return n.Direction
+	case *ReferenceNode:
+		if name, ok := e.names[n.ZmanKey]; ok && name != "" {
+			return name
+		}
+		return zmanKeyName(n.ZmanKey)
+	case *DurationNode:
+		// Keep the author's unit: "72min" reads as 72 minutes, "1h 30min" as hours and minutes
+		if !strings.Contains(n.Raw, "h") {
+			return e.minutes(n.Minutes)
+		}
+		return e.duration(n.Minutes)
+	case *NumberNode:
+		return formatExplainNumber(n.Value)
+	case *StringNode:
+		if s, ok := e.v.seasons[n.Value]; ok {
+			return s
+		}
+		if s, ok := e.v.moonPhases[n.Value]; ok {
+			return s
+		}
+		return n.Value
+	case *BinaryOpNode:
+		return e.explainBinaryOp(n)
+	case *ConditionalNode:
+		if n.FalseBranch == nil {
+			return fmt.Sprintf(e.v.onlyWhen, e.explain(n.TrueBranch), e.explain(n.Condition))
+		}
+		return fmt.Sprintf(e.v.unless, e.explain(n.FalseBranch), e.explain(n.Condition), e.explain(n.TrueBranch))
+	case *ConditionNode:
+		return e.explainCondition(n)
+	case *ConditionVarNode:
+		if s, ok := e.v.conditions[n.Name]; ok {
+			return s
+		}
+		return n.Name
+	case *LogicalOpNode:
+		format := e.v.and
+		if n.Op == "||" {
+			format = e.v.or
+		}
+		return fmt.Sprintf(format, e.explain(n.Left), e.explain(n.Right))
+	case *NotOpNode:
+		return fmt.Sprintf(e.v.not, e.explain(n.Operand))
+	case nil:
+		return ""
+	default:
+		return node.String()
+	}
+}
+
+func (e *explainer) explainFunction(n *FunctionNode) string {
+	switch {
+	case n.Name == "solar" && len(n.Args) == 2:
+		return fmt.Sprintf(e.v.solar, e.explain(n.Args[0]), e.explain(n.Args[1]))
+
+	case n.Name == "proportional_hours" && len(n.Args) == 2:
+		base, ok := n.Args[1].(*BaseNode)
+		if !ok {
+			break
+		}
+		format := e.v.propHours
+		if num, ok := n.Args[0].(*NumberNode); ok && num.Value == 1 {
+			format = e.v.propHour
+		}
+		return fmt.Sprintf(format, e.explain(n.Args[0]), e.explainBase(base), e.dayStart(base))
+
+	case n.Name == "midpoint" && len(n.Args) == 2:
+		return fmt.Sprintf(e.v.midpoint, e.explain(n.Args[0]), e.explain(n.Args[1]))
+	}
+	return n.String()
+}
+
+// explainBase describes the day a proportional-hours base divides into 12 hours
+func (e *explainer) explainBase(n *BaseNode) string {
+	if n.Base == "custom" && len(n.CustomArgs) == 2 {
+		return fmt.Sprintf(e.v.customBase, e.explain(n.CustomArgs[0]), e.explain(n.CustomArgs[1]))
+	}
+	if s, ok := e.v.bases[n.Base]; ok {
+		return s
+	}
+	return n.Base
+}
+
+// dayStart describes where a base's day begins (proportional hours count from there)
+func (e *explainer) dayStart(n *BaseNode) string {
+	if n.Base == "custom" && len(n.CustomArgs) == 2 {
+		return e.explain(n.CustomArgs[0])
+	}
+	if s, ok := e.v.dayStarts[n.Base]; ok {
+		return s
+	}
+	return n.Base
+}
+
+func (e *explainer) explainBinaryOp(n *BinaryOpNode) string {
+	// Offsets of offsets read as one net offset: "sunrise - 72min + 30min"
+	// is 42 minutes before sunrise
+	if base, minutes, hours, ok := timeOffset(n); ok {
+		amount := e.minutes
+		if hours {
+			amount = e.duration
+		}
+		switch {
+		case minutes > 0:
+			return fmt.Sprintf(e.v.after, amount(minutes), e.explain(base))
+		case minutes < 0:
+			return fmt.Sprintf(e.v.before, amount(-minutes), e.explain(base))
+		default:
+			return e.explain(base)
+		}
+	}
+
+	left, right := GetValueType(n.Left), GetValueType(n.Right)
+
+	switch {
+	case n.Op == "+" && left == ValueTypeTime && right == ValueTypeDuration:
+		return fmt.Sprintf(e.v.after, e.explain(n.Right), e.explain(n.Left))
+	case n.Op == "+" && left == ValueTypeDuration && right == ValueTypeTime:
+		return fmt.Sprintf(e.v.after, e.explain(n.Left), e.explain(n.Right))
+	case n.Op == "-" && left == ValueTypeTime && right == ValueTypeDuration:
+		return fmt.Sprintf(e.v.before, e.explain(n.Right), e.explain(n.Left))
+	case n.Op == "-" && left == ValueTypeTime && right == ValueTypeTime:
+		return fmt.Sprintf(e.v.between, e.explain(n.Left), e.explain(n.Right))
+	case n.Op == "+":
+		return fmt.Sprintf(e.v.plus, e.explain(n.Left), e.explain(n.Right))
+	case n.Op == "-":
+		return fmt.Sprintf(e.v.minus, e.explain(n.Left), e.explain(n.Right))
+	}
+
+	// Scaled durations read naturally as "1 hour 12 minutes" when both sides are literal
+	if dur, ok := n.Left.(*DurationNode); ok {
+		if num, ok := n.Right.(*NumberNode); ok {
+			switch n.Op {
+			case "*":
+				return e.duration(dur.Minutes * num.Value)
+			case "/":
+				if num.Value != 0 {
+					return e.duration(dur.Minutes / num.Value)
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("%s %s %s", e.explain(n.Left), explainOperator(n.Op), e.explain(n.Right))
+}
+
+// timeOffset reduces a time moved by literal durations to the time and the
+// net offset in minutes; hours reports whether any duration was written in
+// hours or scaled, so the offset reads in hours and minutes
+func timeOffset(node Node) (base Node, minutes float64, hours bool, ok bool) {
+	n, isOp := node.(*BinaryOpNode)
+	if !isOp || (n.Op != "+" && n.Op != "-") {
+		return nil, 0, false, false
+	}
+	left, right := GetValueType(n.Left), GetValueType(n.Right)
+
+	var timeNode, durNode Node
+	sign := 1.0
+	switch {
+	case left == ValueTypeTime && right == ValueTypeDuration:
+		timeNode, durNode = n.Left, n.Right
+		if n.Op == "-" {
+			sign = -1
+		}
+	case n.Op == "+" && left == ValueTypeDuration && right == ValueTypeTime:
+		timeNode, durNode = n.Right, n.Left
+	default:
+		return nil, 0, false, false
+	}
+
+	offset, durHours, ok := literalDuration(durNode)
+	if !ok {
+		return nil, 0, false, false
+	}
+	base, minutes, hours, nested := timeOffset(timeNode)
+	if !nested {
+		base, minutes, hours = timeNode, 0, false
+	}
+	return base, minutes + sign*offset, hours || durHours, true
+}
+
+// literalDuration evaluates a duration literal, optionally scaled by a number
+func literalDuration(node Node) (minutes float64, hours bool, ok bool) {
+	switch n := node.(type) {
+	case *DurationNode:
+		return n.Minutes, strings.Contains(n.Raw, "h"), true
+	case *BinaryOpNode:
+		dur, isDur := n.Left.(*DurationNode)
+		num, isNum := n.Right.(*NumberNode)
+		if !isDur || !isNum {
+			return 0, false, false
+		}
+		switch {
+		case n.Op == "*":
+			return dur.Minutes * num.Value, true, true
+		case n.Op == "/" && num.Value != 0:
+			return dur.Minutes / num.Value, true, true
+		}
+	}
+	return 0, false, false
+}
+
+// zmanKeyName names a zman from its key when no display name is known:
+// "alos_16_1" reads as "alos 16.1"
+func zmanKeyName(key string) string {
+	parts := strings.Split(key, "_")
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			if isDigits(parts[i-1]) && isDigits(part) {
+				b.WriteByte('.')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *explainer) explainCondition(n *ConditionNode) string {
+	right := e.explain(n.Right)
+	if v, ok := n.Left.(*ConditionVarNode); ok {
+		if _, isNum := n.Right.(*NumberNode); isNum {
+			switch v.Name {
+			case "latitude", "longitude":
+				right += "°"
+			case "elevation":
+				right += e.v.meters
+			}
+		}
+	}
+	return fmt.Sprintf("%s %s %s", e.explain(n.Left), explainOperator(n.Op), right)
+}
+
+// duration renders minutes as hours and minutes, e.g. "1 hour and 30 minutes"
+func (e *explainer) duration(minutes float64) string {
+	sign := ""
+	if minutes < 0 {
+		sign = "-"
+		minutes = -minutes
+	}
+
+	h := math.Floor(minutes / 60)
+	m := minutes - h*60
+	if h == 0 || m != math.Trunc(m) {
+		return sign + e.minutes(minutes)
+	}
+
+	parts := []string{e.plural(h, e.v.hour, e.v.hours)}
+	if m > 0 {
+		parts = append(parts, e.plural(m, e.v.minute, e.v.minutes))
+	}
+	return sign + strings.Join(parts, e.v.durationSep)
+}
+
+func (e *explainer) minutes(minutes float64) string {
+	if minutes < 0 {
+		return "-" + e.plural(-minutes, e.v.minute, e.v.minutes)
+	}
+	return e.plural(minutes, e.v.minute, e.v.minutes)
+}
+
+func (e *explainer) plural(n float64, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return fmt.Sprintf(many, formatExplainNumber(n))
+}
+
+func explainOperator(op string) string {
+	switch op {
+	case "==":
+		return "="
+	case "!=":
+		return "≠"
+	case ">=":
+		return "≥"
+	case "<=":
+		return "≤"
+	case "*":
+		return "×"
+	case "/":
+		return "÷"
+	default:
+		return op
+	}
+}
+
+// formatExplainNumber formats a number without trailing zeros
+func formatExplainNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
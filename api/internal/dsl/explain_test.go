@@ -0,0 +1,103 @@
+package dsl
+
+import "testing"
+
+// TestExplain tests deterministic formula explanations
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		formula string
+		lang    string
+		want    string
+	}{
+		{"sunrise", LangEnglish, "sunrise"},
+		{"sunset + 72min", LangEnglish, "72 minutes after sunset"},
+		{"sunrise - 1h 30min", LangEnglish, "1 hour and 30 minutes before sunrise"},
+		{"sunrise - 1h 30min", LangHebrew, "שעה ו-30 דקות לפני הנץ החמה"},
+		{"sunset + 72min * 2", LangEnglish, "2 hours and 24 minutes after sunset"},
+		{"sunset + 18min", LangHebrew, "18 דקות אחרי שקיעת החמה"},
+		{"solar(16.1, before_sunrise)", LangEnglish, "when the sun is 16.1° below the horizon before sunrise"},
+		{"solar(8.5, after_sunset)", LangHebrew, "כשהשמש 8.5° מתחת לאופק אחרי השקיעה"},
+		{"proportional_hours(3, gra)", LangEnglish, "3 proportional hours (GRA: sunrise→sunset) counted from sunrise"},
+		{"proportional_hours(1, mga)", LangEnglish, "1 proportional hour (MGA: 72 minutes before sunrise→72 minutes after sunset) counted from 72 minutes before sunrise"},
+		{"proportional_hours(4, custom(@alos_16_1, @tzeis_16_1))", LangEnglish, "4 proportional hours (custom: alos 16.1→tzeis 16.1) counted from alos 16.1"},
+		{"midpoint(sunrise, sunset)", LangEnglish, "the midpoint between sunrise and sunset"},
+		{"@alos_hashachar + 30min", LangEnglish, "30 minutes after alos hashachar"},
+		{"sunrise - 72min + 30min", LangEnglish, "42 minutes before sunrise"},
+		{"sunrise - 72min + 72min", LangEnglish, "sunrise"},
+		{"30min + (sunset - 1h)", LangEnglish, "30 minutes before sunset"},
+		{"sunrise - 72min - 18min", LangHebrew, "90 דקות לפני הנץ החמה"},
+		{"sunset + 72min", LangMixed, "72 minutes after שקיעה"},
+		{"proportional_hours(4, mga)", LangMixed, "4 שעות זמניות (מג\"א: 72 minutes before נץ→72 minutes after שקיעה) counted from 72 minutes before נץ"},
+		{
+			"if (latitude > 55) { sunrise - 45min } else { proportional_hours(3, gra) }",
+			LangEnglish,
+			"3 proportional hours (GRA: sunrise→sunset) counted from sunrise, unless latitude > 55°, in which case 45 minutes before sunrise",
+		},
+		{
+			"if (season == \"summer\" && day_length > 14hr) { sunset + 50min } else { sunset + 42min }",
+			LangEnglish,
+			"42 minutes after sunset, unless season = summer and day length > 14 hours, in which case 50 minutes after sunset",
+		},
+		{
+			"if (latitude > 55) { sunrise - 45min } else { proportional_hours(3, gra) }",
+			LangHebrew,
+			"3 שעות זמניות (גר\"א: הנץ→שקיעה) מתחילת היום (הנץ החמה), אלא אם קו הרוחב > 55°, ואז 45 דקות לפני הנץ החמה",
+		},
+		{"sunset + 72min", "fr", "72 minutes after sunset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang+"/"+tt.formula, func(t *testing.T) {
+			got, err := ExplainFormula(tt.formula, tt.lang)
+			if err != nil {
+				t.Fatalf("ExplainFormula() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExplainFormula() =\n  %q\nwant\n  %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExplainWithNames tests that references read by their display names
+func TestExplainWithNames(t *testing.T) {
+	node, err := Parse("@alos_16_1 + 30min")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{"alos_16_1": "Dawn (16.1°)"}
+	if got, want := ExplainWithNames(node, LangEnglish, names), "30 minutes after Dawn (16.1°)"; got != want {
+		t.Errorf("ExplainWithNames() = %q, want %q", got, want)
+	}
+}
+
+// TestExplainCoversRegistryPrimitives ensures every primitive has wording in every language
+func TestExplainCoversRegistryPrimitives(t *testing.T) {
+	for lang, v := range explainVocabs {
+		for name := range Primitives {
+			if _, ok := v.primitives[name]; !ok {
+				t.Errorf("%s: no wording for primitive %s", lang, name)
+			}
+		}
+		for name := range Bases {
+			if _, ok := v.bases[name]; !ok && name != "custom" {
+				t.Errorf("%s: no wording for base %s", lang, name)
+			}
+		}
+		for name := range Directions {
+			if _, ok := v.directions[name]; !ok {
+				t.Errorf("%s: no wording for direction %s", lang, name)
+			}
+		}
+		for name := range ConditionKeywords {
+			if _, ok := v.conditions[name]; !ok {
+				t.Errorf("%s: no wording for condition %s", lang, name)
+			}
+		}
+		for name := range MoonPhases {
+			if _, ok := v.moonPhases[name]; !ok {
+				t.Errorf("%s: no wording for moon phase %s", lang, name)
+			}
+		}
+	}
+}
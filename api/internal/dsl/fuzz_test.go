@@ -0,0 +1,142 @@
+package dsl
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// seedDataPath is the migration holding the master registry's default formulas
+const seedDataPath = "../../../db/migrations/00000000000002_seed_data.sql"
+
+// registryFormulaPattern matches the default_formula_dsl column at the end of a
+// master_zmanim_registry row: ..., 'formula', is_core, is_hidden)
+var registryFormulaPattern = regexp.MustCompile(`'((?:[^']|'')*)', (?:true|false), (?:true|false)\)[,;]`)
+
+// registryFormulas returns the registry's default formulas from the seed data
+func registryFormulas(tb testing.TB) []string {
+	tb.Helper()
+	data, err := os.ReadFile(seedDataPath)
+	if err != nil {
+		tb.Fatalf("read seed data: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var formulas []string
+	for _, m := range registryFormulaPattern.FindAllStringSubmatch(string(data), -1) {
+		formula := strings.ReplaceAll(m[1], "''", "'")
+		if !seen[formula] {
+			seen[formula] = true
+			formulas = append(formulas, formula)
+		}
+	}
+	if len(formulas) == 0 {
+		tb.Fatal("no registry formulas found in seed data")
+	}
+	return formulas
+}
+
+// addFuzzSeeds seeds a fuzz target with the registry formulas plus constructs
+// the registry does not use
+func addFuzzSeeds(f *testing.F) {
+	for _, formula := range registryFormulas(f) {
+		f.Add(formula)
+	}
+	for _, formula := range []string{
+		"",
+		"if (latitude > 50) { solar(12, before_sunrise) } else { solar(16.1, before_sunrise) }",
+		`if (season == "summer" && !(day_length < 10h)) { @plag } else { sunset - 1h 30min }`,
+		"midpoint(@alos, proportional_hours(2.5, custom(@alos, @tzais)))",
+		"// comment\nsunset + 42min",
+		"sunrise - 72",
+		"sunrise -",
+		"((solar_noon))",
+		"\"unterminated",
+	} {
+		f.Add(formula)
+	}
+}
+
+// checkPosition reports positions outside the input. Columns are 1-based
+// bytes; the end of a line (or of the input) is one past its last byte.
+func checkPosition(t *testing.T, input string, what string, line, column int) {
+	t.Helper()
+	lines := strings.Split(input, "\n")
+	if line < 1 || line > len(lines) {
+		t.Fatalf("%s line %d outside input of %d lines: %q", what, line, len(lines), input)
+	}
+	if column < 1 || column > len(lines[line-1])+1 {
+		t.Fatalf("%s column %d outside line %d (%d bytes): %q", what, column, line, len(lines[line-1]), input)
+	}
+}
+
+func FuzzTokenize(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := Tokenize(input)
+		if err != nil {
+			if dslErr, ok := err.(*DSLError); ok {
+				checkPosition(t, input, "error", dslErr.Line, dslErr.Column)
+			}
+			return
+		}
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != TOKEN_EOF {
+			t.Fatalf("token stream does not end with EOF: %v", tokens)
+		}
+		for _, tok := range tokens {
+			checkPosition(t, input, tok.Type.String(), tok.Line, tok.Column)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		node, err := Parse(input)
+		if err != nil {
+			if errList, ok := err.(*ErrorList); ok {
+				for _, e := range errList.ToValidationErrors() {
+					checkPosition(t, input, "error", e.Line, e.Column)
+				}
+			}
+			return
+		}
+
+		// The formatted AST must parse back to the same AST
+		formatted := node.String()
+		reparsed, err := Parse(formatted)
+		if err != nil {
+			t.Fatalf("formatted %q (from %q) does not parse: %v", formatted, input, err)
+		}
+		if got := reparsed.String(); got != formatted {
+			t.Fatalf("round trip changed the AST:\ninput:    %q\nformat:   %q\nreformat: %q", input, formatted, got)
+		}
+	})
+}
+
+func FuzzValidateFormula(f *testing.F) {
+	addFuzzSeeds(f)
+	available := []string{"alos", "tzais", "plag", "sunset_offset"}
+	f.Fuzz(func(t *testing.T, input string) {
+		node, validationErrors, err := ValidateFormula(input, available)
+		if err == nil {
+			if node == nil {
+				t.Fatalf("valid formula %q produced no AST", input)
+			}
+			if len(validationErrors) > 0 {
+				t.Fatalf("valid formula %q reported errors: %v", input, validationErrors)
+			}
+			return
+		}
+		if len(validationErrors) == 0 {
+			t.Fatalf("invalid formula %q reported no errors: %v", input, err)
+		}
+		if node == nil {
+			// Syntax errors carry positions into the input
+			for _, e := range validationErrors {
+				checkPosition(t, input, "error", e.Line, e.Column)
+			}
+		}
+	})
+}
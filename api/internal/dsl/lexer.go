@@ -179,6 +179,10 @@ func (l *Lexer) NextToken() Token {
 
 // readChar reads the next character
 func (l *Lexer) readChar() {
+	if l.readPos > len(l.input) {
+		// Already at end of input; keep the position where it ended
+		return
+	}
 	if l.readPos >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -3,6 +3,7 @@ package dsl
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Parser parses DSL tokens into an AST
@@ -150,7 +151,7 @@ func (p *Parser) parseFactor() Node {
 	case TOKEN_BASE:
 		return p.parseBase()
 
-	case TOKEN_LATITUDE, TOKEN_LONGITUDE, TOKEN_DAY_LENGTH, TOKEN_MONTH, TOKEN_SEASON:
+	case TOKEN_LATITUDE, TOKEN_LONGITUDE, TOKEN_DAY_LENGTH, TOKEN_MONTH, TOKEN_SEASON, TOKEN_MOON_ILLUMINATION, TOKEN_MOON_PHASE:
 		// Condition variable
 		name := p.current.Literal
 		p.advance()
@@ -171,6 +172,11 @@ func (p *Parser) parseFactor() Node {
 		}
 		if durNode, ok := factor.(*DurationNode); ok {
 			durNode.Minutes = -durNode.Minutes
+			if strings.HasPrefix(durNode.Raw, "-") {
+				durNode.Raw = durNode.Raw[1:]
+			} else {
+				durNode.Raw = "-" + durNode.Raw
+			}
 			return durNode
 		}
 		p.addError("unary minus can only be applied to numbers and durations")
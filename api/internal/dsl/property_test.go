@@ -0,0 +1,183 @@
+package dsl
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// propertyLocation is a place the executor properties are checked at
+type propertyLocation struct {
+	name     string
+	lat, lng float64
+	tz       string
+}
+
+var propertyLocations = []propertyLocation{
+	{"Jerusalem", 31.7683, 35.2137, "Asia/Jerusalem"},
+	{"New York", 40.7128, -74.0060, "America/New_York"},
+	{"London", 51.5074, -0.1278, "Europe/London"},
+	{"Johannesburg", -26.2041, 28.0473, "Africa/Johannesburg"},
+	{"Honolulu", 21.3069, -157.8583, "Pacific/Honolulu"},
+}
+
+// propertyDates returns one date per month of 2025 in tz
+func propertyDates(tb testing.TB, tz string) []time.Time {
+	tb.Helper()
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	dates := make([]time.Time, 12)
+	for m := range dates {
+		dates[m] = time.Date(2025, time.Month(m+1), 15, 0, 0, 0, 0, loc)
+	}
+	return dates
+}
+
+// forEachPropertyContext runs fn for every location and date
+func forEachPropertyContext(t *testing.T, fn func(t *testing.T, ctx *ExecutionContext)) {
+	for _, l := range propertyLocations {
+		for _, date := range propertyDates(t, l.tz) {
+			t.Run(fmt.Sprintf("%s/%s", l.name, date.Format("2006-01-02")), func(t *testing.T) {
+				fn(t, NewExecutionContext(date, l.lat, l.lng, 0, date.Location()))
+			})
+		}
+	}
+}
+
+func mustExecute(t *testing.T, formula string, ctx *ExecutionContext) time.Time {
+	t.Helper()
+	result, err := ExecuteFormula(formula, ctx)
+	if err != nil {
+		t.Fatalf("%s: %v", formula, err)
+	}
+	return result
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Six GRA hours after sunrise is, by definition, halfway between sunrise and sunset
+func TestPropertyHalfDayIsMidpoint(t *testing.T) {
+	forEachPropertyContext(t, func(t *testing.T, ctx *ExecutionContext) {
+		halfDay := mustExecute(t, "proportional_hours(6, gra)", ctx)
+		midpoint := mustExecute(t, "midpoint(sunrise, sunset)", ctx)
+		if diff := absDuration(halfDay.Sub(midpoint)); diff > time.Second {
+			t.Errorf("proportional_hours(6, gra) = %s, midpoint(sunrise, sunset) = %s", halfDay, midpoint)
+		}
+
+		// The same holds for a custom day
+		custom := mustExecute(t, "proportional_hours(6, custom(civil_dawn, civil_dusk))", ctx)
+		customMid := mustExecute(t, "midpoint(civil_dawn, civil_dusk)", ctx)
+		if diff := absDuration(custom.Sub(customMid)); diff > time.Second {
+			t.Errorf("custom half day = %s, midpoint = %s", custom, customMid)
+		}
+	})
+}
+
+// A deeper depression is further from the horizon: earlier before sunrise,
+// later after sunset
+func TestPropertySolarMonotonic(t *testing.T) {
+	forEachPropertyContext(t, func(t *testing.T, ctx *ExecutionContext) {
+		var prevDawn, prevDusk time.Time
+		for deg := 1.0; deg <= 18; deg += 0.5 {
+			dawn, errDawn := ExecuteFormula(fmt.Sprintf("solar(%g, before_sunrise)", deg), ctx)
+			dusk, errDusk := ExecuteFormula(fmt.Sprintf("solar(%g, after_sunset)", deg), ctx)
+			if errDawn != nil || errDusk != nil {
+				// The sun never gets that low (high-latitude summer); neither will deeper angles
+				break
+			}
+			if !prevDawn.IsZero() && !dawn.Before(prevDawn) {
+				t.Errorf("solar(%g, before_sunrise) = %s, not before %s", deg, dawn, prevDawn)
+			}
+			if !prevDusk.IsZero() && !dusk.After(prevDusk) {
+				t.Errorf("solar(%g, after_sunset) = %s, not after %s", deg, dusk, prevDusk)
+			}
+			prevDawn, prevDusk = dawn, dusk
+		}
+	})
+}
+
+// The primitives follow the order of the day
+func TestPropertyPrimitivesOrdered(t *testing.T) {
+	order := []string{
+		"astronomical_dawn", "nautical_dawn", "civil_dawn", "sunrise",
+		"solar_noon",
+		"sunset", "civil_dusk", "nautical_dusk", "astronomical_dusk",
+	}
+	forEachPropertyContext(t, func(t *testing.T, ctx *ExecutionContext) {
+		var prev time.Time
+		var prevName string
+		for _, primitive := range order {
+			result, err := ExecuteFormula(primitive, ctx)
+			if err != nil {
+				// No astronomical night in a high-latitude summer
+				continue
+			}
+			if prevName != "" && !result.After(prev) {
+				t.Errorf("%s = %s, not after %s = %s", primitive, result, prevName, prev)
+			}
+			prev, prevName = result, primitive
+		}
+	})
+}
+
+// ExecuteFormulaSet does not depend on map iteration or insertion order
+func TestPropertyExecuteFormulaSetOrderIndependent(t *testing.T) {
+	// Every registry formula that runs in Jerusalem, plus a chain of references
+	loc, _ := time.LoadLocation("Asia/Jerusalem")
+	probe := NewExecutionContext(time.Date(2025, 3, 20, 0, 0, 0, 0, loc), 31.7683, 35.2137, 0, loc)
+	formulas := make(map[string]string)
+	for i, formula := range registryFormulas(t) {
+		if _, err := ExecuteFormula(formula, probe); err == nil {
+			formulas[fmt.Sprintf("registry_%02d", i)] = formula
+		}
+	}
+	if len(formulas) < 20 {
+		t.Fatalf("only %d registry formulas executed", len(formulas))
+	}
+	formulas["chatzos"] = "solar_noon"
+	formulas["mincha_gedola"] = "@chatzos + 30min"
+	formulas["mincha_ketana"] = "@mincha_gedola + 2h 30min"
+	formulas["plag"] = "midpoint(@mincha_ketana, sunset)"
+	formulas["tzais"] = "if (month >= 4 && month <= 9) { sunset + 50min } else { @plag + 1h }"
+
+	keys := make([]string, 0, len(formulas))
+	for key := range formulas {
+		keys = append(keys, key)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, l := range propertyLocations {
+		for _, date := range propertyDates(t, l.tz)[:3] {
+			var want map[string]time.Time
+			for run := 0; run < 10; run++ {
+				rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+				shuffled := make(map[string]string, len(keys))
+				for _, key := range keys {
+					shuffled[key] = formulas[key]
+				}
+
+				got, err := ExecuteFormulaSet(shuffled, NewExecutionContext(date, l.lat, l.lng, 0, date.Location()))
+				if err != nil {
+					t.Fatalf("%s %s: %v", l.name, date.Format("2006-01-02"), err)
+				}
+				if want == nil {
+					want = got
+					continue
+				}
+				for key, w := range want {
+					if !got[key].Equal(w) {
+						t.Errorf("%s %s: %s = %s in run %d, %s in run 0", l.name, date.Format("2006-01-02"), key, got[key], run, w)
+					}
+				}
+			}
+		}
+	}
+}
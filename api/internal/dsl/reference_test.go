@@ -0,0 +1,52 @@
+package dsl
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro/reference"
+)
+
+// TestReferenceVectorsDSL executes each reference zman's formula through the
+// DSL, so the executor is held to the same corpus and baseline as astro
+func TestReferenceVectorsDSL(t *testing.T) {
+	corpus, err := reference.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := corpus.Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := reference.Check(vectors, func(v reference.Vector) (time.Time, error) {
+		ctx := NewExecutionContext(v.Date, v.Location.Latitude, v.Location.Longitude, 0, v.Date.Location())
+		result, err := ExecuteFormula(v.Zman.Formula, ctx)
+		if err != nil {
+			// The DSL reports a zman that does not occur as an error
+			return time.Time{}, nil
+		}
+		return result, nil
+	})
+
+	var drift strings.Builder
+	report.WriteDrift(&drift)
+	t.Logf("DSL drift from %s over %d vectors:\n%s", corpus.Source, report.Checked, drift.String())
+
+	for i, m := range report.Mismatches {
+		if i == 20 {
+			t.Errorf("... and %d more mismatches", len(report.Mismatches)-i)
+			break
+		}
+		t.Error(m.String())
+	}
+
+	baseline, err := reference.LoadBaseline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range report.Regressions(baseline) {
+		t.Errorf("drift regression: %s", r)
+	}
+}
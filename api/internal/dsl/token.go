@@ -2,7 +2,11 @@
 // It provides lexing, parsing, validation, and execution of DSL expressions.
 package dsl
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+)
 
 // TokenType represents the type of a token in the DSL
 type TokenType int
@@ -35,6 +39,8 @@ const (
 	TOKEN_DAY_LENGTH
 	TOKEN_MONTH
 	TOKEN_SEASON
+	TOKEN_MOON_ILLUMINATION
+	TOKEN_MOON_PHASE
 
 	// Operators
 	TOKEN_PLUS     // +
@@ -71,43 +77,45 @@ const (
 )
 
 var tokenTypeNames = map[TokenType]string{
-	TOKEN_ILLEGAL:    "ILLEGAL",
-	TOKEN_EOF:        "EOF",
-	TOKEN_IDENT:      "IDENT",
-	TOKEN_PRIMITIVE:  "PRIMITIVE",
-	TOKEN_FUNCTION:   "FUNCTION",
-	TOKEN_IF:         "IF",
-	TOKEN_ELSE:       "ELSE",
-	TOKEN_DIRECTION:  "DIRECTION",
-	TOKEN_BASE:       "BASE",
-	TOKEN_LATITUDE:   "LATITUDE",
-	TOKEN_LONGITUDE:  "LONGITUDE",
-	TOKEN_DAY_LENGTH: "DAY_LENGTH",
-	TOKEN_MONTH:      "MONTH",
-	TOKEN_SEASON:     "SEASON",
-	TOKEN_PLUS:       "PLUS",
-	TOKEN_MINUS:      "MINUS",
-	TOKEN_MULTIPLY:   "MULTIPLY",
-	TOKEN_DIVIDE:     "DIVIDE",
-	TOKEN_LPAREN:     "LPAREN",
-	TOKEN_RPAREN:     "RPAREN",
-	TOKEN_LBRACE:     "LBRACE",
-	TOKEN_RBRACE:     "RBRACE",
-	TOKEN_COMMA:      "COMMA",
-	TOKEN_AT:         "AT",
-	TOKEN_GT:         "GT",
-	TOKEN_LT:         "LT",
-	TOKEN_GTE:        "GTE",
-	TOKEN_LTE:        "LTE",
-	TOKEN_EQ:         "EQ",
-	TOKEN_NEQ:        "NEQ",
-	TOKEN_AND:        "AND",
-	TOKEN_OR:         "OR",
-	TOKEN_NOT:        "NOT",
-	TOKEN_NUMBER:     "NUMBER",
-	TOKEN_DURATION:   "DURATION",
-	TOKEN_STRING:     "STRING",
-	TOKEN_COMMENT:    "COMMENT",
+	TOKEN_ILLEGAL:           "ILLEGAL",
+	TOKEN_EOF:               "EOF",
+	TOKEN_IDENT:             "IDENT",
+	TOKEN_PRIMITIVE:         "PRIMITIVE",
+	TOKEN_FUNCTION:          "FUNCTION",
+	TOKEN_IF:                "IF",
+	TOKEN_ELSE:              "ELSE",
+	TOKEN_DIRECTION:         "DIRECTION",
+	TOKEN_BASE:              "BASE",
+	TOKEN_LATITUDE:          "LATITUDE",
+	TOKEN_LONGITUDE:         "LONGITUDE",
+	TOKEN_DAY_LENGTH:        "DAY_LENGTH",
+	TOKEN_MONTH:             "MONTH",
+	TOKEN_SEASON:            "SEASON",
+	TOKEN_MOON_ILLUMINATION: "MOON_ILLUMINATION",
+	TOKEN_MOON_PHASE:        "MOON_PHASE",
+	TOKEN_PLUS:              "PLUS",
+	TOKEN_MINUS:             "MINUS",
+	TOKEN_MULTIPLY:          "MULTIPLY",
+	TOKEN_DIVIDE:            "DIVIDE",
+	TOKEN_LPAREN:            "LPAREN",
+	TOKEN_RPAREN:            "RPAREN",
+	TOKEN_LBRACE:            "LBRACE",
+	TOKEN_RBRACE:            "RBRACE",
+	TOKEN_COMMA:             "COMMA",
+	TOKEN_AT:                "AT",
+	TOKEN_GT:                "GT",
+	TOKEN_LT:                "LT",
+	TOKEN_GTE:               "GTE",
+	TOKEN_LTE:               "LTE",
+	TOKEN_EQ:                "EQ",
+	TOKEN_NEQ:               "NEQ",
+	TOKEN_AND:               "AND",
+	TOKEN_OR:                "OR",
+	TOKEN_NOT:               "NOT",
+	TOKEN_NUMBER:            "NUMBER",
+	TOKEN_DURATION:          "DURATION",
+	TOKEN_STRING:            "STRING",
+	TOKEN_COMMENT:           "COMMENT",
 }
 
 func (t TokenType) String() string {
@@ -147,18 +155,22 @@ var Keywords = map[string]TokenType{
 
 // Primitives are built-in astronomical time calculations
 var Primitives = map[string]bool{
-	"sunrise":           true,
-	"sunset":            true,
-	"solar_noon":        true,
-	"solar_midnight":    true,
-	"visible_sunrise":   true,
-	"visible_sunset":    true,
-	"civil_dawn":        true,
-	"civil_dusk":        true,
-	"nautical_dawn":     true,
-	"nautical_dusk":     true,
-	"astronomical_dawn": true,
-	"astronomical_dusk": true,
+	"sunrise":                 true,
+	"sunset":                  true,
+	"solar_noon":              true,
+	"solar_midnight":          true,
+	"visible_sunrise":         true,
+	"visible_sunset":          true,
+	"visible_sunrise_terrain": true,
+	"visible_sunset_terrain":  true,
+	"civil_dawn":              true,
+	"civil_dusk":              true,
+	"nautical_dawn":           true,
+	"nautical_dusk":           true,
+	"astronomical_dawn":       true,
+	"astronomical_dusk":       true,
+	"moonrise":                true,
+	"moonset":                 true,
 }
 
 // Functions are built-in DSL functions
@@ -187,11 +199,25 @@ var Bases = map[string]bool{
 
 // ConditionKeywords are keywords used in conditional expressions
 var ConditionKeywords = map[string]TokenType{
-	"latitude":   TOKEN_LATITUDE,
-	"longitude":  TOKEN_LONGITUDE,
-	"day_length": TOKEN_DAY_LENGTH,
-	"month":      TOKEN_MONTH,
-	"season":     TOKEN_SEASON,
+	"latitude":          TOKEN_LATITUDE,
+	"longitude":         TOKEN_LONGITUDE,
+	"day_length":        TOKEN_DAY_LENGTH,
+	"month":             TOKEN_MONTH,
+	"season":            TOKEN_SEASON,
+	"moon_illumination": TOKEN_MOON_ILLUMINATION,
+	"moon_phase":        TOKEN_MOON_PHASE,
+}
+
+// MoonPhases are the values of the moon_phase condition variable
+var MoonPhases = map[string]bool{
+	astro.MoonNew:            true,
+	astro.MoonWaxingCrescent: true,
+	astro.MoonFirstQuarter:   true,
+	astro.MoonWaxingGibbous:  true,
+	astro.MoonFull:           true,
+	astro.MoonWaningGibbous:  true,
+	astro.MoonLastQuarter:    true,
+	astro.MoonWaningCrescent: true,
 }
 
 // LookupIdent returns the token type for an identifier
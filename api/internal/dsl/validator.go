@@ -362,6 +362,16 @@ func (v *Validator) validateCondition(n *ConditionNode) {
 			if rightType != ValueTypeString {
 				v.addError(n.Pos, "season comparison requires a string, got %s", rightType)
 			}
+		case "moon_illumination":
+			if rightType != ValueTypeNumber {
+				v.addError(n.Pos, "moon_illumination comparison requires a number between 0 and 1, got %s", rightType)
+			}
+		case "moon_phase":
+			if rightType != ValueTypeString {
+				v.addError(n.Pos, "moon_phase comparison requires a string, got %s", rightType)
+			} else if s, ok := n.Right.(*StringNode); ok && !MoonPhases[s.Value] {
+				v.addError(n.Pos, "unknown moon phase %q", s.Value)
+			}
 		}
 	}
 }
@@ -438,19 +448,23 @@ func DetectCircularDependencies(formulas map[string]string) ([]string, error) {
 		deps[key] = refs
 	}
 
-	// Topological sort using Kahn's algorithm
-	// Calculate in-degrees
+	// Topological sort using Kahn's algorithm. A zman's in-degree is the number
+	// of formulas it references, so dependencies come out before dependents.
+	// References to zmanim outside the set are ignored.
 	inDegree := make(map[string]int)
+	dependents := make(map[string][]string)
 	for key := range formulas {
-		if _, ok := inDegree[key]; !ok {
-			inDegree[key] = 0
-		}
+		inDegree[key] = 0
 		for _, dep := range deps[key] {
-			inDegree[dep]++
+			if _, ok := formulas[dep]; !ok {
+				continue
+			}
+			inDegree[key]++
+			dependents[dep] = append(dependents[dep], key)
 		}
 	}
 
-	// Find nodes with no incoming edges
+	// Find nodes with no dependencies
 	var queue []string
 	for key := range formulas {
 		if inDegree[key] == 0 {
@@ -466,10 +480,10 @@ func DetectCircularDependencies(formulas map[string]string) ([]string, error) {
 		order = append(order, node)
 
 		// Decrease in-degree of dependents
-		for _, dep := range deps[node] {
-			inDegree[dep]--
-			if inDegree[dep] == 0 {
-				queue = append(queue, dep)
+		for _, dependent := range dependents[node] {
+			inDegree[dependent]--
+			if inDegree[dependent] == 0 {
+				queue = append(queue, dependent)
 			}
 		}
 	}
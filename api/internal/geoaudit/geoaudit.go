@@ -0,0 +1,704 @@
+// Package geoaudit checks the geographic database for data-quality problems
+// that affect zmanim: cities with missing elevation or timezone, timezones
+// not in effect in the city's country, cities lying outside their region or
+// district boundary, and near-duplicate cities.
+//
+// Run produces a report per category with a sample of the worst cases and a
+// suggested fix for each. ApplySafeFixes applies the fixes that only fill in
+// missing data (elevation from SRTM, timezone from the timezone boundaries);
+// fixes that overwrite or delete data are only ever suggested.
+package geoaudit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jcom-dev/zmanim-lab/internal/terrain"
+)
+
+// Category is a kind of data-quality issue
+type Category string
+
+// Audit categories, in report order
+const (
+	MissingElevation        Category = "missing_elevation"
+	MissingTimezone         Category = "missing_timezone"
+	TimezoneCountryMismatch Category = "timezone_country_mismatch"
+	OutsideRegion           Category = "outside_region"
+	OutsideDistrict         Category = "outside_district"
+	DuplicateCity           Category = "duplicate_city"
+)
+
+// Defaults for Options
+const (
+	DefaultLimit               = 50
+	DefaultDuplicateMeters     = 500
+	DefaultBoundaryToleranceKm = 10
+)
+
+// Options scopes an audit
+type Options struct {
+	CountryCode         string     // ISO 3166-1 alpha-2; empty audits every country
+	Categories          []Category // empty runs every check
+	Limit               int        // issues listed per category, worst first
+	DuplicateMeters     float64    // same-name cities closer than this are duplicates
+	BoundaryToleranceKm float64    // cities this far outside their boundary are flagged
+}
+
+func (o Options) withDefaults() Options {
+	if o.Limit <= 0 {
+		o.Limit = DefaultLimit
+	}
+	if o.DuplicateMeters <= 0 {
+		o.DuplicateMeters = DefaultDuplicateMeters
+	}
+	if o.BoundaryToleranceKm <= 0 {
+		o.BoundaryToleranceKm = DefaultBoundaryToleranceKm
+	}
+	o.CountryCode = strings.ToUpper(o.CountryCode)
+	return o
+}
+
+// country returns the country filter as a nullable query argument
+func (o Options) country() *string {
+	if o.CountryCode == "" {
+		return nil
+	}
+	return &o.CountryCode
+}
+
+// Issue is one city with a problem
+type Issue struct {
+	CityID      string  `json:"city_id"`
+	CityName    string  `json:"city_name"`
+	CountryCode string  `json:"country_code,omitempty"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Detail      string  `json:"detail"`
+	Suggestion  string  `json:"suggestion"`
+	RelatedID   string  `json:"related_id,omitempty"` // the city to keep for a duplicate, or the suggested region or district
+}
+
+// CategoryReport is the result of one check
+type CategoryReport struct {
+	Category    Category `json:"category"`
+	Description string   `json:"description"`
+	Fix         string   `json:"fix"`
+	SafeFix     bool     `json:"safe_fix"` // applied by ApplySafeFixes
+	Count       int      `json:"count"`
+	Skipped     string   `json:"skipped,omitempty"` // why the check did not run
+	Issues      []Issue  `json:"issues"`
+}
+
+// Report is the result of an audit
+type Report struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	CountryCode string           `json:"country_code,omitempty"`
+	Total       int              `json:"total"`
+	Categories  []CategoryReport `json:"categories"`
+	DurationMs  int64            `json:"duration_ms"`
+}
+
+// check is one audit category and how to run it
+type check struct {
+	category       Category
+	description    string
+	fix            string
+	safe           bool
+	needsTimezones bool
+	run            func(ctx context.Context, pool *pgxpool.Pool, opts Options) ([]Issue, int, error)
+}
+
+var checks = []check{
+	{
+		category:    MissingElevation,
+		description: "Elevation is missing or 0 m; elevation shifts sunrise and sunset",
+		fix:         "Fill elevation from SRTM",
+		safe:        true,
+		run:         checkMissingElevation,
+	},
+	{
+		category:       MissingTimezone,
+		description:    "Timezone is missing, invalid, or the UTC placeholder the importer falls back to",
+		fix:            "Set the timezone of the timezone boundary containing the city",
+		safe:           true,
+		needsTimezones: true,
+		run:            checkMissingTimezone,
+	},
+	{
+		category:       TimezoneCountryMismatch,
+		description:    "Timezone is not in effect anywhere in the city's country",
+		fix:            "Review, then set the timezone of the timezone boundary containing the city",
+		needsTimezones: true,
+		run:            checkTimezoneCountryMismatch,
+	},
+	{
+		category:    OutsideRegion,
+		description: "City lies outside its region's boundary by more than the tolerance",
+		fix:         "Review the coordinates, or reassign to the region containing the city",
+		run:         checkOutsideRegion,
+	},
+	{
+		category:    OutsideDistrict,
+		description: "City lies outside its district's boundary by more than the tolerance",
+		fix:         "Review the coordinates, or reassign to the district containing the city",
+		run:         checkOutsideDistrict,
+	},
+	{
+		category:    DuplicateCity,
+		description: "Cities with the same name within the duplicate distance",
+		fix:         "Merge into the city to keep, moving publisher coverage first",
+		run:         checkDuplicates,
+	},
+}
+
+// ParseCategories parses a comma-separated list of categories
+func ParseCategories(s string) ([]Category, error) {
+	var categories []Category
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		known := false
+		for _, c := range checks {
+			if string(c.category) == part {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown audit category %q (want %s)", part, strings.Join(categoryNames(), ", "))
+		}
+		categories = append(categories, Category(part))
+	}
+	return categories, nil
+}
+
+// categoryNames lists every category in report order
+func categoryNames() []string {
+	names := make([]string, len(checks))
+	for i, c := range checks {
+		names[i] = string(c.category)
+	}
+	return names
+}
+
+// selected reports whether opts asks for a category
+func (o Options) selected(c Category) bool {
+	if len(o.Categories) == 0 {
+		return true
+	}
+	for _, s := range o.Categories {
+		if s == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Auditor audits and repairs the geographic tables
+type Auditor struct {
+	Pool *pgxpool.Pool
+
+	// Elevations fills missing elevations in ApplySafeFixes (optional)
+	Elevations terrain.ElevationSource
+}
+
+// Run audits the selected categories
+func (a *Auditor) Run(ctx context.Context, opts Options) (*Report, error) {
+	start := time.Now()
+	opts = opts.withDefaults()
+
+	var haveTimezones bool
+	if err := a.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM geo_timezone_boundaries)`).Scan(&haveTimezones); err != nil {
+		return nil, fmt.Errorf("check timezone boundaries: %w", err)
+	}
+
+	report := &Report{GeneratedAt: start.UTC(), CountryCode: opts.CountryCode, Categories: []CategoryReport{}}
+	for _, c := range checks {
+		if !opts.selected(c.category) {
+			continue
+		}
+		cr := CategoryReport{
+			Category:    c.category,
+			Description: c.description,
+			Fix:         c.fix,
+			SafeFix:     c.safe,
+			Issues:      []Issue{},
+		}
+		if c.needsTimezones && !haveTimezones {
+			cr.Skipped = "timezone boundaries are not imported (import-wof timezones)"
+		} else {
+			issues, count, err := c.run(ctx, a.Pool, opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", c.category, err)
+			}
+			cr.Issues = append(cr.Issues, issues...)
+			cr.Count = count
+		}
+		report.Total += cr.Count
+		report.Categories = append(report.Categories, cr)
+	}
+
+	report.DurationMs = time.Since(start).Milliseconds()
+	return report, nil
+}
+
+// scanIssues reads rows of (id, name, country code, latitude, longitude,
+// extra..., total), letting describe fill in the detail from the extras
+func scanIssues(rows pgx.Rows, extra []any, describe func(*Issue)) ([]Issue, int, error) {
+	defer rows.Close()
+
+	var issues []Issue
+	var total int
+	for rows.Next() {
+		var issue Issue
+		dest := append([]any{&issue.CityID, &issue.CityName, &issue.CountryCode, &issue.Latitude, &issue.Longitude}, extra...)
+		if err := rows.Scan(append(dest, &total)...); err != nil {
+			return nil, 0, err
+		}
+		describe(&issue)
+		issues = append(issues, issue)
+	}
+	return issues, total, rows.Err()
+}
+
+func checkMissingElevation(ctx context.Context, pool *pgxpool.Pool, opts Options) ([]Issue, int, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT c.id::text, c.name, COALESCE(co.code, ''), c.latitude, c.longitude,
+		       c.elevation_m, count(*) OVER ()
+		FROM geo_cities c
+		LEFT JOIN geo_countries co ON co.id = c.country_id
+		WHERE (c.elevation_m IS NULL OR c.elevation_m = 0)
+		  AND ($1::text IS NULL OR co.code = $1)
+		ORDER BY c.population DESC NULLS LAST, c.id
+		LIMIT $2
+	`, opts.country(), opts.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var elevation *int32
+	return scanIssues(rows, []any{&elevation}, func(issue *Issue) {
+		if elevation == nil {
+			issue.Detail = "Elevation is missing"
+			issue.Suggestion = "Fill from SRTM"
+		} else {
+			issue.Detail = "Elevation is 0 m"
+			issue.Suggestion = "Fill from SRTM unless the city is at sea level"
+		}
+	})
+}
+
+func checkMissingTimezone(ctx context.Context, pool *pgxpool.Pool, opts Options) ([]Issue, int, error) {
+	rows, err := pool.Query(ctx, `
+		WITH zones AS MATERIALIZED (
+			SELECT name FROM pg_timezone_names
+		), flagged AS (
+			SELECT c.id, c.name, COALESCE(co.code, '') AS country_code, c.latitude, c.longitude,
+			       c.location, c.population, c.timezone, count(*) OVER () AS total
+			FROM geo_cities c
+			LEFT JOIN geo_countries co ON co.id = c.country_id
+			WHERE (c.timezone IN ('', 'UTC', 'Etc/UTC') OR c.timezone NOT IN (SELECT name FROM zones))
+			  AND ($1::text IS NULL OR co.code = $1)
+			ORDER BY c.population DESC NULLS LAST, c.id
+			LIMIT $2
+		)
+		SELECT f.id::text, f.name, f.country_code, f.latitude, f.longitude,
+		       f.timezone, COALESCE(tz.tzid, ''), f.total
+		FROM flagged f
+		LEFT JOIN LATERAL (
+			SELECT tb.tzid FROM geo_timezone_boundaries tb
+			WHERE ST_Covers(tb.boundary, f.location)
+			LIMIT 1
+		) tz ON true
+		ORDER BY f.population DESC NULLS LAST, f.id
+	`, opts.country(), opts.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var timezone, boundaryZone string
+	return scanIssues(rows, []any{&timezone, &boundaryZone}, func(issue *Issue) {
+		switch timezone {
+		case "":
+			issue.Detail = "Timezone is missing"
+		case "UTC", "Etc/UTC":
+			issue.Detail = "Timezone is the " + timezone + " placeholder"
+		default:
+			issue.Detail = fmt.Sprintf("Timezone %q is not an IANA timezone", timezone)
+		}
+		issue.Suggestion = timezoneSuggestion(boundaryZone)
+	})
+}
+
+func checkTimezoneCountryMismatch(ctx context.Context, pool *pgxpool.Pool, opts Options) ([]Issue, int, error) {
+	// A timezone is in effect in a country when at least one of its cities
+	// lies inside that timezone's boundary. Each country/timezone pair is
+	// checked once.
+	rows, err := pool.Query(ctx, `
+		WITH pairs AS MATERIALIZED (
+			SELECT DISTINCT c.country_id, c.timezone
+			FROM geo_cities c
+			JOIN geo_countries co ON co.id = c.country_id
+			WHERE c.timezone NOT IN ('', 'UTC', 'Etc/UTC')
+			  AND ($1::text IS NULL OR co.code = $1)
+		), mismatched AS MATERIALIZED (
+			SELECT p.country_id, p.timezone
+			FROM pairs p
+			WHERE NOT EXISTS (
+				SELECT 1
+				FROM geo_timezone_boundaries tb
+				JOIN geo_cities c2 ON c2.country_id = p.country_id AND ST_Covers(tb.boundary, c2.location)
+				WHERE tb.tzid = p.timezone
+			)
+		), flagged AS (
+			SELECT c.id, c.name, co.code AS country_code, c.latitude, c.longitude,
+			       c.location, c.population, c.timezone, count(*) OVER () AS total
+			FROM geo_cities c
+			JOIN mismatched m ON m.country_id = c.country_id AND m.timezone = c.timezone
+			JOIN geo_countries co ON co.id = c.country_id
+			ORDER BY c.population DESC NULLS LAST, c.id
+			LIMIT $2
+		)
+		SELECT f.id::text, f.name, f.country_code, f.latitude, f.longitude,
+		       f.timezone, COALESCE(tz.tzid, ''), f.total
+		FROM flagged f
+		LEFT JOIN LATERAL (
+			SELECT tb.tzid FROM geo_timezone_boundaries tb
+			WHERE ST_Covers(tb.boundary, f.location)
+			LIMIT 1
+		) tz ON true
+		ORDER BY f.population DESC NULLS LAST, f.id
+	`, opts.country(), opts.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var timezone, boundaryZone string
+	return scanIssues(rows, []any{&timezone, &boundaryZone}, func(issue *Issue) {
+		issue.Detail = fmt.Sprintf("No city in %s lies in %s", issue.CountryCode, timezone)
+		issue.Suggestion = timezoneSuggestion(boundaryZone)
+	})
+}
+
+// timezoneSuggestion suggests the timezone of the boundary containing a city
+func timezoneSuggestion(boundaryZone string) string {
+	if boundaryZone == "" {
+		return "No timezone boundary contains the city; check its coordinates"
+	}
+	return "Set timezone to " + boundaryZone
+}
+
+func checkOutsideRegion(ctx context.Context, pool *pgxpool.Pool, opts Options) ([]Issue, int, error) {
+	return checkOutsideBoundary(ctx, pool, opts, "region", `
+		WITH flagged AS (
+			SELECT c.id, c.name, COALESCE(co.code, '') AS country_code, c.latitude, c.longitude,
+			       c.location, p.name AS parent_name,
+			       ST_Distance(c.location, b.boundary) / 1000 AS distance_km,
+			       count(*) OVER () AS total
+			FROM geo_cities c
+			JOIN geo_regions p ON p.id = c.region_id
+			JOIN geo_region_boundaries b ON b.region_id = c.region_id
+			LEFT JOIN geo_countries co ON co.id = c.country_id
+			WHERE ($1::text IS NULL OR co.code = $1)
+			  AND NOT ST_DWithin(c.location, b.boundary, $3 * 1000)
+			ORDER BY distance_km DESC, c.id
+			LIMIT $2
+		)
+		SELECT f.id::text, f.name, f.country_code, f.latitude, f.longitude,
+		       f.parent_name, f.distance_km, COALESCE(s.id::text, ''), COALESCE(s.name, ''), f.total
+		FROM flagged f
+		LEFT JOIN LATERAL (
+			SELECT r.id, r.name
+			FROM geo_region_boundaries rb
+			JOIN geo_regions r ON r.id = rb.region_id
+			WHERE ST_Covers(rb.boundary, f.location)
+			ORDER BY rb.area_km2 NULLS LAST
+			LIMIT 1
+		) s ON true
+		ORDER BY f.distance_km DESC, f.id
+	`)
+}
+
+func checkOutsideDistrict(ctx context.Context, pool *pgxpool.Pool, opts Options) ([]Issue, int, error) {
+	return checkOutsideBoundary(ctx, pool, opts, "district", `
+		WITH flagged AS (
+			SELECT c.id, c.name, COALESCE(co.code, '') AS country_code, c.latitude, c.longitude,
+			       c.location, p.name AS parent_name,
+			       ST_Distance(c.location, b.boundary) / 1000 AS distance_km,
+			       count(*) OVER () AS total
+			FROM geo_cities c
+			JOIN geo_districts p ON p.id = c.district_id
+			JOIN geo_district_boundaries b ON b.district_id = c.district_id
+			LEFT JOIN geo_countries co ON co.id = c.country_id
+			WHERE ($1::text IS NULL OR co.code = $1)
+			  AND NOT ST_DWithin(c.location, b.boundary, $3 * 1000)
+			ORDER BY distance_km DESC, c.id
+			LIMIT $2
+		)
+		SELECT f.id::text, f.name, f.country_code, f.latitude, f.longitude,
+		       f.parent_name, f.distance_km, COALESCE(s.id::text, ''), COALESCE(s.name, ''), f.total
+		FROM flagged f
+		LEFT JOIN LATERAL (
+			SELECT d.id, d.name
+			FROM geo_district_boundaries db
+			JOIN geo_districts d ON d.id = db.district_id
+			WHERE ST_Covers(db.boundary, f.location)
+			ORDER BY db.area_km2 NULLS LAST
+			LIMIT 1
+		) s ON true
+		ORDER BY f.distance_km DESC, f.id
+	`)
+}
+
+// checkOutsideBoundary runs a region or district boundary query; see
+// checkOutsideRegion for the columns it returns
+func checkOutsideBoundary(ctx context.Context, pool *pgxpool.Pool, opts Options, level, query string) ([]Issue, int, error) {
+	rows, err := pool.Query(ctx, query, opts.country(), opts.Limit, opts.BoundaryToleranceKm)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var parentName, containingID, containingName string
+	var distanceKm float64
+	return scanIssues(rows, []any{&parentName, &distanceKm, &containingID, &containingName}, func(issue *Issue) {
+		issue.Detail = fmt.Sprintf("%.1f km outside %s %s", distanceKm, level, parentName)
+		if containingID == "" {
+			issue.Suggestion = fmt.Sprintf("No %s boundary contains the city; check its coordinates", level)
+			return
+		}
+		issue.Suggestion = fmt.Sprintf("Check the coordinates, or reassign to %s %s (%s)", level, containingName, containingID)
+		issue.RelatedID = containingID
+	})
+}
+
+// duplicateCity is one side of a duplicate pair
+type duplicateCity struct {
+	id         string
+	name       string
+	population int64
+	covered    bool // referenced by publisher coverage
+}
+
+// keepDuplicate picks which of two duplicates to keep: the one publishers
+// cover, then the more populous, then the first by ID
+func keepDuplicate(a, b duplicateCity) (keep, drop duplicateCity) {
+	switch {
+	case a.covered != b.covered:
+		if a.covered {
+			return a, b
+		}
+		return b, a
+	case a.population != b.population:
+		if a.population > b.population {
+			return a, b
+		}
+		return b, a
+	case a.id <= b.id:
+		return a, b
+	default:
+		return b, a
+	}
+}
+
+func checkDuplicates(ctx context.Context, pool *pgxpool.Pool, opts Options) ([]Issue, int, error) {
+	rows, err := pool.Query(ctx, `
+		SELECT a.id::text, a.name, COALESCE(co.code, ''), a.latitude, a.longitude,
+		       COALESCE(a.population, 0), EXISTS (SELECT 1 FROM publisher_coverage pc WHERE pc.city_id = a.id),
+		       b.id::text, b.name, COALESCE(b.population, 0),
+		       EXISTS (SELECT 1 FROM publisher_coverage pc WHERE pc.city_id = b.id),
+		       ST_Distance(a.location, b.location), count(*) OVER ()
+		FROM geo_cities a
+		JOIN geo_cities b ON b.id > a.id
+		  AND ST_DWithin(a.location, b.location, $3)
+		  AND geo_search_key(b.name) = geo_search_key(a.name)
+		LEFT JOIN geo_countries co ON co.id = a.country_id
+		WHERE ($1::text IS NULL OR co.code = $1)
+		ORDER BY ST_Distance(a.location, b.location), a.id, b.id
+		LIMIT $2
+	`, opts.country(), opts.Limit, opts.DuplicateMeters)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var issues []Issue
+	var total int
+	for rows.Next() {
+		var a, b duplicateCity
+		var issue Issue
+		var meters float64
+		if err := rows.Scan(&a.id, &a.name, &issue.CountryCode, &issue.Latitude, &issue.Longitude,
+			&a.population, &a.covered, &b.id, &b.name, &b.population, &b.covered, &meters, &total); err != nil {
+			return nil, 0, err
+		}
+
+		keep, drop := keepDuplicate(a, b)
+		issue.CityID = drop.id
+		issue.CityName = drop.name
+		issue.RelatedID = keep.id
+		issue.Detail = fmt.Sprintf("Same name as %s (%s), %s away", keep.name, keep.id, formatMeters(meters))
+		if drop.covered {
+			issue.Suggestion = fmt.Sprintf("Move publisher coverage to %s, then merge into it", keep.id)
+		} else {
+			issue.Suggestion = fmt.Sprintf("Merge into %s", keep.id)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, total, rows.Err()
+}
+
+func formatMeters(m float64) string {
+	if m < 1000 {
+		return fmt.Sprintf("%.0f m", m)
+	}
+	return fmt.Sprintf("%.1f km", m/1000)
+}
+
+// FixOptions scopes ApplySafeFixes
+type FixOptions struct {
+	CountryCode   string // ISO 3166-1 alpha-2; empty fixes every country
+	MaxElevations int    // elevations looked up per call; 0 means no limit
+}
+
+// FixResult counts the fixes applied
+type FixResult struct {
+	TimezonesSet          int    `json:"timezones_set"`
+	ElevationsSet         int    `json:"elevations_set"`
+	ElevationsChecked     int    `json:"elevations_checked"`
+	ElevationsUnavailable int    `json:"elevations_unavailable"` // no SRTM data at the city
+	ElevationsSkipped     string `json:"elevations_skipped,omitempty"`
+	DurationMs            int64  `json:"duration_ms"`
+}
+
+// ApplySafeFixes fills in missing timezones from the timezone boundaries
+// and missing elevations from SRTM. A 0 m elevation is only replaced when
+// SRTM disagrees, since some cities really are at sea level.
+func (a *Auditor) ApplySafeFixes(ctx context.Context, opts FixOptions) (*FixResult, error) {
+	start := time.Now()
+	country := Options{CountryCode: opts.CountryCode}.withDefaults().country()
+	result := &FixResult{}
+
+	tag, err := a.Pool.Exec(ctx, `
+		UPDATE geo_cities c SET timezone = tz.tzid
+		FROM geo_cities c2
+		LEFT JOIN geo_countries co ON co.id = c2.country_id
+		CROSS JOIN LATERAL (
+			SELECT tb.tzid FROM geo_timezone_boundaries tb
+			WHERE ST_Covers(tb.boundary, c2.location)
+			LIMIT 1
+		) tz
+		WHERE c.id = c2.id
+		  AND (c2.timezone IN ('', 'UTC', 'Etc/UTC')
+		       OR c2.timezone NOT IN (SELECT name FROM pg_timezone_names))
+		  AND ($1::text IS NULL OR co.code = $1)
+	`, country)
+	if err != nil {
+		return nil, fmt.Errorf("fill timezones: %w", err)
+	}
+	result.TimezonesSet = int(tag.RowsAffected())
+
+	if a.Elevations == nil {
+		result.ElevationsSkipped = "no SRTM elevation source configured"
+	} else if err := a.fillElevations(ctx, country, opts.MaxElevations, result); err != nil {
+		return nil, err
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// fillElevations looks up cities with a missing or 0 m elevation in SRTM,
+// in batches keyed by city ID
+func (a *Auditor) fillElevations(ctx context.Context, country *string, maxChecks int, result *FixResult) error {
+	const batchSize = 500
+	cursor := "00000000-0000-0000-0000-000000000000"
+
+	for maxChecks == 0 || result.ElevationsChecked < maxChecks {
+		limit := batchSize
+		if maxChecks > 0 && maxChecks-result.ElevationsChecked < limit {
+			limit = maxChecks - result.ElevationsChecked
+		}
+
+		type city struct {
+			id        string
+			lat, lng  float64
+			elevation *int32
+		}
+		rows, err := a.Pool.Query(ctx, `
+			SELECT c.id::text, c.latitude, c.longitude, c.elevation_m
+			FROM geo_cities c
+			LEFT JOIN geo_countries co ON co.id = c.country_id
+			WHERE (c.elevation_m IS NULL OR c.elevation_m = 0)
+			  AND ($1::text IS NULL OR co.code = $1)
+			  AND c.id > $2::uuid
+			ORDER BY c.id
+			LIMIT $3
+		`, country, cursor, limit)
+		if err != nil {
+			return fmt.Errorf("load cities without elevation: %w", err)
+		}
+		cities, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (city, error) {
+			var c city
+			err := row.Scan(&c.id, &c.lat, &c.lng, &c.elevation)
+			return c, err
+		})
+		if err != nil {
+			return fmt.Errorf("load cities without elevation: %w", err)
+		}
+		if len(cities) == 0 {
+			return nil
+		}
+
+		batch := &pgx.Batch{}
+		for _, c := range cities {
+			cursor = c.id
+			result.ElevationsChecked++
+
+			meters, err := a.Elevations.Elevation(c.lat, c.lng)
+			if err != nil || math.IsNaN(meters) {
+				result.ElevationsUnavailable++
+				continue
+			}
+			elevation := int32(math.Round(meters))
+			if c.elevation != nil && elevation == 0 {
+				continue // SRTM agrees the city is at sea level
+			}
+			batch.Queue(`UPDATE geo_cities SET elevation_m = $2 WHERE id = $1`, c.id, elevation)
+			result.ElevationsSet++
+		}
+		if batch.Len() > 0 {
+			if err := a.Pool.SendBatch(ctx, batch).Close(); err != nil {
+				return fmt.Errorf("update elevations: %w", err)
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrUnknownCountry is returned by ValidateCountry for codes not in geo_countries
+var ErrUnknownCountry = errors.New("unknown country code")
+
+// ValidateCountry checks that a country code is in geo_countries
+func (a *Auditor) ValidateCountry(ctx context.Context, code string) error {
+	var exists bool
+	if err := a.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM geo_countries WHERE code = $1)`,
+		strings.ToUpper(code)).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownCountry, code)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package geoaudit
+
+import "testing"
+
+func TestParseCategories(t *testing.T) {
+	got, err := ParseCategories(" missing_elevation, duplicate_city ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != MissingElevation || got[1] != DuplicateCity {
+		t.Errorf("ParseCategories() = %v", got)
+	}
+
+	if got, err := ParseCategories(""); err != nil || len(got) != 0 {
+		t.Errorf("ParseCategories(\"\") = %v, %v; want every category", got, err)
+	}
+	if _, err := ParseCategories("missing_elevation,bogus"); err == nil {
+		t.Error("ParseCategories() accepted an unknown category")
+	}
+}
+
+func TestOptionsSelected(t *testing.T) {
+	all := Options{}
+	if !all.selected(OutsideDistrict) {
+		t.Error("empty Categories should select every category")
+	}
+	some := Options{Categories: []Category{MissingTimezone}}
+	if !some.selected(MissingTimezone) || some.selected(MissingElevation) {
+		t.Error("selected() ignored Categories")
+	}
+}
+
+func TestOptionsWithDefaults(t *testing.T) {
+	o := Options{CountryCode: "il"}.withDefaults()
+	if o.Limit != DefaultLimit || o.DuplicateMeters != DefaultDuplicateMeters || o.BoundaryToleranceKm != DefaultBoundaryToleranceKm {
+		t.Errorf("withDefaults() = %+v", o)
+	}
+	if c := o.country(); c == nil || *c != "IL" {
+		t.Errorf("country() = %v, want IL", c)
+	}
+	if c := (Options{}).country(); c != nil {
+		t.Errorf("country() = %v, want nil", *c)
+	}
+}
+
+func TestKeepDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b duplicateCity
+		keep string
+	}{
+		{"covered wins", duplicateCity{id: "a", population: 10}, duplicateCity{id: "b", covered: true}, "b"},
+		{"population wins", duplicateCity{id: "a", population: 10}, duplicateCity{id: "b", population: 500}, "b"},
+		{"coverage beats population", duplicateCity{id: "a", population: 500}, duplicateCity{id: "b", population: 1, covered: true}, "b"},
+		{"first id breaks ties", duplicateCity{id: "b"}, duplicateCity{id: "a"}, "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep, drop := keepDuplicate(tt.a, tt.b)
+			if keep.id != tt.keep || drop.id == tt.keep {
+				t.Errorf("keepDuplicate() kept %s, dropped %s; want %s kept", keep.id, drop.id, tt.keep)
+			}
+		})
+	}
+}
+
+func TestFormatMeters(t *testing.T) {
+	for m, want := range map[float64]string{0: "0 m", 120.4: "120 m", 1500: "1.5 km"} {
+		if got := formatMeters(m); got != want {
+			t.Errorf("formatMeters(%v) = %q, want %q", m, got, want)
+		}
+	}
+}
@@ -23,10 +23,11 @@ type AISearchResponse struct {
 
 // AIContextRequest represents a context assembly request
 type AIContextRequest struct {
-	Query           string `json:"query"`
-	MaxTokens       int    `json:"max_tokens,omitempty"`
-	IncludeExamples bool   `json:"include_examples,omitempty"`
-	IncludeHalachic bool   `json:"include_halachic,omitempty"`
+	Query           string   `json:"query"`
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	IncludeExamples bool     `json:"include_examples,omitempty"`
+	IncludeHalachic bool     `json:"include_halachic,omitempty"`
+	Sources         []string `json:"sources,omitempty"`
 }
 
 // SearchAI performs semantic search on the knowledge base
@@ -58,14 +59,11 @@ func (h *Handlers) SearchAI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var results []ai.SearchResult
-	var err error
-
-	if req.ContentType != "" {
-		results, err = h.aiSearch.SearchSimilarByType(ctx, req.Query, req.ContentType, req.TopK)
-	} else {
-		results, err = h.aiSearch.SearchSimilar(ctx, req.Query, req.TopK)
-	}
+	results, err := h.aiSearch.Search(ctx, req.Query, ai.SearchOptions{
+		TopK:        req.TopK,
+		Sources:     req.Sources,
+		ContentType: req.ContentType,
+	})
 
 	if err != nil {
 		RespondInternalError(w, r, "Search failed")
@@ -109,6 +107,7 @@ func (h *Handlers) GetAIContext(w http.ResponseWriter, r *http.Request) {
 		MaxDocs:         5,
 		IncludeExamples: req.IncludeExamples,
 		IncludeHalachic: req.IncludeHalachic,
+		FilterSources:   req.Sources,
 	}
 
 	if opts.MaxTokens <= 0 {
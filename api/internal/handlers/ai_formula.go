@@ -10,6 +10,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
 	"github.com/jcom-dev/zmanim-lab/internal/middleware"
 )
 
@@ -29,14 +30,18 @@ type GenerateFormulaResponse struct {
 // ExplainFormulaRequest represents a formula explanation request
 type ExplainFormulaRequest struct {
 	Formula  string `json:"formula"`
-	Language string `json:"language,omitempty"` // "en" or "he"
+	Language string `json:"language,omitempty"` // "en", "he" or "mixed"
+	// Elaborate asks the AI service to expand on the precise explanation
+	Elaborate bool `json:"elaborate,omitempty"`
 }
 
 // ExplainFormulaResponse represents the formula explanation response
 type ExplainFormulaResponse struct {
 	Explanation string `json:"explanation"`
 	Language    string `json:"language"`
-	Source      string `json:"source"` // "ai" or "cached"
+	Source      string `json:"source"` // "dsl", "ai" or "cached"
+	// Precise is the deterministic explanation generated from the formula (set when elaborating)
+	Precise string `json:"precise,omitempty"`
 }
 
 // GenerateFormula generates a DSL formula from natural language
@@ -112,7 +117,9 @@ func (h *Handlers) GenerateFormula(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ExplainFormula generates a human-readable explanation of a formula
+// ExplainFormula explains a formula. The default explanation is generated
+// deterministically from the formula's AST; with "elaborate" set and the AI
+// service configured, the AI expands on it.
 // POST /api/ai/explain-formula
 func (h *Handlers) ExplainFormula(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -140,23 +147,41 @@ func (h *Handlers) ExplainFormula(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check cache first
-	formulaHash := hashFormula(req.Formula)
-	cached, err := h.getCachedExplanation(ctx, formulaHash, req.Language)
-	if err == nil && cached != "" {
+	// The precise explanation is generated from the AST and needs no AI service
+	node, err := dsl.Parse(req.Formula)
+	if err != nil {
+		RespondValidationError(w, r, "Invalid formula", map[string]string{
+			"formula": err.Error(),
+		})
+		return
+	}
+	englishNames, hebrewNames := h.registryNames(ctx, dsl.ExtractReferences(node))
+	names := englishNames
+	if req.Language != "en" {
+		names = hebrewNames
+	}
+	precise := dsl.ExplainWithNames(node, req.Language, names)
+
+	if !req.Elaborate || h.aiClaude == nil {
 		RespondJSON(w, r, http.StatusOK, ExplainFormulaResponse{
-			Explanation: cached,
+			Explanation: precise,
 			Language:    req.Language,
-			Source:      "cached",
+			Source:      "dsl",
 		})
 		return
 	}
 
-	// Check if Claude service is configured
-	if h.aiClaude == nil {
-		RespondJSON(w, r, http.StatusServiceUnavailable, map[string]interface{}{
-			"error":   "AI service not configured",
-			"message": "Formula explanation is not available",
+	// Check cache first. Elaborations are anchored to the precise reading, so
+	// the reading is part of the key and a changed reading is not served stale.
+	anchor := dsl.ExplainWithNames(node, dsl.LangEnglish, englishNames)
+	formulaHash := explanationCacheKey(req.Formula, anchor)
+	cached, err := h.getCachedExplanation(ctx, formulaHash, req.Language)
+	if err == nil && cached != "" {
+		RespondJSON(w, r, http.StatusOK, ExplainFormulaResponse{
+			Explanation: cached,
+			Language:    req.Language,
+			Source:      "cached",
+			Precise:     precise,
 		})
 		return
 	}
@@ -178,6 +203,9 @@ func (h *Handlers) ExplainFormula(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Anchor the elaboration to the exact reading of the formula
+	ragContext = "Exact reading of the formula (do not contradict it): " + anchor + "\n\n" + ragContext
+
 	// Generate explanation
 	result, err := h.aiClaude.ExplainFormula(ctx, req.Formula, req.Language, ragContext)
 	durationMs := int(time.Since(startTime).Milliseconds())
@@ -205,6 +233,7 @@ func (h *Handlers) ExplainFormula(w http.ResponseWriter, r *http.Request) {
 		Explanation: result.Explanation,
 		Language:    result.Language,
 		Source:      result.Source,
+		Precise:     precise,
 	})
 }
 
@@ -218,6 +247,40 @@ func hashFormula(formula string) string {
 	return fmt.Sprintf("%016x", hash)
 }
 
+// explanationCacheKey keys a cached elaboration by the formula and the
+// precise English reading it was anchored to
+func explanationCacheKey(formula, reading string) string {
+	return hashFormula(formula + "\n" + reading)
+}
+
+// registryNames looks up the English and Hebrew registry names of zman keys.
+// Keys the registry does not know are left out.
+func (h *Handlers) registryNames(ctx context.Context, keys []string) (english, hebrew map[string]string) {
+	english, hebrew = map[string]string{}, map[string]string{}
+	if len(keys) == 0 {
+		return english, hebrew
+	}
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT zman_key, canonical_english_name, canonical_hebrew_name
+		FROM master_zmanim_registry
+		WHERE zman_key = ANY($1)
+	`, keys)
+	if err != nil {
+		slog.Warn("failed to look up registry names", "error", err)
+		return english, hebrew
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, en, he string
+		if err := rows.Scan(&key, &en, &he); err != nil {
+			slog.Warn("failed to scan registry names", "error", err)
+			continue
+		}
+		english[key], hebrew[key] = en, he
+	}
+	return english, hebrew
+}
+
 // getCachedExplanation retrieves a cached explanation if available
 func (h *Handlers) getCachedExplanation(ctx context.Context, formulaHash, language string) (string, error) {
 	var explanation string
@@ -238,35 +301,63 @@ func (h *Handlers) cacheExplanation(ctx context.Context, formulaHash, language,
 	`, formulaHash, language, explanation)
 }
 
-// validateDSL validates a DSL formula using the parser
+// validateDSL validates a generated formula with the DSL parser and validator.
+// References are not checked against a publisher's zmanim here.
 func (h *Handlers) validateDSL(formula string) error {
-	// Use the DSL validator from the dsl package
-	// This is a placeholder - actual validation is in dsl package
-	return nil
+	_, _, err := dsl.ValidateFormula(formula, nil)
+	return err
+}
+
+// aiAuditEntry is one row of ai_audit_logs
+type aiAuditEntry struct {
+	RequestType string
+	Input       string
+	Output      string
+	TokensUsed  int
+	Confidence  float64
+	Err         error
+	DurationMs  int
+	RAGUsed     bool
+	SessionID   string // formula builder session, if any
+	PublisherID string // defaults to the X-Publisher-Id header
 }
 
 // logAIAudit logs AI request to the audit table
 func (h *Handlers) logAIAudit(ctx context.Context, r *http.Request, requestType string, input string, result *ai.GenerationResult, err error, durationMs int, ragUsed bool) {
+	entry := aiAuditEntry{
+		RequestType: requestType,
+		Input:       input,
+		Err:         err,
+		DurationMs:  durationMs,
+		RAGUsed:     ragUsed,
+	}
+	if result != nil {
+		entry.Output = result.Formula
+		entry.TokensUsed = result.TokensUsed
+		entry.Confidence = result.Confidence
+	}
+	h.insertAIAudit(ctx, r, entry)
+}
+
+// insertAIAudit writes an audit entry; failures are logged, never returned
+func (h *Handlers) insertAIAudit(ctx context.Context, r *http.Request, entry aiAuditEntry) {
 	// Get user info from context if available
 	userID := middleware.GetUserID(r.Context())
 
 	// Get publisher ID from header if available
-	publisherID := r.Header.Get("X-Publisher-Id")
-
-	var outputText string
-	var tokensUsed int
-	var confidence float64
-	success := err == nil
-
-	if result != nil {
-		outputText = result.Formula
-		tokensUsed = result.TokensUsed
-		confidence = result.Confidence
+	publisherID := entry.PublisherID
+	if publisherID == "" {
+		publisherID = r.Header.Get("X-Publisher-Id")
 	}
 
 	var errorMessage string
-	if err != nil {
-		errorMessage = err.Error()
+	if entry.Err != nil {
+		errorMessage = entry.Err.Error()
+	}
+
+	var model string
+	if h.aiClaude != nil {
+		model = h.aiClaude.Model()
 	}
 
 	// Insert audit log
@@ -274,18 +365,20 @@ func (h *Handlers) logAIAudit(ctx context.Context, r *http.Request, requestType
 		INSERT INTO ai_audit_logs (
 			publisher_id, user_id, request_type, input_text, output_text,
 			tokens_used, model, confidence, success, error_message,
-			duration_ms, rag_context_used
+			duration_ms, rag_context_used, session_id
 		) VALUES (
 			NULLIF($1, '')::uuid, NULLIF($2, ''), $3, $4, $5,
-			$6, $7, $8, $9, $10, $11, $12
+			$6, $7, $8, $9, $10, $11, $12, NULLIF($13, '')::uuid
 		)
 	`
 
-	_, _ = h.db.Pool.Exec(ctx, query,
-		publisherID, userID, requestType, input, outputText,
-		tokensUsed, "claude-3-5-sonnet-20241022", confidence, success, errorMessage,
-		durationMs, ragUsed,
-	)
+	if _, err := h.db.Pool.Exec(ctx, query,
+		publisherID, userID, entry.RequestType, entry.Input, entry.Output,
+		entry.TokensUsed, model, entry.Confidence, entry.Err == nil, errorMessage,
+		entry.DurationMs, entry.RAGUsed, entry.SessionID,
+	); err != nil {
+		slog.Warn("failed to write AI audit log", "error", err, "request_type", entry.RequestType)
+	}
 }
 
 // GetAIAuditLogs returns AI audit logs for admin
@@ -296,18 +389,20 @@ func (h *Handlers) GetAIAuditLogs(w http.ResponseWriter, r *http.Request) {
 	// Parse query params
 	limit := 50
 	requestType := r.URL.Query().Get("type")
+	sessionID := r.URL.Query().Get("session_id")
 
 	query := `
 		SELECT id, publisher_id, user_id, request_type, input_text, output_text,
 		       tokens_used, model, confidence, success, error_message,
-		       duration_ms, rag_context_used, created_at
+		       duration_ms, rag_context_used, session_id::text, created_at
 		FROM ai_audit_logs
 		WHERE ($1 = '' OR request_type = $1)
+		  AND ($3 = '' OR session_id::text = $3)
 		ORDER BY created_at DESC
 		LIMIT $2
 	`
 
-	rows, err := h.db.Pool.Query(ctx, query, requestType, limit)
+	rows, err := h.db.Pool.Query(ctx, query, requestType, limit, sessionID)
 	if err != nil {
 		RespondInternalError(w, r, "Failed to query audit logs")
 		return
@@ -328,6 +423,7 @@ func (h *Handlers) GetAIAuditLogs(w http.ResponseWriter, r *http.Request) {
 		ErrorMessage   *string  `json:"error_message"`
 		DurationMs     *int     `json:"duration_ms"`
 		RAGContextUsed bool     `json:"rag_context_used"`
+		SessionID      *string  `json:"session_id,omitempty"`
 		CreatedAt      string   `json:"created_at"`
 	}
 
@@ -339,7 +435,7 @@ func (h *Handlers) GetAIAuditLogs(w http.ResponseWriter, r *http.Request) {
 			&log.ID, &log.PublisherID, &log.UserID, &log.RequestType,
 			&log.InputText, &log.OutputText, &log.TokensUsed, &log.Model,
 			&log.Confidence, &log.Success, &log.ErrorMessage, &log.DurationMs,
-			&log.RAGContextUsed, &createdAt,
+			&log.RAGContextUsed, &log.SessionID, &createdAt,
 		)
 		if err != nil {
 			continue
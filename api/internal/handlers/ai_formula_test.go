@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+)
+
+func TestExplainFormulaElaborateWithFakeProvider(t *testing.T) {
+	h := newDBTestHandlers(t)
+	helper := NewTestHelper(t)
+	ctx := context.Background()
+
+	const formula = "solar(16.1, before_sunrise) - 7min"
+	const elaboration = "Alos at 16.1 degrees, moved 7 minutes earlier."
+	fake := ai.NewFakeLLM().When("explain this zman calculation", elaboration)
+	h.SetAIServices(ai.NewClaudeServiceWithLLM(fake), nil, nil, nil)
+
+	node, err := dsl.Parse(formula)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := explanationCacheKey(formula, dsl.Explain(node, dsl.LangEnglish))
+
+	start := time.Now()
+	clear := func() {
+		_, _ = h.db.Pool.Exec(ctx, `DELETE FROM explanation_cache WHERE formula_hash = ANY($1)`, []string{key, hashFormula(formula)})
+		_, _ = h.db.Pool.Exec(ctx, `
+			DELETE FROM ai_audit_logs
+			WHERE request_type = 'explain_formula' AND input_text = $1 AND created_at >= $2
+		`, formula, start)
+	}
+	clear()
+	t.Cleanup(clear)
+
+	// An explanation cached under the formula alone predates the anchored
+	// elaboration and must not be served
+	if _, err := h.db.Pool.Exec(ctx, `
+		INSERT INTO explanation_cache (formula_hash, language, explanation, expires_at)
+		VALUES ($1, 'en', 'stale', NOW() + INTERVAL '1 day')
+	`, hashFormula(formula)); err != nil {
+		t.Fatalf("insert stale explanation: %v", err)
+	}
+
+	explain := func() ExplainFormulaResponse {
+		w := httptest.NewRecorder()
+		h.ExplainFormula(w, helper.MakeRequest("POST", "/api/v1/ai/explain-formula", ExplainFormulaRequest{
+			Formula: formula, Language: "en", Elaborate: true,
+		}))
+		helper.AssertStatus(w, http.StatusOK)
+		var resp struct {
+			Data ExplainFormulaResponse `json:"data"`
+		}
+		helper.ParseJSONResponse(w, &resp)
+		return resp.Data
+	}
+
+	first := explain()
+	if first.Source != "ai" || first.Explanation != elaboration || first.Precise == "" {
+		t.Errorf("first response = %+v", first)
+	}
+	if calls := fake.Calls(); len(calls) != 1 {
+		t.Fatalf("%d LLM calls, want 1", len(calls))
+	}
+
+	// The elaboration is cached; the provider is not called again
+	second := explain()
+	if second.Source != "cached" || second.Explanation != elaboration {
+		t.Errorf("second response = %+v", second)
+	}
+	if calls := fake.Calls(); len(calls) != 1 {
+		t.Errorf("%d LLM calls after a cache hit, want 1", len(calls))
+	}
+}
+
+func TestExplainFormulaMixed(t *testing.T) {
+	h := &Handlers{}
+	helper := NewTestHelper(t)
+
+	w := httptest.NewRecorder()
+	h.ExplainFormula(w, helper.MakeRequest("POST", "/api/v1/ai/explain-formula", ExplainFormulaRequest{
+		Formula: "sunrise - 72min + 30min", Language: "mixed",
+	}))
+	helper.AssertStatus(w, http.StatusOK)
+	var resp struct {
+		Data ExplainFormulaResponse `json:"data"`
+	}
+	helper.ParseJSONResponse(w, &resp)
+	if want := "42 minutes before נץ"; resp.Data.Explanation != want || resp.Data.Language != "mixed" {
+		t.Errorf("explanation = %q in %q, want %q in mixed", resp.Data.Explanation, resp.Data.Language, want)
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+)
+
+// maxPreviewDates bounds the dates a single preview_formula call may compute
+const maxPreviewDates = 14
+
+// previewKey is the formula-set key the previewed formula is executed under
+const previewKey = "__preview"
+
+// formulaBuilderTools are the server-side tools available to the formula
+// builder. They run with the publisher's zmanim and solar engine so
+// @references resolve and previews match the publisher's published times.
+type formulaBuilderTools struct {
+	h           *Handlers
+	publisherID string
+	formulas    map[string]string
+	engine      astro.SolarEngine // nil uses the default engine
+}
+
+func newFormulaBuilderTools(h *Handlers, publisherID string, formulas map[string]string, engine astro.SolarEngine) *formulaBuilderTools {
+	return &formulaBuilderTools{h: h, publisherID: publisherID, formulas: formulas, engine: engine}
+}
+
+// Specs describes the tools to the model
+func (t *formulaBuilderTools) Specs() []ai.ToolSpec {
+	return []ai.ToolSpec{
+		{
+			Name:        "validate_formula",
+			Description: "Validate a Zmanim DSL formula against the DSL and the publisher's zmanim. Returns ok or the errors.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"formula":{"type":"string"}},"required":["formula"]}`),
+		},
+		{
+			Name:        "preview_formula",
+			Description: "Calculate a formula for up to 14 dates (YYYY-MM-DD) at a city, or at latitude/longitude/timezone. Returns local times.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+				`"formula":{"type":"string"},` +
+				`"dates":{"type":"array","items":{"type":"string"}},` +
+				`"city":{"type":"string","description":"City name, optionally followed by a comma and country"},` +
+				`"latitude":{"type":"number"},"longitude":{"type":"number"},"timezone":{"type":"string"}` +
+				`},"required":["formula","dates"]}`),
+		},
+		{
+			Name:        "lookup_registry",
+			Description: "Search the master zmanim registry by key, name or alias. Returns matching zmanim with their default formulas.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`),
+		},
+	}
+}
+
+// Execute runs a tool call; errors are reported back to the model
+func (t *formulaBuilderTools) Execute(ctx context.Context, call ai.ToolCall) (string, error) {
+	switch call.Name {
+	case "validate_formula":
+		var in struct {
+			Formula string `json:"formula"`
+		}
+		if err := json.Unmarshal(call.Input, &in); err != nil {
+			return "", fmt.Errorf("invalid input: %w", err)
+		}
+		return t.validate(in.Formula), nil
+	case "preview_formula":
+		var in previewToolInput
+		if err := json.Unmarshal(call.Input, &in); err != nil {
+			return "", fmt.Errorf("invalid input: %w", err)
+		}
+		return t.preview(ctx, in)
+	case "lookup_registry":
+		var in struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(call.Input, &in); err != nil {
+			return "", fmt.Errorf("invalid input: %w", err)
+		}
+		return t.lookupRegistry(ctx, in.Query)
+	default:
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+}
+
+func (t *formulaBuilderTools) validate(formula string) string {
+	_, validationErrors, err := dsl.ValidateFormula(formula, mapKeys(t.formulas))
+	if err == nil {
+		return "ok"
+	}
+	if len(validationErrors) == 0 {
+		return "invalid: " + err.Error()
+	}
+	lines := make([]string, 0, len(validationErrors))
+	for _, ve := range validationErrors {
+		lines = append(lines, "- "+ve.Message)
+	}
+	return "invalid:\n" + strings.Join(lines, "\n")
+}
+
+type previewToolInput struct {
+	Formula   string   `json:"formula"`
+	Dates     []string `json:"dates"`
+	City      string   `json:"city,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Timezone  string   `json:"timezone,omitempty"`
+}
+
+func (t *formulaBuilderTools) preview(ctx context.Context, in previewToolInput) (string, error) {
+	if len(in.Dates) == 0 {
+		return "", fmt.Errorf("at least one date is required")
+	}
+	if len(in.Dates) > maxPreviewDates {
+		return "", fmt.Errorf("at most %d dates per preview", maxPreviewDates)
+	}
+	if _, _, err := dsl.ValidateFormula(in.Formula, mapKeys(t.formulas)); err != nil {
+		return "", fmt.Errorf("formula is invalid: %w", err)
+	}
+
+	place := "the given coordinates"
+	var lat, lng, elevation float64
+	timezone := in.Timezone
+	switch {
+	case in.City != "":
+		city, err := t.findCity(ctx, in.City)
+		if err != nil {
+			return "", err
+		}
+		place, lat, lng, elevation, timezone = city.label, city.lat, city.lng, city.elevation, city.timezone
+	case in.Latitude != nil && in.Longitude != nil:
+		lat, lng = *in.Latitude, *in.Longitude
+		if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return "", fmt.Errorf("coordinates out of range")
+		}
+		if timezone == "" {
+			return "", fmt.Errorf("timezone is required with latitude/longitude")
+		}
+	default:
+		return "", fmt.Errorf("a city or latitude/longitude is required")
+	}
+
+	tz, err := time.LoadLocation(timezone)
+	if err != nil {
+		return "", fmt.Errorf("unknown timezone %q", timezone)
+	}
+
+	var horizon astro.Horizon
+	if t.h != nil {
+		horizon = t.h.horizonForPoint(ctx, lat, lng)
+	}
+
+	formulas := referencedFormulas(in.Formula, t.formulas)
+	formulas[previewKey] = in.Formula
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)\n", place, timezone)
+	for _, d := range in.Dates {
+		date, err := time.ParseInLocation("2006-01-02", d, tz)
+		if err != nil {
+			fmt.Fprintf(&b, "%s: invalid date\n", d)
+			continue
+		}
+		execCtx := dsl.NewExecutionContext(date, lat, lng, elevation, tz)
+		execCtx.Engine = t.engine
+		execCtx.Horizon = horizon
+		results, err := dsl.ExecuteFormulaSet(formulas, execCtx)
+		if err != nil {
+			fmt.Fprintf(&b, "%s: %v\n", d, err)
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\n", d, results[previewKey].In(tz).Format("15:04:05"))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+type previewCity struct {
+	label     string
+	lat, lng  float64
+	elevation float64
+	timezone  string
+}
+
+// findCity resolves "Name" or "Name, Country" to the most populous match
+func (t *formulaBuilderTools) findCity(ctx context.Context, query string) (*previewCity, error) {
+	name, country, _ := strings.Cut(query, ",")
+	name, country = strings.TrimSpace(name), strings.TrimSpace(country)
+
+	var c previewCity
+	var cityName, countryName string
+	var elevation *int32
+	err := t.h.db.Pool.QueryRow(ctx, `
+		SELECT c.name, co.name, c.latitude, c.longitude, c.elevation_m, c.timezone
+		FROM geo_cities c
+		JOIN geo_countries co ON co.id = c.country_id
+		WHERE (c.name ILIKE $1 OR c.name_ascii ILIKE $1)
+		  AND ($2 = '' OR co.name ILIKE $2 OR co.code ILIKE $2)
+		ORDER BY c.population DESC NULLS LAST
+		LIMIT 1
+	`, name, country).Scan(&cityName, &countryName, &c.lat, &c.lng, &elevation, &c.timezone)
+	if err != nil {
+		return nil, fmt.Errorf("city %q not found", query)
+	}
+	if elevation != nil {
+		c.elevation = float64(*elevation)
+	}
+	c.label = cityName + ", " + countryName
+	return &c, nil
+}
+
+func (t *formulaBuilderTools) lookupRegistry(ctx context.Context, query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	rows, err := t.h.db.Pool.Query(ctx, `
+		SELECT zman_key, canonical_english_name, canonical_hebrew_name, COALESCE(default_formula_dsl, '')
+		FROM master_zmanim_registry
+		WHERE NOT is_hidden
+		  AND (zman_key ILIKE '%' || $1 || '%'
+		       OR canonical_english_name ILIKE '%' || $1 || '%'
+		       OR canonical_hebrew_name ILIKE '%' || $1 || '%'
+		       OR transliteration ILIKE '%' || $1 || '%'
+		       OR $1 ILIKE ANY(aliases))
+		ORDER BY is_core DESC, zman_key
+		LIMIT 8
+	`, query)
+	if err != nil {
+		return "", fmt.Errorf("registry lookup failed")
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var key, english, hebrew, formula string
+		if err := rows.Scan(&key, &english, &hebrew, &formula); err != nil {
+			return "", fmt.Errorf("registry lookup failed")
+		}
+		line := fmt.Sprintf("@%s - %s (%s)", key, english, hebrew)
+		if formula != "" {
+			line += ": " + formula
+		}
+		if _, ok := t.formulas[key]; !ok {
+			line += " [not enabled by this publisher]"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "no matching zmanim", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// referencedFormulas returns the formulas formula depends on, directly or
+// through other references, so it can be executed without the rest of the set
+func referencedFormulas(formula string, formulas map[string]string) map[string]string {
+	deps := make(map[string]string)
+	pending := []string{formula}
+	for len(pending) > 0 {
+		node, err := dsl.Parse(pending[0])
+		pending = pending[1:]
+		if err != nil {
+			continue
+		}
+		for _, ref := range dsl.ExtractReferences(node) {
+			if _, seen := deps[ref]; seen {
+				continue
+			}
+			if f, ok := formulas[ref]; ok {
+				deps[ref] = f
+				pending = append(pending, f)
+			}
+		}
+	}
+	return deps
+}
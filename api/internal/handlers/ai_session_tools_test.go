@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+)
+
+func TestReferencedFormulas(t *testing.T) {
+	formulas := map[string]string{
+		"alos_hashachar": "solar(16.1, before_sunrise)",
+		"misheyakir":     "solar(11.5, before_sunrise)",
+		"plag_hamincha":  "@mincha_ketana + 75min",
+		"mincha_ketana":  "proportional_hours(9.5, gra)",
+		"tzais":          "solar(8.5, after_sunset)",
+	}
+
+	got := referencedFormulas("midpoint(@alos_hashachar, @plag_hamincha)", formulas)
+	if len(got) != 3 {
+		t.Fatalf("got %v", got)
+	}
+	for _, key := range []string{"alos_hashachar", "plag_hamincha", "mincha_ketana"} {
+		if got[key] != formulas[key] {
+			t.Errorf("missing %s", key)
+		}
+	}
+
+	if got := referencedFormulas("sunset + 42min", formulas); len(got) != 0 {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFormulaBuilderToolsPreviewCoordinates(t *testing.T) {
+	tools := newFormulaBuilderTools(nil, "p", map[string]string{"sunset_ref": "sunset"}, nil)
+	input, _ := json.Marshal(map[string]interface{}{
+		"formula":   "@sunset_ref + 42min",
+		"dates":     []string{"2025-06-21", "not-a-date"},
+		"latitude":  31.7683,
+		"longitude": 35.2137,
+		"timezone":  "Asia/Jerusalem",
+	})
+
+	out, err := tools.Execute(context.Background(), ai.ToolCall{Name: "preview_formula", Input: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "2025-06-21: 20:") || lines[2] != "not-a-date: invalid date" {
+		t.Errorf("output = %q", out)
+	}
+
+	if got := tools.validate("sunrise -"); !strings.HasPrefix(got, "invalid") {
+		t.Errorf("validate = %q", got)
+	}
+	if _, err := tools.Execute(context.Background(), ai.ToolCall{Name: "nope", Input: input}); err == nil {
+		t.Error("expected unknown tool error")
+	}
+}
+
+func TestRespondSessionTurnErrorHidesUpstreamError(t *testing.T) {
+	upstream := errors.New("anthropic: 401 invalid x-api-key sk-ant-secret")
+	for _, status := range []int{http.StatusBadGateway, http.StatusInternalServerError} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/ai/sessions/abc/messages", nil)
+		respondSessionTurnError(w, r, status, upstream)
+
+		if w.Code != status {
+			t.Errorf("status = %d, want %d", w.Code, status)
+		}
+		if body := w.Body.String(); strings.Contains(body, "sk-ant-secret") || strings.Contains(body, "x-api-key") {
+			t.Errorf("status %d response leaks the upstream error: %s", status, body)
+		}
+	}
+}
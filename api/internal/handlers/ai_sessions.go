@@ -0,0 +1,631 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/ai"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+)
+
+// Formula session statuses
+const (
+	FormulaSessionActive  = "active"
+	FormulaSessionApplied = "applied"
+	FormulaSessionClosed  = "closed"
+)
+
+// maxSessionMessageLength bounds a single user message (characters)
+const maxSessionMessageLength = 2000
+
+// FormulaSession is a persisted multi-turn formula builder conversation
+type FormulaSession struct {
+	ID             string       `json:"id"`
+	PublisherID    string       `json:"publisher_id"`
+	ZmanKey        *string      `json:"zman_key,omitempty"`
+	Title          *string      `json:"title,omitempty"`
+	Messages       []ai.Message `json:"messages,omitempty"`
+	CurrentFormula *string      `json:"current_formula,omitempty"`
+	Status         string       `json:"status"`
+	TokensUsed     int          `json:"tokens_used"`
+	AppliedAt      *time.Time   `json:"applied_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// CreateFormulaSessionRequest starts a session, optionally with a first message
+type CreateFormulaSessionRequest struct {
+	ZmanKey *string `json:"zman_key,omitempty"` // zman being refined; its formula seeds the session
+	Message string  `json:"message,omitempty"`
+}
+
+// FormulaSessionMessageRequest is a user message in a session
+type FormulaSessionMessageRequest struct {
+	Message string `json:"message"`
+}
+
+// FormulaSessionTurnResponse is the assistant's answer to one message
+type FormulaSessionTurnResponse struct {
+	Session          FormulaSession        `json:"session"`
+	Reply            string                `json:"reply"`
+	Formula          *string               `json:"formula,omitempty"` // formula proposed this turn
+	FormulaValid     bool                  `json:"formula_valid"`
+	ValidationErrors []dsl.ValidationError `json:"validation_errors,omitempty"`
+	ToolCalls        []ai.ToolCall         `json:"tool_calls,omitempty"`
+	TokensUsed       int                   `json:"tokens_used"`
+}
+
+// ApplyFormulaSessionRequest applies the session's formula to a publisher zman
+type ApplyFormulaSessionRequest struct {
+	ZmanKey        string  `json:"zman_key,omitempty"` // defaults to the session's zman
+	EffectiveFrom  *string `json:"effective_from,omitempty"`
+	EffectiveUntil *string `json:"effective_until,omitempty"`
+}
+
+var errFormulaSessionNotFound = errors.New("formula session not found")
+
+// ListFormulaSessions lists the publisher's formula builder sessions
+// @Summary List AI formula sessions
+// @Tags AI
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Success 200 {object} APIResponse{data=[]FormulaSession} "Sessions without messages, most recent first"
+// @Router /publisher/ai/sessions [get]
+func (h *Handlers) ListFormulaSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT id, publisher_id, zman_key, title, current_formula, status,
+		       tokens_used, applied_at, created_at, updated_at
+		FROM ai_formula_sessions
+		WHERE publisher_id = $1 AND status <> 'closed'
+		ORDER BY updated_at DESC
+		LIMIT 50
+	`, pc.PublisherID)
+	if err != nil {
+		slog.Error("failed to list formula sessions", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to list sessions")
+		return
+	}
+	defer rows.Close()
+
+	sessions := []FormulaSession{}
+	for rows.Next() {
+		var s FormulaSession
+		if err := rows.Scan(&s.ID, &s.PublisherID, &s.ZmanKey, &s.Title, &s.CurrentFormula, &s.Status,
+			&s.TokensUsed, &s.AppliedAt, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			slog.Error("failed to scan formula session", "error", err)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+
+	RespondJSON(w, r, http.StatusOK, sessions)
+}
+
+// CreateFormulaSession starts a formula builder conversation
+// @Summary Start an AI formula session
+// @Description Starts a multi-turn formula builder conversation. With zman_key the zman's current
+// @Description formula is the starting point. With message the first turn runs immediately.
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param request body CreateFormulaSessionRequest true "Session options"
+// @Success 201 {object} APIResponse{data=FormulaSessionTurnResponse} "Created session (and first reply)"
+// @Failure 404 {object} APIResponse{error=APIError} "Zman not found"
+// @Failure 503 {object} APIResponse{error=APIError} "AI service not configured"
+// @Router /publisher/ai/sessions [post]
+func (h *Handlers) CreateFormulaSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req CreateFormulaSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+	if msg := validateSessionMessage(req.Message, false); msg != "" {
+		RespondValidationError(w, r, msg, map[string]string{"message": msg})
+		return
+	}
+	if h.aiClaude == nil {
+		respondAIUnavailable(w, r)
+		return
+	}
+
+	var seedFormula *string
+	if req.ZmanKey != nil && *req.ZmanKey != "" {
+		var formula string
+		err := h.db.Pool.QueryRow(ctx, `
+			SELECT formula_dsl FROM publisher_zmanim
+			WHERE publisher_id = $1 AND zman_key = $2 AND deleted_at IS NULL
+		`, pc.PublisherID, *req.ZmanKey).Scan(&formula)
+		if err == pgx.ErrNoRows {
+			RespondNotFound(w, r, "Zman not found")
+			return
+		}
+		if err != nil {
+			RespondInternalError(w, r, "Failed to load zman")
+			return
+		}
+		seedFormula = &formula
+	} else {
+		req.ZmanKey = nil
+	}
+
+	var session FormulaSession
+	err := h.db.Pool.QueryRow(ctx, `
+		INSERT INTO ai_formula_sessions (publisher_id, user_id, zman_key, current_formula)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		RETURNING id, publisher_id, zman_key, title, current_formula, status,
+		          tokens_used, applied_at, created_at, updated_at
+	`, pc.PublisherID, pc.UserID, req.ZmanKey, seedFormula).Scan(
+		&session.ID, &session.PublisherID, &session.ZmanKey, &session.Title, &session.CurrentFormula, &session.Status,
+		&session.TokensUsed, &session.AppliedAt, &session.CreatedAt, &session.UpdatedAt,
+	)
+	if err != nil {
+		slog.Error("failed to create formula session", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to create session")
+		return
+	}
+	session.Messages = []ai.Message{}
+
+	if req.Message == "" {
+		RespondJSON(w, r, http.StatusCreated, FormulaSessionTurnResponse{Session: session})
+		return
+	}
+
+	resp, status, err := h.runFormulaSessionTurn(ctx, r, pc.PublisherID, &session, req.Message)
+	if err != nil {
+		respondSessionTurnError(w, r, status, err)
+		return
+	}
+	RespondJSON(w, r, http.StatusCreated, resp)
+}
+
+// GetFormulaSession returns a session with its full conversation
+// @Summary Get an AI formula session
+// @Tags AI
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Session ID"
+// @Success 200 {object} APIResponse{data=FormulaSession} "Session"
+// @Failure 404 {object} APIResponse{error=APIError} "Session not found"
+// @Router /publisher/ai/sessions/{id} [get]
+func (h *Handlers) GetFormulaSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	session, err := h.loadFormulaSession(ctx, pc.PublisherID, chi.URLParam(r, "id"))
+	if err == errFormulaSessionNotFound {
+		RespondNotFound(w, r, "Session not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load formula session", "error", err)
+		RespondInternalError(w, r, "Failed to load session")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, session)
+}
+
+// SendFormulaSessionMessage sends the next message in a session
+// @Summary Continue an AI formula session
+// @Description Sends a message ("make it 18 degrees", "only in summer", "what would that give in Manchester
+// @Description on June 21?"). The assistant may validate, preview and look up registry zmanim before replying.
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Session ID"
+// @Param request body FormulaSessionMessageRequest true "Message"
+// @Success 200 {object} APIResponse{data=FormulaSessionTurnResponse} "Assistant reply"
+// @Failure 404 {object} APIResponse{error=APIError} "Session not found"
+// @Failure 409 {object} APIResponse{error=APIError} "Session closed"
+// @Failure 503 {object} APIResponse{error=APIError} "AI service not configured"
+// @Router /publisher/ai/sessions/{id}/messages [post]
+func (h *Handlers) SendFormulaSessionMessage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req FormulaSessionMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+	if msg := validateSessionMessage(req.Message, true); msg != "" {
+		RespondValidationError(w, r, msg, map[string]string{"message": msg})
+		return
+	}
+	if h.aiClaude == nil {
+		respondAIUnavailable(w, r)
+		return
+	}
+
+	session, err := h.loadFormulaSession(ctx, pc.PublisherID, chi.URLParam(r, "id"))
+	if err == errFormulaSessionNotFound {
+		RespondNotFound(w, r, "Session not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load formula session", "error", err)
+		RespondInternalError(w, r, "Failed to load session")
+		return
+	}
+	if session.Status == FormulaSessionClosed {
+		RespondJSON(w, r, http.StatusConflict, map[string]interface{}{
+			"error":   "Session closed",
+			"message": "Start a new session to continue",
+		})
+		return
+	}
+
+	resp, status, err := h.runFormulaSessionTurn(ctx, r, pc.PublisherID, session, req.Message)
+	if err != nil {
+		respondSessionTurnError(w, r, status, err)
+		return
+	}
+	RespondJSON(w, r, http.StatusOK, resp)
+}
+
+// ApplyFormulaSession applies the session's current formula to a publisher zman
+// @Summary Apply an AI formula session
+// @Description Saves the session's current formula to a zman, exactly like PUT /publisher/zmanim/{zmanKey}.
+// @Description With effective_from/effective_until the formula is scheduled for that range.
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Session ID"
+// @Param request body ApplyFormulaSessionRequest false "Target zman and optional effective range"
+// @Success 200 {object} APIResponse{data=PublisherZman} "Updated zman"
+// @Failure 400 {object} APIResponse{error=APIError} "No formula to apply or invalid formula"
+// @Failure 404 {object} APIResponse{error=APIError} "Session or zman not found"
+// @Router /publisher/ai/sessions/{id}/apply [post]
+func (h *Handlers) ApplyFormulaSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	startTime := time.Now()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req ApplyFormulaSessionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			RespondBadRequest(w, r, "Invalid request body")
+			return
+		}
+	}
+
+	session, err := h.loadFormulaSession(ctx, pc.PublisherID, chi.URLParam(r, "id"))
+	if err == errFormulaSessionNotFound {
+		RespondNotFound(w, r, "Session not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load formula session", "error", err)
+		RespondInternalError(w, r, "Failed to load session")
+		return
+	}
+
+	zmanKey := req.ZmanKey
+	if zmanKey == "" && session.ZmanKey != nil {
+		zmanKey = *session.ZmanKey
+	}
+	errs := make(map[string]string)
+	if zmanKey == "" {
+		errs["zman_key"] = "zman_key is required for sessions not started from a zman"
+	}
+	if session.CurrentFormula == nil || *session.CurrentFormula == "" {
+		errs["formula"] = "The session has no formula yet"
+	}
+	if req.EffectiveFrom != nil || req.EffectiveUntil != nil {
+		for field, msg := range validateEffectiveRange(req.EffectiveFrom, req.EffectiveUntil) {
+			errs[field] = msg
+		}
+	}
+	if len(errs) > 0 {
+		RespondValidationError(w, r, "Cannot apply session", errs)
+		return
+	}
+
+	formula := *session.CurrentFormula
+	available, err := h.publisherZmanFormulas(ctx, pc.PublisherID)
+	if err != nil {
+		RespondInternalError(w, r, "Failed to load zmanim")
+		return
+	}
+	if _, validationErrors, err := dsl.ValidateFormula(formula, mapKeys(available)); err != nil {
+		RespondValidationError(w, r, "The session formula is no longer valid", validationErrors)
+		return
+	}
+
+	reason := fmt.Sprintf("Applied from AI formula session %s", session.ID)
+	z, err := h.saveZmanUpdate(ctx, pc.PublisherID, pc.UserID, zmanKey, UpdateZmanRequest{
+		FormulaDSL:     &formula,
+		EffectiveFrom:  req.EffectiveFrom,
+		EffectiveUntil: req.EffectiveUntil,
+		ChangeReason:   &reason,
+	})
+	h.insertAIAudit(ctx, r, aiAuditEntry{
+		RequestType: "formula_session_apply",
+		Input:       zmanKey,
+		Output:      formula,
+		Err:         err,
+		DurationMs:  int(time.Since(startTime).Milliseconds()),
+		SessionID:   session.ID,
+		PublisherID: pc.PublisherID,
+	})
+	if err == pgx.ErrNoRows {
+		RespondNotFound(w, r, "Zman not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to apply formula session", "error", err, "session_id", session.ID)
+		RespondInternalError(w, r, "Failed to update zman")
+		return
+	}
+
+	if _, err := h.db.Pool.Exec(ctx, `
+		UPDATE ai_formula_sessions
+		SET status = 'applied', applied_at = now(), zman_key = $2, updated_at = now()
+		WHERE id = $1
+	`, session.ID, zmanKey); err != nil {
+		slog.Warn("failed to mark formula session applied", "error", err, "session_id", session.ID)
+	}
+
+	RespondJSON(w, r, http.StatusOK, z)
+}
+
+// CloseFormulaSession ends a session; its audit trail is kept
+// @Summary Close an AI formula session
+// @Tags AI
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Session ID"
+// @Success 200 {object} APIResponse{data=object} "Closed"
+// @Failure 404 {object} APIResponse{error=APIError} "Session not found"
+// @Router /publisher/ai/sessions/{id} [delete]
+func (h *Handlers) CloseFormulaSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	tag, err := h.db.Pool.Exec(ctx, `
+		UPDATE ai_formula_sessions SET status = 'closed', updated_at = now()
+		WHERE id::text = $1 AND publisher_id = $2
+	`, chi.URLParam(r, "id"), pc.PublisherID)
+	if err != nil {
+		RespondInternalError(w, r, "Failed to close session")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		RespondNotFound(w, r, "Session not found")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// runFormulaSessionTurn sends one message through the assistant, validates any
+// proposed formula against the publisher's zmanim, persists the turn and audits
+// it. On error it returns the HTTP status to respond with.
+func (h *Handlers) runFormulaSessionTurn(ctx context.Context, r *http.Request, publisherID string, session *FormulaSession, message string) (*FormulaSessionTurnResponse, int, error) {
+	startTime := time.Now()
+
+	formulas, err := h.publisherZmanFormulas(ctx, publisherID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load zmanim: %w", err)
+	}
+
+	// Session state goes first so the model always sees the formula being refined
+	var contextParts []string
+	if session.ZmanKey != nil {
+		contextParts = append(contextParts, fmt.Sprintf("The publisher is editing the zman @%s.", *session.ZmanKey))
+	}
+	if session.CurrentFormula != nil {
+		contextParts = append(contextParts, "Current formula:\n```\n"+*session.CurrentFormula+"\n```")
+	}
+	ragUsed := false
+	if h.aiContext != nil {
+		assembled, err := h.aiContext.AssembleContext(ctx, message, ai.ContextOptions{
+			MaxTokens:       1500,
+			MaxDocs:         3,
+			IncludeExamples: true,
+			IncludeHalachic: true,
+		})
+		if err == nil && assembled != nil && assembled.Context != "" {
+			contextParts = append(contextParts, assembled.Context)
+			ragUsed = len(assembled.Sources) > 0
+		}
+	}
+
+	tools := newFormulaBuilderTools(h, publisherID, formulas, h.publisherSolarEngine(ctx, publisherID))
+	turn, err := h.aiClaude.Converse(ctx, session.Messages, message, strings.Join(contextParts, "\n\n"), tools.Specs(), tools.Execute)
+
+	audit := aiAuditEntry{
+		RequestType: "formula_session",
+		Input:       message,
+		Err:         err,
+		DurationMs:  int(time.Since(startTime).Milliseconds()),
+		RAGUsed:     ragUsed,
+		SessionID:   session.ID,
+		PublisherID: publisherID,
+	}
+	if turn != nil {
+		audit.Output = turn.Reply
+		audit.TokensUsed = turn.TokensUsed
+	}
+	if err != nil {
+		h.insertAIAudit(ctx, r, audit)
+		return nil, http.StatusBadGateway, err
+	}
+
+	resp := &FormulaSessionTurnResponse{
+		Reply:      turn.Reply,
+		ToolCalls:  turn.ToolCalls,
+		TokensUsed: turn.TokensUsed,
+	}
+	var newFormula *string
+	if turn.Formula != "" && !strings.HasPrefix(turn.Formula, "UNSUPPORTED:") {
+		formula := turn.Formula
+		resp.Formula = &formula
+		_, resp.ValidationErrors, err = dsl.ValidateFormula(formula, mapKeys(formulas))
+		resp.FormulaValid = err == nil
+		if resp.FormulaValid {
+			newFormula = &formula
+			audit.Output = formula
+		}
+	}
+	h.insertAIAudit(ctx, r, audit)
+
+	messagesJSON, err := json.Marshal(turn.Messages)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to encode messages: %w", err)
+	}
+	title := message
+	if utf8.RuneCountInString(title) > 80 {
+		title = string([]rune(title)[:80]) + "…"
+	}
+	err = h.db.Pool.QueryRow(ctx, `
+		UPDATE ai_formula_sessions
+		SET messages = messages || $2::jsonb,
+		    current_formula = COALESCE($3, current_formula),
+		    tokens_used = tokens_used + $4,
+		    title = COALESCE(title, $5),
+		    status = CASE WHEN $3::text IS NOT NULL THEN 'active' ELSE status END,
+		    updated_at = now()
+		WHERE id = $1
+		RETURNING title, current_formula, status, tokens_used, updated_at
+	`, session.ID, messagesJSON, newFormula, turn.TokensUsed, title).Scan(
+		&session.Title, &session.CurrentFormula, &session.Status, &session.TokensUsed, &session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	session.Messages = append(session.Messages, turn.Messages...)
+	resp.Session = *session
+	return resp, http.StatusOK, nil
+}
+
+// loadFormulaSession loads a publisher's session with its messages
+func (h *Handlers) loadFormulaSession(ctx context.Context, publisherID, id string) (*FormulaSession, error) {
+	var s FormulaSession
+	var messagesJSON []byte
+	err := h.db.Pool.QueryRow(ctx, `
+		SELECT id, publisher_id, zman_key, title, messages, current_formula, status,
+		       tokens_used, applied_at, created_at, updated_at
+		FROM ai_formula_sessions
+		WHERE id::text = $1 AND publisher_id = $2
+	`, id, publisherID).Scan(
+		&s.ID, &s.PublisherID, &s.ZmanKey, &s.Title, &messagesJSON, &s.CurrentFormula, &s.Status,
+		&s.TokensUsed, &s.AppliedAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, errFormulaSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load formula session: %w", err)
+	}
+	if err := json.Unmarshal(messagesJSON, &s.Messages); err != nil {
+		return nil, fmt.Errorf("failed to decode session messages: %w", err)
+	}
+	return &s, nil
+}
+
+// publisherZmanFormulas returns the publisher's active zman formulas by key
+func (h *Handlers) publisherZmanFormulas(ctx context.Context, publisherID string) (map[string]string, error) {
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT zman_key, formula_dsl FROM publisher_zmanim
+		WHERE publisher_id = $1 AND deleted_at IS NULL
+	`, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	formulas := make(map[string]string)
+	for rows.Next() {
+		var key, formula string
+		if err := rows.Scan(&key, &formula); err != nil {
+			return nil, err
+		}
+		formulas[key] = formula
+	}
+	return formulas, rows.Err()
+}
+
+func validateSessionMessage(message string, required bool) string {
+	if strings.TrimSpace(message) == "" {
+		if required {
+			return "Message is required"
+		}
+		return ""
+	}
+	if utf8.RuneCountInString(message) > maxSessionMessageLength {
+		return fmt.Sprintf("Message exceeds %d character limit", maxSessionMessageLength)
+	}
+	return ""
+}
+
+func respondAIUnavailable(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, r, http.StatusServiceUnavailable, map[string]interface{}{
+		"error":   "AI service not configured",
+		"message": "The formula assistant is not available",
+	})
+}
+
+// respondSessionTurnError logs a failed turn and responds without the
+// underlying error, which may carry provider responses or request details
+func respondSessionTurnError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	slog.Error("formula session turn failed", "error", err, "status", status)
+	if status == http.StatusBadGateway {
+		RespondJSON(w, r, status, map[string]interface{}{
+			"error":   "AI request failed",
+			"message": "The AI provider could not complete the request. Please try again.",
+		})
+		return
+	}
+	RespondInternalError(w, r, "Failed to process message")
+}
+
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
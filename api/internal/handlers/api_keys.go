@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/middleware"
+	"github.com/jcom-dev/zmanim-lab/internal/services"
+)
+
+// CreateAPIKeyRequest is the request body for issuing an API key
+type CreateAPIKeyRequest struct {
+	// Human-readable label, e.g. "Lobby display board"
+	Name string `json:"name" example:"Lobby display board"`
+	// Scopes granted to the key (zmanim:read, calendar:read)
+	Scopes []string `json:"scopes" example:"zmanim:read"`
+	// Optional expiry (RFC3339)
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Publisher the key belongs to (admin only; omit for a platform key)
+	PublisherID *string `json:"publisher_id,omitempty"`
+}
+
+// RotateAPIKeyRequest is the request body for rotating an API key
+type RotateAPIKeyRequest struct {
+	// Hours the old key keeps working (default 24, max 720; 0 revokes immediately)
+	GracePeriodHours *int `json:"grace_period_hours,omitempty" example:"24"`
+}
+
+// SetAPIKeyService configures the API key service
+func (h *Handlers) SetAPIKeyService(s *services.APIKeyService) {
+	h.apiKeyService = s
+}
+
+// ListPublisherAPIKeys returns the publisher's API keys
+// @Summary List API keys
+// @Description Returns the publisher's API keys (secrets are never returned)
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Success 200 {object} APIResponse{data=[]services.APIKeyInfo} "API keys"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/api-keys [get]
+func (h *Handlers) ListPublisherAPIKeys(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), &pc.PublisherID)
+	if err != nil {
+		slog.Error("failed to list api keys", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to list API keys")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, keys)
+}
+
+// CreatePublisherAPIKey issues a new API key for the publisher
+// @Summary Create API key
+// @Description Issues a new API key. The plaintext key is returned only in this response.
+// @Tags Publishers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param request body CreateAPIKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} APIResponse{data=services.CreatedAPIKey} "Created key including plaintext secret"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/api-keys [post]
+func (h *Handlers) CreatePublisherAPIKey(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	params, ok := parseCreateAPIKeyRequest(w, r)
+	if !ok {
+		return
+	}
+	params.PublisherID = &pc.PublisherID
+	params.IssuedBy = "publisher"
+	params.CreatedBy = pc.UserID
+
+	h.createAPIKey(w, r, params)
+}
+
+// RotatePublisherAPIKey replaces a key with a new secret
+// @Summary Rotate API key
+// @Description Issues a replacement key with the same name and scopes; the old key keeps working for the grace period
+// @Tags Publishers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "API key ID"
+// @Param request body RotateAPIKeyRequest false "Grace period"
+// @Success 201 {object} APIResponse{data=services.CreatedAPIKey} "Replacement key including plaintext secret"
+// @Failure 400 {object} APIResponse{error=APIError} "Key is revoked or expired"
+// @Failure 404 {object} APIResponse{error=APIError} "Key not found"
+// @Router /publisher/api-keys/{id}/rotate [post]
+func (h *Handlers) RotatePublisherAPIKey(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req RotateAPIKeyRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			RespondBadRequest(w, r, "Invalid request body")
+			return
+		}
+	}
+	grace := 24
+	if req.GracePeriodHours != nil {
+		if *req.GracePeriodHours < 0 || *req.GracePeriodHours > 720 {
+			RespondValidationError(w, r, "grace_period_hours must be between 0 and 720", nil)
+			return
+		}
+		grace = *req.GracePeriodHours
+	}
+
+	created, err := h.apiKeyService.Rotate(r.Context(), chi.URLParam(r, "id"), &pc.PublisherID, time.Duration(grace)*time.Hour, pc.UserID)
+	if err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to rotate API key")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusCreated, created)
+}
+
+// RevokePublisherAPIKey revokes one of the publisher's keys
+// @Summary Revoke API key
+// @Description Revokes an API key immediately
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "API key ID"
+// @Success 200 {object} APIResponse{data=object} "Revocation confirmation"
+// @Failure 404 {object} APIResponse{error=APIError} "Key not found"
+// @Router /publisher/api-keys/{id} [delete]
+func (h *Handlers) RevokePublisherAPIKey(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	h.revokeAPIKey(w, r, &pc.PublisherID)
+}
+
+// GetPublisherAPIKeyUsage returns daily request counts for a key
+// @Summary API key usage
+// @Description Returns daily request counts for one of the publisher's keys
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "API key ID"
+// @Param days query int false "Number of days (default 30, max 365)"
+// @Success 200 {object} APIResponse{data=services.APIKeyUsage} "Usage statistics"
+// @Failure 404 {object} APIResponse{error=APIError} "Key not found"
+// @Router /publisher/api-keys/{id}/usage [get]
+func (h *Handlers) GetPublisherAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	h.apiKeyUsage(w, r, &pc.PublisherID)
+}
+
+// AdminListAPIKeys returns all API keys, optionally filtered by publisher
+// @Summary List all API keys (admin)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param publisher_id query string false "Filter by publisher"
+// @Success 200 {object} APIResponse{data=[]services.APIKeyInfo} "API keys"
+// @Router /admin/api-keys [get]
+func (h *Handlers) AdminListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var publisherID *string
+	if id := r.URL.Query().Get("publisher_id"); id != "" {
+		publisherID = &id
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), publisherID)
+	if err != nil {
+		slog.Error("failed to list api keys", "error", err)
+		RespondInternalError(w, r, "Failed to list API keys")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, keys)
+}
+
+// AdminCreateAPIKey issues a platform key or a key on behalf of a publisher
+// @Summary Create API key (admin)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Key name, scopes, optional expiry and publisher"
+// @Success 201 {object} APIResponse{data=services.CreatedAPIKey} "Created key including plaintext secret"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid request"
+// @Router /admin/api-keys [post]
+func (h *Handlers) AdminCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseCreateAPIKeyRequest(w, r)
+	if !ok {
+		return
+	}
+	params.IssuedBy = "admin"
+	params.CreatedBy = middleware.GetUserID(r.Context())
+
+	h.createAPIKey(w, r, params)
+}
+
+// AdminRevokeAPIKey revokes any API key
+// @Summary Revoke API key (admin)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} APIResponse{data=object} "Revocation confirmation"
+// @Failure 404 {object} APIResponse{error=APIError} "Key not found"
+// @Router /admin/api-keys/{id} [delete]
+func (h *Handlers) AdminRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	h.revokeAPIKey(w, r, nil)
+}
+
+// AdminGetAPIKeyUsage returns daily request counts for any key
+// @Summary API key usage (admin)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Param days query int false "Number of days (default 30, max 365)"
+// @Success 200 {object} APIResponse{data=services.APIKeyUsage} "Usage statistics"
+// @Router /admin/api-keys/{id}/usage [get]
+func (h *Handlers) AdminGetAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	h.apiKeyUsage(w, r, nil)
+}
+
+// parseCreateAPIKeyRequest decodes and validates a create request
+func parseCreateAPIKeyRequest(w http.ResponseWriter, r *http.Request) (services.CreateAPIKeyParams, bool) {
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return services.CreateAPIKeyParams{}, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		RespondValidationError(w, r, "name is required (max 100 characters)", nil)
+		return services.CreateAPIKeyParams{}, false
+	}
+
+	scopes, err := services.ValidateScopes(req.Scopes)
+	if err != nil {
+		RespondValidationError(w, r, err.Error(), map[string]interface{}{"valid_scopes": middleware.ValidAPIKeyScopes})
+		return services.CreateAPIKeyParams{}, false
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		RespondValidationError(w, r, "expires_at must be in the future", nil)
+		return services.CreateAPIKeyParams{}, false
+	}
+
+	return services.CreateAPIKeyParams{
+		PublisherID: req.PublisherID,
+		Name:        req.Name,
+		Scopes:      scopes,
+		ExpiresAt:   req.ExpiresAt,
+	}, true
+}
+
+// createAPIKey issues a key and responds with the plaintext secret
+func (h *Handlers) createAPIKey(w http.ResponseWriter, r *http.Request, params services.CreateAPIKeyParams) {
+	created, err := h.apiKeyService.Create(r.Context(), params)
+	if err != nil {
+		slog.Error("failed to create api key", "error", err, "publisher_id", params.PublisherID)
+		RespondInternalError(w, r, "Failed to create API key")
+		return
+	}
+
+	slog.Info("api key created", "id", created.ID, "publisher_id", params.PublisherID, "issued_by", params.IssuedBy)
+	RespondJSON(w, r, http.StatusCreated, created)
+}
+
+// revokeAPIKey revokes the key in the URL, scoped to publisherID unless nil
+func (h *Handlers) revokeAPIKey(w http.ResponseWriter, r *http.Request, publisherID *string) {
+	id := chi.URLParam(r, "id")
+	if err := h.apiKeyService.Revoke(r.Context(), id, publisherID); err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to revoke API key")
+		return
+	}
+
+	slog.Info("api key revoked", "id", id, "user_id", middleware.GetUserID(r.Context()))
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"revoked": true,
+	})
+}
+
+// apiKeyUsage responds with usage for the key in the URL, scoped to publisherID unless nil
+func (h *Handlers) apiKeyUsage(w http.ResponseWriter, r *http.Request, publisherID *string) {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := parseIntParam(d); err == nil && parsed > 0 && parsed <= 365 {
+			days = parsed
+		}
+	}
+
+	ctx := r.Context()
+	// Include requests not yet flushed from memory
+	if err := h.apiKeyService.FlushUsage(ctx); err != nil {
+		slog.Warn("failed to flush api key usage", "error", err)
+	}
+
+	usage, err := h.apiKeyService.Usage(ctx, chi.URLParam(r, "id"), publisherID, days)
+	if err != nil {
+		h.respondAPIKeyError(w, r, err, "Failed to get API key usage")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, usage)
+}
+
+// respondAPIKeyError maps service errors to responses
+func (h *Handlers) respondAPIKeyError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		RespondNotFound(w, r, "API key not found")
+	case errors.Is(err, services.ErrAPIKeyInvalid):
+		RespondBadRequest(w, r, "API key is revoked or expired")
+	default:
+		slog.Error(message, "error", err)
+		RespondInternalError(w, r, message)
+	}
+}
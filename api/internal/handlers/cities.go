@@ -14,7 +14,7 @@ import (
 
 // SearchCities handles city search with autocomplete and filtering
 // @Summary Search cities
-// @Description Search for cities by name with optional filtering by country, region, or continent. Uses fuzzy matching for typo tolerance.
+// @Description Search for cities by name with optional filtering by country, region, or continent. Names match in any stored language, by transliteration ("Bnei Brak", "Bene Beraq") and with typo tolerance. A trailing region or country ("Paris, TX", "Monsey NY") narrows the search. Names are returned in the lang parameter or Accept-Language language where known.
 // @Tags Cities
 // @Produce json
 // @Param search query string false "Search query (min 2 chars for fuzzy match)"
@@ -23,6 +23,8 @@ import (
 // @Param continent_code query string false "Continent code (AF, AS, EU, NA, OC, SA, AN)"
 // @Param limit query int false "Max results (default 20, max 100)"
 // @Param offset query int false "Offset for pagination (default 0)"
+// @Param publisher_id query string false "Also search this publisher's locations: published ones, or all of them for the publisher itself"
+// @Param lang query string false "Language for returned names (ISO 639-1 or 639-3); defaults to Accept-Language"
 // @Success 200 {object} APIResponse{data=models.CitySearchResponse} "List of matching cities"
 // @Failure 400 {object} APIResponse{error=APIError} "Invalid parameters"
 // @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
@@ -52,30 +54,61 @@ func (h *Handlers) SearchCities(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// If search is provided and long enough, use fuzzy search
+	// Publisher locations matching the search are listed alongside cities
+	var locations []models.PublisherLocation
+	if publisherID := strings.TrimSpace(r.URL.Query().Get("publisher_id")); publisherID != "" && len(search) >= 2 && offset == 0 {
+		var err error
+		// Unpublished locations are listed only to the publisher itself
+		owner := locationOwner(r, publisherID) == publisherID
+		locations, err = h.searchPublisherLocations(ctx, publisherID, search, limit, owner)
+		if err != nil {
+			slog.Error("failed to search publisher locations", "error", err, "publisher_id", publisherID)
+			RespondInternalError(w, r, "Failed to search cities")
+			return
+		}
+	}
+
+	locale := searchLocale(r)
+
+	// If search is provided and long enough, search every name of every city
 	if search != "" && len(search) >= 2 {
-		rows, err := h.db.Queries.SearchCitiesFuzzy(ctx, sqlcgen.SearchCitiesFuzzyParams{
-			ContinentCode: nullableString(continentCode),
-			CountryCode:   nullableString(countryCode),
-			RegionCode:    nullableString(regionCode),
-			Search:        nullableString(search),
-			Limit:         limit,
-		})
+		filters := citySearchFilters{continentCode: continentCode, countryCode: countryCode, regionCode: regionCode}
+		name, readings := parseCityQuery(search)
+
+		whole, err := h.searchCitiesMultilingual(ctx, name, filters, nil, limit)
 		if err != nil {
 			slog.Error("failed to search cities", "error", err, "search", search)
 			RespondInternalError(w, r, "Failed to search cities")
 			return
 		}
 
-		cities := make([]models.City, 0, len(rows))
-		for _, row := range rows {
-			city := searchCitiesFuzzyRowToCity(row)
-			cities = append(cities, city)
+		// Try reading the end of the search as a region or country
+		var qualified []citySearchMatch
+		var query *models.CitySearchQuery
+		reading, places, err := h.resolveCityQuery(ctx, readings, strings.Contains(search, ","))
+		if err == nil && reading != nil {
+			qualified, err = h.searchCitiesMultilingual(ctx, reading.name, filters, places, limit)
+			if len(qualified) > 0 {
+				query = &models.CitySearchQuery{Name: reading.name, Qualifiers: reading.qualifiers}
+			}
+		}
+		if err != nil {
+			slog.Error("failed to search qualified cities", "error", err, "search", search)
+			RespondInternalError(w, r, "Failed to search cities")
+			return
+		}
+
+		cities := mergeCitySearches(whole, qualified, int(limit))
+		if err := h.localizeCities(ctx, cities, locale); err != nil {
+			slog.Error("failed to localize cities", "error", err, "locale", locale)
 		}
 
 		RespondJSON(w, r, http.StatusOK, models.CitySearchResponse{
-			Cities: cities,
-			Total:  len(cities),
+			Cities:    cities,
+			Total:     len(cities),
+			Locale:    locale,
+			Query:     query,
+			Locations: locations,
 		})
 		return
 	}
@@ -100,10 +133,15 @@ func (h *Handlers) SearchCities(w http.ResponseWriter, r *http.Request) {
 		city := searchCitiesRowToCity(row)
 		cities = append(cities, city)
 	}
+	if err := h.localizeCities(ctx, cities, locale); err != nil {
+		slog.Error("failed to localize cities", "error", err, "locale", locale)
+	}
 
 	RespondJSON(w, r, http.StatusOK, models.CitySearchResponse{
-		Cities: cities,
-		Total:  len(cities),
+		Cities:    cities,
+		Total:     len(cities),
+		Locale:    locale,
+		Locations: locations,
 	})
 }
 
@@ -121,27 +159,9 @@ func (h *Handlers) SearchCities(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) GetNearbyCity(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Parse latitude
-	latStr := r.URL.Query().Get("lat")
-	if latStr == "" {
-		RespondBadRequest(w, r, "Latitude is required")
-		return
-	}
-	lat, err := strconv.ParseFloat(latStr, 64)
-	if err != nil || lat < -90 || lat > 90 {
-		RespondBadRequest(w, r, "Invalid latitude")
-		return
-	}
-
-	// Parse longitude
-	lngStr := r.URL.Query().Get("lng")
-	if lngStr == "" {
-		RespondBadRequest(w, r, "Longitude is required")
-		return
-	}
-	lng, err := strconv.ParseFloat(lngStr, 64)
-	if err != nil || lng < -180 || lng > 180 {
-		RespondBadRequest(w, r, "Invalid longitude")
+	lat, lng, err := parseCoordinates(r)
+	if err != nil {
+		RespondBadRequest(w, r, err.Error())
 		return
 	}
 
@@ -384,30 +404,6 @@ func searchCitiesRowToCity(row sqlcgen.SearchCitiesRow) models.City {
 	return city
 }
 
-func searchCitiesFuzzyRowToCity(row sqlcgen.SearchCitiesFuzzyRow) models.City {
-	city := models.City{
-		ID:          row.ID,
-		Name:        row.Name,
-		Country:     row.Country,
-		CountryCode: row.CountryCode,
-		Region:      &row.Region,
-		Latitude:    row.Latitude,
-		Longitude:   row.Longitude,
-		Timezone:    row.Timezone,
-		Continent:   &row.Continent,
-	}
-	if row.Population != nil {
-		pop := int(*row.Population)
-		city.Population = &pop
-	}
-	if row.ElevationM != nil {
-		elev := int(*row.ElevationM)
-		city.Elevation = &elev
-	}
-	city.DisplayName = buildDisplayName(city)
-	return city
-}
-
 func getCityByIDRowToCity(row sqlcgen.GetCityByIDRow) models.City {
 	city := models.City{
 		ID:          row.ID,
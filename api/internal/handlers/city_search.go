@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+// searchLanguages maps ISO 639-1 codes (and the legacy iw and ji) to the
+// ISO 639-3 codes geo_names is keyed by
+var searchLanguages = map[string]string{
+	"en": "eng", "he": "heb", "iw": "heb", "ar": "ara", "yi": "yid", "ji": "yid",
+	"ru": "rus", "fr": "fra", "de": "deu", "es": "spa", "pt": "por", "zh": "zho",
+	"ja": "jpn", "ko": "kor", "it": "ita", "nl": "nld", "pl": "pol", "hu": "hun",
+	"uk": "ukr", "tr": "tur", "fa": "fas", "hi": "hin",
+}
+
+// searchLocale returns the ISO 639-3 code names should be returned in, from
+// the lang parameter or else the Accept-Language header, or "" when neither
+// names a supported language
+func searchLocale(r *http.Request) string {
+	if lang := strings.TrimSpace(r.URL.Query().Get("lang")); lang != "" {
+		return languageCode(lang)
+	}
+
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		t := tag{lang: strings.TrimSpace(fields[0]), q: 1}
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.lang != "" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	for _, t := range tags {
+		if code := languageCode(t.lang); code != "" {
+			return code
+		}
+	}
+	return ""
+}
+
+// languageCode resolves a language tag such as "he", "he-IL" or "heb" to a
+// supported ISO 639-3 code, or ""
+func languageCode(tag string) string {
+	primary := strings.ToLower(strings.SplitN(strings.ReplaceAll(tag, "_", "-"), "-", 2)[0])
+	if code, ok := searchLanguages[primary]; ok {
+		return code
+	}
+	for _, code := range searchLanguages {
+		if code == primary {
+			return code
+		}
+	}
+	return ""
+}
+
+// cityQuery is one reading of a search: a city name narrowed by region or
+// country qualifiers
+type cityQuery struct {
+	name       string
+	qualifiers []string
+}
+
+// parseCityQuery splits a search into the name to search for when it is read
+// without qualifiers, and the qualified readings to try in order. Commas are
+// explicit ("Paris, TX"); without them the last one to three words are tried
+// as a qualifier, longest first ("Monsey New York", "Monsey NY").
+func parseCityQuery(search string) (string, []cityQuery) {
+	search = strings.Join(strings.Fields(search), " ")
+
+	if strings.Contains(search, ",") {
+		var parts []string
+		for _, p := range strings.Split(search, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		if len(parts) < 2 {
+			return strings.Join(parts, " "), nil
+		}
+		if len(parts) > 4 {
+			parts = parts[:4]
+		}
+		return parts[0], []cityQuery{{name: parts[0], qualifiers: parts[1:]}}
+	}
+
+	words := strings.Fields(search)
+	var readings []cityQuery
+	for k := 3; k >= 1; k-- {
+		if len(words)-k < 1 {
+			continue
+		}
+		name := strings.Join(words[:len(words)-k], " ")
+		if len(name) < 2 {
+			continue
+		}
+		readings = append(readings, cityQuery{name: name, qualifiers: []string{strings.Join(words[len(words)-k:], " ")}})
+	}
+	return search, readings
+}
+
+// placeFilter is the set of countries, regions and districts a qualifier
+// names; a city matches if it is in any of them
+type placeFilter struct {
+	countryIDs  []int16
+	regionIDs   []int32
+	districtIDs []int32
+}
+
+func (f placeFilter) empty() bool {
+	return len(f.countryIDs) == 0 && len(f.regionIDs) == 0 && len(f.districtIDs) == 0
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// resolvePlaceQualifier finds the places a qualifier names, by code, name in
+// any language, or abbreviation
+func (h *Handlers) resolvePlaceQualifier(ctx context.Context, qualifier string) (placeFilter, error) {
+	var f placeFilter
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT 'country', co.id::integer FROM geo_countries co
+		WHERE upper(co.code) = upper($1) OR lower(co.name) = lower($1)
+		   OR co.id::text IN (SELECT entity_id FROM geo_names WHERE entity_type = 'country' AND name ILIKE $2)
+		   OR co.code IN (SELECT country_code FROM geo_name_variants WHERE entity_type = 'country' AND lower(variant) = lower($1))
+		UNION ALL
+		SELECT 'region', r.id FROM geo_regions r
+		JOIN geo_countries co ON co.id = r.country_id
+		WHERE lower(r.name) = lower($1)
+		   OR r.id::text IN (SELECT entity_id FROM geo_names WHERE entity_type = 'region' AND name ILIKE $2)
+		   OR EXISTS (
+			SELECT 1 FROM geo_name_variants v
+			WHERE v.entity_type = 'region' AND v.country_code = co.code
+			  AND lower(v.canonical_name) = lower(r.name) AND lower(v.variant) = lower($1)
+		   )
+		UNION ALL
+		SELECT 'district', d.id FROM geo_districts d
+		WHERE lower(d.name) = lower($1)
+		   OR d.id::text IN (SELECT entity_id FROM geo_names WHERE entity_type = 'district' AND name ILIKE $2)
+	`, qualifier, escapeLike(qualifier))
+	if err != nil {
+		return f, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var level string
+		var id int32
+		if err := rows.Scan(&level, &id); err != nil {
+			return f, err
+		}
+		switch level {
+		case "country":
+			f.countryIDs = append(f.countryIDs, int16(id))
+		case "region":
+			f.regionIDs = append(f.regionIDs, id)
+		case "district":
+			f.districtIDs = append(f.districtIDs, id)
+		}
+	}
+	return f, rows.Err()
+}
+
+// resolveCityQuery returns the first qualified reading whose qualifiers name
+// places, with their filters. With explicit commas, qualifiers that name
+// nothing are dropped rather than failing the reading.
+func (h *Handlers) resolveCityQuery(ctx context.Context, readings []cityQuery, explicit bool) (*cityQuery, []placeFilter, error) {
+	for _, reading := range readings {
+		var resolved []string
+		var filters []placeFilter
+		for _, q := range reading.qualifiers {
+			f, err := h.resolvePlaceQualifier(ctx, q)
+			if err != nil {
+				return nil, nil, err
+			}
+			if f.empty() {
+				if explicit {
+					continue
+				}
+				filters = nil
+				break
+			}
+			resolved = append(resolved, q)
+			filters = append(filters, f)
+		}
+		if len(filters) > 0 {
+			return &cityQuery{name: reading.name, qualifiers: resolved}, filters, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// citySearchFilters are the explicit filters of a city search
+type citySearchFilters struct {
+	continentCode string
+	countryCode   string
+	regionCode    string
+}
+
+// citySearchMatch is a search result with its match tier: 0 exact, 1 prefix,
+// 2 spelling-insensitive exact, 3 spelling-insensitive prefix, 4 fuzzy
+type citySearchMatch struct {
+	city models.City
+	tier int
+}
+
+// searchCitiesMultilingual matches a name against every stored name of a
+// city: its name and ASCII name, its names in every language, and curated
+// transliterations and historic names. Each city is ranked by its best
+// matching name.
+func (h *Handlers) searchCitiesMultilingual(ctx context.Context, name string, filters citySearchFilters, places []placeFilter, limit int32) ([]citySearchMatch, error) {
+	args := []interface{}{name, escapeLike(name), filters.continentCode, filters.countryCode, filters.regionCode, limit}
+	var placeClauses strings.Builder
+	for _, f := range places {
+		n := len(args)
+		fmt.Fprintf(&placeClauses, "\n\t\t  AND (c.country_id = ANY($%d::smallint[]) OR c.region_id = ANY($%d::integer[]) OR c.district_id = ANY($%d::integer[]))", n+1, n+2, n+3)
+		args = append(args, f.countryIDs, f.regionIDs, f.districtIDs)
+	}
+
+	// A name matches by case-insensitive prefix, trigram similarity, or
+	// prefix of its spelling-insensitive search key
+	const matches = `(%[1]s ILIKE $2 || '%%' OR %[1]s %% $1
+		OR (length(geo_search_key($1)) >= 2 AND geo_search_key(%[1]s) LIKE geo_search_key($1) || '%%'))`
+	match := func(column string) string { return fmt.Sprintf(matches, column) }
+
+	rows, err := h.db.Pool.Query(ctx, `
+		WITH candidates AS (
+			SELECT c.id AS city_id, c.name AS matched, NULL::text AS language, 'name' AS match_type
+			FROM geo_cities c
+			WHERE `+match("c.name")+`
+			UNION ALL
+			SELECT c.id, c.name_ascii, NULL, 'ascii'
+			FROM geo_cities c
+			WHERE `+match("c.name_ascii")+`
+			UNION ALL
+			SELECT c.id, n.name, n.language_code::text, 'translation'
+			FROM geo_names n
+			JOIN geo_cities c ON c.id::text = n.entity_id
+			WHERE n.entity_type = 'city' AND `+match("n.name")+`
+			UNION ALL
+			SELECT c.id, v.variant, NULL, v.kind
+			FROM geo_name_variants v
+			JOIN geo_countries co ON co.code = v.country_code
+			JOIN geo_cities c ON c.country_id = co.id
+			 AND (lower(c.name) = lower(v.canonical_name) OR lower(c.name_ascii) = lower(v.canonical_name))
+			WHERE v.entity_type = 'city' AND `+match("v.variant")+`
+		), ranked AS (
+			SELECT DISTINCT ON (city_id) city_id, matched, language, match_type,
+			       CASE WHEN lower(matched) = lower($1) THEN 0
+			            WHEN matched ILIKE $2 || '%' THEN 1
+			            WHEN geo_search_key(matched) = geo_search_key($1) THEN 2
+			            WHEN geo_search_key(matched) LIKE geo_search_key($1) || '%' THEN 3
+			            ELSE 4 END AS tier,
+			       similarity(matched, $1) AS score
+			FROM candidates
+			ORDER BY city_id, tier, score DESC, match_type = 'name' DESC
+		)
+		SELECT c.id::text, c.name, co.code, co.name, r.name, ct.code,
+		       c.latitude, c.longitude, c.timezone, c.population, c.elevation_m,
+		       m.matched, m.language, m.match_type, m.tier
+		FROM ranked m
+		JOIN geo_cities c ON c.id = m.city_id
+		JOIN geo_countries co ON co.id = c.country_id
+		JOIN geo_continents ct ON ct.id = co.continent_id
+		LEFT JOIN geo_regions r ON r.id = c.region_id
+		WHERE ($3 = '' OR ct.code = $3)
+		  AND ($4 = '' OR co.code = $4)
+		  AND ($5 = '' OR r.code = $5)`+placeClauses.String()+`
+		ORDER BY m.tier, m.score DESC, c.population DESC NULLS LAST, c.name
+		LIMIT $6
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []citySearchMatch
+	for rows.Next() {
+		var m citySearchMatch
+		var continent string
+		var population, elevation *int32
+		var matched, matchType string
+		if err := rows.Scan(&m.city.ID, &m.city.Name, &m.city.CountryCode, &m.city.Country, &m.city.Region, &continent,
+			&m.city.Latitude, &m.city.Longitude, &m.city.Timezone, &population, &elevation,
+			&matched, &m.city.MatchedLanguage, &matchType, &m.tier); err != nil {
+			return nil, err
+		}
+		m.city.Continent = &continent
+		if population != nil {
+			pop := int(*population)
+			m.city.Population = &pop
+		}
+		if elevation != nil {
+			elev := int(*elevation)
+			m.city.Elevation = &elev
+		}
+		m.city.MatchedName, m.city.MatchType = &matched, &matchType
+		m.city.DisplayName = buildDisplayName(m.city)
+		results = append(results, m)
+	}
+	return results, rows.Err()
+}
+
+// mergeCitySearches combines the unqualified and qualified results of a
+// search: exact matches of the whole text first (a city really named "New
+// York"), then the qualified results, then the rest
+func mergeCitySearches(whole, qualified []citySearchMatch, limit int) []models.City {
+	cities := make([]models.City, 0, limit)
+	seen := make(map[string]bool)
+	add := func(matches []citySearchMatch, keep func(citySearchMatch) bool) {
+		for _, m := range matches {
+			if len(cities) < limit && !seen[m.city.ID] && keep(m) {
+				seen[m.city.ID] = true
+				cities = append(cities, m.city)
+			}
+		}
+	}
+	add(whole, func(m citySearchMatch) bool { return m.tier == 0 })
+	add(qualified, func(citySearchMatch) bool { return true })
+	add(whole, func(citySearchMatch) bool { return true })
+	return cities
+}
+
+// localizeCities replaces city, region and country names with their names in
+// the given language where geo_names has one
+func (h *Handlers) localizeCities(ctx context.Context, cities []models.City, locale string) error {
+	if locale == "" || len(cities) == 0 {
+		return nil
+	}
+	ids := make([]string, len(cities))
+	for i, c := range cities {
+		ids[i] = c.ID
+	}
+
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT c.id::text, cn.name, rn.name, con.name
+		FROM geo_cities c
+		LEFT JOIN geo_names cn ON cn.entity_type = 'city' AND cn.entity_id = c.id::text AND cn.language_code = $2
+		LEFT JOIN geo_names rn ON rn.entity_type = 'region' AND rn.entity_id = c.region_id::text AND rn.language_code = $2
+		LEFT JOIN geo_names con ON con.entity_type = 'country' AND con.entity_id = c.country_id::text AND con.language_code = $2
+		WHERE c.id = ANY($1::uuid[])
+	`, ids, locale)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type names struct{ city, region, country *string }
+	localized := make(map[string]names, len(cities))
+	for rows.Next() {
+		var id string
+		var n names
+		if err := rows.Scan(&id, &n.city, &n.region, &n.country); err != nil {
+			return err
+		}
+		localized[id] = n
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range cities {
+		n, ok := localized[cities[i].ID]
+		if !ok {
+			continue
+		}
+		if n.city != nil {
+			cities[i].Name = *n.city
+		}
+		if n.region != nil {
+			cities[i].Region = n.region
+		}
+		if n.country != nil {
+			cities[i].Country = *n.country
+		}
+		cities[i].DisplayName = buildDisplayName(cities[i])
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+func TestParseCityQuery(t *testing.T) {
+	tests := []struct {
+		search    string
+		wantName  string
+		wantReads []cityQuery
+	}{
+		{"Jerusalem", "Jerusalem", nil},
+		{"Paris, TX", "Paris", []cityQuery{{"Paris", []string{"TX"}}}},
+		{" Springfield ,  Illinois, USA ", "Springfield", []cityQuery{{"Springfield", []string{"Illinois", "USA"}}}},
+		{"London,", "London", nil},
+		{"Monsey NY", "Monsey NY", []cityQuery{{"Monsey", []string{"NY"}}}},
+		{"Monsey New York", "Monsey New York", []cityQuery{
+			{"Monsey", []string{"New York"}},
+			{"Monsey New", []string{"York"}},
+		}},
+		{"Lakewood New Jersey USA", "Lakewood New Jersey USA", []cityQuery{
+			{"Lakewood", []string{"New Jersey USA"}},
+			{"Lakewood New", []string{"Jersey USA"}},
+			{"Lakewood New Jersey", []string{"USA"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			name, readings := parseCityQuery(tt.search)
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if !reflect.DeepEqual(readings, tt.wantReads) {
+				t.Errorf("readings = %+v, want %+v", readings, tt.wantReads)
+			}
+		})
+	}
+}
+
+func TestSearchLocale(t *testing.T) {
+	tests := []struct {
+		query, acceptLanguage, want string
+	}{
+		{"", "", ""},
+		{"lang=he", "", "heb"},
+		{"lang=heb", "fr", "heb"},
+		{"lang=he-IL", "", "heb"},
+		{"lang=xx", "fr", ""},
+		{"", "fr-CA,fr;q=0.9,en;q=0.8", "fra"},
+		{"", "xx, en;q=0.5, yi;q=0.7", "yid"},
+		{"", "iw", "heb"},
+		{"", "de;q=0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query+"|"+tt.acceptLanguage, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/cities?"+tt.query, nil)
+			if tt.acceptLanguage != "" {
+				r.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if got := searchLocale(r); got != tt.want {
+				t.Errorf("searchLocale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeCitySearches(t *testing.T) {
+	match := func(id string, tier int) citySearchMatch {
+		m := citySearchMatch{tier: tier}
+		m.city.ID = id
+		return m
+	}
+	whole := []citySearchMatch{match("exact", 0), match("fuzzy", 4), match("both", 4)}
+	qualified := []citySearchMatch{match("both", 1), match("qualified", 1)}
+
+	var ids []string
+	for _, c := range mergeCitySearches(whole, qualified, 3) {
+		ids = append(ids, c.ID)
+	}
+	if want := []string{"exact", "both", "qualified"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("merged = %v, want %v", ids, want)
+	}
+}
+
+func TestGeoSearchKeyFoldsTransliterations(t *testing.T) {
+	h := newDBTestHandlers(t)
+	pairs := [][2]string{
+		{"Bnei Brak", "Bene Beraq"},
+		{"Petach Tikvah", "Petah Tikva"},
+		{"Tzfat", "Tsfat"},
+	}
+	for _, p := range pairs {
+		var a, b string
+		if err := h.db.Pool.QueryRow(context.Background(), `SELECT geo_search_key($1), geo_search_key($2)`, p[0], p[1]).Scan(&a, &b); err != nil {
+			t.Fatalf("geo_search_key: %v", err)
+		}
+		if a != b {
+			t.Errorf("geo_search_key(%q) = %q, geo_search_key(%q) = %q; want equal", p[0], a, p[1], b)
+		}
+	}
+}
+
+func TestSearchCitiesMultilingual(t *testing.T) {
+	h := newDBTestHandlers(t)
+	helper := NewTestHelper(t)
+	f := newGeoFixture(t, h)
+
+	north := f.region("NS", "Northshire")
+	south := f.region("SS", "Southshire")
+	petah := f.city("Petah Tikva", north, -48.0, -125.0, 5000)
+	southern := f.city("Petah Tikva", south, -49.0, -125.0, 1000)
+	f.cityName(petah, "heb", "פתח תקווה")
+
+	search := func(query string, header http.Header) models.CitySearchResponse {
+		t.Helper()
+		r := helper.MakeRequest("GET", "/api/v1/cities?country_code="+fixtureGeoCode+"&"+query, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		h.SearchCities(w, r)
+		helper.AssertStatus(w, http.StatusOK)
+		var resp struct {
+			Data models.CitySearchResponse `json:"data"`
+		}
+		helper.ParseJSONResponse(w, &resp)
+		return resp.Data
+	}
+
+	// Another transliteration matches through the search key
+	got := search("search="+url.QueryEscape("Petach Tikvah"), nil)
+	if len(got.Cities) != 2 || got.Cities[0].ID != petah {
+		t.Errorf("Petach Tikvah: %+v, want both fixture cities, the larger first", got.Cities)
+	}
+
+	// A Hebrew name matches through geo_names
+	got = search("search="+url.QueryEscape("פתח תקווה"), nil)
+	if len(got.Cities) != 1 || got.Cities[0].ID != petah {
+		t.Fatalf("Hebrew search: %+v, want the northern city", got.Cities)
+	}
+	if c := got.Cities[0]; c.MatchType == nil || *c.MatchType != "translation" || c.MatchedLanguage == nil || *c.MatchedLanguage != "heb" {
+		t.Errorf("Hebrew search matched %v in %v, want a heb translation", c.MatchType, c.MatchedLanguage)
+	}
+
+	// A region qualifier narrows the search to that region
+	got = search("search="+url.QueryEscape("Petah Tikva Southshire"), nil)
+	if got.Query == nil || got.Query.Name != "Petah Tikva" || !reflect.DeepEqual(got.Query.Qualifiers, []string{"Southshire"}) {
+		t.Errorf("qualified search read as %+v", got.Query)
+	}
+	if len(got.Cities) == 0 || got.Cities[0].ID != southern {
+		t.Errorf("qualified search: %+v, want the Southshire city first", got.Cities)
+	}
+
+	// Names come back in the requested language where there is one
+	got = search("search="+url.QueryEscape("Petah Tikva"), http.Header{"Accept-Language": {"he-IL,he;q=0.9,en;q=0.5"}})
+	if got.Locale != "heb" || len(got.Cities) != 2 || got.Cities[0].Name != "פתח תקווה" || got.Cities[1].Name != "Petah Tikva" {
+		t.Errorf("localized search: locale %q, cities %+v", got.Locale, got.Cities)
+	}
+}
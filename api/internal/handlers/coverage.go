@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jcom-dev/zmanim-lab/internal/db/sqlcgen"
 	"github.com/jcom-dev/zmanim-lab/internal/models"
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
 )
 
 // GetPublisherCoverage returns the current publisher's coverage areas
@@ -48,6 +50,11 @@ func (h *Handlers) GetPublisherCoverage(w http.ResponseWriter, r *http.Request)
 		c := coverageRowToModel(row)
 		coverage = append(coverage, c)
 	}
+	if err := h.attachCoverageDetails(ctx, pc.PublisherID, coverage); err != nil {
+		slog.Error("failed to fetch coverage locations", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to fetch coverage areas")
+		return
+	}
 
 	RespondJSON(w, r, http.StatusOK, models.PublisherCoverageListResponse{
 		Coverage: coverage,
@@ -57,7 +64,7 @@ func (h *Handlers) GetPublisherCoverage(w http.ResponseWriter, r *http.Request)
 
 // CreatePublisherCoverage adds a new coverage area for the publisher
 // @Summary Create coverage area
-// @Description Adds a new geographic coverage area (continent, country, region, district, or city level)
+// @Description Adds a new geographic coverage area (continent, country, region, district, or city level), one of the publisher's own locations, a GeoJSON polygon, or a circle given by center and radius_m
 // @Tags Coverage
 // @Accept json
 // @Produce json
@@ -86,9 +93,9 @@ func (h *Handlers) CreatePublisherCoverage(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Validate coverage level
-	validLevels := map[string]bool{"continent": true, "country": true, "region": true, "district": true, "city": true}
+	validLevels := map[string]bool{"continent": true, "country": true, "region": true, "district": true, "city": true, "location": true, "polygon": true, "radius": true}
 	if !validLevels[req.CoverageLevel] {
-		RespondBadRequest(w, r, "Invalid coverage_level: must be 'continent', 'country', 'region', 'district', or 'city'")
+		RespondBadRequest(w, r, "Invalid coverage_level: must be 'continent', 'country', 'region', 'district', 'city', 'location', 'polygon', or 'radius'")
 		return
 	}
 
@@ -244,8 +251,64 @@ func (h *Handlers) CreatePublisherCoverage(w http.ResponseWriter, r *http.Reques
 			return
 		}
 		coverage = createCoverageCityRowToModel(row)
+
+	case "location":
+		if req.LocationID == nil || *req.LocationID == "" {
+			RespondBadRequest(w, r, "location_id is required for location-level coverage")
+			return
+		}
+		location, err := h.fetchPublisherLocation(ctx, pc.PublisherID, *req.LocationID)
+		if err != nil {
+			slog.Error("failed to fetch location", "error", err)
+			RespondInternalError(w, r, "Failed to create coverage")
+			return
+		}
+		if location == nil {
+			RespondBadRequest(w, r, "location_id must be one of the publisher's locations")
+			return
+		}
+		c, err := h.createLocationCoverage(ctx, pc.PublisherID, location, priority)
+		if isUniqueViolation(err) {
+			RespondBadRequest(w, r, "Coverage already exists for this location")
+			return
+		}
+		if err != nil {
+			slog.Error("failed to create location coverage", "error", err)
+			RespondInternalError(w, r, "Failed to create coverage")
+			return
+		}
+		coverage = c
+
+	case "polygon", "radius":
+		geometry, errs := validateAreaRequest(&req)
+		if len(errs) == 0 && req.CoverageLevel == "polygon" {
+			var err error
+			errs, err = h.checkCoverageGeometry(ctx, geometry)
+			if err != nil {
+				slog.Error("failed to validate coverage geometry", "error", err)
+				RespondInternalError(w, r, "Failed to validate coverage area")
+				return
+			}
+		}
+		if len(errs) > 0 {
+			RespondValidationError(w, r, "Invalid coverage area", errs)
+			return
+		}
+		c, err := h.createAreaCoverage(ctx, pc.PublisherID, &req, geometry, priority)
+		if err != nil {
+			slog.Error("failed to create area coverage", "error", err, "coverage_level", req.CoverageLevel)
+			RespondInternalError(w, r, "Failed to create coverage")
+			return
+		}
+		coverage = c
 	}
 
+	h.invalidateCoverageTiles(ctx, pc.PublisherID)
+	h.emitWebhookEvent(ctx, pc.PublisherID, webhooks.EventCoverageChanged, map[string]interface{}{
+		"action":   "created",
+		"coverage": coverage,
+	})
+
 	RespondJSON(w, r, http.StatusCreated, coverage)
 }
 
@@ -338,6 +401,12 @@ func (h *Handlers) UpdatePublisherCoverage(w http.ResponseWriter, r *http.Reques
 		coverage = updateCoverageActiveRowToModel(row)
 	}
 
+	h.invalidateCoverageTiles(ctx, pc.PublisherID)
+	h.emitWebhookEvent(ctx, pc.PublisherID, webhooks.EventCoverageChanged, map[string]interface{}{
+		"action":   "updated",
+		"coverage": coverage,
+	})
+
 	RespondJSON(w, r, http.StatusOK, coverage)
 }
 
@@ -388,6 +457,13 @@ func (h *Handlers) DeletePublisherCoverage(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	h.invalidateCoverageTiles(ctx, pc.PublisherID)
+	h.emitWebhookEvent(ctx, pc.PublisherID, webhooks.EventCoverageChanged, map[string]interface{}{
+		"action":         "deleted",
+		"coverage_id":    coverageID,
+		"coverage_level": existingCoverage.CoverageLevel,
+	})
+
 	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
 		"message": "Coverage deleted successfully",
 	})
@@ -395,7 +471,7 @@ func (h *Handlers) DeletePublisherCoverage(w http.ResponseWriter, r *http.Reques
 
 // GetPublishersForCity returns publishers serving a specific city
 // @Summary Get publishers for city
-// @Description Returns all publishers that have coverage for the specified city (via city, district, region, country, or continent level)
+// @Description Returns all publishers that have coverage for the specified city (via city, polygon or radius area, district, region, country, or continent level)
 // @Tags Cities
 // @Produce json
 // @Param cityId path string true "City ID (UUID)"
@@ -536,6 +612,64 @@ func (h *Handlers) GetRegions(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// createLocationCoverage covers one of the publisher's own locations
+func (h *Handlers) createLocationCoverage(ctx context.Context, publisherID string, location *models.PublisherLocation, priority int32) (models.PublisherCoverage, error) {
+	c := models.PublisherCoverage{
+		PublisherID:   publisherID,
+		CoverageLevel: "location",
+		LocationID:    &location.ID,
+		LocationName:  &location.Name,
+		Priority:      int(priority),
+		IsActive:      true,
+	}
+	err := h.db.Pool.QueryRow(ctx, `
+		INSERT INTO publisher_coverage (publisher_id, coverage_level, location_id, priority, is_active)
+		VALUES ($1, 'location', $2, $3, true)
+		RETURNING id, created_at, updated_at
+	`, publisherID, location.ID, priority).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+// attachCoverageDetails fills in the location of location-level coverage and
+// the shape of polygon and radius coverage, which the generated coverage
+// queries predate
+func (h *Handlers) attachCoverageDetails(ctx context.Context, publisherID string, coverage []models.PublisherCoverage) error {
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT pc.id, pl.id, pl.name, pc.area_name, pc.area_km2,
+		       ST_Y(pc.center::geometry), ST_X(pc.center::geometry), pc.radius_m
+		FROM publisher_coverage pc
+		LEFT JOIN publisher_locations pl ON pl.id = pc.location_id
+		WHERE pc.publisher_id = $1 AND pc.coverage_level IN ('location', 'polygon', 'radius')
+	`, publisherID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	details := make(map[string]models.PublisherCoverage)
+	for rows.Next() {
+		var coverageID string
+		var d models.PublisherCoverage
+		if err := rows.Scan(&coverageID, &d.LocationID, &d.LocationName, &d.AreaName, &d.AreaKm2,
+			&d.CenterLatitude, &d.CenterLongitude, &d.RadiusM); err != nil {
+			return err
+		}
+		details[coverageID] = d
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range coverage {
+		if d, ok := details[coverage[i].ID]; ok {
+			coverage[i].LocationID, coverage[i].LocationName = d.LocationID, d.LocationName
+			coverage[i].AreaName, coverage[i].AreaKm2 = d.AreaName, d.AreaKm2
+			coverage[i].CenterLatitude, coverage[i].CenterLongitude, coverage[i].RadiusM = d.CenterLatitude, d.CenterLongitude, d.RadiusM
+		}
+	}
+	return nil
+}
+
 // Helper functions to convert SQLc rows to models
 
 func coverageRowToModel(row sqlcgen.GetPublisherCoverageRow) models.PublisherCoverage {
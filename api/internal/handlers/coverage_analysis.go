@@ -0,0 +1,565 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CoverageAnalysis reports, per country (or per region within one country),
+// which publishers serve it, who wins where, equal-priority conflicts and the
+// cities no publisher covers. Coverage is resolved per city exactly as
+// get_publishers_for_city does; location coverage is not city-based and is
+// not counted.
+type CoverageAnalysis struct {
+	Scope           string                 `json:"scope"` // country or region
+	CountryCode     *string                `json:"country_code,omitempty"`
+	PublisherID     *string                `json:"publisher_id,omitempty"`
+	Totals          CoverageAnalysisTotals `json:"totals"`
+	Areas           []CoverageAnalysisArea `json:"areas"`
+	UncoveredCities []UncoveredCity        `json:"uncovered_cities"`
+}
+
+// CoverageAnalysisTotals sums the analyzed areas
+type CoverageAnalysisTotals struct {
+	Cities              int   `json:"cities"`
+	CoveredCities       int   `json:"covered_cities"`
+	Population          int64 `json:"population"`
+	UncoveredPopulation int64 `json:"uncovered_population"`
+	ConflictCities      int   `json:"conflict_cities"`
+}
+
+// CoverageAnalysisArea is one country or region
+type CoverageAnalysisArea struct {
+	ID                  *int32             `json:"id"` // nil for cities not assigned to a region
+	Code                string             `json:"code,omitempty"`
+	Name                string             `json:"name"`
+	Cities              int                `json:"cities"`
+	CoveredCities       int                `json:"covered_cities"`
+	Population          int64              `json:"population"`
+	UncoveredPopulation int64              `json:"uncovered_population"`
+	CoverageRatio       float64            `json:"coverage_ratio"` // share of population covered
+	ConflictCities      int                `json:"conflict_cities"`
+	Publishers          []AreaPublisher    `json:"publishers"`
+	Conflicts           []CoverageConflict `json:"conflicts"`
+
+	geometry json.RawMessage
+}
+
+// AreaPublisher is a publisher serving part or all of an area, listed in
+// effective priority order
+type AreaPublisher struct {
+	PublisherID    string   `json:"publisher_id"`
+	PublisherName  string   `json:"publisher_name"`
+	Priority       int      `json:"priority"`        // highest priority among its matching coverage
+	CoverageLevels []string `json:"coverage_levels"` // levels through which it matches cities here
+	Cities         int      `json:"cities"`
+	Population     int64    `json:"population"`
+	WinsCities     int      `json:"wins_cities"` // cities where it alone has the top priority
+	WinsPopulation int64    `json:"wins_population"`
+	Full           bool     `json:"full"` // covers every city in the area
+}
+
+// CoverageConflict is a set of publishers tied at the top priority for some
+// of an area's cities
+type CoverageConflict struct {
+	Priority   int                 `json:"priority"`
+	Publishers []ConflictPublisher `json:"publishers"`
+	Cities     int                 `json:"cities"`
+	Population int64               `json:"population"`
+}
+
+// ConflictPublisher identifies one publisher in a conflict
+type ConflictPublisher struct {
+	PublisherID   string `json:"publisher_id"`
+	PublisherName string `json:"publisher_name"`
+}
+
+// UncoveredCity is a city with no covering publisher
+type UncoveredCity struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	AreaID     *int32  `json:"area_id"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Population int64   `json:"population"`
+}
+
+// coverageAnalysisOptions selects what an analysis covers
+type coverageAnalysisOptions struct {
+	countryCode    string // "" analyzes per country, worldwide
+	publisherID    string // "" analyzes every area; else only areas the publisher serves
+	uncoveredLimit int
+	minPopulation  int64
+	geometry       bool
+}
+
+// parseCoverageAnalysisOptions reads the query parameters shared by the admin
+// and publisher endpoints
+func parseCoverageAnalysisOptions(r *http.Request) (coverageAnalysisOptions, string, error) {
+	q := r.URL.Query()
+	opts := coverageAnalysisOptions{
+		countryCode:    strings.ToUpper(strings.TrimSpace(q.Get("country_code"))),
+		uncoveredLimit: 50,
+	}
+	if l := q.Get("uncovered_limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 || n > 500 {
+			return opts, "", fmt.Errorf("uncovered_limit must be between 0 and 500")
+		}
+		opts.uncoveredLimit = n
+	}
+	if p := q.Get("min_population"); p != "" {
+		n, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || n < 0 {
+			return opts, "", fmt.Errorf("min_population must be a non-negative integer")
+		}
+		opts.minPopulation = n
+	}
+	format := q.Get("format")
+	switch format {
+	case "", "json":
+		format = "json"
+	case "geojson":
+		opts.geometry = true
+	default:
+		return opts, "", fmt.Errorf("format must be json or geojson")
+	}
+	return opts, format, nil
+}
+
+// AdminGetCoverageAnalysis analyzes coverage overlap and gaps across all publishers
+// @Summary Coverage overlap and gap analysis
+// @Description Per country, or per region when country_code is given: which publishers cover each area in effective priority order, equal-priority conflicts, and uncovered cities by population. format=geojson returns area boundaries and uncovered city points for the map.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param country_code query string false "Analyze the regions of one country (ISO 3166-1 alpha-2)"
+// @Param format query string false "json (default) or geojson"
+// @Param uncovered_limit query int false "Max uncovered cities listed, largest first (default 50, max 500)"
+// @Param min_population query int false "Only list uncovered cities with at least this population"
+// @Success 200 {object} APIResponse{data=CoverageAnalysis} "Coverage analysis"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid parameters"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /admin/coverage/analysis [get]
+func (h *Handlers) AdminGetCoverageAnalysis(w http.ResponseWriter, r *http.Request) {
+	opts, format, err := parseCoverageAnalysisOptions(r)
+	if err != nil {
+		RespondBadRequest(w, r, err.Error())
+		return
+	}
+	h.respondCoverageAnalysis(w, r, opts, format)
+}
+
+// GetPublisherCoverageAnalysis analyzes overlap and gaps where the publisher has coverage
+// @Summary Publisher coverage overlap and gap analysis
+// @Description The coverage analysis restricted to the countries (or regions of country_code) where the publisher covers at least one city, showing which other publishers share them and at what priority
+// @Tags Coverage
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param country_code query string false "Analyze the regions of one country (ISO 3166-1 alpha-2)"
+// @Param format query string false "json (default) or geojson"
+// @Param uncovered_limit query int false "Max uncovered cities listed, largest first (default 50, max 500)"
+// @Param min_population query int false "Only list uncovered cities with at least this population"
+// @Success 200 {object} APIResponse{data=CoverageAnalysis} "Coverage analysis"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid parameters"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/coverage/analysis [get]
+func (h *Handlers) GetPublisherCoverageAnalysis(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return // Response already sent
+	}
+	opts, format, err := parseCoverageAnalysisOptions(r)
+	if err != nil {
+		RespondBadRequest(w, r, err.Error())
+		return
+	}
+	opts.publisherID = pc.PublisherID
+	h.respondCoverageAnalysis(w, r, opts, format)
+}
+
+// respondCoverageAnalysis runs the analysis and writes it as JSON or GeoJSON
+func (h *Handlers) respondCoverageAnalysis(w http.ResponseWriter, r *http.Request, opts coverageAnalysisOptions, format string) {
+	analysis, err := h.coverageAnalysis(r.Context(), opts)
+	if err != nil {
+		slog.Error("coverage analysis failed", "error", err, "country_code", opts.countryCode, "publisher_id", opts.publisherID)
+		RespondInternalError(w, r, "Failed to analyze coverage")
+		return
+	}
+	if format == "json" {
+		RespondJSON(w, r, http.StatusOK, analysis)
+		return
+	}
+
+	fc := coverageAnalysisFeatures(analysis)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(fc)
+}
+
+// coverageAnalysisFeatures renders an analysis for the map: one feature per
+// area with an imported boundary, and one point per uncovered city
+func coverageAnalysisFeatures(a *CoverageAnalysis) GeoJSONFeatureCollection {
+	features := []GeoJSONFeature{}
+	for _, area := range a.Areas {
+		if area.geometry == nil {
+			continue
+		}
+		names := make([]string, 0, len(area.Publishers))
+		for _, p := range area.Publishers {
+			names = append(names, p.PublisherName)
+		}
+		features = append(features, GeoJSONFeature{
+			Type: "Feature",
+			ID:   area.ID,
+			Properties: map[string]interface{}{
+				"kind":                 "area",
+				"level":                a.Scope,
+				"code":                 area.Code,
+				"name":                 area.Name,
+				"cities":               area.Cities,
+				"covered_cities":       area.CoveredCities,
+				"population":           area.Population,
+				"uncovered_population": area.UncoveredPopulation,
+				"coverage_ratio":       area.CoverageRatio,
+				"publisher_count":      len(area.Publishers),
+				"publishers":           names,
+				"conflict_cities":      area.ConflictCities,
+			},
+			Geometry: area.geometry,
+		})
+	}
+	for _, c := range a.UncoveredCities {
+		features = append(features, GeoJSONFeature{
+			Type: "Feature",
+			ID:   c.ID,
+			Properties: map[string]interface{}{
+				"kind":       "uncovered_city",
+				"name":       c.Name,
+				"area_id":    c.AreaID,
+				"population": c.Population,
+			},
+			Geometry: json.RawMessage(fmt.Sprintf(`{"type":"Point","coordinates":[%g,%g]}`, c.Longitude, c.Latitude)),
+		})
+	}
+
+	metadata := map[string]interface{}{
+		"level":  "coverage_analysis",
+		"scope":  a.Scope,
+		"totals": a.Totals,
+		"count":  len(features),
+	}
+	if a.CountryCode != nil {
+		metadata["country_code"] = *a.CountryCode
+	}
+	return GeoJSONFeatureCollection{Type: "FeatureCollection", Metadata: metadata, Features: features}
+}
+
+// The analysis resolves coverage per city into temporary tables, then
+// aggregates them. Matching mirrors get_publishers_for_city: one row per city
+// and publisher, at the publisher's most specific matching coverage.
+const (
+	createAnalysisCities = `
+		CREATE TEMP TABLE coverage_analysis_cities ON COMMIT DROP AS
+		SELECT c.id, c.name, c.latitude, c.longitude, c.location, COALESCE(c.population, 0)::bigint AS population,
+		       c.district_id, c.region_id, c.country_id, cont.code AS continent_code,
+		       CASE WHEN $1 = '' THEN c.country_id::integer ELSE c.region_id END AS area_id
+		FROM geo_cities c
+		JOIN geo_countries co ON co.id = c.country_id
+		JOIN geo_continents cont ON cont.id = co.continent_id
+		WHERE $1 = '' OR co.code = $1`
+
+	createAnalysisMatches = `
+		CREATE TEMP TABLE coverage_analysis_matches ON COMMIT DROP AS
+		WITH active AS (
+			SELECT pc.publisher_id, pc.coverage_level, COALESCE(pc.priority, 0) AS priority,
+			       pc.city_id, pc.district_id, pc.region_id, pc.country_id, pc.continent_code, pc.area
+			FROM publisher_coverage pc
+			JOIN publishers p ON p.id = pc.publisher_id
+			WHERE pc.is_active = true AND p.status = 'active'
+		), matches AS (
+			SELECT c.id AS city_id, a.publisher_id, a.coverage_level, a.priority, 1 AS rank
+			FROM coverage_analysis_cities c JOIN active a ON a.coverage_level = 'city' AND a.city_id = c.id
+			UNION ALL
+			SELECT c.id, a.publisher_id, a.coverage_level, a.priority, 2
+			FROM coverage_analysis_cities c JOIN active a ON a.coverage_level IN ('polygon', 'radius') AND ST_Covers(a.area, c.location)
+			UNION ALL
+			SELECT c.id, a.publisher_id, a.coverage_level, a.priority, 3
+			FROM coverage_analysis_cities c JOIN active a ON a.coverage_level = 'district' AND a.district_id = c.district_id
+			UNION ALL
+			SELECT c.id, a.publisher_id, a.coverage_level, a.priority, 4
+			FROM coverage_analysis_cities c JOIN active a ON a.coverage_level = 'region' AND a.region_id = c.region_id
+			UNION ALL
+			SELECT c.id, a.publisher_id, a.coverage_level, a.priority, 5
+			FROM coverage_analysis_cities c JOIN active a ON a.coverage_level = 'country' AND a.country_id = c.country_id
+			UNION ALL
+			SELECT c.id, a.publisher_id, a.coverage_level, a.priority, 6
+			FROM coverage_analysis_cities c JOIN active a ON a.coverage_level = 'continent' AND a.continent_code = c.continent_code
+		), best AS (
+			SELECT DISTINCT ON (city_id, publisher_id) city_id, publisher_id, coverage_level, priority
+			FROM matches
+			ORDER BY city_id, publisher_id, rank, priority DESC
+		)
+		SELECT b.*,
+		       b.priority = max(b.priority) OVER (PARTITION BY b.city_id) AS top,
+		       count(*) OVER (PARTITION BY b.city_id, b.priority) AS tied
+		FROM best b`
+
+	// Areas in scope; for a publisher, only those where it covers a city
+	createAnalysisAreas = `
+		CREATE TEMP TABLE coverage_analysis_areas ON COMMIT DROP AS
+		SELECT DISTINCT c.area_id
+		FROM coverage_analysis_cities c
+		WHERE $1 = ''
+		   OR EXISTS (
+			SELECT 1 FROM coverage_analysis_matches m
+			JOIN coverage_analysis_cities mc ON mc.id = m.city_id
+			WHERE m.publisher_id::text = $1 AND mc.area_id IS NOT DISTINCT FROM c.area_id
+		   )`
+
+	// Joins a query over coverage_analysis_cities c to the areas in scope,
+	// keeping cities without an area
+	inAnalysisAreas = `JOIN coverage_analysis_areas ar ON ar.area_id IS NOT DISTINCT FROM c.area_id`
+)
+
+// coverageAnalysis runs the analysis in a transaction that is rolled back
+func (h *Handlers) coverageAnalysis(ctx context.Context, opts coverageAnalysisOptions) (*CoverageAnalysis, error) {
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // the temporary tables are all it writes
+
+	if _, err := tx.Exec(ctx, createAnalysisCities, opts.countryCode); err != nil {
+		return nil, fmt.Errorf("failed to select cities: %w", err)
+	}
+	if _, err := tx.Exec(ctx, createAnalysisMatches); err != nil {
+		return nil, fmt.Errorf("failed to resolve coverage: %w", err)
+	}
+	if _, err := tx.Exec(ctx, createAnalysisAreas, opts.publisherID); err != nil {
+		return nil, fmt.Errorf("failed to select areas: %w", err)
+	}
+
+	a := &CoverageAnalysis{Scope: "country", Areas: []CoverageAnalysisArea{}, UncoveredCities: []UncoveredCity{}}
+	if opts.countryCode != "" {
+		a.Scope = "region"
+		a.CountryCode = &opts.countryCode
+	}
+	if opts.publisherID != "" {
+		a.PublisherID = &opts.publisherID
+	}
+
+	areas, err := loadAnalysisAreas(ctx, tx, a.Scope, opts.geometry)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int32]*CoverageAnalysisArea, len(areas))
+	var unassigned *CoverageAnalysisArea
+	for i := range areas {
+		if areas[i].ID == nil {
+			unassigned = &areas[i]
+		} else {
+			byID[*areas[i].ID] = &areas[i]
+		}
+	}
+	areaFor := func(id *int32) *CoverageAnalysisArea {
+		if id == nil {
+			return unassigned
+		}
+		return byID[*id]
+	}
+
+	if err := loadAnalysisPublishers(ctx, tx, areaFor); err != nil {
+		return nil, err
+	}
+	if err := loadAnalysisConflicts(ctx, tx, areaFor); err != nil {
+		return nil, err
+	}
+	if a.UncoveredCities, err = loadUncoveredCities(ctx, tx, opts); err != nil {
+		return nil, err
+	}
+
+	for i := range areas {
+		area := &areas[i]
+		if area.Population > 0 {
+			area.CoverageRatio = float64(area.Population-area.UncoveredPopulation) / float64(area.Population)
+		} else if area.Cities > 0 {
+			area.CoverageRatio = float64(area.CoveredCities) / float64(area.Cities)
+		}
+		for j := range area.Publishers {
+			area.Publishers[j].Full = area.Publishers[j].Cities == area.Cities
+		}
+		a.Totals.Cities += area.Cities
+		a.Totals.CoveredCities += area.CoveredCities
+		a.Totals.Population += area.Population
+		a.Totals.UncoveredPopulation += area.UncoveredPopulation
+		a.Totals.ConflictCities += area.ConflictCities
+	}
+	a.Areas = areas
+	return a, nil
+}
+
+// loadAnalysisAreas returns the analyzed areas with their city and population
+// totals, least covered population first
+func loadAnalysisAreas(ctx context.Context, tx pgx.Tx, scope string, geometry bool) ([]CoverageAnalysisArea, error) {
+	names := `LEFT JOIN geo_countries g ON g.id = s.area_id
+		LEFT JOIN geo_country_boundaries b ON b.country_id = s.area_id`
+	if scope == "region" {
+		names = `LEFT JOIN geo_regions g ON g.id = s.area_id
+		LEFT JOIN geo_region_boundaries b ON b.region_id = s.area_id`
+	}
+	rows, err := tx.Query(ctx, `
+		WITH stats AS (
+			SELECT c.area_id, count(*) AS cities, sum(c.population) AS population,
+			       count(*) FILTER (WHERE covered.city_id IS NOT NULL) AS covered_cities,
+			       COALESCE(sum(c.population) FILTER (WHERE covered.city_id IS NULL), 0) AS uncovered_population,
+			       count(*) FILTER (WHERE conflict.city_id IS NOT NULL) AS conflict_cities
+			FROM coverage_analysis_cities c
+			`+inAnalysisAreas+`
+			LEFT JOIN (SELECT DISTINCT city_id FROM coverage_analysis_matches) covered ON covered.city_id = c.id
+			LEFT JOIN (SELECT DISTINCT city_id FROM coverage_analysis_matches WHERE top AND tied > 1) conflict ON conflict.city_id = c.id
+			GROUP BY c.area_id
+		)
+		SELECT s.area_id, COALESCE(g.code, ''), COALESCE(g.name, 'Unassigned'), s.cities, s.covered_cities,
+		       s.population, s.uncovered_population, s.conflict_cities,
+		       CASE WHEN $1 THEN ST_AsGeoJSON(COALESCE(b.boundary_simplified, b.boundary)::geometry, 5) END
+		FROM stats s
+		`+names+`
+		ORDER BY s.uncovered_population DESC, s.population DESC
+	`, geometry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate areas: %w", err)
+	}
+	defer rows.Close()
+
+	areas := []CoverageAnalysisArea{}
+	for rows.Next() {
+		area := CoverageAnalysisArea{Publishers: []AreaPublisher{}, Conflicts: []CoverageConflict{}}
+		var geojson *string
+		if err := rows.Scan(&area.ID, &area.Code, &area.Name, &area.Cities, &area.CoveredCities,
+			&area.Population, &area.UncoveredPopulation, &area.ConflictCities, &geojson); err != nil {
+			return nil, fmt.Errorf("failed to scan area: %w", err)
+		}
+		if geojson != nil {
+			area.geometry = json.RawMessage(*geojson)
+		}
+		areas = append(areas, area)
+	}
+	return areas, rows.Err()
+}
+
+// loadAnalysisPublishers adds each area's publishers in effective priority
+// order: highest priority first, then most population won
+func loadAnalysisPublishers(ctx context.Context, tx pgx.Tx, areaFor func(*int32) *CoverageAnalysisArea) error {
+	rows, err := tx.Query(ctx, `
+		SELECT c.area_id, m.publisher_id::text, p.name, max(m.priority) AS priority,
+		       array_agg(DISTINCT m.coverage_level), count(*), sum(c.population),
+		       count(*) FILTER (WHERE m.top AND m.tied = 1),
+		       COALESCE(sum(c.population) FILTER (WHERE m.top AND m.tied = 1), 0) AS wins_population
+		FROM coverage_analysis_matches m
+		JOIN coverage_analysis_cities c ON c.id = m.city_id
+		`+inAnalysisAreas+`
+		JOIN publishers p ON p.id = m.publisher_id
+		GROUP BY c.area_id, m.publisher_id, p.name
+		ORDER BY priority DESC, wins_population DESC, p.name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate publishers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var areaID *int32
+		var p AreaPublisher
+		if err := rows.Scan(&areaID, &p.PublisherID, &p.PublisherName, &p.Priority, &p.CoverageLevels,
+			&p.Cities, &p.Population, &p.WinsCities, &p.WinsPopulation); err != nil {
+			return fmt.Errorf("failed to scan area publisher: %w", err)
+		}
+		if area := areaFor(areaID); area != nil {
+			area.Publishers = append(area.Publishers, p)
+		}
+	}
+	return rows.Err()
+}
+
+// loadAnalysisConflicts adds each area's sets of publishers tied at the top
+// priority, largest population first
+func loadAnalysisConflicts(ctx context.Context, tx pgx.Tx, areaFor func(*int32) *CoverageAnalysisArea) error {
+	rows, err := tx.Query(ctx, `
+		WITH tied AS (
+			SELECT m.city_id, m.priority,
+			       array_agg(m.publisher_id::text ORDER BY p.name, m.publisher_id) AS ids,
+			       array_agg(p.name ORDER BY p.name, m.publisher_id) AS names
+			FROM coverage_analysis_matches m
+			JOIN publishers p ON p.id = m.publisher_id
+			WHERE m.top AND m.tied > 1
+			GROUP BY m.city_id, m.priority
+		)
+		SELECT c.area_id, t.priority, t.ids, t.names, count(*), sum(c.population)
+		FROM tied t
+		JOIN coverage_analysis_cities c ON c.id = t.city_id
+		`+inAnalysisAreas+`
+		GROUP BY c.area_id, t.priority, t.ids, t.names
+		ORDER BY sum(c.population) DESC, count(*) DESC
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var areaID *int32
+		var c CoverageConflict
+		var ids, names []string
+		if err := rows.Scan(&areaID, &c.Priority, &ids, &names, &c.Cities, &c.Population); err != nil {
+			return fmt.Errorf("failed to scan conflict: %w", err)
+		}
+		for i := range ids {
+			c.Publishers = append(c.Publishers, ConflictPublisher{PublisherID: ids[i], PublisherName: names[i]})
+		}
+		if area := areaFor(areaID); area != nil {
+			area.Conflicts = append(area.Conflicts, c)
+		}
+	}
+	return rows.Err()
+}
+
+// loadUncoveredCities lists the most populous cities no publisher covers
+func loadUncoveredCities(ctx context.Context, tx pgx.Tx, opts coverageAnalysisOptions) ([]UncoveredCity, error) {
+	cities := []UncoveredCity{}
+	if opts.uncoveredLimit == 0 {
+		return cities, nil
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT c.id::text, c.name, c.area_id, c.latitude, c.longitude, c.population
+		FROM coverage_analysis_cities c
+		`+inAnalysisAreas+`
+		WHERE c.population >= $1
+		  AND NOT EXISTS (SELECT 1 FROM coverage_analysis_matches m WHERE m.city_id = c.id)
+		ORDER BY c.population DESC, c.name
+		LIMIT $2
+	`, opts.minPopulation, opts.uncoveredLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uncovered cities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c UncoveredCity
+		if err := rows.Scan(&c.ID, &c.Name, &c.AreaID, &c.Latitude, &c.Longitude, &c.Population); err != nil {
+			return nil, fmt.Errorf("failed to scan uncovered city: %w", err)
+		}
+		cities = append(cities, c)
+	}
+	return cities, rows.Err()
+}
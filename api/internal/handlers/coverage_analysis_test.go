@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCoverageAnalysisOptions(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantFormat string
+		wantErr    bool
+		check      func(coverageAnalysisOptions) bool
+	}{
+		{"", "json", false, func(o coverageAnalysisOptions) bool {
+			return o.countryCode == "" && o.uncoveredLimit == 50 && !o.geometry
+		}},
+		{"country_code=us&format=geojson&uncovered_limit=10&min_population=5000", "geojson", false, func(o coverageAnalysisOptions) bool {
+			return o.countryCode == "US" && o.uncoveredLimit == 10 && o.minPopulation == 5000 && o.geometry
+		}},
+		{"uncovered_limit=501", "", true, nil},
+		{"min_population=-1", "", true, nil},
+		{"format=kml", "", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			opts, format, err := parseCoverageAnalysisOptions(httptest.NewRequest("GET", "/coverage/analysis?"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if !tt.check(opts) {
+				t.Errorf("unexpected options %+v", opts)
+			}
+		})
+	}
+}
+
+func TestCoverageAnalysisFeatures(t *testing.T) {
+	id := int32(7)
+	a := &CoverageAnalysis{
+		Scope: "region",
+		Areas: []CoverageAnalysisArea{
+			{ID: &id, Name: "New York", Publishers: []AreaPublisher{{PublisherName: "OU"}}, geometry: json.RawMessage(`{"type":"MultiPolygon","coordinates":[]}`)},
+			{Name: "Unassigned"}, // no boundary: not drawn
+		},
+		UncoveredCities: []UncoveredCity{{ID: "c1", Name: "Monsey", Latitude: 41.1, Longitude: -74.07, Population: 20000}},
+	}
+
+	fc := coverageAnalysisFeatures(a)
+	if len(fc.Features) != 2 {
+		t.Fatalf("got %d features, want 2", len(fc.Features))
+	}
+	if kind := fc.Features[0].Properties["kind"]; kind != "area" {
+		t.Errorf("first feature kind = %v, want area", kind)
+	}
+	var point struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(fc.Features[1].Geometry, &point); err != nil {
+		t.Fatalf("uncovered city geometry: %v", err)
+	}
+	if point.Type != "Point" || point.Coordinates[0] != -74.07 || point.Coordinates[1] != 41.1 {
+		t.Errorf("uncovered city geometry = %+v, want Point [-74.07 41.1]", point)
+	}
+}
+
+func TestCoverageAnalysisOverlapAndGaps(t *testing.T) {
+	h := newDBTestHandlers(t)
+	helper := NewTestHelper(t)
+	f := newGeoFixture(t, h)
+
+	// North is covered by A by region and B by one city at the same
+	// priority; South has no coverage
+	north := f.region("N", "North")
+	south := f.region("S", "South")
+	northOne := f.city("North One", north, -48.0, -125.0, 5000)
+	f.city("North Two", north, -48.1, -125.1, 1000)
+	southOne := f.city("South One", south, -49.0, -125.0, 3000)
+	a := f.publisher("Fixture A")
+	b := f.publisher("Fixture B")
+	f.cover(a, "region", north, 1)
+	f.cover(b, "city", northOne, 1)
+
+	w := httptest.NewRecorder()
+	h.AdminGetCoverageAnalysis(w, helper.MakeRequest("GET", "/api/v1/admin/coverage/analysis?country_code="+fixtureGeoCode, nil))
+	helper.AssertStatus(w, http.StatusOK)
+	var resp struct {
+		Data CoverageAnalysis `json:"data"`
+	}
+	helper.ParseJSONResponse(w, &resp)
+	got := resp.Data
+
+	want := CoverageAnalysisTotals{Cities: 3, CoveredCities: 2, Population: 9000, UncoveredPopulation: 3000, ConflictCities: 1}
+	if got.Scope != "region" || got.Totals != want {
+		t.Errorf("scope %q totals %+v, want region %+v", got.Scope, got.Totals, want)
+	}
+	if len(got.Areas) != 2 || got.Areas[0].Name != "South" || got.Areas[1].Name != "North" {
+		t.Fatalf("areas = %+v, want South then North", got.Areas)
+	}
+	if s := got.Areas[0]; s.CoverageRatio != 0 || len(s.Publishers) != 0 {
+		t.Errorf("South = %+v, want uncovered", s)
+	}
+
+	n := got.Areas[1]
+	if n.CoverageRatio != 1 || n.ConflictCities != 1 || len(n.Publishers) != 2 {
+		t.Fatalf("North = %+v", n)
+	}
+	// A alone wins North Two, so it ranks ahead of B at the same priority
+	pa, pb := n.Publishers[0], n.Publishers[1]
+	if pa.PublisherID != a || pa.Cities != 2 || !pa.Full || pa.WinsCities != 1 || pa.WinsPopulation != 1000 {
+		t.Errorf("first North publisher = %+v, want A covering all of North", pa)
+	}
+	if pb.PublisherID != b || pb.Cities != 1 || pb.Full || pb.WinsCities != 0 || len(pb.CoverageLevels) != 1 || pb.CoverageLevels[0] != "city" {
+		t.Errorf("second North publisher = %+v, want B covering one city", pb)
+	}
+	if len(n.Conflicts) != 1 || n.Conflicts[0].Cities != 1 || n.Conflicts[0].Population != 5000 || len(n.Conflicts[0].Publishers) != 2 {
+		t.Errorf("North conflicts = %+v, want A and B tied over North One", n.Conflicts)
+	}
+
+	if len(got.UncoveredCities) != 1 || got.UncoveredCities[0].ID != southOne {
+		t.Errorf("uncovered cities = %+v, want South One", got.UncoveredCities)
+	}
+
+	// Restricted to B, only the region where B covers a city is analyzed
+	scoped, err := h.coverageAnalysis(context.Background(), coverageAnalysisOptions{countryCode: fixtureGeoCode, publisherID: b, uncoveredLimit: 50})
+	if err != nil {
+		t.Fatalf("coverage analysis for B: %v", err)
+	}
+	if len(scoped.Areas) != 1 || scoped.Areas[0].Name != "North" || len(scoped.UncoveredCities) != 0 {
+		t.Errorf("analysis for B = %+v, want North only", scoped)
+	}
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+// Limits on polygon and radius coverage, keeping areas to the scale of a
+// community rather than a substitute for region or country coverage
+const (
+	maxCoverageAreaKm2   = 50000
+	maxCoverageVertices  = 10000
+	minCoverageRadiusM   = 100
+	maxCoverageRadiusM   = 100000
+	maxCoverageAreaName  = 200
+	coverageGeoJSONDigit = 6 // ~10cm
+)
+
+// geoJSONObject is the subset of a GeoJSON object read when accepting an
+// uploaded coverage polygon
+type geoJSONObject struct {
+	Type        string            `json:"type"`
+	Coordinates json.RawMessage   `json:"coordinates"`
+	Geometry    json.RawMessage   `json:"geometry"`
+	Features    []json.RawMessage `json:"features"`
+}
+
+// parseCoverageGeometry accepts a GeoJSON Polygon or MultiPolygon, or a
+// Feature or single-feature FeatureCollection wrapping one, and returns the
+// bare geometry. Structure, ring closure, coordinate ranges and the vertex
+// limit are checked here; self-intersection is left to PostGIS.
+func parseCoverageGeometry(raw json.RawMessage) (json.RawMessage, error) {
+	var obj geoJSONObject
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("must be a GeoJSON object")
+	}
+
+	switch obj.Type {
+	case "FeatureCollection":
+		if len(obj.Features) != 1 {
+			return nil, fmt.Errorf("a FeatureCollection must contain exactly one feature")
+		}
+		return parseCoverageGeometry(obj.Features[0])
+	case "Feature":
+		if len(obj.Geometry) == 0 || string(obj.Geometry) == "null" {
+			return nil, fmt.Errorf("feature has no geometry")
+		}
+		return parseCoverageGeometry(obj.Geometry)
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates")
+		}
+		if err := checkPolygons([][][][]float64{polygon}); err != nil {
+			return nil, err
+		}
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates")
+		}
+		if len(polygons) == 0 {
+			return nil, fmt.Errorf("MultiPolygon has no polygons")
+		}
+		if err := checkPolygons(polygons); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("must be a Polygon or MultiPolygon, not %q", obj.Type)
+	}
+
+	return json.Marshal(struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}{obj.Type, obj.Coordinates})
+}
+
+// checkPolygons validates the rings of each polygon
+func checkPolygons(polygons [][][][]float64) error {
+	vertices := 0
+	for _, polygon := range polygons {
+		if len(polygon) == 0 {
+			return fmt.Errorf("polygon has no rings")
+		}
+		for _, ring := range polygon {
+			if len(ring) < 4 {
+				return fmt.Errorf("rings must have at least 4 positions")
+			}
+			for _, pos := range ring {
+				if len(pos) < 2 {
+					return fmt.Errorf("positions must have a longitude and latitude")
+				}
+				if pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+					return fmt.Errorf("position [%g, %g] is out of range", pos[0], pos[1])
+				}
+			}
+			first, last := ring[0], ring[len(ring)-1]
+			if first[0] != last[0] || first[1] != last[1] {
+				return fmt.Errorf("rings must be closed")
+			}
+			vertices += len(ring)
+		}
+	}
+	if vertices > maxCoverageVertices {
+		return fmt.Errorf("has %d vertices, more than the limit of %d", vertices, maxCoverageVertices)
+	}
+	return nil
+}
+
+// validateAreaRequest checks the fields of a polygon or radius coverage request
+// that do not need the database, returning the polygon's bare geometry
+func validateAreaRequest(req *models.PublisherCoverageCreateRequest) (json.RawMessage, map[string]string) {
+	errs := make(map[string]string)
+	if req.Name != nil && len(strings.TrimSpace(*req.Name)) > maxCoverageAreaName {
+		errs["name"] = fmt.Sprintf("must be at most %d characters", maxCoverageAreaName)
+	}
+
+	var geometry json.RawMessage
+	switch req.CoverageLevel {
+	case "polygon":
+		if len(req.Geometry) == 0 {
+			errs["geometry"] = "is required for polygon coverage"
+			break
+		}
+		g, err := parseCoverageGeometry(req.Geometry)
+		if err != nil {
+			errs["geometry"] = err.Error()
+		}
+		geometry = g
+	case "radius":
+		if req.CenterLatitude == nil || *req.CenterLatitude < -90 || *req.CenterLatitude > 90 {
+			errs["center_latitude"] = "is required and must be between -90 and 90"
+		}
+		if req.CenterLongitude == nil || *req.CenterLongitude < -180 || *req.CenterLongitude > 180 {
+			errs["center_longitude"] = "is required and must be between -180 and 180"
+		}
+		if req.RadiusM == nil || *req.RadiusM < minCoverageRadiusM || *req.RadiusM > maxCoverageRadiusM {
+			errs["radius_m"] = fmt.Sprintf("is required and must be between %d and %d meters", minCoverageRadiusM, maxCoverageRadiusM)
+		}
+	}
+	return geometry, errs
+}
+
+// checkCoverageGeometry has PostGIS validate a parsed polygon, reporting
+// self-intersections and areas over the size limit
+func (h *Handlers) checkCoverageGeometry(ctx context.Context, geometry json.RawMessage) (map[string]string, error) {
+	var valid bool
+	var reason string
+	var areaKm2 float64
+	err := h.db.Pool.QueryRow(ctx, `
+		WITH g AS (SELECT ST_Force2D(ST_SetSRID(ST_GeomFromGeoJSON($1), 4326)) AS geom)
+		SELECT ST_IsValid(geom), ST_IsValidReason(geom), ST_Area(geom::geography) / 1e6
+		FROM g
+	`, string(geometry)).Scan(&valid, &reason, &areaKm2)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make(map[string]string)
+	switch {
+	case !valid:
+		errs["geometry"] = "is not a valid polygon: " + reason
+	case areaKm2 > maxCoverageAreaKm2:
+		errs["geometry"] = fmt.Sprintf("covers %.0f km², more than the limit of %d km²", areaKm2, maxCoverageAreaKm2)
+	}
+	return errs, nil
+}
+
+// createAreaCoverage stores polygon or radius coverage
+func (h *Handlers) createAreaCoverage(ctx context.Context, publisherID string, req *models.PublisherCoverageCreateRequest, geometry json.RawMessage, priority int32) (models.PublisherCoverage, error) {
+	c := models.PublisherCoverage{
+		PublisherID:   publisherID,
+		CoverageLevel: req.CoverageLevel,
+		Priority:      int(priority),
+		IsActive:      true,
+	}
+	var name *string
+	if req.Name != nil {
+		if trimmed := strings.TrimSpace(*req.Name); trimmed != "" {
+			name = &trimmed
+		}
+	}
+	c.AreaName = name
+
+	var row pgx.Row
+	if req.CoverageLevel == "polygon" {
+		row = h.db.Pool.QueryRow(ctx, `
+			INSERT INTO publisher_coverage (publisher_id, coverage_level, area, area_name, area_km2, priority, is_active)
+			SELECT $1, 'polygon', ST_Multi(geom)::geography, $3, ST_Area(geom::geography) / 1e6, $4, true
+			FROM (SELECT ST_Force2D(ST_SetSRID(ST_GeomFromGeoJSON($2), 4326)) AS geom) g
+			RETURNING id, area_km2, created_at, updated_at
+		`, publisherID, string(geometry), name, priority)
+	} else {
+		c.CenterLatitude, c.CenterLongitude, c.RadiusM = req.CenterLatitude, req.CenterLongitude, req.RadiusM
+		row = h.db.Pool.QueryRow(ctx, `
+			INSERT INTO publisher_coverage (publisher_id, coverage_level, area, area_name, area_km2, center, radius_m, priority, is_active)
+			SELECT $1, 'radius', ST_Multi(ST_Buffer(center, $4)::geometry)::geography, $5,
+			       ST_Area(ST_Buffer(center, $4)) / 1e6, center, $4, $6, true
+			FROM (SELECT ST_SetSRID(ST_MakePoint($3, $2), 4326)::geography AS center) c
+			RETURNING id, area_km2, created_at, updated_at
+		`, publisherID, *req.CenterLatitude, *req.CenterLongitude, *req.RadiusM, name, priority)
+	}
+
+	var areaKm2 float64
+	err := row.Scan(&c.ID, &areaKm2, &c.CreatedAt, &c.UpdatedAt)
+	c.AreaKm2 = &areaKm2
+	return c, err
+}
+
+// publishersForPoint returns the publishers serving a coordinate, best match
+// per publisher
+func (h *Handlers) publishersForPoint(ctx context.Context, latitude, longitude float64) ([]models.PublisherForCity, error) {
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT publisher_id, publisher_name, coverage_level, priority, match_type
+		FROM get_publishers_for_point($1, $2)
+		ORDER BY priority DESC, publisher_name
+	`, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	publishers := []models.PublisherForCity{}
+	for rows.Next() {
+		var p models.PublisherForCity
+		if err := rows.Scan(&p.PublisherID, &p.PublisherName, &p.CoverageLevel, &p.Priority, &p.MatchType); err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, p)
+	}
+	return publishers, rows.Err()
+}
+
+// GetCoverageBoundaries returns a publisher's active coverage as GeoJSON
+// @Summary Get coverage boundaries
+// @Description Returns a publisher's active coverage as a GeoJSON FeatureCollection: polygon and radius areas as drawn, administrative coverage as its (simplified) boundary, and cities and locations as points. Continent coverage has no geometry and is omitted.
+// @Tags Geographic Boundaries
+// @Produce json
+// @Param publisher_id query string true "Publisher ID"
+// @Success 200 {object} GeoJSONFeatureCollection "GeoJSON FeatureCollection of coverage areas"
+// @Failure 400 {object} APIResponse{error=APIError} "publisher_id is required"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /geo/boundaries/coverage [get]
+func (h *Handlers) GetCoverageBoundaries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	publisherID := r.URL.Query().Get("publisher_id")
+	if publisherID == "" {
+		RespondBadRequest(w, r, "publisher_id is required")
+		return
+	}
+
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT pc.id, pc.coverage_level, pc.priority,
+		       COALESCE(pc.area_name, pl.name, ci.name, d.name, rg.name, co.name),
+		       pc.area_km2, pc.radius_m,
+		       ST_AsGeoJSON(COALESCE(
+		           pc.area::geometry,
+		           pl.location::geometry,
+		           COALESCE(cb.boundary_simplified, cb.boundary)::geometry,
+		           ci.location::geometry,
+		           COALESCE(db.boundary_simplified, db.boundary)::geometry,
+		           COALESCE(rb.boundary_simplified, rb.boundary)::geometry,
+		           COALESCE(cob.boundary_simplified, cob.boundary)::geometry
+		       ), $2)
+		FROM publisher_coverage pc
+		JOIN publishers p ON p.id = pc.publisher_id
+		LEFT JOIN publisher_locations pl ON pl.id = pc.location_id
+		LEFT JOIN geo_cities ci ON ci.id = pc.city_id
+		LEFT JOIN geo_city_boundaries cb ON cb.city_id = pc.city_id
+		LEFT JOIN geo_districts d ON d.id = pc.district_id
+		LEFT JOIN geo_district_boundaries db ON db.district_id = pc.district_id
+		LEFT JOIN geo_regions rg ON rg.id = pc.region_id
+		LEFT JOIN geo_region_boundaries rb ON rb.region_id = pc.region_id
+		LEFT JOIN geo_countries co ON co.id = pc.country_id
+		LEFT JOIN geo_country_boundaries cob ON cob.country_id = pc.country_id
+		WHERE pc.publisher_id = $1 AND pc.is_active = true AND p.status = 'active'
+		  AND pc.coverage_level <> 'continent'
+		ORDER BY pc.priority DESC, pc.created_at
+	`, publisherID, coverageGeoJSONDigit)
+	if err != nil {
+		slog.Error("failed to get coverage boundaries", "error", err, "publisher_id", publisherID)
+		RespondInternalError(w, r, "Failed to get coverage boundaries")
+		return
+	}
+	defer rows.Close()
+
+	features := []GeoJSONFeature{}
+	for rows.Next() {
+		var id, level string
+		var priority *int32
+		var name, geometry *string
+		var areaKm2, radiusM *float64
+		if err := rows.Scan(&id, &level, &priority, &name, &areaKm2, &radiusM, &geometry); err != nil {
+			slog.Error("failed to scan coverage boundary", "error", err)
+			RespondInternalError(w, r, "Failed to get coverage boundaries")
+			return
+		}
+		if geometry == nil {
+			continue // boundary not imported
+		}
+		properties := map[string]interface{}{
+			"coverage_level": level,
+			"priority":       priority,
+		}
+		if name != nil {
+			properties["name"] = *name
+		}
+		if areaKm2 != nil {
+			properties["area_km2"] = *areaKm2
+		}
+		if radiusM != nil {
+			properties["radius_m"] = *radiusM
+		}
+		features = append(features, GeoJSONFeature{
+			Type:       "Feature",
+			ID:         id,
+			Properties: properties,
+			Geometry:   json.RawMessage(*geometry),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("failed to read coverage boundaries", "error", err)
+		RespondInternalError(w, r, "Failed to get coverage boundaries")
+		return
+	}
+
+	fc := GeoJSONFeatureCollection{
+		Type: "FeatureCollection",
+		Metadata: map[string]interface{}{
+			"level":        "coverage",
+			"publisher_id": publisherID,
+			"count":        len(features),
+		},
+		Features: features,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(fc)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+func TestParseCoverageGeometry(t *testing.T) {
+	square := `[[[35.1,31.7],[35.2,31.7],[35.2,31.8],[35.1,31.8],[35.1,31.7]]]`
+	polygon := `{"type":"Polygon","coordinates":` + square + `}`
+
+	// A ring with more vertices than the limit
+	var big strings.Builder
+	big.WriteString(`{"type":"Polygon","coordinates":[[`)
+	for i := 0; i < maxCoverageVertices; i++ {
+		fmt.Fprintf(&big, "[%g,31.7],", 35+float64(i)/1e6)
+	}
+	big.WriteString(`[35,31.8],[35,31.7]]]}`)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{"polygon", polygon, polygon, ""},
+		{"multipolygon", `{"type":"MultiPolygon","coordinates":[` + square + `]}`, `{"type":"MultiPolygon","coordinates":[` + square + `]}`, ""},
+		{"feature", `{"type":"Feature","properties":{"name":"Eruv"},"geometry":` + polygon + `}`, polygon, ""},
+		{"single feature collection", `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":` + polygon + `}]}`, polygon, ""},
+		{"two features", `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":` + polygon + `},{"type":"Feature","geometry":` + polygon + `}]}`, "", "exactly one feature"},
+		{"feature without geometry", `{"type":"Feature","geometry":null}`, "", "no geometry"},
+		{"point", `{"type":"Point","coordinates":[35.1,31.7]}`, "", "Polygon or MultiPolygon"},
+		{"open ring", `{"type":"Polygon","coordinates":[[[35.1,31.7],[35.2,31.7],[35.2,31.8],[35.1,31.8]]]}`, "", "closed"},
+		{"too few positions", `{"type":"Polygon","coordinates":[[[35.1,31.7],[35.2,31.7],[35.1,31.7]]]}`, "", "at least 4"},
+		{"out of range", `{"type":"Polygon","coordinates":[[[35.1,91],[35.2,31.7],[35.2,31.8],[35.1,91]]]}`, "", "out of range"},
+		{"too many vertices", big.String(), "", "vertices"},
+		{"not json", `polygon`, "", "GeoJSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCoverageGeometry(json.RawMessage(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseCoverageGeometry() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCoverageGeometry() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("parseCoverageGeometry() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAreaRequest(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		req     models.PublisherCoverageCreateRequest
+		invalid []string
+	}{
+		{"radius", models.PublisherCoverageCreateRequest{
+			CoverageLevel: "radius", CenterLatitude: ptr(40.6), CenterLongitude: ptr(-73.9), RadiusM: ptr(2500),
+		}, nil},
+		{"radius missing fields", models.PublisherCoverageCreateRequest{CoverageLevel: "radius"},
+			[]string{"center_latitude", "center_longitude", "radius_m"}},
+		{"radius too large", models.PublisherCoverageCreateRequest{
+			CoverageLevel: "radius", CenterLatitude: ptr(40.6), CenterLongitude: ptr(-73.9), RadiusM: ptr(maxCoverageRadiusM + 1),
+		}, []string{"radius_m"}},
+		{"polygon missing geometry", models.PublisherCoverageCreateRequest{CoverageLevel: "polygon"}, []string{"geometry"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := validateAreaRequest(&tt.req)
+			if len(errs) != len(tt.invalid) {
+				t.Fatalf("validateAreaRequest() = %v, want errors for %v", errs, tt.invalid)
+			}
+			for _, field := range tt.invalid {
+				if _, ok := errs[field]; !ok {
+					t.Errorf("expected an error for %s, got %v", field, errs)
+				}
+			}
+		})
+	}
+}
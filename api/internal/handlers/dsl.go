@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
 	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+	"github.com/jcom-dev/zmanim-lab/internal/models"
 )
 
 // DSL API Request/Response Types
@@ -32,6 +34,9 @@ type DSLPreviewRequest struct {
 	Longitude  float64 `json:"longitude,omitempty"`
 	Timezone   string  `json:"timezone,omitempty"`  // e.g., "America/New_York"
 	Elevation  float64 `json:"elevation,omitempty"` // Optional elevation in meters
+	Engine     string  `json:"engine,omitempty"`    // Optional solar engine: noaa (default) or spa
+	// Optional publisher location; its coordinates, elevation, timezone and horizon are used
+	PublisherLocationID string `json:"publisher_location_id,omitempty"`
 }
 
 // DSLPreviewResponse represents the response from formula preview/calculation
@@ -116,13 +121,18 @@ func (h *Handlers) PreviewDSLFormula(w http.ResponseWriter, r *http.Request) {
 	if req.Date == "" {
 		validationErrors["date"] = "Date is required"
 	}
+	engine, err := astro.EngineByName(req.Engine)
+	if err != nil {
+		validationErrors["engine"] = err.Error()
+	}
 
-	// Need either location_id or lat/long
+	// Need either location_id, publisher_location_id or lat/long
 	hasLocation := req.LocationID != ""
+	hasPublisherLocation := req.PublisherLocationID != ""
 	hasCoordinates := req.Latitude != 0 || req.Longitude != 0
 
-	if !hasLocation && !hasCoordinates {
-		validationErrors["location"] = "Either location_id or latitude/longitude is required"
+	if !hasLocation && !hasPublisherLocation && !hasCoordinates {
+		validationErrors["location"] = "Either location_id, publisher_location_id or latitude/longitude is required"
 	}
 
 	if len(validationErrors) > 0 {
@@ -140,8 +150,22 @@ func (h *Handlers) PreviewDSLFormula(w http.ResponseWriter, r *http.Request) {
 	// Get location data
 	var latitude, longitude float64
 	var timezone string
+	var location *models.PublisherLocation
 
-	if hasLocation {
+	if hasPublisherLocation {
+		location, err = h.previewPublisherLocation(r, req.PublisherLocationID)
+		if err != nil {
+			slog.Error("failed to fetch location", "error", err, "location_id", req.PublisherLocationID)
+			RespondInternalError(w, r, "Failed to fetch location")
+			return
+		}
+		if location == nil {
+			RespondNotFound(w, r, "Location not found")
+			return
+		}
+		latitude, longitude, timezone = location.Latitude, location.Longitude, location.Timezone
+		req.Elevation = location.ElevationM
+	} else if hasLocation {
 		// Fetch location from database
 		cityQuery := `
 			SELECT latitude, longitude, timezone
@@ -186,6 +210,11 @@ func (h *Handlers) PreviewDSLFormula(w http.ResponseWriter, r *http.Request) {
 
 	// Create execution context
 	execCtx := dsl.NewExecutionContext(date, latitude, longitude, req.Elevation, tz)
+	execCtx.Engine = engine
+	execCtx.Horizon = h.horizonForPoint(ctx, latitude, longitude)
+	if location != nil && locationHorizon(location) != nil {
+		execCtx.Horizon = locationHorizon(location)
+	}
 
 	// Execute the formula with breakdown
 	result, breakdown, err := dsl.ExecuteFormulaWithBreakdown(req.Formula, execCtx)
@@ -225,6 +254,13 @@ type DSLPreviewWeekRequest struct {
 	Longitude  float64 `json:"longitude,omitempty"`
 	Timezone   string  `json:"timezone,omitempty"` // e.g., "America/New_York"
 	Elevation  float64 `json:"elevation,omitempty"`
+	Engine     string  `json:"engine,omitempty"` // Optional solar engine: noaa (default) or spa
+	// Optional publisher location; its coordinates, elevation, timezone and horizon are used
+	PublisherLocationID string `json:"publisher_location_id,omitempty"`
+	// Optional scheduled change preview: days before EffectiveFrom use CurrentFormula,
+	// days from EffectiveFrom on use Formula
+	EffectiveFrom  string `json:"effective_from,omitempty"`  // YYYY-MM-DD
+	CurrentFormula string `json:"current_formula,omitempty"` // Formula in force before EffectiveFrom
 }
 
 // DayPreview represents a single day's calculation result
@@ -237,6 +273,9 @@ type DayPreview struct {
 	Events     []string `json:"events"`      // Jewish holidays, Shabbat, etc.
 	IsShabbat  bool     `json:"is_shabbat"`
 	IsYomTov   bool     `json:"is_yom_tov"`
+	// Set only when previewing a scheduled change
+	FormulaSource string `json:"formula_source,omitempty"` // "current" or "new"
+	IsTransition  bool   `json:"is_transition,omitempty"`  // The day the new formula takes effect
 }
 
 // DSLPreviewWeekResponse represents weekly preview response
@@ -246,7 +285,8 @@ type DSLPreviewWeekResponse struct {
 
 // PreviewDSLFormulaWeek calculates formula for 7 consecutive days
 // @Summary Preview DSL formula for a week
-// @Description Calculates a zmanim formula for 7 consecutive days starting from the specified date, including Hebrew dates and Shabbat/holiday markers
+// @Description Calculates a zmanim formula for 7 consecutive days starting from the specified date, including Hebrew dates and Shabbat/holiday markers.
+// @Description With effective_from and current_formula it previews a scheduled change, marking the transition day.
 // @Tags DSL
 // @Accept json
 // @Produce json
@@ -272,13 +312,25 @@ func (h *Handlers) PreviewDSLFormulaWeek(w http.ResponseWriter, r *http.Request)
 	if req.StartDate == "" {
 		validationErrors["start_date"] = "Start date is required"
 	}
+	engine, err := astro.EngineByName(req.Engine)
+	if err != nil {
+		validationErrors["engine"] = err.Error()
+	}
+	if req.EffectiveFrom != "" {
+		if _, err := time.Parse("2006-01-02", req.EffectiveFrom); err != nil {
+			validationErrors["effective_from"] = "Invalid date format. Use YYYY-MM-DD"
+		} else if req.CurrentFormula == "" {
+			validationErrors["current_formula"] = "Current formula is required with effective_from"
+		}
+	}
 
-	// Need either location_id or lat/long
+	// Need either location_id, publisher_location_id or lat/long
 	hasLocation := req.LocationID != ""
+	hasPublisherLocation := req.PublisherLocationID != ""
 	hasCoordinates := req.Latitude != 0 || req.Longitude != 0
 
-	if !hasLocation && !hasCoordinates {
-		validationErrors["location"] = "Either location_id or latitude/longitude is required"
+	if !hasLocation && !hasPublisherLocation && !hasCoordinates {
+		validationErrors["location"] = "Either location_id, publisher_location_id or latitude/longitude is required"
 	}
 
 	if len(validationErrors) > 0 {
@@ -296,8 +348,22 @@ func (h *Handlers) PreviewDSLFormulaWeek(w http.ResponseWriter, r *http.Request)
 	// Get location data
 	var latitude, longitude float64
 	var timezone string
+	var location *models.PublisherLocation
 
-	if hasLocation {
+	if hasPublisherLocation {
+		location, err = h.previewPublisherLocation(r, req.PublisherLocationID)
+		if err != nil {
+			slog.Error("failed to fetch location", "error", err, "location_id", req.PublisherLocationID)
+			RespondInternalError(w, r, "Failed to fetch location")
+			return
+		}
+		if location == nil {
+			RespondNotFound(w, r, "Location not found")
+			return
+		}
+		latitude, longitude, timezone = location.Latitude, location.Longitude, location.Timezone
+		req.Elevation = location.ElevationM
+	} else if hasLocation {
 		// Fetch location from database
 		cityQuery := `
 			SELECT latitude, longitude, timezone
@@ -327,6 +393,11 @@ func (h *Handlers) PreviewDSLFormulaWeek(w http.ResponseWriter, r *http.Request)
 		tz = time.UTC
 	}
 
+	horizon := h.horizonForPoint(ctx, latitude, longitude)
+	if location != nil && locationHorizon(location) != nil {
+		horizon = locationHorizon(location)
+	}
+
 	// Calculate for 7 days
 	days := []DayPreview{}
 	for i := 0; i < 7; i++ {
@@ -335,14 +406,13 @@ func (h *Handlers) PreviewDSLFormulaWeek(w http.ResponseWriter, r *http.Request)
 
 		// Create execution context for this day
 		execCtx := dsl.NewExecutionContext(currentDate, latitude, longitude, req.Elevation, tz)
+		execCtx.Engine = engine
+		execCtx.Horizon = horizon
 
 		// Calculate sunrise and sunset for reference using DSL
 		sunriseTime, _ := dsl.ExecuteFormula("sunrise", execCtx)
 		sunsetTime, _ := dsl.ExecuteFormula("sunset", execCtx)
 
-		// Execute the formula
-		result, _, err := dsl.ExecuteFormulaWithBreakdown(req.Formula, execCtx)
-
 		dayPreview := DayPreview{
 			Date:       currentDate.Format("2006-01-02"),
 			HebrewDate: formatHebrewDate(currentDate), // Helper function to format Hebrew date
@@ -351,6 +421,21 @@ func (h *Handlers) PreviewDSLFormulaWeek(w http.ResponseWriter, r *http.Request)
 			IsYomTov:   false, // TODO: Implement with hebcal integration
 		}
 
+		// Pick the formula in force on this day when previewing a scheduled change
+		formula := req.Formula
+		if req.EffectiveFrom != "" {
+			if dayPreview.Date < req.EffectiveFrom {
+				formula = req.CurrentFormula
+				dayPreview.FormulaSource = "current"
+			} else {
+				dayPreview.FormulaSource = "new"
+				dayPreview.IsTransition = dayPreview.Date == req.EffectiveFrom
+			}
+		}
+
+		// Execute the formula
+		result, _, err := dsl.ExecuteFormulaWithBreakdown(formula, execCtx)
+
 		if err == nil {
 			dayPreview.Result = result.Format("15:04:05")
 		} else {
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jcom-dev/zmanim-lab/internal/geoaudit"
+)
+
+// Elevation lookups per fix request; the SRTM source only reads tiles already
+// cached, and larger backfills belong in cmd/geo-audit
+const (
+	defaultAuditFixElevations = 500
+	maxAuditFixElevations     = 5000
+)
+
+// parseGeoAuditOptions reads audit options from the query string. A country
+// is required: whole-database audits run longer than a request may, so they
+// are left to cmd/geo-audit.
+func parseGeoAuditOptions(r *http.Request) (geoaudit.Options, error) {
+	q := r.URL.Query()
+	opts := geoaudit.Options{CountryCode: strings.ToUpper(strings.TrimSpace(q.Get("country_code")))}
+	if len(opts.CountryCode) != 2 {
+		return opts, fmt.Errorf("country_code is required (ISO 3166-1 alpha-2); audit every country with cmd/geo-audit")
+	}
+
+	categories, err := geoaudit.ParseCategories(q.Get("checks"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Categories = categories
+
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 500 {
+			return opts, fmt.Errorf("limit must be between 1 and 500")
+		}
+		opts.Limit = n
+	}
+	if m := q.Get("duplicate_meters"); m != "" {
+		n, err := strconv.ParseFloat(m, 64)
+		if err != nil || n <= 0 || n > 5000 {
+			return opts, fmt.Errorf("duplicate_meters must be between 0 and 5000")
+		}
+		opts.DuplicateMeters = n
+	}
+	if k := q.Get("boundary_km"); k != "" {
+		n, err := strconv.ParseFloat(k, 64)
+		if err != nil || n <= 0 || n > 500 {
+			return opts, fmt.Errorf("boundary_km must be between 0 and 500")
+		}
+		opts.BoundaryToleranceKm = n
+	}
+	return opts, nil
+}
+
+// AdminGetGeoAudit audits one country's geographic data
+// @Summary Geographic data-quality audit
+// @Description Checks a country's cities for missing or 0 m elevation, missing or invalid timezones, timezones not in effect in the country, cities outside their region or district boundary, and same-name cities within duplicate_meters. Each category has a count, its worst cases and a suggested fix; safe_fix categories are applied by POST /admin/geo/audit/fix.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param country_code query string true "Country to audit (ISO 3166-1 alpha-2)"
+// @Param checks query string false "Comma-separated categories: missing_elevation, missing_timezone, timezone_country_mismatch, outside_region, outside_district, duplicate_city (default all)"
+// @Param limit query int false "Issues listed per category, worst first (default 50, max 500)"
+// @Param duplicate_meters query number false "Same-name cities closer than this are duplicates (default 500)"
+// @Param boundary_km query number false "Tolerance outside region and district boundaries (default 10)"
+// @Success 200 {object} APIResponse{data=geoaudit.Report} "Audit report"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid parameters"
+// @Failure 404 {object} APIResponse{error=APIError} "Unknown country"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /admin/geo/audit [get]
+func (h *Handlers) AdminGetGeoAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	opts, err := parseGeoAuditOptions(r)
+	if err != nil {
+		RespondBadRequest(w, r, err.Error())
+		return
+	}
+
+	auditor := &geoaudit.Auditor{Pool: h.db.Pool}
+	if err := auditor.ValidateCountry(ctx, opts.CountryCode); err != nil {
+		if errors.Is(err, geoaudit.ErrUnknownCountry) {
+			RespondNotFound(w, r, "Country not found")
+			return
+		}
+		slog.Error("failed to validate audit country", "error", err, "country_code", opts.CountryCode)
+		RespondInternalError(w, r, "Failed to run audit")
+		return
+	}
+
+	report, err := auditor.Run(ctx, opts)
+	if err != nil {
+		slog.Error("geo audit failed", "error", err, "country_code", opts.CountryCode)
+		RespondInternalError(w, r, "Failed to run audit")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, report)
+}
+
+// GeoAuditFixRequest scopes the safe fixes
+type GeoAuditFixRequest struct {
+	CountryCode   string `json:"country_code"`
+	MaxElevations int    `json:"max_elevations"` // default 500, max 5000
+}
+
+// AdminApplyGeoAuditFixes applies the audit's safe fixes to one country
+// @Summary Apply safe geographic data fixes
+// @Description Fills missing or invalid timezones from the timezone boundaries, and missing or 0 m elevations from SRTM tiles already cached on the server (a 0 m elevation is kept where SRTM agrees). Fixes that overwrite or delete data are never applied. Cached zmanim are flushed when anything changed.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body GeoAuditFixRequest true "Country and elevation lookup limit"
+// @Success 200 {object} APIResponse{data=geoaudit.FixResult} "Fixes applied"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure 404 {object} APIResponse{error=APIError} "Unknown country"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /admin/geo/audit/fix [post]
+func (h *Handlers) AdminApplyGeoAuditFixes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req GeoAuditFixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+	req.CountryCode = strings.ToUpper(strings.TrimSpace(req.CountryCode))
+	if len(req.CountryCode) != 2 {
+		RespondBadRequest(w, r, "country_code is required (ISO 3166-1 alpha-2)")
+		return
+	}
+	if req.MaxElevations == 0 {
+		req.MaxElevations = defaultAuditFixElevations
+	}
+	if req.MaxElevations < 0 || req.MaxElevations > maxAuditFixElevations {
+		RespondBadRequest(w, r, fmt.Sprintf("max_elevations must be between 1 and %d", maxAuditFixElevations))
+		return
+	}
+
+	auditor := &geoaudit.Auditor{Pool: h.db.Pool, Elevations: h.elevations}
+	if err := auditor.ValidateCountry(ctx, req.CountryCode); err != nil {
+		if errors.Is(err, geoaudit.ErrUnknownCountry) {
+			RespondNotFound(w, r, "Country not found")
+			return
+		}
+		slog.Error("failed to validate audit country", "error", err, "country_code", req.CountryCode)
+		RespondInternalError(w, r, "Failed to apply fixes")
+		return
+	}
+
+	result, err := auditor.ApplySafeFixes(ctx, geoaudit.FixOptions{
+		CountryCode:   req.CountryCode,
+		MaxElevations: req.MaxElevations,
+	})
+	if err != nil {
+		slog.Error("geo audit fixes failed", "error", err, "country_code", req.CountryCode)
+		RespondInternalError(w, r, "Failed to apply fixes")
+		return
+	}
+
+	slog.Info("geo audit fixes applied", "country_code", req.CountryCode,
+		"timezones_set", result.TimezonesSet, "elevations_set", result.ElevationsSet)
+
+	// Timezone and elevation feed every zmanim calculation for these cities
+	if h.cache != nil && result.TimezonesSet+result.ElevationsSet > 0 {
+		if err := h.cache.FlushAllZmanim(ctx); err != nil {
+			slog.Warn("failed to flush zmanim cache after geo fixes", "error", err)
+		}
+	}
+
+	RespondJSON(w, r, http.StatusOK, result)
+}
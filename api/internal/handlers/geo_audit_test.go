@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jcom-dev/zmanim-lab/internal/geoaudit"
+)
+
+func TestParseGeoAuditOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		check   func(geoaudit.Options) bool
+	}{
+		{"country_code=il", false, func(o geoaudit.Options) bool {
+			return o.CountryCode == "IL" && len(o.Categories) == 0 && o.Limit == 0
+		}},
+		{"country_code=US&checks=missing_elevation,duplicate_city&limit=10&duplicate_meters=250&boundary_km=5", false, func(o geoaudit.Options) bool {
+			return len(o.Categories) == 2 && o.Limit == 10 && o.DuplicateMeters == 250 && o.BoundaryToleranceKm == 5
+		}},
+		{"", true, nil},
+		{"country_code=USA", true, nil},
+		{"country_code=US&checks=bogus", true, nil},
+		{"country_code=US&limit=0", true, nil},
+		{"country_code=US&duplicate_meters=-1", true, nil},
+		{"country_code=US&boundary_km=abc", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			opts, err := parseGeoAuditOptions(httptest.NewRequest("GET", "/admin/geo/audit?"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.check(opts) {
+				t.Errorf("unexpected options: %+v", opts)
+			}
+		})
+	}
+}
+
+// seaShelf is an elevation source with 120 m ground north of 50°S and no
+// data south of it
+type seaShelf struct{}
+
+func (seaShelf) Elevation(lat, lng float64) (float64, error) {
+	if lat < -50 {
+		return math.NaN(), nil
+	}
+	return 120, nil
+}
+
+func TestGeoAuditAndSafeFixes(t *testing.T) {
+	h := newDBTestHandlers(t)
+	helper := NewTestHelper(t)
+	f := newGeoFixture(t, h)
+	h.SetElevationSource(seaShelf{})
+
+	north := f.region("NA", "Northaudit")
+	south := f.region("SA", "Southaudit")
+	f.regionBoundary(north, -126, -48.5, -124, -47.5)
+	f.regionBoundary(south, -126, -53, -124, -51)
+	f.city("Northton", north, -47.9, -125.0, 2000)
+	stray := f.city("Strayville", north, -52.0, -125.0, 500) // ~390 km south of its region
+	duptonA := f.city("Dupton", 0, -48.0, -125.0, 100)
+	duptonB := f.city("Dupton", 0, -48.0003, -125.0, 50) // ~33 m away
+	f.cover(f.publisher("Fixture A"), "city", duptonB, 1)
+
+	audit := func(checks string) geoaudit.Report {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.AdminGetGeoAudit(w, helper.MakeRequest("GET", "/api/v1/admin/geo/audit?country_code="+fixtureGeoCode+"&checks="+checks, nil))
+		helper.AssertStatus(w, http.StatusOK)
+		var resp struct {
+			Data geoaudit.Report `json:"data"`
+		}
+		helper.ParseJSONResponse(w, &resp)
+		return resp.Data
+	}
+
+	report := audit("missing_elevation,outside_region,duplicate_city")
+	if len(report.Categories) != 3 || report.Total != 6 {
+		t.Fatalf("report total %d over %d categories, want 6 over 3: %+v", report.Total, len(report.Categories), report.Categories)
+	}
+	if c := report.Categories[0]; c.Category != geoaudit.MissingElevation || c.Count != 4 || !c.SafeFix {
+		t.Errorf("missing elevation = %+v, want the 4 cities at 0 m", c)
+	}
+	if c := report.Categories[1]; c.Count != 1 || c.Issues[0].CityID != stray || c.Issues[0].RelatedID != strconv.Itoa(int(south)) {
+		t.Errorf("outside region = %+v, want Strayville, suggested for Southaudit", c)
+	}
+	// The covered duplicate is kept
+	if c := report.Categories[2]; c.Count != 1 || c.Issues[0].CityID != duptonA || c.Issues[0].RelatedID != duptonB {
+		t.Errorf("duplicates = %+v, want the uncovered Dupton merged into the covered one", c)
+	}
+
+	w := httptest.NewRecorder()
+	h.AdminApplyGeoAuditFixes(w, helper.MakeRequest("POST", "/api/v1/admin/geo/audit/fix", GeoAuditFixRequest{CountryCode: fixtureGeoCode}))
+	helper.AssertStatus(w, http.StatusOK)
+	var fixed struct {
+		Data geoaudit.FixResult `json:"data"`
+	}
+	helper.ParseJSONResponse(w, &fixed)
+	if r := fixed.Data; r.ElevationsChecked != 4 || r.ElevationsSet != 3 || r.ElevationsUnavailable != 1 {
+		t.Errorf("fix result = %+v, want 3 of 4 elevations set", r)
+	}
+
+	// Only the city without SRTM data is left
+	report = audit("missing_elevation")
+	if c := report.Categories[0]; c.Count != 1 || c.Issues[0].CityID != stray {
+		t.Errorf("missing elevation after fixes = %+v, want Strayville only", c)
+	}
+}
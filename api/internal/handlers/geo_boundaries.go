@@ -8,6 +8,7 @@ import (
 	"strconv"
 
 	"github.com/jcom-dev/zmanim-lab/internal/db/sqlcgen"
+	"github.com/jcom-dev/zmanim-lab/internal/models"
 )
 
 // GeoJSONFeatureCollection represents a GeoJSON FeatureCollection
@@ -31,6 +32,9 @@ type PointLookupResponse struct {
 	Region       *RegionInfo   `json:"region,omitempty"`
 	District     *DistrictInfo `json:"district,omitempty"`
 	NearestCites []NearestCity `json:"nearest_cities,omitempty"`
+
+	// Publishers serving the point, best match per publisher
+	Publishers []models.PublisherForCity `json:"publishers"`
 }
 
 // SmartLookupResponse represents the response from zoom-aware point lookup
@@ -277,7 +281,7 @@ func (h *Handlers) GetDistrictBoundaries(w http.ResponseWriter, r *http.Request)
 
 // LookupPointLocation performs point-in-polygon lookup to find country/region/district at coordinates
 // @Summary Point-in-polygon lookup
-// @Description Given lat/lng coordinates, returns the country, region, and district containing that point, plus nearby cities
+// @Description Given lat/lng coordinates, returns the country, region, and district containing that point, nearby cities, and the publishers whose coverage (including polygon and radius areas) serves it
 // @Tags Geographic Boundaries
 // @Produce json
 // @Param lat query number true "Latitude"
@@ -388,6 +392,15 @@ func (h *Handlers) LookupPointLocation(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Publishers whose coverage includes the point, polygons and radii included
+	publishers, err := h.publishersForPoint(ctx, lat, lng)
+	if err != nil {
+		slog.Error("failed to get publishers for point", "error", err, "lat", lat, "lng", lng)
+		RespondInternalError(w, r, "Failed to look up publishers for point")
+		return
+	}
+	response.Publishers = publishers
+
 	RespondJSON(w, r, http.StatusOK, response)
 }
 
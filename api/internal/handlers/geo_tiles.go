@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jcom-dev/zmanim-lab/internal/cache"
+)
+
+// tileMinZoom is the lowest zoom each boundary layer is drawn at; below it
+// the layer's tiles are empty
+var tileMinZoom = map[string]int{
+	"countries": 0,
+	"regions":   3,
+	"districts": 6,
+	"cities":    8,
+}
+
+// maxTileZoom is the highest zoom tiles are generated at; map clients
+// overzoom beyond it
+const maxTileZoom = 16
+
+// parseTileCoordinates validates a tile's layer and z/x/y
+func parseTileCoordinates(layer, zStr, xStr, yStr string) (z, x, y int, err error) {
+	if _, ok := tileMinZoom[layer]; !ok {
+		return 0, 0, 0, fmt.Errorf("layer must be one of countries, regions, districts or cities")
+	}
+	if z, err = strconv.Atoi(zStr); err != nil || z < 0 || z > maxTileZoom {
+		return 0, 0, 0, fmt.Errorf("zoom must be between 0 and %d", maxTileZoom)
+	}
+	n := 1 << z
+	if x, err = strconv.Atoi(xStr); err != nil || x < 0 || x >= n {
+		return 0, 0, 0, fmt.Errorf("x must be between 0 and %d at zoom %d", n-1, z)
+	}
+	if y, err = strconv.Atoi(yStr); err != nil || y < 0 || y >= n {
+		return 0, 0, 0, fmt.Errorf("y must be between 0 and %d at zoom %d", n-1, z)
+	}
+	return z, x, y, nil
+}
+
+// tileETag returns the strong ETag of a tile's content
+func tileETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBoundaryTile returns a boundary layer as a Mapbox Vector Tile
+// @Summary Get boundary vector tile
+// @Description Returns country, region, district or city boundaries in a z/x/y tile as a Mapbox Vector Tile (one layer named after the path layer), simplified for the zoom. Features carry id, code, name and area_km2; with publisher_id they also carry coverage: full, partial or none. Layers below their minimum zoom (countries 0, regions 3, districts 6, cities 8) and tiles with no features return 204. Responses carry an ETag and honor If-None-Match.
+// @Tags Geographic Boundaries
+// @Produce application/vnd.mapbox-vector-tile
+// @Param layer path string true "Layer: countries, regions, districts or cities"
+// @Param z path int true "Zoom (0-16)"
+// @Param x path int true "Tile column"
+// @Param y path int true "Tile row"
+// @Param publisher_id query string false "Publisher whose coverage status is attached to each feature"
+// @Success 200 {file} binary "Vector tile"
+// @Success 204 "Empty tile"
+// @Success 304 "Tile unchanged"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid layer or tile coordinates"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /geo/tiles/{layer}/{z}/{x}/{y}.mvt [get]
+func (h *Handlers) GetBoundaryTile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	layer := chi.URLParam(r, "layer")
+
+	z, x, y, err := parseTileCoordinates(layer, chi.URLParam(r, "z"), chi.URLParam(r, "x"), chi.URLParam(r, "y"))
+	if err != nil {
+		RespondBadRequest(w, r, err.Error())
+		return
+	}
+
+	publisherID := r.URL.Query().Get("publisher_id")
+	if publisherID != "" {
+		if _, err := uuid.Parse(publisherID); err != nil {
+			RespondBadRequest(w, r, "Invalid publisher_id")
+			return
+		}
+	}
+
+	// Coverage changes invalidate publisher tiles, so they are revalidated
+	// sooner than plain boundaries
+	if publisherID != "" {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+
+	if z < tileMinZoom[layer] {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var entry *cache.TileEntry
+	if h.cache != nil {
+		entry, err = h.cache.GetTile(ctx, publisherID, layer, z, x, y)
+		if err != nil {
+			slog.Warn("failed to read cached tile", "error", err, "layer", layer, "z", z, "x", x, "y", y)
+		}
+	}
+
+	if entry == nil {
+		var publisher *string
+		if publisherID != "" {
+			publisher = &publisherID
+		}
+		var data []byte
+		if err := h.db.Pool.QueryRow(ctx, `SELECT geo_boundary_tile($1, $2, $3, $4, $5::uuid)`,
+			layer, z, x, y, publisher).Scan(&data); err != nil {
+			slog.Error("failed to generate tile", "error", err, "layer", layer, "z", z, "x", x, "y", y)
+			RespondInternalError(w, r, "Failed to generate tile")
+			return
+		}
+		entry = &cache.TileEntry{Data: data, ETag: tileETag(data)}
+
+		if h.cache != nil {
+			if err := h.cache.SetTile(ctx, publisherID, layer, z, x, y, entry); err != nil {
+				slog.Warn("failed to cache tile", "error", err, "layer", layer, "z", z, "x", x, "y", y)
+			}
+		}
+	}
+
+	w.Header().Set("ETag", entry.ETag)
+	if etagMatches(r.Header.Get("If-None-Match"), entry.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if len(entry.Data) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Header().Set("Content-Length", strconv.Itoa(len(entry.Data)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(entry.Data)
+}
+
+// invalidateCoverageTiles clears cached coverage tiles after a publisher's
+// coverage changes
+func (h *Handlers) invalidateCoverageTiles(ctx context.Context, publisherID string) {
+	if h.cache == nil {
+		return
+	}
+	if err := h.cache.InvalidateTiles(ctx, publisherID); err != nil {
+		slog.Warn("failed to invalidate coverage tiles", "error", err, "publisher_id", publisherID)
+	}
+}
+
+// AdminFlushTileCache clears all cached boundary vector tiles
+// DELETE /api/admin/cache/tiles
+func (h *Handlers) AdminFlushTileCache(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if h.cache == nil {
+		RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+			"message": "Cache not configured",
+			"flushed": false,
+		})
+		return
+	}
+
+	if err := h.cache.FlushAllTiles(ctx); err != nil {
+		slog.Error("failed to flush tile cache", "error", err)
+		RespondInternalError(w, r, "Failed to flush cache")
+		return
+	}
+
+	slog.Info("tile cache flushed by admin")
+
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"message": "Tile cache flushed successfully",
+		"flushed": true,
+	})
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestParseTileCoordinates(t *testing.T) {
+	tests := []struct {
+		layer, z, x, y string
+		wantErr        bool
+	}{
+		{"countries", "0", "0", "0", false},
+		{"districts", "10", "1023", "0", false},
+		{"cities", "16", "65535", "65535", false},
+		{"roads", "3", "1", "1", true},
+		{"regions", "17", "0", "0", true},
+		{"regions", "-1", "0", "0", true},
+		{"regions", "2", "4", "0", true},
+		{"regions", "2", "0", "4", true},
+		{"regions", "2", "a", "0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layer+"/"+tt.z+"/"+tt.x+"/"+tt.y, func(t *testing.T) {
+			_, _, _, err := parseTileCoordinates(tt.layer, tt.z, tt.x, tt.y)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseTileCoordinates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	etag := tileETag([]byte("tile"))
+	if etag != tileETag([]byte("tile")) || etag == tileETag([]byte("other")) {
+		t.Fatal("tileETag() is not a content hash")
+	}
+
+	for header, want := range map[string]bool{
+		"":                    false,
+		etag:                  true,
+		"W/" + etag:           true,
+		`"abc", ` + etag:      true,
+		"*":                   true,
+		`"0123456789abcdef"`:  false,
+		`"abc", "0123456789"`: false,
+	} {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestGetBoundaryTileBelowMinZoom(t *testing.T) {
+	h := &Handlers{}
+	r := chi.NewRouter()
+	r.Get("/geo/tiles/{layer}/{z}/{x}/{y}.mvt", h.GetBoundaryTile)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/geo/tiles/districts/2/1/1.mvt", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/geo/tiles/districts/2/1/1.mvt?publisher_id=nope", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid publisher_id status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// tileAt returns the x and y of the z tile containing a point
+func tileAt(lat, lng float64, z int) (int, int) {
+	n := float64(int(1) << z)
+	phi := lat * math.Pi / 180
+	x := (lng + 180) / 360 * n
+	y := (1 - math.Asinh(math.Tan(phi))/math.Pi) / 2 * n
+	return int(x), int(y)
+}
+
+func TestPublisherBoundaryCoverage(t *testing.T) {
+	h := newDBTestHandlers(t)
+	f := newGeoFixture(t, h)
+
+	north := f.region("NT", "Northtile")
+	south := f.region("ST", "Southtile")
+	southCity := f.city("Southtile Town", south, -49.0, -125.0, 1000)
+	a := f.publisher("Fixture A")
+	f.cover(a, "region", north, 1)
+	f.cover(a, "city", southCity, 1)
+
+	status := func(regionID int32) string {
+		t.Helper()
+		var s string
+		if err := h.db.Pool.QueryRow(context.Background(), `
+			SELECT publisher_boundary_coverage($1::uuid, NULL, $2, $3, $4, NULL, NULL)
+		`, a, fixtureGeoCode, f.countryID, regionID).Scan(&s); err != nil {
+			t.Fatalf("publisher_boundary_coverage: %v", err)
+		}
+		return s
+	}
+	other := f.region("OT", "Othertile")
+	for _, tt := range []struct {
+		region int32
+		want   string
+	}{
+		{north, "full"},    // covered at its level
+		{south, "partial"}, // a city inside it is covered
+		{other, "none"},
+	} {
+		if got := status(tt.region); got != tt.want {
+			t.Errorf("region %d coverage = %q, want %q", tt.region, got, tt.want)
+		}
+	}
+
+	// The whole country is partly covered through its regions and city
+	var country string
+	if err := h.db.Pool.QueryRow(context.Background(), `
+		SELECT publisher_boundary_coverage($1::uuid, NULL, $2, $3, NULL, NULL, NULL)
+	`, a, fixtureGeoCode, f.countryID).Scan(&country); err != nil {
+		t.Fatalf("publisher_boundary_coverage: %v", err)
+	}
+	if country != "partial" {
+		t.Errorf("country coverage = %q, want partial", country)
+	}
+}
+
+func TestGetBoundaryTile(t *testing.T) {
+	h := newDBTestHandlers(t)
+	f := newGeoFixture(t, h)
+
+	north := f.region("NT", "Northtile")
+	f.regionBoundary(north, -126, -48.5, -124, -47.5)
+	a := f.publisher("Fixture A")
+	f.cover(a, "region", north, 1)
+
+	const z = 4
+	x, y := tileAt(-48, -125, z)
+	get := func(query, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/api/v1/geo/tiles/regions/4/x/y.mvt"+query, nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("layer", "regions")
+		rctx.URLParams.Add("z", strconv.Itoa(z))
+		rctx.URLParams.Add("x", strconv.Itoa(x))
+		rctx.URLParams.Add("y", strconv.Itoa(y))
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		h.GetBoundaryTile(w, r)
+		return w
+	}
+
+	w := get("", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/vnd.mapbox-vector-tile" {
+		t.Fatalf("tile status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	// MVT stores layer names and string properties verbatim
+	if tile := w.Body.Bytes(); !bytes.Contains(tile, []byte("regions")) || !bytes.Contains(tile, []byte("Northtile")) {
+		t.Errorf("tile does not contain the regions layer with Northtile")
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("tile has no ETag")
+	}
+	if w := get("", etag); w.Code != http.StatusNotModified {
+		t.Errorf("revalidation status %d, want 304", w.Code)
+	}
+
+	w = get("?publisher_id="+a, "")
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("full")) {
+		t.Errorf("publisher tile status %d, want 200 with Northtile covered in full", w.Code)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Error("publisher tile has the same ETag as the plain tile")
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/jcom-dev/zmanim-lab/internal/middleware"
 	"github.com/jcom-dev/zmanim-lab/internal/models"
 	"github.com/jcom-dev/zmanim-lab/internal/services"
+	"github.com/jcom-dev/zmanim-lab/internal/terrain"
 )
 
 // Handlers holds all HTTP handlers
@@ -26,13 +27,19 @@ type Handlers struct {
 	clerkService     *services.ClerkService
 	emailService     *services.EmailService
 	snapshotService  *services.SnapshotService
+	apiKeyService    *services.APIKeyService
+	webhookService   *services.WebhookService
 	// PublisherResolver consolidates publisher ID resolution logic
 	publisherResolver *PublisherResolver
 	// AI services (optional - may be nil if not configured)
 	aiSearch    *ai.SearchService
 	aiContext   *ai.ContextService
-	aiEmbedding *ai.EmbeddingService
+	aiEmbedding ai.Embedder
 	aiClaude    *ai.ClaudeService
+	// horizons caches terrain horizon profiles by location
+	horizons *terrain.Cache
+	// elevations resolves ground elevation for arbitrary points (optional)
+	elevations terrain.ElevationSource
 }
 
 // New creates a new handlers instance
@@ -56,11 +63,12 @@ func New(database *db.DB) *Handlers {
 		emailService:      emailService,
 		snapshotService:   snapshotService,
 		publisherResolver: publisherResolver,
+		horizons:          terrain.NewCache(1000),
 	}
 }
 
 // SetAIServices configures the AI services (optional - services may be nil if not configured)
-func (h *Handlers) SetAIServices(claude *ai.ClaudeService, search *ai.SearchService, context *ai.ContextService, embedding *ai.EmbeddingService) {
+func (h *Handlers) SetAIServices(claude *ai.ClaudeService, search *ai.SearchService, context *ai.ContextService, embedding ai.Embedder) {
 	h.aiClaude = claude
 	h.aiSearch = search
 	h.aiContext = context
@@ -72,6 +80,12 @@ func (h *Handlers) SetCache(c *cache.Cache) {
 	h.cache = c
 }
 
+// SetElevationSource configures the elevation source used for coordinate-only
+// requests (optional - nearest-city elevations are used if nil)
+func (h *Handlers) SetElevationSource(src terrain.ElevationSource) {
+	h.elevations = src
+}
+
 // HealthCheck returns the health status of the API
 // @Summary Health check
 // @Description Returns the health status of the API and database connection
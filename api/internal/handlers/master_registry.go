@@ -91,6 +91,8 @@ type ZmanVersion struct {
 	PublisherZmanID string    `json:"publisher_zman_id"`
 	VersionNumber   int       `json:"version_number"`
 	FormulaDSL      string    `json:"formula_dsl"`
+	EffectiveFrom   *string   `json:"effective_from,omitempty"`
+	EffectiveUntil  *string   `json:"effective_until,omitempty"`
 	CreatedBy       *string   `json:"created_by,omitempty"`
 	CreatedAt       time.Time `json:"created_at"`
 }
@@ -878,7 +880,8 @@ func (h *Handlers) GetZmanVersionHistory(w http.ResponseWriter, r *http.Request)
 
 	rows, err := h.db.Pool.Query(ctx, `
 		SELECT pzv.id, pzv.publisher_zman_id, pzv.version_number,
-			pzv.formula_dsl, pzv.created_by, pzv.created_at
+			pzv.formula_dsl, to_char(pzv.effective_from, 'YYYY-MM-DD'), to_char(pzv.effective_until, 'YYYY-MM-DD'),
+			pzv.created_by, pzv.created_at
 		FROM publisher_zman_versions pzv
 		JOIN publisher_zmanim pz ON pz.id = pzv.publisher_zman_id
 		WHERE pz.publisher_id = $1 AND pz.zman_key = $2
@@ -896,7 +899,7 @@ func (h *Handlers) GetZmanVersionHistory(w http.ResponseWriter, r *http.Request)
 	for rows.Next() {
 		var v ZmanVersion
 		err := rows.Scan(&v.ID, &v.PublisherZmanID, &v.VersionNumber,
-			&v.FormulaDSL, &v.CreatedBy, &v.CreatedAt)
+			&v.FormulaDSL, &v.EffectiveFrom, &v.EffectiveUntil, &v.CreatedBy, &v.CreatedAt)
 		if err != nil {
 			slog.Error("error scanning version", "error", err)
 			continue
@@ -940,12 +943,13 @@ func (h *Handlers) GetZmanVersionDetail(w http.ResponseWriter, r *http.Request)
 	var v ZmanVersion
 	err = h.db.Pool.QueryRow(ctx, `
 		SELECT pzv.id, pzv.publisher_zman_id, pzv.version_number,
-			pzv.formula_dsl, pzv.created_by, pzv.created_at
+			pzv.formula_dsl, to_char(pzv.effective_from, 'YYYY-MM-DD'), to_char(pzv.effective_until, 'YYYY-MM-DD'),
+			pzv.created_by, pzv.created_at
 		FROM publisher_zman_versions pzv
 		JOIN publisher_zmanim pz ON pz.id = pzv.publisher_zman_id
 		WHERE pz.publisher_id = $1 AND pz.zman_key = $2 AND pzv.version_number = $3
 	`, publisherID, zmanKey, version).Scan(&v.ID, &v.PublisherZmanID, &v.VersionNumber,
-		&v.FormulaDSL, &v.CreatedBy, &v.CreatedAt)
+		&v.FormulaDSL, &v.EffectiveFrom, &v.EffectiveUntil, &v.CreatedBy, &v.CreatedAt)
 
 	if err == pgx.ErrNoRows {
 		RespondNotFound(w, r, "Version not found")
@@ -2153,13 +2157,14 @@ func (h *Handlers) loadPrimitivesCache(ctx context.Context) error {
 
 	// Build grouped list
 	categoryMap := make(map[string][]AstronomicalPrimitive)
-	categoryOrder := []string{"horizon", "civil_twilight", "nautical_twilight", "astronomical_twilight", "solar_position"}
+	categoryOrder := []string{"horizon", "civil_twilight", "nautical_twilight", "astronomical_twilight", "solar_position", "lunar"}
 	categoryDisplayNames := map[string]string{
 		"horizon":               "Horizon Events",
 		"civil_twilight":        "Civil Twilight",
 		"nautical_twilight":     "Nautical Twilight",
 		"astronomical_twilight": "Astronomical Twilight",
 		"solar_position":        "Solar Position",
+		"lunar":                 "Lunar",
 	}
 
 	for _, prim := range flatList {
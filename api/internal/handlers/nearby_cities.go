@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+// Nearest-city paging. Deep offsets walk the KNN index past every skipped
+// city, so they are capped; filtered searches are bounded by maxNearbyRadiusKm
+// so a filter nothing matches cannot scan every city.
+const (
+	defaultNearbyLimit = 20
+	maxNearbyLimit     = 100
+	maxNearbyOffset    = 1000
+	maxNearbyRadiusKm  = 500
+)
+
+// compassPoints are the eight compass directions, clockwise from north
+var compassPoints = [...]string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// nearbyCitiesQuery is a parsed k-nearest / within-radius city search
+type nearbyCitiesQuery struct {
+	lat, lng      float64
+	limit, offset int
+	radiusKm      *float64
+	minPopulation *int
+	hasPublisher  bool
+}
+
+// parseCoordinates reads the required lat and lng query parameters
+func parseCoordinates(r *http.Request) (float64, float64, error) {
+	q := r.URL.Query()
+
+	latStr := q.Get("lat")
+	if latStr == "" {
+		return 0, 0, fmt.Errorf("Latitude is required")
+	}
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, fmt.Errorf("Invalid latitude")
+	}
+
+	lngStr := q.Get("lng")
+	if lngStr == "" {
+		return 0, 0, fmt.Errorf("Longitude is required")
+	}
+	lng, err := strconv.ParseFloat(lngStr, 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return 0, 0, fmt.Errorf("Invalid longitude")
+	}
+	return lat, lng, nil
+}
+
+// parseNearbyCitiesQuery reads a nearest-cities search from the query string
+func parseNearbyCitiesQuery(r *http.Request) (nearbyCitiesQuery, error) {
+	var nq nearbyCitiesQuery
+	var err error
+	if nq.lat, nq.lng, err = parseCoordinates(r); err != nil {
+		return nq, err
+	}
+
+	q := r.URL.Query()
+	nq.limit = defaultNearbyLimit
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxNearbyLimit {
+			return nq, fmt.Errorf("limit must be between 1 and %d", maxNearbyLimit)
+		}
+		nq.limit = n
+	}
+	if o := q.Get("offset"); o != "" {
+		n, err := strconv.Atoi(o)
+		if err != nil || n < 0 || n > maxNearbyOffset {
+			return nq, fmt.Errorf("offset must be between 0 and %d", maxNearbyOffset)
+		}
+		nq.offset = n
+	}
+	if rk := q.Get("radius_km"); rk != "" {
+		km, err := strconv.ParseFloat(rk, 64)
+		if err != nil || km <= 0 || km > maxNearbyRadiusKm {
+			return nq, fmt.Errorf("radius_km must be between 0 and %d", maxNearbyRadiusKm)
+		}
+		nq.radiusKm = &km
+	}
+	if p := q.Get("min_population"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nq, fmt.Errorf("min_population must be a non-negative integer")
+		}
+		nq.minPopulation = &n
+	}
+	if hp := q.Get("has_publisher"); hp != "" {
+		b, err := strconv.ParseBool(hp)
+		if err != nil {
+			return nq, fmt.Errorf("has_publisher must be true or false")
+		}
+		nq.hasPublisher = b
+	}
+
+	// Filters may skip any number of cities, so bound how far out they look
+	if nq.radiusKm == nil && (nq.hasPublisher || nq.minPopulation != nil) {
+		km := float64(maxNearbyRadiusKm)
+		nq.radiusKm = &km
+	}
+	return nq, nil
+}
+
+// initialBearing returns the great-circle bearing from one point to another in
+// degrees clockwise from north, in [0, 360)
+func initialBearing(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dLng := (lng2 - lng1) * math.Pi / 180
+
+	y := math.Sin(dLng) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// compassDirection returns the compass point nearest a bearing
+func compassDirection(bearing float64) string {
+	return compassPoints[int(math.Mod(bearing+22.5, 360)/45)%len(compassPoints)]
+}
+
+// GetNearestCities returns the cities nearest a point
+// @Summary Get nearest cities
+// @Description Returns cities nearest a point, closest first, with distance, bearing and the number of publishers serving each. radius_km limits the search to a circle; has_publisher keeps only cities some publisher serves (through city, area, district, region, country or continent coverage) and min_population drops smaller places. Filtered searches look at most 500 km out. Page with limit and offset; has_more is set when another page follows.
+// @Tags Cities
+// @Produce json
+// @Param lat query number true "Latitude (-90 to 90)"
+// @Param lng query number true "Longitude (-180 to 180)"
+// @Param radius_km query number false "Only cities within this distance (max 500)"
+// @Param has_publisher query bool false "Only cities served by at least one publisher"
+// @Param min_population query int false "Only cities with at least this population"
+// @Param limit query int false "Cities per page (default 20, max 100)"
+// @Param offset query int false "Cities to skip (max 1000)"
+// @Success 200 {object} APIResponse{data=models.NearbyCitiesResponse} "Nearest cities"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid parameters"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Failure 503 {object} APIResponse{error=APIError} "Search timed out"
+// @Router /cities/nearest [get]
+func (h *Handlers) GetNearestCities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	nq, err := parseNearbyCitiesQuery(r)
+	if err != nil {
+		RespondBadRequest(w, r, err.Error())
+		return
+	}
+
+	cities, hasMore, err := h.findNearestCities(ctx, nq)
+	if err != nil {
+		if isQueryCanceled(err) {
+			RespondServiceUnavailable(w, r, "Search took too long; try a smaller radius_km")
+			return
+		}
+		slog.Error("failed to find nearest cities", "error", err, "lat", nq.lat, "lng", nq.lng)
+		RespondInternalError(w, r, "Failed to find nearest cities")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, models.NearbyCitiesResponse{
+		Cities:      cities,
+		Limit:       nq.limit,
+		Offset:      nq.offset,
+		HasMore:     hasMore,
+		SearchedLat: nq.lat,
+		SearchedLng: nq.lng,
+		RadiusKm:    nq.radiusKm,
+	})
+}
+
+// nearestCitiesTimeout bounds the query; anonymous callers can reach it
+const nearestCitiesTimeout = "5s"
+
+// findNearestCities runs a nearest-cities search, returning one page and
+// whether another follows.
+//
+// ORDER BY <-> against a constant point walks idx_geo_cities_location nearest
+// first. has_publisher is checked with a plain EXISTS over active coverage
+// (the same levels get_publishers_for_city matches), and the per-city
+// publisher count, which calls that function, is computed for the returned
+// page only.
+func (h *Handlers) findNearestCities(ctx context.Context, nq nearbyCitiesQuery) ([]models.NearbyCity, bool, error) {
+	var radiusM *float64
+	if nq.radiusKm != nil {
+		m := *nq.radiusKm * 1000
+		radiusM = &m
+	}
+
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // read-only; the transaction only scopes the timeout
+	if _, err := tx.Exec(ctx, "SET LOCAL statement_timeout = '"+nearestCitiesTimeout+"'"); err != nil {
+		return nil, false, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		WITH page AS (
+			SELECT c.id, c.name, c.country_id, c.region_id, c.continent_id,
+			       c.latitude, c.longitude, c.timezone, c.population, c.elevation_m,
+			       ST_Distance(c.location, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography) AS distance_m
+			FROM geo_cities c
+			WHERE ($3::float8 IS NULL OR ST_DWithin(c.location, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography, $3))
+			  AND ($4::int IS NULL OR c.population >= $4)
+			  AND (NOT $5::bool OR EXISTS (
+				SELECT 1
+				FROM publisher_coverage pc
+				JOIN publishers p ON p.id = pc.publisher_id AND p.status = 'active'
+				WHERE pc.is_active
+				  AND (
+					(pc.coverage_level = 'city' AND pc.city_id = c.id)
+					OR (pc.coverage_level IN ('polygon', 'radius') AND ST_Covers(pc.area, c.location))
+					OR (pc.coverage_level = 'district' AND pc.district_id = c.district_id)
+					OR (pc.coverage_level = 'region' AND pc.region_id = c.region_id)
+					OR (pc.coverage_level = 'country' AND pc.country_id = c.country_id)
+					OR (pc.coverage_level = 'continent' AND pc.continent_code = (
+						SELECT ct.code FROM geo_countries co JOIN geo_continents ct ON ct.id = co.continent_id
+						WHERE co.id = c.country_id))
+				  )
+			  ))
+			ORDER BY c.location <-> ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography
+			LIMIT $6 OFFSET $7
+		)
+		SELECT page.id::text, page.name, COALESCE(co.name, ''), COALESCE(co.code, ''), r.name, ct.code,
+		       page.latitude, page.longitude, page.timezone, page.population, page.elevation_m, page.distance_m,
+		       (SELECT count(*)::int FROM get_publishers_for_city(page.id))
+		FROM page
+		LEFT JOIN geo_countries co ON co.id = page.country_id
+		LEFT JOIN geo_continents ct ON ct.id = page.continent_id
+		LEFT JOIN geo_regions r ON r.id = page.region_id
+		ORDER BY page.distance_m, page.id
+	`, nq.lat, nq.lng, radiusM, nq.minPopulation, nq.hasPublisher, nq.limit+1, nq.offset)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	cities := []models.NearbyCity{}
+	for rows.Next() {
+		var nc models.NearbyCity
+		var region, continent *string
+		var population, elevation *int32
+		var distanceMeters float64
+		if err := rows.Scan(&nc.City.ID, &nc.City.Name, &nc.City.Country, &nc.City.CountryCode, &region, &continent,
+			&nc.City.Latitude, &nc.City.Longitude, &nc.City.Timezone, &population, &elevation,
+			&distanceMeters, &nc.PublisherCount); err != nil {
+			return nil, false, err
+		}
+		nc.City.Region, nc.City.Continent = region, continent
+		if population != nil {
+			pop := int(*population)
+			nc.City.Population = &pop
+		}
+		if elevation != nil {
+			elev := int(*elevation)
+			nc.City.Elevation = &elev
+		}
+		nc.City.DisplayName = buildDisplayName(nc.City)
+
+		nc.DistanceKm = math.Round(distanceMeters/1000*10) / 10
+		nc.DistanceMiles = math.Round(distanceMeters/1609.34*10) / 10
+		bearing := initialBearing(nq.lat, nq.lng, nc.City.Latitude, nc.City.Longitude)
+		nc.BearingDeg = math.Round(bearing*10) / 10
+		nc.Direction = compassDirection(bearing)
+		cities = append(cities, nc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	// One extra row was fetched to tell whether another page follows
+	hasMore := len(cities) > nq.limit
+	if hasMore {
+		cities = cities[:nq.limit]
+	}
+	return cities, hasMore, nil
+}
+
+// isQueryCanceled reports whether err is a statement timeout or cancellation
+func isQueryCanceled(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "57014"
+}
+
+// GetPublishersForPoint returns publishers serving a coordinate
+// @Summary Get publishers for a point
+// @Description Returns the publishers serving a coordinate, best match per publisher. The point is placed in the coverage hierarchy by boundary polygons, falling back to the nearest city within 50 km, and matched against city, polygon or radius area, district, region, country and continent coverage.
+// @Tags Publishers
+// @Produce json
+// @Param lat query number true "Latitude (-90 to 90)"
+// @Param lng query number true "Longitude (-180 to 180)"
+// @Success 200 {object} APIResponse{data=models.PublishersForPointResponse} "Publishers serving this point"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid coordinates"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publishers/at-point [get]
+func (h *Handlers) GetPublishersForPoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	lat, lng, err := parseCoordinates(r)
+	if err != nil {
+		RespondBadRequest(w, r, err.Error())
+		return
+	}
+
+	publishers, err := h.publishersForPoint(ctx, lat, lng)
+	if err != nil {
+		slog.Error("failed to get publishers for point", "error", err, "lat", lat, "lng", lng)
+		RespondInternalError(w, r, "Failed to fetch publishers for point")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, models.PublishersForPointResponse{
+		Publishers: publishers,
+		Total:      len(publishers),
+		Latitude:   lat,
+		Longitude:  lng,
+	})
+}
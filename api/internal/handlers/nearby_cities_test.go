@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+func TestParseNearbyCitiesQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		check   func(nearbyCitiesQuery) bool
+	}{
+		{"lat=31.78&lng=35.22", false, func(q nearbyCitiesQuery) bool {
+			return q.limit == defaultNearbyLimit && q.offset == 0 && q.radiusKm == nil && !q.hasPublisher
+		}},
+		{"lat=40.7&lng=-74&radius_km=30&has_publisher=true&limit=5&offset=10", false, func(q nearbyCitiesQuery) bool {
+			return q.radiusKm != nil && *q.radiusKm == 30 && q.hasPublisher && q.limit == 5 && q.offset == 10
+		}},
+		{"lat=40.7&lng=-74&min_population=1000", false, func(q nearbyCitiesQuery) bool {
+			return q.minPopulation != nil && *q.minPopulation == 1000 && q.radiusKm != nil && *q.radiusKm == maxNearbyRadiusKm
+		}},
+		{"lng=35.22", true, nil},
+		{"lat=91&lng=0", true, nil},
+		{"lat=0&lng=abc", true, nil},
+		{"lat=0&lng=0&limit=0", true, nil},
+		{"lat=0&lng=0&limit=101", true, nil},
+		{"lat=0&lng=0&offset=-1", true, nil},
+		{"lat=0&lng=0&offset=1001", true, nil},
+		{"lat=0&lng=0&radius_km=0", true, nil},
+		{"lat=0&lng=0&radius_km=501", true, nil},
+		{"lat=0&lng=0&min_population=-5", true, nil},
+		{"lat=0&lng=0&has_publisher=maybe", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseNearbyCitiesQuery(httptest.NewRequest("GET", "/cities/nearest?"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.check(q) {
+				t.Errorf("unexpected query: %+v", q)
+			}
+		})
+	}
+}
+
+func TestInitialBearing(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{"due north", 0, 0, 10, 0, 0},
+		{"due east", 0, 0, 0, 10, 90},
+		{"due south", 10, 0, 0, 0, 180},
+		{"due west", 0, 10, 0, 0, 270},
+		{"Jerusalem to Tel Aviv", 31.7683, 35.2137, 32.0853, 34.7818, 311.4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := initialBearing(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("initialBearing() = %.1f, want %.1f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompassDirection(t *testing.T) {
+	for bearing, want := range map[float64]string{
+		0: "N", 22.4: "N", 22.5: "NE", 90: "E", 180: "S", 225: "SW", 311.4: "NW", 337.5: "N", 359.9: "N",
+	} {
+		if got := compassDirection(bearing); got != want {
+			t.Errorf("compassDirection(%v) = %q, want %q", bearing, got, want)
+		}
+	}
+}
+
+func TestGetNearestCitiesAndPublishersForPoint(t *testing.T) {
+	h := newDBTestHandlers(t)
+	helper := NewTestHelper(t)
+	f := newGeoFixture(t, h)
+
+	// Three cities east, north and south of a point in the open ocean, far
+	// from any real city
+	const lat, lng = "-48.0", "-125.0"
+	alpha := f.city("Alpha", 0, -48.0, -124.9, 100)     // ~7.5 km E
+	bravo := f.city("Bravo", 0, -47.9, -125.0, 5000)    // ~11.1 km N
+	charlie := f.city("Charlie", 0, -48.2, -125.0, 300) // ~22.2 km S
+	a := f.publisher("Fixture A")
+	f.cover(a, "city", bravo, 1)
+
+	nearest := func(query string) models.NearbyCitiesResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.GetNearestCities(w, helper.MakeRequest("GET", "/api/v1/cities/nearest?lat="+lat+"&lng="+lng+"&"+query, nil))
+		helper.AssertStatus(w, http.StatusOK)
+		var resp struct {
+			Data models.NearbyCitiesResponse `json:"data"`
+		}
+		helper.ParseJSONResponse(w, &resp)
+		return resp.Data
+	}
+	ids := func(cities []models.NearbyCity) []string {
+		out := make([]string, len(cities))
+		for i, c := range cities {
+			out[i] = c.City.ID
+		}
+		return out
+	}
+
+	page := nearest("radius_km=50&limit=2")
+	if got := ids(page.Cities); len(got) != 2 || got[0] != alpha || got[1] != bravo || !page.HasMore {
+		t.Fatalf("first page = %v (has_more %v), want Alpha, Bravo and more", got, page.HasMore)
+	}
+	east, north := page.Cities[0], page.Cities[1]
+	if east.Direction != "E" || math.Abs(east.DistanceKm-7.5) > 0.2 || east.PublisherCount != 0 {
+		t.Errorf("Alpha = %+v, want ~7.5 km E with no publishers", east)
+	}
+	if north.Direction != "N" || math.Abs(north.DistanceKm-11.1) > 0.2 || north.PublisherCount != 1 {
+		t.Errorf("Bravo = %+v, want ~11.1 km N with one publisher", north)
+	}
+
+	page = nearest("radius_km=50&limit=2&offset=2")
+	if got := ids(page.Cities); len(got) != 1 || got[0] != charlie || page.HasMore || page.Cities[0].Direction != "S" {
+		t.Errorf("second page = %v (has_more %v), want Charlie only", got, page.HasMore)
+	}
+
+	if got := ids(nearest("radius_km=10").Cities); len(got) != 1 || got[0] != alpha {
+		t.Errorf("within 10 km = %v, want Alpha", got)
+	}
+	if got := ids(nearest("has_publisher=true").Cities); len(got) != 1 || got[0] != bravo {
+		t.Errorf("has_publisher = %v, want Bravo", got)
+	}
+	if got := ids(nearest("min_population=200").Cities); len(got) != 2 || got[0] != bravo || got[1] != charlie {
+		t.Errorf("min_population=200 = %v, want Bravo, Charlie", got)
+	}
+
+	// A point is placed at its nearest city when no boundary covers it
+	atPoint := func(lat, lng string) []models.PublisherForCity {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.GetPublishersForPoint(w, helper.MakeRequest("GET", "/api/v1/publishers/at-point?lat="+lat+"&lng="+lng, nil))
+		helper.AssertStatus(w, http.StatusOK)
+		var resp struct {
+			Data models.PublishersForPointResponse `json:"data"`
+		}
+		helper.ParseJSONResponse(w, &resp)
+		return resp.Data.Publishers
+	}
+	if got := atPoint("-47.901", "-125.0"); len(got) != 1 || got[0].PublisherID != a || got[0].MatchType != "exact_city" {
+		t.Errorf("publishers near Bravo = %+v, want A by city", got)
+	}
+	if got := atPoint("-48.199", "-125.0"); len(got) != 0 {
+		t.Errorf("publishers near Charlie = %+v, want none", got)
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/calendar"
+)
+
+// Data sources reported in LocationResolution.Sources
+const (
+	sourceTimezoneBoundaries = "timezone-boundary-builder"
+	sourceCountryBoundaries  = "geo_country_boundaries"
+	sourceSRTM               = "srtm"
+	sourceNearestCity        = "nearest-city"
+	sourceCity               = "city"
+	sourceBoundingBox        = "bounding-box"
+	sourceDefault            = "default"
+)
+
+// LocationResolution describes how a bare coordinate was resolved into the
+// timezone, elevation and Israel/diaspora status used for a calculation
+type LocationResolution struct {
+	Timezone    string                    `json:"timezone"`
+	Elevation   float64                   `json:"elevation_m"`
+	IsIsrael    bool                      `json:"is_israel"`
+	NearestCity *NearestCityInfo          `json:"nearest_city,omitempty"`
+	Sources     LocationResolutionSources `json:"sources"`
+}
+
+// LocationResolutionSources names the dataset each resolved value came from
+type LocationResolutionSources struct {
+	Timezone  string `json:"timezone"`  // timezone-boundary-builder, nearest-city or default (UTC)
+	Elevation string `json:"elevation"` // srtm, nearest-city or default (sea level)
+	Israel    string `json:"israel"`    // geo_country_boundaries or bounding-box
+}
+
+// NearestCityInfo is the closest city to a coordinate, for display
+type NearestCityInfo struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Country    string  `json:"country,omitempty"`
+	Region     *string `json:"region,omitempty"`
+	DistanceKm float64 `json:"distance_km"`
+	timezone   string
+	elevation  *int
+}
+
+// resolvePoint resolves a coordinate using the offline datasets, falling back
+// to the nearest city and then to defaults when a dataset has no answer
+func (h *Handlers) resolvePoint(ctx context.Context, latitude, longitude float64) (*LocationResolution, error) {
+	res := &LocationResolution{Timezone: "UTC"}
+	res.Sources.Timezone = sourceDefault
+	res.Sources.Elevation = sourceDefault
+
+	nearest, err := h.nearestCity(ctx, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+	res.NearestCity = nearest
+
+	// Timezone: boundary polygon, else nearest city
+	tzid, err := h.timezoneAt(ctx, latitude, longitude)
+	if err != nil {
+		slog.Warn("timezone boundary lookup failed", "error", err, "latitude", latitude, "longitude", longitude)
+	}
+	switch {
+	case tzid != "":
+		res.Timezone = tzid
+		res.Sources.Timezone = sourceTimezoneBoundaries
+	case nearest != nil && nearest.timezone != "":
+		res.Timezone = nearest.timezone
+		res.Sources.Timezone = sourceNearestCity
+	}
+
+	// Elevation: SRTM, else nearest city
+	var cityElevation *int
+	if nearest != nil {
+		cityElevation = nearest.elevation
+	}
+	res.Elevation, res.Sources.Elevation = h.elevationAt(latitude, longitude, cityElevation, sourceNearestCity)
+
+	res.IsIsrael, res.Sources.Israel = h.israelStatus(ctx, latitude, longitude)
+
+	return res, nil
+}
+
+// israelStatus decides whether a point follows the Israeli calendar from the
+// country boundary, else the legacy bounding box, and names the source used
+func (h *Handlers) israelStatus(ctx context.Context, latitude, longitude float64) (bool, string) {
+	inside, found, err := h.inIsraelBoundary(ctx, latitude, longitude)
+	if err != nil {
+		slog.Warn("country boundary lookup failed", "error", err, "latitude", latitude, "longitude", longitude)
+	}
+	if found {
+		return inside, sourceCountryBoundaries
+	}
+	return calendar.IsLocationInIsrael(latitude, longitude), sourceBoundingBox
+}
+
+// nearestCity returns the closest city to a point, or nil if there are none
+func (h *Handlers) nearestCity(ctx context.Context, latitude, longitude float64) (*NearestCityInfo, error) {
+	c := &NearestCityInfo{}
+	var distanceM float64
+	err := h.db.Pool.QueryRow(ctx, `
+		SELECT c.id, c.name, COALESCE(co.name, ''), r.name, c.timezone, c.elevation_m,
+		       ST_Distance(c.location, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography)
+		FROM geo_cities c
+		LEFT JOIN geo_countries co ON co.id = c.country_id
+		LEFT JOIN geo_regions r ON r.id = c.region_id
+		ORDER BY c.location <-> ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography
+		LIMIT 1
+	`, latitude, longitude).Scan(&c.ID, &c.Name, &c.Country, &c.Region, &c.timezone, &c.elevation, &distanceM)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.DistanceKm = math.Round(distanceM/100) / 10
+	return c, nil
+}
+
+// timezoneAt returns the IANA timezone whose boundary contains a point, or ""
+func (h *Handlers) timezoneAt(ctx context.Context, latitude, longitude float64) (string, error) {
+	var tzid string
+	err := h.db.Pool.QueryRow(ctx, `
+		SELECT tzid
+		FROM geo_timezone_boundaries
+		WHERE ST_Intersects(boundary, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography)
+		LIMIT 1
+	`, latitude, longitude).Scan(&tzid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return tzid, err
+}
+
+// inIsraelBoundary reports whether a point is inside Israel's country
+// boundary. found is false when no boundary for Israel has been imported.
+func (h *Handlers) inIsraelBoundary(ctx context.Context, latitude, longitude float64) (inside, found bool, err error) {
+	err = h.db.Pool.QueryRow(ctx, `
+		SELECT COUNT(*) > 0,
+		       COALESCE(bool_or(ST_Covers(b.boundary, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography)), false)
+		FROM geo_country_boundaries b
+		JOIN geo_countries co ON co.id = b.country_id
+		WHERE co.code = 'IL'
+	`, latitude, longitude).Scan(&found, &inside)
+	return inside, found, err
+}
+
+// elevationAt returns the SRTM ground elevation at a point, else a city's
+// recorded elevation (named by citySource), else sea level, and the source used
+func (h *Handlers) elevationAt(latitude, longitude float64, cityElevation *int, citySource string) (float64, string) {
+	if elevation, ok := h.srtmElevation(latitude, longitude); ok {
+		return elevation, sourceSRTM
+	}
+	if cityElevation != nil {
+		return float64(*cityElevation), citySource
+	}
+	return 0, sourceDefault
+}
+
+// srtmElevation reads the ground elevation from SRTM if a tile is available
+func (h *Handlers) srtmElevation(latitude, longitude float64) (float64, bool) {
+	if h.elevations == nil {
+		return 0, false
+	}
+	elevation, err := h.elevations.Elevation(latitude, longitude)
+	if err != nil || math.IsNaN(elevation) {
+		return 0, false
+	}
+	return elevation, true
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jcom-dev/zmanim-lab/internal/algorithm"
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
 )
 
 // AlgorithmResponse represents the algorithm configuration response
@@ -627,6 +628,13 @@ func (h *Handlers) PublishAlgorithm(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	h.emitWebhookEvent(ctx, publisherID, webhooks.EventAlgorithmPublished, map[string]interface{}{
+		"algorithm_id": algID,
+		"name":         algName,
+		"version":      newVersion,
+		"published_at": publishedAt,
+	})
+
 	RespondJSON(w, r, http.StatusOK, AlgorithmResponse{
 		ID:            algID,
 		Name:          algName,
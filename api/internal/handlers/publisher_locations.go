@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+	"github.com/jcom-dev/zmanim-lab/internal/middleware"
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+	"github.com/jcom-dev/zmanim-lab/internal/terrain"
+)
+
+// PublisherLocationRequest creates or replaces a publisher location. Omitted
+// elevation and timezone are resolved from SRTM and the timezone boundaries.
+type PublisherLocationRequest struct {
+	Name             string    `json:"name" example:"Beit Knesset Har Nof"`
+	Description      *string   `json:"description,omitempty"`
+	Latitude         float64   `json:"latitude" example:"31.7857"`
+	Longitude        float64   `json:"longitude" example:"35.1781"`
+	ElevationM       *float64  `json:"elevation_m,omitempty" example:"810"`
+	Timezone         string    `json:"timezone,omitempty" example:"Asia/Jerusalem"`
+	HorizonAltitudes []float64 `json:"horizon_altitudes,omitempty"`
+}
+
+// validate checks the request against the ranges the database accepts
+func (req PublisherLocationRequest) validate() map[string]string {
+	errs := make(map[string]string)
+	if name := strings.TrimSpace(req.Name); name == "" || len(name) > 200 {
+		errs["name"] = "is required and must be at most 200 characters"
+	}
+	if req.Latitude < -90 || req.Latitude > 90 {
+		errs["latitude"] = "must be between -90 and 90"
+	}
+	if req.Longitude < -180 || req.Longitude > 180 {
+		errs["longitude"] = "must be between -180 and 180"
+	}
+	if req.ElevationM != nil && (*req.ElevationM < -500 || *req.ElevationM > 9000) {
+		errs["elevation_m"] = "must be between -500 and 9000 meters"
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			errs["timezone"] = "must be an IANA timezone name"
+		}
+	}
+	if n := len(req.HorizonAltitudes); n > 360 {
+		errs["horizon_altitudes"] = "must have at most 360 entries"
+	}
+	for _, alt := range req.HorizonAltitudes {
+		if alt < -5 || alt > 45 {
+			errs["horizon_altitudes"] = "altitudes must be between -5 and 45 degrees"
+			break
+		}
+	}
+	return errs
+}
+
+// locationHorizon returns a location's horizon override, or nil if it has none
+func locationHorizon(l *models.PublisherLocation) astro.Horizon {
+	if len(l.HorizonAltitudes) == 0 {
+		return nil
+	}
+	return &terrain.Profile{
+		Latitude:          l.Latitude,
+		Longitude:         l.Longitude,
+		GroundElevation:   l.ElevationM,
+		ObserverElevation: l.ElevationM,
+		AzimuthStep:       360 / float64(len(l.HorizonAltitudes)),
+		Altitudes:         l.HorizonAltitudes,
+	}
+}
+
+// locationCalculationSettings loads a publisher's engine and applies the
+// location's elevation and horizon, falling back to the SRTM horizon
+func (h *Handlers) locationCalculationSettings(ctx context.Context, publisherID string, l *models.PublisherLocation) calculationSettings {
+	settings := calculationSettings{
+		engine:    h.publisherSolarEngine(ctx, publisherID),
+		elevation: l.ElevationM,
+		horizon:   locationHorizon(l),
+	}
+	if settings.horizon == nil {
+		settings.horizon = h.horizonForPoint(ctx, l.Latitude, l.Longitude)
+	}
+	return settings
+}
+
+const publisherLocationColumns = `
+	id, publisher_id, name, description, latitude, longitude, elevation_m,
+	timezone, horizon_altitudes, created_at, updated_at`
+
+func scanPublisherLocation(row pgx.Row) (*models.PublisherLocation, error) {
+	l := &models.PublisherLocation{}
+	err := row.Scan(&l.ID, &l.PublisherID, &l.Name, &l.Description, &l.Latitude, &l.Longitude,
+		&l.ElevationM, &l.Timezone, &l.HorizonAltitudes, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// fetchPublisherLocation loads one of publisherID's locations, or nil if it
+// does not exist or belongs to another publisher
+func (h *Handlers) fetchPublisherLocation(ctx context.Context, publisherID, id string) (*models.PublisherLocation, error) {
+	if _, err := uuid.Parse(id); err != nil || publisherID == "" {
+		return nil, nil
+	}
+	l, err := scanPublisherLocation(h.db.Pool.QueryRow(ctx, `
+		SELECT `+publisherLocationColumns+`
+		FROM publisher_locations
+		WHERE id = $1 AND publisher_id::text = $2
+	`, id, publisherID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return l, err
+}
+
+// publishedLocationCondition matches locations their publisher covers, which
+// makes them public; other locations are visible only to their publisher
+const publishedLocationCondition = `EXISTS (
+			SELECT 1 FROM publisher_coverage pc
+			WHERE pc.location_id = publisher_locations.id
+			  AND pc.publisher_id = publisher_locations.publisher_id
+			  AND pc.coverage_level = 'location'
+			  AND pc.is_active
+		)`
+
+// fetchVisibleLocation loads a location for the public zmanim endpoint: a
+// published location, or any location of the caller's own publisher
+func (h *Handlers) fetchVisibleLocation(r *http.Request, id string) (*models.PublisherLocation, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, nil
+	}
+	l, err := scanPublisherLocation(h.db.Pool.QueryRow(r.Context(), `
+		SELECT `+publisherLocationColumns+`
+		FROM publisher_locations
+		WHERE id = $1 AND (publisher_id::text = $2 OR `+publishedLocationCondition+`)
+	`, id, locationOwner(r, r.Header.Get("X-Publisher-Id"))))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return l, err
+}
+
+// locationOwner returns the publisher whose unpublished locations the request
+// may see: requested (or the user's primary publisher when requested is
+// empty) if the authenticated user has access to it, otherwise ""
+func locationOwner(r *http.Request, requested string) string {
+	if middleware.GetUserID(r.Context()) == "" {
+		return ""
+	}
+	return middleware.GetValidatedPublisherID(r.Context(), requested)
+}
+
+// searchPublisherLocations finds a publisher's locations by name, only the
+// published ones unless includeUnpublished is set
+func (h *Handlers) searchPublisherLocations(ctx context.Context, publisherID, search string, limit int32, includeUnpublished bool) ([]models.PublisherLocation, error) {
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT `+publisherLocationColumns+`
+		FROM publisher_locations
+		WHERE publisher_id::text = $1
+		  AND (name ILIKE '%' || $2 || '%' OR similarity(name, $2) > 0.3)
+		  AND ($4 OR `+publishedLocationCondition+`)
+		ORDER BY similarity(name, $2) DESC, name
+		LIMIT $3
+	`, publisherID, search, limit, includeUnpublished)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []models.PublisherLocation{}
+	for rows.Next() {
+		l, err := scanPublisherLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, *l)
+	}
+	return locations, rows.Err()
+}
+
+// requestPublisherLocation loads the publisher location named by the
+// location_id query parameter, or nil if there is none. It responds and
+// returns false if the location cannot be loaded.
+func (h *Handlers) requestPublisherLocation(w http.ResponseWriter, r *http.Request, publisherID string) (*models.PublisherLocation, bool) {
+	id := r.URL.Query().Get("location_id")
+	if id == "" {
+		return nil, true
+	}
+	l, err := h.fetchPublisherLocation(r.Context(), publisherID, id)
+	if err != nil {
+		slog.Error("failed to fetch location", "error", err, "publisher_id", publisherID, "location_id", id)
+		RespondInternalError(w, r, "Failed to fetch location")
+		return nil, false
+	}
+	if l == nil {
+		RespondNotFound(w, r, "Location not found")
+		return nil, false
+	}
+	return l, true
+}
+
+// previewPublisherLocation loads a location for the preview endpoints. Only
+// the authenticated publisher's own locations can be previewed.
+func (h *Handlers) previewPublisherLocation(r *http.Request, id string) (*models.PublisherLocation, error) {
+	return h.fetchPublisherLocation(r.Context(), locationOwner(r, r.Header.Get("X-Publisher-Id")), id)
+}
+
+// resolveLocationRequest fills in an omitted elevation and timezone from the
+// offline datasets
+func (h *Handlers) resolveLocationRequest(ctx context.Context, req *PublisherLocationRequest) error {
+	if req.ElevationM != nil && req.Timezone != "" {
+		return nil
+	}
+	res, err := h.resolvePoint(ctx, req.Latitude, req.Longitude)
+	if err != nil {
+		return err
+	}
+	if req.ElevationM == nil {
+		elevation := res.Elevation
+		req.ElevationM = &elevation
+	}
+	if req.Timezone == "" {
+		req.Timezone = res.Timezone
+	}
+	return nil
+}
+
+// invalidateLocationCache clears cached zmanim calculated for a changed location
+func (h *Handlers) invalidateLocationCache(ctx context.Context, publisherID string) {
+	if h.cache == nil {
+		return
+	}
+	if err := h.cache.InvalidatePublisherCache(ctx, publisherID); err != nil {
+		slog.Warn("failed to invalidate cache after location change", "error", err, "publisher_id", publisherID)
+	}
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// ListPublisherLocations returns the publisher's locations
+// @Summary List publisher locations
+// @Description Returns the named locations the publisher has defined
+// @Tags Publisher
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Success 200 {object} APIResponse{data=[]models.PublisherLocation} "Publisher locations"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/locations [get]
+func (h *Handlers) ListPublisherLocations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT `+publisherLocationColumns+`
+		FROM publisher_locations
+		WHERE publisher_id = $1
+		ORDER BY name
+	`, pc.PublisherID)
+	if err != nil {
+		slog.Error("failed to list locations", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to list locations")
+		return
+	}
+	defer rows.Close()
+
+	locations := []models.PublisherLocation{}
+	for rows.Next() {
+		l, err := scanPublisherLocation(rows)
+		if err != nil {
+			slog.Error("failed to scan location", "error", err, "publisher_id", pc.PublisherID)
+			RespondInternalError(w, r, "Failed to list locations")
+			return
+		}
+		locations = append(locations, *l)
+	}
+
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"locations": locations,
+		"total":     len(locations),
+	})
+}
+
+// GetPublisherLocation returns one of the publisher's locations
+// @Summary Get publisher location
+// @Tags Publisher
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Location ID"
+// @Success 200 {object} APIResponse{data=models.PublisherLocation} "Publisher location"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 404 {object} APIResponse{error=APIError} "Location not found"
+// @Router /publisher/locations/{id} [get]
+func (h *Handlers) GetPublisherLocation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	l, err := h.fetchPublisherLocation(ctx, pc.PublisherID, chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("failed to fetch location", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to fetch location")
+		return
+	}
+	if l == nil {
+		RespondNotFound(w, r, "Location not found")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, l)
+}
+
+// CreatePublisherLocation adds a named location
+// @Summary Create publisher location
+// @Description Adds a named location with exact coordinates. Elevation and timezone are resolved from SRTM and the timezone boundaries when omitted.
+// @Tags Publisher
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param request body PublisherLocationRequest true "Location"
+// @Success 201 {object} APIResponse{data=models.PublisherLocation} "Created location"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid request or duplicate name"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/locations [post]
+func (h *Handlers) CreatePublisherLocation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req PublisherLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+	if errs := req.validate(); len(errs) > 0 {
+		RespondValidationError(w, r, "Invalid location", errs)
+		return
+	}
+	if err := h.resolveLocationRequest(ctx, &req); err != nil {
+		slog.Error("failed to resolve location", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to resolve elevation and timezone")
+		return
+	}
+
+	l, err := scanPublisherLocation(h.db.Pool.QueryRow(ctx, `
+		INSERT INTO publisher_locations (publisher_id, name, description, latitude, longitude, elevation_m, timezone, horizon_altitudes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+publisherLocationColumns,
+		pc.PublisherID, strings.TrimSpace(req.Name), req.Description, req.Latitude, req.Longitude,
+		*req.ElevationM, req.Timezone, nullableFloats(req.HorizonAltitudes), pc.UserID))
+	if isUniqueViolation(err) {
+		RespondBadRequest(w, r, fmt.Sprintf("A location named %q already exists", req.Name))
+		return
+	}
+	if err != nil {
+		slog.Error("failed to create location", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to create location")
+		return
+	}
+
+	slog.Info("location created", "id", l.ID, "publisher_id", pc.PublisherID)
+	RespondJSON(w, r, http.StatusCreated, l)
+}
+
+// UpdatePublisherLocation replaces a location's details
+// @Summary Update publisher location
+// @Description Replaces a location's details. Clears the publisher's cached zmanim.
+// @Tags Publisher
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Location ID"
+// @Param request body PublisherLocationRequest true "Location"
+// @Success 200 {object} APIResponse{data=models.PublisherLocation} "Updated location"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid request or duplicate name"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 404 {object} APIResponse{error=APIError} "Location not found"
+// @Router /publisher/locations/{id} [put]
+func (h *Handlers) UpdatePublisherLocation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	existing, err := h.fetchPublisherLocation(ctx, pc.PublisherID, chi.URLParam(r, "id"))
+	if err != nil {
+		slog.Error("failed to fetch location", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to fetch location")
+		return
+	}
+	if existing == nil {
+		RespondNotFound(w, r, "Location not found")
+		return
+	}
+
+	var req PublisherLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+	if errs := req.validate(); len(errs) > 0 {
+		RespondValidationError(w, r, "Invalid location", errs)
+		return
+	}
+	if err := h.resolveLocationRequest(ctx, &req); err != nil {
+		slog.Error("failed to resolve location", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to resolve elevation and timezone")
+		return
+	}
+
+	l, err := scanPublisherLocation(h.db.Pool.QueryRow(ctx, `
+		UPDATE publisher_locations
+		SET name = $3, description = $4, latitude = $5, longitude = $6, elevation_m = $7,
+		    timezone = $8, horizon_altitudes = $9
+		WHERE id = $1 AND publisher_id = $2
+		RETURNING `+publisherLocationColumns,
+		existing.ID, pc.PublisherID, strings.TrimSpace(req.Name), req.Description, req.Latitude, req.Longitude,
+		*req.ElevationM, req.Timezone, nullableFloats(req.HorizonAltitudes)))
+	if isUniqueViolation(err) {
+		RespondBadRequest(w, r, fmt.Sprintf("A location named %q already exists", req.Name))
+		return
+	}
+	if err != nil {
+		slog.Error("failed to update location", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to update location")
+		return
+	}
+
+	// Cached times were calculated for the previous coordinates
+	h.invalidateLocationCache(ctx, pc.PublisherID)
+
+	RespondJSON(w, r, http.StatusOK, l)
+}
+
+// DeletePublisherLocation removes a location and any coverage of it
+// @Summary Delete publisher location
+// @Tags Publisher
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Location ID"
+// @Success 200 {object} APIResponse{data=object} "Deletion confirmation"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 404 {object} APIResponse{error=APIError} "Location not found"
+// @Router /publisher/locations/{id} [delete]
+func (h *Handlers) DeletePublisherLocation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		RespondNotFound(w, r, "Location not found")
+		return
+	}
+	tag, err := h.db.Pool.Exec(ctx, `DELETE FROM publisher_locations WHERE id = $1 AND publisher_id = $2`, id, pc.PublisherID)
+	if err != nil {
+		slog.Error("failed to delete location", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to delete location")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		RespondNotFound(w, r, "Location not found")
+		return
+	}
+
+	h.invalidateLocationCache(ctx, pc.PublisherID)
+
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"message": "Location deleted successfully",
+	})
+}
+
+// nullableFloats stores an empty slice as NULL
+func nullableFloats(v []float64) []float64 {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jcom-dev/zmanim-lab/internal/middleware"
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+func TestPublisherLocationRequestValidate(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+	valid := PublisherLocationRequest{Name: "Har Nof", Latitude: 31.7857, Longitude: 35.1781}
+
+	tests := []struct {
+		name    string
+		modify  func(*PublisherLocationRequest)
+		invalid []string
+	}{
+		{"minimal", func(*PublisherLocationRequest) {}, nil},
+		{"full", func(req *PublisherLocationRequest) {
+			req.ElevationM = ptr(810)
+			req.Timezone = "Asia/Jerusalem"
+			req.HorizonAltitudes = []float64{0.5, 1, 2.5, 1}
+		}, nil},
+		{"blank name", func(req *PublisherLocationRequest) { req.Name = "  " }, []string{"name"}},
+		{"out of range", func(req *PublisherLocationRequest) {
+			req.Latitude, req.Longitude, req.ElevationM = 91, -181, ptr(9500)
+		}, []string{"latitude", "longitude", "elevation_m"}},
+		{"unknown timezone", func(req *PublisherLocationRequest) { req.Timezone = "Mars/Olympus" }, []string{"timezone"}},
+		{"horizon too high", func(req *PublisherLocationRequest) { req.HorizonAltitudes = []float64{1, 60} }, []string{"horizon_altitudes"}},
+		{"horizon too long", func(req *PublisherLocationRequest) { req.HorizonAltitudes = make([]float64, 361) }, []string{"horizon_altitudes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			errs := req.validate()
+			if len(errs) != len(tt.invalid) {
+				t.Fatalf("validate() = %v, want errors for %v", errs, tt.invalid)
+			}
+			for _, field := range tt.invalid {
+				if _, ok := errs[field]; !ok {
+					t.Errorf("expected an error for %s, got %v", field, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestLocationHorizon(t *testing.T) {
+	if h := locationHorizon(&models.PublisherLocation{}); h != nil {
+		t.Errorf("locationHorizon without override = %v, want nil", h)
+	}
+
+	uniform := locationHorizon(&models.PublisherLocation{HorizonAltitudes: []float64{2}})
+	for _, azimuth := range []float64{0, 90, 233.5} {
+		if got := uniform.Altitude(azimuth); got != 2 {
+			t.Errorf("uniform Altitude(%v) = %v, want 2", azimuth, got)
+		}
+	}
+
+	// Four entries are 90° apart: north 0°, east 4°, south 0°, west 0°
+	quarters := locationHorizon(&models.PublisherLocation{HorizonAltitudes: []float64{0, 4, 0, 0}})
+	tests := []struct{ azimuth, want float64 }{{90, 4}, {45, 2}, {135, 2}, {270, 0}}
+	for _, tt := range tests {
+		if got := quarters.Altitude(tt.azimuth); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Altitude(%v) = %v, want %v", tt.azimuth, got, tt.want)
+		}
+	}
+}
+
+func TestLocationOwner(t *testing.T) {
+	withUser := func(role, primary string, access ...string) context.Context {
+		ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user_1")
+		ctx = context.WithValue(ctx, middleware.UserRoleKey, role)
+		ctx = context.WithValue(ctx, middleware.PrimaryPublisherIDKey, primary)
+		return context.WithValue(ctx, middleware.PublisherAccessListKey, access)
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested string
+		want      string
+	}{
+		{"anonymous", context.Background(), "pub-a", ""},
+		{"anonymous without request", context.Background(), "", ""},
+		{"primary publisher", withUser("publisher", "pub-a"), "pub-a", "pub-a"},
+		{"defaults to primary", withUser("publisher", "pub-a"), "", "pub-a"},
+		{"access list", withUser("publisher", "pub-a", "pub-b"), "pub-b", "pub-b"},
+		{"someone else's publisher", withUser("publisher", "pub-a"), "pub-c", ""},
+		{"admin", withUser("admin", ""), "pub-c", "pub-c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/cities", nil).WithContext(tt.ctx)
+			if got := locationOwner(r, tt.requested); got != tt.want {
+				t.Errorf("locationOwner(%q) = %q, want %q", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
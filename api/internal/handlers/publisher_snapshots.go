@@ -2,13 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jcom-dev/zmanim-lab/internal/services"
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
 )
 
 // ExportPublisherSnapshot exports the current publisher state as JSON
@@ -47,7 +51,9 @@ func (h *Handlers) ExportPublisherSnapshot(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-// ImportPublisherSnapshot imports a snapshot from JSON
+// ImportPublisherSnapshot imports a snapshot from JSON. Version 1 files are
+// migrated on import; "sections" limits which parts are applied and "dry_run"
+// returns the diff without changing anything.
 // POST /api/v1/publisher/snapshot/import
 func (h *Handlers) ImportPublisherSnapshot(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -63,6 +69,8 @@ func (h *Handlers) ImportPublisherSnapshot(w http.ResponseWriter, r *http.Reques
 	// 3. Parse body
 	var req struct {
 		Snapshot services.PublisherSnapshot `json:"snapshot"`
+		Sections []string                   `json:"sections"`
+		DryRun   bool                       `json:"dry_run"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		RespondBadRequest(w, r, "Invalid request body")
@@ -70,36 +78,83 @@ func (h *Handlers) ImportPublisherSnapshot(w http.ResponseWriter, r *http.Reques
 	}
 
 	// 4. Validate
-	if req.Snapshot.Version == 0 {
-		RespondValidationError(w, r, "Invalid snapshot format", map[string]string{
-			"version": "Snapshot version is required",
-		})
-		return
-	}
-	if req.Snapshot.Version != 1 {
-		RespondValidationError(w, r, "Unsupported snapshot version", map[string]string{
-			"version": fmt.Sprintf("Version %d is not supported. Only version 1 is supported.", req.Snapshot.Version),
-		})
+	if err := services.MigrateSnapshot(&req.Snapshot); err != nil {
+		respondSnapshotError(w, r, err)
 		return
 	}
 
 	// 5. Import snapshot
-	err := h.snapshotService.ImportSnapshot(ctx, pc.PublisherID, pc.UserID, &req.Snapshot)
+	opts := services.ApplyOptions{Sections: req.Sections, DryRun: req.DryRun}
+	diff, err := h.snapshotService.ImportSnapshot(ctx, pc.PublisherID, pc.UserID, &req.Snapshot, opts)
 	if err != nil {
+		if isSnapshotValidationError(err) {
+			respondSnapshotError(w, r, err)
+			return
+		}
 		slog.Error("failed to import snapshot", "error", err, "publisher_id", pc.PublisherID)
 		RespondInternalError(w, r, "Failed to import snapshot")
 		return
 	}
 
+	if !req.DryRun {
+		h.emitWebhookEvent(ctx, pc.PublisherID, webhooks.EventSnapshotRestored, map[string]interface{}{
+			"source":       "import",
+			"zmanim_count": len(req.Snapshot.Zmanim),
+			"sections":     appliedSnapshotSections(diff),
+		})
+	}
+
 	// 6. Respond
 	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
 		"success": true,
+		"dry_run": req.DryRun,
+		"diff":    diff,
 		"stats": map[string]int{
-			"zmanim": len(req.Snapshot.Zmanim),
+			"zmanim":    len(req.Snapshot.Zmanim),
+			"aliases":   len(req.Snapshot.Aliases),
+			"tags":      len(req.Snapshot.Tags),
+			"day_types": len(req.Snapshot.DayTypes),
+			"events":    len(req.Snapshot.Events),
+			"coverage":  len(req.Snapshot.Coverage),
+			"changes":   diff.ChangeCount(),
 		},
 	})
 }
 
+// isSnapshotValidationError reports whether err is caused by the snapshot or options rather than the server
+func isSnapshotValidationError(err error) bool {
+	return errors.Is(err, services.ErrInvalidSnapshot) ||
+		errors.Is(err, services.ErrUnsupportedSnapshotVersion) ||
+		errors.Is(err, services.ErrUnknownSnapshotSection)
+}
+
+// respondSnapshotError maps snapshot validation errors to a 400 response
+func respondSnapshotError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnsupportedSnapshotVersion):
+		RespondValidationError(w, r, "Unsupported snapshot version", map[string]string{
+			"version": fmt.Sprintf("Only versions 1 and %d are supported", services.SnapshotFormatVersion),
+		})
+	case errors.Is(err, services.ErrUnknownSnapshotSection):
+		RespondValidationError(w, r, "Unknown snapshot section", map[string]string{
+			"sections": fmt.Sprintf("%s (valid sections: %s)", err.Error(), strings.Join(services.SnapshotSections, ", ")),
+		})
+	default:
+		RespondValidationError(w, r, "Invalid snapshot format", map[string]string{
+			"snapshot": err.Error(),
+		})
+	}
+}
+
+// appliedSnapshotSections lists the sections a diff covers
+func appliedSnapshotSections(diff *services.SnapshotDiff) []string {
+	sections := make([]string, len(diff.Sections))
+	for i, s := range diff.Sections {
+		sections[i] = s.Section
+	}
+	return sections
+}
+
 // SavePublisherSnapshot creates a new version snapshot
 // POST /api/v1/publisher/snapshot
 func (h *Handlers) SavePublisherSnapshot(w http.ResponseWriter, r *http.Request) {
@@ -205,7 +260,8 @@ func (h *Handlers) GetPublisherSnapshot(w http.ResponseWriter, r *http.Request)
 	RespondJSON(w, r, http.StatusOK, snapshot)
 }
 
-// RestorePublisherSnapshot restores from a saved version
+// RestorePublisherSnapshot restores from a saved version. The optional body
+// accepts "sections" and "dry_run" as for import.
 // POST /api/v1/publisher/snapshot/{id}/restore
 func (h *Handlers) RestorePublisherSnapshot(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -223,22 +279,55 @@ func (h *Handlers) RestorePublisherSnapshot(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// 3. No body
+	// 3. Parse optional body
+	var req struct {
+		Sections []string `json:"sections"`
+		DryRun   bool     `json:"dry_run"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			RespondBadRequest(w, r, "Invalid request body")
+			return
+		}
+	}
 
 	// 4. No additional validation
 
 	// 5. Restore snapshot (auto-saves current state first)
-	autoSave, err := h.snapshotService.RestoreSnapshot(ctx, snapshotID, pc.PublisherID, pc.UserID)
+	opts := services.ApplyOptions{Sections: req.Sections, DryRun: req.DryRun}
+	autoSave, diff, err := h.snapshotService.RestoreSnapshot(ctx, snapshotID, pc.PublisherID, pc.UserID, opts)
 	if err != nil {
+		if isSnapshotValidationError(err) {
+			respondSnapshotError(w, r, err)
+			return
+		}
 		slog.Error("failed to restore snapshot", "error", err, "snapshot_id", snapshotID, "publisher_id", pc.PublisherID)
 		RespondInternalError(w, r, "Failed to restore version")
 		return
 	}
 
+	if req.DryRun {
+		RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"dry_run": true,
+			"diff":    diff,
+		})
+		return
+	}
+
+	h.emitWebhookEvent(ctx, pc.PublisherID, webhooks.EventSnapshotRestored, map[string]interface{}{
+		"source":       "restore",
+		"snapshot_id":  snapshotID,
+		"auto_save_id": autoSave.ID,
+		"sections":     appliedSnapshotSections(diff),
+	})
+
 	// 6. Respond
 	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
 		"success":      true,
+		"dry_run":      false,
 		"auto_save_id": autoSave.ID,
+		"diff":         diff,
 	})
 }
 
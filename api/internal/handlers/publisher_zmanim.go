@@ -18,6 +18,7 @@ import (
 	"github.com/jcom-dev/zmanim-lab/internal/calendar"
 	"github.com/jcom-dev/zmanim-lab/internal/db/sqlcgen"
 	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
 )
 
 // PublisherZman represents a single zman formula for a publisher
@@ -57,6 +58,8 @@ type PublisherZman struct {
 	SourceTransliteration *string `json:"source_transliteration,omitempty" db:"source_transliteration"`
 	SourceDescription     *string `json:"source_description,omitempty" db:"source_description"`
 	SourceFormulaDSL      *string `json:"source_formula_dsl,omitempty" db:"source_formula_dsl"`
+	// Effective-dated formula versions (override FormulaDSL within their date range)
+	Schedule []ZmanScheduledFormula `json:"schedule,omitempty" db:"-"`
 }
 
 // ZmanimTemplate represents a system-wide default zman template
@@ -99,6 +102,12 @@ type UpdateZmanRequest struct {
 	IsPublished      *bool   `json:"is_published"`
 	IsBeta           *bool   `json:"is_beta"`
 	Category         *string `json:"category"`
+	// Scheduling: when either date is set, formula_dsl is recorded as a version that
+	// applies only to that range (YYYY-MM-DD, until exclusive) instead of replacing
+	// the formula for all dates
+	EffectiveFrom  *string `json:"effective_from,omitempty"`
+	EffectiveUntil *string `json:"effective_until,omitempty"`
+	ChangeReason   *string `json:"change_reason,omitempty"`
 }
 
 // DayContext contains day-specific information for zmanim filtering
@@ -127,6 +136,8 @@ type PublisherZmanWithTime struct {
 	PublisherZman
 	Time  *string `json:"time,omitempty"`  // Calculated time HH:MM:SS (nil if calculation failed)
 	Error *string `json:"error,omitempty"` // Error message if calculation failed
+	// ScheduledVersion is set when a scheduled formula (not the base formula) was used for this date
+	ScheduledVersion *int `json:"scheduled_version,omitempty"`
 }
 
 // FilteredZmanimResponse is returned when date/location params are provided
@@ -171,6 +182,7 @@ var dayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday",
 // @Param latitude query number false "Latitude for time calculation (required with date)"
 // @Param longitude query number false "Longitude for time calculation (required with date)"
 // @Param timezone query string false "Timezone for calculation (defaults to UTC)"
+// @Param location_id query string false "Publisher location to calculate for, instead of latitude/longitude/timezone"
 // @Success 200 {object} APIResponse{data=object} "List of zmanim or filtered response with day context"
 // @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
 // @Failure 404 {object} APIResponse{error=APIError} "Publisher not found"
@@ -233,9 +245,19 @@ func (h *Handlers) GetPublisherZmanim(w http.ResponseWriter, r *http.Request) {
 	if timezone == "" {
 		timezone = "UTC"
 	}
+	location, ok := h.requestPublisherLocation(w, r, publisherID)
+	if !ok {
+		return
+	}
+	if location != nil {
+		latitude, longitude, timezone = location.Latitude, location.Longitude, location.Timezone
+	}
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("%s:%s:%.4f:%.4f", publisherID, dateStr, latitude, longitude)
+	if location != nil {
+		cacheKey = fmt.Sprintf("%s:%s:location:%s", publisherID, dateStr, location.ID)
+	}
 	if h.cache != nil {
 		cached, err := h.cache.GetZmanim(ctx, publisherID, cacheKey, dateStr)
 		if err == nil && cached != nil {
@@ -294,7 +316,11 @@ func (h *Handlers) GetPublisherZmanim(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Filter and calculate times
-	filteredZmanim := h.filterAndCalculateZmanim(zmanim, dayCtx, date, latitude, longitude, timezone)
+	settings := h.calculationSettings(ctx, publisherID, latitude, longitude)
+	if location != nil {
+		settings = h.locationCalculationSettings(ctx, publisherID, location)
+	}
+	filteredZmanim := h.filterAndCalculateZmanim(zmanim, dayCtx, date, latitude, longitude, timezone, settings)
 
 	response := FilteredZmanimResponse{
 		DayContext: dayCtx,
@@ -321,11 +347,14 @@ type WeekDayZmanim struct {
 // WeekZmanimResponse is the response for the batch week endpoint
 type WeekZmanimResponse struct {
 	Days []WeekDayZmanim `json:"days"`
+	// Transitions lists days on which a zman switches to a different (scheduled) formula
+	Transitions []ZmanFormulaTransition `json:"transitions"`
 }
 
 // GetPublisherZmanimWeek returns all zmanim for a publisher for an entire week
 // This is a batch endpoint that calculates all 7 days in one request with caching
 // GET /api/v1/publisher/zmanim/week?start_date=YYYY-MM-DD&latitude=X&longitude=Y&timezone=Z
+// or GET /api/v1/publisher/zmanim/week?start_date=YYYY-MM-DD&location_id=ID
 func (h *Handlers) GetPublisherZmanimWeek(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -373,9 +402,19 @@ func (h *Handlers) GetPublisherZmanimWeek(w http.ResponseWriter, r *http.Request
 	if timezone == "" {
 		timezone = "UTC"
 	}
+	location, ok := h.requestPublisherLocation(w, r, publisherID)
+	if !ok {
+		return
+	}
+	if location != nil {
+		latitude, longitude, timezone = location.Latitude, location.Longitude, location.Timezone
+	}
 
 	// Check cache first - use week start date as key
 	cacheKey := fmt.Sprintf("week:%s:%s:%.4f:%.4f", publisherID, startDateStr, latitude, longitude)
+	if location != nil {
+		cacheKey = fmt.Sprintf("week:%s:%s:location:%s", publisherID, startDateStr, location.ID)
+	}
 	if h.cache != nil {
 		cached, err := h.cache.GetZmanim(ctx, publisherID, cacheKey, startDateStr)
 		if err == nil && cached != nil {
@@ -406,6 +445,10 @@ func (h *Handlers) GetPublisherZmanimWeek(w http.ResponseWriter, r *http.Request
 	}
 
 	// Calculate all 7 days
+	settings := h.calculationSettings(ctx, publisherID, latitude, longitude)
+	if location != nil {
+		settings = h.locationCalculationSettings(ctx, publisherID, location)
+	}
 	days := make([]WeekDayZmanim, 7)
 	for i := 0; i < 7; i++ {
 		date := startDate.AddDate(0, 0, i)
@@ -448,7 +491,7 @@ func (h *Handlers) GetPublisherZmanimWeek(w http.ResponseWriter, r *http.Request
 		}
 
 		// Filter and calculate times for this day
-		filteredZmanim := h.filterAndCalculateZmanim(zmanim, dayCtx, date, latitude, longitude, timezone)
+		filteredZmanim := h.filterAndCalculateZmanim(zmanim, dayCtx, date, latitude, longitude, timezone, settings)
 
 		days[i] = WeekDayZmanim{
 			DayContext: dayCtx,
@@ -456,7 +499,7 @@ func (h *Handlers) GetPublisherZmanimWeek(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	response := WeekZmanimResponse{Days: days}
+	response := WeekZmanimResponse{Days: days, Transitions: formulaTransitions(days)}
 
 	// Cache the response
 	if h.cache != nil {
@@ -590,13 +633,22 @@ func (h *Handlers) fetchPublisherZmanim(ctx context.Context, publisherID string)
 
 		zmanim = append(zmanim, z)
 	}
+	rows.Close()
+
+	schedules, err := h.fetchZmanSchedules(ctx, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range zmanim {
+		zmanim[i].Schedule = schedules[zmanim[i].ID]
+	}
 
 	return zmanim, nil
 }
 
 // filterAndCalculateZmanim filters zmanim based on day context and calculates times
 // Filtering is entirely tag-driven - no hardcoded zman keys
-func (h *Handlers) filterAndCalculateZmanim(zmanim []PublisherZman, dayCtx DayContext, date time.Time, lat, lon float64, timezone string) []PublisherZmanWithTime {
+func (h *Handlers) filterAndCalculateZmanim(zmanim []PublisherZman, dayCtx DayContext, date time.Time, lat, lon float64, timezone string, settings calculationSettings) []PublisherZmanWithTime {
 	var result []PublisherZmanWithTime
 
 	// Load timezone
@@ -611,8 +663,10 @@ func (h *Handlers) filterAndCalculateZmanim(zmanim []PublisherZman, dayCtx DayCo
 	// Create DSL execution context for time calculation
 	var execCtx *dsl.ExecutionContext
 	if lat != 0 || lon != 0 {
-		execCtx = dsl.NewExecutionContext(date, lat, lon, 0, tz)
+		execCtx = dsl.NewExecutionContext(date, lat, lon, settings.elevation, tz)
+		settings.apply(execCtx)
 	}
+	dateStr := date.Format("2006-01-02")
 
 	for _, z := range zmanim {
 		// Only include enabled zmanim
@@ -630,9 +684,16 @@ func (h *Handlers) filterAndCalculateZmanim(zmanim []PublisherZman, dayCtx DayCo
 			PublisherZman: z,
 		}
 
+		// Use the formula in force on this date
+		if scheduled := resolveScheduledFormula(z.Schedule, dateStr); scheduled != nil {
+			zwt.FormulaDSL = scheduled.FormulaDSL
+			version := scheduled.VersionNumber
+			zwt.ScheduledVersion = &version
+		}
+
 		// Calculate time if we have location
-		if execCtx != nil && z.FormulaDSL != "" {
-			timeResult, _, calcErr := dsl.ExecuteFormulaWithBreakdown(z.FormulaDSL, execCtx)
+		if execCtx != nil && zwt.FormulaDSL != "" {
+			timeResult, _, calcErr := dsl.ExecuteFormulaWithBreakdown(zwt.FormulaDSL, execCtx)
 			if calcErr != nil {
 				errStr := calcErr.Error()
 				zwt.Error = &errStr
@@ -1092,7 +1153,8 @@ func (h *Handlers) CreatePublisherZman(w http.ResponseWriter, r *http.Request) {
 
 // UpdatePublisherZman updates an existing zman
 // @Summary Update zman
-// @Description Updates an existing zman formula's properties
+// @Description Updates an existing zman formula's properties. With effective_from/effective_until the
+// @Description formula is scheduled for that date range only; other dates keep their current formula.
 // @Tags Publisher Zmanim
 // @Accept json
 // @Produce json
@@ -1148,6 +1210,51 @@ func (h *Handlers) UpdatePublisherZman(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A formula with an effective date range is scheduled for that range only
+	if req.EffectiveFrom != nil || req.EffectiveUntil != nil {
+		if req.FormulaDSL == nil {
+			RespondValidationError(w, r, "formula_dsl is required with effective_from/effective_until", nil)
+			return
+		}
+		if errs := validateEffectiveRange(req.EffectiveFrom, req.EffectiveUntil); len(errs) > 0 {
+			RespondValidationError(w, r, "Invalid effective date range", errs)
+			return
+		}
+	}
+
+	z, err := h.saveZmanUpdate(ctx, publisherID, pc.UserID, zmanKey, req)
+	if err == pgx.ErrNoRows {
+		RespondNotFound(w, r, "Zman not found")
+		return
+	}
+	if err != nil {
+		slog.Error("UpdatePublisherZman: failed to save", "error", err, "zman_key", zmanKey)
+		RespondInternalError(w, r, "Failed to update zman")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, z)
+}
+
+// saveZmanUpdate persists a validated update request. A formula with an effective
+// date range is scheduled as a version instead of replacing the formula for every
+// date. Invalidates the publisher cache and emits zman.updated. Returns
+// pgx.ErrNoRows when the zman does not exist.
+func (h *Handlers) saveZmanUpdate(ctx context.Context, publisherID, userID, zmanKey string, req UpdateZmanRequest) (PublisherZman, error) {
+	var scheduled *ZmanScheduledFormula
+	if req.EffectiveFrom != nil || req.EffectiveUntil != nil {
+		s, err := h.scheduleZmanFormula(ctx, publisherID, zmanKey, *req.FormulaDSL,
+			req.EffectiveFrom, req.EffectiveUntil, req.ChangeReason, userID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return PublisherZman{}, err
+			}
+			return PublisherZman{}, fmt.Errorf("failed to schedule formula: %w", err)
+		}
+		scheduled = &s
+		req.FormulaDSL = nil
+	}
+
 	// Extract dependencies if formula is updated
 	var dependencies []string
 	if req.FormulaDSL != nil {
@@ -1172,18 +1279,15 @@ func (h *Handlers) UpdatePublisherZman(w http.ResponseWriter, r *http.Request) {
 		Category:         req.Category,
 		Dependencies:     dependencies,
 	})
-
 	if updateErr == pgx.ErrNoRows {
-		RespondNotFound(w, r, "Zman not found")
-		return
+		return PublisherZman{}, updateErr
 	}
 	if updateErr != nil {
-		RespondInternalError(w, r, "Failed to update zman")
-		return
+		return PublisherZman{}, fmt.Errorf("failed to update zman: %w", updateErr)
 	}
 
 	// Invalidate all cached data for this publisher when formula or is_enabled changes
-	if h.cache != nil && (req.FormulaDSL != nil || req.IsEnabled != nil) {
+	if h.cache != nil && (req.FormulaDSL != nil || req.IsEnabled != nil || scheduled != nil) {
 		if err := h.cache.InvalidatePublisherCache(ctx, publisherID); err != nil {
 			slog.Warn("failed to invalidate cache", "error", err, "publisher_id", publisherID)
 		} else {
@@ -1193,7 +1297,20 @@ func (h *Handlers) UpdatePublisherZman(w http.ResponseWriter, r *http.Request) {
 
 	z := updatePublisherZmanRowToPublisherZman(sqlcZman)
 
-	RespondJSON(w, r, http.StatusOK, z)
+	event := map[string]interface{}{
+		"zman_key":        z.ZmanKey,
+		"formula_dsl":     z.FormulaDSL,
+		"formula_changed": req.FormulaDSL != nil,
+		"is_enabled":      z.IsEnabled,
+		"is_published":    z.IsPublished,
+	}
+	if scheduled != nil {
+		z.Schedule = []ZmanScheduledFormula{*scheduled}
+		event["scheduled_formula"] = scheduled
+	}
+	h.emitWebhookEvent(ctx, publisherID, webhooks.EventZmanUpdated, event)
+
+	return z, nil
 }
 
 // DeletePublisherZman deletes a custom zman
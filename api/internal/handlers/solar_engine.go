@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+)
+
+// SolarEngineSettings is a publisher's choice of solar position algorithm.
+// The atmosphere fields only apply to the SPA engine; nil uses its default.
+type SolarEngineSettings struct {
+	Engine       string   `json:"engine"`
+	DeltaT       *float64 `json:"delta_t,omitempty"`       // TT - UT1, seconds
+	PressureMbar *float64 `json:"pressure_mbar,omitempty"` // annual average local pressure
+	TemperatureC *float64 `json:"temperature_c,omitempty"` // annual average local temperature
+}
+
+// SolarEngineResponse is a publisher's solar engine settings and the engines available
+type SolarEngineResponse struct {
+	SolarEngineSettings
+	Available []string `json:"available"`
+}
+
+// validate checks the settings against the ranges the database accepts
+func (s SolarEngineSettings) validate() map[string]string {
+	errs := make(map[string]string)
+	if _, err := astro.EngineByName(s.Engine); err != nil || s.Engine == "" {
+		errs["engine"] = fmt.Sprintf("must be one of %v", astro.EngineNames)
+	}
+	if s.DeltaT != nil && (*s.DeltaT < -300 || *s.DeltaT > 300) {
+		errs["delta_t"] = "must be between -300 and 300 seconds"
+	}
+	if s.PressureMbar != nil && (*s.PressureMbar < 300 || *s.PressureMbar > 1100) {
+		errs["pressure_mbar"] = "must be between 300 and 1100 mbar"
+	}
+	if s.TemperatureC != nil && (*s.TemperatureC < -60 || *s.TemperatureC > 60) {
+		errs["temperature_c"] = "must be between -60 and 60 °C"
+	}
+	return errs
+}
+
+// engine builds the solar engine the settings describe
+func (s SolarEngineSettings) engine() (astro.SolarEngine, error) {
+	if s.Engine != astro.EngineSPA {
+		return astro.EngineByName(s.Engine)
+	}
+	spa := astro.NewSPAEngine()
+	if s.DeltaT != nil {
+		spa.DeltaT = *s.DeltaT
+	}
+	if s.PressureMbar != nil {
+		spa.Pressure = *s.PressureMbar
+	}
+	if s.TemperatureC != nil {
+		spa.Temperature = *s.TemperatureC
+	}
+	return spa, nil
+}
+
+// fetchSolarEngineSettings loads a publisher's solar engine settings
+func (h *Handlers) fetchSolarEngineSettings(ctx context.Context, publisherID string) (SolarEngineSettings, error) {
+	var s SolarEngineSettings
+	err := h.db.Pool.QueryRow(ctx, `
+		SELECT solar_engine, solar_delta_t, solar_pressure_mbar, solar_temperature_c
+		FROM publishers WHERE id = $1
+	`, publisherID).Scan(&s.Engine, &s.DeltaT, &s.PressureMbar, &s.TemperatureC)
+	return s, err
+}
+
+// publisherSolarEngine returns the engine a publisher calculates with, falling
+// back to the default engine if the settings cannot be loaded
+func (h *Handlers) publisherSolarEngine(ctx context.Context, publisherID string) astro.SolarEngine {
+	settings, err := h.fetchSolarEngineSettings(ctx, publisherID)
+	if err != nil {
+		slog.Warn("failed to load solar engine, using default", "error", err, "publisher_id", publisherID)
+		return astro.DefaultEngine
+	}
+	engine, err := settings.engine()
+	if err != nil {
+		slog.Warn("invalid solar engine, using default", "error", err, "publisher_id", publisherID)
+		return astro.DefaultEngine
+	}
+	return engine
+}
+
+// GetPublisherSolarEngine returns the publisher's solar engine settings
+// @Summary Get solar engine
+// @Description Returns the solar position algorithm used for the publisher's zmanim
+// @Tags Publisher
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Success 200 {object} APIResponse{data=SolarEngineResponse} "Solar engine settings"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Router /publisher/solar-engine [get]
+func (h *Handlers) GetPublisherSolarEngine(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	settings, err := h.fetchSolarEngineSettings(r.Context(), pc.PublisherID)
+	if err != nil {
+		slog.Error("failed to fetch solar engine", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to fetch solar engine")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, SolarEngineResponse{SolarEngineSettings: settings, Available: astro.EngineNames})
+}
+
+// UpdatePublisherSolarEngine selects the publisher's solar engine
+// @Summary Update solar engine
+// @Description Selects the solar position algorithm (noaa or spa) and SPA's ΔT and atmosphere. Clears the publisher's cached zmanim.
+// @Tags Publisher
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param request body SolarEngineSettings true "Solar engine settings"
+// @Success 200 {object} APIResponse{data=SolarEngineResponse} "Updated settings"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid settings"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Router /publisher/solar-engine [put]
+func (h *Handlers) UpdatePublisherSolarEngine(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req SolarEngineSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+	if errs := req.validate(); len(errs) > 0 {
+		RespondValidationError(w, r, "Invalid solar engine settings", errs)
+		return
+	}
+	if req.Engine != astro.EngineSPA {
+		// Atmosphere and ΔT only mean something to SPA
+		req.DeltaT, req.PressureMbar, req.TemperatureC = nil, nil, nil
+	}
+
+	_, err := h.db.Pool.Exec(ctx, `
+		UPDATE publishers
+		SET solar_engine = $2, solar_delta_t = $3, solar_pressure_mbar = $4, solar_temperature_c = $5, updated_at = NOW()
+		WHERE id = $1
+	`, pc.PublisherID, req.Engine, req.DeltaT, req.PressureMbar, req.TemperatureC)
+	if err != nil {
+		slog.Error("failed to update solar engine", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to update solar engine")
+		return
+	}
+
+	// Cached times were calculated with the previous engine
+	if h.cache != nil {
+		if err := h.cache.InvalidatePublisherCache(ctx, pc.PublisherID); err != nil {
+			slog.Warn("failed to invalidate cache after solar engine change", "error", err, "publisher_id", pc.PublisherID)
+		}
+	}
+
+	slog.Info("solar engine updated", "publisher_id", pc.PublisherID, "engine", req.Engine)
+	RespondJSON(w, r, http.StatusOK, SolarEngineResponse{SolarEngineSettings: req, Available: astro.EngineNames})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+)
+
+func TestSolarEngineSettingsValidate(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		settings SolarEngineSettings
+		invalid  []string
+	}{
+		{"noaa", SolarEngineSettings{Engine: "noaa"}, nil},
+		{"spa with atmosphere", SolarEngineSettings{Engine: "spa", DeltaT: ptr(69.2), PressureMbar: ptr(950), TemperatureC: ptr(18)}, nil},
+		{"missing engine", SolarEngineSettings{}, []string{"engine"}},
+		{"unknown engine", SolarEngineSettings{Engine: "vsop87"}, []string{"engine"}},
+		{"out of range", SolarEngineSettings{Engine: "spa", DeltaT: ptr(1000), PressureMbar: ptr(10), TemperatureC: ptr(90)},
+			[]string{"delta_t", "pressure_mbar", "temperature_c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := tt.settings.validate()
+			if len(errs) != len(tt.invalid) {
+				t.Fatalf("validate() = %v, want errors for %v", errs, tt.invalid)
+			}
+			for _, field := range tt.invalid {
+				if _, ok := errs[field]; !ok {
+					t.Errorf("expected an error for %s, got %v", field, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestSolarEngineSettingsEngine(t *testing.T) {
+	pressure, temperature := 820.0, 11.0
+	engine, err := SolarEngineSettings{Engine: "spa", PressureMbar: &pressure, TemperatureC: &temperature}.engine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spa, ok := engine.(*astro.SPAEngine)
+	if !ok {
+		t.Fatalf("engine = %T, want *astro.SPAEngine", engine)
+	}
+	if spa.Pressure != 820 || spa.Temperature != 11 || spa.DeltaT != 0 {
+		t.Errorf("engine = %+v, want pressure 820, temperature 11 and estimated ΔT", *spa)
+	}
+
+	engine, err = SolarEngineSettings{Engine: "noaa"}.engine()
+	if err != nil || engine.Name() != astro.EngineNOAA {
+		t.Errorf("noaa settings gave %v, %v", engine, err)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+	"github.com/jcom-dev/zmanim-lab/internal/terrain"
+)
+
+// horizonMatchRadiusMeters is how far a location may be from a city with a
+// computed horizon profile for that profile to be used. Beyond this the
+// skyline can differ enough that a flat-horizon error is preferable.
+const horizonMatchRadiusMeters = 1000
+
+// Profiles are computed offline by import-wof, so a point without one may gain
+// one later: misses are cached for horizonMissTTL. A failed lookup is cached
+// as a miss for horizonErrorTTL so a failing database is not queried on every
+// request.
+const (
+	horizonMissTTL  = time.Hour
+	horizonErrorTTL = 30 * time.Second
+)
+
+// calculationSettings is what a DSL execution needs beyond date and location:
+// the publisher's solar engine, the observer's elevation and the terrain
+// horizon at the location
+type calculationSettings struct {
+	engine    astro.SolarEngine
+	elevation float64
+	horizon   astro.Horizon
+}
+
+// apply sets the settings on an execution context
+func (s calculationSettings) apply(execCtx *dsl.ExecutionContext) {
+	execCtx.Engine = s.engine
+	execCtx.Horizon = s.horizon
+}
+
+// calculationSettings loads a publisher's engine and the horizon for a location
+func (h *Handlers) calculationSettings(ctx context.Context, publisherID string, latitude, longitude float64) calculationSettings {
+	return calculationSettings{
+		engine:  h.publisherSolarEngine(ctx, publisherID),
+		horizon: h.horizonForPoint(ctx, latitude, longitude),
+	}
+}
+
+// horizonForPoint returns the horizon profile of the nearest city within
+// horizonMatchRadiusMeters that has one, or nil. Lookups are cached, including
+// misses and errors for a while, keyed by the point rounded to about 10 m.
+func (h *Handlers) horizonForPoint(ctx context.Context, latitude, longitude float64) astro.Horizon {
+	if h.horizons == nil || (latitude == 0 && longitude == 0) {
+		return nil
+	}
+	key := fmt.Sprintf("%.4f,%.4f", latitude, longitude)
+	profile, ok := h.horizons.Get(key)
+	if !ok {
+		var err error
+		profile, err = h.fetchHorizonProfile(ctx, latitude, longitude)
+		switch {
+		case err != nil:
+			slog.Warn("failed to load horizon profile", "error", err, "latitude", latitude, "longitude", longitude)
+			h.horizons.Put(key, nil, horizonErrorTTL)
+			return nil
+		case profile == nil:
+			h.horizons.Put(key, nil, horizonMissTTL)
+		default:
+			h.horizons.Put(key, profile, 0)
+		}
+	}
+	// Return an untyped nil so the DSL sees no horizon rather than a nil *Profile
+	if profile == nil {
+		return nil
+	}
+	return profile
+}
+
+// fetchHorizonProfile loads the stored profile nearest a point, or nil if none is close enough
+func (h *Handlers) fetchHorizonProfile(ctx context.Context, latitude, longitude float64) (*terrain.Profile, error) {
+	p := &terrain.Profile{}
+	err := h.db.Pool.QueryRow(ctx, `
+		SELECT c.latitude, c.longitude, hz.ground_elevation_m, hz.observer_elevation_m,
+		       hz.azimuth_step, hz.max_distance_m, hz.altitudes
+		FROM geo_city_horizons hz
+		JOIN geo_cities c ON c.id = hz.city_id
+		WHERE ST_DWithin(c.location, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography, $3)
+		ORDER BY c.location <-> ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography
+		LIMIT 1
+	`, latitude, longitude, horizonMatchRadiusMeters).Scan(
+		&p.Latitude, &p.Longitude, &p.GroundElevation, &p.ObserverElevation,
+		&p.AzimuthStep, &p.MaxDistance, &p.Altitudes,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/services"
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
+)
+
+// CreateWebhookRequest is the request body for registering a webhook
+type CreateWebhookRequest struct {
+	// HTTPS endpoint that receives signed POST requests
+	URL string `json:"url" example:"https://example.com/hooks/zmanim"`
+	// Event types to receive; empty subscribes to all
+	EventTypes []string `json:"event_types" example:"algorithm.published"`
+	// Optional label
+	Description *string `json:"description,omitempty"`
+}
+
+// UpdateWebhookRequest is the request body for changing a webhook; omitted fields are unchanged
+type UpdateWebhookRequest struct {
+	URL         *string   `json:"url,omitempty"`
+	EventTypes  *[]string `json:"event_types,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	IsActive    *bool     `json:"is_active,omitempty"`
+}
+
+// SetWebhookService configures the webhook service
+func (h *Handlers) SetWebhookService(s *services.WebhookService) {
+	h.webhookService = s
+}
+
+// emitWebhookEvent enqueues a webhook event for the publisher. Failures are
+// logged and never affect the response of the request that caused the event.
+func (h *Handlers) emitWebhookEvent(ctx context.Context, publisherID, eventType string, data interface{}) {
+	if h.webhookService == nil {
+		return
+	}
+	// The request context may be cancelled as soon as the response is written
+	if err := h.webhookService.Emit(context.WithoutCancel(ctx), publisherID, eventType, data); err != nil {
+		slog.Error("failed to emit webhook event", "error", err, "publisher_id", publisherID, "event_type", eventType)
+	}
+}
+
+// ListPublisherWebhooks returns the publisher's webhook subscriptions
+// @Summary List webhooks
+// @Description Returns the publisher's webhook subscriptions (secrets are never returned)
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Success 200 {object} APIResponse{data=[]services.WebhookSubscription} "Webhook subscriptions"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/webhooks [get]
+func (h *Handlers) ListPublisherWebhooks(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	subs, err := h.webhookService.ListSubscriptions(r.Context(), pc.PublisherID)
+	if err != nil {
+		slog.Error("failed to list webhooks", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to list webhooks")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"webhooks":    subs,
+		"event_types": webhooks.EventTypes,
+	})
+}
+
+// CreatePublisherWebhook registers a webhook endpoint
+// @Summary Create webhook
+// @Description Registers a webhook endpoint. The signing secret is returned only in this response.
+// @Description Deliveries are signed with X-Zmanim-Signature: t=<unix>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>.
+// @Tags Publishers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param request body CreateWebhookRequest true "Endpoint URL and event types"
+// @Success 201 {object} APIResponse{data=services.CreatedWebhookSubscription} "Created webhook including signing secret"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/webhooks [post]
+func (h *Handlers) CreatePublisherWebhook(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	if err := h.webhookService.ValidateURL(req.URL); err != nil {
+		RespondValidationError(w, r, err.Error(), nil)
+		return
+	}
+	eventTypes, err := services.ValidateWebhookEventTypes(req.EventTypes)
+	if err != nil {
+		RespondValidationError(w, r, err.Error(), map[string]interface{}{"valid_event_types": webhooks.EventTypes})
+		return
+	}
+
+	created, err := h.webhookService.CreateSubscription(r.Context(), services.CreateWebhookParams{
+		PublisherID: pc.PublisherID,
+		URL:         req.URL,
+		EventTypes:  eventTypes,
+		Description: req.Description,
+		CreatedBy:   pc.UserID,
+	})
+	if err != nil {
+		slog.Error("failed to create webhook", "error", err, "publisher_id", pc.PublisherID)
+		RespondInternalError(w, r, "Failed to create webhook")
+		return
+	}
+
+	slog.Info("webhook created", "id", created.ID, "publisher_id", pc.PublisherID)
+	RespondJSON(w, r, http.StatusCreated, created)
+}
+
+// UpdatePublisherWebhook changes a webhook's URL, event types, description or active flag
+// @Summary Update webhook
+// @Tags Publishers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Webhook ID"
+// @Param request body UpdateWebhookRequest true "Fields to change"
+// @Success 200 {object} APIResponse{data=services.WebhookSubscription} "Updated webhook"
+// @Failure 400 {object} APIResponse{error=APIError} "Invalid request"
+// @Failure 404 {object} APIResponse{error=APIError} "Webhook not found"
+// @Router /publisher/webhooks/{id} [put]
+func (h *Handlers) UpdatePublisherWebhook(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	params := services.UpdateWebhookParams{
+		Description: req.Description,
+		IsActive:    req.IsActive,
+	}
+	if req.URL != nil {
+		u := strings.TrimSpace(*req.URL)
+		if err := h.webhookService.ValidateURL(u); err != nil {
+			RespondValidationError(w, r, err.Error(), nil)
+			return
+		}
+		params.URL = &u
+	}
+	if req.EventTypes != nil {
+		eventTypes, err := services.ValidateWebhookEventTypes(*req.EventTypes)
+		if err != nil {
+			RespondValidationError(w, r, err.Error(), map[string]interface{}{"valid_event_types": webhooks.EventTypes})
+			return
+		}
+		params.EventTypes = eventTypes
+	}
+
+	sub, err := h.webhookService.UpdateSubscription(r.Context(), chi.URLParam(r, "id"), pc.PublisherID, params)
+	if err != nil {
+		h.respondWebhookError(w, r, err, "Failed to update webhook")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, sub)
+}
+
+// DeletePublisherWebhook removes a webhook and its delivery log
+// @Summary Delete webhook
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} APIResponse{data=object} "Deletion confirmation"
+// @Failure 404 {object} APIResponse{error=APIError} "Webhook not found"
+// @Router /publisher/webhooks/{id} [delete]
+func (h *Handlers) DeletePublisherWebhook(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := h.webhookService.DeleteSubscription(r.Context(), id, pc.PublisherID); err != nil {
+		h.respondWebhookError(w, r, err, "Failed to delete webhook")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"deleted": true,
+	})
+}
+
+// GetPublisherWebhookDeliveries returns the delivery log of a webhook
+// @Summary Webhook delivery log
+// @Description Returns deliveries newest first with attempt counts, last response and next retry time
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Filter by status (pending, delivering, delivered, failed)"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} APIResponse{data=[]services.WebhookDelivery} "Deliveries"
+// @Failure 404 {object} APIResponse{error=APIError} "Webhook not found"
+// @Router /publisher/webhooks/{id}/deliveries [get]
+func (h *Handlers) GetPublisherWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	status := query.Get("status")
+	switch status {
+	case "", services.WebhookStatusPending, services.WebhookStatusDelivering, services.WebhookStatusDelivered, services.WebhookStatusFailed:
+	default:
+		RespondValidationError(w, r, "status must be one of pending, delivering, delivered, failed", nil)
+		return
+	}
+
+	limit := 50
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := parseIntParam(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+	offset := 0
+	if o := query.Get("offset"); o != "" {
+		if parsed, err := parseIntParam(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), chi.URLParam(r, "id"), pc.PublisherID, status, limit, offset)
+	if err != nil {
+		h.respondWebhookError(w, r, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusOK, deliveries)
+}
+
+// TestPublisherWebhook sends a ping event to a webhook
+// @Summary Send test event
+// @Description Queues a "ping" event for the webhook; check the delivery log for the result
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Webhook ID"
+// @Success 202 {object} APIResponse{data=object} "Queued delivery ID"
+// @Failure 404 {object} APIResponse{error=APIError} "Webhook not found"
+// @Router /publisher/webhooks/{id}/test [post]
+func (h *Handlers) TestPublisherWebhook(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	deliveryID, err := h.webhookService.SendTest(r.Context(), chi.URLParam(r, "id"), pc.PublisherID)
+	if err != nil {
+		h.respondWebhookError(w, r, err, "Failed to send test event")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"delivery_id": deliveryID,
+		"event_type":  webhooks.EventPing,
+	})
+}
+
+// RedeliverPublisherWebhook requeues a delivery
+// @Summary Redeliver webhook event
+// @Description Requeues a delivery for immediate sending with a fresh retry budget
+// @Tags Publishers
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param id path string true "Webhook ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} APIResponse{data=object} "Requeued"
+// @Failure 404 {object} APIResponse{error=APIError} "Delivery not found or in progress"
+// @Router /publisher/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *Handlers) RedeliverPublisherWebhook(w http.ResponseWriter, r *http.Request) {
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	deliveryID := chi.URLParam(r, "deliveryId")
+	if err := h.webhookService.Redeliver(r.Context(), deliveryID, chi.URLParam(r, "id"), pc.PublisherID); err != nil {
+		h.respondWebhookError(w, r, err, "Failed to requeue delivery")
+		return
+	}
+
+	RespondJSON(w, r, http.StatusAccepted, map[string]interface{}{
+		"delivery_id": deliveryID,
+		"status":      services.WebhookStatusPending,
+	})
+}
+
+// respondWebhookError maps service errors to responses
+func (h *Handlers) respondWebhookError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		RespondNotFound(w, r, "Webhook not found")
+		return
+	}
+	slog.Error(message, "error", err)
+	RespondInternalError(w, r, message)
+}
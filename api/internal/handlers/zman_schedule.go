@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+)
+
+// ZmanScheduledFormula is a formula version that applies to a date range.
+// Dates are YYYY-MM-DD; from is inclusive, until is exclusive, nil is unbounded.
+type ZmanScheduledFormula struct {
+	VersionNumber  int       `json:"version_number"`
+	FormulaDSL     string    `json:"formula_dsl"`
+	EffectiveFrom  *string   `json:"effective_from,omitempty"`
+	EffectiveUntil *string   `json:"effective_until,omitempty"`
+	ChangeReason   *string   `json:"change_reason,omitempty"`
+	CreatedBy      *string   `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// contains reports whether the formula is in force on date (YYYY-MM-DD)
+func (s ZmanScheduledFormula) contains(date string) bool {
+	if s.EffectiveFrom != nil && date < *s.EffectiveFrom {
+		return false
+	}
+	if s.EffectiveUntil != nil && date >= *s.EffectiveUntil {
+		return false
+	}
+	return true
+}
+
+// resolveScheduledFormula returns the scheduled formula in force on date, or nil
+// when the zman's base formula applies. Overlapping ranges resolve to the latest
+// effective_from (an unbounded start sorts first), then the highest version.
+func resolveScheduledFormula(schedule []ZmanScheduledFormula, date string) *ZmanScheduledFormula {
+	var best *ZmanScheduledFormula
+	for i := range schedule {
+		s := &schedule[i]
+		if !s.contains(date) {
+			continue
+		}
+		if best == nil || laterSchedule(s, best) {
+			best = s
+		}
+	}
+	return best
+}
+
+// laterSchedule reports whether a takes precedence over b
+func laterSchedule(a, b *ZmanScheduledFormula) bool {
+	aFrom, bFrom := "", ""
+	if a.EffectiveFrom != nil {
+		aFrom = *a.EffectiveFrom
+	}
+	if b.EffectiveFrom != nil {
+		bFrom = *b.EffectiveFrom
+	}
+	if aFrom != bFrom {
+		return aFrom > bFrom
+	}
+	return a.VersionNumber > b.VersionNumber
+}
+
+// applyScheduledFormulas recalculates the /zmanim entries whose publisher zman
+// has a formula version in force on date, so the public endpoint follows the
+// same schedule as the publisher's own views. A scheduled formula that fails
+// leaves the zman without a time rather than with the superseded one.
+func applyScheduledFormulas(zmanim []ZmanWithFormula, publisherZmanim []PublisherZman, date string, execCtx *dsl.ExecutionContext) {
+	scheduled := make(map[string]*ZmanScheduledFormula)
+	for _, z := range publisherZmanim {
+		if !z.IsEnabled {
+			continue
+		}
+		if s := resolveScheduledFormula(z.Schedule, date); s != nil {
+			scheduled[z.ZmanKey] = s
+		}
+	}
+
+	for i := range zmanim {
+		s, ok := scheduled[zmanim[i].Key]
+		if !ok {
+			continue
+		}
+		zmanim[i].Time = ""
+		zmanim[i].Formula = FormulaDetails{
+			Method:         "dsl",
+			DisplayName:    zmanim[i].Formula.DisplayName,
+			DSL:            s.FormulaDSL,
+			Parameters:     map[string]interface{}{"version": s.VersionNumber},
+			Explanation:    fmt.Sprintf("Formula version %d, scheduled for this date", s.VersionNumber),
+			HalachicSource: zmanim[i].Formula.HalachicSource,
+		}
+		t, err := dsl.ExecuteFormula(s.FormulaDSL, execCtx)
+		if err != nil {
+			slog.Warn("scheduled formula failed", "error", err, "zman_key", zmanim[i].Key, "version", s.VersionNumber)
+			continue
+		}
+		zmanim[i].Time = astro.FormatTime(t)
+	}
+}
+
+// ZmanFormulaTransition marks a day on which a zman switches formula
+type ZmanFormulaTransition struct {
+	Date        string `json:"date"`
+	ZmanKey     string `json:"zman_key"`
+	FromFormula string `json:"from_formula"`
+	ToFormula   string `json:"to_formula"`
+	// ToVersion is the scheduled version taking effect (nil when reverting to the base formula)
+	ToVersion *int `json:"to_version,omitempty"`
+}
+
+// formulaTransitions lists the formula changes between consecutive days
+func formulaTransitions(days []WeekDayZmanim) []ZmanFormulaTransition {
+	transitions := []ZmanFormulaTransition{}
+	previous := make(map[string]string)
+	for i, day := range days {
+		for _, z := range day.Zmanim {
+			prev, seen := previous[z.ZmanKey]
+			if i > 0 && seen && prev != z.FormulaDSL {
+				transitions = append(transitions, ZmanFormulaTransition{
+					Date:        day.DayContext.Date,
+					ZmanKey:     z.ZmanKey,
+					FromFormula: prev,
+					ToFormula:   z.FormulaDSL,
+					ToVersion:   z.ScheduledVersion,
+				})
+			}
+			previous[z.ZmanKey] = z.FormulaDSL
+		}
+	}
+	return transitions
+}
+
+// scheduledFormulaColumns is the column list scanned by scanScheduledFormula
+const scheduledFormulaColumns = `pzv.version_number, COALESCE(pzv.formula_dsl, ''),
+	to_char(pzv.effective_from, 'YYYY-MM-DD'), to_char(pzv.effective_until, 'YYYY-MM-DD'),
+	pzv.change_reason, pzv.created_by, pzv.created_at`
+
+// scanScheduledFormula scans a row selected with scheduledFormulaColumns
+func scanScheduledFormula(row pgx.Row) (ZmanScheduledFormula, error) {
+	var s ZmanScheduledFormula
+	err := row.Scan(&s.VersionNumber, &s.FormulaDSL, &s.EffectiveFrom, &s.EffectiveUntil,
+		&s.ChangeReason, &s.CreatedBy, &s.CreatedAt)
+	return s, err
+}
+
+// fetchZmanSchedules returns the scheduled formulas of all a publisher's zmanim, keyed by publisher_zman_id
+func (h *Handlers) fetchZmanSchedules(ctx context.Context, publisherID string) (map[string][]ZmanScheduledFormula, error) {
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT pzv.publisher_zman_id, `+scheduledFormulaColumns+`
+		FROM publisher_zman_versions pzv
+		JOIN publisher_zmanim pz ON pz.id = pzv.publisher_zman_id
+		WHERE pz.publisher_id = $1
+		  AND pz.deleted_at IS NULL
+		  AND (pzv.effective_from IS NOT NULL OR pzv.effective_until IS NOT NULL)
+		ORDER BY pzv.effective_from NULLS FIRST, pzv.version_number
+	`, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make(map[string][]ZmanScheduledFormula)
+	for rows.Next() {
+		var zmanID string
+		var s ZmanScheduledFormula
+		if err := rows.Scan(&zmanID, &s.VersionNumber, &s.FormulaDSL, &s.EffectiveFrom, &s.EffectiveUntil,
+			&s.ChangeReason, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		schedules[zmanID] = append(schedules[zmanID], s)
+	}
+	return schedules, rows.Err()
+}
+
+// scheduleZmanFormula records a formula version that applies from/until the given dates
+func (h *Handlers) scheduleZmanFormula(ctx context.Context, publisherID, zmanKey, formula string, from, until, reason *string, userID string) (ZmanScheduledFormula, error) {
+	var createdBy *string
+	if userID != "" {
+		createdBy = &userID
+	}
+	return scanScheduledFormula(h.db.Pool.QueryRow(ctx, `
+		INSERT INTO publisher_zman_versions AS pzv (
+			publisher_zman_id, version_number, hebrew_name, english_name, formula_dsl,
+			created_by, change_reason, effective_from, effective_until
+		)
+		SELECT
+			pz.id,
+			COALESCE((SELECT MAX(version_number) FROM publisher_zman_versions WHERE publisher_zman_id = pz.id), 0) + 1,
+			pz.hebrew_name, pz.english_name, $3, $4, $5, $6::date, $7::date
+		FROM publisher_zmanim pz
+		WHERE pz.publisher_id = $1 AND pz.zman_key = $2 AND pz.deleted_at IS NULL
+		RETURNING `+scheduledFormulaColumns,
+		publisherID, zmanKey, formula, createdBy, reason, from, until))
+}
+
+// validateEffectiveRange checks YYYY-MM-DD bounds and that until is after from
+func validateEffectiveRange(from, until *string) map[string]string {
+	errs := make(map[string]string)
+	if from != nil {
+		if _, err := time.Parse("2006-01-02", *from); err != nil {
+			errs["effective_from"] = "must be a date (YYYY-MM-DD)"
+		}
+	}
+	if until != nil {
+		if _, err := time.Parse("2006-01-02", *until); err != nil {
+			errs["effective_until"] = "must be a date (YYYY-MM-DD)"
+		}
+	}
+	if len(errs) == 0 && from != nil && until != nil && *until <= *from {
+		errs["effective_until"] = "must be after effective_from"
+	}
+	return errs
+}
+
+// GetZmanSchedule lists the scheduled (effective-dated) formulas of a zman
+// @Summary Get scheduled formulas for a zman
+// @Description Returns formula versions that apply to a date range, ordered by start date
+// @Tags Publisher Zmanim
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param zmanKey path string true "Zman key"
+// @Success 200 {object} APIResponse{data=[]ZmanScheduledFormula} "Scheduled formulas"
+// @Failure 401 {object} APIResponse{error=APIError} "Unauthorized"
+// @Failure 500 {object} APIResponse{error=APIError} "Internal server error"
+// @Router /publisher/zmanim/{zmanKey}/schedule [get]
+func (h *Handlers) GetZmanSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	rows, err := h.db.Pool.Query(ctx, `
+		SELECT `+scheduledFormulaColumns+`
+		FROM publisher_zman_versions pzv
+		JOIN publisher_zmanim pz ON pz.id = pzv.publisher_zman_id
+		WHERE pz.publisher_id = $1 AND pz.zman_key = $2
+		  AND (pzv.effective_from IS NOT NULL OR pzv.effective_until IS NOT NULL)
+		ORDER BY pzv.effective_from NULLS FIRST, pzv.version_number
+	`, pc.PublisherID, chi.URLParam(r, "zmanKey"))
+	if err != nil {
+		slog.Error("error getting zman schedule", "error", err)
+		RespondInternalError(w, r, "Failed to get schedule")
+		return
+	}
+	defer rows.Close()
+
+	schedule := []ZmanScheduledFormula{}
+	for rows.Next() {
+		s, err := scanScheduledFormula(rows)
+		if err != nil {
+			slog.Error("error scanning scheduled formula", "error", err)
+			continue
+		}
+		schedule = append(schedule, s)
+	}
+
+	RespondJSON(w, r, http.StatusOK, schedule)
+}
+
+// CancelZmanSchedule removes a scheduled formula
+// @Summary Cancel a scheduled formula
+// @Description Deletes an effective-dated version; dates it covered fall back to other versions or the base formula
+// @Tags Publisher Zmanim
+// @Produce json
+// @Security BearerAuth
+// @Param X-Publisher-Id header string true "Publisher ID"
+// @Param zmanKey path string true "Zman key"
+// @Param version path int true "Version number"
+// @Success 200 {object} APIResponse{data=object} "Cancellation confirmation"
+// @Failure 404 {object} APIResponse{error=APIError} "Scheduled version not found"
+// @Router /publisher/zmanim/{zmanKey}/schedule/{version} [delete]
+func (h *Handlers) CancelZmanSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	zmanKey := chi.URLParam(r, "zmanKey")
+
+	version, err := parseIntParam(chi.URLParam(r, "version"))
+	if err != nil {
+		RespondBadRequest(w, r, "Invalid version number")
+		return
+	}
+
+	pc := h.publisherResolver.MustResolve(w, r)
+	if pc == nil {
+		return
+	}
+
+	var deleted int
+	err = h.db.Pool.QueryRow(ctx, `
+		DELETE FROM publisher_zman_versions pzv
+		USING publisher_zmanim pz
+		WHERE pz.id = pzv.publisher_zman_id
+		  AND pz.publisher_id = $1 AND pz.zman_key = $2 AND pzv.version_number = $3
+		  AND (pzv.effective_from IS NOT NULL OR pzv.effective_until IS NOT NULL)
+		RETURNING pzv.version_number
+	`, pc.PublisherID, zmanKey, version).Scan(&deleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		RespondNotFound(w, r, "Scheduled version not found")
+		return
+	}
+	if err != nil {
+		slog.Error("error cancelling scheduled formula", "error", err)
+		RespondInternalError(w, r, "Failed to cancel scheduled formula")
+		return
+	}
+
+	if h.cache != nil {
+		if err := h.cache.InvalidatePublisherCache(ctx, pc.PublisherID); err != nil {
+			slog.Warn("failed to invalidate cache", "error", err, "publisher_id", pc.PublisherID)
+		}
+	}
+
+	RespondJSON(w, r, http.StatusOK, map[string]interface{}{
+		"zman_key":       zmanKey,
+		"version_number": deleted,
+		"cancelled":      true,
+	})
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestResolveScheduledFormula(t *testing.T) {
+	schedule := []ZmanScheduledFormula{
+		// 8.5° from Rosh Hashana onward
+		{VersionNumber: 2, FormulaDSL: "solar(8.5, after_sunset)", EffectiveFrom: strPtr("2025-09-23")},
+		// Temporary override inside that range
+		{VersionNumber: 3, FormulaDSL: "sunset + 50min", EffectiveFrom: strPtr("2025-10-01"), EffectiveUntil: strPtr("2025-10-08")},
+		// Pinned formula for historical dates
+		{VersionNumber: 4, FormulaDSL: "sunset + 42min", EffectiveUntil: strPtr("2025-01-01")},
+	}
+
+	tests := []struct {
+		date        string
+		wantVersion int // 0 = base formula
+	}{
+		{"2024-12-31", 4},
+		{"2025-01-01", 0},
+		{"2025-09-22", 0},
+		{"2025-09-23", 2},
+		{"2025-10-01", 3},
+		{"2025-10-07", 3},
+		{"2025-10-08", 2},
+		{"2030-01-01", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			got := resolveScheduledFormula(schedule, tt.date)
+			gotVersion := 0
+			if got != nil {
+				gotVersion = got.VersionNumber
+			}
+			if gotVersion != tt.wantVersion {
+				t.Errorf("version = %d, want %d", gotVersion, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestFilterAndCalculateZmanimUsesScheduledFormula(t *testing.T) {
+	h := &Handlers{}
+	zmanim := []PublisherZman{{
+		ID:         "z1",
+		ZmanKey:    "tzeis",
+		FormulaDSL: "sunset + 42min",
+		IsEnabled:  true,
+		Schedule: []ZmanScheduledFormula{
+			{VersionNumber: 5, FormulaDSL: "sunset + 72min", EffectiveFrom: strPtr("2025-03-05")},
+		},
+	}}
+
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	days := make([]WeekDayZmanim, 4)
+	for i := range days {
+		date := start.AddDate(0, 0, i)
+		days[i] = WeekDayZmanim{
+			DayContext: DayContext{Date: date.Format("2006-01-02")},
+			Zmanim:     h.filterAndCalculateZmanim(zmanim, DayContext{}, date, 40.7128, -74.0060, "America/New_York", calculationSettings{}),
+		}
+	}
+
+	before, after := days[1].Zmanim[0], days[2].Zmanim[0]
+	if before.FormulaDSL != "sunset + 42min" || before.ScheduledVersion != nil {
+		t.Errorf("before transition: formula %q version %v", before.FormulaDSL, before.ScheduledVersion)
+	}
+	if after.FormulaDSL != "sunset + 72min" || after.ScheduledVersion == nil || *after.ScheduledVersion != 5 {
+		t.Errorf("after transition: formula %q version %v", after.FormulaDSL, after.ScheduledVersion)
+	}
+	if before.Time == nil || after.Time == nil {
+		t.Fatal("expected calculated times")
+	}
+
+	transitions := formulaTransitions(days)
+	if len(transitions) != 1 {
+		t.Fatalf("transitions = %d, want 1", len(transitions))
+	}
+	if tr := transitions[0]; tr.Date != "2025-03-05" || tr.FromFormula != "sunset + 42min" || tr.ToFormula != "sunset + 72min" {
+		t.Errorf("unexpected transition %+v", tr)
+	}
+}
+
+func TestApplyScheduledFormulas(t *testing.T) {
+	publisherZmanim := []PublisherZman{{
+		ZmanKey:    "tzais",
+		FormulaDSL: "solar(8.5, after_sunset)",
+		IsEnabled:  true,
+		Schedule: []ZmanScheduledFormula{
+			{VersionNumber: 3, FormulaDSL: "sunset + 72min", EffectiveFrom: strPtr("2025-03-05")},
+			{VersionNumber: 4, FormulaDSL: "not a formula", EffectiveFrom: strPtr("2025-04-01")},
+		},
+	}}
+	ny, _ := time.LoadLocation("America/New_York")
+
+	for _, tt := range []struct {
+		date       string
+		wantMethod string
+		wantTime   func(sunset time.Time) string
+	}{
+		{"2025-03-04", "solar_angle", func(time.Time) string { return "18:40:00" }},
+		{"2025-03-05", "dsl", func(sunset time.Time) string { return astro.FormatTime(sunset.Add(72 * time.Minute)) }},
+		{"2025-04-01", "dsl", func(time.Time) string { return "" }},
+	} {
+		t.Run(tt.date, func(t *testing.T) {
+			date, _ := time.ParseInLocation("2006-01-02", tt.date, ny)
+			execCtx := dsl.NewExecutionContext(date, 40.7128, -74.0060, 0, ny)
+			zmanim := []ZmanWithFormula{
+				{Key: "sunset", Time: "18:00:00", Formula: FormulaDetails{Method: "sunset"}},
+				{Key: "tzais", Time: "18:40:00", Formula: FormulaDetails{Method: "solar_angle"}},
+			}
+
+			applyScheduledFormulas(zmanim, publisherZmanim, tt.date, execCtx)
+
+			sunset := astro.CalculateSunTimes(date, 40.7128, -74.0060, ny).Sunset
+			if got := zmanim[1]; got.Formula.Method != tt.wantMethod || got.Time != tt.wantTime(sunset) {
+				t.Errorf("tzais = %s by %s, want %s by %s", got.Time, got.Formula.Method, tt.wantTime(sunset), tt.wantMethod)
+			}
+			if zmanim[0].Time != "18:00:00" {
+				t.Errorf("sunset changed to %s, but it has no schedule", zmanim[0].Time)
+			}
+		})
+	}
+}
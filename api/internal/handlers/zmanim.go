@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/jcom-dev/zmanim-lab/internal/algorithm"
 	"github.com/jcom-dev/zmanim-lab/internal/astro"
 	"github.com/jcom-dev/zmanim-lab/internal/calendar"
+	"github.com/jcom-dev/zmanim-lab/internal/dsl"
 	"github.com/jcom-dev/zmanim-lab/internal/middleware"
 	"github.com/jcom-dev/zmanim-lab/internal/models"
 )
@@ -28,6 +31,8 @@ type ZmanimWithFormulaResponse struct {
 	Zmanim    []ZmanWithFormula    `json:"zmanim"`
 	Cached    bool                 `json:"cached"`
 	CachedAt  *time.Time           `json:"cached_at,omitempty"`
+	// Resolution is set for coordinate-only requests
+	Resolution *LocationResolution `json:"resolution,omitempty"`
 }
 
 // ZmanimPublisherInfo contains publisher details for the response
@@ -47,6 +52,10 @@ type ZmanimLocationInfo struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Timezone  string  `json:"timezone"`
+
+	// Publisher location, for locationId requests
+	LocationID   string `json:"location_id,omitempty"`
+	LocationName string `json:"location_name,omitempty"`
 }
 
 // ZmanWithFormula represents a single zman with formula details
@@ -74,11 +83,14 @@ type FormulaDetails struct {
 
 // GetZmanimForCity calculates zmanim for a city with formula details
 // @Summary Get zmanim for a city
-// @Description Calculates Jewish prayer times (zmanim) for a specific city and date, optionally using a publisher's custom algorithm
+// @Description Calculates Jewish prayer times (zmanim) for a city or a bare coordinate and a date, optionally using a publisher's custom algorithm, solar engine and the formula versions it has scheduled for the date
 // @Tags Zmanim
 // @Accept json
 // @Produce json
-// @Param cityId query string true "City ID from the cities database"
+// @Param cityId query string false "City ID from the cities database (or give lat and lng)"
+// @Param lat query number false "Latitude, for coordinate-only requests (timezone, elevation and Israel status are resolved automatically)"
+// @Param lng query number false "Longitude, for coordinate-only requests"
+// @Param locationId query string false "Publisher location ID, published by coverage or the caller's own (uses the location's coordinates, elevation and timezone; publisherId defaults to its publisher)"
 // @Param publisherId query string false "Publisher ID for custom algorithm (uses default if not specified)"
 // @Param date query string false "Date in YYYY-MM-DD format (defaults to today)"
 // @Success 200 {object} APIResponse{data=ZmanimWithFormulaResponse} "Calculated zmanim with formula details"
@@ -91,13 +103,53 @@ func (h *Handlers) GetZmanimForCity(w http.ResponseWriter, r *http.Request) {
 
 	// Parse query parameters
 	cityID := r.URL.Query().Get("cityId")
+	locationID := r.URL.Query().Get("locationId")
 	publisherID := r.URL.Query().Get("publisherId")
 	dateStr := r.URL.Query().Get("date")
 
-	// Validate required parameters
-	if cityID == "" {
-		RespondBadRequest(w, r, "cityId parameter is required")
-		return
+	// A city, a publisher location or a coordinate is required
+	var latitude, longitude float64
+	var location *models.PublisherLocation
+	coordinateMode := cityID == "" && locationID == ""
+	if coordinateMode {
+		latStr, lngStr := r.URL.Query().Get("lat"), r.URL.Query().Get("lng")
+		if latStr == "" || lngStr == "" {
+			RespondBadRequest(w, r, "cityId, locationId, or lat and lng parameters are required")
+			return
+		}
+		var latErr, lngErr error
+		latitude, latErr = strconv.ParseFloat(latStr, 64)
+		longitude, lngErr = strconv.ParseFloat(lngStr, 64)
+		if latErr != nil || lngErr != nil || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			RespondBadRequest(w, r, "lat must be between -90 and 90 and lng between -180 and 180")
+			return
+		}
+	}
+
+	if cityID == "" && locationID != "" {
+		var err error
+		location, err = h.fetchVisibleLocation(r, locationID)
+		if err != nil {
+			slog.Error("failed to fetch location", "error", err, "location_id", locationID)
+			RespondInternalError(w, r, "Failed to fetch location")
+			return
+		}
+		if location == nil {
+			RespondNotFound(w, r, "Location not found")
+			return
+		}
+		// A location is calculated with its own publisher's algorithm by default
+		if publisherID == "" {
+			publisherID = location.PublisherID
+		}
+	}
+
+	// Coordinates are cached at about 10 m resolution
+	cacheLocationKey := cityID
+	if location != nil {
+		cacheLocationKey = "location:" + location.ID
+	} else if coordinateMode {
+		cacheLocationKey = fmt.Sprintf("%.4f,%.4f", latitude, longitude)
 	}
 
 	// Default publisher ID for cache key
@@ -120,7 +172,7 @@ func (h *Handlers) GetZmanimForCity(w http.ResponseWriter, r *http.Request) {
 
 	// Check cache first (if available)
 	if h.cache != nil {
-		cached, err := h.cache.GetZmanim(ctx, cachePublisherID, cityID, dateStr)
+		cached, err := h.cache.GetZmanim(ctx, cachePublisherID, cacheLocationKey, dateStr)
 		if err != nil {
 			slog.Error("cache read error", "error", err)
 		} else if cached != nil {
@@ -135,24 +187,47 @@ func (h *Handlers) GetZmanimForCity(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get city details
+	// Get city details, or resolve the coordinate
 	var cityName, country, timezone string
 	var region *string
-	var latitude, longitude float64
+	var elevation float64
+	var resolution *LocationResolution
+	isIsrael := false
 
-	cityQuery := `
-		SELECT c.name, co.name as country, r.name as region, c.timezone, c.latitude, c.longitude
-		FROM cities c
-		JOIN geo_regions r ON c.region_id = r.id
-		JOIN geo_countries co ON r.country_id = co.id
-		WHERE c.id = $1
-	`
-	err = h.db.Pool.QueryRow(ctx, cityQuery, cityID).Scan(
-		&cityName, &country, &region, &timezone, &latitude, &longitude,
-	)
-	if err != nil {
-		RespondNotFound(w, r, "City not found")
-		return
+	if coordinateMode {
+		resolution, err = h.resolvePoint(ctx, latitude, longitude)
+		if err != nil {
+			slog.Error("failed to resolve location", "error", err, "latitude", latitude, "longitude", longitude)
+			RespondInternalError(w, r, "Failed to resolve location")
+			return
+		}
+		timezone = resolution.Timezone
+		elevation = resolution.Elevation
+		isIsrael = resolution.IsIsrael
+	} else if location != nil {
+		latitude, longitude = location.Latitude, location.Longitude
+		timezone = location.Timezone
+		elevation = location.ElevationM
+		isIsrael, _ = h.israelStatus(ctx, latitude, longitude)
+	} else {
+		cityQuery := `
+			SELECT c.name, COALESCE(co.name, '') as country, r.name as region, c.timezone, c.latitude, c.longitude, c.elevation_m
+			FROM geo_cities c
+			LEFT JOIN geo_regions r ON c.region_id = r.id
+			LEFT JOIN geo_countries co ON c.country_id = co.id
+			WHERE c.id = $1
+		`
+		var cityElevation *int
+		err = h.db.Pool.QueryRow(ctx, cityQuery, cityID).Scan(
+			&cityName, &country, &region, &timezone, &latitude, &longitude, &cityElevation,
+		)
+		if err != nil {
+			RespondNotFound(w, r, "City not found")
+			return
+		}
+		// Resolved like a coordinate, with the city's own elevation as the fallback
+		elevation, _ = h.elevationAt(latitude, longitude, cityElevation, sourceCity)
+		isIsrael, _ = h.israelStatus(ctx, latitude, longitude)
 	}
 
 	// Load timezone
@@ -200,8 +275,15 @@ func (h *Handlers) GetZmanimForCity(w http.ResponseWriter, r *http.Request) {
 		algorithmConfig = algorithm.DefaultAlgorithm()
 	}
 
-	// Execute algorithm
-	executor := algorithm.NewExecutor(date, latitude, longitude, loc)
+	// Execute algorithm with the publisher's solar engine; a publisher location
+	// brings its own horizon
+	settings := calculationSettings{engine: astro.DefaultEngine, elevation: elevation}
+	if location != nil {
+		settings = h.locationCalculationSettings(ctx, publisherID, location)
+	} else if publisherID != "" {
+		settings.engine = h.publisherSolarEngine(ctx, publisherID)
+	}
+	executor := algorithm.NewExecutorWithEngine(date, latitude, longitude, settings.elevation, loc, settings.engine)
 	results, err := executor.Execute(algorithmConfig)
 	if err != nil {
 		RespondInternalError(w, r, "Failed to calculate zmanim")
@@ -294,9 +376,14 @@ func (h *Handlers) GetZmanimForCity(w http.ResponseWriter, r *http.Request) {
 			Longitude: longitude,
 			Timezone:  timezone,
 		},
-		Publisher: publisherInfo,
-		Zmanim:    make([]ZmanWithFormula, 0, len(results.Zmanim)),
-		Cached:    false,
+		Publisher:  publisherInfo,
+		Zmanim:     make([]ZmanWithFormula, 0, len(results.Zmanim)),
+		Cached:     false,
+		Resolution: resolution,
+	}
+	if location != nil {
+		response.Location.LocationID = location.ID
+		response.Location.LocationName = location.Name
 	}
 
 	for _, zman := range results.Zmanim {
@@ -327,9 +414,21 @@ func (h *Handlers) GetZmanimForCity(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	// A publisher's formula versions scheduled for this date replace the algorithm's
+	if publisherID != "" {
+		publisherZmanim, err := h.fetchPublisherZmanim(ctx, publisherID)
+		if err != nil {
+			slog.Error("failed to fetch publisher zmanim", "error", err, "publisher_id", publisherID)
+			RespondInternalError(w, r, "Failed to calculate zmanim")
+			return
+		}
+		execCtx := dsl.NewExecutionContext(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc), latitude, longitude, settings.elevation, loc)
+		settings.apply(execCtx)
+		applyScheduledFormulas(response.Zmanim, publisherZmanim, dateStr, execCtx)
+	}
+
 	// Add event-based zmanim (candle lighting, havdalah)
 	calService := calendar.NewCalendarService()
-	isIsrael := calendar.IsLocationInIsrael(latitude, longitude)
 	zmanimContext := calService.GetZmanimContext(date.In(loc), calendar.Location{
 		Latitude:  latitude,
 		Longitude: longitude,
@@ -403,7 +502,7 @@ func (h *Handlers) GetZmanimForCity(w http.ResponseWriter, r *http.Request) {
 
 	// Cache the result (if cache available)
 	if h.cache != nil {
-		if err := h.cache.SetZmanim(ctx, cachePublisherID, cityID, dateStr, response); err != nil {
+		if err := h.cache.SetZmanim(ctx, cachePublisherID, cacheLocationKey, dateStr, response); err != nil {
 			slog.Error("cache write error", "error", err)
 		}
 	}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/astro"
+	"github.com/jcom-dev/zmanim-lab/internal/models"
+)
+
+// getZmanim calls GET /zmanim and returns the zmanim times by key
+func getZmanim(t *testing.T, h *Handlers, query url.Values) map[string]ZmanWithFormula {
+	t.Helper()
+	helper := NewTestHelper(t)
+	w := httptest.NewRecorder()
+	h.GetZmanimForCity(w, helper.MakeRequest("GET", "/api/v1/zmanim?"+query.Encode(), nil))
+	helper.AssertStatus(w, http.StatusOK)
+	var resp struct {
+		Data ZmanimWithFormulaResponse `json:"data"`
+	}
+	helper.ParseJSONResponse(w, &resp)
+	zmanim := make(map[string]ZmanWithFormula, len(resp.Data.Zmanim))
+	for _, z := range resp.Data.Zmanim {
+		zmanim[z.Key] = z
+	}
+	return zmanim
+}
+
+func TestGetZmanimForCityUsesPublisherEngine(t *testing.T) {
+	h := newDBTestHandlers(t)
+	f := newGeoFixture(t, h)
+
+	// A coordinate beside a fixture city resolves to UTC at sea level
+	const lat, lng = -48.0, -125.0
+	f.city("Alpha", 0, lat, lng+0.1, 100)
+	noaa := f.publisher("Fixture NOAA")
+	spa := f.publisher("Fixture SPA")
+
+	// Thin air bends the sun less, so SPA's sunrise is visibly later
+	pressure := 500.0
+	settings := SolarEngineSettings{Engine: astro.EngineSPA, PressureMbar: &pressure}
+	if _, err := h.db.Pool.Exec(context.Background(), `
+		UPDATE publishers SET solar_engine = $2, solar_pressure_mbar = $3 WHERE id = $1
+	`, spa, settings.Engine, pressure); err != nil {
+		t.Fatalf("select SPA: %v", err)
+	}
+	spaEngine, err := settings.engine()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2025, 6, 21, 0, 0, 0, 0, time.UTC)
+	want := map[string]string{
+		noaa: astro.FormatTime(astro.DefaultEngine.SunTimes(date, lat, lng, 0, time.UTC).Sunrise),
+		spa:  astro.FormatTime(spaEngine.SunTimes(date, lat, lng, 0, time.UTC).Sunrise),
+	}
+	if want[noaa] == want[spa] {
+		t.Fatalf("NOAA and SPA agree on sunrise (%s), so the test cannot tell them apart", want[noaa])
+	}
+
+	for publisherID, sunrise := range want {
+		got := getZmanim(t, h, url.Values{
+			"lat": {"-48"}, "lng": {"-125"}, "date": {"2025-06-21"}, "publisherId": {publisherID},
+		})
+		if got["sunrise"].Time != sunrise {
+			t.Errorf("publisher %s sunrise = %q, want %q", publisherID, got["sunrise"].Time, sunrise)
+		}
+	}
+}
+
+// addPublisherZman gives a publisher a zman with a base formula
+func addPublisherZman(t *testing.T, h *Handlers, publisherID, key, formula string) string {
+	t.Helper()
+	var id string
+	if err := h.db.Pool.QueryRow(context.Background(), `
+		INSERT INTO publisher_zmanim (publisher_id, zman_key, hebrew_name, formula_dsl)
+		VALUES ($1, $2, $2, $3) RETURNING id::text
+	`, publisherID, key, formula).Scan(&id); err != nil {
+		t.Fatalf("insert publisher zman %s: %v", key, err)
+	}
+	return id
+}
+
+func TestGetZmanimForCityFollowsFormulaSchedule(t *testing.T) {
+	h := newDBTestHandlers(t)
+	f := newGeoFixture(t, h)
+
+	const lat, lng = -48.0, -125.0
+	f.city("Alpha", 0, lat, lng+0.1, 100)
+	publisher := f.publisher("Fixture Scheduled")
+	zmanID := addPublisherZman(t, h, publisher, "tzais", "solar(8.5, after_sunset)")
+	if _, err := h.db.Pool.Exec(context.Background(), `
+		INSERT INTO publisher_zman_versions (publisher_zman_id, version_number, hebrew_name, formula_dsl, effective_from)
+		VALUES ($1, 2, 'tzais', 'sunset + 72min', '2025-03-05')
+	`, zmanID); err != nil {
+		t.Fatalf("schedule formula: %v", err)
+	}
+
+	for _, tt := range []struct {
+		date       string
+		wantMethod string
+	}{
+		{"2025-03-04", "solar_angle"},
+		{"2025-03-05", "dsl"},
+	} {
+		got := getZmanim(t, h, url.Values{
+			"lat": {"-48"}, "lng": {"-125"}, "date": {tt.date}, "publisherId": {publisher},
+		})["tzais"]
+		if got.Formula.Method != tt.wantMethod {
+			t.Errorf("%s: tzais calculated by %q, want %q", tt.date, got.Formula.Method, tt.wantMethod)
+		}
+		if tt.wantMethod != "dsl" {
+			continue
+		}
+		date, _ := time.Parse("2006-01-02", tt.date)
+		sunset := astro.DefaultEngine.SunTimes(date, lat, lng, 0, time.UTC).Sunset
+		if want := astro.FormatTime(sunset.Add(72 * time.Minute)); got.Time != want || got.Formula.DSL != "sunset + 72min" {
+			t.Errorf("%s: tzais = %s by %q, want %s by the scheduled formula", tt.date, got.Time, got.Formula.DSL, want)
+		}
+	}
+}
+
+func TestGetZmanimForCityUsesLocationHorizon(t *testing.T) {
+	h := newDBTestHandlers(t)
+	f := newGeoFixture(t, h)
+
+	// A published location in a bowl of hills 3° high all round
+	const lat, lng = -48.0, -125.0
+	publisher := f.publisher("Fixture Valley")
+	location := &models.PublisherLocation{Latitude: lat, Longitude: lng, HorizonAltitudes: []float64{3}}
+	if err := h.db.Pool.QueryRow(context.Background(), `
+		INSERT INTO publisher_locations (publisher_id, name, latitude, longitude, timezone, horizon_altitudes)
+		VALUES ($1, 'Valley', $2, $3, 'UTC', $4) RETURNING id::text
+	`, publisher, lat, lng, location.HorizonAltitudes).Scan(&location.ID); err != nil {
+		t.Fatalf("insert location: %v", err)
+	}
+	f.cover(publisher, "location", location.ID, 1)
+
+	zmanID := addPublisherZman(t, h, publisher, "sunrise", "sunrise")
+	if _, err := h.db.Pool.Exec(context.Background(), `
+		INSERT INTO publisher_zman_versions (publisher_zman_id, version_number, hebrew_name, formula_dsl, effective_from)
+		VALUES ($1, 2, 'sunrise', 'visible_sunrise_terrain', '2025-01-01')
+	`, zmanID); err != nil {
+		t.Fatalf("schedule formula: %v", err)
+	}
+
+	date := time.Date(2025, 6, 21, 0, 0, 0, 0, time.UTC)
+	rise, _ := astro.VisibleSunTimesOverHorizon(date, lat, lng, 0, time.UTC, locationHorizon(location))
+	if sea := astro.DefaultEngine.SunTimes(date, lat, lng, 0, time.UTC).Sunrise; rise.Sub(sea) < 10*time.Minute {
+		t.Fatalf("sunrise over the hills %s is not clearly after sunrise %s", rise, sea)
+	}
+
+	got := getZmanim(t, h, url.Values{"locationId": {location.ID}, "date": {"2025-06-21"}})["sunrise"]
+	if want := astro.FormatTime(rise); got.Time != want {
+		t.Errorf("sunrise = %s, want %s over the location's horizon", got.Time, want)
+	}
+}
+
+func TestGetZmanimForCityResolvesCityLikeCoordinates(t *testing.T) {
+	h := newDBTestHandlers(t)
+	f := newGeoFixture(t, h)
+
+	// With no SRTM tiles the city's recorded elevation applies
+	const lat, lng = -48.0, -125.0
+	city := f.city("Highland", 0, lat, lng, 100)
+	if _, err := h.db.Pool.Exec(context.Background(), `UPDATE geo_cities SET elevation_m = 800 WHERE id = $1`, city); err != nil {
+		t.Fatalf("set city elevation: %v", err)
+	}
+
+	date := time.Date(2025, 6, 21, 0, 0, 0, 0, time.UTC)
+	want := astro.FormatTime(astro.DefaultEngine.SunTimes(date, lat, lng, 800, time.UTC).Sunrise)
+	if sea := astro.FormatTime(astro.DefaultEngine.SunTimes(date, lat, lng, 0, time.UTC).Sunrise); sea == want {
+		t.Fatalf("sunrise at 800 m equals sunrise at sea level (%s)", sea)
+	}
+
+	got := getZmanim(t, h, url.Values{"cityId": {city}, "date": {"2025-06-21"}})
+	if got["sunrise"].Time != want {
+		t.Errorf("sunrise = %s, want %s at the city's elevation", got["sunrise"].Time, want)
+	}
+}
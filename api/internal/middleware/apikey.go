@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// APIKeyPrefix identifies API keys presented as bearer tokens (Clerk JWTs never start with it)
+const APIKeyPrefix = "zl_"
+
+// API key scopes
+const (
+	// ScopeReadZmanim allows reading calculated zmanim
+	ScopeReadZmanim = "zmanim:read"
+	// ScopeReadCalendar allows reading Hebrew calendar data
+	ScopeReadCalendar = "calendar:read"
+)
+
+// ValidAPIKeyScopes lists every scope that can be granted to a key
+var ValidAPIKeyScopes = []string{ScopeReadZmanim, ScopeReadCalendar}
+
+const (
+	// APIKeyKey is the context key for the validated API key
+	APIKeyKey contextKey = "api_key"
+)
+
+// APIKey is a validated API key attached to the request context
+type APIKey struct {
+	ID string
+	// PublisherID is empty for platform keys issued by an admin
+	PublisherID string
+	Scopes      []string
+}
+
+// HasScope reports whether the key was granted the given scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyValidator resolves a plaintext API key. It returns an error for unknown,
+// revoked or expired keys.
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, rawKey string) (*APIKey, error)
+}
+
+// SetAPIKeyValidator enables API key authentication alongside Clerk JWTs (optional)
+func (am *AuthMiddleware) SetAPIKeyValidator(v APIKeyValidator) {
+	am.apiKeys = v
+}
+
+// GetAPIKey retrieves the validated API key from the request context
+func GetAPIKey(ctx context.Context) *APIKey {
+	if key, ok := ctx.Value(APIKeyKey).(*APIKey); ok {
+		return key
+	}
+	return nil
+}
+
+// extractAPIKey returns the API key presented in X-API-Key or as a bearer token
+func extractAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" && strings.HasPrefix(parts[1], APIKeyPrefix) {
+		return parts[1]
+	}
+	return ""
+}
+
+// authenticateAPIKey validates a presented API key and adds it to the context.
+// ok is false when a key was presented but rejected; the error response has been written.
+func (am *AuthMiddleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, rawKey string) (*http.Request, bool) {
+	if am.apiKeys == nil {
+		respondAuthError(w, http.StatusUnauthorized, "UNAUTHORIZED", "API keys are not enabled")
+		return r, false
+	}
+
+	key, err := am.apiKeys.ValidateAPIKey(r.Context(), rawKey)
+	if err != nil {
+		slog.Warn("API key authentication failed", "error", err, "path", r.URL.Path)
+		respondAuthError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid, revoked or expired API key")
+		return r, false
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), APIKeyKey, key)), true
+}
+
+// RequireAPIKeyScope rejects requests made with an API key that lacks the scope.
+// Requests without an API key (anonymous or JWT) pass through unchanged.
+func RequireAPIKeyScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := GetAPIKey(r.Context()); key != nil && !key.HasScope(scope) {
+				respondAuthError(w, http.StatusForbidden, "FORBIDDEN", "API key is missing required scope '"+scope+"'")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeKeys is an APIKeyValidator backed by a map
+type fakeKeys map[string]*APIKey
+
+func (f fakeKeys) ValidateAPIKey(_ context.Context, rawKey string) (*APIKey, error) {
+	if key, ok := f[rawKey]; ok {
+		return key, nil
+	}
+	return nil, errors.New("invalid key")
+}
+
+func TestOptionalAuthWithAPIKey(t *testing.T) {
+	am := NewAuthMiddleware("", "")
+	am.SetAPIKeyValidator(fakeKeys{
+		"zl_zmanim": {ID: "key-1", PublisherID: "pub-1", Scopes: []string{ScopeReadZmanim}},
+	})
+
+	var seen *APIKey
+	handler := am.OptionalAuth(RequireAPIKeyScope(ScopeReadZmanim)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetAPIKey(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})))
+	calendar := am.OptionalAuth(RequireAPIKeyScope(ScopeReadCalendar)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		headers map[string]string
+		want    int
+		wantKey string
+	}{
+		{"anonymous", handler, nil, http.StatusOK, ""},
+		{"x-api-key header", handler, map[string]string{"X-API-Key": "zl_zmanim"}, http.StatusOK, "key-1"},
+		{"bearer api key", handler, map[string]string{"Authorization": "Bearer zl_zmanim"}, http.StatusOK, "key-1"},
+		{"unknown key is rejected", handler, map[string]string{"X-API-Key": "zl_nope"}, http.StatusUnauthorized, ""},
+		{"missing scope", calendar, map[string]string{"X-API-Key": "zl_zmanim"}, http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/zmanim", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			gotKey := ""
+			if seen != nil {
+				gotKey = seen.ID
+			}
+			if gotKey != tt.wantKey {
+				t.Errorf("api key in context = %q, want %q", gotKey, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestRateLimiterChargesAPIKey(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterConfig{AnonymousRequestsPerHour: 1, AuthenticatedRequestsPerHour: 5})
+	defer rl.Stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/cheap", nil)
+	req = req.WithContext(context.WithValue(req.Context(), APIKeyKey, &APIKey{ID: "key-1"}))
+	if got := rl.getSubject(req); got != "api_key:key-1" {
+		t.Errorf("subject = %q, want api_key:key-1", got)
+	}
+	if q := rl.getQuota(req, "api_key:key-1"); q.Limit != 5 {
+		t.Errorf("quota limit = %d, want authenticated limit 5", q.Limit)
+	}
+}
@@ -70,6 +70,7 @@ type AuthMiddleware struct {
 	keys      map[string]*rsa.PublicKey
 	keysMutex sync.RWMutex
 	lastFetch time.Time
+	apiKeys   APIKeyValidator
 }
 
 // NewAuthMiddleware creates a new authentication middleware
@@ -253,9 +254,20 @@ func (am *AuthMiddleware) RequireRole(role string) func(http.Handler) http.Handl
 	}
 }
 
-// OptionalAuth returns a middleware that extracts user info if present but doesn't require it
+// OptionalAuth returns a middleware that extracts user info if present but doesn't require it.
+// An API key (X-API-Key header or "Bearer zl_..." token) is accepted in place of a JWT;
+// a presented key that is invalid is rejected rather than treated as anonymous.
 func (am *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rawKey := extractAPIKey(r); rawKey != "" {
+			r, ok := am.authenticateAPIKey(w, r, rawKey)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		claims, err := am.validateToken(r)
 		if err == nil {
 			// Add user info to context
@@ -263,6 +275,13 @@ func (am *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 			if role := getRoleFromClaims(claims); role != "" {
 				ctx = context.WithValue(ctx, UserRoleKey, role)
 			}
+			// Publisher info lets downstream middleware (e.g. rate limiting) attribute the request
+			if primaryPubID := getPrimaryPublisherIDFromClaims(claims); primaryPubID != "" {
+				ctx = context.WithValue(ctx, PrimaryPublisherIDKey, primaryPubID)
+			}
+			if accessList := getPublisherAccessListFromClaims(claims); len(accessList) > 0 {
+				ctx = context.WithValue(ctx, PublisherAccessListKey, accessList)
+			}
 			r = r.WithContext(ctx)
 		}
 		next.ServeHTTP(w, r)
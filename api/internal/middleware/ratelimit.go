@@ -1,18 +1,55 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
+// Quota describes a token bucket: up to Limit cost units may be spent per Window,
+// refilling continuously at Limit/Window.
+type Quota struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult is the outcome of taking tokens from a bucket
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until enough tokens are available for the rejected request
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is completely full again
+	ResetAfter time.Duration
+}
+
+// RateLimitStore holds token bucket state. Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	// Take attempts to remove cost tokens from the bucket identified by key
+	Take(ctx context.Context, key string, cost int, quota Quota) (RateLimitResult, error)
+}
+
+// QuotaSource supplies per-subject quota overrides and per-route cost weights.
+// Subjects are "api_key:<id>", "publisher:<id>", "user:<id>" or "ip:<addr>".
+type QuotaSource interface {
+	QuotaFor(ctx context.Context, subject string) (Quota, bool)
+	RouteCost(ctx context.Context, routePattern string) (int, bool)
+}
+
 // RateLimiterConfig holds rate limiter configuration
 type RateLimiterConfig struct {
 	AnonymousRequestsPerHour     int
 	AuthenticatedRequestsPerHour int
 	CleanupInterval              time.Duration
+	// RouteCosts maps chi route patterns to the number of tokens a request consumes.
+	// Routes not listed cost 1. Overridden by the QuotaSource when one is configured.
+	RouteCosts map[string]int
 }
 
 // DefaultRateLimiterConfig returns default rate limiter configuration
@@ -21,146 +58,156 @@ func DefaultRateLimiterConfig() RateLimiterConfig {
 		AnonymousRequestsPerHour:     10000,  // AC: 10000 requests/hour for anonymous (increased for dev)
 		AuthenticatedRequestsPerHour: 100000, // Higher limit for authenticated (increased for dev)
 		CleanupInterval:              5 * time.Minute,
+		RouteCosts: map[string]int{
+			"/api/v1/dsl/preview-week":         5,
+			"/api/v1/ai/generate-formula":      20,
+			"/api/v1/ai/explain-formula":       10,
+			"/api/v1/ai/search":                5,
+			"/api/v1/geo/boundaries/regions":   5,
+			"/api/v1/geo/boundaries/districts": 10,
+		},
 	}
 }
 
-// clientInfo tracks rate limit state for a client
-type clientInfo struct {
-	count       int
-	windowStart time.Time
-}
-
-// RateLimiter provides rate limiting functionality
+// RateLimiter provides token bucket rate limiting backed by a pluggable store.
+// With the in-memory store limits are per process; with the Redis store they are
+// shared across all replicas.
 type RateLimiter struct {
-	config      RateLimiterConfig
-	clients     map[string]*clientInfo
-	mutex       sync.RWMutex
-	stopCleanup chan struct{}
+	config RateLimiterConfig
+	store  RateLimitStore
+	quotas QuotaSource
+	stop   func()
 }
 
-// NewRateLimiter creates a new rate limiter with the given config
+// NewRateLimiter creates a new in-memory rate limiter with the given config
 func NewRateLimiter(config RateLimiterConfig) *RateLimiter {
-	rl := &RateLimiter{
-		config:      config,
-		clients:     make(map[string]*clientInfo),
-		stopCleanup: make(chan struct{}),
+	store := NewMemoryRateLimitStore(config.CleanupInterval)
+	return &RateLimiter{
+		config: config,
+		store:  store,
+		stop:   store.Stop,
 	}
+}
 
-	// Start background cleanup goroutine
-	go rl.cleanup()
-
-	return rl
-}
-
-// cleanup periodically removes expired client entries
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.config.CleanupInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			rl.mutex.Lock()
-			now := time.Now()
-			for key, info := range rl.clients {
-				if now.Sub(info.windowStart) > time.Hour {
-					delete(rl.clients, key)
-				}
-			}
-			rl.mutex.Unlock()
-		case <-rl.stopCleanup:
-			return
-		}
+// NewRateLimiterWithStore creates a rate limiter using the given store (e.g. Redis)
+func NewRateLimiterWithStore(config RateLimiterConfig, store RateLimitStore) *RateLimiter {
+	return &RateLimiter{
+		config: config,
+		store:  store,
+		stop:   func() {},
 	}
 }
 
-// Stop stops the cleanup goroutine
-func (rl *RateLimiter) Stop() {
-	close(rl.stopCleanup)
+// SetQuotaSource configures per-publisher/per-API-key quotas and route costs (optional)
+func (rl *RateLimiter) SetQuotaSource(source QuotaSource) {
+	rl.quotas = source
 }
 
-// getClientKey returns a unique key for the client
-// SECURITY: For authenticated users, we use the user ID as the key.
-// For anonymous users, we ONLY use RemoteAddr which is set by the
-// trusted RealIP middleware from chi. We do NOT read X-Forwarded-For
-// or X-Real-IP headers directly as they can be spoofed.
-func (rl *RateLimiter) getClientKey(r *http.Request) string {
-	// Check if user is authenticated - use their user ID
-	if userID := GetUserID(r.Context()); userID != "" {
-		return "user:" + userID
-	}
+// Stop releases background resources held by the store
+func (rl *RateLimiter) Stop() {
+	rl.stop()
+}
 
-	// Use RemoteAddr for anonymous users.
-	// The RealIP middleware must run before this to properly set RemoteAddr
-	// from trusted proxy headers. We do NOT read headers directly here.
+// clientIP returns the client IP from RemoteAddr without the port.
+// SECURITY: We ONLY use RemoteAddr which is set by the trusted RealIP middleware
+// from chi. We do NOT read X-Forwarded-For or X-Real-IP headers directly as they
+// can be spoofed.
+func clientIP(r *http.Request) string {
 	ip := r.RemoteAddr
 	// RemoteAddr may include port, extract just the IP
-	if colonIdx := len(ip) - 1; colonIdx > 0 {
-		for i := colonIdx; i >= 0; i-- {
-			if ip[i] == ':' {
-				ip = ip[:i]
-				break
-			}
+	for i := len(ip) - 1; i > 0; i-- {
+		if ip[i] == ':' {
+			return ip[:i]
+		}
+		if ip[i] == ']' {
+			break
 		}
 	}
-	return "ip:" + ip
+	return ip
 }
 
-// getLimit returns the rate limit for the client
-func (rl *RateLimiter) getLimit(r *http.Request) int {
-	if userID := GetUserID(r.Context()); userID != "" {
-		return rl.config.AuthenticatedRequestsPerHour
+// getSubject returns the identity a request is charged against, most specific first:
+// API key, then the publisher the caller acts for, then user, then client IP.
+func (rl *RateLimiter) getSubject(r *http.Request) string {
+	ctx := r.Context()
+	if key := GetAPIKey(ctx); key != nil {
+		return "api_key:" + key.ID
 	}
-	return rl.config.AnonymousRequestsPerHour
+	if userID := GetUserID(ctx); userID != "" {
+		if publisherID := GetValidatedPublisherID(ctx, r.Header.Get("X-Publisher-Id")); publisherID != "" {
+			return "publisher:" + publisherID
+		}
+		return "user:" + userID
+	}
+	return "ip:" + clientIP(r)
 }
 
-// isAllowed checks if the request is within rate limits
-func (rl *RateLimiter) isAllowed(r *http.Request) (allowed bool, remaining int, resetSeconds int) {
-	key := rl.getClientKey(r)
-	limit := rl.getLimit(r)
-	now := time.Now()
-
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
-
-	info, exists := rl.clients[key]
-	if !exists || now.Sub(info.windowStart) > time.Hour {
-		// New window
-		rl.clients[key] = &clientInfo{
-			count:       1,
-			windowStart: now,
+// getQuota returns the quota for a subject, preferring configured overrides
+func (rl *RateLimiter) getQuota(r *http.Request, subject string) Quota {
+	if rl.quotas != nil {
+		if q, ok := rl.quotas.QuotaFor(r.Context(), subject); ok && q.Limit > 0 && q.Window > 0 {
+			return q
 		}
-		return true, limit - 1, 3600
 	}
-
-	// Calculate time until reset
-	elapsed := now.Sub(info.windowStart)
-	resetSeconds = int((time.Hour - elapsed).Seconds())
-	if resetSeconds < 0 {
-		resetSeconds = 0
+	if IsAuthenticated(r.Context()) || GetAPIKey(r.Context()) != nil {
+		return Quota{Limit: rl.config.AuthenticatedRequestsPerHour, Window: time.Hour}
 	}
+	return Quota{Limit: rl.config.AnonymousRequestsPerHour, Window: time.Hour}
+}
 
-	if info.count >= limit {
-		return false, 0, resetSeconds
+// getCost returns the token cost of the matched route
+func (rl *RateLimiter) getCost(r *http.Request) int {
+	pattern := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" {
+			pattern = p
+		}
 	}
-
-	info.count++
-	return true, limit - info.count, resetSeconds
+	if rl.quotas != nil {
+		if cost, ok := rl.quotas.RouteCost(r.Context(), pattern); ok && cost > 0 {
+			return cost
+		}
+	}
+	if cost, ok := rl.config.RouteCosts[pattern]; ok && cost > 0 {
+		return cost
+	}
+	return 1
 }
 
-// Middleware returns the rate limiting middleware handler
+// Middleware returns the rate limiting middleware handler.
+// It must run after routing (inside a chi Group/Route) so per-route costs can be resolved.
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		allowed, remaining, resetSeconds := rl.isAllowed(r)
-
-		// Add rate limit headers
-		limit := rl.getLimit(r)
-		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
-		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", resetSeconds))
+		subject := rl.getSubject(r)
+		quota := rl.getQuota(r, subject)
+		// A route costing more than the whole quota could never succeed, so it
+		// takes the full bucket instead
+		cost := min(rl.getCost(r), quota.Limit)
+
+		result, err := rl.store.Take(r.Context(), "ratelimit:"+subject, cost, quota)
+		if err != nil {
+			// Fail open: an unavailable store must not take the API down
+			slog.Error("rate limit store error", "error", err, "subject", subject)
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		if !allowed {
-			w.Header().Set("Retry-After", fmt.Sprintf("%d", resetSeconds))
+		resetSeconds := ceilSeconds(result.ResetAfter)
+		windowSeconds := int(quota.Window / time.Second)
+
+		// Standard headers (draft-ietf-httpapi-ratelimit-headers)
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(quota.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(resetSeconds))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", quota.Limit, windowSeconds))
+		// Legacy headers kept for existing clients
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(quota.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(resetSeconds))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusTooManyRequests)
 			_ = json.NewEncoder(w).Encode(map[string]interface{}{
@@ -168,8 +215,10 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 					"code":    "RATE_LIMITED",
 					"message": "Rate limit exceeded. Please try again later.",
 					"details": map[string]interface{}{
-						"limit":               limit,
-						"retry_after_seconds": resetSeconds,
+						"limit":               quota.Limit,
+						"window_seconds":      windowSeconds,
+						"cost":                cost,
+						"retry_after_seconds": retryAfter,
 					},
 				},
 			})
@@ -180,6 +229,14 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
 // NewDefaultRateLimiter creates a rate limiter with default configuration
 func NewDefaultRateLimiter() *RateLimiter {
 	return NewRateLimiter(DefaultRateLimiterConfig())
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bucket is the state of a single token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket will have refilled to the limit
+}
+
+// takeTokens refills the bucket for the time elapsed since it was last touched and
+// then tries to remove cost tokens. It is shared by the in-memory store and mirrored
+// by the Redis Lua script so both backends behave identically.
+func takeTokens(b *bucket, now time.Time, cost int, quota Quota) RateLimitResult {
+	capacity := float64(quota.Limit)
+	rate := capacity / quota.Window.Seconds() // tokens per second
+
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * rate
+		if b.tokens > capacity {
+			b.tokens = capacity
+		}
+	}
+	b.last = now
+
+	result := RateLimitResult{}
+	if b.tokens >= float64(cost) {
+		b.tokens -= float64(cost)
+		result.Allowed = true
+	} else {
+		deficit := float64(cost) - b.tokens
+		result.RetryAfter = time.Duration(deficit / rate * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = time.Duration((capacity - b.tokens) / rate * float64(time.Second))
+	b.full = now.Add(result.ResetAfter)
+	return result
+}
+
+// MemoryRateLimitStore keeps token buckets in process memory.
+// Limits reset on restart and are not shared between replicas.
+type MemoryRateLimitStore struct {
+	buckets     map[string]*bucket
+	mutex       sync.Mutex
+	now         func() time.Time
+	stopCleanup chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewMemoryRateLimitStore creates an in-memory store, pruning idle buckets every cleanupInterval
+func NewMemoryRateLimitStore(cleanupInterval time.Duration) *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		buckets:     make(map[string]*bucket),
+		now:         time.Now,
+		stopCleanup: make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		go s.cleanup(cleanupInterval)
+	}
+
+	return s
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, cost int, quota Quota) (RateLimitResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(quota.Limit), last: now}
+		s.buckets[key] = b
+	}
+
+	return takeTokens(b, now, cost, quota), nil
+}
+
+// cleanup periodically prunes buckets that have refilled
+func (s *MemoryRateLimitStore) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.prune()
+		case <-s.stopCleanup:
+			return
+		}
+	}
+}
+
+// prune removes buckets that have refilled to their limit, which is the same
+// as keeping them full. How long that takes depends on the quota's window, so
+// a bucket on a daily quota is kept for up to a day.
+func (s *MemoryRateLimitStore) prune() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Stop stops the cleanup goroutine
+func (s *MemoryRateLimitStore) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCleanup) })
+}
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	quota := Quota{Limit: 10, Window: 10 * time.Second} // 1 token/second
+	ctx := context.Background()
+
+	// Full bucket allows a burst up to the limit
+	for i := 0; i < 10; i++ {
+		res, _ := store.Take(ctx, "k", 1, quota)
+		if !res.Allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+		if res.Remaining != 9-i {
+			t.Errorf("request %d: remaining = %d, want %d", i+1, res.Remaining, 9-i)
+		}
+	}
+
+	res, _ := store.Take(ctx, "k", 1, quota)
+	if res.Allowed {
+		t.Fatal("11th request should be rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", res.RetryAfter)
+	}
+	if res.ResetAfter != 10*time.Second {
+		t.Errorf("ResetAfter = %v, want 10s", res.ResetAfter)
+	}
+
+	// Tokens refill continuously
+	now = now.Add(3 * time.Second)
+	res, _ = store.Take(ctx, "k", 3, quota)
+	if !res.Allowed {
+		t.Fatal("request costing 3 should be allowed after 3s refill")
+	}
+
+	// Costly requests are rejected without consuming tokens
+	now = now.Add(2 * time.Second)
+	res, _ = store.Take(ctx, "k", 5, quota)
+	if res.Allowed {
+		t.Fatal("request costing 5 should be rejected with 2 tokens")
+	}
+	if res.Remaining != 2 {
+		t.Errorf("remaining = %d, want 2", res.Remaining)
+	}
+
+	// Other keys are independent
+	res, _ = store.Take(ctx, "other", 1, quota)
+	if !res.Allowed || res.Remaining != 9 {
+		t.Errorf("independent key: allowed=%v remaining=%d", res.Allowed, res.Remaining)
+	}
+}
+
+func TestMemoryStoreRefillCapsAtLimit(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	quota := Quota{Limit: 5, Window: time.Minute}
+
+	_, _ = store.Take(context.Background(), "k", 5, quota)
+	now = now.Add(24 * time.Hour)
+	res, _ := store.Take(context.Background(), "k", 1, quota)
+	if res.Remaining != 4 {
+		t.Errorf("remaining = %d, want 4 (bucket must not exceed limit)", res.Remaining)
+	}
+}
+
+func TestMemoryStorePrunesRefilledBuckets(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	hourly := Quota{Limit: 10, Window: time.Hour}
+	daily := Quota{Limit: 10, Window: 24 * time.Hour}
+
+	_, _ = store.Take(context.Background(), "hourly", 10, hourly)
+	_, _ = store.Take(context.Background(), "daily", 10, daily)
+
+	// Two hours on, the hourly bucket is full again but the daily one is not
+	now = now.Add(2 * time.Hour)
+	store.prune()
+	if _, ok := store.buckets["hourly"]; ok {
+		t.Error("refilled hourly bucket should be pruned")
+	}
+	if _, ok := store.buckets["daily"]; !ok {
+		t.Fatal("daily bucket was pruned before it refilled")
+	}
+	if res, _ := store.Take(context.Background(), "daily", 1, daily); res.Allowed {
+		t.Errorf("daily quota allowed a request after two idle hours (remaining %d)", res.Remaining)
+	}
+
+	now = now.Add(24 * time.Hour)
+	store.prune()
+	if len(store.buckets) != 0 {
+		t.Errorf("%d buckets left after a day, want none", len(store.buckets))
+	}
+}
+
+// staticQuotas is a QuotaSource for tests
+type staticQuotas struct {
+	quotas map[string]Quota
+	costs  map[string]int
+}
+
+func (s staticQuotas) QuotaFor(_ context.Context, subject string) (Quota, bool) {
+	q, ok := s.quotas[subject]
+	return q, ok
+}
+
+func (s staticQuotas) RouteCost(_ context.Context, pattern string) (int, bool) {
+	c, ok := s.costs[pattern]
+	return c, ok
+}
+
+func newTestRouter(rl *RateLimiter) http.Handler {
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(rl.Middleware)
+		r.Get("/cheap", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+		r.Get("/costly/{id}", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	})
+	return r
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	config := RateLimiterConfig{
+		AnonymousRequestsPerHour:     3,
+		AuthenticatedRequestsPerHour: 100,
+		RouteCosts:                   map[string]int{"/costly/{id}": 2},
+	}
+	rl := NewRateLimiter(config)
+	defer rl.Stop()
+	router := newTestRouter(rl)
+
+	do := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/costly/42", "10.0.0.1:1234")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "3" {
+		t.Errorf("RateLimit-Limit = %q, want 3", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("RateLimit-Remaining = %q, want 1 (route costs 2)", got)
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "3;w=3600" {
+		t.Errorf("RateLimit-Policy = %q, want 3;w=3600", got)
+	}
+
+	// Same client from a different port shares the bucket
+	if rec := do("/costly/43", "10.0.0.1:5678"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	} else if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header missing on 429")
+	}
+
+	if rec := do("/cheap", "10.0.0.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("cheap route status = %d, want 200", rec.Code)
+	}
+
+	// A different client has its own bucket
+	if rec := do("/costly/42", "10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("other client status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimiterQuotaSource(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterConfig{AnonymousRequestsPerHour: 1, AuthenticatedRequestsPerHour: 1})
+	defer rl.Stop()
+	rl.SetQuotaSource(staticQuotas{
+		quotas: map[string]Quota{"publisher:pub-1": {Limit: 50, Window: time.Minute}},
+		costs:  map[string]int{"/cheap": 10},
+	})
+	router := newTestRouter(rl)
+
+	req := httptest.NewRequest(http.MethodGet, "/cheap", nil)
+	ctx := context.WithValue(req.Context(), UserIDKey, "user_1")
+	ctx = context.WithValue(ctx, PrimaryPublisherIDKey, "pub-1")
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "50" {
+		t.Errorf("RateLimit-Limit = %q, want 50 (publisher quota)", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "40" {
+		t.Errorf("RateLimit-Remaining = %q, want 40 (configured route cost 10)", got)
+	}
+}
+
+func TestRateLimiterClampsCostToLimit(t *testing.T) {
+	rl := NewRateLimiter(RateLimiterConfig{
+		AnonymousRequestsPerHour:     3,
+		AuthenticatedRequestsPerHour: 3,
+		RouteCosts:                   map[string]int{"/costly/{id}": 10},
+	})
+	defer rl.Stop()
+	router := newTestRouter(rl)
+
+	do := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/costly/1", nil))
+		return rec
+	}
+
+	// The route costs more than the quota, so it takes the whole bucket
+	if rec := do(); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("status = %d, remaining %q; want 200 with the bucket emptied", rec.Code, rec.Header().Get("RateLimit-Remaining"))
+	}
+	// and can be retried once the bucket has refilled, an hour later
+	if rec := do(); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "3600" {
+		t.Errorf("status = %d, Retry-After %q; want 429 after 3600s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
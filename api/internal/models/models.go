@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -68,12 +69,30 @@ type City struct {
 	Continent   *string `json:"continent,omitempty"` // Continent code (AF, AN, AS, EU, NA, OC, SA)
 	// Computed display field
 	DisplayName string `json:"display_name"`
+	// Search match: the stored name that matched, its language (ISO 639-3) and
+	// how it matched (name, ascii, translation, transliteration, historic)
+	MatchedName     *string `json:"matched_name,omitempty"`
+	MatchedLanguage *string `json:"matched_language,omitempty"`
+	MatchType       *string `json:"match_type,omitempty"`
 }
 
 // CitySearchResponse represents the response for city search
 type CitySearchResponse struct {
 	Cities []City `json:"cities"`
 	Total  int    `json:"total"`
+	// Locale is the ISO 639-3 language names are returned in; empty for the default names
+	Locale string `json:"locale,omitempty"`
+	// Query is how the search text was read, when it carried a region or country qualifier
+	Query *CitySearchQuery `json:"query,omitempty"`
+	// Locations are the publisher's own locations matching the search, when publisher_id is given
+	Locations []PublisherLocation `json:"locations,omitempty"`
+}
+
+// CitySearchQuery is a search split into a city name and the region or
+// country qualifiers that narrowed it, as in "Monsey NY"
+type CitySearchQuery struct {
+	Name       string   `json:"name"`
+	Qualifiers []string `json:"qualifiers"`
 }
 
 // CoverageArea represents a publisher's coverage area
@@ -195,17 +214,19 @@ type HealthResponse struct {
 	Version  string `json:"version"`
 }
 
-// PublisherCoverage represents a publisher's coverage area at continent, country, region, district, or city level
-// Uses hierarchical IDs: continent_code, country_id, region_id, district_id, city_id
+// PublisherCoverage represents a publisher's coverage area at continent, country, region, district, city or location level,
+// or a polygon or radius area
+// Uses hierarchical IDs: continent_code, country_id, region_id, district_id, city_id, or a publisher location_id
 type PublisherCoverage struct {
 	ID            string    `json:"id"`
 	PublisherID   string    `json:"publisher_id"`
-	CoverageLevel string    `json:"coverage_level"` // continent, country, region, district, city
+	CoverageLevel string    `json:"coverage_level"` // continent, country, region, district, city, location, polygon, radius
 	ContinentCode *string   `json:"continent_code,omitempty"`
 	CountryID     *int16    `json:"country_id,omitempty"`
 	RegionID      *int32    `json:"region_id,omitempty"`
 	DistrictID    *int32    `json:"district_id,omitempty"`
-	CityID        *string   `json:"city_id,omitempty"` // UUID string
+	CityID        *string   `json:"city_id,omitempty"`     // UUID string
+	LocationID    *string   `json:"location_id,omitempty"` // Publisher location UUID
 	Priority      int       `json:"priority"`
 	IsActive      bool      `json:"is_active"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -219,17 +240,49 @@ type PublisherCoverage struct {
 	DistrictCode  *string `json:"district_code,omitempty"`
 	DistrictName  *string `json:"district_name,omitempty"`
 	CityName      *string `json:"city_name,omitempty"`
+	LocationName  *string `json:"location_name,omitempty"`
+	// Polygon and radius areas
+	AreaName        *string  `json:"area_name,omitempty"`
+	AreaKm2         *float64 `json:"area_km2,omitempty"`
+	CenterLatitude  *float64 `json:"center_latitude,omitempty"`
+	CenterLongitude *float64 `json:"center_longitude,omitempty"`
+	RadiusM         *float64 `json:"radius_m,omitempty"`
+}
+
+// PublisherLocation is a named point a publisher calculates zmanim for, more
+// precise than the nearest city
+type PublisherLocation struct {
+	ID          string  `json:"id"`
+	PublisherID string  `json:"publisher_id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	ElevationM  float64 `json:"elevation_m"`
+	Timezone    string  `json:"timezone"`
+	// HorizonAltitudes overrides the SRTM horizon: degrees at evenly spaced
+	// azimuths from north eastward. A single value is a uniform horizon.
+	HorizonAltitudes []float64 `json:"horizon_altitudes,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 // PublisherCoverageCreateRequest represents a request to create coverage
 type PublisherCoverageCreateRequest struct {
-	CoverageLevel string  `json:"coverage_level"` // continent, country, region, district, city
+	CoverageLevel string  `json:"coverage_level"` // continent, country, region, district, city, location, polygon, radius
 	ContinentCode *string `json:"continent_code,omitempty"`
 	CountryID     *int16  `json:"country_id,omitempty"`
 	RegionID      *int32  `json:"region_id,omitempty"`
 	DistrictID    *int32  `json:"district_id,omitempty"`
-	CityID        *string `json:"city_id,omitempty"` // UUID string
+	CityID        *string `json:"city_id,omitempty"`     // UUID string
+	LocationID    *string `json:"location_id,omitempty"` // Publisher location UUID
 	Priority      *int    `json:"priority,omitempty"`
+	// Polygon and radius areas
+	Name            *string         `json:"name,omitempty"`
+	Geometry        json.RawMessage `json:"geometry,omitempty" swaggertype:"object"` // GeoJSON Polygon, MultiPolygon or a Feature of one
+	CenterLatitude  *float64        `json:"center_latitude,omitempty"`
+	CenterLongitude *float64        `json:"center_longitude,omitempty"`
+	RadiusM         *float64        `json:"radius_m,omitempty"`
 }
 
 // PublisherCoverageUpdateRequest represents a request to update coverage
@@ -260,6 +313,36 @@ type PublishersForCityResponse struct {
 	CityID     string             `json:"city_id"`
 }
 
+// PublishersForPointResponse represents the response for publishers serving a coordinate
+type PublishersForPointResponse struct {
+	Publishers []PublisherForCity `json:"publishers"`
+	Total      int                `json:"total"`
+	Latitude   float64            `json:"latitude"`
+	Longitude  float64            `json:"longitude"`
+}
+
+// NearbyCity is a city with its distance and direction from a searched point
+type NearbyCity struct {
+	City          City    `json:"city"`
+	DistanceKm    float64 `json:"distance_km"`
+	DistanceMiles float64 `json:"distance_miles"`
+	BearingDeg    float64 `json:"bearing_deg"` // Initial great-circle bearing, clockwise from north
+	Direction     string  `json:"direction"`   // Compass point of the bearing (N, NE, E, ...)
+	// PublisherCount is the number of publishers serving the city through any coverage level
+	PublisherCount int `json:"publisher_count"`
+}
+
+// NearbyCitiesResponse represents a page of cities nearest a point
+type NearbyCitiesResponse struct {
+	Cities      []NearbyCity `json:"cities"`
+	Limit       int          `json:"limit"`
+	Offset      int          `json:"offset"`
+	HasMore     bool         `json:"has_more"`
+	SearchedLat float64      `json:"searched_lat"`
+	SearchedLng float64      `json:"searched_lng"`
+	RadiusKm    *float64     `json:"radius_km,omitempty"`
+}
+
 // AlgorithmResponse represents an algorithm with its configuration
 type AlgorithmResponse struct {
 	ID          string                 `json:"id"`
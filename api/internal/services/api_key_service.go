@@ -0,0 +1,436 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/db"
+	"github.com/jcom-dev/zmanim-lab/internal/middleware"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyInvalid  = errors.New("api key is invalid, revoked or expired")
+)
+
+// apiKeyPrefixLength is how many leading characters of a key are stored for display
+const apiKeyPrefixLength = 12
+
+// APIKeyService manages API keys for third-party consumers and validates keys
+// presented to the public API. Usage is counted in memory and flushed to
+// api_key_usage periodically so validation does not write on every request.
+type APIKeyService struct {
+	db *db.DB
+
+	usageMu sync.Mutex
+	usage   map[string]int64 // api key ID -> requests since last flush
+
+	stopFlush chan struct{}
+	stopOnce  sync.Once
+}
+
+// APIKeyInfo describes an API key (never includes the secret)
+type APIKeyInfo struct {
+	ID            string     `json:"id"`
+	PublisherID   *string    `json:"publisher_id,omitempty"`
+	PublisherName *string    `json:"publisher_name,omitempty"`
+	Name          string     `json:"name"`
+	KeyPrefix     string     `json:"key_prefix"`
+	Scopes        []string   `json:"scopes"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	RotatedFromID *string    `json:"rotated_from_id,omitempty"`
+	IssuedBy      string     `json:"issued_by"`
+	CreatedBy     *string    `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Status        string     `json:"status"`
+}
+
+// CreatedAPIKey is returned once on creation/rotation and includes the plaintext key
+type CreatedAPIKey struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyParams contains the fields for issuing a key
+type CreateAPIKeyParams struct {
+	PublisherID *string
+	Name        string
+	Scopes      []string
+	ExpiresAt   *time.Time
+	IssuedBy    string // "publisher" or "admin"
+	CreatedBy   string
+}
+
+// APIKeyUsageDay is the request count for one day
+type APIKeyUsageDay struct {
+	Date     string `json:"date"`
+	Requests int64  `json:"requests"`
+}
+
+// APIKeyUsage summarizes a key's usage over a period
+type APIKeyUsage struct {
+	APIKeyID      string           `json:"api_key_id"`
+	Days          int              `json:"days"`
+	TotalRequests int64            `json:"total_requests"`
+	LastUsedAt    *time.Time       `json:"last_used_at,omitempty"`
+	Daily         []APIKeyUsageDay `json:"daily"`
+}
+
+// NewAPIKeyService creates a new API key service and starts the usage flusher
+func NewAPIKeyService(database *db.DB) *APIKeyService {
+	s := &APIKeyService{
+		db:        database,
+		usage:     make(map[string]int64),
+		stopFlush: make(chan struct{}),
+	}
+	go s.flushLoop(30 * time.Second)
+	return s
+}
+
+// Stop flushes pending usage counts and stops the background flusher
+func (s *APIKeyService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopFlush)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.FlushUsage(ctx); err != nil {
+			slog.Error("failed to flush api key usage on shutdown", "error", err)
+		}
+	})
+}
+
+// GenerateAPIKey returns a new random key in the form zl_<43 url-safe chars>
+func GenerateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return middleware.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hex SHA-256 digest stored for a key.
+// Keys carry 256 bits of entropy so a fast unsalted hash is sufficient.
+func HashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateScopes checks that every scope is known and removes duplicates
+func ValidateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	seen := make(map[string]bool)
+	var result []string
+	for _, scope := range scopes {
+		valid := false
+		for _, v := range middleware.ValidAPIKeyScopes {
+			if scope == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// apiKeyColumns is the column list scanned by scanAPIKey
+const apiKeyColumns = `
+	k.id, k.publisher_id, p.name, k.name, k.key_prefix, k.scopes, k.expires_at, k.revoked_at,
+	k.last_used_at, k.rotated_from_id, k.issued_by, k.created_by, k.created_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row pgx.Row) (*APIKeyInfo, error) {
+	var k APIKeyInfo
+	err := row.Scan(&k.ID, &k.PublisherID, &k.PublisherName, &k.Name, &k.KeyPrefix, &k.Scopes,
+		&k.ExpiresAt, &k.RevokedAt, &k.LastUsedAt, &k.RotatedFromID, &k.IssuedBy, &k.CreatedBy, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	k.Status = apiKeyStatus(&k, time.Now())
+	return &k, nil
+}
+
+// apiKeyStatus returns active, expired or revoked
+func apiKeyStatus(k *APIKeyInfo, now time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return "revoked"
+	case k.ExpiresAt != nil && !k.ExpiresAt.After(now):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// Create issues a new API key. The plaintext key is only available in the result.
+func (s *APIKeyService) Create(ctx context.Context, params CreateAPIKeyParams) (*CreatedAPIKey, error) {
+	return s.create(ctx, s.db.Pool, params, nil)
+}
+
+// rowQuerier is satisfied by both *pgxpool.Pool and pgx.Tx
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// create inserts a key using the given querier (pool or transaction)
+func (s *APIKeyService) create(ctx context.Context, q rowQuerier, params CreateAPIKeyParams, rotatedFromID *string) (*CreatedAPIKey, error) {
+	rawKey, err := GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var id string
+	err = q.QueryRow(ctx, `
+		INSERT INTO api_keys (publisher_id, name, key_prefix, key_hash, scopes, expires_at, rotated_from_id, issued_by, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, params.PublisherID, params.Name, rawKey[:apiKeyPrefixLength], HashAPIKey(rawKey), params.Scopes,
+		params.ExpiresAt, rotatedFromID, params.IssuedBy, params.CreatedBy).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert api key: %w", err)
+	}
+
+	info, err := scanAPIKey(q.QueryRow(ctx, `SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		LEFT JOIN publishers p ON p.id = k.publisher_id
+		WHERE k.id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api key: %w", err)
+	}
+
+	return &CreatedAPIKey{APIKeyInfo: *info, Key: rawKey}, nil
+}
+
+// List returns keys for a publisher, or every key when publisherID is nil (admin)
+func (s *APIKeyService) List(ctx context.Context, publisherID *string) ([]APIKeyInfo, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		LEFT JOIN publishers p ON p.id = k.publisher_id
+		WHERE ($1::uuid IS NULL OR k.publisher_id = $1)
+		ORDER BY k.created_at DESC`, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKeyInfo{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// Get returns a key, scoped to a publisher unless publisherID is nil (admin)
+func (s *APIKeyService) Get(ctx context.Context, id string, publisherID *string) (*APIKeyInfo, error) {
+	k, err := scanAPIKey(s.db.Pool.QueryRow(ctx, `SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		LEFT JOIN publishers p ON p.id = k.publisher_id
+		WHERE k.id = $1 AND ($2::uuid IS NULL OR k.publisher_id = $2)`, id, publisherID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return k, nil
+}
+
+// Revoke revokes a key immediately
+func (s *APIKeyService) Revoke(ctx context.Context, id string, publisherID *string) error {
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()), updated_at = now()
+		WHERE id = $1 AND ($2::uuid IS NULL OR publisher_id = $2)
+	`, id, publisherID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Rotate issues a replacement key with the same name, scopes and expiry.
+// The old key keeps working for gracePeriod so consumers can switch over.
+func (s *APIKeyService) Rotate(ctx context.Context, id string, publisherID *string, gracePeriod time.Duration, userID string) (*CreatedAPIKey, error) {
+	old, err := s.Get(ctx, id, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	if old.Status != "active" {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	created, err := s.create(ctx, tx, CreateAPIKeyParams{
+		PublisherID: old.PublisherID,
+		Name:        old.Name,
+		Scopes:      old.Scopes,
+		ExpiresAt:   old.ExpiresAt,
+		IssuedBy:    old.IssuedBy,
+		CreatedBy:   userID,
+	}, &old.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	graceEnd := time.Now().Add(gracePeriod)
+	_, err = tx.Exec(ctx, `
+		UPDATE api_keys
+		SET expires_at = LEAST(COALESCE(expires_at, $2), $2), updated_at = now()
+		WHERE id = $1
+	`, old.ID, graceEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire rotated api key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit rotation: %w", err)
+	}
+	return created, nil
+}
+
+// Usage returns daily request counts for the last days days (including today)
+func (s *APIKeyService) Usage(ctx context.Context, id string, publisherID *string, days int) (*APIKeyUsage, error) {
+	key, err := s.Get(ctx, id, publisherID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT usage_date, request_count
+		FROM api_key_usage
+		WHERE api_key_id = $1 AND usage_date > CURRENT_DATE - $2::int
+		ORDER BY usage_date
+	`, id, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := &APIKeyUsage{
+		APIKeyID:   id,
+		Days:       days,
+		LastUsedAt: key.LastUsedAt,
+		Daily:      []APIKeyUsageDay{},
+	}
+	for rows.Next() {
+		var date time.Time
+		var count int64
+		if err := rows.Scan(&date, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan api key usage: %w", err)
+		}
+		usage.Daily = append(usage.Daily, APIKeyUsageDay{Date: date.Format("2006-01-02"), Requests: count})
+		usage.TotalRequests += count
+	}
+	return usage, rows.Err()
+}
+
+// ValidateAPIKey implements middleware.APIKeyValidator
+func (s *APIKeyService) ValidateAPIKey(ctx context.Context, rawKey string) (*middleware.APIKey, error) {
+	var key middleware.APIKey
+	var publisherID *string
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT id, publisher_id, scopes
+		FROM api_keys
+		WHERE key_hash = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > now())
+	`, HashAPIKey(rawKey)).Scan(&key.ID, &publisherID, &key.Scopes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate api key: %w", err)
+	}
+	if publisherID != nil {
+		key.PublisherID = *publisherID
+	}
+
+	s.usageMu.Lock()
+	s.usage[key.ID]++
+	s.usageMu.Unlock()
+
+	return &key, nil
+}
+
+// flushLoop periodically writes accumulated usage counts
+func (s *APIKeyService) flushLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := s.FlushUsage(ctx); err != nil {
+				slog.Error("failed to flush api key usage", "error", err)
+			}
+			cancel()
+		case <-s.stopFlush:
+			return
+		}
+	}
+}
+
+// FlushUsage writes accumulated usage counts and last-used timestamps
+func (s *APIKeyService) FlushUsage(ctx context.Context) error {
+	s.usageMu.Lock()
+	pending := s.usage
+	s.usage = make(map[string]int64)
+	s.usageMu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for id, count := range pending {
+		batch.Queue(`
+			INSERT INTO api_key_usage (api_key_id, usage_date, request_count)
+			VALUES ($1, CURRENT_DATE, $2)
+			ON CONFLICT (api_key_id, usage_date)
+			DO UPDATE SET request_count = api_key_usage.request_count + EXCLUDED.request_count
+		`, id, count)
+		batch.Queue(`UPDATE api_keys SET last_used_at = now() WHERE id = $1`, id)
+	}
+
+	if err := s.db.Pool.SendBatch(ctx, batch).Close(); err != nil {
+		// Put the counts back so they are retried on the next flush
+		s.usageMu.Lock()
+		for id, count := range pending {
+			s.usage[id] += count
+		}
+		s.usageMu.Unlock()
+		return fmt.Errorf("failed to write api key usage: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/db"
+	"github.com/jcom-dev/zmanim-lab/internal/middleware"
+)
+
+// Rate limit keys in system_config
+const (
+	rateLimitAnonymousKey     = "rate_limit_anonymous"
+	rateLimitAuthenticatedKey = "rate_limit_authenticated"
+	rateLimitQuotasKey        = "rate_limit_quotas"
+	rateLimitRouteCostsKey    = "rate_limit_route_costs"
+)
+
+// RateLimitQuota is a quota as stored in system_config
+type RateLimitQuota struct {
+	Limit         int `json:"limit"`
+	WindowSeconds int `json:"window_seconds"`
+}
+
+// RateLimitQuotas is the value of the rate_limit_quotas system_config entry
+type RateLimitQuotas struct {
+	Publishers map[string]RateLimitQuota `json:"publishers"`
+	APIKeys    map[string]RateLimitQuota `json:"api_keys"`
+}
+
+// rateLimitSnapshot is an immutable view of the rate limit configuration
+type rateLimitSnapshot struct {
+	anonymous     *middleware.Quota
+	authenticated *middleware.Quota
+	publishers    map[string]middleware.Quota
+	apiKeys       map[string]middleware.Quota
+	routeCosts    map[string]int
+}
+
+// RateLimitService loads rate limit quotas and route costs from system_config.
+// It implements middleware.QuotaSource and refreshes its snapshot periodically so
+// admin changes take effect on every replica without a restart.
+type RateLimitService struct {
+	db              *db.DB
+	refreshInterval time.Duration
+
+	mu       sync.RWMutex
+	snapshot rateLimitSnapshot
+	loadedAt time.Time
+}
+
+// NewRateLimitService creates a new rate limit service
+func NewRateLimitService(database *db.DB, refreshInterval time.Duration) *RateLimitService {
+	return &RateLimitService{
+		db:              database,
+		refreshInterval: refreshInterval,
+	}
+}
+
+// QuotaFor implements middleware.QuotaSource
+func (s *RateLimitService) QuotaFor(ctx context.Context, subject string) (middleware.Quota, bool) {
+	snap := s.current(ctx)
+
+	kind, id, _ := strings.Cut(subject, ":")
+	switch kind {
+	case "publisher":
+		if q, ok := snap.publishers[id]; ok {
+			return q, true
+		}
+		if snap.authenticated != nil {
+			return *snap.authenticated, true
+		}
+	case "api_key":
+		if q, ok := snap.apiKeys[id]; ok {
+			return q, true
+		}
+		if snap.authenticated != nil {
+			return *snap.authenticated, true
+		}
+	case "user":
+		if snap.authenticated != nil {
+			return *snap.authenticated, true
+		}
+	case "ip":
+		if snap.anonymous != nil {
+			return *snap.anonymous, true
+		}
+	}
+	return middleware.Quota{}, false
+}
+
+// RouteCost implements middleware.QuotaSource
+func (s *RateLimitService) RouteCost(ctx context.Context, routePattern string) (int, bool) {
+	cost, ok := s.current(ctx).routeCosts[routePattern]
+	return cost, ok
+}
+
+// current returns the cached snapshot, reloading it when stale.
+// On load failure the previous snapshot is kept.
+func (s *RateLimitService) current(ctx context.Context) rateLimitSnapshot {
+	s.mu.RLock()
+	snap := s.snapshot
+	fresh := time.Since(s.loadedAt) < s.refreshInterval
+	s.mu.RUnlock()
+	if fresh {
+		return snap
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another request may have refreshed while we waited for the lock
+	if time.Since(s.loadedAt) < s.refreshInterval {
+		return s.snapshot
+	}
+	// Set loadedAt before loading so a failing database is not hit on every request
+	s.loadedAt = time.Now()
+
+	loaded, err := s.load(ctx)
+	if err != nil {
+		slog.Warn("failed to load rate limit config, keeping previous", "error", err)
+		return s.snapshot
+	}
+	s.snapshot = loaded
+	return loaded
+}
+
+// load reads the rate limit entries from system_config
+func (s *RateLimitService) load(ctx context.Context) (rateLimitSnapshot, error) {
+	snap := rateLimitSnapshot{
+		publishers: map[string]middleware.Quota{},
+		apiKeys:    map[string]middleware.Quota{},
+		routeCosts: map[string]int{},
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT key, value
+		FROM system_config
+		WHERE key = ANY($1)
+	`, []string{rateLimitAnonymousKey, rateLimitAuthenticatedKey, rateLimitQuotasKey, rateLimitRouteCostsKey})
+	if err != nil {
+		return snap, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			return snap, err
+		}
+
+		switch key {
+		case rateLimitAnonymousKey, rateLimitAuthenticatedKey:
+			// Admin settings store {"requests_per_hour": N}
+			var v struct {
+				RequestsPerHour int `json:"requests_per_hour"`
+			}
+			if err := json.Unmarshal(value, &v); err != nil || v.RequestsPerHour <= 0 {
+				slog.Warn("invalid rate limit config value", "key", key, "error", err)
+				continue
+			}
+			q := middleware.Quota{Limit: v.RequestsPerHour, Window: time.Hour}
+			if key == rateLimitAnonymousKey {
+				snap.anonymous = &q
+			} else {
+				snap.authenticated = &q
+			}
+		case rateLimitQuotasKey:
+			var v RateLimitQuotas
+			if err := json.Unmarshal(value, &v); err != nil {
+				slog.Warn("invalid rate limit quotas", "error", err)
+				continue
+			}
+			for id, q := range v.Publishers {
+				if quota, ok := q.toQuota(); ok {
+					snap.publishers[id] = quota
+				}
+			}
+			for id, q := range v.APIKeys {
+				if quota, ok := q.toQuota(); ok {
+					snap.apiKeys[id] = quota
+				}
+			}
+		case rateLimitRouteCostsKey:
+			if err := json.Unmarshal(value, &snap.routeCosts); err != nil {
+				slog.Warn("invalid rate limit route costs", "error", err)
+			}
+		}
+	}
+
+	return snap, rows.Err()
+}
+
+// toQuota converts a stored quota, rejecting non-positive values
+func (q RateLimitQuota) toQuota() (middleware.Quota, bool) {
+	if q.Limit <= 0 || q.WindowSeconds <= 0 {
+		return middleware.Quota{}, false
+	}
+	return middleware.Quota{Limit: q.Limit, Window: time.Duration(q.WindowSeconds) * time.Second}, true
+}
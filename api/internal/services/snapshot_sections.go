@@ -0,0 +1,546 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/db/sqlcgen"
+)
+
+// Snapshot sections. A section listed in PublisherSnapshot.Sections is
+// authoritative: applying it makes the publisher's data match it exactly.
+const (
+	SnapshotSectionProfile  = "profile"
+	SnapshotSectionZmanim   = "zmanim"
+	SnapshotSectionAliases  = "aliases"
+	SnapshotSectionTags     = "tags"
+	SnapshotSectionDayTypes = "day_types"
+	SnapshotSectionEvents   = "events"
+	SnapshotSectionCoverage = "coverage"
+)
+
+// SnapshotSections lists every section in the order it is applied
+var SnapshotSections = []string{
+	SnapshotSectionProfile,
+	SnapshotSectionZmanim,
+	SnapshotSectionAliases,
+	SnapshotSectionTags,
+	SnapshotSectionDayTypes,
+	SnapshotSectionEvents,
+	SnapshotSectionCoverage,
+}
+
+// IsValidSnapshotSection reports whether name is a known snapshot section
+func IsValidSnapshotSection(name string) bool {
+	for _, s := range SnapshotSections {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SnapshotProfile contains the publisher's public profile
+type SnapshotProfile struct {
+	Name        string  `json:"name"`
+	Email       string  `json:"email"`
+	Phone       *string `json:"phone,omitempty"`
+	Website     *string `json:"website,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Bio         *string `json:"bio,omitempty"`
+	LogoURL     *string `json:"logo_url,omitempty"`
+	LogoData    *string `json:"logo_data,omitempty"`
+}
+
+// SnapshotZmanAlias is an alternative name for a zman (unique per publisher by Hebrew text)
+type SnapshotZmanAlias struct {
+	ZmanKey              string  `json:"zman_key"`
+	AliasHebrew          string  `json:"alias_hebrew"`
+	AliasEnglish         *string `json:"alias_english,omitempty"`
+	AliasTransliteration *string `json:"alias_transliteration,omitempty"`
+	Context              *string `json:"context,omitempty"`
+	IsPrimary            bool    `json:"is_primary"`
+	SortOrder            int32   `json:"sort_order"`
+}
+
+// SnapshotZmanTag links a zman to a tag by tag_key
+type SnapshotZmanTag struct {
+	ZmanKey   string `json:"zman_key"`
+	TagKey    string `json:"tag_key"`
+	IsNegated bool   `json:"is_negated"`
+}
+
+// SnapshotZmanDayType is a per-day-type override, keyed by day type name
+type SnapshotZmanDayType struct {
+	ZmanKey             string  `json:"zman_key"`
+	DayType             string  `json:"day_type"`
+	OverrideFormulaDSL  *string `json:"override_formula_dsl,omitempty"`
+	OverrideHebrewName  *string `json:"override_hebrew_name,omitempty"`
+	OverrideEnglishName *string `json:"override_english_name,omitempty"`
+}
+
+// SnapshotZmanEvent is a per-event override, keyed by jewish event code
+type SnapshotZmanEvent struct {
+	ZmanKey             string  `json:"zman_key"`
+	EventCode           string  `json:"event_code"`
+	OverrideFormulaDSL  *string `json:"override_formula_dsl,omitempty"`
+	OverrideHebrewName  *string `json:"override_hebrew_name,omitempty"`
+	OverrideEnglishName *string `json:"override_english_name,omitempty"`
+	IsEnabled           bool    `json:"is_enabled"`
+}
+
+// SnapshotCoverage is a coverage area identified by geographic codes rather than
+// database IDs, so it can be imported into another environment. Cities are the
+// exception: they have no stable code and are referenced by ID. Location,
+// polygon and radius coverage are publisher-drawn and not part of snapshots.
+type SnapshotCoverage struct {
+	CoverageLevel string  `json:"coverage_level"`
+	ContinentCode *string `json:"continent_code,omitempty"`
+	CountryCode   *string `json:"country_code,omitempty"`
+	RegionCode    *string `json:"region_code,omitempty"`
+	DistrictCode  *string `json:"district_code,omitempty"`
+	CityID        *string `json:"city_id,omitempty"`
+	CityName      *string `json:"city_name,omitempty" diff:"-"`
+	Priority      int32   `json:"priority"`
+	IsActive      bool    `json:"is_active"`
+
+	// Resolved database IDs of the level's own geography
+	countryID  *int16
+	regionID   *int32
+	districtID *int32
+}
+
+// normalize clears the codes that do not identify the coverage at its level
+func (c *SnapshotCoverage) normalize() {
+	level := c.CoverageLevel
+	if level != "continent" {
+		c.ContinentCode = nil
+	}
+	if level == "continent" || level == "city" {
+		c.CountryCode = nil
+	}
+	if level != "region" && level != "district" {
+		c.RegionCode = nil
+	}
+	if level != "district" {
+		c.DistrictCode = nil
+	}
+	if level != "city" {
+		c.CityID, c.CityName = nil, nil
+	}
+}
+
+// Snapshot diff actions
+const (
+	SnapshotActionAdd     = "add"
+	SnapshotActionUpdate  = "update"
+	SnapshotActionRemove  = "remove"
+	SnapshotActionRestore = "restore"
+)
+
+// SnapshotChange is a single row-level change an apply would make
+type SnapshotChange struct {
+	Action string `json:"action"`
+	Key    string `json:"key"`
+	// Fields lists the changed fields of an update
+	Fields []string `json:"fields,omitempty"`
+}
+
+// SnapshotSectionDiff holds the changes to one section
+type SnapshotSectionDiff struct {
+	Section string           `json:"section"`
+	Changes []SnapshotChange `json:"changes"`
+}
+
+// SnapshotDiff describes what applying a snapshot changes (or changed)
+type SnapshotDiff struct {
+	DryRun   bool                  `json:"dry_run"`
+	Sections []SnapshotSectionDiff `json:"sections"`
+	// Warnings lists entries that were skipped, e.g. references to unknown tags or cities
+	Warnings []string `json:"warnings"`
+}
+
+// ChangeCount returns the total number of changes across sections
+func (d *SnapshotDiff) ChangeCount() int {
+	n := 0
+	for _, s := range d.Sections {
+		n += len(s.Changes)
+	}
+	return n
+}
+
+func (d *SnapshotDiff) warnf(format string, args ...interface{}) {
+	d.Warnings = append(d.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Snapshot record keys used in diffs
+func profileKey(SnapshotProfile) string       { return "profile" }
+func zmanKey(z SnapshotZman) string           { return z.ZmanKey }
+func aliasKey(a SnapshotZmanAlias) string     { return a.AliasHebrew }
+func tagKey(t SnapshotZmanTag) string         { return t.ZmanKey + "/" + t.TagKey }
+func dayTypeKey(d SnapshotZmanDayType) string { return d.ZmanKey + "/" + d.DayType }
+func eventKey(e SnapshotZmanEvent) string     { return e.ZmanKey + "/" + e.EventCode }
+
+func coverageKey(c SnapshotCoverage) string {
+	switch c.CoverageLevel {
+	case "continent":
+		return "continent:" + ptrToString(c.ContinentCode)
+	case "country":
+		return "country:" + ptrToString(c.CountryCode)
+	case "region":
+		return "region:" + ptrToString(c.CountryCode) + "/" + ptrToString(c.RegionCode)
+	case "district":
+		return "district:" + ptrToString(c.CountryCode) + "/" + ptrToString(c.RegionCode) + "/" + ptrToString(c.DistrictCode)
+	default:
+		return c.CoverageLevel + ":" + ptrToString(c.CityID)
+	}
+}
+
+// sectionPlan holds the writes that bring one section in line with a snapshot
+type sectionPlan[T any] struct {
+	upserts []T // adds and updates, in snapshot order
+	removes []T
+	diff    SnapshotSectionDiff
+}
+
+// planSection compares current rows with the snapshot's rows by key.
+// Duplicate keys in the snapshot keep their first occurrence.
+func planSection[T any](section string, current, desired []T, key func(T) string) sectionPlan[T] {
+	p := sectionPlan[T]{diff: SnapshotSectionDiff{Section: section, Changes: []SnapshotChange{}}}
+
+	currentByKey := make(map[string]T, len(current))
+	for _, c := range current {
+		currentByKey[key(c)] = c
+	}
+
+	desiredKeys := make(map[string]bool, len(desired))
+	for _, d := range desired {
+		k := key(d)
+		if desiredKeys[k] {
+			continue
+		}
+		desiredKeys[k] = true
+
+		cur, exists := currentByKey[k]
+		if !exists {
+			p.upserts = append(p.upserts, d)
+			p.diff.Changes = append(p.diff.Changes, SnapshotChange{Action: SnapshotActionAdd, Key: k})
+			continue
+		}
+		if fields := changedFields(cur, d); len(fields) > 0 {
+			p.upserts = append(p.upserts, d)
+			p.diff.Changes = append(p.diff.Changes, SnapshotChange{Action: SnapshotActionUpdate, Key: k, Fields: fields})
+		}
+	}
+
+	for _, c := range current {
+		if k := key(c); !desiredKeys[k] {
+			p.removes = append(p.removes, c)
+			p.diff.Changes = append(p.diff.Changes, SnapshotChange{Action: SnapshotActionRemove, Key: k})
+		}
+	}
+
+	return p
+}
+
+// changedFields lists the JSON names of the exported fields that differ between
+// two structs of the same type. Fields tagged diff:"-" are informational only.
+func changedFields(a, b interface{}) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("diff") == "-" {
+			continue
+		}
+		if reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// filterSnapshotRows drops rows for which check reports a problem, recording a warning
+func filterSnapshotRows[T any](diff *SnapshotDiff, section string, rows []T, key func(T) string, check func(T) string) []T {
+	kept := make([]T, 0, len(rows))
+	for _, row := range rows {
+		if problem := check(row); problem != "" {
+			diff.warnf("%s %s skipped: %s", section, key(row), problem)
+			continue
+		}
+		kept = append(kept, row)
+	}
+	return kept
+}
+
+// ============================================================================
+// Loading current state
+// ============================================================================
+
+func loadSnapshotProfile(ctx context.Context, dbtx sqlcgen.DBTX, publisherID string) (SnapshotProfile, error) {
+	var p SnapshotProfile
+	err := dbtx.QueryRow(ctx, `
+		SELECT name, email, phone, website, description, bio, logo_url, logo_data
+		FROM publishers
+		WHERE id = $1
+	`, publisherID).Scan(&p.Name, &p.Email, &p.Phone, &p.Website, &p.Description, &p.Bio, &p.LogoURL, &p.LogoData)
+	if err != nil {
+		return p, fmt.Errorf("failed to get publisher profile: %w", err)
+	}
+	return p, nil
+}
+
+func loadSnapshotZmanim(ctx context.Context, q *sqlcgen.Queries, publisherID string) ([]SnapshotZman, error) {
+	rows, err := q.GetPublisherZmanimForSnapshot(ctx, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get publisher zmanim: %w", err)
+	}
+
+	zmanim := make([]SnapshotZman, len(rows))
+	for i, z := range rows {
+		var masterZmanID, linkedZmanID *string
+		if z.MasterZmanID.Valid {
+			id := uuidBytesToString(z.MasterZmanID.Bytes)
+			masterZmanID = &id
+		}
+		if z.LinkedPublisherZmanID.Valid {
+			id := uuidBytesToString(z.LinkedPublisherZmanID.Bytes)
+			linkedZmanID = &id
+		}
+		sourceType := z.SourceType
+		if sourceType == "" {
+			sourceType = "registry"
+		}
+		zmanim[i] = SnapshotZman{
+			ZmanKey:               z.ZmanKey,
+			HebrewName:            z.HebrewName,
+			EnglishName:           z.EnglishName,
+			Transliteration:       z.Transliteration,
+			Description:           z.Description,
+			FormulaDSL:            z.FormulaDsl,
+			AIExplanation:         z.AiExplanation,
+			PublisherComment:      z.PublisherComment,
+			IsEnabled:             z.IsEnabled,
+			IsVisible:             z.IsVisible,
+			IsPublished:           z.IsPublished,
+			IsBeta:                z.IsBeta,
+			IsCustom:              z.IsCustom,
+			Category:              z.Category,
+			MasterZmanID:          masterZmanID,
+			LinkedPublisherZmanID: linkedZmanID,
+			SourceType:            sourceType,
+		}
+	}
+	return zmanim, nil
+}
+
+func loadSnapshotAliases(ctx context.Context, dbtx sqlcgen.DBTX, publisherID string) ([]SnapshotZmanAlias, error) {
+	rows, err := dbtx.Query(ctx, `
+		SELECT pz.zman_key, a.alias_hebrew, a.alias_english, a.alias_transliteration,
+		       a.context, a.is_primary, COALESCE(a.sort_order, 0)
+		FROM publisher_zman_aliases a
+		JOIN publisher_zmanim pz ON pz.id = a.publisher_zman_id
+		WHERE a.publisher_id = $1 AND pz.deleted_at IS NULL
+		ORDER BY pz.zman_key, a.sort_order, a.alias_hebrew
+	`, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zman aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := []SnapshotZmanAlias{}
+	for rows.Next() {
+		var a SnapshotZmanAlias
+		if err := rows.Scan(&a.ZmanKey, &a.AliasHebrew, &a.AliasEnglish, &a.AliasTransliteration,
+			&a.Context, &a.IsPrimary, &a.SortOrder); err != nil {
+			return nil, fmt.Errorf("failed to scan zman alias: %w", err)
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+func loadSnapshotTags(ctx context.Context, dbtx sqlcgen.DBTX, publisherID string) ([]SnapshotZmanTag, error) {
+	rows, err := dbtx.Query(ctx, `
+		SELECT pz.zman_key, t.tag_key, pzt.is_negated
+		FROM publisher_zman_tags pzt
+		JOIN publisher_zmanim pz ON pz.id = pzt.publisher_zman_id
+		JOIN zman_tags t ON t.id = pzt.tag_id
+		WHERE pz.publisher_id = $1 AND pz.deleted_at IS NULL
+		ORDER BY pz.zman_key, t.tag_key
+	`, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zman tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []SnapshotZmanTag{}
+	for rows.Next() {
+		var t SnapshotZmanTag
+		if err := rows.Scan(&t.ZmanKey, &t.TagKey, &t.IsNegated); err != nil {
+			return nil, fmt.Errorf("failed to scan zman tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func loadSnapshotDayTypes(ctx context.Context, dbtx sqlcgen.DBTX, publisherID string) ([]SnapshotZmanDayType, error) {
+	rows, err := dbtx.Query(ctx, `
+		SELECT pz.zman_key, dt.name, pzdt.override_formula_dsl, pzdt.override_hebrew_name, pzdt.override_english_name
+		FROM publisher_zman_day_types pzdt
+		JOIN publisher_zmanim pz ON pz.id = pzdt.publisher_zman_id
+		JOIN day_types dt ON dt.id = pzdt.day_type_id
+		WHERE pz.publisher_id = $1 AND pz.deleted_at IS NULL
+		ORDER BY pz.zman_key, dt.name
+	`, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zman day types: %w", err)
+	}
+	defer rows.Close()
+
+	dayTypes := []SnapshotZmanDayType{}
+	for rows.Next() {
+		var d SnapshotZmanDayType
+		if err := rows.Scan(&d.ZmanKey, &d.DayType, &d.OverrideFormulaDSL, &d.OverrideHebrewName, &d.OverrideEnglishName); err != nil {
+			return nil, fmt.Errorf("failed to scan zman day type: %w", err)
+		}
+		dayTypes = append(dayTypes, d)
+	}
+	return dayTypes, rows.Err()
+}
+
+func loadSnapshotEvents(ctx context.Context, dbtx sqlcgen.DBTX, publisherID string) ([]SnapshotZmanEvent, error) {
+	rows, err := dbtx.Query(ctx, `
+		SELECT pz.zman_key, je.code, pze.override_formula_dsl, pze.override_hebrew_name,
+		       pze.override_english_name, pze.is_enabled
+		FROM publisher_zman_events pze
+		JOIN publisher_zmanim pz ON pz.id = pze.publisher_zman_id
+		JOIN jewish_events je ON je.id = pze.jewish_event_id
+		WHERE pz.publisher_id = $1 AND pz.deleted_at IS NULL
+		ORDER BY pz.zman_key, je.code
+	`, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get zman events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []SnapshotZmanEvent{}
+	for rows.Next() {
+		var e SnapshotZmanEvent
+		if err := rows.Scan(&e.ZmanKey, &e.EventCode, &e.OverrideFormulaDSL, &e.OverrideHebrewName,
+			&e.OverrideEnglishName, &e.IsEnabled); err != nil {
+			return nil, fmt.Errorf("failed to scan zman event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func loadSnapshotCoverage(ctx context.Context, dbtx sqlcgen.DBTX, publisherID string) ([]SnapshotCoverage, error) {
+	rows, err := dbtx.Query(ctx, `
+		SELECT pc.coverage_level, pc.continent_code, co.code, r.code, d.code, pc.city_id::text, ci.name,
+		       COALESCE(pc.priority, 0), pc.is_active, pc.country_id, pc.region_id, pc.district_id
+		FROM publisher_coverage pc
+		LEFT JOIN geo_districts d ON d.id = pc.district_id
+		LEFT JOIN geo_regions r ON r.id = COALESCE(pc.region_id, d.region_id)
+		LEFT JOIN geo_countries co ON co.id = COALESCE(pc.country_id, r.country_id, d.country_id)
+		LEFT JOIN geo_cities ci ON ci.id = pc.city_id
+		WHERE pc.publisher_id = $1
+		  AND pc.coverage_level IN ('continent', 'country', 'region', 'district', 'city')
+		ORDER BY pc.coverage_level, pc.priority DESC, pc.created_at
+	`, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coverage: %w", err)
+	}
+	defer rows.Close()
+
+	coverage := []SnapshotCoverage{}
+	for rows.Next() {
+		var c SnapshotCoverage
+		if err := rows.Scan(&c.CoverageLevel, &c.ContinentCode, &c.CountryCode, &c.RegionCode, &c.DistrictCode,
+			&c.CityID, &c.CityName, &c.Priority, &c.IsActive, &c.countryID, &c.regionID, &c.districtID); err != nil {
+			return nil, fmt.Errorf("failed to scan coverage: %w", err)
+		}
+		c.normalize()
+		coverage = append(coverage, c)
+	}
+	return coverage, rows.Err()
+}
+
+// loadKeySet returns the values of a single text column as a set
+func loadKeySet(ctx context.Context, dbtx sqlcgen.DBTX, query string, args ...interface{}) (map[string]bool, error) {
+	rows, err := dbtx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys[k] = true
+	}
+	return keys, rows.Err()
+}
+
+// resolveCoverage looks up the database ID of a snapshot coverage area's geography.
+// It returns a non-empty problem when the area does not exist in this database.
+func resolveCoverage(ctx context.Context, dbtx sqlcgen.DBTX, c *SnapshotCoverage) (string, error) {
+	var err error
+	switch c.CoverageLevel {
+	case "continent":
+		if c.ContinentCode == nil || *c.ContinentCode == "" {
+			return "continent_code is required", nil
+		}
+		return "", nil
+	case "country":
+		err = dbtx.QueryRow(ctx, `SELECT id FROM geo_countries WHERE code = $1`,
+			ptrToString(c.CountryCode)).Scan(&c.countryID)
+	case "region":
+		err = dbtx.QueryRow(ctx, `
+			SELECT r.id FROM geo_regions r
+			JOIN geo_countries co ON co.id = r.country_id
+			WHERE co.code = $1 AND r.code = $2
+			LIMIT 1
+		`, ptrToString(c.CountryCode), ptrToString(c.RegionCode)).Scan(&c.regionID)
+	case "district":
+		err = dbtx.QueryRow(ctx, `
+			SELECT d.id FROM geo_districts d
+			LEFT JOIN geo_regions r ON r.id = d.region_id
+			JOIN geo_countries co ON co.id = COALESCE(d.country_id, r.country_id)
+			WHERE co.code = $1 AND r.code IS NOT DISTINCT FROM $2 AND d.code = $3
+			LIMIT 1
+		`, ptrToString(c.CountryCode), c.RegionCode, ptrToString(c.DistrictCode)).Scan(&c.districtID)
+	case "city":
+		if _, perr := uuid.Parse(ptrToString(c.CityID)); perr != nil {
+			return "city_id must be a UUID", nil
+		}
+		var id string
+		err = dbtx.QueryRow(ctx, `SELECT id::text FROM geo_cities WHERE id = $1::uuid`, *c.CityID).Scan(&id)
+	default:
+		return fmt.Sprintf("unknown coverage level %q", c.CoverageLevel), nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "geography not found", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve coverage %s: %w", coverageKey(*c), err)
+	}
+	return "", nil
+}
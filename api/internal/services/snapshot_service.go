@@ -3,10 +3,12 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jcom-dev/zmanim-lab/internal/db"
 	"github.com/jcom-dev/zmanim-lab/internal/db/sqlcgen"
@@ -22,12 +24,94 @@ func NewSnapshotService(database *db.DB) *SnapshotService {
 	return &SnapshotService{db: database}
 }
 
-// PublisherSnapshot represents a snapshot of publisher zmanim data (zmanim-only, not profile/coverage)
+// SnapshotFormatVersion is the snapshot format written by BuildSnapshot.
+// Version 1 files contain zmanim only; MigrateSnapshot upgrades them on read.
+const SnapshotFormatVersion = 2
+
+// Snapshot validation errors
+var (
+	ErrInvalidSnapshot            = errors.New("invalid snapshot")
+	ErrUnsupportedSnapshotVersion = errors.New("unsupported snapshot version")
+	ErrUnknownSnapshotSection     = errors.New("unknown snapshot section")
+)
+
+// PublisherSnapshot represents a snapshot of publisher data. Sections lists the
+// parts of the publisher the snapshot covers; sections not listed are left
+// untouched when the snapshot is applied.
 type PublisherSnapshot struct {
-	Version     int            `json:"version"`
-	ExportedAt  string         `json:"exported_at"`
-	Description string         `json:"description"`
-	Zmanim      []SnapshotZman `json:"zmanim"`
+	Version     int                   `json:"version"`
+	ExportedAt  string                `json:"exported_at"`
+	Description string                `json:"description"`
+	Sections    []string              `json:"sections,omitempty"`
+	Profile     *SnapshotProfile      `json:"profile,omitempty"`
+	Zmanim      []SnapshotZman        `json:"zmanim"`
+	Aliases     []SnapshotZmanAlias   `json:"aliases,omitempty"`
+	Tags        []SnapshotZmanTag     `json:"tags,omitempty"`
+	DayTypes    []SnapshotZmanDayType `json:"day_types,omitempty"`
+	Events      []SnapshotZmanEvent   `json:"events,omitempty"`
+	Coverage    []SnapshotCoverage    `json:"coverage,omitempty"`
+}
+
+// HasSection reports whether the snapshot covers the named section
+func (p *PublisherSnapshot) HasSection(name string) bool {
+	for _, s := range p.Sections {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateSnapshot validates a snapshot and upgrades it to SnapshotFormatVersion
+func MigrateSnapshot(snapshot *PublisherSnapshot) error {
+	switch snapshot.Version {
+	case 0:
+		return fmt.Errorf("%w: version is required", ErrInvalidSnapshot)
+	case 1:
+		// v1 only ever carried zmanim
+		snapshot.Version = SnapshotFormatVersion
+		snapshot.Sections = []string{SnapshotSectionZmanim}
+	case SnapshotFormatVersion:
+		if len(snapshot.Sections) == 0 {
+			return fmt.Errorf("%w: sections is required", ErrInvalidSnapshot)
+		}
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedSnapshotVersion, snapshot.Version)
+	}
+
+	for _, section := range snapshot.Sections {
+		if !IsValidSnapshotSection(section) {
+			return fmt.Errorf("%w: %s", ErrUnknownSnapshotSection, section)
+		}
+	}
+	if snapshot.HasSection(SnapshotSectionProfile) && snapshot.Profile == nil {
+		return fmt.Errorf("%w: profile section has no profile", ErrInvalidSnapshot)
+	}
+	for i := range snapshot.Zmanim {
+		if snapshot.Zmanim[i].SourceType == "" {
+			snapshot.Zmanim[i].SourceType = "registry"
+		}
+	}
+	return nil
+}
+
+// ApplyOptions controls how a snapshot is applied
+type ApplyOptions struct {
+	// Sections limits the apply to these sections (default: all sections in the snapshot)
+	Sections []string
+	// DryRun computes the diff without writing anything
+	DryRun bool
+}
+
+// validate rejects unknown sections, so a bad request fails before the
+// current state is auto-saved
+func (o ApplyOptions) validate() error {
+	for _, section := range o.Sections {
+		if !IsValidSnapshotSection(section) {
+			return fmt.Errorf("%w: %s", ErrUnknownSnapshotSection, section)
+		}
+	}
+	return nil
 }
 
 // SnapshotZman contains zman fields
@@ -59,56 +143,38 @@ type SnapshotMeta struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-// BuildSnapshot creates a snapshot of the current publisher zmanim state
+// BuildSnapshot creates a snapshot of the current publisher state covering every section
 func (s *SnapshotService) BuildSnapshot(ctx context.Context, publisherID string, description string) (*PublisherSnapshot, error) {
-	// Get zmanim (only active, non-deleted)
-	zmanimRows, err := s.db.Queries.GetPublisherZmanimForSnapshot(ctx, publisherID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get publisher zmanim: %w", err)
-	}
-
-	// Build snapshot
 	snapshot := &PublisherSnapshot{
-		Version:     1,
+		Version:     SnapshotFormatVersion,
 		ExportedAt:  time.Now().UTC().Format(time.RFC3339),
 		Description: description,
-		Zmanim:      make([]SnapshotZman, len(zmanimRows)),
-	}
-
-	// Map zmanim
-	for i, z := range zmanimRows {
-		var masterZmanID, linkedZmanID *string
-		if z.MasterZmanID.Valid {
-			id := uuidBytesToString(z.MasterZmanID.Bytes)
-			masterZmanID = &id
-		}
-		if z.LinkedPublisherZmanID.Valid {
-			id := uuidBytesToString(z.LinkedPublisherZmanID.Bytes)
-			linkedZmanID = &id
-		}
-		sourceType := z.SourceType
-		if sourceType == "" {
-			sourceType = "registry"
-		}
-		snapshot.Zmanim[i] = SnapshotZman{
-			ZmanKey:               z.ZmanKey,
-			HebrewName:            z.HebrewName,
-			EnglishName:           z.EnglishName,
-			Transliteration:       z.Transliteration,
-			Description:           z.Description,
-			FormulaDSL:            z.FormulaDsl,
-			AIExplanation:         z.AiExplanation,
-			PublisherComment:      z.PublisherComment,
-			IsEnabled:             z.IsEnabled,
-			IsVisible:             z.IsVisible,
-			IsPublished:           z.IsPublished,
-			IsBeta:                z.IsBeta,
-			IsCustom:              z.IsCustom,
-			Category:              z.Category,
-			MasterZmanID:          masterZmanID,
-			LinkedPublisherZmanID: linkedZmanID,
-			SourceType:            sourceType,
-		}
+		Sections:    SnapshotSections,
+	}
+
+	profile, err := loadSnapshotProfile(ctx, s.db.Pool, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Profile = &profile
+
+	if snapshot.Zmanim, err = loadSnapshotZmanim(ctx, s.db.Queries, publisherID); err != nil {
+		return nil, err
+	}
+	if snapshot.Aliases, err = loadSnapshotAliases(ctx, s.db.Pool, publisherID); err != nil {
+		return nil, err
+	}
+	if snapshot.Tags, err = loadSnapshotTags(ctx, s.db.Pool, publisherID); err != nil {
+		return nil, err
+	}
+	if snapshot.DayTypes, err = loadSnapshotDayTypes(ctx, s.db.Pool, publisherID); err != nil {
+		return nil, err
+	}
+	if snapshot.Events, err = loadSnapshotEvents(ctx, s.db.Pool, publisherID); err != nil {
+		return nil, err
+	}
+	if snapshot.Coverage, err = loadSnapshotCoverage(ctx, s.db.Pool, publisherID); err != nil {
+		return nil, err
 	}
 
 	return snapshot, nil
@@ -181,6 +247,9 @@ func (s *SnapshotService) GetSnapshot(ctx context.Context, snapshotID, publisher
 	if err := json.Unmarshal(row.SnapshotData, &snapshot); err != nil {
 		return nil, fmt.Errorf("failed to parse snapshot data: %w", err)
 	}
+	if err := MigrateSnapshot(&snapshot); err != nil {
+		return nil, err
+	}
 
 	return &snapshot, nil
 }
@@ -197,173 +266,465 @@ func (s *SnapshotService) DeleteSnapshot(ctx context.Context, snapshotID, publis
 	return nil
 }
 
-// ApplySnapshot applies a snapshot to the publisher's zmanim with smart diff logic:
-// - Zmanim in snapshot but not in current state: insert new or restore if soft-deleted
-// - Zmanim in both: update only if different (creates new version)
-// - Zmanim in current state but not in snapshot: soft-delete
-func (s *SnapshotService) ApplySnapshot(ctx context.Context, publisherID string, userID string, snapshot *PublisherSnapshot) error {
-	// Build a map of snapshot zmanim for quick lookup
-	snapshotZmanim := make(map[string]SnapshotZman)
-	for _, z := range snapshot.Zmanim {
-		snapshotZmanim[z.ZmanKey] = z
+// snapshotPlan holds the per-section writes computed for an apply
+type snapshotPlan struct {
+	diff     SnapshotDiff
+	sections map[string]bool
+
+	profile  sectionPlan[SnapshotProfile]
+	zmanim   sectionPlan[SnapshotZman]
+	deleted  map[string]bool // soft-deleted zman keys, restored instead of inserted
+	aliases  sectionPlan[SnapshotZmanAlias]
+	tags     sectionPlan[SnapshotZmanTag]
+	dayTypes sectionPlan[SnapshotZmanDayType]
+	events   sectionPlan[SnapshotZmanEvent]
+	coverage sectionPlan[SnapshotCoverage]
+}
+
+// selectSnapshotSections returns the requested sections in apply order. An empty
+// request selects every section the snapshot covers.
+func selectSnapshotSections(snapshot *PublisherSnapshot, requested []string, diff *SnapshotDiff) (map[string]bool, error) {
+	if len(requested) == 0 {
+		requested = snapshot.Sections
+	}
+	selected := make(map[string]bool)
+	for _, section := range requested {
+		if !IsValidSnapshotSection(section) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSnapshotSection, section)
+		}
+		if !snapshot.HasSection(section) {
+			diff.warnf("section %s is not in the snapshot and was skipped", section)
+			continue
+		}
+		selected[section] = true
+	}
+	return selected, nil
+}
+
+// ApplySnapshot brings the publisher's data in line with the selected snapshot
+// sections, in a single transaction, and returns the changes made. Within each
+// section:
+// - Rows in the snapshot but not in current state are added (soft-deleted zmanim are restored)
+// - Rows in both are updated only if different (zman updates create a new version)
+// - Rows in current state but not in the snapshot are removed (zmanim are soft-deleted)
+//
+// With DryRun set nothing is written and the returned diff is what would change.
+func (s *SnapshotService) ApplySnapshot(ctx context.Context, publisherID string, userID string, snapshot *PublisherSnapshot, opts ApplyOptions) (*SnapshotDiff, error) {
+	if err := MigrateSnapshot(snapshot); err != nil {
+		return nil, err
 	}
 
-	// Get current active zman keys
-	currentKeys, err := s.db.Queries.GetAllPublisherZmanimKeys(ctx, publisherID)
+	tx, err := s.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get current zman keys: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	currentKeySet := make(map[string]bool)
-	for _, key := range currentKeys {
-		currentKeySet[key] = true
+	q := s.db.Queries.WithTx(tx)
+
+	plan, err := s.planSnapshot(ctx, tx, q, publisherID, snapshot, opts.Sections)
+	if err != nil {
+		return nil, err
+	}
+	plan.diff.DryRun = opts.DryRun
+	if opts.DryRun {
+		return &plan.diff, nil
 	}
 
-	// 1. Soft-delete zmanim not in snapshot
-	for key := range currentKeySet {
-		if _, inSnapshot := snapshotZmanim[key]; !inSnapshot {
-			err := s.db.Queries.SoftDeleteZmanForRestore(ctx, sqlcgen.SoftDeleteZmanForRestoreParams{
-				PublisherID: publisherID,
-				ZmanKey:     key,
-				DeletedBy:   &userID,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to soft-delete zman %s: %w", key, err)
-			}
+	if err := s.executeSnapshotPlan(ctx, tx, q, publisherID, userID, plan); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+
+	return &plan.diff, nil
+}
+
+// planSnapshot compares the selected snapshot sections with the publisher's current state
+func (s *SnapshotService) planSnapshot(ctx context.Context, tx pgx.Tx, q *sqlcgen.Queries, publisherID string, snapshot *PublisherSnapshot, requested []string) (*snapshotPlan, error) {
+	plan := &snapshotPlan{diff: SnapshotDiff{Sections: []SnapshotSectionDiff{}, Warnings: []string{}}}
+
+	sections, err := selectSnapshotSections(snapshot, requested, &plan.diff)
+	if err != nil {
+		return nil, err
+	}
+	plan.sections = sections
+
+	if sections[SnapshotSectionProfile] {
+		current, err := loadSnapshotProfile(ctx, tx, publisherID)
+		if err != nil {
+			return nil, err
+		}
+		desired := []SnapshotProfile{*snapshot.Profile}
+		if desired[0].Name == "" || desired[0].Email == "" {
+			plan.diff.warnf("profile skipped: name and email are required")
+			desired = []SnapshotProfile{current}
 		}
+		plan.profile = planSection(SnapshotSectionProfile, []SnapshotProfile{current}, desired, profileKey)
+		plan.diff.Sections = append(plan.diff.Sections, plan.profile.diff)
 	}
 
-	// 2. Process each zman in the snapshot
-	for _, snapZman := range snapshot.Zmanim {
-		// Check if zman exists in current active state
-		if currentKeySet[snapZman.ZmanKey] {
-			// Zman exists - check if different and update if so
-			existing, err := s.db.Queries.GetPublisherZmanForSnapshotCompare(ctx, sqlcgen.GetPublisherZmanForSnapshotCompareParams{
-				PublisherID: publisherID,
-				ZmanKey:     snapZman.ZmanKey,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to get existing zman %s: %w", snapZman.ZmanKey, err)
-			}
+	// Child rows reference zmanim by key; they can only attach to zmanim that
+	// exist once the apply is done.
+	current, err := loadSnapshotZmanim(ctx, q, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	zmanKeys := make(map[string]bool)
+	if sections[SnapshotSectionZmanim] {
+		plan.deleted, err = loadKeySet(ctx, tx, `
+			SELECT zman_key FROM publisher_zmanim WHERE publisher_id = $1 AND deleted_at IS NOT NULL
+		`, publisherID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deleted zmanim: %w", err)
+		}
 
-			// Compare and update only if different
-			if s.zmanDiffers(existing, snapZman) {
-				err = s.updateZmanFromSnapshot(ctx, publisherID, snapZman)
-				if err != nil {
-					return fmt.Errorf("failed to update zman %s: %w", snapZman.ZmanKey, err)
-				}
-			}
-		} else {
-			// Zman doesn't exist in active state - check if soft-deleted
-			deleted, err := s.db.Queries.GetDeletedZmanByKey(ctx, sqlcgen.GetDeletedZmanByKeyParams{
-				PublisherID: publisherID,
-				ZmanKey:     snapZman.ZmanKey,
-			})
-			if err == nil && deleted.ID != "" {
-				// Restore soft-deleted zman and then update
-				err = s.db.Queries.RestoreDeletedZmanForSnapshot(ctx, sqlcgen.RestoreDeletedZmanForSnapshotParams{
-					PublisherID: publisherID,
-					ZmanKey:     snapZman.ZmanKey,
-				})
-				if err != nil {
-					return fmt.Errorf("failed to restore deleted zman %s: %w", snapZman.ZmanKey, err)
-				}
-				// Update with snapshot data
-				err = s.updateZmanFromSnapshot(ctx, publisherID, snapZman)
-				if err != nil {
-					return fmt.Errorf("failed to update restored zman %s: %w", snapZman.ZmanKey, err)
-				}
-			} else {
-				// Zman doesn't exist at all - insert new
-				err = s.insertZmanFromSnapshot(ctx, publisherID, snapZman)
-				if err != nil {
-					return fmt.Errorf("failed to insert zman %s: %w", snapZman.ZmanKey, err)
-				}
+		plan.zmanim = planSection(SnapshotSectionZmanim, current, snapshot.Zmanim, zmanKey)
+		for i, change := range plan.zmanim.diff.Changes {
+			if change.Action == SnapshotActionAdd && plan.deleted[change.Key] {
+				plan.zmanim.diff.Changes[i].Action = SnapshotActionRestore
 			}
 		}
-	}
+		plan.diff.Sections = append(plan.diff.Sections, plan.zmanim.diff)
 
-	return nil
-}
+		for _, z := range snapshot.Zmanim {
+			zmanKeys[z.ZmanKey] = true
+		}
+	} else {
+		for _, z := range current {
+			zmanKeys[z.ZmanKey] = true
+		}
+	}
+	missingZman := func(key string) string {
+		if !zmanKeys[key] {
+			return fmt.Sprintf("zman %q does not exist", key)
+		}
+		return ""
+	}
+	ownZman := func(key string) bool { return zmanKeys[key] }
 
-// zmanDiffers checks if an existing zman differs from a snapshot zman
-func (s *SnapshotService) zmanDiffers(existing sqlcgen.GetPublisherZmanForSnapshotCompareRow, snap SnapshotZman) bool {
-	if existing.HebrewName != snap.HebrewName {
-		return true
+	if sections[SnapshotSectionAliases] {
+		current, err := loadSnapshotAliases(ctx, tx, publisherID)
+		if err != nil {
+			return nil, err
+		}
+		current = keepRows(current, func(a SnapshotZmanAlias) bool { return ownZman(a.ZmanKey) })
+		desired := filterSnapshotRows(&plan.diff, SnapshotSectionAliases, snapshot.Aliases, aliasKey, func(a SnapshotZmanAlias) string {
+			if a.AliasHebrew == "" {
+				return "alias_hebrew is required"
+			}
+			return missingZman(a.ZmanKey)
+		})
+		plan.aliases = planSection(SnapshotSectionAliases, current, desired, aliasKey)
+		plan.diff.Sections = append(plan.diff.Sections, plan.aliases.diff)
 	}
-	if existing.EnglishName != snap.EnglishName {
-		return true
+
+	if sections[SnapshotSectionTags] {
+		known, err := loadKeySet(ctx, tx, `SELECT tag_key FROM zman_tags`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tags: %w", err)
+		}
+		current, err := loadSnapshotTags(ctx, tx, publisherID)
+		if err != nil {
+			return nil, err
+		}
+		current = keepRows(current, func(t SnapshotZmanTag) bool { return ownZman(t.ZmanKey) })
+		desired := filterSnapshotRows(&plan.diff, SnapshotSectionTags, snapshot.Tags, tagKey, func(t SnapshotZmanTag) string {
+			if !known[t.TagKey] {
+				return fmt.Sprintf("unknown tag %q", t.TagKey)
+			}
+			return missingZman(t.ZmanKey)
+		})
+		plan.tags = planSection(SnapshotSectionTags, current, desired, tagKey)
+		plan.diff.Sections = append(plan.diff.Sections, plan.tags.diff)
 	}
-	if !ptrStringEqual(existing.Transliteration, snap.Transliteration) {
-		return true
+
+	if sections[SnapshotSectionDayTypes] {
+		known, err := loadKeySet(ctx, tx, `SELECT name FROM day_types`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get day types: %w", err)
+		}
+		current, err := loadSnapshotDayTypes(ctx, tx, publisherID)
+		if err != nil {
+			return nil, err
+		}
+		current = keepRows(current, func(d SnapshotZmanDayType) bool { return ownZman(d.ZmanKey) })
+		desired := filterSnapshotRows(&plan.diff, SnapshotSectionDayTypes, snapshot.DayTypes, dayTypeKey, func(d SnapshotZmanDayType) string {
+			if !known[d.DayType] {
+				return fmt.Sprintf("unknown day type %q", d.DayType)
+			}
+			return missingZman(d.ZmanKey)
+		})
+		plan.dayTypes = planSection(SnapshotSectionDayTypes, current, desired, dayTypeKey)
+		plan.diff.Sections = append(plan.diff.Sections, plan.dayTypes.diff)
 	}
-	if !ptrStringEqual(existing.Description, snap.Description) {
-		return true
+
+	if sections[SnapshotSectionEvents] {
+		known, err := loadKeySet(ctx, tx, `SELECT code FROM jewish_events`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get jewish events: %w", err)
+		}
+		current, err := loadSnapshotEvents(ctx, tx, publisherID)
+		if err != nil {
+			return nil, err
+		}
+		current = keepRows(current, func(e SnapshotZmanEvent) bool { return ownZman(e.ZmanKey) })
+		desired := filterSnapshotRows(&plan.diff, SnapshotSectionEvents, snapshot.Events, eventKey, func(e SnapshotZmanEvent) string {
+			if !known[e.EventCode] {
+				return fmt.Sprintf("unknown event %q", e.EventCode)
+			}
+			return missingZman(e.ZmanKey)
+		})
+		plan.events = planSection(SnapshotSectionEvents, current, desired, eventKey)
+		plan.diff.Sections = append(plan.diff.Sections, plan.events.diff)
 	}
-	if existing.FormulaDsl != snap.FormulaDSL {
-		return true
+
+	if sections[SnapshotSectionCoverage] {
+		current, err := loadSnapshotCoverage(ctx, tx, publisherID)
+		if err != nil {
+			return nil, err
+		}
+		desired := make([]SnapshotCoverage, 0, len(snapshot.Coverage))
+		for _, c := range snapshot.Coverage {
+			c.normalize()
+			problem, err := resolveCoverage(ctx, tx, &c)
+			if err != nil {
+				return nil, err
+			}
+			if problem != "" {
+				plan.diff.warnf("%s %s skipped: %s", SnapshotSectionCoverage, coverageKey(c), problem)
+				continue
+			}
+			desired = append(desired, c)
+		}
+		plan.coverage = planSection(SnapshotSectionCoverage, current, desired, coverageKey)
+		plan.diff.Sections = append(plan.diff.Sections, plan.coverage.diff)
 	}
-	if !ptrStringEqual(existing.AiExplanation, snap.AIExplanation) {
-		return true
+
+	return plan, nil
+}
+
+// keepRows returns the rows for which keep is true
+func keepRows[T any](rows []T, keep func(T) bool) []T {
+	kept := rows[:0:0]
+	for _, row := range rows {
+		if keep(row) {
+			kept = append(kept, row)
+		}
 	}
-	if !ptrStringEqual(existing.PublisherComment, snap.PublisherComment) {
-		return true
+	return kept
+}
+
+// executeSnapshotPlan writes a computed plan. Zmanim are written before the rows
+// that reference them; removals run before upserts so renamed aliases do not collide.
+func (s *SnapshotService) executeSnapshotPlan(ctx context.Context, tx pgx.Tx, q *sqlcgen.Queries, publisherID, userID string, plan *snapshotPlan) error {
+	for _, p := range plan.profile.upserts {
+		_, err := tx.Exec(ctx, `
+			UPDATE publishers
+			SET name = $2, email = $3, phone = $4, website = $5, description = $6,
+			    bio = $7, logo_url = $8, logo_data = $9, updated_at = NOW()
+			WHERE id = $1
+		`, publisherID, p.Name, p.Email, p.Phone, p.Website, p.Description, p.Bio, p.LogoURL, p.LogoData)
+		if err != nil {
+			return fmt.Errorf("failed to update profile: %w", err)
+		}
 	}
-	if existing.IsEnabled != snap.IsEnabled {
-		return true
+
+	for _, z := range plan.zmanim.removes {
+		err := q.SoftDeleteZmanForRestore(ctx, sqlcgen.SoftDeleteZmanForRestoreParams{
+			PublisherID: publisherID,
+			ZmanKey:     z.ZmanKey,
+			DeletedBy:   &userID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to soft-delete zman %s: %w", z.ZmanKey, err)
+		}
 	}
-	if existing.IsVisible != snap.IsVisible {
-		return true
+	existing := make(map[string]bool)
+	for _, z := range plan.zmanim.diff.Changes {
+		existing[z.Key] = z.Action == SnapshotActionUpdate
 	}
-	if existing.IsPublished != snap.IsPublished {
-		return true
+	for _, z := range plan.zmanim.upserts {
+		switch {
+		case existing[z.ZmanKey]:
+			if err := updateZmanFromSnapshot(ctx, q, publisherID, z); err != nil {
+				return fmt.Errorf("failed to update zman %s: %w", z.ZmanKey, err)
+			}
+		case plan.deleted[z.ZmanKey]:
+			err := q.RestoreDeletedZmanForSnapshot(ctx, sqlcgen.RestoreDeletedZmanForSnapshotParams{
+				PublisherID: publisherID,
+				ZmanKey:     z.ZmanKey,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to restore deleted zman %s: %w", z.ZmanKey, err)
+			}
+			if err := updateZmanFromSnapshot(ctx, q, publisherID, z); err != nil {
+				return fmt.Errorf("failed to update restored zman %s: %w", z.ZmanKey, err)
+			}
+		default:
+			if err := insertZmanFromSnapshot(ctx, q, publisherID, z); err != nil {
+				return fmt.Errorf("failed to insert zman %s: %w", z.ZmanKey, err)
+			}
+		}
 	}
-	if existing.IsBeta != snap.IsBeta {
-		return true
+
+	for _, a := range plan.aliases.removes {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM publisher_zman_aliases WHERE publisher_id = $1 AND alias_hebrew = $2
+		`, publisherID, a.AliasHebrew); err != nil {
+			return fmt.Errorf("failed to remove alias %s: %w", a.AliasHebrew, err)
+		}
 	}
-	if existing.IsCustom != snap.IsCustom {
-		return true
+	for _, a := range plan.aliases.upserts {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO publisher_zman_aliases (
+				publisher_zman_id, publisher_id, alias_hebrew, alias_english,
+				alias_transliteration, context, is_primary, sort_order
+			)
+			SELECT pz.id, pz.publisher_id, $3, $4, $5, $6, $7, $8
+			FROM publisher_zmanim pz
+			WHERE pz.publisher_id = $1 AND pz.zman_key = $2 AND pz.deleted_at IS NULL
+			ON CONFLICT (publisher_id, alias_hebrew) DO UPDATE SET
+				publisher_zman_id = EXCLUDED.publisher_zman_id,
+				alias_english = EXCLUDED.alias_english,
+				alias_transliteration = EXCLUDED.alias_transliteration,
+				context = EXCLUDED.context,
+				is_primary = EXCLUDED.is_primary,
+				sort_order = EXCLUDED.sort_order
+		`, publisherID, a.ZmanKey, a.AliasHebrew, a.AliasEnglish, a.AliasTransliteration,
+			a.Context, a.IsPrimary, a.SortOrder); err != nil {
+			return fmt.Errorf("failed to write alias %s: %w", a.AliasHebrew, err)
+		}
 	}
-	if existing.Category != snap.Category {
-		return true
+
+	for _, t := range plan.tags.removes {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM publisher_zman_tags pzt
+			USING publisher_zmanim pz, zman_tags t
+			WHERE pz.id = pzt.publisher_zman_id AND t.id = pzt.tag_id
+			  AND pz.publisher_id = $1 AND pz.zman_key = $2 AND t.tag_key = $3
+		`, publisherID, t.ZmanKey, t.TagKey); err != nil {
+			return fmt.Errorf("failed to remove tag %s: %w", tagKey(t), err)
+		}
 	}
-	if !uuidPtrEqual(existing.MasterZmanID, snap.MasterZmanID) {
-		return true
+	for _, t := range plan.tags.upserts {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO publisher_zman_tags (publisher_zman_id, tag_id, is_negated)
+			SELECT pz.id, t.id, $4
+			FROM publisher_zmanim pz
+			JOIN zman_tags t ON t.tag_key = $3
+			WHERE pz.publisher_id = $1 AND pz.zman_key = $2 AND pz.deleted_at IS NULL
+			ON CONFLICT (publisher_zman_id, tag_id) DO UPDATE SET is_negated = EXCLUDED.is_negated
+		`, publisherID, t.ZmanKey, t.TagKey, t.IsNegated); err != nil {
+			return fmt.Errorf("failed to write tag %s: %w", tagKey(t), err)
+		}
 	}
-	if !uuidPtrEqual(existing.LinkedPublisherZmanID, snap.LinkedPublisherZmanID) {
-		return true
+
+	for _, d := range plan.dayTypes.removes {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM publisher_zman_day_types pzdt
+			USING publisher_zmanim pz, day_types dt
+			WHERE pz.id = pzdt.publisher_zman_id AND dt.id = pzdt.day_type_id
+			  AND pz.publisher_id = $1 AND pz.zman_key = $2 AND dt.name = $3
+		`, publisherID, d.ZmanKey, d.DayType); err != nil {
+			return fmt.Errorf("failed to remove day type %s: %w", dayTypeKey(d), err)
+		}
 	}
-	if existing.SourceType != snap.SourceType {
-		return true
+	for _, d := range plan.dayTypes.upserts {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO publisher_zman_day_types (
+				publisher_zman_id, day_type_id, override_formula_dsl, override_hebrew_name, override_english_name
+			)
+			SELECT pz.id, dt.id, $4, $5, $6
+			FROM publisher_zmanim pz
+			JOIN day_types dt ON dt.name = $3
+			WHERE pz.publisher_id = $1 AND pz.zman_key = $2 AND pz.deleted_at IS NULL
+			ON CONFLICT (publisher_zman_id, day_type_id) DO UPDATE SET
+				override_formula_dsl = EXCLUDED.override_formula_dsl,
+				override_hebrew_name = EXCLUDED.override_hebrew_name,
+				override_english_name = EXCLUDED.override_english_name,
+				updated_at = NOW()
+		`, publisherID, d.ZmanKey, d.DayType, d.OverrideFormulaDSL, d.OverrideHebrewName, d.OverrideEnglishName); err != nil {
+			return fmt.Errorf("failed to write day type %s: %w", dayTypeKey(d), err)
+		}
 	}
-	return false
-}
 
-// Helper to compare optional string pointers
-func ptrStringEqual(a *string, b *string) bool {
-	if a == nil && b == nil {
-		return true
+	for _, e := range plan.events.removes {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM publisher_zman_events pze
+			USING publisher_zmanim pz, jewish_events je
+			WHERE pz.id = pze.publisher_zman_id AND je.id = pze.jewish_event_id
+			  AND pz.publisher_id = $1 AND pz.zman_key = $2 AND je.code = $3
+		`, publisherID, e.ZmanKey, e.EventCode); err != nil {
+			return fmt.Errorf("failed to remove event %s: %w", eventKey(e), err)
+		}
 	}
-	if a == nil || b == nil {
-		return false
+	for _, e := range plan.events.upserts {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO publisher_zman_events (
+				publisher_zman_id, jewish_event_id, override_formula_dsl, override_hebrew_name,
+				override_english_name, is_enabled
+			)
+			SELECT pz.id, je.id, $4, $5, $6, $7
+			FROM publisher_zmanim pz
+			JOIN jewish_events je ON je.code = $3
+			WHERE pz.publisher_id = $1 AND pz.zman_key = $2 AND pz.deleted_at IS NULL
+			ON CONFLICT (publisher_zman_id, jewish_event_id) DO UPDATE SET
+				override_formula_dsl = EXCLUDED.override_formula_dsl,
+				override_hebrew_name = EXCLUDED.override_hebrew_name,
+				override_english_name = EXCLUDED.override_english_name,
+				is_enabled = EXCLUDED.is_enabled,
+				updated_at = NOW()
+		`, publisherID, e.ZmanKey, e.EventCode, e.OverrideFormulaDSL, e.OverrideHebrewName,
+			e.OverrideEnglishName, e.IsEnabled); err != nil {
+			return fmt.Errorf("failed to write event %s: %w", eventKey(e), err)
+		}
 	}
-	return *a == *b
-}
 
-// Helper to compare pgtype.UUID with string pointer
-func uuidPtrEqual(a pgtype.UUID, b *string) bool {
-	if !a.Valid && b == nil {
-		return true
+	// Coverage rows are matched on the column that identifies their level
+	const coverageMatch = `publisher_id = $1 AND coverage_level = $2 AND CASE $2
+		WHEN 'continent' THEN continent_code = $3
+		WHEN 'country' THEN country_id = $4
+		WHEN 'region' THEN region_id = $5
+		WHEN 'district' THEN district_id = $6
+		ELSE city_id = $7::uuid
+	END`
+	for _, c := range plan.coverage.removes {
+		if _, err := tx.Exec(ctx, `DELETE FROM publisher_coverage WHERE `+coverageMatch,
+			publisherID, c.CoverageLevel, c.ContinentCode, c.countryID, c.regionID, c.districtID, c.CityID); err != nil {
+			return fmt.Errorf("failed to remove coverage %s: %w", coverageKey(c), err)
+		}
 	}
-	if !a.Valid || b == nil {
-		return false
+	for _, c := range plan.coverage.upserts {
+		tag, err := tx.Exec(ctx, `
+			UPDATE publisher_coverage SET priority = $8, is_active = $9, updated_at = NOW()
+			WHERE `+coverageMatch,
+			publisherID, c.CoverageLevel, c.ContinentCode, c.countryID, c.regionID, c.districtID, c.CityID,
+			c.Priority, c.IsActive)
+		if err != nil {
+			return fmt.Errorf("failed to update coverage %s: %w", coverageKey(c), err)
+		}
+		if tag.RowsAffected() > 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO publisher_coverage (
+				publisher_id, coverage_level, continent_code, country_id, region_id, district_id, city_id,
+				priority, is_active
+			) VALUES ($1, $2, $3, $4, $5, $6, $7::uuid, $8, $9)
+		`, publisherID, c.CoverageLevel, c.ContinentCode, c.countryID, c.regionID, c.districtID, c.CityID,
+			c.Priority, c.IsActive); err != nil {
+			return fmt.Errorf("failed to insert coverage %s: %w", coverageKey(c), err)
+		}
 	}
-	return uuidBytesToString(a.Bytes) == *b
+
+	return nil
 }
 
 // updateZmanFromSnapshot updates an existing zman with snapshot data
-func (s *SnapshotService) updateZmanFromSnapshot(ctx context.Context, publisherID string, z SnapshotZman) error {
-	return s.db.Queries.UpdateZmanFromSnapshot(ctx, sqlcgen.UpdateZmanFromSnapshotParams{
+func updateZmanFromSnapshot(ctx context.Context, q *sqlcgen.Queries, publisherID string, z SnapshotZman) error {
+	return q.UpdateZmanFromSnapshot(ctx, sqlcgen.UpdateZmanFromSnapshotParams{
 		PublisherID:           publisherID,
 		ZmanKey:               z.ZmanKey,
 		HebrewName:            z.HebrewName,
@@ -386,8 +747,8 @@ func (s *SnapshotService) updateZmanFromSnapshot(ctx context.Context, publisherI
 }
 
 // insertZmanFromSnapshot inserts a new zman from snapshot
-func (s *SnapshotService) insertZmanFromSnapshot(ctx context.Context, publisherID string, z SnapshotZman) error {
-	return s.db.Queries.InsertZmanFromSnapshot(ctx, sqlcgen.InsertZmanFromSnapshotParams{
+func insertZmanFromSnapshot(ctx context.Context, q *sqlcgen.Queries, publisherID string, z SnapshotZman) error {
+	return q.InsertZmanFromSnapshot(ctx, sqlcgen.InsertZmanFromSnapshotParams{
 		PublisherID:           publisherID,
 		ZmanKey:               z.ZmanKey,
 		HebrewName:            z.HebrewName,
@@ -417,45 +778,59 @@ func ptrToString(p *string) string {
 	return *p
 }
 
-// RestoreSnapshot restores from a saved snapshot (auto-saves current state first)
-func (s *SnapshotService) RestoreSnapshot(ctx context.Context, snapshotID, publisherID string, userID string) (*SnapshotMeta, error) {
+// RestoreSnapshot restores from a saved snapshot. Unless it is a dry run, the
+// current state is auto-saved first and the auto-save is returned.
+func (s *SnapshotService) RestoreSnapshot(ctx context.Context, snapshotID, publisherID string, userID string, opts ApplyOptions) (*SnapshotMeta, *SnapshotDiff, error) {
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+
 	// 1. Get the snapshot to restore
 	snapshot, err := s.GetSnapshot(ctx, snapshotID, publisherID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 2. Auto-save current state before restore
-	autoSaveDesc := fmt.Sprintf("Auto-save before restore - %s", time.Now().Format("Jan 2, 2006 3:04 PM"))
-	autoSave, err := s.SaveSnapshot(ctx, publisherID, userID, autoSaveDesc)
-	if err != nil {
-		return nil, fmt.Errorf("failed to auto-save before restore: %w", err)
+	var autoSave *SnapshotMeta
+	if !opts.DryRun {
+		autoSaveDesc := fmt.Sprintf("Auto-save before restore - %s", time.Now().Format("Jan 2, 2006 3:04 PM"))
+		autoSave, err = s.SaveSnapshot(ctx, publisherID, userID, autoSaveDesc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to auto-save before restore: %w", err)
+		}
 	}
 
 	// 3. Apply the snapshot with smart diff logic
-	if err := s.ApplySnapshot(ctx, publisherID, userID, snapshot); err != nil {
-		return nil, fmt.Errorf("failed to apply snapshot: %w", err)
+	diff, err := s.ApplySnapshot(ctx, publisherID, userID, snapshot, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply snapshot: %w", err)
 	}
 
-	return autoSave, nil
+	return autoSave, diff, nil
 }
 
-// ImportSnapshot applies a snapshot from JSON (uploaded by user)
-func (s *SnapshotService) ImportSnapshot(ctx context.Context, publisherID string, userID string, snapshot *PublisherSnapshot) error {
-	// Validate snapshot version
-	if snapshot.Version != 1 {
-		return fmt.Errorf("unsupported snapshot version: %d", snapshot.Version)
+// ImportSnapshot applies a snapshot from JSON (uploaded by user). Version 1
+// files are migrated; unless it is a dry run, the current state is auto-saved first.
+func (s *SnapshotService) ImportSnapshot(ctx context.Context, publisherID string, userID string, snapshot *PublisherSnapshot, opts ApplyOptions) (*SnapshotDiff, error) {
+	// Validate and migrate snapshot version
+	if err := MigrateSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
 	}
 
 	// Auto-save current state before import
-	autoSaveDesc := fmt.Sprintf("Auto-save before import - %s", time.Now().Format("Jan 2, 2006 3:04 PM"))
-	_, err := s.SaveSnapshot(ctx, publisherID, userID, autoSaveDesc)
-	if err != nil {
-		return fmt.Errorf("failed to auto-save before import: %w", err)
+	if !opts.DryRun {
+		autoSaveDesc := fmt.Sprintf("Auto-save before import - %s", time.Now().Format("Jan 2, 2006 3:04 PM"))
+		if _, err := s.SaveSnapshot(ctx, publisherID, userID, autoSaveDesc); err != nil {
+			return nil, fmt.Errorf("failed to auto-save before import: %w", err)
+		}
 	}
 
 	// Apply the imported snapshot with smart diff logic
-	return s.ApplySnapshot(ctx, publisherID, userID, snapshot)
+	return s.ApplySnapshot(ctx, publisherID, userID, snapshot, opts)
 }
 
 // Helper to convert pgtype.UUID bytes to string
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateSnapshot(t *testing.T) {
+	v1 := []byte(`{"version":1,"exported_at":"2025-01-01T00:00:00Z","description":"old","zmanim":[{"zman_key":"alos","formula_dsl":"solar(16.1, before_sunrise)"}]}`)
+
+	var snapshot PublisherSnapshot
+	if err := json.Unmarshal(v1, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateSnapshot(&snapshot); err != nil {
+		t.Fatalf("MigrateSnapshot(v1) = %v", err)
+	}
+	if snapshot.Version != SnapshotFormatVersion {
+		t.Errorf("version = %d, want %d", snapshot.Version, SnapshotFormatVersion)
+	}
+	if !reflect.DeepEqual(snapshot.Sections, []string{SnapshotSectionZmanim}) {
+		t.Errorf("sections = %v, want [zmanim]", snapshot.Sections)
+	}
+	if snapshot.Zmanim[0].SourceType != "registry" {
+		t.Errorf("source_type = %q, want registry", snapshot.Zmanim[0].SourceType)
+	}
+
+	tests := []struct {
+		name     string
+		snapshot PublisherSnapshot
+		wantErr  error
+	}{
+		{"missing version", PublisherSnapshot{}, ErrInvalidSnapshot},
+		{"future version", PublisherSnapshot{Version: 9}, ErrUnsupportedSnapshotVersion},
+		{"v2 without sections", PublisherSnapshot{Version: 2}, ErrInvalidSnapshot},
+		{"unknown section", PublisherSnapshot{Version: 2, Sections: []string{"zmanim", "themes"}}, ErrUnknownSnapshotSection},
+		{"profile section without profile", PublisherSnapshot{Version: 2, Sections: []string{"profile"}}, ErrInvalidSnapshot},
+		{"v2", PublisherSnapshot{Version: 2, Sections: SnapshotSections, Profile: &SnapshotProfile{Name: "n", Email: "e"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := MigrateSnapshot(&tt.snapshot); !errors.Is(err, tt.wantErr) {
+				t.Errorf("MigrateSnapshot() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectSnapshotSections(t *testing.T) {
+	snapshot := &PublisherSnapshot{Version: 2, Sections: []string{SnapshotSectionZmanim, SnapshotSectionTags}}
+
+	var diff SnapshotDiff
+	got, err := selectSnapshotSections(snapshot, nil, &diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got[SnapshotSectionZmanim] || !got[SnapshotSectionTags] || len(got) != 2 {
+		t.Errorf("default sections = %v", got)
+	}
+
+	got, err = selectSnapshotSections(snapshot, []string{SnapshotSectionTags, SnapshotSectionCoverage}, &diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got[SnapshotSectionTags] || len(got) != 1 || len(diff.Warnings) != 1 {
+		t.Errorf("selected = %v, warnings = %v", got, diff.Warnings)
+	}
+
+	if _, err := selectSnapshotSections(snapshot, []string{"themes"}, &diff); !errors.Is(err, ErrUnknownSnapshotSection) {
+		t.Errorf("unknown section error = %v", err)
+	}
+}
+
+func TestRestoreValidatesSectionsBeforeSaving(t *testing.T) {
+	// No database: an unknown section must be refused before the snapshot is
+	// loaded or the current state auto-saved
+	s := &SnapshotService{}
+	opts := ApplyOptions{Sections: []string{SnapshotSectionZmanim, "themes"}}
+
+	if _, _, err := s.RestoreSnapshot(context.Background(), "snapshot", "publisher", "user", opts); !errors.Is(err, ErrUnknownSnapshotSection) {
+		t.Errorf("RestoreSnapshot() error = %v, want ErrUnknownSnapshotSection", err)
+	}
+	snapshot := &PublisherSnapshot{Version: 2, Sections: []string{SnapshotSectionZmanim}}
+	if _, err := s.ImportSnapshot(context.Background(), "publisher", "user", snapshot, opts); !errors.Is(err, ErrUnknownSnapshotSection) {
+		t.Errorf("ImportSnapshot() error = %v, want ErrUnknownSnapshotSection", err)
+	}
+}
+
+func TestPlanSection(t *testing.T) {
+	english := "Dawn"
+	current := []SnapshotZmanAlias{
+		{ZmanKey: "alos", AliasHebrew: "עלות"},
+		{ZmanKey: "alos", AliasHebrew: "עלות השחר", SortOrder: 1},
+		{ZmanKey: "shkia", AliasHebrew: "שקיעה"},
+	}
+	desired := []SnapshotZmanAlias{
+		{ZmanKey: "alos", AliasHebrew: "עלות", AliasEnglish: &english},
+		{ZmanKey: "alos", AliasHebrew: "עלות השחר", SortOrder: 1},
+		{ZmanKey: "tzeis", AliasHebrew: "צאת"},
+		{ZmanKey: "tzeis", AliasHebrew: "צאת"}, // duplicate ignored
+	}
+
+	plan := planSection(SnapshotSectionAliases, current, desired, aliasKey)
+	want := []SnapshotChange{
+		{Action: SnapshotActionUpdate, Key: "עלות", Fields: []string{"alias_english"}},
+		{Action: SnapshotActionAdd, Key: "צאת"},
+		{Action: SnapshotActionRemove, Key: "שקיעה"},
+	}
+	if !reflect.DeepEqual(plan.diff.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", plan.diff.Changes, want)
+	}
+	if len(plan.upserts) != 2 || len(plan.removes) != 1 {
+		t.Errorf("upserts = %d, removes = %d", len(plan.upserts), len(plan.removes))
+	}
+}
+
+func TestPlanCoverageIgnoresResolvedIDsAndNames(t *testing.T) {
+	cityID := "6f1c2a52-5f7e-4f5c-9a53-3b1f0b8f1d10"
+	us, ny := "US", "NY"
+	name := "Brooklyn"
+	countryID := int16(7)
+	regionID := int32(42)
+
+	current := []SnapshotCoverage{
+		{CoverageLevel: "city", CityID: &cityID, CityName: &name, Priority: 5, IsActive: true},
+		{CoverageLevel: "region", CountryCode: &us, RegionCode: &ny, Priority: 1, IsActive: true, regionID: &regionID},
+	}
+	desired := []SnapshotCoverage{
+		{CoverageLevel: "city", CityID: &cityID, Priority: 5, IsActive: true},
+		{CoverageLevel: "region", CountryCode: &us, RegionCode: &ny, Priority: 2, IsActive: true, countryID: &countryID},
+	}
+	for i := range desired {
+		desired[i].normalize()
+	}
+
+	plan := planSection(SnapshotSectionCoverage, current, desired, coverageKey)
+	want := []SnapshotChange{{Action: SnapshotActionUpdate, Key: "region:US/NY", Fields: []string{"priority"}}}
+	if !reflect.DeepEqual(plan.diff.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", plan.diff.Changes, want)
+	}
+}
@@ -0,0 +1,556 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jcom-dev/zmanim-lab/internal/db"
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// Webhook delivery statuses
+const (
+	WebhookStatusPending    = "pending"
+	WebhookStatusDelivering = "delivering"
+	WebhookStatusDelivered  = "delivered"
+	WebhookStatusFailed     = "failed"
+)
+
+const (
+	// webhookLease is how long a claimed delivery is reserved for one worker.
+	// Rows left in 'delivering' by a crashed worker are retried after it expires.
+	webhookLease = 2 * time.Minute
+	// webhookBatchSize is the number of due deliveries claimed per poll
+	webhookBatchSize = 20
+)
+
+// WebhookService manages webhook subscriptions and delivers events from the
+// webhook_deliveries queue. Emit only enqueues; a background worker (Start)
+// sends due deliveries and reschedules failures with exponential backoff, so
+// events survive restarts and several API replicas can share the queue.
+type WebhookService struct {
+	db                   *db.DB
+	sender               *webhooks.Sender
+	allowPrivateNetworks bool
+	pollInterval         time.Duration
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// WebhookSubscription describes a subscription (never includes the secret)
+type WebhookSubscription struct {
+	ID          string    `json:"id"`
+	PublisherID string    `json:"publisher_id"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"`
+	Description *string   `json:"description,omitempty"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreatedWebhookSubscription is returned once on creation and includes the signing secret
+type CreatedWebhookSubscription struct {
+	WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// CreateWebhookParams contains the fields for creating a subscription
+type CreateWebhookParams struct {
+	PublisherID string
+	URL         string
+	EventTypes  []string
+	Description *string
+	CreatedBy   string
+}
+
+// UpdateWebhookParams contains the fields that can be changed; nil leaves a field unchanged
+type UpdateWebhookParams struct {
+	URL         *string
+	EventTypes  []string
+	Description *string
+	IsActive    *bool
+}
+
+// WebhookDelivery is an entry in the delivery log
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	LastResponseMs *int            `json:"last_response_ms,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// NewWebhookService creates a new webhook service. Call Start to run the delivery worker.
+// allowPrivateNetworks lets subscriptions target loopback and private addresses; it
+// exists for local testing and must stay off in production.
+func NewWebhookService(database *db.DB, pollInterval time.Duration, allowPrivateNetworks bool) *WebhookService {
+	return &WebhookService{
+		db:                   database,
+		sender:               webhooks.NewSender(10*time.Second, allowPrivateNetworks),
+		allowPrivateNetworks: allowPrivateNetworks,
+		pollInterval:         pollInterval,
+		wake:                 make(chan struct{}, 1),
+		stop:                 make(chan struct{}),
+		done:                 make(chan struct{}),
+	}
+}
+
+// ValidateURL checks a subscription URL against the service's network policy
+func (s *WebhookService) ValidateURL(raw string) error {
+	return ValidateWebhookURL(raw, s.allowPrivateNetworks)
+}
+
+// ValidateWebhookURL requires an absolute https URL whose host is not a
+// literal loopback, private, link-local or unspecified address, nor localhost.
+// With allowPrivateNetworks (local testing only) those hosts are accepted, and
+// http is allowed for loopback. Hostnames are checked again when delivered,
+// after DNS resolution.
+func ValidateWebhookURL(raw string, allowPrivateNetworks bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("url must be an absolute URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	ip := net.ParseIP(host)
+	loopback := host == "localhost" || strings.HasSuffix(host, ".localhost") || (ip != nil && ip.IsLoopback())
+
+	if !allowPrivateNetworks && (loopback || (ip != nil && !webhooks.IsPublicIP(ip))) {
+		return fmt.Errorf("url must not point to a private or local network address")
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if loopback {
+			return nil
+		}
+		return fmt.Errorf("url must use https")
+	default:
+		return fmt.Errorf("url must use https")
+	}
+}
+
+// ValidateWebhookEventTypes checks every event type and removes duplicates.
+// An empty list subscribes to all events.
+func ValidateWebhookEventTypes(types []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, t := range types {
+		if !webhooks.IsValidEventType(t) {
+			return nil, fmt.Errorf("unknown event type %q", t)
+		}
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// webhookSubscriptionColumns is the column list scanned by scanWebhookSubscription
+const webhookSubscriptionColumns = `id, publisher_id, url, event_types, description, is_active, created_at, updated_at`
+
+// scanWebhookSubscription scans a row selected with webhookSubscriptionColumns
+func scanWebhookSubscription(row pgx.Row) (*WebhookSubscription, error) {
+	var s WebhookSubscription
+	err := row.Scan(&s.ID, &s.PublisherID, &s.URL, &s.EventTypes, &s.Description, &s.IsActive, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSubscription registers a webhook endpoint and generates its signing secret
+func (s *WebhookService) CreateSubscription(ctx context.Context, params CreateWebhookParams) (*CreatedWebhookSubscription, error) {
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := scanWebhookSubscription(s.db.Pool.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (publisher_id, url, secret, event_types, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+webhookSubscriptionColumns,
+		params.PublisherID, params.URL, secret, params.EventTypes, params.Description, params.CreatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert webhook subscription: %w", err)
+	}
+
+	return &CreatedWebhookSubscription{WebhookSubscription: *sub, Secret: secret}, nil
+}
+
+// ListSubscriptions returns a publisher's subscriptions
+func (s *WebhookService) ListSubscriptions(ctx context.Context, publisherID string) ([]WebhookSubscription, error) {
+	rows, err := s.db.Pool.Query(ctx, `SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE publisher_id = $1
+		ORDER BY created_at DESC`, publisherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// UpdateSubscription changes a subscription owned by publisherID
+func (s *WebhookService) UpdateSubscription(ctx context.Context, id, publisherID string, params UpdateWebhookParams) (*WebhookSubscription, error) {
+	sub, err := scanWebhookSubscription(s.db.Pool.QueryRow(ctx, `
+		UPDATE webhook_subscriptions SET
+			url = COALESCE($3, url),
+			event_types = COALESCE($4, event_types),
+			description = COALESCE($5, description),
+			is_active = COALESCE($6, is_active),
+			updated_at = now()
+		WHERE id = $1 AND publisher_id = $2
+		RETURNING `+webhookSubscriptionColumns,
+		id, publisherID, params.URL, params.EventTypes, params.Description, params.IsActive))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// DeleteSubscription removes a subscription and its delivery log
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id, publisherID string) error {
+	tag, err := s.db.Pool.Exec(ctx, `
+		DELETE FROM webhook_subscriptions WHERE id = $1 AND publisher_id = $2
+	`, id, publisherID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Emit enqueues an event for every active subscription of the publisher that
+// selected eventType. Delivery happens asynchronously.
+func (s *WebhookService) Emit(ctx context.Context, publisherID, eventType string, data interface{}) error {
+	event := webhooks.Event{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		PublisherID: publisherID,
+		CreatedAt:   time.Now().UTC(),
+		Data:        data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	tag, err := s.db.Pool.Exec(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $2, $3, $4
+		FROM webhook_subscriptions
+		WHERE publisher_id = $1
+		  AND is_active
+		  AND (cardinality(event_types) = 0 OR $3 = ANY(event_types))
+	`, publisherID, event.ID, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		s.notify()
+	}
+	return nil
+}
+
+// SendTest enqueues a ping event for one subscription and returns the delivery ID
+func (s *WebhookService) SendTest(ctx context.Context, subscriptionID, publisherID string) (string, error) {
+	event := webhooks.Event{
+		ID:          uuid.New().String(),
+		Type:        webhooks.EventPing,
+		PublisherID: publisherID,
+		CreatedAt:   time.Now().UTC(),
+		Data:        map[string]string{"message": "Test event from Zmanim Lab"},
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	var deliveryID string
+	err = s.db.Pool.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $3, $4, $5
+		FROM webhook_subscriptions
+		WHERE id = $1 AND publisher_id = $2
+		RETURNING id
+	`, subscriptionID, publisherID, event.ID, webhooks.EventPing, payload).Scan(&deliveryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrWebhookNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue test delivery: %w", err)
+	}
+
+	s.notify()
+	return deliveryID, nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first.
+// An empty status returns every status.
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID, publisherID, status string, limit, offset int) ([]WebhookDelivery, error) {
+	var exists bool
+	err := s.db.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1 AND publisher_id = $2)
+	`, subscriptionID, publisherID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check webhook subscription: %w", err)
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	rows, err := s.db.Pool.Query(ctx, `
+		SELECT id, subscription_id, event_id, event_type, payload, status, attempts,
+			CASE WHEN status IN ('pending', 'delivering') THEN next_attempt_at END,
+			last_status_code, last_error, last_response_ms, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`, subscriptionID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.LastResponseMs, &d.DeliveredAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redeliver requeues a delivery (typically a failed one) for immediate sending with a fresh attempt budget
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID, subscriptionID, publisherID string) error {
+	tag, err := s.db.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		FROM webhook_subscriptions s
+		WHERE d.id = $1 AND d.subscription_id = $2
+		  AND s.id = d.subscription_id AND s.publisher_id = $3
+		  AND d.status <> 'delivering'
+	`, deliveryID, subscriptionID, publisherID)
+	if err != nil {
+		return fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	s.notify()
+	return nil
+}
+
+// Start runs the delivery worker until Stop is called
+func (s *WebhookService) Start() {
+	go s.run()
+}
+
+// Stop stops the delivery worker and waits for the current batch to finish
+func (s *WebhookService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+// notify wakes the worker without waiting for the next poll
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run is the delivery worker loop
+func (s *WebhookService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain full batches before waiting again
+		for {
+			n, err := s.ProcessDue(context.Background())
+			if err != nil {
+				slog.Error("webhook delivery batch failed", "error", err)
+			}
+			if n < webhookBatchSize {
+				break
+			}
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// claimedDelivery is a due delivery reserved by this worker
+type claimedDelivery struct {
+	id        string
+	eventType string
+	payload   []byte
+	attempts  int
+	url       string
+	secret    string
+}
+
+// ProcessDue claims and sends due deliveries once, returning how many were attempted
+func (s *WebhookService) ProcessDue(ctx context.Context) (int, error) {
+	rows, err := s.db.Pool.Query(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'delivering', attempts = d.attempts + 1, next_attempt_at = now() + make_interval(secs => $2)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id
+		  AND d.id IN (
+			SELECT q.id
+			FROM webhook_deliveries q
+			JOIN webhook_subscriptions qs ON qs.id = q.subscription_id AND qs.is_active
+			WHERE q.status IN ('pending', 'delivering') AND q.next_attempt_at <= now()
+			ORDER BY q.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF q SKIP LOCKED
+		  )
+		RETURNING d.id, d.event_type, d.payload, d.attempts, s.url, s.secret
+	`, webhookBatchSize, webhookLease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	var claimed []claimedDelivery
+	for rows.Next() {
+		var c claimedDelivery
+		if err := rows.Scan(&c.id, &c.eventType, &c.payload, &c.attempts, &c.url, &c.secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan claimed delivery: %w", err)
+		}
+		claimed = append(claimed, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range claimed {
+		wg.Add(1)
+		go func(c claimedDelivery) {
+			defer wg.Done()
+			s.deliver(ctx, c)
+		}(c)
+	}
+	wg.Wait()
+
+	return len(claimed), nil
+}
+
+// deliver sends one claimed delivery and records the outcome
+func (s *WebhookService) deliver(ctx context.Context, c claimedDelivery) {
+	res, err := s.sender.Deliver(ctx, c.url, c.secret, c.id, c.eventType, c.payload)
+
+	var statusCode *int
+	if res.StatusCode != 0 {
+		statusCode = &res.StatusCode
+	}
+	responseMs := int(res.Duration.Milliseconds())
+
+	if err == nil && res.Success() {
+		_, dbErr := s.db.Pool.Exec(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'delivered', delivered_at = now(), last_status_code = $2,
+				last_error = NULL, last_response_ms = $3
+			WHERE id = $1
+		`, c.id, statusCode, responseMs)
+		if dbErr != nil {
+			slog.Error("failed to record webhook delivery", "error", dbErr, "delivery_id", c.id)
+		}
+		return
+	}
+
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+	} else {
+		lastError = fmt.Sprintf("receiver responded %d: %s", res.StatusCode, res.ResponseBody)
+	}
+
+	status := WebhookStatusPending
+	if c.attempts >= webhooks.MaxAttempts {
+		status = WebhookStatusFailed
+	}
+	nextAttempt := time.Now().Add(webhooks.Backoff(c.attempts))
+
+	_, dbErr := s.db.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5, last_response_ms = $6
+		WHERE id = $1
+	`, c.id, status, nextAttempt, statusCode, lastError, responseMs)
+	if dbErr != nil {
+		slog.Error("failed to record webhook failure", "error", dbErr, "delivery_id", c.id)
+	}
+
+	slog.Warn("webhook delivery failed",
+		"delivery_id", c.id,
+		"event_type", c.eventType,
+		"attempt", c.attempts,
+		"status", status,
+		"error", lastError)
+}
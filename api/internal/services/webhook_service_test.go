@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{"https://hooks.example.com/zmanim", false, false},
+		{"https://93.184.216.34/hook", false, false},
+		{"http://hooks.example.com/zmanim", false, true},
+		{"ftp://hooks.example.com", false, true},
+		{"/relative", false, true},
+		{"https://localhost/hook", false, true},
+		{"https://api.localhost/hook", false, true},
+		{"https://127.0.0.1/hook", false, true},
+		{"https://[::1]/hook", false, true},
+		{"https://10.0.0.5/hook", false, true},
+		{"https://172.20.1.1/hook", false, true},
+		{"https://192.168.1.10/hook", false, true},
+		{"https://169.254.169.254/latest/meta-data", false, true},
+		{"https://[fd00::1]/hook", false, true},
+		{"https://0.0.0.0/hook", false, true},
+		{"http://127.0.0.1:8080/hook", false, true},
+		// Local testing flag
+		{"http://127.0.0.1:8080/hook", true, false},
+		{"http://localhost:8080/hook", true, false},
+		{"https://10.0.0.5/hook", true, false},
+		{"http://10.0.0.5/hook", true, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateWebhookURL(tt.url, tt.allowPrivate)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateWebhookURL(%q, %v) error = %v, wantErr %v", tt.url, tt.allowPrivate, err, tt.wantErr)
+		}
+	}
+}
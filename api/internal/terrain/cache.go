@@ -0,0 +1,59 @@
+package terrain
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache holds recently used horizon profiles in memory, keyed by city. When
+// full, the least recently added profile is evicted.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]cacheEntry
+	order   []string
+	now     func() time.Time
+}
+
+// cacheEntry is a cached profile and when it stops being served
+type cacheEntry struct {
+	profile *Profile
+	expires time.Time // zero for no expiry
+}
+
+// NewCache creates a cache holding up to size profiles
+func NewCache(size int) *Cache {
+	return &Cache{size: size, entries: make(map[string]cacheEntry, size), now: time.Now}
+}
+
+// Get returns the cached profile for key. A cached nil profile records that
+// the key has no profile, so absent profiles are not looked up repeatedly.
+// Expired entries are not returned.
+func (c *Cache) Get(key string) (profile *Profile, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || (!e.expires.IsZero() && !c.now().Before(e.expires)) {
+		return nil, false
+	}
+	return e.profile, true
+}
+
+// Put stores a profile (or nil for none) under key for ttl, or until evicted
+// when ttl is 0
+func (c *Cache) Put(key string, profile *Profile, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		if len(c.order) >= c.size {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	e := cacheEntry{profile: profile}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+	c.entries[key] = e
+}
@@ -0,0 +1,158 @@
+// Package terrain computes horizon profiles from a digital elevation model,
+// so visible sunrise and sunset can account for the mountains around a
+// location (true netz for vasikin) rather than assuming a flat horizon.
+package terrain
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	deg2rad = math.Pi / 180
+	rad2deg = 180 / math.Pi
+
+	// earthRadiusMeters is the mean Earth radius
+	earthRadiusMeters = 6371000.0
+
+	// refractionCoefficient is the standard terrestrial refraction coefficient:
+	// light from distant terrain bends to follow the Earth's curvature by this fraction
+	refractionCoefficient = 0.13
+)
+
+// ElevationSource returns ground elevation in meters above sea level.
+// Implementations return NaN where there is no data.
+type ElevationSource interface {
+	Elevation(latitude, longitude float64) (float64, error)
+}
+
+// Options controls how a horizon profile is sampled
+type Options struct {
+	AzimuthStep float64 // degrees between profile rays (default 0.5)
+	MaxDistance float64 // how far each ray is followed, meters (default 60 km)
+	MinSpacing  float64 // closest sample spacing, meters (default 90, about 3 SRTM cells)
+	EyeHeight   float64 // observer's eye above the ground, meters (default 1.7)
+}
+
+// DefaultOptions suit SRTM1 data: mountains beyond 60 km rarely rise above
+// nearer terrain by enough to matter
+var DefaultOptions = Options{AzimuthStep: 0.5, MaxDistance: 60000, MinSpacing: 90, EyeHeight: 1.7}
+
+func (o Options) withDefaults() Options {
+	if o.AzimuthStep <= 0 {
+		o.AzimuthStep = DefaultOptions.AzimuthStep
+	}
+	if o.MaxDistance <= 0 {
+		o.MaxDistance = DefaultOptions.MaxDistance
+	}
+	if o.MinSpacing <= 0 {
+		o.MinSpacing = DefaultOptions.MinSpacing
+	}
+	if o.EyeHeight <= 0 {
+		o.EyeHeight = DefaultOptions.EyeHeight
+	}
+	return o
+}
+
+// Profile is the apparent altitude of the horizon around a location. It
+// implements astro.Horizon.
+type Profile struct {
+	Latitude          float64   `json:"latitude"`
+	Longitude         float64   `json:"longitude"`
+	GroundElevation   float64   `json:"ground_elevation"`   // DEM elevation at the location, meters
+	ObserverElevation float64   `json:"observer_elevation"` // ground plus eye height, meters
+	AzimuthStep       float64   `json:"azimuth_step"`       // degrees between entries of Altitudes
+	MaxDistance       float64   `json:"max_distance"`       // meters
+	Altitudes         []float64 `json:"altitudes"`          // horizon altitude in degrees, from azimuth 0 (north) eastward
+}
+
+// Altitude implements astro.Horizon, interpolating between sampled azimuths
+func (p *Profile) Altitude(azimuth float64) float64 {
+	n := len(p.Altitudes)
+	if n == 0 {
+		return 0
+	}
+	azimuth = math.Mod(azimuth, 360)
+	if azimuth < 0 {
+		azimuth += 360
+	}
+	pos := azimuth / p.AzimuthStep
+	i := int(pos)
+	frac := pos - float64(i)
+	return p.Altitudes[i%n]*(1-frac) + p.Altitudes[(i+1)%n]*frac
+}
+
+// Compute samples src along rays from the location and records, for each
+// azimuth, the highest apparent altitude of the terrain. Missing data (SRTM
+// has no tiles over open sea) is taken as sea level.
+func Compute(src ElevationSource, latitude, longitude float64, opts Options) (*Profile, error) {
+	opts = opts.withDefaults()
+
+	ground, err := src.Elevation(latitude, longitude)
+	if err != nil {
+		return nil, fmt.Errorf("elevation at %.5f,%.5f: %w", latitude, longitude, err)
+	}
+	if math.IsNaN(ground) {
+		ground = 0
+	}
+	observer := ground + opts.EyeHeight
+
+	distances := sampleDistances(opts.MinSpacing, opts.MaxDistance)
+	n := int(math.Round(360 / opts.AzimuthStep))
+	p := &Profile{
+		Latitude:          latitude,
+		Longitude:         longitude,
+		GroundElevation:   ground,
+		ObserverElevation: observer,
+		AzimuthStep:       360 / float64(n),
+		MaxDistance:       opts.MaxDistance,
+		Altitudes:         make([]float64, n),
+	}
+
+	for i := range p.Altitudes {
+		azimuth := float64(i) * p.AzimuthStep
+		// Without terrain the horizon is the sea-level horizon seen from the observer's height
+		highest := apparentAltitude(0, observer, opts.MaxDistance)
+		for _, d := range distances {
+			lat, lng := destination(latitude, longitude, azimuth, d)
+			h, err := src.Elevation(lat, lng)
+			if err != nil {
+				return nil, fmt.Errorf("elevation at %.5f,%.5f: %w", lat, lng, err)
+			}
+			if math.IsNaN(h) {
+				h = 0
+			}
+			highest = math.Max(highest, apparentAltitude(h, observer, d))
+		}
+		p.Altitudes[i] = highest
+	}
+	return p, nil
+}
+
+// apparentAltitude is the altitude in degrees at which a point of elevation h
+// at distance d is seen from observer elevation, allowing for the Earth's
+// curvature less terrestrial refraction
+func apparentAltitude(h, observer, d float64) float64 {
+	drop := d * d / (2 * earthRadiusMeters) * (1 - refractionCoefficient)
+	return math.Atan2(h-drop-observer, d) * rad2deg
+}
+
+// sampleDistances spaces samples finely near the observer and more widely
+// with distance, where a cell subtends a smaller angle
+func sampleDistances(minSpacing, maxDistance float64) []float64 {
+	var distances []float64
+	for d := minSpacing; d <= maxDistance; d += math.Max(minSpacing, d*0.01) {
+		distances = append(distances, d)
+	}
+	return distances
+}
+
+// destination is the point at distance meters along an initial bearing
+func destination(latitude, longitude, bearing, distance float64) (float64, float64) {
+	lat1, lng1, brg := latitude*deg2rad, longitude*deg2rad, bearing*deg2rad
+	delta := distance / earthRadiusMeters
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(brg))
+	lng2 := lng1 + math.Atan2(math.Sin(brg)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+	return lat2 * rad2deg, math.Remainder(lng2*rad2deg, 360)
+}
@@ -0,0 +1,111 @@
+package terrain
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// ridgeSource is flat ground at 0 m with a 500 m ridge running north-south
+// 5 km east of the origin
+type ridgeSource struct{}
+
+func (ridgeSource) Elevation(lat, lng float64) (float64, error) {
+	// 5 km east of the origin at the equator is about 0.045° of longitude
+	if lng > 0.0445 && lng < 0.0465 {
+		return 500, nil
+	}
+	if lng < -0.5 {
+		return math.NaN(), nil // no data, like open sea
+	}
+	return 0, nil
+}
+
+func TestCompute(t *testing.T) {
+	p, err := Compute(ridgeSource{}, 0, 0, Options{AzimuthStep: 1, MaxDistance: 20000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Altitudes) != 360 {
+		t.Fatalf("got %d altitudes, want 360", len(p.Altitudes))
+	}
+
+	// Due east the ridge top is 500 m up at 5 km
+	want := math.Atan2(500-p.ObserverElevation, 5000) * rad2deg
+	if got := p.Altitude(90); math.Abs(got-want) > 0.1 {
+		t.Errorf("east horizon = %.3f°, want %.3f°", got, want)
+	}
+
+	// Due west the horizon is the sea-level dip seen from eye height
+	west := p.Altitude(270)
+	if west >= 0 || west < -0.1 {
+		t.Errorf("west horizon = %.4f°, want a small dip below 0°", west)
+	}
+}
+
+func TestProfileAltitude(t *testing.T) {
+	p := &Profile{AzimuthStep: 90, Altitudes: []float64{0, 4, 2, 1}}
+
+	tests := []struct {
+		azimuth float64
+		want    float64
+	}{
+		{0, 0},
+		{45, 2},
+		{90, 4},
+		{135, 3},
+		{315, 0.5}, // between 270 (1) and 360 wrapping to 0 (0)
+		{450, 4},   // wraps to 90
+		{-90, 1},   // wraps to 270
+	}
+	for _, tt := range tests {
+		if got := p.Altitude(tt.azimuth); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Altitude(%v) = %v, want %v", tt.azimuth, got, tt.want)
+		}
+	}
+
+	if got := (&Profile{}).Altitude(90); got != 0 {
+		t.Errorf("empty profile Altitude = %v, want 0", got)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	a, b := &Profile{Latitude: 1}, &Profile{Latitude: 2}
+
+	c.Put("a", a, 0)
+	c.Put("b", b, 0)
+	c.Put("none", nil, time.Minute) // evicts a
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have been evicted")
+	}
+	if got, ok := c.Get("b"); !ok || got != b {
+		t.Errorf("Get(b) = %v, %v", got, ok)
+	}
+	if got, ok := c.Get("none"); !ok || got != nil {
+		t.Errorf("Get(none) = %v, %v; want a cached miss", got, ok)
+	}
+
+	// A miss expires so a profile computed later is found
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("none"); ok {
+		t.Error("the cached miss should have expired")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("b has no expiry and should still be cached")
+	}
+	c.Put("none", a, 0)
+	if got, ok := c.Get("none"); !ok || got != a {
+		t.Errorf("Get(none) = %v, %v after storing a profile", got, ok)
+	}
+}
+
+func TestOfflineSRTMSourceNeverDownloads(t *testing.T) {
+	// With an empty cache even the tile index is missing, and fetching it must fail
+	if _, err := NewOfflineSRTMSource(t.TempDir()); err == nil {
+		t.Error("NewOfflineSRTMSource with an empty cache succeeded; it should not download")
+	}
+}
@@ -0,0 +1,78 @@
+package terrain
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/tkrajina/go-elevations/geoelevations"
+)
+
+// maxLoadedTiles bounds the SRTM tiles an SRTMSource keeps in memory (an
+// SRTM1 tile is about 25 MB). Beyond it the loaded tiles are dropped.
+const maxLoadedTiles = 16
+
+// errOffline is returned for tiles that are not in the local cache when the
+// source may not download
+var errOffline = errors.New("SRTM tile not in local cache")
+
+// SRTMSource reads elevations from SRTM tiles, downloading and caching them
+// in a local directory on first use (the same cache cmd/import-wof uses).
+// It is safe for concurrent use.
+type SRTMSource struct {
+	mu       sync.Mutex
+	client   *http.Client
+	cacheDir string
+	srtm     *geoelevations.Srtm
+	tiles    map[[2]int]bool
+}
+
+// NewSRTMSource opens an SRTM source backed by cacheDir
+func NewSRTMSource(client *http.Client, cacheDir string) (*SRTMSource, error) {
+	s := &SRTMSource{client: client, cacheDir: cacheDir}
+	if err := s.reset(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewOfflineSRTMSource opens an SRTM source that only reads tiles already in
+// cacheDir and never downloads, for use while serving requests. Points on
+// missing tiles return an error.
+func NewOfflineSRTMSource(cacheDir string) (*SRTMSource, error) {
+	return NewSRTMSource(&http.Client{Transport: offlineTransport{}}, cacheDir)
+}
+
+// Elevation implements ElevationSource; it returns NaN where SRTM has no data
+func (s *SRTMSource) Elevation(latitude, longitude float64) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tile := [2]int{int(math.Floor(latitude)), int(math.Floor(longitude))}
+	if !s.tiles[tile] && len(s.tiles) >= maxLoadedTiles {
+		if err := s.reset(); err != nil {
+			return math.NaN(), err
+		}
+	}
+	s.tiles[tile] = true
+	return s.srtm.GetElevation(s.client, latitude, longitude)
+}
+
+// reset drops all loaded tiles
+func (s *SRTMSource) reset() error {
+	srtm, err := geoelevations.NewSrtmWithCustomCacheDir(s.client, s.cacheDir)
+	if err != nil {
+		return err
+	}
+	s.srtm = srtm
+	s.tiles = make(map[[2]int]bool)
+	return nil
+}
+
+// offlineTransport fails every request
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errOffline
+}
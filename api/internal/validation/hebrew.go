@@ -3,6 +3,7 @@ package validation
 
 import (
 	"errors"
+	"strings"
 	"unicode"
 )
 
@@ -86,8 +87,43 @@ func ValidateBilingualNames(nameHebrew, nameEnglish string) map[string]string {
 	return errors
 }
 
-// NormalizeHebrew normalizes Hebrew text (removes extra spaces, trims)
+// NormalizeHebrew normalizes Hebrew text for matching and search: strips
+// niqqud and cantillation marks, drops geresh/gershayim (and ASCII quotes used
+// as such inside abbreviations, so שו"ע matches שו״ע and שוע), turns maqaf
+// into a space, collapses whitespace and trims.
 func NormalizeHebrew(s string) string {
-	// Simple normalization - can be extended
-	return s
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+
+	for i, r := range runes {
+		switch {
+		case isHebrewMark(r), r == '\u05F3', r == '\u05F4':
+			continue
+		case (r == '"' || r == '\'') && i > 0 && IsHebrewChar(runes[i-1]) && i+1 < len(runes) && IsHebrewChar(runes[i+1]):
+			continue
+		case r == '\u05BE' || unicode.IsSpace(r):
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isHebrewMark reports whether r is a niqqud or cantillation mark (but not
+// maqaf, paseq or sof pasuq, which separate words)
+func isHebrewMark(r rune) bool {
+	switch {
+	case r >= 0x0591 && r <= 0x05BD:
+		return true
+	case r == 0x05BF, r == 0x05C1, r == 0x05C2, r == 0x05C4, r == 0x05C5, r == 0x05C7:
+		return true
+	}
+	return false
 }
@@ -145,3 +145,29 @@ func TestIsHebrewChar(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeHebrew(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "שקיעה", "שקיעה"},
+		{"niqqud", "שְׁקִיעָה", "שקיעה"},
+		{"cantillation", "בְּרֵאשִׁ֖ית", "בראשית"},
+		{"gershayim", "שו״ע", "שוע"},
+		{"ascii abbreviation", `שו"ע או"ח`, "שוע אוח"},
+		{"geresh", "ר׳ תם", "ר תם"},
+		{"maqaf", "בין־השמשות", "בין השמשות"},
+		{"whitespace", "  עלות   השחר\n", "עלות השחר"},
+		{"english quotes kept", `the "gra" method`, `the "gra" method`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeHebrew(tt.in); got != tt.want {
+				t.Errorf("NormalizeHebrew(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
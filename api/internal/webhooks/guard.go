@@ -0,0 +1,64 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned when a webhook URL resolves to an address
+// inside a private or local network
+var ErrForbiddenAddress = errors.New("webhook address is not publicly routable")
+
+// nonPublicNetworks are ranges not covered by the net.IP predicates that
+// still reach infrastructure rather than a publisher's receiver
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // "this network"
+	"100.64.0.0/10", // carrier-grade NAT, also used for cloud metadata services
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+)
+
+// IsPublicIP reports whether webhooks may be delivered to ip: it must not be
+// loopback, private (RFC 1918, fc00::/7), link-local (including the
+// 169.254.169.254 metadata service), multicast or unspecified.
+// IPv4-mapped IPv6 addresses are judged by their IPv4 address.
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialControl runs after DNS resolution, on the address actually dialed, so
+// hostnames that resolve (or re-resolve, as in DNS rebinding) to an internal
+// address are refused too
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
@@ -0,0 +1,255 @@
+// Package webhooks implements signed outbound event notifications for publishers.
+//
+// Every delivery is an HTTP POST with a JSON Event body and these headers:
+//
+//	X-Zmanim-Event:     the event type, e.g. "algorithm.published"
+//	X-Zmanim-Delivery:  unique delivery ID (stable across retries)
+//	X-Zmanim-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>
+//
+// Receivers should recompute the signature with their subscription secret and
+// reject stale timestamps to prevent replay (see Verify).
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types
+const (
+	// EventAlgorithmPublished fires when a publisher publishes an algorithm version
+	EventAlgorithmPublished = "algorithm.published"
+	// EventZmanUpdated fires when a publisher edits one of their zmanim
+	EventZmanUpdated = "zman.updated"
+	// EventSnapshotRestored fires when a publisher restores or imports a snapshot
+	EventSnapshotRestored = "snapshot.restored"
+	// EventCoverageChanged fires when coverage is added, updated or removed
+	EventCoverageChanged = "coverage.changed"
+	// EventPing is sent by the "send test event" endpoint; always delivered
+	EventPing = "ping"
+)
+
+// EventTypes lists the event types a subscription can select
+var EventTypes = []string{EventAlgorithmPublished, EventZmanUpdated, EventSnapshotRestored, EventCoverageChanged}
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-Zmanim-Event"
+	HeaderDelivery  = "X-Zmanim-Delivery"
+	HeaderSignature = "X-Zmanim-Signature"
+)
+
+// Retry policy
+const (
+	// MaxAttempts is the number of delivery attempts before a delivery is marked failed
+	MaxAttempts = 10
+	// initialBackoff is the delay after the first failed attempt
+	initialBackoff = 30 * time.Second
+	// maxBackoff caps the delay between attempts
+	maxBackoff = 6 * time.Hour
+	// DefaultTolerance is the recommended maximum signature age for receivers
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMissingSignature = errors.New("missing webhook signature")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// Event is the JSON body of a delivery
+type Event struct {
+	ID          string      `json:"id"`
+	Type        string      `json:"type"`
+	PublisherID string      `json:"publisher_id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Data        interface{} `json:"data"`
+}
+
+// IsValidEventType reports whether t can be subscribed to
+func IsValidEventType(t string) bool {
+	for _, e := range EventTypes {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateSecret returns a new random signing secret in the form whsec_<64 hex chars>
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the X-Zmanim-Signature header value for body sent at ts
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + computeSignature(secret, t, body)
+}
+
+// computeSignature returns hex HMAC-SHA256 over "<t>.<body>"
+func computeSignature(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header against body. A zero tolerance skips the
+// timestamp check.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var t string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if t == "" || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	expected := computeSignature(secret, t, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Backoff returns the delay before the next attempt after attempt failures
+// (30s, 1m, 2m, ... capped at 6h)
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := initialBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// Result describes a single delivery attempt
+type Result struct {
+	StatusCode int
+	Duration   time.Duration
+	// ResponseBody is truncated to maxResponseBody bytes
+	ResponseBody string
+}
+
+// Success reports whether the receiver accepted the delivery (any 2xx)
+func (r Result) Success() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// maxResponseBody limits how much of a receiver's response is kept for the delivery log
+const maxResponseBody = 1024
+
+// Sender performs signed HTTP deliveries
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender creates a sender with the given per-request timeout. Unless
+// allowPrivateNetworks is set (local testing only), connections to loopback,
+// private, link-local and unspecified addresses are refused after DNS
+// resolution, so a subscription cannot make the server call internal services.
+func NewSender(timeout time.Duration, allowPrivateNetworks bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if !allowPrivateNetworks {
+		dialer.Control = dialControl
+	}
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			// No proxy: the dial guard must see the receiver's own address
+			Transport: &http.Transport{
+				DialContext:           dialer.DialContext,
+				ForceAttemptHTTP2:     true,
+				MaxIdleConns:          100,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: time.Second,
+			},
+			// Receivers must answer directly; following redirects would re-send signed bodies elsewhere
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Deliver POSTs body to url, signed with secret. A non-nil error means the
+// request could not be completed (network error, timeout); non-2xx responses
+// are reported through Result.
+func (s *Sender) Deliver(ctx context.Context, url, secret, deliveryID, eventType string, body []byte) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Zmanim-Lab-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderSignature, Sign(secret, s.now(), body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{Duration: time.Since(start)}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return Result{
+		StatusCode:   resp.StatusCode,
+		Duration:     time.Since(start),
+		ResponseBody: string(respBody),
+	}, nil
+}
@@ -0,0 +1,166 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks/webhooktest"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"type":"ping"}`)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	header := webhooks.Sign(secret, now, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr error
+	}{
+		{"valid", secret, header, body, now, nil},
+		{"within tolerance", secret, header, body, now.Add(4 * time.Minute), nil},
+		{"wrong secret", "whsec_other", header, body, now, webhooks.ErrInvalidSignature},
+		{"tampered body", secret, header, []byte(`{"type":"pong"}`), now, webhooks.ErrInvalidSignature},
+		{"stale", secret, header, body, now.Add(10 * time.Minute), webhooks.ErrSignatureExpired},
+		{"missing", secret, "", body, now, webhooks.ErrMissingSignature},
+		{"malformed", secret, "v1=abc", body, now, webhooks.ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhooks.Verify(tt.secret, tt.header, tt.body, tt.now, webhooks.DefaultTolerance)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhooks.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestSenderDeliversToReceiver(t *testing.T) {
+	secret, err := webhooks.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcv := webhooktest.NewReceiver(secret)
+	defer rcv.Close()
+
+	// The receiver listens on loopback, which only a test sender may reach
+	sender := webhooks.NewSender(5*time.Second, true)
+	ctx := context.Background()
+	body := []byte(`{"id":"evt-1","type":"zman.updated","publisher_id":"pub-1","data":{"zman_key":"alos"}}`)
+
+	// First attempt fails, second succeeds
+	rcv.FailNext(1, http.StatusInternalServerError)
+	res, err := sender.Deliver(ctx, rcv.URL(), secret, "del-1", webhooks.EventZmanUpdated, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Success() {
+		t.Fatalf("first attempt status = %d, want failure", res.StatusCode)
+	}
+	res, err = sender.Deliver(ctx, rcv.URL(), secret, "del-1", webhooks.EventZmanUpdated, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Success() {
+		t.Fatalf("second attempt status = %d, want 2xx", res.StatusCode)
+	}
+
+	got := rcv.WaitFor(t, 2, time.Second)
+	d := got[1]
+	if d.SignatureErr != nil {
+		t.Errorf("signature error: %v", d.SignatureErr)
+	}
+	if d.DeliveryID != "del-1" || d.EventType != webhooks.EventZmanUpdated {
+		t.Errorf("headers = %q/%q", d.DeliveryID, d.EventType)
+	}
+	evt, err := d.Event()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.PublisherID != "pub-1" {
+		t.Errorf("publisher_id = %q, want pub-1", evt.PublisherID)
+	}
+
+	// A receiver with a different secret rejects the delivery
+	res, _ = sender.Deliver(ctx, rcv.URL(), "whsec_wrong", "del-2", webhooks.EventZmanUpdated, body)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong secret status = %d, want 401", res.StatusCode)
+	}
+}
+
+func TestSenderRefusesPrivateAddresses(t *testing.T) {
+	rcv := webhooktest.NewReceiver("secret")
+	defer rcv.Close()
+
+	sender := webhooks.NewSender(5*time.Second, false)
+	body := []byte(`{}`)
+
+	// The receiver's literal loopback address, and a hostname resolving to it
+	urls := []string{rcv.URL(), strings.Replace(rcv.URL(), "127.0.0.1", "localhost", 1)}
+	for _, u := range urls {
+		_, err := sender.Deliver(context.Background(), u, "secret", "del-1", webhooks.EventZmanUpdated, body)
+		if !errors.Is(err, webhooks.ErrForbiddenAddress) {
+			t.Errorf("Deliver(%s) error = %v, want ErrForbiddenAddress", u, err)
+		}
+	}
+	if n := len(rcv.Deliveries()); n != 0 {
+		t.Errorf("receiver got %d deliveries, want none", n)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"172.31.255.255":  false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fc00::1":         false,
+		"fd12:3456::1":    false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"100.100.100.200": false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	}
+	for addr, want := range tests {
+		if got := webhooks.IsPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
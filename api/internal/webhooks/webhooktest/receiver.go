@@ -0,0 +1,132 @@
+// Package webhooktest provides a local webhook receiver for integration tests.
+//
+//	rcv := webhooktest.NewReceiver(secret)
+//	defer rcv.Close()
+//	// register rcv.URL() as a subscription, trigger an event...
+//	d := rcv.WaitFor(t, 1, 5*time.Second)[0]
+package webhooktest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jcom-dev/zmanim-lab/internal/webhooks"
+)
+
+// Delivery is a request received by the Receiver
+type Delivery struct {
+	DeliveryID string
+	EventType  string
+	Signature  string
+	Body       []byte
+	// SignatureErr is the result of verifying the signature with the receiver's secret
+	SignatureErr error
+	ReceivedAt   time.Time
+}
+
+// Event decodes the delivery body
+func (d Delivery) Event() (webhooks.Event, error) {
+	var e webhooks.Event
+	err := json.Unmarshal(d.Body, &e)
+	return e, err
+}
+
+// Receiver is an httptest server that records and verifies webhook deliveries.
+// Deliveries with an invalid signature are answered with 401.
+type Receiver struct {
+	server *httptest.Server
+	secret string
+
+	mu         sync.Mutex
+	deliveries []Delivery
+	failNext   int
+	failStatus int
+	notify     chan struct{}
+}
+
+// NewReceiver starts a receiver that verifies signatures with secret
+func NewReceiver(secret string) *Receiver {
+	r := &Receiver{
+		secret: secret,
+		notify: make(chan struct{}, 1),
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.handle))
+	return r
+}
+
+// URL returns the receiver endpoint
+func (r *Receiver) URL() string {
+	return r.server.URL
+}
+
+// Close shuts the server down
+func (r *Receiver) Close() {
+	r.server.Close()
+}
+
+// FailNext makes the next n deliveries respond with status (e.g. 500) to exercise retries
+func (r *Receiver) FailNext(n, status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failNext = n
+	r.failStatus = status
+}
+
+// Deliveries returns every delivery received so far, including failed ones
+func (r *Receiver) Deliveries() []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Delivery(nil), r.deliveries...)
+}
+
+// WaitFor blocks until at least n deliveries were received or fails the test after timeout
+func (r *Receiver) WaitFor(t testing.TB, n int, timeout time.Duration) []Delivery {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		if d := r.Deliveries(); len(d) >= n {
+			return d
+		}
+		select {
+		case <-r.notify:
+		case <-deadline:
+			t.Fatalf("timed out waiting for %d webhook deliveries, got %d", n, len(r.Deliveries()))
+			return nil
+		}
+	}
+}
+
+func (r *Receiver) handle(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	d := Delivery{
+		DeliveryID: req.Header.Get(webhooks.HeaderDelivery),
+		EventType:  req.Header.Get(webhooks.HeaderEvent),
+		Signature:  req.Header.Get(webhooks.HeaderSignature),
+		Body:       body,
+		ReceivedAt: time.Now(),
+	}
+	d.SignatureErr = webhooks.Verify(r.secret, d.Signature, body, d.ReceivedAt, webhooks.DefaultTolerance)
+
+	r.mu.Lock()
+	r.deliveries = append(r.deliveries, d)
+	status := http.StatusOK
+	if d.SignatureErr != nil {
+		status = http.StatusUnauthorized
+	} else if r.failNext > 0 {
+		r.failNext--
+		status = r.failStatus
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+
+	w.WriteHeader(status)
+}
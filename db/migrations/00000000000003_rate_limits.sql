@@ -0,0 +1,15 @@
+-- Migration: Rate Limits
+-- Description: Token bucket rate limit configuration (per-publisher/per-API-key quotas and route costs)
+
+-- ============================================================================
+-- SYSTEM CONFIG
+-- ============================================================================
+-- rate_limit_anonymous / rate_limit_authenticated: {"requests_per_hour": N}
+-- rate_limit_quotas: per-subject overrides, {"limit": N, "window_seconds": S}
+-- rate_limit_route_costs: tokens consumed per request, keyed by route pattern (default 1)
+INSERT INTO system_config (key, value, description) VALUES
+('rate_limit_anonymous', '{"requests_per_hour": 10000}', 'Rate limit for anonymous API requests'),
+('rate_limit_authenticated', '{"requests_per_hour": 100000}', 'Rate limit for authenticated API requests'),
+('rate_limit_quotas', '{"publishers": {}, "api_keys": {}}', 'Per-publisher and per-API-key rate limit quotas'),
+('rate_limit_route_costs', '{"/api/v1/dsl/preview-week": 5, "/api/v1/ai/generate-formula": 20, "/api/v1/ai/explain-formula": 10, "/api/v1/ai/search": 5, "/api/v1/geo/boundaries/regions": 5, "/api/v1/geo/boundaries/districts": 10}', 'Rate limit token cost per route')
+ON CONFLICT (key) DO NOTHING;
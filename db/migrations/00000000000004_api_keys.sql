@@ -0,0 +1,42 @@
+-- Migration: API Keys
+-- Description: Hashed API keys for third-party consumers of the public zmanim API
+
+-- ============================================================================
+-- API KEYS
+-- ============================================================================
+-- Only the SHA-256 hash of a key is stored; the plaintext is shown once at creation.
+-- publisher_id is NULL for platform keys issued by an admin.
+CREATE TABLE public.api_keys (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    publisher_id uuid,
+    name text NOT NULL,
+    key_prefix text NOT NULL,
+    key_hash text NOT NULL,
+    scopes text[] DEFAULT '{}'::text[] NOT NULL,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    last_used_at timestamptz,
+    rotated_from_id uuid,
+    issued_by text NOT NULL CHECK (issued_by IN ('publisher', 'admin')),
+    created_by text,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+COMMENT ON TABLE public.api_keys IS 'API keys for third-party consumers (display boards, websites)';
+
+-- Daily request counts per key
+CREATE TABLE public.api_key_usage (
+    api_key_id uuid NOT NULL,
+    usage_date date NOT NULL,
+    request_count bigint DEFAULT 0 NOT NULL
+);
+
+ALTER TABLE ONLY public.api_keys ADD CONSTRAINT api_keys_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.api_keys ADD CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash);
+ALTER TABLE ONLY public.api_key_usage ADD CONSTRAINT api_key_usage_pkey PRIMARY KEY (api_key_id, usage_date);
+
+ALTER TABLE ONLY public.api_keys ADD CONSTRAINT api_keys_publisher_id_fkey FOREIGN KEY (publisher_id) REFERENCES public.publishers(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.api_keys ADD CONSTRAINT api_keys_rotated_from_id_fkey FOREIGN KEY (rotated_from_id) REFERENCES public.api_keys(id) ON DELETE SET NULL;
+ALTER TABLE ONLY public.api_key_usage ADD CONSTRAINT api_key_usage_api_key_id_fkey FOREIGN KEY (api_key_id) REFERENCES public.api_keys(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_api_keys_publisher ON public.api_keys USING btree (publisher_id, created_at DESC);
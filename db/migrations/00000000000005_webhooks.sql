@@ -0,0 +1,53 @@
+-- Migration: Webhooks
+-- Description: Per-publisher webhook subscriptions and a durable delivery queue
+
+-- ============================================================================
+-- WEBHOOK SUBSCRIPTIONS
+-- ============================================================================
+-- secret signs every delivery (HMAC-SHA256); it is shown once at creation.
+-- An empty event_types array subscribes to every event type.
+CREATE TABLE public.webhook_subscriptions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    publisher_id uuid NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    event_types text[] DEFAULT '{}'::text[] NOT NULL,
+    description text,
+    is_active boolean DEFAULT true NOT NULL,
+    created_by text,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+COMMENT ON TABLE public.webhook_subscriptions IS 'Publisher webhook endpoints notified of algorithm, zman, snapshot and coverage changes';
+
+-- ============================================================================
+-- WEBHOOK DELIVERIES
+-- ============================================================================
+-- Doubles as the retry queue and the delivery log. Workers claim due rows with
+-- FOR UPDATE SKIP LOCKED; failed attempts are rescheduled with exponential backoff.
+CREATE TABLE public.webhook_deliveries (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    subscription_id uuid NOT NULL,
+    event_id uuid NOT NULL,
+    event_type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'delivering', 'delivered', 'failed')),
+    attempts integer DEFAULT 0 NOT NULL,
+    next_attempt_at timestamptz DEFAULT now() NOT NULL,
+    last_status_code integer,
+    last_error text,
+    last_response_ms integer,
+    delivered_at timestamptz,
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+COMMENT ON TABLE public.webhook_deliveries IS 'Webhook delivery queue and log';
+
+ALTER TABLE ONLY public.webhook_subscriptions ADD CONSTRAINT webhook_subscriptions_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.webhook_deliveries ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.webhook_subscriptions ADD CONSTRAINT webhook_subscriptions_publisher_id_fkey FOREIGN KEY (publisher_id) REFERENCES public.publishers(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.webhook_deliveries ADD CONSTRAINT webhook_deliveries_subscription_id_fkey FOREIGN KEY (subscription_id) REFERENCES public.webhook_subscriptions(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_webhook_subscriptions_publisher ON public.webhook_subscriptions USING btree (publisher_id) WHERE is_active;
+CREATE INDEX idx_webhook_deliveries_due ON public.webhook_deliveries USING btree (next_attempt_at) WHERE status IN ('pending', 'delivering');
+CREATE INDEX idx_webhook_deliveries_subscription ON public.webhook_deliveries USING btree (subscription_id, created_at DESC);
@@ -0,0 +1,39 @@
+-- Migration: Effective-dated zman formulas
+-- Description: Lets publisher_zman_versions entries apply to a date range, so a
+-- formula change can be scheduled ("from Rosh Hashana tzeis is 8.5°") without
+-- rewriting historical or already-published dates.
+
+-- ============================================================================
+-- PUBLISHER ZMAN VERSIONS
+-- ============================================================================
+-- A version with effective_from and/or effective_until set is a scheduled formula.
+-- For a given date the calculation uses the scheduled version whose range contains
+-- it (from inclusive, until exclusive), preferring the latest effective_from;
+-- otherwise publisher_zmanim.formula_dsl applies. NULL bounds are open-ended.
+ALTER TABLE public.publisher_zman_versions
+    ADD COLUMN effective_from date,
+    ADD COLUMN effective_until date,
+    ADD CONSTRAINT publisher_zman_versions_effective_range
+        CHECK (effective_from IS NULL OR effective_until IS NULL OR effective_until > effective_from);
+
+COMMENT ON COLUMN public.publisher_zman_versions.effective_from IS 'First date (inclusive) this version applies to; NULL = unbounded';
+COMMENT ON COLUMN public.publisher_zman_versions.effective_until IS 'Date (exclusive) this version stops applying; NULL = unbounded';
+
+CREATE INDEX idx_publisher_zman_versions_effective ON public.publisher_zman_versions USING btree (publisher_zman_id, effective_from)
+    WHERE effective_from IS NOT NULL OR effective_until IS NOT NULL;
+
+-- Only prune plain history; scheduled versions are still needed for calculation
+CREATE OR REPLACE FUNCTION public.prune_zman_versions() RETURNS trigger
+    LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM publisher_zman_versions
+    WHERE publisher_zman_id = NEW.publisher_zman_id
+    AND effective_from IS NULL AND effective_until IS NULL
+    AND id NOT IN (
+        SELECT id FROM publisher_zman_versions
+        WHERE publisher_zman_id = NEW.publisher_zman_id
+        ORDER BY version_number DESC LIMIT 7
+    );
+    RETURN NEW;
+END;
+$$;
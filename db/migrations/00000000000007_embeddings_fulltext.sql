@@ -0,0 +1,19 @@
+-- Migration: Full-text search over RAG embeddings
+-- Description: Adds a tsvector to embeddings so formula RAG can fuse lexical and
+-- vector retrieval, and still answer queries when no embedder is configured.
+
+-- ============================================================================
+-- EMBEDDINGS
+-- ============================================================================
+-- search_text holds the chunk content after validation.NormalizeHebrew (niqqud,
+-- gershayim and maqaf removed) and is written by cmd/indexer. Rows indexed
+-- before this migration fall back to the raw content.
+ALTER TABLE public.embeddings
+    ADD COLUMN search_text text,
+    ADD COLUMN search_tsv tsvector
+        GENERATED ALWAYS AS (to_tsvector('english'::regconfig, COALESCE(search_text, content))) STORED;
+
+COMMENT ON COLUMN public.embeddings.search_text IS 'Hebrew-normalized content used for full-text search';
+COMMENT ON COLUMN public.embeddings.search_tsv IS 'Full-text index of search_text (or content)';
+
+CREATE INDEX embeddings_search_tsv_idx ON public.embeddings USING gin (search_tsv);
@@ -0,0 +1,41 @@
+-- Migration: Conversational formula builder sessions
+-- Description: Persists multi-turn AI formula conversations so a publisher can
+-- refine a formula over several messages and apply the result to a zman.
+
+-- ============================================================================
+-- AI FORMULA SESSIONS
+-- ============================================================================
+-- messages holds the full conversation (user, assistant and tool-result turns)
+-- as sent to the model. current_formula is the last validated formula the
+-- assistant proposed; applying it sets status to 'applied'.
+CREATE TABLE public.ai_formula_sessions (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    publisher_id uuid NOT NULL,
+    user_id text,
+    zman_key varchar(100),
+    title text,
+    messages jsonb DEFAULT '[]'::jsonb NOT NULL,
+    current_formula text,
+    status varchar(20) DEFAULT 'active' NOT NULL CHECK (status IN ('active', 'applied', 'closed')),
+    tokens_used integer DEFAULT 0 NOT NULL,
+    applied_at timestamptz,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+COMMENT ON TABLE public.ai_formula_sessions IS 'Multi-turn AI formula builder conversations';
+COMMENT ON COLUMN public.ai_formula_sessions.zman_key IS 'Publisher zman the session is refining, if any';
+
+ALTER TABLE ONLY public.ai_formula_sessions ADD CONSTRAINT ai_formula_sessions_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.ai_formula_sessions ADD CONSTRAINT ai_formula_sessions_publisher_id_fkey FOREIGN KEY (publisher_id) REFERENCES public.publishers(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_ai_formula_sessions_publisher ON public.ai_formula_sessions USING btree (publisher_id, updated_at DESC);
+
+-- ============================================================================
+-- AI AUDIT LOGS
+-- ============================================================================
+-- Every session turn is audited with its session so the trail can be replayed
+ALTER TABLE public.ai_audit_logs
+    ADD COLUMN session_id uuid REFERENCES public.ai_formula_sessions(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_ai_audit_logs_session ON public.ai_audit_logs USING btree (session_id, created_at)
+    WHERE session_id IS NOT NULL;
@@ -0,0 +1,20 @@
+-- Migration: Per-publisher solar engine
+-- Description: Lets a publisher calculate with the NREL Solar Position Algorithm
+-- instead of the default NOAA calculator, with the ΔT and atmospheric conditions
+-- SPA accepts, so times can match a printed luach to the second.
+
+-- ============================================================================
+-- PUBLISHERS
+-- ============================================================================
+-- The atmosphere columns only affect the 'spa' engine. NULL means the engine
+-- default: an estimated ΔT and the standard atmosphere (1010 mbar, 10 °C).
+ALTER TABLE public.publishers
+    ADD COLUMN solar_engine varchar(10) DEFAULT 'noaa' NOT NULL CHECK (solar_engine IN ('noaa', 'spa')),
+    ADD COLUMN solar_delta_t double precision CHECK (solar_delta_t BETWEEN -300 AND 300),
+    ADD COLUMN solar_pressure_mbar double precision CHECK (solar_pressure_mbar BETWEEN 300 AND 1100),
+    ADD COLUMN solar_temperature_c double precision CHECK (solar_temperature_c BETWEEN -60 AND 60);
+
+COMMENT ON COLUMN public.publishers.solar_engine IS 'Solar position algorithm for DSL calculations: noaa (default) or spa (NREL SPA)';
+COMMENT ON COLUMN public.publishers.solar_delta_t IS 'SPA: TT - UT1 in seconds; NULL = estimated from the date';
+COMMENT ON COLUMN public.publishers.solar_pressure_mbar IS 'SPA: annual average local pressure for refraction; NULL = 1010 mbar';
+COMMENT ON COLUMN public.publishers.solar_temperature_c IS 'SPA: annual average local temperature for refraction; NULL = 10 °C';
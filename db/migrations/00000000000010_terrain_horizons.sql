@@ -0,0 +1,35 @@
+-- Migration: Terrain horizon profiles
+-- Description: Stores the skyline around each city, computed from SRTM
+-- elevation data, so visible sunrise and sunset can account for the mountains
+-- that hide the sun (visible_sunrise_terrain / visible_sunset_terrain).
+
+-- ============================================================================
+-- GEO CITY HORIZONS
+-- ============================================================================
+-- One profile per city, computed offline by `import-wof horizons`. altitudes[i]
+-- is the apparent altitude of the skyline in degrees at azimuth i * azimuth_step,
+-- measured clockwise from north.
+CREATE TABLE public.geo_city_horizons (
+    city_id uuid PRIMARY KEY REFERENCES public.geo_cities(id) ON DELETE CASCADE,
+    ground_elevation_m double precision NOT NULL,
+    observer_elevation_m double precision NOT NULL,
+    azimuth_step double precision NOT NULL CHECK (azimuth_step > 0 AND azimuth_step <= 10),
+    max_distance_m double precision NOT NULL,
+    altitudes double precision[] NOT NULL,
+    source text DEFAULT 'srtm' NOT NULL,
+    computed_at timestamptz DEFAULT now() NOT NULL
+);
+
+COMMENT ON TABLE public.geo_city_horizons IS 'Terrain horizon profile around each city, for terrain-aware visible sunrise and sunset';
+COMMENT ON COLUMN public.geo_city_horizons.ground_elevation_m IS 'DEM elevation at the city point, meters';
+COMMENT ON COLUMN public.geo_city_horizons.observer_elevation_m IS 'Ground elevation plus eye height, meters';
+COMMENT ON COLUMN public.geo_city_horizons.azimuth_step IS 'Degrees between entries of altitudes';
+COMMENT ON COLUMN public.geo_city_horizons.max_distance_m IS 'How far each ray was followed, meters';
+COMMENT ON COLUMN public.geo_city_horizons.altitudes IS 'Apparent horizon altitude in degrees, from azimuth 0 (north) eastward';
+
+-- ============================================================================
+-- ASTRONOMICAL PRIMITIVES
+-- ============================================================================
+INSERT INTO astronomical_primitives (id, variable_name, display_name, description, formula_dsl, category, calculation_type, solar_angle, is_dawn, edge_type, sort_order) VALUES
+('6b0f2d1e-3c58-4a0e-9d7b-1f4e8a2c5b90', 'sunrise_visible_terrain', 'Sunrise (Visible over Terrain)', 'First edge of the sun appears over the local skyline of mountains and hills (SRTM terrain)', 'visible_sunrise_terrain', 'horizon', 'horizon', NULL, true, 'top_edge', 104),
+('a7c34e59-80d2-4f1b-b6e3-5d9f0c7a2e14', 'sunset_visible_terrain', 'Sunset (Visible over Terrain)', 'Last edge of the sun disappears behind the local skyline of mountains and hills (SRTM terrain)', 'visible_sunset_terrain', 'horizon', 'horizon', NULL, false, 'top_edge', 105);
@@ -0,0 +1,17 @@
+-- Migration: Lunar primitives
+-- Description: Registers moonrise, moonset and the moon's illuminated fraction,
+-- for Kiddush Levana times and displays around the molad.
+
+-- ============================================================================
+-- ASTRONOMICAL PRIMITIVES
+-- ============================================================================
+-- Lunar primitives are found by following the moon's position rather than
+-- solving for a solar angle, so they get their own calculation type.
+ALTER TABLE public.astronomical_primitives DROP CONSTRAINT astronomical_primitives_calculation_type_check;
+ALTER TABLE public.astronomical_primitives ADD CONSTRAINT astronomical_primitives_calculation_type_check
+    CHECK (calculation_type IN ('horizon', 'solar_angle', 'transit', 'fixed_minutes', 'lunar'));
+
+INSERT INTO astronomical_primitives (id, variable_name, display_name, description, formula_dsl, category, calculation_type, solar_angle, is_dawn, edge_type, sort_order) VALUES
+('3f8e61c2-5a07-4d9b-8e14-c2b7a9d05f31', 'moonrise', 'Moonrise', 'Upper edge of the moon rises above the horizon (accounting for refraction). About one day a month has no moonrise.', 'moonrise', 'lunar', 'lunar', NULL, NULL, 'top_edge', 600),
+('9d24b7e0-1c6f-4a38-b5d2-7e0f3a8c1b46', 'moonset', 'Moonset', 'Upper edge of the moon sets below the horizon (accounting for refraction). About one day a month has no moonset.', 'moonset', 'lunar', 'lunar', NULL, NULL, 'top_edge', 601),
+('c51a0f93-6e2d-4b87-a0c4-58d9e3b72f10', 'moon_illumination', 'Moon Illumination', 'Illuminated fraction of the moon''s disc at sunset, from 0 (new) to 1 (full). A condition variable, e.g. if (moon_illumination > 0.5)', 'moon_illumination', 'lunar', 'lunar', NULL, NULL, NULL, 602);
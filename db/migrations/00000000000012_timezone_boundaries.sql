@@ -0,0 +1,22 @@
+-- Migration: Timezone boundaries
+-- Description: Offline IANA timezone polygons (timezone-boundary-builder), so
+-- zmanim can be calculated from raw coordinates without the caller supplying a
+-- timezone. Imported with `import-wof timezones`.
+
+-- ============================================================================
+-- GEO TIMEZONE BOUNDARIES
+-- ============================================================================
+-- The "with oceans" release covers the whole globe, including territorial and
+-- international waters, so every point resolves to exactly one zone.
+CREATE TABLE public.geo_timezone_boundaries (
+    id integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    tzid text NOT NULL,
+    boundary geography(MultiPolygon,4326) NOT NULL,
+    created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX idx_geo_timezone_boundaries_boundary ON public.geo_timezone_boundaries USING gist (boundary);
+CREATE INDEX idx_geo_timezone_boundaries_tzid ON public.geo_timezone_boundaries USING btree (tzid);
+
+COMMENT ON TABLE public.geo_timezone_boundaries IS 'IANA timezone polygons from timezone-boundary-builder, for resolving a timezone from coordinates';
+COMMENT ON COLUMN public.geo_timezone_boundaries.tzid IS 'IANA timezone name, e.g. Asia/Jerusalem';
@@ -0,0 +1,52 @@
+-- Migration: Publisher locations
+-- Description: Named points a publisher defines for itself (a shul on a
+-- hilltop, a camp) with exact coordinates, elevation, timezone and an optional
+-- horizon override. Locations can be covered, looked up with
+-- /zmanim?locationId=, previewed, and are found by publisher-scoped search.
+
+-- ============================================================================
+-- PUBLISHER LOCATIONS
+-- ============================================================================
+-- horizon_altitudes overrides the SRTM horizon profile: entry i is the apparent
+-- altitude of the skyline in degrees at azimuth i * 360 / n from north. A
+-- single entry is a uniform horizon.
+CREATE TABLE public.publisher_locations (
+    id uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    publisher_id uuid NOT NULL REFERENCES public.publishers(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    description text,
+    latitude double precision NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude double precision NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    location geography(Point,4326) GENERATED ALWAYS AS ((st_setsrid(st_makepoint(longitude, latitude), 4326))::geography) STORED,
+    elevation_m double precision DEFAULT 0 NOT NULL CHECK (elevation_m BETWEEN -500 AND 9000),
+    timezone text NOT NULL,
+    horizon_altitudes double precision[] CHECK (cardinality(horizon_altitudes) BETWEEN 1 AND 360),
+    created_by text,
+    created_at timestamptz DEFAULT now() NOT NULL,
+    updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_publisher_locations_name ON public.publisher_locations USING btree (publisher_id, lower(name));
+CREATE INDEX idx_publisher_locations_location ON public.publisher_locations USING gist (location);
+CREATE INDEX idx_publisher_locations_name_trgm ON public.publisher_locations USING gin (name gin_trgm_ops);
+
+CREATE TRIGGER update_publisher_locations_updated_at BEFORE UPDATE ON public.publisher_locations FOR EACH ROW EXECUTE FUNCTION public.update_updated_at_column();
+
+COMMENT ON TABLE public.publisher_locations IS 'Named points defined by a publisher, with exact coordinates, elevation, timezone and optional horizon';
+COMMENT ON COLUMN public.publisher_locations.elevation_m IS 'Observer elevation above sea level, meters';
+COMMENT ON COLUMN public.publisher_locations.timezone IS 'IANA timezone name';
+COMMENT ON COLUMN public.publisher_locations.horizon_altitudes IS 'Horizon override in degrees, evenly spaced from azimuth 0 (north) eastward; NULL uses the SRTM profile';
+
+-- ============================================================================
+-- LOCATION COVERAGE
+-- ============================================================================
+ALTER TABLE public.publisher_coverage
+    ADD COLUMN location_id uuid REFERENCES public.publisher_locations(id) ON DELETE CASCADE;
+
+ALTER TABLE public.publisher_coverage DROP CONSTRAINT publisher_coverage_coverage_level_check;
+ALTER TABLE public.publisher_coverage ADD CONSTRAINT publisher_coverage_coverage_level_check
+    CHECK (coverage_level IN ('location', 'city', 'district', 'region', 'country', 'continent'));
+
+CREATE UNIQUE INDEX idx_publisher_coverage_unique_location ON public.publisher_coverage USING btree (publisher_id, location_id) WHERE (coverage_level = 'location');
+
+COMMENT ON COLUMN public.publisher_coverage.location_id IS 'Publisher location for location-level coverage';
@@ -0,0 +1,195 @@
+-- Migration: Polygon and radius coverage
+-- Description: Coverage areas that do not follow administrative boundaries: an
+-- uploaded GeoJSON polygon (an eruv, a neighbourhood) or a circle around a
+-- center point. Both are stored as a geography MultiPolygon in
+-- publisher_coverage.area and are matched by get_publishers_for_city and the
+-- new get_publishers_for_point.
+
+-- ============================================================================
+-- AREA COVERAGE
+-- ============================================================================
+ALTER TABLE public.publisher_coverage
+    ADD COLUMN area geography(MultiPolygon,4326),
+    ADD COLUMN area_name text,
+    ADD COLUMN area_km2 double precision,
+    ADD COLUMN center geography(Point,4326),
+    ADD COLUMN radius_m double precision CHECK (radius_m > 0);
+
+ALTER TABLE public.publisher_coverage DROP CONSTRAINT publisher_coverage_coverage_level_check;
+ALTER TABLE public.publisher_coverage ADD CONSTRAINT publisher_coverage_coverage_level_check
+    CHECK (coverage_level IN ('location', 'polygon', 'radius', 'city', 'district', 'region', 'country', 'continent'));
+
+ALTER TABLE public.publisher_coverage ADD CONSTRAINT publisher_coverage_area_check
+    CHECK ((coverage_level IN ('polygon', 'radius')) = (area IS NOT NULL)
+       AND (coverage_level = 'radius') = (center IS NOT NULL AND radius_m IS NOT NULL));
+
+CREATE INDEX idx_publisher_coverage_area ON public.publisher_coverage USING gist (area) WHERE (area IS NOT NULL);
+
+COMMENT ON COLUMN public.publisher_coverage.area IS 'Covered area for polygon and radius coverage; a radius is stored as its buffered circle';
+COMMENT ON COLUMN public.publisher_coverage.area_name IS 'Display name of a polygon or radius area';
+COMMENT ON COLUMN public.publisher_coverage.area_km2 IS 'Size of the covered area in square kilometers';
+COMMENT ON COLUMN public.publisher_coverage.center IS 'Center point of radius coverage';
+COMMENT ON COLUMN public.publisher_coverage.radius_m IS 'Radius of radius coverage, meters';
+
+-- ============================================================================
+-- PUBLISHER MATCHING
+-- ============================================================================
+CREATE OR REPLACE FUNCTION public.get_publishers_for_city(p_city_id uuid) RETURNS TABLE(publisher_id uuid, publisher_name text, coverage_level text, priority integer, match_type text)
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    v_city RECORD;
+BEGIN
+    SELECT
+        c.id,
+        c.country_id,
+        c.region_id,
+        c.district_id,
+        c.location,
+        co.continent_id,
+        cont.code as continent_code
+    INTO v_city
+    FROM geo_cities c
+    JOIN geo_countries co ON c.country_id = co.id
+    JOIN geo_continents cont ON co.continent_id = cont.id
+    WHERE c.id = p_city_id;
+
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    RETURN QUERY
+    SELECT DISTINCT ON (pc.publisher_id)
+        pc.publisher_id,
+        p.name::TEXT as publisher_name,
+        pc.coverage_level,
+        pc.priority,
+        CASE pc.coverage_level
+            WHEN 'city' THEN 'exact_city'
+            WHEN 'polygon' THEN 'area_match'
+            WHEN 'radius' THEN 'area_match'
+            WHEN 'district' THEN 'district_match'
+            WHEN 'region' THEN 'region_match'
+            WHEN 'country' THEN 'country_match'
+            WHEN 'continent' THEN 'continent_match'
+        END as match_type
+    FROM publisher_coverage pc
+    JOIN publishers p ON p.id = pc.publisher_id
+    WHERE pc.is_active = TRUE
+      AND p.status = 'active'
+      AND (
+        (pc.coverage_level = 'city' AND pc.city_id = p_city_id)
+        OR (pc.coverage_level IN ('polygon', 'radius') AND ST_Covers(pc.area, v_city.location))
+        OR (pc.coverage_level = 'district' AND pc.district_id = v_city.district_id AND v_city.district_id IS NOT NULL)
+        OR (pc.coverage_level = 'region' AND pc.region_id = v_city.region_id AND v_city.region_id IS NOT NULL)
+        OR (pc.coverage_level = 'country' AND pc.country_id = v_city.country_id)
+        OR (pc.coverage_level = 'continent' AND pc.continent_code = v_city.continent_code)
+      )
+    ORDER BY pc.publisher_id,
+             CASE pc.coverage_level
+                 WHEN 'city' THEN 1
+                 WHEN 'polygon' THEN 2
+                 WHEN 'radius' THEN 2
+                 WHEN 'district' THEN 3
+                 WHEN 'region' THEN 4
+                 WHEN 'country' THEN 5
+                 WHEN 'continent' THEN 6
+             END,
+             pc.priority DESC;
+END;
+$$;
+
+COMMENT ON FUNCTION public.get_publishers_for_city(p_city_id uuid) IS 'Find publishers serving a city based on geographic hierarchy (city > polygon/radius area > district > region > country > continent)';
+
+-- Points are placed in the hierarchy by boundary polygons. Where no boundary
+-- covers the point at a level, the nearest city within 50km supplies it.
+CREATE FUNCTION public.get_publishers_for_point(p_latitude double precision, p_longitude double precision) RETURNS TABLE(publisher_id uuid, publisher_name text, coverage_level text, priority integer, match_type text)
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    v_point geography := ST_SetSRID(ST_MakePoint(p_longitude, p_latitude), 4326)::geography;
+    v_city RECORD;
+    v_city_id uuid;
+    v_country_id smallint;
+    v_region_id integer;
+    v_district_id integer;
+    v_continent_code varchar(2);
+BEGIN
+    SELECT c.id, c.country_id, c.region_id, c.district_id
+    INTO v_city
+    FROM geo_cities c
+    WHERE ST_DWithin(c.location, v_point, 50000)
+    ORDER BY c.location <-> v_point
+    LIMIT 1;
+
+    SELECT b.city_id INTO v_city_id
+    FROM geo_city_boundaries b
+    WHERE ST_Covers(b.boundary, v_point)
+    LIMIT 1;
+    v_city_id := COALESCE(v_city_id, v_city.id);
+
+    SELECT b.country_id INTO v_country_id
+    FROM geo_country_boundaries b
+    WHERE ST_Covers(b.boundary, v_point)
+    LIMIT 1;
+    v_country_id := COALESCE(v_country_id, v_city.country_id);
+
+    SELECT b.region_id INTO v_region_id
+    FROM geo_region_boundaries b
+    WHERE ST_Covers(b.boundary, v_point)
+    LIMIT 1;
+    v_region_id := COALESCE(v_region_id, v_city.region_id);
+
+    SELECT b.district_id INTO v_district_id
+    FROM geo_district_boundaries b
+    WHERE ST_Covers(b.boundary, v_point)
+    LIMIT 1;
+    v_district_id := COALESCE(v_district_id, v_city.district_id);
+
+    SELECT cont.code INTO v_continent_code
+    FROM geo_countries co
+    JOIN geo_continents cont ON cont.id = co.continent_id
+    WHERE co.id = v_country_id;
+
+    RETURN QUERY
+    SELECT DISTINCT ON (pc.publisher_id)
+        pc.publisher_id,
+        p.name::TEXT as publisher_name,
+        pc.coverage_level,
+        pc.priority,
+        CASE pc.coverage_level
+            WHEN 'city' THEN 'exact_city'
+            WHEN 'polygon' THEN 'area_match'
+            WHEN 'radius' THEN 'area_match'
+            WHEN 'district' THEN 'district_match'
+            WHEN 'region' THEN 'region_match'
+            WHEN 'country' THEN 'country_match'
+            WHEN 'continent' THEN 'continent_match'
+        END as match_type
+    FROM publisher_coverage pc
+    JOIN publishers p ON p.id = pc.publisher_id
+    WHERE pc.is_active = TRUE
+      AND p.status = 'active'
+      AND (
+        (pc.coverage_level = 'city' AND pc.city_id = v_city_id)
+        OR (pc.coverage_level IN ('polygon', 'radius') AND ST_Covers(pc.area, v_point))
+        OR (pc.coverage_level = 'district' AND pc.district_id = v_district_id)
+        OR (pc.coverage_level = 'region' AND pc.region_id = v_region_id)
+        OR (pc.coverage_level = 'country' AND pc.country_id = v_country_id)
+        OR (pc.coverage_level = 'continent' AND pc.continent_code = v_continent_code)
+      )
+    ORDER BY pc.publisher_id,
+             CASE pc.coverage_level
+                 WHEN 'city' THEN 1
+                 WHEN 'polygon' THEN 2
+                 WHEN 'radius' THEN 2
+                 WHEN 'district' THEN 3
+                 WHEN 'region' THEN 4
+                 WHEN 'country' THEN 5
+                 WHEN 'continent' THEN 6
+             END,
+             pc.priority DESC;
+END;
+$$;
+
+COMMENT ON FUNCTION public.get_publishers_for_point(p_latitude double precision, p_longitude double precision) IS 'Find publishers serving a coordinate: polygon/radius areas, plus city, district, region, country and continent coverage from boundary polygons';
@@ -0,0 +1,194 @@
+-- Migration: Multilingual city search
+-- Description: Search keys that fold the spelling differences between
+-- transliterations (Bnei Brak / Bene Beraq, Petach Tikvah / Petah Tikva), and
+-- curated name variants the folding cannot reach: Jewish-community names
+-- (Yerushalayim, Tzfat, Kiryas Joel), historic names (Vilna, Lemberg) and
+-- region and country abbreviations used as search qualifiers (Monsey NY).
+
+-- ============================================================================
+-- SEARCH KEY
+-- ============================================================================
+-- Lower-cased, accents and apostrophes removed, common transliteration
+-- spellings unified (tz/ts -> z, ch/kh -> h, q -> k, ph -> f, w -> v), a
+-- word-final h after a vowel dropped, vowels other than a word's first letter
+-- dropped and doubled letters collapsed. Hebrew-script names key to
+-- themselves, lower-cased and without punctuation.
+CREATE FUNCTION public.geo_search_key(p_name text) RETURNS text
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE
+    AS $$
+    SELECT btrim(regexp_replace(
+        regexp_replace(
+            regexp_replace(
+                regexp_replace(
+                    replace(replace(replace(replace(replace(replace(replace(replace(replace(
+                        regexp_replace(
+                            regexp_replace(
+                                translate(lower(p_name),
+                                    'áàâäãåāăąéèêëēėęěíìîïīįóòôöõøōőúùûüūůűçćčñńňšśşșžźżłřťțďğý',
+                                    'aaaaaaaaaeeeeeeeeiiiiiioooooooouuuuuuucccnnnsssszzzlrttdgy'),
+                                '[''’`׳״"]', '', 'g'),
+                            '[^[:alnum:]]+', ' ', 'g'),
+                        'sch', 'sh'), 'tz', 'z'), 'ts', 'z'), 'ch', 'h'), 'kh', 'h'),
+                        'ck', 'k'), 'q', 'k'), 'ph', 'f'), 'w', 'v'),
+                    '([aeiouy])h\M', '\1', 'g'),
+                '\Y[aeiouy]+', '', 'g'),
+            '(.)\1+', '\1', 'g'),
+        ' +', ' ', 'g'))
+$$;
+
+COMMENT ON FUNCTION public.geo_search_key(p_name text) IS 'Spelling-insensitive key for matching transliterated place names, e.g. Bnei Brak and Bene Beraq both key to "bn brk"';
+
+CREATE INDEX idx_geo_cities_name_search_key ON public.geo_cities USING btree (public.geo_search_key(name) text_pattern_ops);
+CREATE INDEX idx_geo_cities_name_ascii_search_key ON public.geo_cities USING btree (public.geo_search_key(name_ascii) text_pattern_ops);
+CREATE INDEX idx_geo_names_city_search_key ON public.geo_names USING btree (public.geo_search_key(name) text_pattern_ops) WHERE ((entity_type)::text = 'city');
+
+-- ============================================================================
+-- NAME VARIANTS
+-- ============================================================================
+-- Variants are keyed by country code and canonical name rather than entity ID
+-- so they survive re-imports; canonical_name is matched case-insensitively
+-- against name or name_ascii.
+CREATE TABLE public.geo_name_variants (
+    id integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    entity_type varchar(20) NOT NULL CHECK (entity_type IN ('city', 'region', 'country')),
+    country_code varchar(2) NOT NULL,
+    canonical_name text NOT NULL,
+    variant text NOT NULL,
+    kind text DEFAULT 'transliteration' NOT NULL CHECK (kind IN ('transliteration', 'historic', 'abbreviation')),
+    created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_geo_name_variants_unique ON public.geo_name_variants USING btree (entity_type, country_code, lower(canonical_name), lower(variant));
+CREATE INDEX idx_geo_name_variants_variant_trgm ON public.geo_name_variants USING gin (variant gin_trgm_ops);
+
+COMMENT ON TABLE public.geo_name_variants IS 'Alternate spellings, historic names and abbreviations for search, keyed by country and canonical name';
+COMMENT ON COLUMN public.geo_name_variants.canonical_name IS 'Name or ASCII name of the entity the variant refers to; for countries, the country name';
+COMMENT ON COLUMN public.geo_name_variants.kind IS 'transliteration, historic or abbreviation';
+
+INSERT INTO public.geo_name_variants (entity_type, country_code, canonical_name, variant, kind) VALUES
+-- Israel
+('city', 'IL', 'Jerusalem', 'Yerushalayim', 'transliteration'),
+('city', 'IL', 'Jerusalem', 'Yerushalaim', 'transliteration'),
+('city', 'IL', 'Safed', 'Tzfat', 'transliteration'),
+('city', 'IL', 'Safed', 'Tsfat', 'transliteration'),
+('city', 'IL', 'Safed', 'Zefat', 'transliteration'),
+('city', 'IL', 'Tiberias', 'Teverya', 'transliteration'),
+('city', 'IL', 'Tiberias', 'Tveria', 'transliteration'),
+('city', 'IL', 'Hebron', 'Chevron', 'transliteration'),
+('city', 'IL', 'Beersheba', 'Beer Sheva', 'transliteration'),
+('city', 'IL', 'Beersheba', 'Be''er Sheva', 'transliteration'),
+('city', 'IL', 'Acre', 'Akko', 'transliteration'),
+('city', 'IL', 'Acre', 'Acco', 'transliteration'),
+('city', 'IL', 'Caesarea', 'Kesarya', 'transliteration'),
+('city', 'IL', 'Tel Aviv-Yafo', 'Tel Aviv', 'transliteration'),
+('city', 'IL', 'Tel Aviv', 'Tel Aviv-Yafo', 'transliteration'),
+('city', 'IL', 'Modiin Illit', 'Kiryat Sefer', 'transliteration'),
+('city', 'IL', 'Modi''in Illit', 'Kiryat Sefer', 'transliteration'),
+('city', 'IL', 'Nof HaGalil', 'Nazareth Illit', 'historic'),
+('city', 'IL', 'Nof HaGalil', 'Natzrat Illit', 'historic'),
+-- United States
+('city', 'US', 'Kiryas Joel', 'Kiryat Yoel', 'transliteration'),
+('city', 'US', 'New Square', 'Skver', 'transliteration'),
+('city', 'US', 'New Square', 'Skvere', 'transliteration'),
+-- Europe
+('city', 'BE', 'Antwerp', 'Antwerpen', 'transliteration'),
+('city', 'LT', 'Vilnius', 'Vilna', 'historic'),
+('city', 'LT', 'Kaunas', 'Kovno', 'historic'),
+('city', 'BY', 'Brest', 'Brisk', 'historic'),
+('city', 'BY', 'Grodno', 'Horodna', 'historic'),
+('city', 'UA', 'Lviv', 'Lemberg', 'historic'),
+('city', 'UA', 'Lviv', 'Lvov', 'historic'),
+('city', 'UA', 'Chernivtsi', 'Czernowitz', 'historic'),
+('city', 'UA', 'Berdychiv', 'Berditchev', 'historic'),
+('city', 'UA', 'Mukachevo', 'Munkacs', 'historic'),
+('city', 'UA', 'Vyzhnytsia', 'Vizhnitz', 'historic'),
+('city', 'RU', 'Lyubavichi', 'Lubavitch', 'historic'),
+('city', 'PL', 'Krakow', 'Cracow', 'historic'),
+('city', 'PL', 'Gora Kalwaria', 'Ger', 'historic'),
+('city', 'PL', 'Bobowa', 'Bobov', 'historic'),
+('city', 'RO', 'Satu Mare', 'Satmar', 'historic'),
+('city', 'RO', 'Sighetu Marmatiei', 'Sighet', 'historic'),
+('city', 'SK', 'Bratislava', 'Pressburg', 'historic'),
+-- Countries
+('country', 'US', 'United States', 'USA', 'abbreviation'),
+('country', 'US', 'United States', 'United States of America', 'transliteration'),
+('country', 'US', 'United States', 'America', 'transliteration'),
+('country', 'GB', 'United Kingdom', 'UK', 'abbreviation'),
+('country', 'GB', 'United Kingdom', 'Great Britain', 'transliteration'),
+('country', 'IL', 'Israel', 'Eretz Yisrael', 'transliteration'),
+('country', 'NL', 'Netherlands', 'Holland', 'transliteration'),
+-- US states
+('region', 'US', 'Alabama', 'AL', 'abbreviation'),
+('region', 'US', 'Alaska', 'AK', 'abbreviation'),
+('region', 'US', 'Arizona', 'AZ', 'abbreviation'),
+('region', 'US', 'Arkansas', 'AR', 'abbreviation'),
+('region', 'US', 'California', 'CA', 'abbreviation'),
+('region', 'US', 'Colorado', 'CO', 'abbreviation'),
+('region', 'US', 'Connecticut', 'CT', 'abbreviation'),
+('region', 'US', 'Delaware', 'DE', 'abbreviation'),
+('region', 'US', 'District of Columbia', 'DC', 'abbreviation'),
+('region', 'US', 'Florida', 'FL', 'abbreviation'),
+('region', 'US', 'Georgia', 'GA', 'abbreviation'),
+('region', 'US', 'Hawaii', 'HI', 'abbreviation'),
+('region', 'US', 'Idaho', 'ID', 'abbreviation'),
+('region', 'US', 'Illinois', 'IL', 'abbreviation'),
+('region', 'US', 'Indiana', 'IN', 'abbreviation'),
+('region', 'US', 'Iowa', 'IA', 'abbreviation'),
+('region', 'US', 'Kansas', 'KS', 'abbreviation'),
+('region', 'US', 'Kentucky', 'KY', 'abbreviation'),
+('region', 'US', 'Louisiana', 'LA', 'abbreviation'),
+('region', 'US', 'Maine', 'ME', 'abbreviation'),
+('region', 'US', 'Maryland', 'MD', 'abbreviation'),
+('region', 'US', 'Massachusetts', 'MA', 'abbreviation'),
+('region', 'US', 'Michigan', 'MI', 'abbreviation'),
+('region', 'US', 'Minnesota', 'MN', 'abbreviation'),
+('region', 'US', 'Mississippi', 'MS', 'abbreviation'),
+('region', 'US', 'Missouri', 'MO', 'abbreviation'),
+('region', 'US', 'Montana', 'MT', 'abbreviation'),
+('region', 'US', 'Nebraska', 'NE', 'abbreviation'),
+('region', 'US', 'Nevada', 'NV', 'abbreviation'),
+('region', 'US', 'New Hampshire', 'NH', 'abbreviation'),
+('region', 'US', 'New Jersey', 'NJ', 'abbreviation'),
+('region', 'US', 'New Mexico', 'NM', 'abbreviation'),
+('region', 'US', 'New York', 'NY', 'abbreviation'),
+('region', 'US', 'North Carolina', 'NC', 'abbreviation'),
+('region', 'US', 'North Dakota', 'ND', 'abbreviation'),
+('region', 'US', 'Ohio', 'OH', 'abbreviation'),
+('region', 'US', 'Oklahoma', 'OK', 'abbreviation'),
+('region', 'US', 'Oregon', 'OR', 'abbreviation'),
+('region', 'US', 'Pennsylvania', 'PA', 'abbreviation'),
+('region', 'US', 'Rhode Island', 'RI', 'abbreviation'),
+('region', 'US', 'South Carolina', 'SC', 'abbreviation'),
+('region', 'US', 'South Dakota', 'SD', 'abbreviation'),
+('region', 'US', 'Tennessee', 'TN', 'abbreviation'),
+('region', 'US', 'Texas', 'TX', 'abbreviation'),
+('region', 'US', 'Utah', 'UT', 'abbreviation'),
+('region', 'US', 'Vermont', 'VT', 'abbreviation'),
+('region', 'US', 'Virginia', 'VA', 'abbreviation'),
+('region', 'US', 'Washington', 'WA', 'abbreviation'),
+('region', 'US', 'West Virginia', 'WV', 'abbreviation'),
+('region', 'US', 'Wisconsin', 'WI', 'abbreviation'),
+('region', 'US', 'Wyoming', 'WY', 'abbreviation'),
+-- Canadian provinces and territories
+('region', 'CA', 'Alberta', 'AB', 'abbreviation'),
+('region', 'CA', 'British Columbia', 'BC', 'abbreviation'),
+('region', 'CA', 'Manitoba', 'MB', 'abbreviation'),
+('region', 'CA', 'New Brunswick', 'NB', 'abbreviation'),
+('region', 'CA', 'Newfoundland and Labrador', 'NL', 'abbreviation'),
+('region', 'CA', 'Nova Scotia', 'NS', 'abbreviation'),
+('region', 'CA', 'Northwest Territories', 'NT', 'abbreviation'),
+('region', 'CA', 'Nunavut', 'NU', 'abbreviation'),
+('region', 'CA', 'Ontario', 'ON', 'abbreviation'),
+('region', 'CA', 'Prince Edward Island', 'PE', 'abbreviation'),
+('region', 'CA', 'Quebec', 'QC', 'abbreviation'),
+('region', 'CA', 'Saskatchewan', 'SK', 'abbreviation'),
+('region', 'CA', 'Yukon', 'YT', 'abbreviation'),
+-- Australian states and territories
+('region', 'AU', 'Australian Capital Territory', 'ACT', 'abbreviation'),
+('region', 'AU', 'New South Wales', 'NSW', 'abbreviation'),
+('region', 'AU', 'Northern Territory', 'NT', 'abbreviation'),
+('region', 'AU', 'Queensland', 'QLD', 'abbreviation'),
+('region', 'AU', 'South Australia', 'SA', 'abbreviation'),
+('region', 'AU', 'Tasmania', 'TAS', 'abbreviation'),
+('region', 'AU', 'Victoria', 'VIC', 'abbreviation'),
+('region', 'AU', 'Western Australia', 'WA', 'abbreviation');
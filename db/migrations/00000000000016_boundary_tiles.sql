@@ -0,0 +1,152 @@
+-- Migration: Boundary vector tiles
+-- Description: geo_boundary_tile renders country, region, district and city
+-- boundaries as a Mapbox Vector Tile with ST_AsMVT, simplified for the zoom
+-- level. For a publisher, each boundary carries its coverage status, computed
+-- by publisher_boundary_coverage.
+
+-- ============================================================================
+-- COVERAGE STATUS
+-- ============================================================================
+CREATE FUNCTION public.publisher_boundary_coverage(
+    p_publisher_id uuid,
+    p_boundary geography,
+    p_continent_code text,
+    p_country_id integer,
+    p_region_id integer,
+    p_district_id integer,
+    p_city_id uuid
+) RETURNS text
+    LANGUAGE sql STABLE
+    AS $$
+    SELECT CASE
+        -- Covered at this level or by a containing one
+        WHEN EXISTS (
+            SELECT 1 FROM publisher_coverage pc
+            WHERE pc.publisher_id = p_publisher_id AND pc.is_active = true
+              AND ((pc.coverage_level = 'continent' AND pc.continent_code = p_continent_code)
+                OR (pc.coverage_level = 'country' AND pc.country_id = p_country_id)
+                OR (pc.coverage_level = 'region' AND pc.region_id = p_region_id)
+                OR (pc.coverage_level = 'district' AND pc.district_id = p_district_id)
+                OR (pc.coverage_level = 'city' AND pc.city_id = p_city_id))
+        ) THEN 'full'
+        -- Covered in part, by a contained boundary, area or location
+        WHEN EXISTS (
+            SELECT 1 FROM publisher_coverage pc
+            LEFT JOIN geo_regions r ON r.id = pc.region_id
+            LEFT JOIN geo_districts d ON d.id = pc.district_id
+            LEFT JOIN geo_cities c ON c.id = pc.city_id
+            LEFT JOIN publisher_locations pl ON pl.id = pc.location_id
+            WHERE pc.publisher_id = p_publisher_id AND pc.is_active = true
+              AND ((pc.coverage_level = 'region' AND p_region_id IS NULL AND r.country_id = p_country_id)
+                OR (pc.coverage_level = 'district' AND p_district_id IS NULL
+                    AND (d.region_id = p_region_id OR (p_region_id IS NULL AND d.country_id = p_country_id)))
+                OR (pc.coverage_level = 'city' AND p_city_id IS NULL
+                    AND (c.district_id = p_district_id
+                      OR (p_district_id IS NULL AND c.region_id = p_region_id)
+                      OR (p_region_id IS NULL AND c.country_id = p_country_id)))
+                OR (pc.coverage_level IN ('polygon', 'radius') AND ST_Intersects(pc.area, p_boundary))
+                OR (pc.coverage_level = 'location' AND ST_Intersects(pl.location, p_boundary)))
+        ) THEN 'partial'
+        ELSE 'none'
+    END
+$$;
+
+COMMENT ON FUNCTION public.publisher_boundary_coverage(uuid, geography, text, integer, integer, integer, uuid) IS 'Coverage of a boundary by a publisher: full (covered at its level or above), partial (something inside it is covered) or none. Pass the boundary''s own id and its ancestors; levels below it are NULL.';
+
+-- ============================================================================
+-- VECTOR TILES
+-- ============================================================================
+CREATE FUNCTION public.geo_boundary_tile(p_layer text, p_z integer, p_x integer, p_y integer, p_publisher_id uuid DEFAULT NULL) RETURNS bytea
+    LANGUAGE plpgsql STABLE
+    AS $$
+DECLARE
+    v_env geometry := ST_TileEnvelope(p_z, p_x, p_y);
+    v_box geometry := ST_Transform(v_env, 4326);
+    v_margin double precision := 360.0 / 2 ^ p_z / 8;
+    v_bbox geography;
+    -- One tile unit in Web Mercator meters
+    v_tolerance double precision := 40075016.686 / 2 ^ p_z / 4096;
+    -- Pre-simplified boundaries are detailed enough below zoom 6
+    v_simplified boolean := p_z < 6;
+    v_tile bytea;
+BEGIN
+    -- A geography envelope is ambiguous at half the world wide or more; the
+    -- lowest zooms scan every boundary instead
+    IF p_z > 1 THEN
+        v_bbox := ST_MakeEnvelope(
+            GREATEST(ST_XMin(v_box) - v_margin, -180), GREATEST(ST_YMin(v_box) - v_margin, -90),
+            LEAST(ST_XMax(v_box) + v_margin, 180), LEAST(ST_YMax(v_box) + v_margin, 90), 4326)::geography;
+    END IF;
+
+    IF p_layer = 'countries' THEN
+        SELECT ST_AsMVT(t.*, p_layer, 4096, 'geom') INTO v_tile FROM (
+            SELECT co.id, co.code, co.name, ct.code AS continent_code, b.area_km2,
+                   CASE WHEN p_publisher_id IS NOT NULL THEN
+                       publisher_boundary_coverage(p_publisher_id, b.boundary, ct.code, co.id, NULL, NULL, NULL)
+                   END AS coverage,
+                   ST_AsMVTGeom(ST_Simplify(ST_Transform(
+                       (CASE WHEN v_simplified THEN COALESCE(b.boundary_simplified, b.boundary) ELSE b.boundary END)::geometry,
+                       3857), v_tolerance, true), v_env, 4096, 64, true) AS geom
+            FROM geo_country_boundaries b
+            JOIN geo_countries co ON co.id = b.country_id
+            JOIN geo_continents ct ON ct.id = co.continent_id
+            WHERE v_bbox IS NULL OR b.boundary && v_bbox
+        ) t WHERE t.geom IS NOT NULL;
+
+    ELSIF p_layer = 'regions' THEN
+        SELECT ST_AsMVT(t.*, p_layer, 4096, 'geom') INTO v_tile FROM (
+            SELECT r.id, r.code, r.name, co.code AS country_code, b.area_km2,
+                   CASE WHEN p_publisher_id IS NOT NULL THEN
+                       publisher_boundary_coverage(p_publisher_id, b.boundary, ct.code, r.country_id, r.id, NULL, NULL)
+                   END AS coverage,
+                   ST_AsMVTGeom(ST_Simplify(ST_Transform(
+                       (CASE WHEN v_simplified THEN COALESCE(b.boundary_simplified, b.boundary) ELSE b.boundary END)::geometry,
+                       3857), v_tolerance, true), v_env, 4096, 64, true) AS geom
+            FROM geo_region_boundaries b
+            JOIN geo_regions r ON r.id = b.region_id
+            JOIN geo_countries co ON co.id = r.country_id
+            JOIN geo_continents ct ON ct.id = co.continent_id
+            WHERE v_bbox IS NULL OR b.boundary && v_bbox
+        ) t WHERE t.geom IS NOT NULL;
+
+    ELSIF p_layer = 'districts' THEN
+        SELECT ST_AsMVT(t.*, p_layer, 4096, 'geom') INTO v_tile FROM (
+            SELECT d.id, d.code, d.name, d.region_id, co.code AS country_code, b.area_km2,
+                   CASE WHEN p_publisher_id IS NOT NULL THEN
+                       publisher_boundary_coverage(p_publisher_id, b.boundary, ct.code, d.country_id, d.region_id, d.id, NULL)
+                   END AS coverage,
+                   ST_AsMVTGeom(ST_Simplify(ST_Transform(
+                       (CASE WHEN v_simplified THEN COALESCE(b.boundary_simplified, b.boundary) ELSE b.boundary END)::geometry,
+                       3857), v_tolerance, true), v_env, 4096, 64, true) AS geom
+            FROM geo_district_boundaries b
+            JOIN geo_districts d ON d.id = b.district_id
+            JOIN geo_countries co ON co.id = d.country_id
+            JOIN geo_continents ct ON ct.id = co.continent_id
+            WHERE v_bbox IS NULL OR b.boundary && v_bbox
+        ) t WHERE t.geom IS NOT NULL;
+
+    ELSIF p_layer = 'cities' THEN
+        SELECT ST_AsMVT(t.*, p_layer, 4096, 'geom') INTO v_tile FROM (
+            SELECT c.id::text AS id, c.name, co.code AS country_code, c.population, b.area_km2,
+                   CASE WHEN p_publisher_id IS NOT NULL THEN
+                       publisher_boundary_coverage(p_publisher_id, b.boundary, ct.code, c.country_id, c.region_id, c.district_id, c.id)
+                   END AS coverage,
+                   ST_AsMVTGeom(ST_Simplify(ST_Transform(
+                       (CASE WHEN v_simplified THEN COALESCE(b.boundary_simplified, b.boundary) ELSE b.boundary END)::geometry,
+                       3857), v_tolerance, true), v_env, 4096, 64, true) AS geom
+            FROM geo_city_boundaries b
+            JOIN geo_cities c ON c.id = b.city_id
+            JOIN geo_countries co ON co.id = c.country_id
+            JOIN geo_continents ct ON ct.id = co.continent_id
+            WHERE v_bbox IS NULL OR b.boundary && v_bbox
+        ) t WHERE t.geom IS NOT NULL;
+
+    ELSE
+        RAISE EXCEPTION 'unknown tile layer: %', p_layer;
+    END IF;
+
+    RETURN v_tile;
+END;
+$$;
+
+COMMENT ON FUNCTION public.geo_boundary_tile(text, integer, integer, integer, uuid) IS 'Mapbox Vector Tile of countries, regions, districts or cities boundaries at z/x/y, with coverage status per boundary when a publisher is given';
@@ -0,0 +1,39 @@
+-- Migration: Resumable WOF imports
+-- Description: WOF imports run placetype by placetype in WOF ID order and
+-- record their progress in geo_boundary_imports, one row per placetype per
+-- run, so an interrupted import resumes from its last checkpoint. Each geo
+-- row keeps the WOF lastmodified it was imported from, so an update run only
+-- applies records modified since.
+
+-- ============================================================================
+-- IMPORT RUNS
+-- ============================================================================
+ALTER TABLE public.geo_boundary_imports
+    ADD COLUMN run_id uuid,
+    ADD COLUMN mode text CHECK (mode IN ('full', 'update')),
+    ADD COLUMN status text DEFAULT 'completed' NOT NULL CHECK (status IN ('running', 'completed', 'failed')),
+    ADD COLUMN checkpoint_wof_id bigint,
+    ADD COLUMN since_lastmodified bigint,
+    ADD COLUMN updated_at timestamptz DEFAULT now(),
+    ADD COLUMN completed_at timestamptz;
+
+CREATE UNIQUE INDEX idx_geo_boundary_imports_run_level ON public.geo_boundary_imports USING btree (run_id, level) WHERE (run_id IS NOT NULL);
+
+COMMENT ON COLUMN public.geo_boundary_imports.run_id IS 'Import run this placetype belongs to; NULL for one-shot imports';
+COMMENT ON COLUMN public.geo_boundary_imports.mode IS 'full (every record) or update (records modified since since_lastmodified)';
+COMMENT ON COLUMN public.geo_boundary_imports.status IS 'running, completed or failed; running and failed runs can be resumed';
+COMMENT ON COLUMN public.geo_boundary_imports.checkpoint_wof_id IS 'Last WOF ID committed; records are imported in WOF ID order';
+COMMENT ON COLUMN public.geo_boundary_imports.since_lastmodified IS 'Update mode: only WOF records with a later lastmodified are applied';
+
+-- ============================================================================
+-- WOF LASTMODIFIED
+-- ============================================================================
+ALTER TABLE public.geo_countries ADD COLUMN wof_lastmodified bigint;
+ALTER TABLE public.geo_regions ADD COLUMN wof_lastmodified bigint;
+ALTER TABLE public.geo_districts ADD COLUMN wof_lastmodified bigint;
+ALTER TABLE public.geo_cities ADD COLUMN wof_lastmodified bigint;
+
+COMMENT ON COLUMN public.geo_countries.wof_lastmodified IS 'WOF lastmodified (unix seconds) of the imported record';
+COMMENT ON COLUMN public.geo_regions.wof_lastmodified IS 'WOF lastmodified (unix seconds) of the imported record';
+COMMENT ON COLUMN public.geo_districts.wof_lastmodified IS 'WOF lastmodified (unix seconds) of the imported record';
+COMMENT ON COLUMN public.geo_cities.wof_lastmodified IS 'WOF lastmodified (unix seconds) of the imported record';
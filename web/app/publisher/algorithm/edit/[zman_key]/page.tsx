@@ -262,7 +262,7 @@ export default function ZmanEditorPage() {
     try {
       const response = await api.post<{ explanation: string; language: string; source: string }>(
         '/ai/explain-formula',
-        { body: JSON.stringify({ formula, language }) }
+        { body: JSON.stringify({ formula, language, elaborate: true }) }
       );
       setAiExplanation(response.explanation);
       setHasChanges(true);
@@ -11,7 +11,7 @@ interface FormulaExplanationProps {
 
 interface ExplainResult {
   explanation: string;
-  source: 'ai' | 'cached' | 'custom';
+  source: 'dsl' | 'ai' | 'cached' | 'custom';
   language: string;
 }
 
@@ -73,6 +73,31 @@ export const DSL_PRIMITIVES: ReferenceItem[] = [
     snippet: 'visible_sunset',
     category: 'primitive',
   },
+  {
+    name: 'visible_sunrise_terrain',
+    description: 'Visible sunrise over the local mountains (SRTM terrain), for vasikin',
+    snippet: 'visible_sunrise_terrain',
+    category: 'primitive',
+  },
+  {
+    name: 'visible_sunset_terrain',
+    description: 'Visible sunset behind the local mountains (SRTM terrain)',
+    snippet: 'visible_sunset_terrain',
+    category: 'primitive',
+  },
+  // Moon
+  {
+    name: 'moonrise',
+    description: 'Moonrise - upper edge of the moon rises (none on about one day a month)',
+    snippet: 'moonrise',
+    category: 'primitive',
+  },
+  {
+    name: 'moonset',
+    description: 'Moonset - upper edge of the moon sets (none on about one day a month)',
+    snippet: 'moonset',
+    category: 'primitive',
+  },
   // Civil twilight (-6°)
   {
     name: 'civil_dawn',